@@ -2,10 +2,14 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
 	"github.com/srediag/srediag/internal/core"
@@ -14,7 +18,7 @@ import (
 // OutputFormat standardizes command output formats
 // Only CLI wiring and context setup should be present in this file.
 type OutputFormat struct {
-	Format     string // json, yaml, table
+	Format     string // json, yaml, table, sarif
 	Quiet      bool   // only output essential information
 	NoColor    bool   // disable color output
 	OutputFile string // file to write output to
@@ -126,14 +130,7 @@ and integrates with various observability backends.`,
 		SilenceUsage: true,
 	}
 
-	cmd.PersistentFlags().String("config", "", "path to SREDIAG configuration file (env: SREDIAG_CONFIG)")
-	cmd.PersistentFlags().String("output", "table", "output format (json, yaml, table)")
-	cmd.PersistentFlags().Bool("quiet", false, "only output essential information")
-	cmd.PersistentFlags().Bool("no-color", false, "disable color output")
-	cmd.PersistentFlags().String("output-file", "", "write output to file")
-	cmd.PersistentFlags().String("log-level", "", "set log level (env: SREDIAG_LOG_LEVEL, config: log_level)")
-	cmd.PersistentFlags().String("log-format", "", "set log format: json|console (env: SREDIAG_LOG_FORMAT, config: log_format)")
-	cmd.PersistentFlags().BoolVar(&printConfig, "print-config", false, "print the effective merged config and exit")
+	addGlobalFlags(cmd.PersistentFlags(), &printConfig)
 
 	if err := viper.BindPFlag("srediag.config", cmd.PersistentFlags().Lookup("config")); err != nil {
 		return nil
@@ -154,6 +151,19 @@ and integrates with various observability backends.`,
 	return cmd
 }
 
+// addGlobalFlags defines the root persistent flags on fs.
+// It is also used to build the reserved flag set that diagnostic plugins must not redefine.
+func addGlobalFlags(fs *pflag.FlagSet, printConfig *bool) {
+	fs.String("config", "", "path to SREDIAG configuration file (env: SREDIAG_CONFIG)")
+	fs.String("output", "table", "output format (json, yaml, table, sarif)")
+	fs.Bool("quiet", false, "only output essential information")
+	fs.Bool("no-color", false, "disable color output")
+	fs.String("output-file", "", "write output to file")
+	fs.String("log-level", "", "set log level (env: SREDIAG_LOG_LEVEL, config: log_level)")
+	fs.String("log-format", "", "set log format: json|console (env: SREDIAG_LOG_FORMAT, config: log_format)")
+	fs.BoolVar(printConfig, "print-config", false, "print the effective merged config and exit")
+}
+
 // viperAllSettings returns a map of all viper settings for overlay.
 func viperAllSettings() map[string]string {
 	settings := make(map[string]string)
@@ -165,8 +175,11 @@ func viperAllSettings() map[string]string {
 	return settings
 }
 
-// Execute creates the root command with the given context and executes it
+// Execute creates the root command with the given context and executes it.
+// SIGINT and SIGTERM cancel the command context, which stops in-flight diagnostics.
 func Execute(ctx *core.AppContext) error {
 	rootCmd := NewRootCommand(ctx, nil)
-	return rootCmd.Execute()
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return rootCmd.ExecuteContext(sigCtx)
 }
@@ -3,54 +3,122 @@ package commands
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/srediag/srediag/internal/core"
 	"github.com/srediag/srediag/internal/diagnose"
 )
 
+// diagPluginsDirFunc resolves plugins.dir for installed diagnostic plugin discovery.
+// The command tree is built before the root PersistentPreRunE loads config, so this reads config and env directly.
+// Patchable in tests.
+var diagPluginsDirFunc = func() string {
+	cfg, err := core.LoadPluginConfig(nil)
+	if err != nil || cfg.Dir == "" {
+		return core.DefaultPluginDir()
+	}
+	return cfg.Dir
+}
+
 // newDiagnoseCmd creates a new command for running system diagnostics
-// Only CLI wiring is present here; all business logic is delegated to internal/diagnostic functions:
-// CLI_SystemDiagnostics, CLI_PerformanceDiagnostics, and CLI_SecurityDiagnostics.
+// Only CLI wiring is present here; subcommands are contributed by built-in and installed diagnostic plugins
+// through diagnose.AttachPlugins. Built-in commands delegate to internal/diagnose functions:
+// CLI_SystemDiagnostics, CLI_PerformanceDiagnostics, CLI_SecurityDiagnostics, CLI_KubernetesDiagnostics,
+// CLI_NetworkDiagnostics, CLI_LogsDiagnostics, CLI_ContainerDiagnostics, CLI_CloudDiagnostics, CLI_IaCDiagnostics,
+// CLI_Bundle, CLI_Diff, and CLI_Replay.
 func newDiagnoseCmd(ctx *core.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "diagnose [type]",
 		Short: "Run system diagnostics",
-		Long: `The diagnose command runs diagnostic checks to identify potential issues.
-It provides insights about the system's health, performance, and security.
+		RunE:  runDiagnose,
+	}
+	cmd.PersistentFlags().Duration("timeout", 30*time.Second, "hard timeout per diagnostic attempt (config: diagnostics.defaults.timeout)")
+	cmd.PersistentFlags().String("fail-on", "", "exit with code 6 when findings reach this severity (info, warning, critical)")
+	cmd.PersistentFlags().String("export", "", "send findings (OTLP logs) and measurements (OTLP metrics) to otlp[s]://host:port or http(s)://url")
+	cmd.PersistentFlags().String("save-baseline", "", "save the report as a named baseline for 'srediag diagnose diff'")
+	cmd.PersistentFlags().String("baseline-dir", "", "baseline store (config: diagnostics.baseline.dir, default: <state dir>/baselines)")
+	cmd.PersistentFlags().Duration("watch", 0, "refresh the report at this interval until Ctrl-C (0: diagnostics.watch.interval, default 5s)")
+	cmd.PersistentFlags().Duration("for", 0, "with --watch, stop after this long (config: diagnostics.watch.duration)")
+	cmd.PersistentFlags().String("record", "", "with --watch, write the timeline as JSON Lines for 'srediag diagnose replay'")
+	cmd.PersistentFlags().StringSlice("checks", nil, "with --watch, only show these checks and measurements (globs or dotted prefixes)")
+	cmd.PersistentFlags().String("metrics-export", "", "export srediag_diag_* metrics on exit: otlp[s]://host:port, http(s)://url or a .prom textfile path")
 
-Available diagnostic types:
-  - system: Check system health, resource usage, and configuration.
-  - performance: Analyze system and application performance metrics.
-  - security: Check security configurations and potential vulnerabilities.`,
-		RunE: runDiagnose,
+	plugins := builtinDiagPlugins(ctx)
+	installed, errs := diagnose.DiscoverInstalledPlugins(diagPluginsDirFunc())
+	plugins = append(plugins, installed...)
+
+	// Plugins must not redefine the root or diagnose persistent flags.
+	reserved := pflag.NewFlagSet("global", pflag.ContinueOnError)
+	addGlobalFlags(reserved, new(bool))
+	reserved.AddFlagSet(cmd.PersistentFlags())
+	attached, attachErrs := diagnose.AttachPlugins(ctx, cmd, reserved, plugins)
+	for _, err := range append(errs, attachErrs...) {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 	}
 
-	// Add subcommands
-	cmd.AddCommand(
-		newSystemDiagCmd(ctx),
-		newPerformanceDiagCmd(ctx),
-		newSecurityDiagCmd(ctx),
-	)
+	cmd.Long = `The diagnose command runs diagnostic checks to identify potential issues.
+It provides insights about the system's health, performance, and security.
+
+Available diagnostic types:
+` + diagnose.PluginCommandsHelp(cmd, attached)
 
 	return cmd
 }
+
+// builtinDiagPlugins wraps the compiled-in diagnostic commands as diagnostic plugins.
+func builtinDiagPlugins(ctx *core.AppContext) []diagnose.DiagPlugin {
+	return []diagnose.DiagPlugin{
+		diagnose.NewBuiltinPlugin(diagnose.BuiltinSystemSnapshot, "System health, resource usage, and configuration",
+			[]string{diagnose.CapabilitySystem}, newSystemDiagCmd(ctx)),
+		diagnose.NewBuiltinPlugin(diagnose.BuiltinPerfProfiler, "System and application performance",
+			[]string{diagnose.CapabilityPerf}, newPerformanceDiagCmd(ctx)),
+		diagnose.NewBuiltinPlugin(diagnose.BuiltinCISBaseline, "Security configuration baseline",
+			[]string{diagnose.CapabilitySecurity}, newSecurityDiagCmd(ctx)),
+		diagnose.NewBuiltinPlugin(diagnose.BuiltinKubernetes, "Kubernetes cluster, node, and pod health",
+			[]string{diagnose.CapabilityKubernetes}, newKubernetesDiagCmd(ctx)),
+		diagnose.NewBuiltinPlugin(diagnose.BuiltinNetwork, "Connectivity, DNS, TLS and HTTP probes, and local network stack health",
+			[]string{diagnose.CapabilityNetwork}, newNetworkDiagCmd(ctx)),
+		diagnose.NewBuiltinPlugin(diagnose.BuiltinLogs, "Error signatures and known failure patterns in logs and journald exports",
+			[]string{diagnose.CapabilityLogs}, newLogsDiagCmd(ctx)),
+		diagnose.NewBuiltinPlugin(diagnose.BuiltinContainers, "Container restarts, OOM kills, exits, image ages, limits and cgroup usage",
+			[]string{diagnose.CapabilityContainers}, newContainersDiagCmd(ctx)),
+		diagnose.NewBuiltinPlugin(diagnose.BuiltinCloud, "Cloud instance metadata: maintenance, spot notices, volumes and IAM identity",
+			[]string{diagnose.CapabilityCloud}, newCloudDiagCmd(ctx)),
+		diagnose.NewBuiltinPlugin(diagnose.BuiltinIaC, "Terraform, Kubernetes manifest, and Helm analysis",
+			[]string{diagnose.CapabilityIaC}, newIaCDiagCmd(ctx)),
+		diagnose.NewBuiltinPlugin(diagnose.BuiltinSupportBundle, "Support bundle for vendor escalation",
+			[]string{diagnose.CapabilityBundle}, newBundleDiagCmd(ctx)),
+		diagnose.NewBuiltinPlugin(diagnose.BuiltinBaselineDiff, "Drift between a saved baseline and a later report",
+			[]string{diagnose.CapabilityBaseline}, newDiffDiagCmd(ctx)),
+		diagnose.NewBuiltinPlugin(diagnose.BuiltinWatchReplay, "Replay of timelines recorded in watch mode",
+			[]string{diagnose.CapabilityReplay}, newReplayDiagCmd(ctx)),
+	}
+}
+
 func runDiagnose(cmd *cobra.Command, args []string) error {
 	if len(args) == 0 {
-		fmt.Println("Please specify a diagnostic type (e.g., 'system', 'performance', 'security'). Use --help for more details.")
+		fmt.Println("Please specify a diagnostic type (use --help to list installed diagnostics).")
 		return cmd.Help()
 	}
 
-	validTypes := map[string]bool{
-		"system":      true,
-		"performance": true,
-		"security":    true,
+	validTypes := map[string]bool{}
+	for _, c := range cmd.Commands() {
+		if diagnose.IsPluginCommand(c) {
+			validTypes[c.Name()] = true
+		}
 	}
 
 	if !validTypes[args[0]] {
 		fmt.Printf("Error: Invalid diagnostic type '%s'. Please specify a valid type (use --help for available types).\n", args[0])
-		return cmd.Help()
+		if err := cmd.Help(); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %q", diagnose.ErrNotFound, args[0])
 	}
 
 	fmt.Printf("Running '%s' diagnostics...\n", args[0])
@@ -75,10 +143,20 @@ func newSystemDiagCmd(ctx *core.AppContext) *cobra.Command {
 
 // newPerformanceDiagCmd wires the 'performance' subcommand to diagnostic.CLI_PerformanceDiagnostics.
 func newPerformanceDiagCmd(ctx *core.AppContext) *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "performance",
 		Short: "Run performance diagnostics",
-		Long:  `Analyze system and application performance metrics.`,
+		Long: `Analyze system and application performance metrics.
+
+With --ebpf, kernel probes trace the host for --trace-duration and report
+off-CPU time per process, block IO latency histograms per device, TCP
+retransmits and connect latency per destination, and syscall counts per
+process. The probes are built into the binary and need root (or CAP_BPF and
+CAP_PERFMON) and a kernel with BTF; where BPF is not permitted the report
+carries a perf.ebpf.unsupported finding instead of failing.`,
+		Example: `  sudo srediag diagnose performance --ebpf --trace-duration 5s
+  sudo srediag diagnose performance --ebpf --probes biolatency,tcpconnect --top 5 --output json`,
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := diagnose.CLI_PerformanceDiagnostics(ctx, cmd, args)
 			if err != nil {
@@ -87,6 +165,11 @@ func newPerformanceDiagCmd(ctx *core.AppContext) *cobra.Command {
 			return err
 		},
 	}
+	cmd.Flags().Bool("ebpf", false, "run the eBPF tracing probes (config: diagnostics.performance.ebpf)")
+	cmd.Flags().Duration("trace-duration", diagnose.DefaultTraceDuration, "eBPF trace window (config: diagnostics.performance.duration)")
+	cmd.Flags().StringSlice("probes", nil, "eBPF probes: "+strings.Join(diagnose.EBPFProbes, ", ")+"; default all (config: diagnostics.performance.probes)")
+	cmd.Flags().Int("top", diagnose.DefaultTraceTop, "entries reported per probe (config: diagnostics.performance.top)")
+	return cmd
 }
 
 // newSecurityDiagCmd wires the 'security' subcommand to diagnostic.CLI_SecurityDiagnostics.
@@ -104,3 +187,271 @@ func newSecurityDiagCmd(ctx *core.AppContext) *cobra.Command {
 		},
 	}
 }
+
+// newKubernetesDiagCmd wires the 'kubernetes' subcommand to diagnostic.CLI_KubernetesDiagnostics.
+func newKubernetesDiagCmd(ctx *core.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "kubernetes",
+		Aliases: []string{"k8s"},
+		Short:   "Run Kubernetes cluster diagnostics",
+		Long: `Check node conditions and pressure, pods stuck in CrashLoopBackOff or Pending,
+OOMKilled containers, failing probes, resource requests vs limits vs usage,
+unbound PVCs, and recent warning events.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := diagnose.CLI_KubernetesDiagnostics(ctx, cmd, args)
+			if err != nil {
+				fmt.Printf("Error running kubernetes diagnostics: %v\n", err)
+			}
+			return err
+		},
+	}
+	cmd.Flags().String("kubeconfig", "", "path to kubeconfig (default: $KUBECONFIG, ~/.kube/config, or in-cluster)")
+	cmd.Flags().String("context", "", "kubeconfig context to use (default: current context)")
+	cmd.Flags().StringP("namespace", "n", "", "namespace to inspect (default: all namespaces)")
+	cmd.Flags().Duration("event-window", time.Hour, "how far back to report warning events")
+	return cmd
+}
+
+// newNetworkDiagCmd wires the 'network' subcommand to diagnostic.CLI_NetworkDiagnostics.
+func newNetworkDiagCmd(ctx *core.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "network",
+		Aliases: []string{"net"},
+		Short:   "Run network connectivity, DNS, TLS and HTTP diagnostics",
+		Long: `Probe the targets in diagnostics.network (or the flags below) concurrently:
+TCP connect, DNS resolution through the system resolver and specific servers,
+TLS handshake inspection (chain, expiry, SANs, protocol, cipher) and HTTP
+requests with a DNS/connect/TLS/first-byte timing breakdown.
+
+Also reports local socket states, TCP retransmits and interface errors from
+/proc/net unless --skip-local is set.`,
+		Example: `  srediag diagnose network --tcp db:5432 --dns api.example.com --dns-server 1.1.1.1
+  srediag diagnose network --tls example.com --http https://example.com/healthz --output json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := diagnose.CLI_NetworkDiagnostics(ctx, cmd, args)
+			if err != nil {
+				fmt.Printf("Error running network diagnostics: %v\n", err)
+			}
+			return err
+		},
+	}
+	cmd.Flags().StringSlice("tcp", nil, "host:port targets for TCP connect probes (config: diagnostics.network.tcp)")
+	cmd.Flags().StringSlice("dns", nil, "names to resolve (config: diagnostics.network.dns.names)")
+	cmd.Flags().StringSlice("dns-server", nil, "DNS servers queried besides the system resolver (config: diagnostics.network.dns.servers)")
+	cmd.Flags().StringSlice("tls", nil, "host[:port] targets for TLS inspection (config: diagnostics.network.tls)")
+	cmd.Flags().StringSlice("http", nil, "URLs for HTTP probes (config: diagnostics.network.http)")
+	cmd.Flags().Duration("probe-timeout", diagnose.DefaultProbeTimeout, "timeout per probe (config: diagnostics.network.timeout)")
+	cmd.Flags().Bool("skip-local", false, "skip /proc/net socket, retransmit and interface checks")
+	return cmd
+}
+
+// newLogsDiagCmd wires the 'logs' subcommand to diagnostic.CLI_LogsDiagnostics.
+func newLogsDiagCmd(ctx *core.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Cluster log errors into signatures and detect known failure patterns",
+		Long: `Scan plain log files and journald export streams, group error messages into
+signatures (numbers, addresses and IDs normalised, similar messages merged) and
+detect known failure patterns: OOM killer, full disks, segfaults, TLS handshake
+errors and connection refused storms. Each signature and pattern is reported
+with its count, first and last occurrence and per-window frequencies.
+
+Without --file or --journal (and diagnostics.logs), /var/log/syslog,
+/var/log/messages and /var/log/kern.log are scanned when present. Extra
+patterns are configured in diagnostics.logs.patterns.`,
+		Example: `  srediag diagnose logs --file '/var/log/app/*.log' --since 24h
+  journalctl -o export --since -6h | srediag diagnose logs --journal - --window 15m`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := diagnose.CLI_LogsDiagnostics(ctx, cmd, args)
+			if err != nil {
+				fmt.Printf("Error running log analysis: %v\n", err)
+			}
+			return err
+		},
+	}
+	cmd.Flags().StringSlice("file", nil, "log files or glob patterns, '-' for stdin (config: diagnostics.logs.files)")
+	cmd.Flags().StringSlice("journal", nil, "journald export files, '-' for stdin (config: diagnostics.logs.journal)")
+	cmd.Flags().Duration("since", 0, "only analyse entries newer than this (config: diagnostics.logs.since)")
+	cmd.Flags().Duration("window", diagnose.DefaultLogWindow, "width of the frequency windows (config: diagnostics.logs.window)")
+	cmd.Flags().Int("top", diagnose.DefaultLogTopSignatures, "number of error signatures reported (config: diagnostics.logs.top)")
+	return cmd
+}
+
+// newContainersDiagCmd wires the 'containers' subcommand to diagnostic.CLI_ContainerDiagnostics.
+func newContainersDiagCmd(ctx *core.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "containers",
+		Aliases: []string{"ctr"},
+		Short:   "Run container runtime diagnostics via the Docker or containerd socket",
+		Long: `List containers through the Docker Engine API or the containerd API over their
+local sockets, map them to their cgroups for CPU, memory and IO usage, and
+report restarts, OOM kills, non-zero exit codes, old images and containers
+running without memory or CPU limits.
+
+With --runtime auto (default) the Docker socket is used when present, then the
+containerd socket.`,
+		Example: `  srediag diagnose containers
+  srediag diagnose containers --runtime containerd --namespace k8s.io --output json
+  srediag diagnose containers --watch 10s --checks container.oom`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := diagnose.CLI_ContainerDiagnostics(ctx, cmd, args)
+			if err != nil {
+				fmt.Printf("Error running container diagnostics: %v\n", err)
+			}
+			return err
+		},
+	}
+	cmd.Flags().String("runtime", diagnose.RuntimeAuto, "container runtime: docker, containerd or auto (config: diagnostics.containers.runtime)")
+	cmd.Flags().String("socket", "", "runtime socket path (config: diagnostics.containers.socket)")
+	cmd.Flags().StringSlice("namespace", nil, "containerd namespaces; all when empty (config: diagnostics.containers.namespaces)")
+	cmd.Flags().Duration("image-max-age", diagnose.DefaultImageMaxAge, "image age that is flagged (config: diagnostics.containers.image_max_age)")
+	return cmd
+}
+
+// newCloudDiagCmd wires the 'cloud' subcommand to diagnostic.CLI_CloudDiagnostics.
+func newCloudDiagCmd(ctx *core.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cloud",
+		Short: "Run cloud instance diagnostics via the instance metadata service",
+		Long: `Query the instance metadata service of AWS, Azure, GCP or OCI and report the
+instance type and zone, scheduled maintenance events, spot/preemption notices,
+attached volumes and the IAM role or service identity in use.
+
+Risky settings are flagged: pending interruptions, maintenance within
+--maintenance-warning, IMDSv1 left enabled on AWS, and the GCP default service
+account with the cloud-platform scope.
+
+With --provider auto (default) each provider's metadata service is tried in turn.`,
+		Example: `  srediag diagnose cloud
+  srediag diagnose cloud --provider gcp --output json
+  srediag diagnose cloud --watch 30s --checks cloud.interruption`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := diagnose.CLI_CloudDiagnostics(ctx, cmd, args)
+			if err != nil {
+				fmt.Printf("Error running cloud diagnostics: %v\n", err)
+			}
+			return err
+		},
+	}
+	cmd.Flags().String("provider", diagnose.CloudAuto, "cloud provider: aws, azure, gcp, oci or auto (config: diagnostics.cloud.provider)")
+	cmd.Flags().String("endpoint", diagnose.DefaultCloudEndpoint, "instance metadata base URL (config: diagnostics.cloud.endpoint)")
+	cmd.Flags().Duration("metadata-timeout", diagnose.DefaultCloudTimeout, "timeout per metadata request (config: diagnostics.cloud.timeout)")
+	cmd.Flags().Duration("maintenance-warning", diagnose.DefaultMaintenanceWarning,
+		"maintenance starting within this window is critical (config: diagnostics.cloud.maintenance_warning)")
+	return cmd
+}
+
+// newIaCDiagCmd wires the 'iac' subcommand to diagnostic.CLI_IaCDiagnostics.
+func newIaCDiagCmd(ctx *core.AppContext) *cobra.Command {
+	return &cobra.Command{
+		Use:   "iac <path>",
+		Short: "Analyze Infrastructure-as-Code for reliability and security anti-patterns",
+		Long: `Statically analyze Terraform HCL, Kubernetes YAML, and rendered Helm charts.
+
+Flags missing resource limits and probes, floating image tags, privileged
+containers, single-replica workloads without a PodDisruptionBudget, public S3
+buckets, and world-open ingress. Use --output sarif for code review tooling.`,
+		Example: `  srediag diagnose iac ./deploy
+  helm template ./chart > rendered.yaml && srediag diagnose iac rendered.yaml --output sarif`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := diagnose.CLI_IaCDiagnostics(ctx, cmd, args)
+			if err != nil {
+				fmt.Printf("Error running iac diagnostics: %v\n", err)
+			}
+			return err
+		},
+	}
+}
+
+// newBundleDiagCmd wires the 'bundle' subcommand to diagnostic.CLI_Bundle.
+func newBundleDiagCmd(ctx *core.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Collect a support bundle (.tar.gz) for vendor escalation",
+		Long: `Run the configured diagnostics and collect the effective config (redacted),
+recent logs, plugin states, versions, and goroutine and heap profiles of the
+running agent (fetched over service.socket) into one timestamped .tar.gz with a
+SHA-256 manifest (manifest.json). Without a running agent the profiles are left
+out and the manifest says so.
+
+Secrets are redacted with built-in patterns plus diagnostics.bundle.redact_patterns
+and --redact. Size limits and include/exclude filters are recorded in the manifest.`,
+		Example: `  srediag diagnose bundle --output-dir /tmp
+  srediag diagnose bundle --exclude runtime/heap.pb.gz --max-size 20MiB
+  srediag diagnose bundle --include config --include logs --redact 'customer_id=\d+'`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := diagnose.CLI_Bundle(ctx, cmd, args)
+			if err != nil {
+				fmt.Printf("Error creating support bundle: %v\n", err)
+			}
+			return err
+		},
+	}
+	cmd.Flags().String("output-dir", "", "directory for the bundle (config: diagnostics.bundle.output_dir, default: .)")
+	cmd.Flags().StringSlice("diagnostics", nil, "diagnostics to run into the bundle (default: system,performance,security)")
+	cmd.Flags().StringSlice("log-file", nil, "log files or glob patterns to include (default: *.log next to service.log_file)")
+	cmd.Flags().StringSlice("include", nil, "only keep bundle paths matching these globs or directories")
+	cmd.Flags().StringSlice("exclude", nil, "drop bundle paths matching these globs or directories")
+	cmd.Flags().String("max-size", "", "total size limit before compression, 0 for none (default: 100MiB)")
+	cmd.Flags().String("max-file-size", "", "per-file size limit; logs keep their tail (default: 10MiB)")
+	cmd.Flags().StringArray("redact", nil, "additional secret pattern (regular expression; repeatable)")
+	return cmd
+}
+
+// newDiffDiagCmd wires the 'diff' subcommand to diagnostic.CLI_Diff.
+func newDiffDiagCmd(ctx *core.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <baseline> [<report>]",
+		Short: "Compare a report against a saved baseline",
+		Long: `Compare two diagnostic reports structurally: new and resolved findings,
+measurement changes past --threshold percent, and added, removed or changed
+config files, packages, listening ports and kernel parameters.
+
+Arguments are baseline names (saved with --save-baseline) or report files
+written with --output json. Without <report>, the baseline's diagnostic is
+run again. Use --output json or --fail-on-drift (exit code 7) to gate pipelines.`,
+		Example: `  srediag diagnose system --save-baseline healthy
+  srediag diagnose diff healthy
+  srediag diagnose diff healthy after-upgrade.json --output json --fail-on-drift`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := diagnose.CLI_Diff(ctx, cmd, args)
+			if err != nil {
+				fmt.Printf("Error comparing reports: %v\n", err)
+			}
+			return err
+		},
+	}
+	cmd.Flags().Float64("threshold", diagnose.DefaultBaselineThreshold, "minimum measurement change to report, in percent (config: diagnostics.baseline.threshold)")
+	cmd.Flags().Bool("fail-on-drift", false, "exit with code 7 when the reports differ")
+	return cmd
+}
+
+// newReplayDiagCmd wires the 'replay' subcommand to diagnostic.CLI_Replay.
+func newReplayDiagCmd(ctx *core.AppContext) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay <file.jsonl>",
+		Short: "Replay a timeline recorded with --watch --record",
+		Long: `Render the frames of a watch-mode timeline with the same highlighting as
+the live view: findings fired and measurements changed since the previous refresh.`,
+		Example: `  srediag diagnose system --watch 5s --for 10m --record incident.jsonl
+  srediag diagnose replay incident.jsonl --speed 10
+  srediag diagnose replay incident.jsonl --speed 0 --no-color | less`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := diagnose.CLI_Replay(ctx, cmd, args)
+			if err != nil {
+				fmt.Printf("Error replaying timeline: %v\n", err)
+			}
+			return err
+		},
+	}
+	cmd.Flags().Float64("speed", 1, "playback speed relative to the recording (0: no waiting)")
+	return cmd
+}
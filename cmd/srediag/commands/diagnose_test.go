@@ -3,6 +3,8 @@ package commands
 import (
 	"bytes"
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/spf13/cobra"
@@ -94,6 +96,45 @@ func TestNewDiagnoseCmd_HasSubcommands(t *testing.T) {
 	assert.Contains(t, names, "system")
 	assert.Contains(t, names, "performance")
 	assert.Contains(t, names, "security")
+	assert.Contains(t, names, "kubernetes")
+	assert.Contains(t, names, "iac")
+	assert.Contains(t, names, "bundle")
+	assert.Contains(t, names, "diff")
+	assert.Contains(t, names, "replay")
+	assert.Contains(t, names, "network")
+	assert.Contains(t, names, "logs")
+	assert.Contains(t, names, "containers")
+	assert.Contains(t, names, "cloud")
+}
+
+func TestNewDiagnoseCmd_ListsInstalledPlugins(t *testing.T) {
+	pluginsDir := t.TempDir()
+	dir := filepath.Join(pluginsDir, "diagnostics", "fsmonitor")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(`name: fsmonitor
+type: diagnostic
+version: 0.2.0
+entrypoint: ./fsmonitor
+capabilities: [diag/fs]
+commands:
+  - name: inode-usage
+    short: Report inode usage
+`), 0o600))
+	orig := diagPluginsDirFunc
+	diagPluginsDirFunc = func() string { return pluginsDir }
+	defer func() { diagPluginsDirFunc = orig }()
+
+	ctx := &core.AppContext{}
+	cmd := newDiagnoseCmd(ctx)
+	b := &bytes.Buffer{}
+	cmd.SetOut(b)
+	cmd.SetArgs([]string{"--help"})
+	require.NoError(t, cmd.Execute())
+	out := b.String()
+	assert.Contains(t, out, "inode-usage: Report inode usage [fsmonitor 0.2.0, installed]")
+	assert.Contains(t, out, "system: Run system diagnostics")
+	assert.Contains(t, ctx.GetRBAC().Capabilities(), "diag/fs")
+	assert.Contains(t, ctx.GetRBAC().Capabilities(), "diag/system")
 }
 
 func TestRunDiagnose_NoArgs_ShowsHelp(t *testing.T) {
@@ -2,6 +2,8 @@
 package commands
 
 import (
+	"time"
+
 	"github.com/spf13/cobra"
 
 	"github.com/srediag/srediag/internal/core"
@@ -22,7 +24,7 @@ import (
 // - validate: Dry-run parse YAML + plugin refs
 // - install-unit: Create & enable systemd unit
 // - uninstall-unit: Remove systemd unit
-// - gc: Purge stale PID/socket/logs
+// - gc: Delete stale agent state
 // Only CLI wiring is present here; all business logic is delegated to internal/service CLI_* functions.
 func NewServiceCmd(ctx *core.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
@@ -54,10 +56,20 @@ func newServiceStartCmd(ctx *core.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "start",
 		Short: "Start the SREDIAG service",
+		Long: `Run the SREDIAG agent in the foreground: the embedded OpenTelemetry Collector (collector.enabled),
+the diag_exporter, remote diagnostics jobs and the local admin API on service.socket.
+
+The agent holds service.pid_file while it runs and stops on SIGINT/SIGTERM or 'srediag service stop'.
+On stop, receivers stop accepting first, then processors and exporters drain, the diag_exporter queue
+is synced, plugins are terminated and the log is flushed, all within --shutdown-timeout. A second
+signal abandons the drain. The agent exits non-zero if data had to be dropped.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return service.CLI_Start(ctx, cmd, args)
 		},
 	}
+	cmd.Flags().Bool("detach", false, "start in the background (same as 'srediag service detach')")
+	cmd.Flags().Duration("timeout", 30*time.Second, "with --detach, how long to wait for the agent to start")
+	cmd.Flags().Duration("shutdown-timeout", 0, "deadline for draining pipelines and buffers on stop (default: service.shutdown_timeout, 30s)")
 	return cmd
 }
 
@@ -66,10 +78,12 @@ func newServiceStopCmd(ctx *core.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "stop",
 		Short: "Stop the SREDIAG service",
+		Long:  "Ask the running agent to shut down over its admin socket and wait until it has exited.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return service.CLI_Stop(ctx, cmd, args)
 		},
 	}
+	cmd.Flags().Duration("timeout", 30*time.Second, "how long to wait for the agent to exit (raised to service.shutdown_timeout unless given)")
 	return cmd
 }
 
@@ -78,10 +92,12 @@ func newServiceRestartCmd(ctx *core.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "restart",
 		Short: "Restart the SREDIAG service",
+		Long:  "Stop the running agent (if any) and start it again in the background.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return service.CLI_Restart(ctx, cmd, args)
 		},
 	}
+	cmd.Flags().Duration("timeout", 30*time.Second, "how long to wait for the agent to stop and to start")
 	return cmd
 }
 
@@ -90,10 +106,17 @@ func newServiceReloadCmd(ctx *core.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "reload",
 		Short: "Hot-reload YAML configuration",
+		Long: `Ask the running agent to re-read its configuration, the collector config with its plugins.d fragments
+and the plugin binaries, as SIGHUP does.
+
+Only the pipelines whose config changed are restarted. An invalid configuration is rejected (exit code 2)
+and a configuration that fails to start is rolled back; in both cases the previous configuration keeps
+running. Changes of agent settings other than collector.config_path are reported as requiring a restart.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return service.CLI_Reload(ctx, cmd, args)
 		},
 	}
+	cmd.Flags().Duration("timeout", 30*time.Second, "how long to wait for the reload to finish")
 	return cmd
 }
 
@@ -102,10 +125,13 @@ func newServiceDetachCmd(ctx *core.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "detach",
 		Short: "Fork to background (daemonize)",
+		Long: `Start the agent in the background in a new session, with its output appended to service.log_file,
+and wait until it answers on the admin socket.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return service.CLI_Detach(ctx, cmd, args)
 		},
 	}
+	cmd.Flags().Duration("timeout", 30*time.Second, "how long to wait for the agent to start")
 	return cmd
 }
 
@@ -114,6 +140,9 @@ func newServiceStatusCmd(ctx *core.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "status",
 		Short: "Show service health and resource usage",
+		Long: `Show the running agent's state, PID, uptime, version, config digest, collector pipelines and plugins.
+
+Use the global --output flag for json or yaml.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return service.CLI_Status(ctx, cmd, args)
 		},
@@ -137,11 +166,23 @@ func newServiceHealthCmd(ctx *core.AppContext) *cobra.Command {
 func newServiceProfileCmd(ctx *core.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "profile",
-		Short: "Gather CPU+heap profile bundle",
+		Short: "Fetch a runtime profile of the running agent",
+		Long: `Capture a profile of the running agent over its admin socket and write it to a file.
+
+Types: cpu, heap, allocs, goroutine, mutex, block and trace. cpu, mutex, block and trace are captured for
+--duration; the others are snapshots. Open profiles with 'go tool pprof' and traces with 'go tool trace'.
+
+Continuous profiling (service.profiling.continuous) and the heap profile taken when the agent's RSS crosses
+security.runtime.mem_guard_mib are written to service.profiling.dir instead.`,
+		Example: `  srediag service profile --type cpu --duration 30s -o cpu.pb.gz
+  srediag service profile --type heap -o heap.pb.gz && go tool pprof -top heap.pb.gz`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return service.CLI_Profile(ctx, cmd, args)
 		},
 	}
+	cmd.Flags().String("type", "cpu", "profile type: cpu, heap, allocs, goroutine, mutex, block or trace")
+	cmd.Flags().Duration("duration", 30*time.Second, "capture time of cpu, mutex, block and trace profiles")
+	cmd.Flags().StringP("output-file", "o", "", "write the profile here ('-' for stdout; default: srediag-<type>-<time>.pb.gz)")
 	return cmd
 }
 
@@ -150,18 +191,40 @@ func newServiceTailLogsCmd(ctx *core.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "tail-logs",
 		Short: "Stream live service logs",
+		Long: `Print the recent log entries of the running agent, read from its in-memory buffer over the admin socket.
+
+The agent keeps its last 2000 entries at or above its log level (logging.level), including the embedded
+collector's. Filters are applied by the agent; --output json prints one JSON object per entry.`,
+		Example: `  srediag service tail-logs -f --level warn
+  srediag service tail-logs --since 10m --component receiver --field plugin=otlp
+  srediag service tail-logs --field 'msg=*timeout*' --output json`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return service.CLI_TailLogs(ctx, cmd, args)
 		},
 	}
+	cmd.Flags().BoolP("follow", "f", false, "keep streaming new entries until Ctrl-C")
+	cmd.Flags().String("since", "", "only entries newer than a duration (10m) or an RFC 3339 time")
+	cmd.Flags().String("level", "", "minimum level: debug, info, warn, error")
+	cmd.Flags().StringSlice("component", nil, "only these components: a name, a kind (receiver) or a glob")
+	cmd.Flags().StringArray("field", nil, "only entries whose field matches: key=value (value may be a glob; msg matches the message)")
+	cmd.Flags().IntP("lines", "n", 100, "print the last N matching buffered entries (0 for all)")
 	return cmd
 }
 
 // newServiceValidateCmd wires the 'validate' subcommand to service.CLI_Validate.
 func newServiceValidateCmd(ctx *core.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "validate",
+		Use:   "validate [file]",
 		Short: "Dry-run parse YAML + plugin refs",
+		Long: `Validate a collector config (the file argument, or collector.config_path) without starting anything.
+
+The config and its includes are strictly decoded, every component is resolved against the built-in
+components and the plugins in plugins.exec_dir, and every component config is decoded and validated
+by its factory. Pipelines must reference components that support their signal, connectors must join
+pipelines they can convert between, and every defined component must be used.
+
+All problems are reported at once with file, line and field path; the exit code is 2 if there are any.`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return service.CLI_Validate(ctx, cmd, args)
 		},
@@ -174,10 +237,21 @@ func newServiceInstallUnitCmd(ctx *core.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "install-unit",
 		Short: "Create & enable systemd unit",
+		Long: `Write srediag.service for this binary and --config, run 'systemctl daemon-reload' and enable it.
+
+The unit is hardened: ProtectSystem=strict with ReadWritePaths limited to the runtime and state directories,
+NoNewPrivileges, MemoryMax from collector.memory_limit_mib, CPUQuota from security.runtime.cpu_guard_pct and, for
+system units, a capability bounding set limited to what the diagnostics and eBPF probes need.
+
+System units go to /etc/systemd/system (run as root); --user installs to ~/.config/systemd/user instead.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return service.CLI_InstallUnit(ctx, cmd, args)
 		},
 	}
+	cmd.Flags().Bool("user", false, "install a user unit (systemctl --user)")
+	cmd.Flags().Bool("dry-run", false, "print the unit instead of installing it")
+	cmd.Flags().Bool("now", false, "also start the agent (systemctl enable --now)")
+	cmd.Flags().String("run-as", "", "system units: run the agent as this user with ambient capabilities (default: root)")
 	return cmd
 }
 
@@ -186,10 +260,15 @@ func newServiceUninstallUnitCmd(ctx *core.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "uninstall-unit",
 		Short: "Remove systemd unit",
+		Long: `Stop and disable srediag.service, remove it and run 'systemctl daemon-reload'.
+
+The runtime and state directories of the agent are left in place.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return service.CLI_UninstallUnit(ctx, cmd, args)
 		},
 	}
+	cmd.Flags().Bool("user", false, "remove the user unit (systemctl --user)")
+	cmd.Flags().Bool("dry-run", false, "print what would be done")
 	return cmd
 }
 
@@ -197,10 +276,22 @@ func newServiceUninstallUnitCmd(ctx *core.AppContext) *cobra.Command {
 func newServiceGcCmd(ctx *core.AppContext) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "gc",
-		Short: "Purge stale PID/socket/logs",
+		Short: "Delete stale agent state (sockets, IPC files, logs, builds, bundles, spill dirs)",
+		Long: `Lists every kind of state srediag leaves on disk, skips items still used by a running process and
+deletes the rest according to the retention policy of its kind (service.gc.retention).
+
+Kinds: runtime (stale PID file and socket), ipc (shmipc files), logs (rotated logs), build (srediag build artefacts),
+plugins (backup and orphaned plugin binaries), bundles (support bundles), jobs (remote job spill directories)
+and profiles.`,
+		Example: `  srediag service gc --dry-run
+  sudo srediag service gc --retention 7d
+  srediag service gc --kind ipc,jobs --output json`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return service.CLI_Gc(ctx, cmd, args)
 		},
 	}
+	cmd.Flags().Bool("dry-run", false, "only report what would be deleted")
+	cmd.Flags().StringSlice("kind", nil, "kinds to collect (default: all)")
+	cmd.Flags().String("retention", "", "delete unused items older than this (e.g. 7d or 72h), overriding every kind's max_age")
 	return cmd
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/srediag/srediag/cmd/srediag/commands"
 	"github.com/srediag/srediag/internal/core"
+	"github.com/srediag/srediag/internal/diagnose"
 )
 
 // Allow injection of Execute and os.Exit for testing
@@ -17,6 +18,6 @@ func main() {
 	// Here we only create the AppContext and execute the root command.
 	ctx := &core.AppContext{}
 	if err := executeFunc(ctx); err != nil {
-		osExit(1)
+		osExit(diagnose.ExitCode(err))
 	}
 }
@@ -2,9 +2,11 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/srediag/srediag/internal/core"
+	"github.com/srediag/srediag/internal/diagnose"
 )
 
 // mockExit is used to capture os.Exit calls.
@@ -61,3 +63,30 @@ func TestMain_ExecuteSuccess(t *testing.T) {
 func init() {
 	// No redeclaration; osExit is now declared in main.go
 }
+
+// TestMain_ExitCodeMapping tests that typed errors map to the documented exit codes.
+func TestMain_ExitCodeMapping(t *testing.T) {
+	origExit := osExit
+	defer func() { osExit = origExit }()
+	origExecute := executeFunc
+	defer func() { executeFunc = origExecute }()
+	osExit = mockExit
+
+	executeFunc = func(ctx *core.AppContext) error {
+		return fmt.Errorf("system diagnostics failed: %w", diagnose.ErrTimeout)
+	}
+	exitCode = 0
+	main()
+	if exitCode != diagnose.ExitTimeout {
+		t.Errorf("expected exit code %d, got %d", diagnose.ExitTimeout, exitCode)
+	}
+
+	executeFunc = func(ctx *core.AppContext) error {
+		return &diagnose.FindingsError{Threshold: diagnose.SeverityWarning, Count: 2}
+	}
+	exitCode = 0
+	main()
+	if exitCode != diagnose.ExitFindings {
+		t.Errorf("expected exit code %d, got %d", diagnose.ExitFindings, exitCode)
+	}
+}
@@ -0,0 +1,125 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Package admin provides the client side of the local admin API of the SREDIAG agent.
+//
+// This file defines Client, the HTTP-over-Unix-socket transport shared by every caller of the admin API: the
+// 'srediag service' subcommands (through service.AdminClient, which adds the typed endpoints) and 'srediag diagnose
+// bundle', which cannot import the service package. The endpoints themselves are served by service.AdminServer.
+//
+// Usage:
+//   - NewClient(socket), then Do for JSON endpoints or Send for streamed responses; ErrNotRunning means no agent
+//     listens on the socket.
+//
+// Best Practices:
+//   - Resolve the socket from service.socket, falling back to core.DefaultAdminSocket.
+//   - Bound requests with RequestTimeout unless the endpoint streams or waits on purpose.
+
+// RequestTimeout bounds one admin API request, so a hung agent cannot block its clients.
+const RequestTimeout = 10 * time.Second
+
+// ErrNotRunning is returned by Client when no agent listens on the admin socket.
+var ErrNotRunning = errors.New("service is not running")
+
+// Client sends requests to the admin API of a running agent.
+type Client struct {
+	client *http.Client
+}
+
+// NewClient creates an admin API client for a Unix socket.
+//
+// Parameters:
+//   - socket: Path of the agent's admin socket (service.socket).
+//
+// Returns:
+//   - *Client: The client; the socket is dialled per request.
+func NewClient(socket string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}
+	return &Client{client: &http.Client{Transport: transport}}
+}
+
+// Send sends an admin API request; the caller closes the response body.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts.
+//   - method: HTTP method.
+//   - path: Request path and query, e.g. "/v1/status".
+//
+// Returns:
+//   - *http.Response: The response, whatever its status.
+//   - error: ErrNotRunning if no agent listens, or a detailed error.
+func (c *Client) Send(ctx context.Context, method, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, "http://srediag"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.ECONNREFUSED) {
+			return nil, ErrNotRunning
+		}
+		return nil, fmt.Errorf("admin API %s %s: %w", method, path, err)
+	}
+	return resp, nil
+}
+
+// Do sends an admin API request, bounded by RequestTimeout, and decodes the JSON response into out (when non-nil).
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts.
+//   - method: HTTP method.
+//   - path: Request path and query.
+//   - out: Destination of the JSON response; nil discards it.
+//
+// Returns:
+//   - error: ErrNotRunning if no agent listens, a StatusError for a non-2xx response, or a detailed error.
+func (c *Client) Do(ctx context.Context, method, path string, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, RequestTimeout)
+	defer cancel()
+	resp, err := c.Send(ctx, method, path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		return StatusError(method, path, resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode admin API response: %w", err)
+	}
+	return nil
+}
+
+// StatusError describes an unexpected admin API response with the start of its body.
+//
+// Parameters:
+//   - method: HTTP method of the request.
+//   - path: Request path.
+//   - resp: The response; its body is read but not closed.
+//
+// Returns:
+//   - error: "admin API <method> <path>: <status>: <body>".
+func StatusError(method, path string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("admin API %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,52 @@
+package admin
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serve serves handler on a Unix socket in a temp dir and returns the socket.
+func serve(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "srediag.sock")
+	ln, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	srv := &http.Server{Handler: handler}
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return socket
+}
+
+func TestClient(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, `{"state":"running"}`)
+	})
+	mux.HandleFunc("POST /v1/stop", func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "busy", http.StatusConflict)
+	})
+	client := NewClient(serve(t, mux))
+
+	var status struct{ State string }
+	require.NoError(t, client.Do(context.Background(), http.MethodGet, "/v1/status", &status))
+	assert.Equal(t, "running", status.State)
+	assert.EqualError(t, client.Do(context.Background(), http.MethodPost, "/v1/stop", nil),
+		"admin API POST /v1/stop: 409 Conflict: busy")
+
+	resp, err := client.Send(context.Background(), http.MethodGet, "/v1/status")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestClient_NotRunning(t *testing.T) {
+	client := NewClient(filepath.Join(t.TempDir(), "missing.sock"))
+	assert.ErrorIs(t, client.Do(context.Background(), http.MethodGet, "/v1/status", nil), ErrNotRunning)
+}
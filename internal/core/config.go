@@ -33,6 +33,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/spf13/viper"
 	yaml "gopkg.in/yaml.v3"
 )
@@ -74,16 +75,46 @@ type Config struct {
 
 // ServiceConfig maps to the 'service:' section in YAML (docs: service.md)
 //
-// Usage: Used for service-level settings (name, port, environment).
+// Usage: Used for service-level settings (name, port, environment, daemon files).
 //
 // Fields:
 //   - Name: Service name. Used for identification and logging.
 //   - Port: Service port. Used for network binding and health checks.
 //   - Environment: Deployment environment (e.g., dev, staging, prod). Used for environment-specific logic.
+//   - PIDFile: PID/lock file of the running agent. Held locked while the agent runs.
+//   - Socket: Unix socket of the local admin API used by the 'srediag service' commands.
+//   - LogFile: Log file of a detached agent (stdout and stderr are redirected there).
+//   - Profiling: Continuous profiling ring buffer and the heap profile taken when RSS crosses the memory guard.
+//   - GC: Interval of the agent's periodic 'service gc' and per-kind retention overrides.
 type ServiceConfig struct {
-	Name        string `yaml:"name"`        // Service name
-	Port        int    `yaml:"port"`        // Service port
-	Environment string `yaml:"environment"` // Deployment environment
+	Name            string `yaml:"name"`             // Service name
+	Port            int    `yaml:"port"`             // Service port
+	Environment     string `yaml:"environment"`      // Deployment environment
+	PIDFile         string `yaml:"pid_file"`         // PID/lock file
+	Socket          string `yaml:"socket"`           // Admin API socket
+	LogFile         string `yaml:"log_file"`         // Detached agent log file
+	ShutdownTimeout string `yaml:"shutdown_timeout"` // Deadline for draining pipelines and buffers on stop (default 30s)
+	Profiling       struct {
+		Continuous  bool   `yaml:"continuous"`   // Capture CPU, heap and goroutine profiles periodically
+		Dir         string `yaml:"dir"`          // Ring buffer directory (default: <state dir>/profiles)
+		Interval    string `yaml:"interval"`     // Time between continuous captures (default 5m)
+		CPUDuration string `yaml:"cpu_duration"` // Length of each continuous CPU profile (default 10s)
+		Keep        int    `yaml:"keep"`         // Profiles kept per type (default 12)
+	} `yaml:"profiling"`
+	GC struct {
+		Interval  string                       `yaml:"interval"`  // Run gc in the agent this often (default: off)
+		Retention map[string]GCRetentionConfig `yaml:"retention"` // Per-kind overrides (runtime, ipc, logs, build, ...)
+	} `yaml:"gc"`
+}
+
+// GCRetentionConfig is the retention policy of one kind of state (service.gc.retention.<kind>).
+//
+// Fields:
+//   - MaxAge: Unused items older than this are deleted (Go duration or days, e.g., 14d).
+//   - Keep: The newest Keep items are kept regardless of age.
+type GCRetentionConfig struct {
+	MaxAge string `yaml:"max_age"`
+	Keep   *int   `yaml:"keep"`
 }
 
 // LoggingConfig maps to the 'logging:' section in YAML (docs: README.md)
@@ -162,10 +193,12 @@ type SecurityConfig struct {
 //   - Enabled: Whether the collector is enabled.
 //   - ConfigPath: Path to the collector config file.
 //   - MemoryLimitMiB: Memory limit for the collector in MiB.
+//   - Watch: Whether 'srediag service start' reloads when the config files or plugin binaries change.
 type CollectorConfig struct {
 	Enabled        bool   `yaml:"enabled"`          // Enable collector
 	ConfigPath     string `yaml:"config_path"`      // Path to collector config
 	MemoryLimitMiB int    `yaml:"memory_limit_mib"` // Memory limit (MiB)
+	Watch          bool   `yaml:"watch"`            // Reload on config file changes
 }
 
 // PluginsConfig maps to the 'plugins:' section in YAML (docs: plugin.md)
@@ -182,18 +215,129 @@ type PluginsConfig struct {
 	Enabled []string `yaml:"enabled"`  // List of enabled plugins
 }
 
+// LogPatternConfig is a failure pattern of 'diagnose logs' (diagnostics.logs.patterns).
+//
+// Fields:
+//   - Name: Pattern identifier (finding check "logs.pattern.<name>").
+//   - Match: Regular expression matched against log messages.
+//   - Severity: info, warning or critical (default warning).
+//   - Threshold: Minimum occurrences within one window before the pattern is reported.
+//   - Description: What a match means.
+type LogPatternConfig struct {
+	Name        string `yaml:"name"`
+	Match       string `yaml:"match"`
+	Severity    string `yaml:"severity"`
+	Threshold   int    `yaml:"threshold"`
+	Description string `yaml:"description"`
+}
+
 // DiagnosticsConfig maps to the 'diagnostics:' section in YAML (docs: diagnose.md)
 //
 // Usage: Used for diagnostics defaults and plugin configs.
 //
 // Fields:
-//   - Defaults: Default output format and timeout for diagnostics.
+//   - Defaults: Default output format, timeout and retries for diagnostics.
+//   - Metrics: Export target for diagnostics run metrics in CLI mode.
+//   - Export: OTLP endpoint for diagnostic results in CLI mode.
+//   - Bundle: Support bundle contents, filters, size limits and redaction patterns.
+//   - Baseline: Baseline store location, diff threshold and inventory sources.
+//   - Watch: Default interval, duration, timeline file and check filters of --watch mode.
+//   - Performance: eBPF tracing switch, window, probes and report size of 'diagnose performance'.
+//   - Network: Probe targets, probe timeout and certificate expiry thresholds of 'diagnose network'.
+//   - Logs: Log sources, time range and failure patterns of 'diagnose logs'.
+//   - Containers: Runtime, socket, namespaces and image age threshold of 'diagnose containers'.
+//   - Cloud: Provider, metadata endpoint, timeout and maintenance threshold of 'diagnose cloud'.
+//   - Remote: Command source, signing keys, limits and artifact sink of the service's remote job runner.
 //   - Plugins: Map of plugin-specific diagnostic configs.
 type DiagnosticsConfig struct {
 	Defaults struct {
 		OutputFormat string `yaml:"output_format"` // Default output format
 		Timeout      string `yaml:"timeout"`       // Default timeout
+		MaxRetries   int    `yaml:"max_retries"`   // Retries after a failed diagnostic run
 	} `yaml:"defaults"`
+	Metrics struct {
+		Export string `yaml:"export"` // CLI-mode srediag_diag_* export target (OTLP endpoint or Prometheus textfile)
+	} `yaml:"metrics"`
+	Export struct {
+		Endpoint string `yaml:"endpoint"` // CLI-mode OTLP endpoint for findings (logs) and measurements (metrics)
+	} `yaml:"export"`
+	Bundle struct {
+		OutputDir      string   `yaml:"output_dir"`      // Directory for support bundles
+		Diagnostics    []string `yaml:"diagnostics"`     // Diagnostics run into the bundle
+		LogFiles       []string `yaml:"log_files"`       // Log files or glob patterns to include
+		Include        []string `yaml:"include"`         // Bundle paths to keep (globs or directories)
+		Exclude        []string `yaml:"exclude"`         // Bundle paths to drop (globs or directories)
+		MaxSize        string   `yaml:"max_size"`        // Total size limit (e.g., 100MiB)
+		MaxFileSize    string   `yaml:"max_file_size"`   // Per-file size limit (e.g., 10MiB)
+		RedactPatterns []string `yaml:"redact_patterns"` // Extra secret patterns (regular expressions)
+	} `yaml:"bundle"`
+	Baseline struct {
+		Dir          string   `yaml:"dir"`           // Baseline store (default: <state dir>/baselines)
+		Threshold    float64  `yaml:"threshold"`     // Minimum measurement change reported by diff (%)
+		ConfigFiles  []string `yaml:"config_files"`  // Config files or glob patterns hashed into the inventory
+		KernelParams []string `yaml:"kernel_params"` // sysctl key prefixes collected into the inventory
+	} `yaml:"baseline"`
+	Watch struct {
+		Interval string   `yaml:"interval"` // Refresh interval used by --watch 0 (default 5s)
+		Duration string   `yaml:"duration"` // Stop after this long (default: until Ctrl-C)
+		Record   string   `yaml:"record"`   // JSON Lines timeline file
+		Checks   []string `yaml:"checks"`   // Check and measurement filters (globs or dotted prefixes)
+	} `yaml:"watch"`
+	Performance struct {
+		EBPF     bool     `yaml:"ebpf"`     // Run the eBPF tracing probes (needs root or CAP_BPF and CAP_PERFMON)
+		Duration string   `yaml:"duration"` // Trace window (default 10s)
+		Probes   []string `yaml:"probes"`   // offcpu, biolatency, tcpretrans, tcpconnect, syscalls (default: all)
+		Top      int      `yaml:"top"`      // Processes, devices or destinations reported per probe (default 10)
+	} `yaml:"performance"`
+	Network struct {
+		Timeout string   `yaml:"timeout"` // Timeout per probe (default 5s)
+		TCP     []string `yaml:"tcp"`     // host:port targets for TCP connect probes
+		DNS     struct {
+			Names   []string `yaml:"names"`   // Names to resolve
+			Servers []string `yaml:"servers"` // DNS servers queried besides the system resolver
+		} `yaml:"dns"`
+		TLS                []string `yaml:"tls"`                  // host[:port] targets for TLS inspection
+		HTTP               []string `yaml:"http"`                 // URLs for HTTP probes
+		CertExpiryWarning  string   `yaml:"cert_expiry_warning"`  // Remaining validity that warns (default 720h)
+		CertExpiryCritical string   `yaml:"cert_expiry_critical"` // Remaining validity that is critical (default 168h)
+		SkipLocal          bool     `yaml:"skip_local"`           // Skip /proc/net socket and interface checks
+	} `yaml:"network"`
+	Logs struct {
+		Files    []string           `yaml:"files"`    // Plain log files or glob patterns (.gz supported)
+		Journal  []string           `yaml:"journal"`  // journald export files ('journalctl -o export')
+		Since    string             `yaml:"since"`    // Only analyse entries newer than this (e.g., 24h)
+		Window   string             `yaml:"window"`   // Width of the frequency windows (default 1h)
+		Top      int                `yaml:"top"`      // Number of error signatures reported (default 20)
+		Patterns []LogPatternConfig `yaml:"patterns"` // Extra failure patterns; a built-in name replaces the built-in
+	} `yaml:"logs"`
+	Containers struct {
+		Runtime     string   `yaml:"runtime"`       // docker, containerd or auto (default)
+		Socket      string   `yaml:"socket"`        // Runtime socket (default: the runtime's standard socket)
+		Namespaces  []string `yaml:"namespaces"`    // containerd namespaces (default: all)
+		ImageMaxAge string   `yaml:"image_max_age"` // Image age that is flagged (default 2160h)
+	} `yaml:"containers"`
+	Cloud struct {
+		Provider           string `yaml:"provider"`            // aws, azure, gcp, oci or auto (default)
+		Endpoint           string `yaml:"endpoint"`            // Metadata base URL (default http://169.254.169.254)
+		Timeout            string `yaml:"timeout"`             // Per-request timeout (default 2s)
+		MaintenanceWarning string `yaml:"maintenance_warning"` // Events closer than this are critical (default 24h)
+	} `yaml:"cloud"`
+	Remote struct {
+		Enabled        bool     `yaml:"enabled"`           // Run remote jobs in 'srediag service'
+		Source         string   `yaml:"source"`            // http(s):// command endpoint or watched file
+		PollInterval   string   `yaml:"poll_interval"`     // Interval between polls (default 30s)
+		PublicKeys     []string `yaml:"public_keys"`       // PEM ed25519 public keys trusted to sign jobs
+		MaxAge         string   `yaml:"max_age"`           // Jobs older than this are rejected (default 15m)
+		MaxJobsPerHour int      `yaml:"max_jobs_per_hour"` // Rate limit of started jobs (default 12)
+		Burst          int      `yaml:"burst"`             // Jobs that may start back to back (default 3)
+		Timeout        string   `yaml:"timeout"`           // Wall-clock limit per job (default 5m)
+		MemoryLimitMiB int      `yaml:"memory_limit_mib"`  // Heap cap per job (default: security.runtime.mem_guard_mib, else 256)
+		WorkDir        string   `yaml:"work_dir"`          // Per-job artifact directories (default: <state dir>/jobs)
+		Sink           struct {
+			URL     string            `yaml:"url"`     // http(s):// upload endpoint or directory
+			Headers map[string]string `yaml:"headers"` // Extra upload headers (${VAR} expanded)
+		} `yaml:"sink"`
+	} `yaml:"remote"`
 	Plugins map[string]map[string]interface{} `yaml:"plugins"` // Plugin-specific configs
 }
 
@@ -287,9 +431,21 @@ func LoadConfigWithOverlay(spec interface{}, cliFlags map[string]string, opts ..
 	v.SetDefault("plugins.exec_dir", DefaultPluginExecDir())
 	v.SetDefault("service.port", 8080)
 	v.SetDefault("service.name", "srediag")
+	v.SetDefault("service.pid_file", filepath.Join(DefaultRuntimeDir(), "srediag.pid"))
+	v.SetDefault("service.socket", DefaultAdminSocket())
+	v.SetDefault("service.log_file", filepath.Join(DefaultLogDir(), "srediag.log"))
 	v.SetDefault("collector.enabled", false)
 	v.SetDefault("collector.config_path", "/etc/srediag/srediag-service.yaml")
+	v.SetDefault("collector.watch", false)
 	v.SetDefault("build.output_dir", DefaultBuildOutputDir())
+	v.SetDefault("diagnostics.defaults.timeout", "30s")
+	v.SetDefault("diagnostics.defaults.max_retries", 1)
+	v.SetDefault("diagnostics.bundle.max_size", "100MiB")
+	v.SetDefault("diagnostics.bundle.max_file_size", "10MiB")
+	v.SetDefault("diagnostics.baseline.threshold", 10)
+	v.SetDefault("diagnostics.remote.poll_interval", "30s")
+	v.SetDefault("diagnostics.remote.max_age", "15m")
+	v.SetDefault("diagnostics.remote.timeout", "5m")
 	// Bind all documented env vars
 	bindEnvs := map[string]string{
 		"logging.level":                      "SREDIAG_LOG_LEVEL",
@@ -298,8 +454,12 @@ func LoadConfigWithOverlay(spec interface{}, cliFlags map[string]string, opts ..
 		"plugins.exec_dir":                   "SREDIAG_PLUGINS_EXEC_DIR",
 		"service.port":                       "SREDIAG_SERVICE_PORT",
 		"service.name":                       "SREDIAG_SERVICE_NAME",
+		"service.pid_file":                   "SREDIAG_SERVICE_PID_FILE",
+		"service.socket":                     "SREDIAG_SERVICE_SOCKET",
+		"service.log_file":                   "SREDIAG_SERVICE_LOG_FILE",
 		"collector.enabled":                  "SREDIAG_COLLECTOR_ENABLED",
 		"collector.config_path":              "SREDIAG_COLLECTOR_CONFIG_PATH",
+		"collector.watch":                    "SREDIAG_COLLECTOR_WATCH",
 		"build.output_dir":                   "SREDIAG_BUILD_OUTPUT_DIR",
 		"security.tls.enabled":               "SREDIAG_TLS_ENABLED",
 		"security.tls.cert_file":             "SREDIAG_TLS_CERT_FILE",
@@ -309,6 +469,11 @@ func LoadConfigWithOverlay(spec interface{}, cliFlags map[string]string, opts ..
 		"security.rbac.enabled":              "SREDIAG_RBAC_ENABLED",
 		"diagnostics.defaults.output_format": "SREDIAG_DIAG_OUTPUT_FORMAT",
 		"diagnostics.defaults.timeout":       "SREDIAG_DIAG_TIMEOUT",
+		"diagnostics.defaults.max_retries":   "SREDIAG_DIAG_MAX_RETRIES",
+		"diagnostics.metrics.export":         "SREDIAG_DIAG_METRICS_EXPORT",
+		"diagnostics.export.endpoint":        "SREDIAG_DIAG_EXPORT_ENDPOINT",
+		"diagnostics.bundle.output_dir":      "SREDIAG_DIAG_BUNDLE_DIR",
+		"diagnostics.baseline.dir":           "SREDIAG_DIAG_BASELINE_DIR",
 	}
 	for key, env := range bindEnvs {
 		if err := v.BindEnv(key, env); err != nil {
@@ -343,7 +508,8 @@ func LoadConfigWithOverlay(spec interface{}, cliFlags map[string]string, opts ..
 		}
 	}
 	_ = v.ReadInConfig() // ignore error if not found
-	return v.Unmarshal(spec)
+	// Decode with the yaml tags so snake_case keys (e.g., diagnostics.defaults.output_format) reach their fields.
+	return v.Unmarshal(spec, func(dc *mapstructure.DecoderConfig) { dc.TagName = "yaml" })
 }
 
 // DefaultPluginDir returns the default plugin directory based on install context.
@@ -384,11 +550,64 @@ func DefaultPluginExecDir() string {
 // Returns:
 //   - string: Path to the default build output directory.
 func DefaultBuildOutputDir() string {
+	return filepath.Join(DefaultStateDir(), "build")
+}
+
+// DefaultStateDir returns the default directory for local state (build artefacts, diagnostic baselines).
+//
+// Usage:
+//   - Used to derive build output and baseline store defaults.
+//
+// Returns:
+//   - string: /var/lib/srediag for system installs, ~/.srediag otherwise.
+func DefaultStateDir() string {
 	if isSystemInstall() {
-		return "/var/lib/srediag/build"
+		return "/var/lib/srediag"
 	}
 	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".srediag", "build")
+	return filepath.Join(home, ".srediag")
+}
+
+// DefaultRuntimeDir returns the default directory for runtime files (PID file, admin socket).
+//
+// Usage:
+//   - Used to derive the service.pid_file and service.socket defaults.
+//
+// Returns:
+//   - string: /run/srediag for system installs, $XDG_RUNTIME_DIR/srediag or ~/.srediag/run otherwise.
+func DefaultRuntimeDir() string {
+	if isSystemInstall() {
+		return "/run/srediag"
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "srediag")
+	}
+	return filepath.Join(DefaultStateDir(), "run")
+}
+
+// DefaultAdminSocket returns the default admin socket of the agent.
+//
+// Usage:
+//   - Used for the service.socket default and by clients when service.socket is unset.
+//
+// Returns:
+//   - string: srediag.sock in DefaultRuntimeDir.
+func DefaultAdminSocket() string {
+	return filepath.Join(DefaultRuntimeDir(), "srediag.sock")
+}
+
+// DefaultLogDir returns the default directory for the logs of a detached agent.
+//
+// Usage:
+//   - Used to derive the service.log_file default.
+//
+// Returns:
+//   - string: /var/log/srediag for system installs, ~/.srediag/logs otherwise.
+func DefaultLogDir() string {
+	if isSystemInstall() {
+		return "/var/log/srediag"
+	}
+	return filepath.Join(DefaultStateDir(), "logs")
 }
 
 // isSystemInstall returns true if running as a system install (heuristic: root, /usr/bin, etc)
@@ -59,6 +59,9 @@ func TestLoadConfigWithOverlay_Defaults(t *testing.T) {
 	assert.Equal(t, "console", cfg.Logging.Format)
 	assert.Equal(t, 8080, cfg.Service.Port)
 	assert.Equal(t, "srediag", cfg.Service.Name)
+	assert.Equal(t, filepath.Join(DefaultRuntimeDir(), "srediag.pid"), cfg.Service.PIDFile)
+	assert.Equal(t, filepath.Join(DefaultRuntimeDir(), "srediag.sock"), cfg.Service.Socket)
+	assert.Equal(t, filepath.Join(DefaultLogDir(), "srediag.log"), cfg.Service.LogFile)
 }
 
 func TestLoadConfigWithOverlay_CLIOverride(t *testing.T) {
@@ -0,0 +1,191 @@
+// Package core provides foundational types and utilities for the SREDIAG system.
+//
+// This file defines LogBuffer, an in-memory ring buffer of recent log entries. A Logger feeds it through Tee;
+// every logger derived from it (WithComponent, UnderlyingZap, the embedded collector's loggers) is teed as well.
+// The service's admin API serves the buffer to 'srediag service tail-logs'.
+//
+// Usage:
+//   - Create a buffer with NewLogBuffer and attach it with Logger.Tee.
+//   - Call Subscribe for the buffered entries plus a live feed of new ones; Close the subscription when done.
+//
+// Best Practices:
+//   - Only entries at or above the logger's level reach the buffer; run with debug logging to tail debug entries.
+package core
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// logSubscriptionBuffer is how many entries a subscriber may fall behind before entries are dropped.
+const logSubscriptionBuffer = 256
+
+// LogEntry is one buffered log entry.
+//
+// Fields:
+//   - Time, Level, Message: When, how severe ("debug" to "fatal") and what was logged.
+//   - Logger: Name of the zap logger, if any.
+//   - Component: The "component" field (WithComponent), or "<kind>/<id>" of an embedded collector component.
+//   - Caller: file:line of the log call, if recorded.
+//   - Fields: Remaining structured fields.
+type LogEntry struct {
+	Time      time.Time      `json:"ts"`
+	Level     string         `json:"level"`
+	Logger    string         `json:"logger,omitempty"`
+	Component string         `json:"component,omitempty"`
+	Message   string         `json:"msg"`
+	Caller    string         `json:"caller,omitempty"`
+	Fields    map[string]any `json:"fields,omitempty"`
+}
+
+// LogBuffer keeps the most recent log entries and feeds them to subscribers.
+type LogBuffer struct {
+	mu      sync.Mutex
+	entries []LogEntry
+	next    int
+	full    bool
+	subs    map[*LogSubscription]struct{}
+}
+
+// NewLogBuffer creates a ring buffer holding the last size entries.
+//
+// Parameters:
+//   - size: Number of entries kept; at least 1.
+//
+// Returns:
+//   - *LogBuffer: The empty buffer.
+func NewLogBuffer(size int) *LogBuffer {
+	return &LogBuffer{entries: make([]LogEntry, max(size, 1)), subs: make(map[*LogSubscription]struct{})}
+}
+
+// Entries returns the buffered entries, oldest first.
+//
+// Returns:
+//   - []LogEntry: A copy of the buffered entries.
+func (b *LogBuffer) Entries() []LogEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.snapshot()
+}
+
+// snapshot returns the buffered entries, oldest first; the caller holds mu.
+func (b *LogBuffer) snapshot() []LogEntry {
+	if !b.full {
+		return append([]LogEntry(nil), b.entries[:b.next]...)
+	}
+	return append(append([]LogEntry(nil), b.entries[b.next:]...), b.entries[:b.next]...)
+}
+
+// Subscribe returns the buffered entries and a subscription to every entry added afterwards, atomically so no
+// entry is missed or repeated.
+//
+// Returns:
+//   - []LogEntry: The buffered entries, oldest first.
+//   - *LogSubscription: The live feed; Close it when done.
+func (b *LogBuffer) Subscribe() ([]LogEntry, *LogSubscription) {
+	sub := &LogSubscription{buf: b, ch: make(chan LogEntry, logSubscriptionBuffer)}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sub] = struct{}{}
+	return b.snapshot(), sub
+}
+
+// Add appends an entry, overwriting the oldest one when the buffer is full, and passes it to the subscribers.
+//
+// Parameters:
+//   - e: The entry.
+func (b *LogBuffer) Add(e LogEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries[b.next] = e
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+	for sub := range b.subs {
+		select {
+		case sub.ch <- e:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// LogSubscription is a live feed of new entries of a LogBuffer.
+type LogSubscription struct {
+	buf     *LogBuffer
+	ch      chan LogEntry
+	dropped atomic.Uint64
+	once    sync.Once
+}
+
+// C returns the channel of new entries; it is closed by Close.
+//
+// Returns:
+//   - <-chan LogEntry: The feed.
+func (s *LogSubscription) C() <-chan LogEntry {
+	return s.ch
+}
+
+// Dropped returns how many entries were dropped so far because the subscriber fell behind.
+//
+// Returns:
+//   - uint64: The number of dropped entries.
+func (s *LogSubscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close ends the subscription and closes C.
+func (s *LogSubscription) Close() {
+	s.once.Do(func() {
+		s.buf.mu.Lock()
+		delete(s.buf.subs, s)
+		s.buf.mu.Unlock()
+		close(s.ch)
+	})
+}
+
+// logTee holds the buffer a logger and all loggers derived from it feed; it is shared by pointer so Tee also
+// applies to loggers derived before it was called.
+type logTee struct {
+	buf atomic.Pointer[LogBuffer]
+}
+
+// write adds an entry with its context and call-site fields to the buffer, if one is attached.
+func (t *logTee) write(ent zapcore.Entry, context, fields []zapcore.Field) {
+	if t == nil {
+		return
+	}
+	buf := t.buf.Load()
+	if buf == nil {
+		return
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range context {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	e := LogEntry{Time: ent.Time, Level: ent.Level.String(), Logger: ent.LoggerName, Message: ent.Message}
+	if ent.Caller.Defined {
+		e.Caller = ent.Caller.TrimmedPath()
+	}
+	if c, ok := enc.Fields["component"].(string); ok {
+		e.Component = c
+		delete(enc.Fields, "component")
+	} else if id, ok := enc.Fields["otelcol.component.id"].(string); ok {
+		e.Component = id
+		if kind, ok := enc.Fields["otelcol.component.kind"].(string); ok {
+			e.Component = strings.ToLower(kind) + "/" + id
+		}
+	}
+	if len(enc.Fields) > 0 {
+		e.Fields = enc.Fields
+	}
+	buf.Add(e)
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLogBuffer_Ring(t *testing.T) {
+	buf := NewLogBuffer(2)
+	assert.Empty(t, buf.Entries())
+	for _, msg := range []string{"a", "b", "c"} {
+		buf.Add(LogEntry{Message: msg})
+	}
+	entries := buf.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "b", entries[0].Message)
+	assert.Equal(t, "c", entries[1].Message)
+
+	backlog, sub := buf.Subscribe()
+	assert.Len(t, backlog, 2)
+	for range logSubscriptionBuffer + 3 {
+		buf.Add(LogEntry{Message: "d"})
+	}
+	assert.Equal(t, uint64(3), sub.Dropped(), "a subscriber that falls behind loses entries instead of blocking")
+	assert.Equal(t, "d", (<-sub.C()).Message)
+	sub.Close()
+	sub.Close()
+	buf.Add(LogEntry{Message: "e"})
+}
+
+func TestLogger_Tee(t *testing.T) {
+	logger := NewTestLogger(&bytes.Buffer{})
+	derived := logger.WithComponent("plugin")
+	buf := NewLogBuffer(10)
+	logger.Tee(buf)
+
+	logger.Debug("not enabled")
+	derived.Warn("plugin crashed", ZapString("plugin", "otlp"), ZapInt("restarts", 2))
+	logger.UnderlyingZap().With(zap.String("otelcol.component.id", "otlp"), zap.String("otelcol.component.kind", "Receiver")).
+		Info("Starting GRPC server")
+
+	entries := buf.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "warn", entries[0].Level)
+	assert.Equal(t, "plugin", entries[0].Component, "loggers derived before Tee are teed too")
+	assert.Equal(t, map[string]any{"plugin": "otlp", "restarts": int64(2)}, entries[0].Fields)
+	assert.Equal(t, "receiver/otlp", entries[1].Component)
+
+	logger.Tee(nil)
+	logger.Info("detached")
+	assert.Len(t, buf.Entries(), 2)
+}
@@ -34,6 +34,7 @@ import (
 type Logger struct {
 	logger            *zap.Logger
 	gates             *featuregate.Registry
+	tee               *logTee
 	Level             string                 // Level is the minimum enabled logging level
 	Format            string                 // Format specifies the output format (json, console)
 	OutputPaths       []string               // OutputPaths is a list of URLs or file paths to write logging output to
@@ -120,11 +121,13 @@ func NewLogger(cfg *Logger) (*Logger, error) {
 	}
 
 	// Build the logger
+	tee := &logTee{}
 	logger, err := zapConfig.Build(
 		zap.AddCallerSkip(1),
 		zap.WrapCore(func(core zapcore.Core) zapcore.Core {
 			return &otelCore{
 				Core: core,
+				tee:  tee,
 			}
 		}),
 	)
@@ -135,6 +138,7 @@ func NewLogger(cfg *Logger) (*Logger, error) {
 	return &Logger{
 		logger:            logger,
 		gates:             featuregate.NewRegistry(),
+		tee:               tee,
 		Level:             cfg.Level,
 		Format:            cfg.Format,
 		OutputPaths:       cfg.OutputPaths,
@@ -171,11 +175,13 @@ func parseLevel(level string) zapcore.Level {
 	}
 }
 
-// otelCore wraps zapcore.Core to integrate with OpenTelemetry.
+// otelCore wraps zapcore.Core to integrate with OpenTelemetry and to feed the LogBuffer attached with Tee.
 //
 // Usage: Used internally by NewLogger to wrap zapcore.Core for OTel integration.
 type otelCore struct {
 	zapcore.Core
+	tee     *logTee
+	context []zapcore.Field // Fields added with With, for the LogBuffer
 }
 
 // With adds structured context to the Core.
@@ -183,7 +189,9 @@ type otelCore struct {
 // Usage: Used internally for context propagation in zap.
 func (c *otelCore) With(fields []zapcore.Field) zapcore.Core {
 	return &otelCore{
-		Core: c.Core.With(fields),
+		Core:    c.Core.With(fields),
+		tee:     c.tee,
+		context: append(c.context[:len(c.context):len(c.context)], fields...),
 	}
 }
 
@@ -202,7 +210,9 @@ func (c *otelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.C
 // Usage: Used internally by zap for log output. Can be extended for OTel log export.
 func (c *otelCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
 	// Here we could add OpenTelemetry specific handling if needed
-	return c.Core.Write(ent, fields)
+	err := c.Core.Write(ent, fields)
+	c.tee.write(ent, c.context, fields)
+	return err
 }
 
 // Sync flushes any buffered log entries.
@@ -233,6 +243,7 @@ func (l *Logger) WithComponent(component string) *Logger {
 	return &Logger{
 		logger:            l.logger.With(zap.String("component", component)),
 		gates:             l.gates,
+		tee:               l.tee,
 		Level:             l.Level,
 		Format:            l.Format,
 		OutputPaths:       l.OutputPaths,
@@ -245,6 +256,17 @@ func (l *Logger) WithComponent(component string) *Logger {
 	}
 }
 
+// Tee feeds every entry written by this logger, and by all loggers derived from it, into buf.
+//
+// Usage:
+//   - Call once in the agent so 'srediag service tail-logs' can read recent entries; pass nil to detach.
+//   - Has no effect on loggers not created by NewLogger or NewTestLogger.
+func (l *Logger) Tee(buf *LogBuffer) {
+	if l.tee != nil {
+		l.tee.buf.Store(buf)
+	}
+}
+
 // WithFeatureGates adds OpenTelemetry feature gates to the logger.
 //
 // Usage: Use to enable or configure OTel feature gates for this logger.
@@ -324,6 +346,13 @@ func ZapInt(key string, val int) zap.Field {
 	return zap.Int(key, val)
 }
 
+// ZapBool returns a zap.Field for a bool key/value.
+//
+// Usage: Use to add boolean fields to logs in a structured way.
+func ZapBool(key string, val bool) zap.Field {
+	return zap.Bool(key, val)
+}
+
 // ZapReflect returns a zap.Field for a reflect value.
 //
 // Usage: Use to add arbitrary structured data to logs.
@@ -331,15 +360,17 @@ func ZapReflect(key string, val interface{}) zap.Field {
 	return zap.Reflect(key, val)
 }
 
-// NewTestLogger returns a *Logger that writes to the provided buffer. For test use only.
+// NewTestLogger returns a *Logger that writes to the provided buffer, serializing concurrent writes. For test use only.
 func NewTestLogger(buf *bytes.Buffer) *Logger {
-	zapLogger := zap.New(zapcore.NewCore(
+	tee := &logTee{}
+	zapLogger := zap.New(&otelCore{Core: zapcore.NewCore(
 		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
-		zapcore.AddSync(buf),
+		zapcore.Lock(zapcore.AddSync(buf)),
 		zapcore.InfoLevel,
-	))
+	), tee: tee})
 	return &Logger{
 		logger: zapLogger,
+		tee:    tee,
 		Level:  "info",
 		Format: "json",
 	}
@@ -1,5 +1,147 @@
-// TODO(architecture/plugin.md §5, §6, §9): Implement RBAC and authentication/authorization using JWT or OAuth2, and map verbs to capabilities for access control.
-// TODO(architecture/plugin.md §5, §6, §9): Enforce RBAC policy for all plugin and service operations, ensuring only authorized actions are permitted.
+// Package core provides foundational types and utilities for the SREDIAG system.
+//
+// This file defines the RBAC capability registry and the permission check used by CLI and plugin operations.
+// Plugins report the capabilities they provide (e.g., "diag/system"); operations are authorized as "<verb>:<capability>"
+// against the role definitions in the 'security.rbac' config section.
+//
+// Usage:
+//   - Call RegisterCapabilities when a plugin is attached, so RBAC knows which capabilities exist and who owns them.
+//   - Call Authorize before executing a capability; it is a no-op while security.rbac.enabled is false.
+//
+// Best Practices:
+//   - Use slash-separated capability names ("diag/perf/cpu") so role patterns like "execute:diag/*" apply.
+//   - Wrap denials with ErrAccessDenied so callers can map them to exit codes or HTTP 403.
+//
+// TODO(architecture/plugin.md §5, §6, §9): Resolve roles from JWT/OAuth2 claims instead of security.rbac.default_role.
 // TODO(architecture/plugin.md §5, §6, §9): Integrate RBAC checks with the plugin trust chain and capability validation logic.
-
 package core
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrAccessDenied is returned (wrapped) when RBAC denies an operation.
+var ErrAccessDenied = errors.New("access denied")
+
+// defaultRBACRoles are used when security.rbac.roles is empty (docs: configuration/security.md).
+var defaultRBACRoles = map[string][]string{
+	"admin":    {"*"},
+	"operator": {"read:*", "execute:diag/*", "write:config", "write:telemetry"},
+	"viewer":   {"read:*"},
+}
+
+// RBAC tracks the capabilities registered by plugins and authorizes operations against them.
+//
+// Usage:
+//   - Create once per process with NewRBAC and store it in AppContext.RBAC.
+//   - Thread-safe for concurrent registration and lookup.
+//
+// Fields:
+//   - owners: Map of capability name to the plugins that provide it.
+//   - mu: RWMutex for thread safety.
+type RBAC struct {
+	owners map[string][]string
+	mu     sync.RWMutex
+}
+
+// NewRBAC creates an empty capability registry.
+func NewRBAC() *RBAC {
+	return &RBAC{owners: make(map[string][]string)}
+}
+
+// RegisterCapabilities records the capabilities provided by a plugin.
+//
+// Parameters:
+//   - owner: Name of the plugin providing the capabilities.
+//   - capabilities: Capability names (e.g., "diag/system"); must be non-empty and contain no whitespace or ':'.
+//
+// Returns:
+//   - error: If any capability name is invalid, returns a detailed error and registers nothing.
+func (r *RBAC) RegisterCapabilities(owner string, capabilities []string) error {
+	for _, c := range capabilities {
+		if c == "" || strings.ContainsAny(c, " \t\n:") {
+			return fmt.Errorf("invalid capability %q for %s", c, owner)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range capabilities {
+		if !slices.Contains(r.owners[c], owner) {
+			r.owners[c] = append(r.owners[c], owner)
+		}
+	}
+	return nil
+}
+
+// Capabilities returns a copy of the registered capabilities and their owners.
+func (r *RBAC) Capabilities() map[string][]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make(map[string][]string, len(r.owners))
+	for c, owners := range r.owners {
+		sorted := append([]string(nil), owners...)
+		sort.Strings(sorted)
+		out[c] = sorted
+	}
+	return out
+}
+
+// Authorize checks whether a role may perform verb on a registered capability.
+//
+// Parameters:
+//   - cfg: Security configuration (security.rbac is used).
+//   - role: Caller role; empty uses security.rbac.default_role.
+//   - verb: Operation verb (e.g., "read", "execute").
+//   - capability: Capability being accessed.
+//
+// Returns:
+//   - error: nil if allowed or RBAC is disabled; otherwise an error wrapping ErrAccessDenied.
+func (r *RBAC) Authorize(cfg SecurityConfig, role, verb, capability string) error {
+	if !cfg.RBAC.Enabled {
+		return nil
+	}
+	r.mu.RLock()
+	_, known := r.owners[capability]
+	r.mu.RUnlock()
+	if !known {
+		return fmt.Errorf("%w: capability %q is not registered", ErrAccessDenied, capability)
+	}
+	if role == "" {
+		role = cfg.RBAC.DefaultRole
+	}
+	roles := cfg.RBAC.Roles
+	if len(roles) == 0 {
+		roles = defaultRBACRoles
+	}
+	perms, ok := roles[role]
+	if !ok {
+		return fmt.Errorf("%w: unknown role %q", ErrAccessDenied, role)
+	}
+	for _, p := range perms {
+		if permissionMatches(p, verb, capability) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: role %q may not %s %s", ErrAccessDenied, role, verb, capability)
+}
+
+// permissionMatches reports whether a permission ("*", "<verb>:<pattern>") grants verb on capability.
+// Patterns match exactly, or by prefix when they end in '*'.
+func permissionMatches(perm, verb, capability string) bool {
+	if perm == "*" {
+		return true
+	}
+	pv, pc, ok := strings.Cut(perm, ":")
+	if !ok || (pv != "*" && pv != verb) {
+		return false
+	}
+	if prefix, wildcard := strings.CutSuffix(pc, "*"); wildcard {
+		return strings.HasPrefix(capability, prefix)
+	}
+	return pc == capability
+}
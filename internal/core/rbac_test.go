@@ -0,0 +1,31 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRBAC_Authorize(t *testing.T) {
+	r := NewRBAC()
+	require.NoError(t, r.RegisterCapabilities("systemsnapshot", []string{"diag/system"}))
+	require.NoError(t, r.RegisterCapabilities("perfprofiler", []string{"diag/perf/cpu"}))
+	assert.Error(t, r.RegisterCapabilities("bad", []string{"diag:system"}))
+
+	var cfg SecurityConfig
+	assert.NoError(t, r.Authorize(cfg, "viewer", "execute", "diag/unknown"), "RBAC disabled allows everything")
+
+	cfg.RBAC.Enabled = true
+	cfg.RBAC.DefaultRole = "viewer"
+	assert.ErrorIs(t, r.Authorize(cfg, "", "execute", "diag/system"), ErrAccessDenied)
+	assert.NoError(t, r.Authorize(cfg, "", "read", "diag/system"))
+	assert.NoError(t, r.Authorize(cfg, "operator", "execute", "diag/perf/cpu"))
+	assert.NoError(t, r.Authorize(cfg, "admin", "write", "diag/system"))
+	assert.ErrorIs(t, r.Authorize(cfg, "admin", "execute", "diag/unknown"), ErrAccessDenied)
+	assert.ErrorIs(t, r.Authorize(cfg, "nobody", "read", "diag/system"), ErrAccessDenied)
+
+	cfg.RBAC.Roles = map[string][]string{"sre": {"execute:diag/perf/*"}}
+	assert.NoError(t, r.Authorize(cfg, "sre", "execute", "diag/perf/cpu"))
+	assert.ErrorIs(t, r.Authorize(cfg, "sre", "execute", "diag/system"), ErrAccessDenied)
+}
@@ -35,12 +35,14 @@ import (
 //   - BuildInfo: Build/version metadata.
 //   - TelemetrySettings: OpenTelemetry collector settings.
 //   - Config: Loaded configuration for the application.
+//   - RBAC: Capability registry and access checks (see RBAC).
 type AppContext struct {
 	Logger            *Logger
 	ComponentManager  *ComponentManager
 	BuildInfo         BuildInfo
 	TelemetrySettings component.TelemetrySettings
 	Config            *Config
+	RBAC              *RBAC
 }
 
 // GetLogger returns the logger from the context, or a no-op logger if nil.
@@ -67,6 +69,17 @@ func (ctx *AppContext) GetConfig() *Config {
 	return ctx.Config
 }
 
+// GetRBAC returns the RBAC registry from the context, creating an empty one if nil.
+//
+// Usage:
+//   - Use to register plugin capabilities and authorize operations.
+func (ctx *AppContext) GetRBAC() *RBAC {
+	if ctx.RBAC == nil {
+		ctx.RBAC = NewRBAC()
+	}
+	return ctx.RBAC
+}
+
 // Settings is deprecated. Use AppContext instead.
 //
 // Usage:
@@ -0,0 +1,452 @@
+package diagnose
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file implements baselines and drift comparison: a BaselineStore keeps named reports on disk
+// ('srediag diagnose <type> --save-baseline <name>'), and DiffReports compares two reports structurally
+// ('srediag diagnose diff <baseline> [<report>]').
+//
+// A diff contains:
+//   - New and resolved findings (matched on check, severity, resource and location).
+//   - Measurement deltas whose relative change reaches the threshold (percent).
+//   - Added, removed and changed inventory items (config files, packages, listening ports, kernel parameters).
+//
+// Best Practices:
+//   - Save baselines from a known-healthy state and name them after it (e.g., "post-deploy-2026-10").
+//   - Gate pipelines on the JSON output or on --fail-on-drift (exit code 7).
+
+// DefaultBaselineThreshold is the minimum relative measurement change (percent) reported by a diff.
+const DefaultBaselineThreshold = 10.0
+
+// Change kinds of measurement and inventory diffs.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// baselineNameRe restricts baseline names to safe file names.
+var baselineNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// DefaultBaselineDir returns the default baseline store, <state dir>/baselines.
+func DefaultBaselineDir() string {
+	return filepath.Join(core.DefaultStateDir(), "baselines")
+}
+
+// BaselineStore keeps named reports as JSON files in a directory.
+//
+// Usage:
+//   - Create with NewBaselineStore; Save a report under a name and Load it back later.
+type BaselineStore struct {
+	dir string
+}
+
+// NewBaselineStore creates a store rooted at dir.
+//
+// Parameters:
+//   - dir: Store directory; empty uses DefaultBaselineDir.
+//
+// Returns:
+//   - *BaselineStore: The store (the directory is created on first Save).
+func NewBaselineStore(dir string) *BaselineStore {
+	if dir == "" {
+		dir = DefaultBaselineDir()
+	}
+	return &BaselineStore{dir: dir}
+}
+
+// Dir returns the store directory.
+func (s *BaselineStore) Dir() string { return s.dir }
+
+// path returns the file of a baseline after validating its name.
+func (s *BaselineStore) path(name string) (string, error) {
+	if !baselineNameRe.MatchString(name) {
+		return "", fmt.Errorf("invalid baseline name %q (letters, digits, '.', '_' and '-' only)", name)
+	}
+	return filepath.Join(s.dir, name+".json"), nil
+}
+
+// Save stores r under name, replacing any previous baseline of that name.
+//
+// Parameters:
+//   - name: Baseline name.
+//   - r: Report to store.
+//
+// Returns:
+//   - string: Path of the stored baseline.
+//   - error: If the name is invalid or the file cannot be written, returns a detailed error.
+func (s *BaselineStore) Save(name string, r *Report) (string, error) {
+	p, err := s.path(name)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode baseline %s: %w", name, err)
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create baseline store %s: %w", s.dir, err)
+	}
+	tmp, err := os.CreateTemp(s.dir, "."+name+".*")
+	if err != nil {
+		return "", fmt.Errorf("failed to save baseline %s: %w", name, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return "", fmt.Errorf("failed to save baseline %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to save baseline %s: %w", name, err)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return "", fmt.Errorf("failed to save baseline %s: %w", name, err)
+	}
+	return p, nil
+}
+
+// Load reads the baseline stored under name.
+//
+// Parameters:
+//   - name: Baseline name.
+//
+// Returns:
+//   - *Report: The stored report.
+//   - error: ErrNotFound if no such baseline exists, or a detailed read/decode error.
+func (s *BaselineStore) Load(name string) (*Report, error) {
+	p, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	r, err := LoadReport(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: baseline %q not found in %s", ErrNotFound, name, s.dir)
+	}
+	return r, err
+}
+
+// LoadReport reads a report written with --output json (or a stored baseline).
+//
+// Parameters:
+//   - path: Report file.
+//
+// Returns:
+//   - *Report: The decoded report.
+//   - error: If the file cannot be read or is not a JSON report, returns a detailed error.
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report %s: %w", path, err)
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to decode report %s (expected --output json): %w", path, err)
+	}
+	if r.Diagnostic == "" {
+		return nil, fmt.Errorf("failed to decode report %s: missing diagnostic name", path)
+	}
+	return &r, nil
+}
+
+// resolveReport loads ref as a baseline name, falling back to a report file path.
+func resolveReport(store *BaselineStore, ref string) (*Report, error) {
+	if baselineNameRe.MatchString(ref) {
+		r, err := store.Load(ref)
+		if err == nil || !errors.Is(err, ErrNotFound) {
+			return r, err
+		}
+	}
+	if _, err := os.Stat(ref); err == nil {
+		return LoadReport(ref)
+	}
+	return nil, fmt.Errorf("%w: %q is neither a baseline in %s nor a report file", ErrNotFound, ref, store.Dir())
+}
+
+// DiffOptions controls DiffReports.
+//
+// Fields:
+//   - Threshold: Minimum relative measurement change in percent; 0 reports every change.
+type DiffOptions struct {
+	Threshold float64
+}
+
+// MeasurementDelta is a measurement that changed past the threshold, appeared or disappeared.
+//
+// Fields:
+//   - Name: Measurement name.
+//   - Change: ChangeAdded, ChangeRemoved or ChangeChanged.
+//   - Baseline, Current: Values in each report (0 when absent).
+//   - Delta: Current - Baseline.
+//   - Percent: Relative change; omitted when the baseline value is 0.
+type MeasurementDelta struct {
+	Name     string   `json:"name" yaml:"name"`
+	Change   string   `json:"change" yaml:"change"`
+	Baseline float64  `json:"baseline" yaml:"baseline"`
+	Current  float64  `json:"current" yaml:"current"`
+	Delta    float64  `json:"delta" yaml:"delta"`
+	Percent  *float64 `json:"percent,omitempty" yaml:"percent,omitempty"`
+}
+
+// InventoryChange is an inventory item that was added, removed or changed.
+//
+// Fields:
+//   - Section: Inventory section (e.g., InventoryPackages).
+//   - Key: Item key (file path, package name, socket, sysctl key).
+//   - Change: ChangeAdded, ChangeRemoved or ChangeChanged.
+//   - Baseline, Current: Item values in each report (empty when absent).
+type InventoryChange struct {
+	Section  string `json:"section" yaml:"section"`
+	Key      string `json:"key" yaml:"key"`
+	Change   string `json:"change" yaml:"change"`
+	Baseline string `json:"baseline,omitempty" yaml:"baseline,omitempty"`
+	Current  string `json:"current,omitempty" yaml:"current,omitempty"`
+}
+
+// ReportDiff is the structural difference between a baseline and a later report.
+//
+// Fields:
+//   - Diagnostic: Diagnostic name shared by both reports.
+//   - Baseline, Current: Labels of the compared reports (baseline name or file path).
+//   - BaselineAt, CurrentAt: Start times of the compared reports.
+//   - Threshold: Measurement threshold (percent) applied.
+//   - NewFindings: Findings in Current but not in Baseline.
+//   - ResolvedFindings: Findings in Baseline but not in Current.
+//   - Measurements: Measurement changes past the threshold.
+//   - Inventory: Inventory changes, for sections present in both reports.
+type ReportDiff struct {
+	Diagnostic       string             `json:"diagnostic" yaml:"diagnostic"`
+	Baseline         string             `json:"baseline" yaml:"baseline"`
+	Current          string             `json:"current" yaml:"current"`
+	BaselineAt       time.Time          `json:"baseline_at" yaml:"baseline_at"`
+	CurrentAt        time.Time          `json:"current_at" yaml:"current_at"`
+	Threshold        float64            `json:"threshold" yaml:"threshold"`
+	NewFindings      []Finding          `json:"new_findings" yaml:"new_findings"`
+	ResolvedFindings []Finding          `json:"resolved_findings" yaml:"resolved_findings"`
+	Measurements     []MeasurementDelta `json:"measurements" yaml:"measurements"`
+	Inventory        []InventoryChange  `json:"inventory" yaml:"inventory"`
+}
+
+// Changes returns the total number of differences.
+func (d *ReportDiff) Changes() int {
+	return len(d.NewFindings) + len(d.ResolvedFindings) + len(d.Measurements) + len(d.Inventory)
+}
+
+// DiffReports compares current against base.
+//
+// Parameters:
+//   - base: Baseline report.
+//   - current: Later report of the same diagnostic.
+//   - opts: Measurement threshold.
+//
+// Returns:
+//   - *ReportDiff: The differences; labels are left for the caller to set.
+//   - error: If the reports come from different diagnostics, returns a detailed error.
+func DiffReports(base, current *Report, opts DiffOptions) (*ReportDiff, error) {
+	if base.Diagnostic != current.Diagnostic {
+		return nil, fmt.Errorf("cannot compare a %q report with a %q baseline", current.Diagnostic, base.Diagnostic)
+	}
+	d := &ReportDiff{
+		Diagnostic:       base.Diagnostic,
+		BaselineAt:       base.StartedAt,
+		CurrentAt:        current.StartedAt,
+		Threshold:        opts.Threshold,
+		NewFindings:      subtractFindings(current.Findings, base.Findings),
+		ResolvedFindings: subtractFindings(base.Findings, current.Findings),
+		Measurements:     diffMeasurements(base.Measurements, current.Measurements, opts.Threshold),
+		Inventory:        []InventoryChange{},
+	}
+	sections := make([]string, 0, len(base.Inventory))
+	for section := range base.Inventory {
+		// A section missing from one side means its source was unavailable, not that everything was removed.
+		if _, ok := current.Inventory[section]; ok {
+			sections = append(sections, section)
+		}
+	}
+	sort.Strings(sections)
+	for _, section := range sections {
+		d.Inventory = append(d.Inventory, diffInventory(section, base.Inventory[section], current.Inventory[section])...)
+	}
+	return d, nil
+}
+
+// findingKey identifies a finding across runs.
+func findingKey(f Finding) string {
+	loc := ""
+	if f.Location != nil {
+		loc = f.Location.String()
+	}
+	return strings.Join([]string{f.Check, string(f.Severity), f.Resource, loc}, "\x00")
+}
+
+// subtractFindings returns the findings of a whose key does not occur in b.
+func subtractFindings(a, b []Finding) []Finding {
+	seen := make(map[string]bool, len(b))
+	for _, f := range b {
+		seen[findingKey(f)] = true
+	}
+	out := []Finding{}
+	for _, f := range a {
+		if !seen[findingKey(f)] {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// diffMeasurements returns added, removed and changed measurements; changes below threshold percent are dropped.
+func diffMeasurements(base, current map[string]float64, threshold float64) []MeasurementDelta {
+	names := map[string]bool{}
+	for name := range base {
+		names[name] = true
+	}
+	for name := range current {
+		names[name] = true
+	}
+	out := []MeasurementDelta{}
+	for name := range names {
+		b, inBase := base[name]
+		c, inCurrent := current[name]
+		m := MeasurementDelta{Name: name, Baseline: b, Current: c, Delta: c - b}
+		switch {
+		case !inBase:
+			m.Change = ChangeAdded
+		case !inCurrent:
+			m.Change = ChangeRemoved
+		case b == c:
+			continue
+		default:
+			m.Change = ChangeChanged
+			if b != 0 {
+				pct := (c - b) / math.Abs(b) * 100
+				if math.Abs(pct) < threshold {
+					continue
+				}
+				m.Percent = &pct
+			}
+		}
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// diffInventory returns the changes of one inventory section, sorted by key.
+func diffInventory(section string, base, current map[string]string) []InventoryChange {
+	var out []InventoryChange
+	for key, b := range base {
+		c, ok := current[key]
+		switch {
+		case !ok:
+			out = append(out, InventoryChange{Section: section, Key: key, Change: ChangeRemoved, Baseline: b})
+		case b != c:
+			out = append(out, InventoryChange{Section: section, Key: key, Change: ChangeChanged, Baseline: b, Current: c})
+		}
+	}
+	for key, c := range current {
+		if _, ok := base[key]; !ok {
+			out = append(out, InventoryChange{Section: section, Key: key, Change: ChangeAdded, Current: c})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// WriteDiff renders a diff in the requested format.
+//
+// Parameters:
+//   - w: Destination writer.
+//   - d: Diff to render.
+//   - format: One of "table" (default), "json" or "yaml".
+//
+// Returns:
+//   - error: If the format is unknown or encoding fails, returns a detailed error.
+func WriteDiff(w io.Writer, d *ReportDiff, format string) error {
+	switch strings.ToLower(format) {
+	case "", "table":
+		return writeDiffTable(w, d)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(d); err != nil {
+			return fmt.Errorf("failed to encode diff as YAML: %w", err)
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("unsupported diff format %q (expected table, json, or yaml)", format)
+	}
+}
+
+// writeDiffTable renders a diff as aligned, human-readable sections.
+func writeDiffTable(w io.Writer, d *ReportDiff) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "DIAGNOSTIC: %s\tBASELINE: %s (%s)\tCURRENT: %s (%s)\n",
+		d.Diagnostic, d.Baseline, d.BaselineAt.Format(time.RFC3339), d.Current, d.CurrentAt.Format(time.RFC3339))
+	if d.Changes() == 0 {
+		fmt.Fprintln(tw, "\nNo drift.")
+		return tw.Flush()
+	}
+	writeFindings := func(title string, findings []Finding) {
+		if len(findings) == 0 {
+			return
+		}
+		fmt.Fprintf(tw, "\n%s\n", title)
+		fmt.Fprintln(tw, "SEVERITY\tCHECK\tRESOURCE\tMESSAGE")
+		for _, f := range findings {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", strings.ToUpper(string(f.Severity)), f.Check, f.Resource, f.Message)
+		}
+	}
+	writeFindings("NEW FINDINGS", d.NewFindings)
+	writeFindings("RESOLVED FINDINGS", d.ResolvedFindings)
+	if len(d.Measurements) > 0 {
+		fmt.Fprintf(tw, "\nMEASUREMENT\tCHANGE\tBASELINE\tCURRENT\tDELTA\n")
+		for _, m := range d.Measurements {
+			delta := fmt.Sprintf("%+g", m.Delta)
+			if m.Percent != nil {
+				delta += fmt.Sprintf(" (%+.1f%%)", *m.Percent)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%g\t%g\t%s\n", m.Name, m.Change, m.Baseline, m.Current, delta)
+		}
+	}
+	if len(d.Inventory) > 0 {
+		fmt.Fprintf(tw, "\nSECTION\tKEY\tCHANGE\tBASELINE\tCURRENT\n")
+		for _, c := range d.Inventory {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.Section, c.Key, c.Change, shortValue(c.Baseline), shortValue(c.Current))
+		}
+	}
+	fmt.Fprintf(tw, "\n%d change(s)\n", d.Changes())
+	return tw.Flush()
+}
+
+// shortValue abbreviates long inventory values (e.g., SHA-256 digests) for table output.
+func shortValue(v string) string {
+	if v == "" {
+		return "-"
+	}
+	if len(v) > 40 {
+		return v[:12] + "…"
+	}
+	return v
+}
@@ -0,0 +1,173 @@
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// driftReports returns a baseline and a later system report that differ in every diff category.
+func driftReports() (*Report, *Report) {
+	base := NewReport("system")
+	base.Add(Finding{Check: "sys.disk.usage", Severity: SeverityWarning, Resource: "/var", Message: "disk 85% full"})
+	base.Add(Finding{Check: "sys.ntp", Severity: SeverityInfo, Message: "clock synchronised"})
+	base.SetMeasurement("sys.load1", 1.0)
+	base.SetMeasurement("sys.mem.used", 100)
+	base.SetMeasurement("sys.procs", 200)
+	base.Inventory = map[string]map[string]string{
+		InventoryPackages:       {"openssl": "3.0.13", "curl": "8.5.0"},
+		InventoryListeningPorts: {"tcp/0.0.0.0:22": "uid=0"},
+		InventoryKernelParams:   {"vm.swappiness": "60"},
+	}
+
+	current := NewReport("system")
+	current.Add(Finding{Check: "sys.ntp", Severity: SeverityInfo, Message: "clock synchronised"})
+	current.Add(Finding{Check: "sys.oom", Severity: SeverityCritical, Resource: "pid/42", Message: "OOM kill"})
+	current.SetMeasurement("sys.load1", 2.5)
+	current.SetMeasurement("sys.mem.used", 105)
+	current.SetMeasurement("sys.threads", 900)
+	current.Inventory = map[string]map[string]string{
+		InventoryPackages:       {"openssl": "3.0.14", "curl": "8.5.0", "nginx": "1.24.0"},
+		InventoryListeningPorts: {},
+		InventoryConfigFiles:    {"/etc/hosts": "abc"},
+	}
+	return base, current
+}
+
+func TestBaselineStore(t *testing.T) {
+	store := NewBaselineStore(filepath.Join(t.TempDir(), "baselines"))
+	base, _ := driftReports()
+	path, err := store.Save("healthy", base)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(store.Dir(), "healthy.json"), path)
+
+	got, err := store.Load("healthy")
+	require.NoError(t, err)
+	assert.Equal(t, base.Findings, got.Findings)
+	assert.Equal(t, base.Inventory, got.Inventory)
+
+	_, err = store.Load("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = store.Save("../escape", base)
+	assert.ErrorContains(t, err, "invalid baseline name")
+}
+
+func TestDiffReports(t *testing.T) {
+	base, current := driftReports()
+	d, err := DiffReports(base, current, DiffOptions{Threshold: 10})
+	require.NoError(t, err)
+
+	require.Len(t, d.NewFindings, 1)
+	assert.Equal(t, "sys.oom", d.NewFindings[0].Check)
+	require.Len(t, d.ResolvedFindings, 1)
+	assert.Equal(t, "sys.disk.usage", d.ResolvedFindings[0].Check)
+
+	require.Len(t, d.Measurements, 3, "sys.mem.used changed by 5%, below the threshold")
+	assert.Equal(t, "sys.load1", d.Measurements[0].Name)
+	assert.Equal(t, ChangeChanged, d.Measurements[0].Change)
+	assert.InDelta(t, 150.0, *d.Measurements[0].Percent, 0.001)
+	assert.Equal(t, ChangeRemoved, d.Measurements[1].Change)
+	assert.Equal(t, ChangeAdded, d.Measurements[2].Change)
+
+	assert.Equal(t, []InventoryChange{
+		{Section: InventoryListeningPorts, Key: "tcp/0.0.0.0:22", Change: ChangeRemoved, Baseline: "uid=0"},
+		{Section: InventoryPackages, Key: "nginx", Change: ChangeAdded, Current: "1.24.0"},
+		{Section: InventoryPackages, Key: "openssl", Change: ChangeChanged, Baseline: "3.0.13", Current: "3.0.14"},
+	}, d.Inventory, "sections missing on one side are not compared")
+	assert.Equal(t, 8, d.Changes())
+
+	_, err = DiffReports(base, NewReport("security"), DiffOptions{})
+	assert.ErrorContains(t, err, "cannot compare")
+}
+
+func TestWriteDiff(t *testing.T) {
+	base, current := driftReports()
+	d, err := DiffReports(base, current, DiffOptions{Threshold: 10})
+	require.NoError(t, err)
+
+	var table bytes.Buffer
+	require.NoError(t, WriteDiff(&table, d, "table"))
+	for _, want := range []string{"NEW FINDINGS", "RESOLVED FINDINGS", "sys.load1", "+1.5 (+150.0%)", "openssl", "8 change(s)"} {
+		assert.Contains(t, table.String(), want)
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, WriteDiff(&out, d, "json"))
+	var decoded ReportDiff
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, d.Changes(), decoded.Changes())
+
+	empty, err := DiffReports(base, base, DiffOptions{})
+	require.NoError(t, err)
+	table.Reset()
+	require.NoError(t, WriteDiff(&table, empty, ""))
+	assert.Contains(t, table.String(), "No drift.")
+	assert.Error(t, WriteDiff(&table, d, "sarif"))
+}
+
+func TestCLI_SaveBaselineAndDiff(t *testing.T) {
+	base, current := driftReports()
+	orig := namedDiagnostics["system"]
+	next := base
+	namedDiagnostics["system"] = func(*DiagnoseManager, context.Context) (*Report, error) { return next, nil }
+	defer func() { namedDiagnostics["system"] = orig }()
+
+	dir := t.TempDir()
+	ctx := &core.AppContext{Logger: core.NewTestLogger(&bytes.Buffer{}), Config: core.NewConfig()}
+	ctx.Config.Diagnostics.Baseline.Dir = dir
+	ctx.Config.Diagnostics.Baseline.Threshold = DefaultBaselineThreshold
+
+	save := &cobra.Command{Use: "system"}
+	save.Flags().String("save-baseline", "healthy", "")
+	save.SetOut(&bytes.Buffer{})
+	require.NoError(t, finishReport(ctx, save, base))
+	assert.FileExists(t, filepath.Join(dir, "healthy.json"))
+
+	newDiffCmd := func() (*cobra.Command, *bytes.Buffer) {
+		cmd := &cobra.Command{Use: "diff"}
+		cmd.Flags().String("output", "table", "")
+		cmd.Flags().Float64("threshold", DefaultBaselineThreshold, "")
+		cmd.Flags().Bool("fail-on-drift", false, "")
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		return cmd, &out
+	}
+
+	// Re-run: the fake system diagnostic returns the baseline again.
+	cmd, out := newDiffCmd()
+	require.NoError(t, cmd.Flags().Set("fail-on-drift", "true"))
+	require.NoError(t, CLI_Diff(ctx, cmd, []string{"healthy"}))
+	assert.Contains(t, out.String(), "No drift.")
+
+	// Explicit report file, JSON output, gated.
+	reportFile := filepath.Join(dir, "after.json")
+	f, err := os.Create(reportFile)
+	require.NoError(t, err)
+	require.NoError(t, WriteReport(f, current, "json"))
+	require.NoError(t, f.Close())
+
+	cmd, out = newDiffCmd()
+	require.NoError(t, cmd.Flags().Set("output", "json"))
+	require.NoError(t, cmd.Flags().Set("fail-on-drift", "true"))
+	err = CLI_Diff(ctx, cmd, []string{"healthy", reportFile})
+	var drift *DriftError
+	require.ErrorAs(t, err, &drift)
+	assert.Equal(t, 8, drift.Changes)
+	assert.Equal(t, ExitDrift, ExitCode(err))
+	var decoded ReportDiff
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, "healthy", decoded.Baseline)
+	assert.Equal(t, reportFile, decoded.Current)
+
+	cmd, _ = newDiffCmd()
+	assert.ErrorIs(t, CLI_Diff(ctx, cmd, []string{"nope"}), ErrNotFound)
+}
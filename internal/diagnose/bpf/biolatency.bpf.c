@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: GPL-2.0-only
+/* biolatency measures the latency of block requests per device between block_rq_issue and block_rq_complete. */
+#include "vmlinux.h"
+#include "bpf_helpers.h"
+#include "bpf_core_read.h"
+#include "bpf_tracing.h"
+#include "common.h"
+
+char LICENSE[] SEC("license") = "GPL";
+
+/* dev_bucket is a histogram bucket of one device (dev_t as major << 20 | minor). */
+struct dev_bucket {
+	__u32 dev;
+	__u32 bucket;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, __u64); /* struct request * */
+	__type(value, __u64);
+} start SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 256);
+	__type(key, __u32);
+	__type(value, __u64);
+} totals SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 256 * HIST_BUCKETS);
+	__type(key, struct dev_bucket);
+	__type(value, __u64);
+} hist SEC(".maps");
+
+/* request___x is struct request before 5.17, when the disk hung off the request instead of its queue. */
+struct request___x {
+	struct gendisk *rq_disk;
+} __attribute__((preserve_access_index));
+
+static __always_inline __u32 request_dev(struct request *rq)
+{
+	struct request___x *old = (void *)rq;
+	struct gendisk *disk;
+
+	if (bpf_core_field_exists(old->rq_disk))
+		disk = BPF_CORE_READ(old, rq_disk);
+	else
+		disk = BPF_CORE_READ(rq, q, disk);
+	if (!disk)
+		return 0;
+	return BPF_CORE_READ(disk, major) << 20 | BPF_CORE_READ(disk, first_minor);
+}
+
+static __always_inline int trace_issue(struct request *rq)
+{
+	__u64 key = (__u64)rq;
+	__u64 ts = bpf_ktime_get_ns();
+
+	bpf_map_update_elem(&start, &key, &ts, BPF_ANY);
+	return 0;
+}
+
+SEC("tp_btf/block_rq_issue")
+int BPF_PROG(block_rq_issue, struct request *rq)
+{
+	return trace_issue(rq);
+}
+
+/* block_rq_issue_q is block_rq_issue for kernels before 5.11, whose tracepoint also passed the queue. */
+SEC("tp_btf/block_rq_issue")
+int BPF_PROG(block_rq_issue_q, struct request_queue *q, struct request *rq)
+{
+	return trace_issue(rq);
+}
+
+SEC("tp_btf/block_rq_complete")
+int BPF_PROG(block_rq_complete, struct request *rq)
+{
+	__u64 now = bpf_ktime_get_ns();
+	__u64 key = (__u64)rq;
+	struct dev_bucket bucket = {};
+	__u64 *ts, *total, *count;
+	__u64 delta, zero = 0;
+
+	ts = bpf_map_lookup_elem(&start, &key);
+	if (!ts)
+		return 0;
+	delta = now - *ts;
+	bpf_map_delete_elem(&start, &key);
+
+	bucket.dev = request_dev(rq);
+	total = lookup_or_init(&totals, &bucket.dev, &zero);
+	if (!total)
+		return 0;
+	__sync_fetch_and_add(total, delta);
+	bucket.bucket = log2_bucket(delta);
+	count = lookup_or_init(&hist, &bucket, &zero);
+	if (count)
+		__sync_fetch_and_add(count, 1);
+	return 0;
+}
@@ -0,0 +1,59 @@
+/* SPDX-License-Identifier: GPL-2.0-only */
+/* Helpers shared by the perfprofiler probes. */
+#ifndef __SREDIAG_COMMON_H__
+#define __SREDIAG_COMMON_H__
+
+/* TASK_UNINTERRUPTIBLE in task_struct->__state. */
+#define TASK_UNINTERRUPTIBLE 2
+
+/* MAX_ENTRIES bounds the per-thread, per-request and per-destination maps. */
+#define MAX_ENTRIES 10240
+
+/* HIST_BUCKETS is the size of a Log2Histogram (see ebpf.go). */
+#define HIST_BUCKETS 64
+
+/* lookup_or_init returns map[key], inserting init first; NULL once the map is full. */
+static __always_inline void *lookup_or_init(void *map, const void *key, const void *init)
+{
+	void *val = bpf_map_lookup_elem(map, key);
+
+	if (val)
+		return val;
+	bpf_map_update_elem(map, key, init, BPF_NOEXIST);
+	return bpf_map_lookup_elem(map, key);
+}
+
+/* log2_bucket returns the Log2Histogram bucket of a latency in ns: floor(log2(ns / 1000)), 0 below 2 us. */
+static __always_inline __u32 log2_bucket(__u64 ns)
+{
+	__u64 v = ns / 1000;
+	__u32 r, shift;
+
+	r = (v > 0xFFFFFFFF) << 5;
+	v >>= r;
+	shift = (v > 0xFFFF) << 4;
+	v >>= shift;
+	r |= shift;
+	shift = (v > 0xFF) << 3;
+	v >>= shift;
+	r |= shift;
+	shift = (v > 0xF) << 2;
+	v >>= shift;
+	r |= shift;
+	shift = (v > 0x3) << 1;
+	v >>= shift;
+	r |= shift;
+	r |= (v >> 1);
+	return r;
+}
+
+/* hist_increment counts one sample in bucket of a HIST_BUCKETS array map. */
+static __always_inline void hist_increment(void *hist, __u32 bucket)
+{
+	__u64 *count = bpf_map_lookup_elem(hist, &bucket);
+
+	if (count)
+		__sync_fetch_and_add(count, 1);
+}
+
+#endif /* __SREDIAG_COMMON_H__ */
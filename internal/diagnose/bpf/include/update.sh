@@ -0,0 +1,16 @@
+#!/usr/bin/env bash
+# Refreshes the vendored libbpf headers; vmlinux.h is trimmed by hand (see its header).
+
+set -euo pipefail
+
+LIBBPF_VERSION=0.6.1
+
+cd "$(dirname "$0")"
+curl -sSfL "https://github.com/libbpf/libbpf/archive/refs/tags/v${LIBBPF_VERSION}.tar.gz" |
+	tar -xz --xform='s#.*/##' \
+		"libbpf-${LIBBPF_VERSION}/LICENSE.BSD-2-Clause" \
+		"libbpf-${LIBBPF_VERSION}/src/bpf_core_read.h" \
+		"libbpf-${LIBBPF_VERSION}/src/bpf_endian.h" \
+		"libbpf-${LIBBPF_VERSION}/src/bpf_helper_defs.h" \
+		"libbpf-${LIBBPF_VERSION}/src/bpf_helpers.h" \
+		"libbpf-${LIBBPF_VERSION}/src/bpf_tracing.h"
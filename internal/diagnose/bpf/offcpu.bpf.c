@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: GPL-2.0-only
+/* offcpu measures the off-CPU time of every thread, and its log2 histogram, on sched_switch. */
+#include "vmlinux.h"
+#include "bpf_helpers.h"
+#include "bpf_core_read.h"
+#include "bpf_tracing.h"
+#include "common.h"
+
+char LICENSE[] SEC("license") = "GPL";
+
+/* switch_out is when a thread left the CPU and whether it went to uninterruptible sleep. */
+struct switch_out {
+	__u64 ts;
+	__u64 uninterruptible;
+};
+
+/* offcpu_stats is the off-CPU time of one thread. */
+struct offcpu_stats {
+	__u64 total;
+	__u64 switches;
+	__u64 uninterruptible;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, __u32);
+	__type(value, struct switch_out);
+} start SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, __u32);
+	__type(value, struct offcpu_stats);
+} stats SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_ARRAY);
+	__uint(max_entries, HIST_BUCKETS);
+	__type(key, __u32);
+	__type(value, __u64);
+} hist SEC(".maps");
+
+/* task_struct___o is task_struct before 5.14, when __state was called state. */
+struct task_struct___o {
+	long state;
+} __attribute__((preserve_access_index));
+
+static __always_inline __u64 task_state(struct task_struct *task)
+{
+	if (bpf_core_field_exists(task->__state))
+		return BPF_CORE_READ(task, __state);
+	return BPF_CORE_READ((struct task_struct___o *)task, state);
+}
+
+SEC("tp_btf/sched_switch")
+int BPF_PROG(sched_switch, bool preempt, struct task_struct *prev, struct task_struct *next)
+{
+	__u64 now = bpf_ktime_get_ns();
+	struct offcpu_stats zero = {};
+	struct offcpu_stats *stat;
+	struct switch_out *out;
+	__u64 delta, uninterruptible;
+	__u32 tid;
+
+	/* Switch-out of prev; the idle task (pid 0) is ignored. */
+	tid = BPF_CORE_READ(prev, pid);
+	if (tid) {
+		struct switch_out prev_out = {
+			.ts = now,
+			.uninterruptible = task_state(prev) & TASK_UNINTERRUPTIBLE,
+		};
+
+		bpf_map_update_elem(&start, &tid, &prev_out, BPF_ANY);
+	}
+
+	/* Switch-in of next. */
+	tid = BPF_CORE_READ(next, pid);
+	if (!tid)
+		return 0;
+	out = bpf_map_lookup_elem(&start, &tid);
+	if (!out)
+		return 0;
+	delta = now - out->ts;
+	uninterruptible = out->uninterruptible;
+	bpf_map_delete_elem(&start, &tid);
+
+	stat = lookup_or_init(&stats, &tid, &zero);
+	if (!stat)
+		return 0;
+	__sync_fetch_and_add(&stat->total, delta);
+	__sync_fetch_and_add(&stat->switches, 1);
+	if (uninterruptible)
+		__sync_fetch_and_add(&stat->uninterruptible, delta);
+	hist_increment(&hist, log2_bucket(delta));
+	return 0;
+}
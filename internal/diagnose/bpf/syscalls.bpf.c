@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: GPL-2.0-only
+/* syscalls counts syscalls per process and syscall number on sys_enter. */
+#include "vmlinux.h"
+#include "bpf_helpers.h"
+#include "bpf_core_read.h"
+#include "bpf_tracing.h"
+#include "common.h"
+
+char LICENSE[] SEC("license") = "GPL";
+
+/* syscall_key is one syscall number of one process. */
+struct syscall_key {
+	__u32 tgid;
+	__u32 nr;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct syscall_key);
+	__type(value, __u64);
+} counts SEC(".maps");
+
+SEC("tp_btf/sys_enter")
+int BPF_PROG(sys_enter, struct pt_regs *regs, long id)
+{
+	struct syscall_key key = {
+		.tgid = bpf_get_current_pid_tgid() >> 32,
+		.nr = id,
+	};
+	__u64 *count, zero = 0;
+
+	count = lookup_or_init(&counts, &key, &zero);
+	if (count)
+		__sync_fetch_and_add(count, 1);
+	return 0;
+}
@@ -0,0 +1,29 @@
+/* SPDX-License-Identifier: GPL-2.0-only */
+/* TCP destination keys shared by the tcpretrans and tcpconnect probes. */
+#ifndef __SREDIAG_TCP_H__
+#define __SREDIAG_TCP_H__
+
+/* tcp_key is a TCP destination: the IPv6 (or v4-mapped) address and the port in host order. */
+struct tcp_key {
+	__u8 addr[16];
+	__u16 port;
+	__u8 pad[6];
+};
+
+/* tcp_destination fills key with the peer of sk, mapping IPv4 peers to ::ffff:a.b.c.d like the tcp tracepoints. */
+static __always_inline void tcp_destination(const struct sock *sk, struct tcp_key *key)
+{
+	__builtin_memset(key, 0, sizeof(*key));
+	if (BPF_CORE_READ(sk, __sk_common.skc_family) == AF_INET6) {
+		BPF_CORE_READ_INTO(&key->addr, sk, __sk_common.skc_v6_daddr.in6_u.u6_addr8);
+	} else {
+		__be32 daddr = BPF_CORE_READ(sk, __sk_common.skc_daddr);
+
+		key->addr[10] = 0xff;
+		key->addr[11] = 0xff;
+		__builtin_memcpy(&key->addr[12], &daddr, sizeof(daddr));
+	}
+	key->port = bpf_ntohs(BPF_CORE_READ(sk, __sk_common.skc_dport));
+}
+
+#endif /* __SREDIAG_TCP_H__ */
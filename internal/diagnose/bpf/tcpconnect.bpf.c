@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: GPL-2.0-only
+/* tcpconnect measures SYN_SENT -> ESTABLISHED latency, and failed connects, per destination on inet_sock_set_state. */
+#include "vmlinux.h"
+#include "bpf_helpers.h"
+#include "bpf_core_read.h"
+#include "bpf_endian.h"
+#include "bpf_tracing.h"
+#include "common.h"
+#include "tcp.h"
+
+char LICENSE[] SEC("license") = "GPL";
+
+/* connect_stats aggregates the connects towards one destination. */
+struct connect_stats {
+	__u64 connects;
+	__u64 total;
+	__u64 failures;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, __u64); /* struct sock * */
+	__type(value, __u64);
+} start SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct tcp_key);
+	__type(value, struct connect_stats);
+} dests SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_ARRAY);
+	__uint(max_entries, HIST_BUCKETS);
+	__type(key, __u32);
+	__type(value, __u64);
+} hist SEC(".maps");
+
+SEC("tp_btf/inet_sock_set_state")
+int BPF_PROG(inet_sock_set_state, const struct sock *sk, const int oldstate, const int newstate)
+{
+	__u64 key = (__u64)sk;
+	struct connect_stats zero = {};
+	struct connect_stats *stat;
+	struct tcp_key dest;
+	__u64 *ts, delta, now;
+
+	/* sk_protocol is a bitfield before 5.6. */
+	if (BPF_CORE_READ_BITFIELD_PROBED(sk, sk_protocol) != IPPROTO_TCP)
+		return 0;
+	now = bpf_ktime_get_ns();
+	if (newstate == TCP_SYN_SENT) {
+		bpf_map_update_elem(&start, &key, &now, BPF_ANY);
+		return 0;
+	}
+	if (oldstate != TCP_SYN_SENT)
+		return 0;
+	ts = bpf_map_lookup_elem(&start, &key);
+	if (!ts)
+		return 0;
+	delta = now - *ts;
+	bpf_map_delete_elem(&start, &key);
+
+	tcp_destination(sk, &dest);
+	stat = lookup_or_init(&dests, &dest, &zero);
+	if (!stat)
+		return 0;
+	if (newstate != TCP_ESTABLISHED) {
+		__sync_fetch_and_add(&stat->failures, 1);
+		return 0;
+	}
+	__sync_fetch_and_add(&stat->connects, 1);
+	__sync_fetch_and_add(&stat->total, delta);
+	hist_increment(&hist, log2_bucket(delta));
+	return 0;
+}
@@ -0,0 +1,31 @@
+// SPDX-License-Identifier: GPL-2.0-only
+/* tcpretrans counts TCP retransmits per destination on tcp_retransmit_skb. */
+#include "vmlinux.h"
+#include "bpf_helpers.h"
+#include "bpf_core_read.h"
+#include "bpf_endian.h"
+#include "bpf_tracing.h"
+#include "common.h"
+#include "tcp.h"
+
+char LICENSE[] SEC("license") = "GPL";
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct tcp_key);
+	__type(value, __u64);
+} counts SEC(".maps");
+
+SEC("tp_btf/tcp_retransmit_skb")
+int BPF_PROG(tcp_retransmit_skb, const struct sock *sk, const struct sk_buff *skb)
+{
+	struct tcp_key key;
+	__u64 *count, zero = 0;
+
+	tcp_destination(sk, &key);
+	count = lookup_or_init(&counts, &key, &zero);
+	if (count)
+		__sync_fetch_and_add(count, 1);
+	return 0;
+}
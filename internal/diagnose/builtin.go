@@ -1,2 +1,94 @@
-// TODO: Implement built-in diagnostic plugins: systemsnapshot, perfprofiler, and cisbaseline (see docs/architecture/diagnose.md §5)
 package diagnose
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file adapts the diagnostics compiled into the binary (system, performance, security, kubernetes, iac,
+// network, logs, containers, bundle, diff, replay) to the DiagPlugin contract, so they are attached the same way as
+// installed plugins.
+//
+// Usage:
+//   - The CLI builds its Cobra commands and wraps them with NewBuiltinPlugin.
+//
+// TODO: Move systemsnapshot, perfprofiler, and cisbaseline collectors behind their plugin IDs (see docs/architecture/diagnose.md §5)
+
+// Built-in plugin IDs and capabilities (docs/architecture/diagnose.md §5).
+const (
+	BuiltinSystemSnapshot = "systemsnapshot"
+	BuiltinPerfProfiler   = "perfprofiler"
+	BuiltinCISBaseline    = "cisbaseline"
+	BuiltinKubernetes     = "k8sclusterdiagnostics"
+	BuiltinIaC            = "iacanalyzer"
+	BuiltinNetwork        = "netdiag"
+	BuiltinLogs           = "loganalyzer"
+	BuiltinContainers     = "containerdiag"
+	BuiltinCloud          = "cloudmeta"
+	BuiltinSupportBundle  = "supportbundle"
+	BuiltinBaselineDiff   = "baselinediff"
+	BuiltinWatchReplay    = "watchreplay"
+
+	CapabilitySystem     = "diag/system"
+	CapabilityPerf       = "diag/perf"
+	CapabilitySecurity   = "diag/security"
+	CapabilityKubernetes = "diag/k8s"
+	CapabilityIaC        = "diag/iac"
+	CapabilityNetwork    = "diag/network"
+	CapabilityLogs       = "diag/logs"
+	CapabilityContainers = "diag/containers"
+	CapabilityCloud      = "diag/cloud"
+	CapabilityBundle     = "diag/bundle"
+	CapabilityBaseline   = "diag/baseline"
+	CapabilityReplay     = "diag/replay"
+)
+
+// builtinPlugin is a DiagPlugin backed by Cobra commands compiled into the binary.
+type builtinPlugin struct {
+	info         PluginInfo
+	capabilities []string
+	commands     []*cobra.Command
+}
+
+// NewBuiltinPlugin wraps compiled-in Cobra commands as a DiagPlugin.
+//
+// Parameters:
+//   - name: Plugin ID (e.g., BuiltinSystemSnapshot).
+//   - description: Human-readable summary.
+//   - capabilities: RBAC capabilities the commands exercise.
+//   - commands: Commands to register under the plugin's branch.
+//
+// Returns:
+//   - DiagPlugin: The built-in plugin, versioned with the binary.
+func NewBuiltinPlugin(name, description string, capabilities []string, commands ...*cobra.Command) DiagPlugin {
+	return &builtinPlugin{
+		info: PluginInfo{
+			Name:        name,
+			Version:     core.DefaultBuildInfo.Version,
+			Description: description,
+			Source:      PluginSourceBuiltin,
+		},
+		capabilities: capabilities,
+		commands:     commands,
+	}
+}
+
+// Info returns the plugin identity.
+func (p *builtinPlugin) Info() PluginInfo { return p.info }
+
+// Register adds the compiled-in commands to the private branch.
+func (p *builtinPlugin) Register(branch *cobra.Command) error {
+	branch.AddCommand(p.commands...)
+	return nil
+}
+
+// Capabilities returns the RBAC capabilities of the plugin.
+func (p *builtinPlugin) Capabilities() []string { return p.capabilities }
+
+// Health always succeeds: built-in plugins ship with the binary.
+func (p *builtinPlugin) Health(_ context.Context) error { return nil }
@@ -0,0 +1,630 @@
+package diagnose
+
+import (
+	"archive/tar"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/srediag/srediag/internal/admin"
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file implements the support bundle writer behind 'srediag diagnose bundle': collected entries are redacted,
+// filtered, size-limited and written to a timestamped .tar.gz together with a SHA-256 manifest.
+//
+// Usage:
+//   - CLI_Bundle gathers BundleEntry values with collectBundle and writes them with WriteBundleFile.
+//   - Build the Redactor with NewRedactor; configured patterns are added to DefaultRedactPatterns.
+//
+// Best Practices:
+//   - Mark every human-readable entry as Text so it is redacted; only binary profiles skip redaction.
+//   - Order entries by importance: once the total size limit is hit, later entries are skipped.
+
+// BundleManifestName is the path of the manifest inside the bundle.
+const BundleManifestName = "manifest.json"
+
+// redactedValue replaces secrets in bundle files.
+const redactedValue = "[REDACTED]"
+
+// DefaultRedactPatterns are always applied to text entries. When a pattern has a group named "value", only that
+// group is replaced; otherwise the whole match is.
+var DefaultRedactPatterns = []string{
+	`(?i)[\w.-]*(?:password|passwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key|credentials?)[\w.-]*"?\s*[:=]\s*"?(?P<value>[^\s"',}]+)`,
+	`(?i)\b(?:bearer|basic)\s+(?P<value>[A-Za-z0-9._~+/=-]{8,})`,
+	`(?i)\b[a-z][a-z0-9+.-]*://[^/\s:@]+:(?P<value>[^@\s/]+)@`,
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`,
+}
+
+// Redactor masks secrets in bundle files.
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor compiles DefaultRedactPatterns plus extra patterns.
+//
+// Parameters:
+//   - extra: Additional regular expressions (e.g., from diagnostics.bundle.redact_patterns).
+//
+// Returns:
+//   - *Redactor: The compiled redactor.
+//   - error: If a pattern does not compile, returns a detailed error.
+func NewRedactor(extra []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, p := range append(append([]string(nil), DefaultRedactPatterns...), extra...) {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// Redact returns data with every secret replaced by "[REDACTED]".
+func (r *Redactor) Redact(data []byte) []byte {
+	for _, re := range r.patterns {
+		group := re.SubexpIndex("value")
+		if group < 0 {
+			data = re.ReplaceAll(data, []byte(redactedValue))
+			continue
+		}
+		var out bytes.Buffer
+		last := 0
+		for _, m := range re.FindAllSubmatchIndex(data, -1) {
+			start, end := m[2*group], m[2*group+1]
+			if start < 0 {
+				continue
+			}
+			out.Write(data[last:start])
+			out.WriteString(redactedValue)
+			last = end
+		}
+		out.Write(data[last:])
+		data = out.Bytes()
+	}
+	return data
+}
+
+// BundleEntry is a file to place in the support bundle.
+//
+// Fields:
+//   - Path: Slash-separated path inside the bundle (e.g., "config/srediag.yaml").
+//   - Data: File contents.
+//   - Text: Redact the contents before writing.
+//   - Tail: When larger than the per-file limit, keep the end of the file (logs) instead of skipping it.
+type BundleEntry struct {
+	Path string
+	Data []byte
+	Text bool
+	Tail bool
+}
+
+// BundleOptions controls which entries end up in the bundle.
+//
+// Fields:
+//   - Include: Glob patterns (path.Match) or directories (e.g., "logs"); when set, only matching paths are kept.
+//   - Exclude: Glob patterns of paths to drop; evaluated after Include.
+//   - MaxFileSize: Per-file limit in bytes; zero disables it.
+//   - MaxTotalSize: Limit on the sum of file sizes in bytes (before compression); zero disables it.
+//   - Redactor: Redactor for Text entries; nil uses DefaultRedactPatterns.
+type BundleOptions struct {
+	Include      []string
+	Exclude      []string
+	MaxFileSize  int64
+	MaxTotalSize int64
+	Redactor     *Redactor
+}
+
+// BundleManifest lists the contents of a support bundle.
+//
+// Fields:
+//   - CreatedAt: Time the bundle was written.
+//   - Host: Hostname of the machine.
+//   - Version: srediag version.
+//   - Files: Files in the bundle with their SHA-256 digests.
+//   - Skipped: Entries left out because of filters or size limits.
+//   - Agent: The running agent whose profiles were collected, or why there are none.
+type BundleManifest struct {
+	CreatedAt time.Time       `json:"created_at"`
+	Host      string          `json:"host"`
+	Version   string          `json:"version"`
+	Files     []BundleFile    `json:"files"`
+	Skipped   []BundleSkipped `json:"skipped,omitempty"`
+	Agent     *BundleAgent    `json:"agent,omitempty"`
+}
+
+// BundleAgent records whether the runtime profiles of the agent could be fetched from its admin socket.
+type BundleAgent struct {
+	Socket  string `json:"socket"`
+	Running bool   `json:"running"`
+	Error   string `json:"error,omitempty"`
+}
+
+// BundleFile describes one file of the bundle.
+type BundleFile struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	SHA256    string `json:"sha256"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// BundleSkipped records an entry that was not written and why.
+type BundleSkipped struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// WriteBundle writes entries and the manifest as a gzip-compressed tarball.
+//
+// Parameters:
+//   - w: Destination.
+//   - root: Top-level directory name inside the tarball.
+//   - entries: Entries in priority order.
+//   - opts: Filters, size limits and redaction.
+//   - manifest: Manifest to fill; CreatedAt, Host and Version are taken as given.
+//
+// Returns:
+//   - error: If writing fails, returns a detailed error.
+func WriteBundle(w io.Writer, root string, entries []BundleEntry, opts BundleOptions, manifest *BundleManifest) error {
+	redactor := opts.Redactor
+	if redactor == nil {
+		var err error
+		if redactor, err = NewRedactor(nil); err != nil {
+			return err
+		}
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	manifest.Files = []BundleFile{}
+	var total int64
+	for _, e := range entries {
+		if reason := filterBundlePath(e.Path, opts); reason != "" {
+			manifest.Skipped = append(manifest.Skipped, BundleSkipped{Path: e.Path, Reason: reason})
+			continue
+		}
+		data := e.Data
+		if e.Text {
+			data = redactor.Redact(data)
+		}
+		truncated := false
+		if opts.MaxFileSize > 0 && int64(len(data)) > opts.MaxFileSize {
+			if !e.Tail {
+				manifest.Skipped = append(manifest.Skipped, BundleSkipped{Path: e.Path,
+					Reason: fmt.Sprintf("size %d exceeds max_file_size %d", len(data), opts.MaxFileSize)})
+				continue
+			}
+			data = tailBytes(data, opts.MaxFileSize)
+			truncated = true
+		}
+		if opts.MaxTotalSize > 0 && total+int64(len(data)) > opts.MaxTotalSize {
+			manifest.Skipped = append(manifest.Skipped, BundleSkipped{Path: e.Path,
+				Reason: fmt.Sprintf("max_size %d reached", opts.MaxTotalSize)})
+			continue
+		}
+		if err := writeTarFile(tw, path.Join(root, e.Path), data, manifest.CreatedAt); err != nil {
+			return err
+		}
+		total += int64(len(data))
+		sum := sha256.Sum256(data)
+		manifest.Files = append(manifest.Files, BundleFile{Path: e.Path, Size: int64(len(data)),
+			SHA256: hex.EncodeToString(sum[:]), Truncated: truncated})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle manifest: %w", err)
+	}
+	if err := writeTarFile(tw, path.Join(root, BundleManifestName), append(data, '\n'), manifest.CreatedAt); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finish bundle archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to finish bundle compression: %w", err)
+	}
+	return nil
+}
+
+// WriteBundleFile writes a bundle to dir/<name>.tar.gz atomically with 0600 permissions.
+//
+// Parameters:
+//   - dir: Output directory (created if missing).
+//   - name: Bundle name, used for the file and the top-level directory.
+//   - entries, opts, manifest: See WriteBundle.
+//
+// Returns:
+//   - string: Path of the written bundle.
+//   - error: If writing fails, returns a detailed error.
+func WriteBundleFile(dir, name string, entries []BundleEntry, opts BundleOptions, manifest *BundleManifest) (string, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+name+".*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if err := WriteBundle(tmp, name, entries, opts, manifest); err != nil {
+		_ = tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write bundle: %w", err)
+	}
+	dest := filepath.Join(dir, name+".tar.gz")
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return "", fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return dest, nil
+}
+
+// BundleName returns the timestamped bundle name "srediag-bundle-<host>-<UTC time>".
+func BundleName(host string, t time.Time) string {
+	safe := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, host)
+	return fmt.Sprintf("srediag-bundle-%s-%s", safe, t.UTC().Format("20060102T150405Z"))
+}
+
+// filterBundlePath returns why p is excluded by the include/exclude filters, or "" if it is kept.
+func filterBundlePath(p string, opts BundleOptions) string {
+	if len(opts.Include) > 0 && !matchAnyBundlePattern(p, opts.Include) {
+		return "not matched by include filters"
+	}
+	if matchAnyBundlePattern(p, opts.Exclude) {
+		return "matched by exclude filters"
+	}
+	return ""
+}
+
+// matchAnyBundlePattern reports whether p matches a glob pattern or lies under a directory pattern.
+func matchAnyBundlePattern(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		dir := strings.TrimSuffix(pattern, "/")
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// tailBytes keeps the last limit bytes of data, starting at a line boundary when possible.
+func tailBytes(data []byte, limit int64) []byte {
+	out := data[int64(len(data))-limit:]
+	if i := bytes.IndexByte(out, '\n'); i >= 0 && i < len(out)-1 {
+		out = out[i+1:]
+	}
+	return out
+}
+
+// writeTarFile adds a regular file to the archive.
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: modTime, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to add %s to bundle: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to add %s to bundle: %w", name, err)
+	}
+	return nil
+}
+
+// ParseByteSize parses sizes such as "512", "10KB", "5MiB" or "1GB" into bytes.
+//
+// Parameters:
+//   - s: Size with an optional B, KB/KiB, MB/MiB or GB/GiB suffix (case-insensitive); empty means 0.
+//
+// Returns:
+//   - int64: Size in bytes.
+//   - error: If the size is malformed or negative, returns a detailed error.
+func ParseByteSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	upper := strings.ToUpper(s)
+	units := []struct {
+		suffix string
+		factor int64
+	}{
+		{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+		{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000},
+		{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"B", 1},
+	}
+	factor := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(upper, u.suffix) {
+			upper = strings.TrimSpace(strings.TrimSuffix(upper, u.suffix))
+			factor = u.factor
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(upper, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(factor)), nil
+}
+
+// DefaultBundleDiagnostics are the diagnostics bundled when neither flags nor diagnostics.bundle name any.
+var DefaultBundleDiagnostics = []string{"system", "performance", "security"}
+
+// DefaultBundleLogFiles returns the log files bundled when neither flags nor diagnostics.bundle name any: the
+// *.log files next to the agent's log file (service.log_file, by default srediag.log in the default log directory).
+//
+// Parameters:
+//   - cfg: Effective agent config.
+//
+// Returns:
+//   - []string: Glob patterns.
+func DefaultBundleLogFiles(cfg *core.Config) []string {
+	dir := core.DefaultLogDir()
+	if cfg.Service.LogFile != "" {
+		dir = filepath.Dir(cfg.Service.LogFile)
+	}
+	return []string{filepath.Join(dir, "*.log")}
+}
+
+// bundleAgentProfiles are the profiles of the running agent fetched into runtime/ over its admin API.
+var bundleAgentProfiles = []string{"goroutine", "heap"}
+
+// Default bundle size limits ("0" disables a limit).
+const (
+	DefaultBundleMaxSize     = "100MiB"
+	DefaultBundleMaxFileSize = "10MiB"
+)
+
+// bundlePluginState is one diagnostic plugin in plugins/states.json.
+type bundlePluginState struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version,omitempty"`
+	Source   string   `json:"source"`
+	Commands []string `json:"commands,omitempty"`
+	Health   string   `json:"health"`
+}
+
+// collectBundle gathers the support bundle entries in priority order.
+//
+// Parameters:
+//   - ctx: Application context (effective config, build info).
+//   - cmd: The 'bundle' command; its root is walked for attached diagnostic plugins.
+//   - mgr: Manager used to run the configured diagnostics.
+//   - diagnostics: Diagnostic names to run (keys of namedDiagnostics).
+//   - logFiles: Log file paths or glob patterns.
+//   - maxFileSize: Per-file limit; logs are read from the end up to this size (0 reads whole files).
+//
+// Returns:
+//   - []BundleEntry: Entries for WriteBundle.
+//   - *BundleAgent: Whether the agent's profiles were collected, for the manifest.
+func collectBundle(ctx *core.AppContext, cmd *cobra.Command, mgr *DiagnoseManager, diagnostics, logFiles []string, maxFileSize int64) ([]BundleEntry, *BundleAgent) {
+	runCtx := commandContext(cmd)
+	var entries []BundleEntry
+	addJSON := func(p string, v interface{}) {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			data = []byte(fmt.Sprintf("failed to encode %s: %v\n", p, err))
+		}
+		entries = append(entries, BundleEntry{Path: p, Data: append(data, '\n'), Text: true})
+	}
+
+	addJSON("versions.json", bundleVersions(ctx))
+	if data, err := yaml.Marshal(ctx.GetConfig()); err == nil {
+		entries = append(entries, BundleEntry{Path: "config/srediag.yaml", Data: data, Text: true})
+	} else {
+		entries = append(entries, BundleEntry{Path: "config/error.txt", Data: []byte(err.Error() + "\n"), Text: true})
+	}
+	addJSON("plugins/states.json", bundlePluginStates(runCtx, ctx, cmd))
+
+	for _, name := range diagnostics {
+		report, err := namedDiagnostics[name](mgr, runCtx)
+		if err != nil {
+			entries = append(entries, BundleEntry{Path: "diagnostics/" + name + ".error.txt", Data: []byte(err.Error() + "\n"), Text: true})
+			continue
+		}
+		addJSON("diagnostics/"+name+".json", report)
+	}
+
+	profiles, agent := collectAgentProfiles(runCtx, ctx.GetConfig())
+	entries = append(entries, profiles...)
+
+	seen := map[string]int{}
+	for _, pattern := range logFiles {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			continue
+		}
+		for _, file := range matches {
+			data, err := readTail(file, maxFileSize)
+			if err != nil {
+				continue
+			}
+			name := filepath.Base(file)
+			if n := seen[name]; n > 0 {
+				name = fmt.Sprintf("%d-%s", n, name)
+			}
+			seen[filepath.Base(file)]++
+			entries = append(entries, BundleEntry{Path: "logs/" + name, Data: data, Text: true, Tail: true})
+		}
+	}
+	return entries, agent
+}
+
+// collectAgentProfiles fetches bundleAgentProfiles from the admin API of the running agent (service.socket); the
+// bundle command itself is not what is being diagnosed.
+func collectAgentProfiles(ctx context.Context, cfg *core.Config) ([]BundleEntry, *BundleAgent) {
+	socket := cmp.Or(cfg.Service.Socket, core.DefaultAdminSocket())
+	agent := &BundleAgent{Socket: socket}
+	client := admin.NewClient(socket)
+
+	var entries []BundleEntry
+	var errs []string
+	for _, kind := range bundleAgentProfiles {
+		data, err := fetchAgentProfile(ctx, client, kind)
+		if errors.Is(err, admin.ErrNotRunning) {
+			agent.Error = "agent not running"
+			return nil, agent
+		}
+		agent.Running = true
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		entries = append(entries, BundleEntry{Path: "runtime/" + kind + ".pb.gz", Data: data})
+	}
+	agent.Error = strings.Join(errs, "; ")
+	return entries, agent
+}
+
+// fetchAgentProfile returns one profile from GET /v1/profile/{kind} of the agent's admin API.
+func fetchAgentProfile(ctx context.Context, client *admin.Client, kind string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, admin.RequestTimeout)
+	defer cancel()
+	path := "/v1/profile/" + kind
+	resp, err := client.Send(ctx, http.MethodGet, path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, admin.StatusError(http.MethodGet, path, resp)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s profile: %w", kind, err)
+	}
+	return data, nil
+}
+
+// bundleVersions describes the binary, Go runtime and linked modules.
+func bundleVersions(ctx *core.AppContext) map[string]interface{} {
+	bi := ctx.BuildInfo
+	if bi.Version == "" {
+		bi = core.DefaultBuildInfo
+	}
+	versions := map[string]interface{}{
+		"srediag": map[string]string{"version": bi.Version, "commit": bi.Commit, "date": bi.Date},
+		"go":      runtime.Version(),
+		"os":      runtime.GOOS,
+		"arch":    runtime.GOARCH,
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		modules := map[string]string{}
+		for _, dep := range info.Deps {
+			modules[dep.Path] = dep.Version
+		}
+		versions["modules"] = modules
+	}
+	return versions
+}
+
+// bundlePluginStates reports attached diagnostic plugins (with installed plugin health) and collector plugin settings.
+func bundlePluginStates(runCtx context.Context, ctx *core.AppContext, cmd *cobra.Command) map[string]interface{} {
+	cfg := ctx.GetConfig()
+	byName := map[string]*bundlePluginState{}
+	walkCommands(cmd.Root(), func(c *cobra.Command) {
+		owner, ok := c.Annotations[pluginAnnotation]
+		if !ok || !c.Runnable() {
+			return
+		}
+		st, ok := byName[owner]
+		if !ok {
+			st = &bundlePluginState{Name: owner, Version: core.DefaultBuildInfo.Version, Source: PluginSourceBuiltin, Health: "ok"}
+			byName[owner] = st
+		}
+		st.Commands = append(st.Commands, c.Name())
+	})
+
+	pluginsDir := cfg.Plugins.Dir
+	if pluginsDir == "" {
+		pluginsDir = core.DefaultPluginDir()
+	}
+	installed, errs := DiscoverInstalledPlugins(pluginsDir)
+	for _, p := range installed {
+		info := p.Info()
+		st, ok := byName[info.Name]
+		if !ok {
+			st = &bundlePluginState{Name: info.Name}
+			byName[info.Name] = st
+		}
+		st.Version, st.Source, st.Health = info.Version, info.Source, "ok"
+		if err := p.Health(runCtx); err != nil {
+			st.Health = err.Error()
+		}
+	}
+
+	states := make([]*bundlePluginState, 0, len(byName))
+	for _, st := range byName {
+		sort.Strings(st.Commands)
+		states = append(states, st)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	discoveryErrors := make([]string, 0, len(errs))
+	for _, err := range errs {
+		discoveryErrors = append(discoveryErrors, err.Error())
+	}
+	return map[string]interface{}{
+		"diagnostic":       states,
+		"discovery_errors": discoveryErrors,
+		"collector": map[string]interface{}{
+			"dir":      cfg.Plugins.Dir,
+			"exec_dir": cfg.Plugins.ExecDir,
+			"enabled":  cfg.Plugins.Enabled,
+		},
+	}
+}
+
+// readTail reads at most limit bytes from the end of a file (the whole file when limit is 0).
+func readTail(file string, limit int64) ([]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", file)
+	}
+	if limit > 0 && info.Size() > limit {
+		// Read one extra byte so WriteBundle still sees the file as oversized and marks it truncated.
+		if _, err := f.Seek(info.Size()-limit-1, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	return io.ReadAll(f)
+}
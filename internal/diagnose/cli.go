@@ -1,19 +1,23 @@
 package diagnose
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/kubernetes"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
 
 	"github.com/srediag/srediag/internal/core"
 )
 
 // TODO(D-01 Phase 3): Implement system diagnostics plugin for CPU, memory, IO, and network metrics (see TODO.md D-01, Phase 3)
-// TODO(D-02 Phase 3): Implement Kubernetes diagnostics plugin for cluster, node, and pod health (see TODO.md D-02, Phase 3)
-// TODO(D-03 Phase 5): Implement cloud provider diagnostic stubs for AWS, Azure, and GCP (see TODO.md D-03, Phase 5)
-// TODO(D-04 Phase 5): Implement Infrastructure-as-Code analyzers for Terraform, K8s manifests, and Helm charts (see TODO.md D-04, Phase 5)
-// TODO: Add support for context.Context to all CLI functions for cancellation and timeouts.
-// TODO: Refactor to reduce repeated logger fallback logic in CLI functions.
 
 // Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
 //
@@ -23,14 +27,23 @@ import (
 //   - Use these CLI functions as entrypoints for 'srediag diagnose' subcommands.
 //   - Each function extracts parameters from the CLI context, instantiates the DiagnoseManager, and delegates to the appropriate method.
 //
+//   - The command context (cmd.Context()) is passed to the manager, which applies --timeout and max_retries.
+//   - finishReport renders the report, saves it with --save-baseline and, with --fail-on, returns a
+//     *FindingsError (exit code 6).
+//   - With --watch, runOrWatch re-runs the diagnostic until Ctrl-C or --for elapses (see watch.go).
+//
 // Best Practices:
 //   - Always validate required flags and parameters before calling DiagnoseManager methods.
 //   - Log all errors and important events for traceability.
 //   - Use context-aware logging and error handling for better diagnostics.
-//
-// TODO:
-//   - Add context.Context support for cancellation and timeouts.
-//   - Refactor to reduce repeated logger fallback logic.
+
+// newContainerRuntimeFunc connects to the container runtime; patchable for tests.
+var newContainerRuntimeFunc = NewContainerRuntime
+
+// newKubernetesClientsFunc builds Kubernetes clients; patchable for tests.
+var newKubernetesClientsFunc = func(kubeconfig, kubeContext string) (kubernetes.Interface, metricsclient.Interface, error) {
+	return NewKubernetesClients(kubeconfig, kubeContext)
+}
 
 // CLI_SystemDiagnostics is the entrypoint for 'srediag diagnose system'.
 //
@@ -42,48 +55,80 @@ import (
 // Returns:
 //   - error: If system diagnostics fail, returns a detailed error.
 func CLI_SystemDiagnostics(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
-	logger := ctx.Logger
-	if logger == nil {
-		var err error
-		logger, err = core.NewLogger(nil)
+	mgr, logger, err := newCLIManager(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	return runOrWatch(ctx, cmd, func(runCtx context.Context) (*Report, error) {
+		report, err := mgr.RunSystem(runCtx)
 		if err != nil {
-			return fmt.Errorf("failed to create fallback logger: %w", err)
+			logger.Error("System diagnostics failed", core.ZapError(err))
+			return nil, fmt.Errorf("system diagnostics failed: %w", err)
 		}
-	}
-	mgr := NewDiagnoseManager(logger)
-	if err := mgr.RunSystem(); err != nil {
-		logger.Error("System diagnostics failed", core.ZapError(err))
-		return fmt.Errorf("system diagnostics failed: %w", err)
-	}
-	logger.Info("System diagnostics completed successfully")
-	return nil
+		logger.Info("System diagnostics completed successfully")
+		return report, nil
+	})
 }
 
 // CLI_PerformanceDiagnostics is the entrypoint for 'srediag diagnose performance'.
 //
 // Parameters:
 //   - ctx: Application context containing logger and configuration.
-//   - cmd: Cobra command instance.
+//   - cmd: Cobra command instance (reads --ebpf, --trace-duration, --probes, --top; each falls back to
+//     diagnostics.performance).
 //   - args: Command-line arguments.
 //
 // Returns:
-//   - error: If performance diagnostics fail, returns a detailed error.
+//   - error: If the options are invalid or performance diagnostics fail, returns a detailed error.
 func CLI_PerformanceDiagnostics(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
-	logger := ctx.Logger
-	if logger == nil {
-		var err error
-		logger, err = core.NewLogger(nil)
+	mgr, logger, err := newCLIManager(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	opts, err := performanceOptions(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	return runOrWatch(ctx, cmd, func(runCtx context.Context) (*Report, error) {
+		report, err := mgr.RunPerformance(runCtx, opts)
 		if err != nil {
-			return fmt.Errorf("failed to create fallback logger: %w", err)
+			logger.Error("Performance diagnostics failed", core.ZapError(err))
+			return nil, fmt.Errorf("performance diagnostics failed: %w", err)
 		}
+		logger.Info("Performance diagnostics completed successfully")
+		return report, nil
+	})
+}
+
+// performanceOptions resolves the eBPF tracing options from flags, then diagnostics.performance.
+func performanceOptions(ctx *core.AppContext, cmd *cobra.Command) (PerformanceOptions, error) {
+	cfg := ctx.GetConfig().Diagnostics.Performance
+	opts := PerformanceOptions{
+		EBPF:   cfg.EBPF,
+		Probes: flagSlice(cmd, "probes", cfg.Probes, nil),
+		Top:    cfg.Top,
 	}
-	mgr := NewDiagnoseManager(logger)
-	if err := mgr.RunPerformance(); err != nil {
-		logger.Error("Performance diagnostics failed", core.ZapError(err))
-		return fmt.Errorf("performance diagnostics failed: %w", err)
+	if f := cmd.Flag("ebpf"); f != nil && f.Changed {
+		opts.EBPF = f.Value.String() == "true"
 	}
-	logger.Info("Performance diagnostics completed successfully")
-	return nil
+	if f := cmd.Flag("top"); f != nil && f.Changed {
+		top, err := strconv.Atoi(f.Value.String())
+		if err != nil {
+			return opts, fmt.Errorf("invalid --top %q: %w", f.Value.String(), err)
+		}
+		opts.Top = top
+	}
+	if d := flagString(cmd, "trace-duration", cfg.Duration, ""); d != "" {
+		v, err := time.ParseDuration(d)
+		if err != nil {
+			return opts, fmt.Errorf("invalid trace duration %q: %w", d, err)
+		}
+		opts.Duration = v
+	}
+	if _, err := selectedProbes(opts.Probes); err != nil {
+		return opts, err
+	}
+	return opts, nil
 }
 
 // CLI_SecurityDiagnostics is the entrypoint for 'srediag diagnose security'.
@@ -96,19 +141,785 @@ func CLI_PerformanceDiagnostics(ctx *core.AppContext, cmd *cobra.Command, args [
 // Returns:
 //   - error: If security diagnostics fail, returns a detailed error.
 func CLI_SecurityDiagnostics(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
+	mgr, logger, err := newCLIManager(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	return runOrWatch(ctx, cmd, func(runCtx context.Context) (*Report, error) {
+		report, err := mgr.RunSecurity(runCtx)
+		if err != nil {
+			logger.Error("Security diagnostics failed", core.ZapError(err))
+			return nil, fmt.Errorf("security diagnostics failed: %w", err)
+		}
+		logger.Info("Security diagnostics completed successfully")
+		return report, nil
+	})
+}
+
+// CLI_KubernetesDiagnostics is the entrypoint for 'srediag diagnose kubernetes'.
+//
+// Parameters:
+//   - ctx: Application context containing logger and configuration.
+//   - cmd: Cobra command instance (reads --kubeconfig, --context, --namespace, --event-window).
+//   - args: Command-line arguments.
+//
+// Returns:
+//   - error: If Kubernetes diagnostics fail, returns a detailed error.
+func CLI_KubernetesDiagnostics(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
+	mgr, logger, err := newCLIManager(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	kubeconfig, _ := cmd.Flags().GetString("kubeconfig")
+	kubeContext, _ := cmd.Flags().GetString("context")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	eventWindow, _ := cmd.Flags().GetDuration("event-window")
+	client, metrics, err := newKubernetesClientsFunc(kubeconfig, kubeContext)
+	if err != nil {
+		logger.Error("Kubernetes client setup failed", core.ZapError(err))
+		return fmt.Errorf("kubernetes diagnostics failed: %w", err)
+	}
+	return runOrWatch(ctx, cmd, func(runCtx context.Context) (*Report, error) {
+		report, err := mgr.RunKubernetes(runCtx, client, metrics, KubernetesOptions{Namespace: namespace, EventWindow: eventWindow})
+		if err != nil {
+			logger.Error("Kubernetes diagnostics failed", core.ZapError(err))
+			return nil, fmt.Errorf("kubernetes diagnostics failed: %w", err)
+		}
+		logger.Info("Kubernetes diagnostics completed successfully")
+		return report, nil
+	})
+}
+
+// CLI_NetworkDiagnostics is the entrypoint for 'srediag diagnose network'.
+//
+// Parameters:
+//   - ctx: Application context containing logger and configuration.
+//   - cmd: Cobra command instance (reads --tcp, --dns, --dns-server, --tls, --http, --probe-timeout, --skip-local;
+//     each falls back to diagnostics.network).
+//   - args: Command-line arguments.
+//
+// Returns:
+//   - error: If the options are invalid or network diagnostics fail, returns a detailed error.
+func CLI_NetworkDiagnostics(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
+	mgr, logger, err := newCLIManager(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	opts, err := networkOptions(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	return runOrWatch(ctx, cmd, func(runCtx context.Context) (*Report, error) {
+		report, err := mgr.RunNetwork(runCtx, opts)
+		if err != nil {
+			logger.Error("Network diagnostics failed", core.ZapError(err))
+			return nil, fmt.Errorf("network diagnostics failed: %w", err)
+		}
+		logger.Info("Network diagnostics completed successfully")
+		return report, nil
+	})
+}
+
+// networkOptions resolves the network probe targets and thresholds from flags, then diagnostics.network.
+func networkOptions(ctx *core.AppContext, cmd *cobra.Command) (NetworkOptions, error) {
+	cfg := ctx.GetConfig().Diagnostics.Network
+	opts := NetworkOptions{
+		TCP:        flagSlice(cmd, "tcp", cfg.TCP, nil),
+		DNSNames:   flagSlice(cmd, "dns", cfg.DNS.Names, nil),
+		DNSServers: flagSlice(cmd, "dns-server", cfg.DNS.Servers, nil),
+		TLS:        flagSlice(cmd, "tls", cfg.TLS, nil),
+		HTTP:       flagSlice(cmd, "http", cfg.HTTP, nil),
+		SkipLocal:  cfg.SkipLocal,
+	}
+	if f := cmd.Flag("skip-local"); f != nil && f.Changed {
+		opts.SkipLocal = f.Value.String() == "true"
+	}
+	durations := []struct {
+		value string
+		key   string
+		dst   *time.Duration
+	}{
+		{flagString(cmd, "probe-timeout", cfg.Timeout, ""), "probe timeout", &opts.Timeout},
+		{cfg.CertExpiryWarning, "diagnostics.network.cert_expiry_warning", &opts.CertExpiryWarning},
+		{cfg.CertExpiryCritical, "diagnostics.network.cert_expiry_critical", &opts.CertExpiryCritical},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s %q: %w", d.key, d.value, err)
+		}
+		*d.dst = v
+	}
+	return opts, nil
+}
+
+// CLI_LogsDiagnostics is the entrypoint for 'srediag diagnose logs'.
+//
+// Parameters:
+//   - ctx: Application context containing logger and configuration.
+//   - cmd: Cobra command instance (reads --file, --journal, --since, --window, --top; each falls back to
+//     diagnostics.logs, which also provides extra patterns).
+//   - args: Command-line arguments.
+//
+// Returns:
+//   - error: If the options are invalid or log analysis fails, returns a detailed error.
+func CLI_LogsDiagnostics(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
+	mgr, logger, err := newCLIManager(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	opts, err := logOptions(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	return runOrWatch(ctx, cmd, func(runCtx context.Context) (*Report, error) {
+		report, err := mgr.RunLogs(runCtx, opts)
+		if err != nil {
+			logger.Error("Log analysis failed", core.ZapError(err))
+			return nil, fmt.Errorf("log analysis failed: %w", err)
+		}
+		logger.Info("Log analysis completed successfully")
+		return report, nil
+	})
+}
+
+// logOptions resolves the log sources, time range and patterns from flags, then diagnostics.logs.
+func logOptions(ctx *core.AppContext, cmd *cobra.Command) (LogOptions, error) {
+	cfg := ctx.GetConfig().Diagnostics.Logs
+	opts := LogOptions{
+		Files:   flagSlice(cmd, "file", cfg.Files, nil),
+		Journal: flagSlice(cmd, "journal", cfg.Journal, nil),
+		Top:     cfg.Top,
+	}
+	if f := cmd.Flag("top"); f != nil && f.Changed {
+		top, err := strconv.Atoi(f.Value.String())
+		if err != nil {
+			return opts, fmt.Errorf("invalid --top %q: %w", f.Value.String(), err)
+		}
+		opts.Top = top
+	}
+	durations := []struct {
+		value string
+		key   string
+		dst   *time.Duration
+	}{
+		{flagString(cmd, "since", cfg.Since, ""), "since", &opts.Since},
+		{flagString(cmd, "window", cfg.Window, ""), "window", &opts.Window},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s %q: %w", d.key, d.value, err)
+		}
+		*d.dst = v
+	}
+	extra := make([]LogPattern, 0, len(cfg.Patterns))
+	for _, p := range cfg.Patterns {
+		sev := SeverityWarning
+		if p.Severity != "" {
+			var err error
+			if sev, err = ParseSeverity(p.Severity); err != nil {
+				return opts, fmt.Errorf("invalid severity of log pattern %q: %w", p.Name, err)
+			}
+		}
+		extra = append(extra, LogPattern{Name: p.Name, Match: p.Match, Severity: sev, Threshold: p.Threshold,
+			Description: p.Description})
+	}
+	opts.Patterns = MergeLogPatterns(DefaultLogPatterns, extra)
+	return opts, nil
+}
+
+// CLI_ContainerDiagnostics is the entrypoint for 'srediag diagnose containers'.
+//
+// Parameters:
+//   - ctx: Application context containing logger and configuration.
+//   - cmd: Cobra command instance (reads --runtime, --socket, --namespace, --image-max-age; each falls back to
+//     diagnostics.containers).
+//   - args: Command-line arguments.
+//
+// Returns:
+//   - error: If the runtime cannot be reached or container diagnostics fail, returns a detailed error.
+func CLI_ContainerDiagnostics(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
+	mgr, logger, err := newCLIManager(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	cfg := ctx.GetConfig().Diagnostics.Containers
+	var opts ContainerOptions
+	if v := flagString(cmd, "image-max-age", cfg.ImageMaxAge, ""); v != "" {
+		if opts.ImageMaxAge, err = time.ParseDuration(v); err != nil {
+			return fmt.Errorf("invalid image max age %q: %w", v, err)
+		}
+	}
+	runtime, err := newContainerRuntimeFunc(flagString(cmd, "runtime", cfg.Runtime, RuntimeAuto),
+		flagString(cmd, "socket", cfg.Socket, ""), flagSlice(cmd, "namespace", cfg.Namespaces, nil))
+	if err != nil {
+		logger.Error("Container runtime setup failed", core.ZapError(err))
+		return fmt.Errorf("container diagnostics failed: %w", err)
+	}
+	defer runtime.Close()
+	return runOrWatch(ctx, cmd, func(runCtx context.Context) (*Report, error) {
+		report, err := mgr.RunContainers(runCtx, runtime, opts)
+		if err != nil {
+			logger.Error("Container diagnostics failed", core.ZapError(err))
+			return nil, fmt.Errorf("container diagnostics failed: %w", err)
+		}
+		logger.Info("Container diagnostics completed successfully")
+		return report, nil
+	})
+}
+
+// CLI_CloudDiagnostics is the entrypoint for 'srediag diagnose cloud'.
+//
+// Parameters:
+//   - ctx: Application context containing logger and configuration.
+//   - cmd: Cobra command instance (reads --provider, --endpoint, --metadata-timeout, --maintenance-warning; each falls
+//     back to diagnostics.cloud).
+//   - args: Command-line arguments.
+//
+// Returns:
+//   - error: If no metadata service answers or cloud diagnostics fail, returns a detailed error.
+func CLI_CloudDiagnostics(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
+	mgr, logger, err := newCLIManager(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	cfg := ctx.GetConfig().Diagnostics.Cloud
+	var opts CloudOptions
+	var timeout time.Duration
+	durations := []struct {
+		value, key string
+		dst        *time.Duration
+	}{
+		{flagString(cmd, "metadata-timeout", cfg.Timeout, ""), "metadata timeout", &timeout},
+		{flagString(cmd, "maintenance-warning", cfg.MaintenanceWarning, ""), "maintenance warning", &opts.MaintenanceWarning},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		if *d.dst, err = time.ParseDuration(d.value); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", d.key, d.value, err)
+		}
+	}
+	endpoint := flagString(cmd, "endpoint", cfg.Endpoint, DefaultCloudEndpoint)
+	var provider CloudProvider
+	if name := flagString(cmd, "provider", cfg.Provider, CloudAuto); name == CloudAuto || name == "" {
+		provider, err = DetectCloudProvider(commandContext(cmd), endpoint, timeout)
+	} else {
+		provider, err = NewCloudProvider(name, endpoint, timeout)
+	}
+	if err != nil {
+		logger.Error("Cloud provider setup failed", core.ZapError(err))
+		return fmt.Errorf("cloud diagnostics failed: %w", err)
+	}
+	return runOrWatch(ctx, cmd, func(runCtx context.Context) (*Report, error) {
+		report, err := mgr.RunCloud(runCtx, provider, opts)
+		if err != nil {
+			logger.Error("Cloud diagnostics failed", core.ZapError(err))
+			return nil, fmt.Errorf("cloud diagnostics failed: %w", err)
+		}
+		logger.Info("Cloud diagnostics completed successfully")
+		return report, nil
+	})
+}
+
+// CLI_IaCDiagnostics is the entrypoint for 'srediag diagnose iac <path>'.
+//
+// Parameters:
+//   - ctx: Application context containing logger and configuration.
+//   - cmd: Cobra command instance.
+//   - args: Command-line arguments; args[0] is the file or directory to analyze.
+//
+// Returns:
+//   - error: If IaC diagnostics fail, returns a detailed error.
+func CLI_IaCDiagnostics(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
+	mgr, logger, err := newCLIManager(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 || args[0] == "" {
+		return fmt.Errorf("a path to analyze is required")
+	}
+	return runOrWatch(ctx, cmd, func(runCtx context.Context) (*Report, error) {
+		report, err := mgr.RunIaC(runCtx, args[0])
+		if err != nil {
+			logger.Error("IaC diagnostics failed", core.ZapError(err))
+			return nil, fmt.Errorf("iac diagnostics failed: %w", err)
+		}
+		logger.Info("IaC diagnostics completed successfully")
+		return report, nil
+	})
+}
+
+// CLI_Bundle is the entrypoint for 'srediag diagnose bundle'.
+//
+// Parameters:
+//   - ctx: Application context containing logger and configuration.
+//   - cmd: Cobra command instance (reads --output-dir, --diagnostics, --log-file, --include, --exclude,
+//     --max-size, --max-file-size, --redact; each falls back to diagnostics.bundle).
+//   - args: Command-line arguments.
+//
+// Returns:
+//   - error: If the bundle cannot be written, returns a detailed error.
+func CLI_Bundle(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
+	mgr, logger, err := newCLIManager(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	cfg := ctx.GetConfig().Diagnostics.Bundle
+	outputDir := flagString(cmd, "output-dir", cfg.OutputDir, ".")
+	diagnostics := flagSlice(cmd, "diagnostics", cfg.Diagnostics, DefaultBundleDiagnostics)
+	logFiles := flagSlice(cmd, "log-file", cfg.LogFiles, DefaultBundleLogFiles(ctx.GetConfig()))
+	for _, name := range diagnostics {
+		if _, ok := namedDiagnostics[name]; !ok {
+			return fmt.Errorf("%w: %q cannot be bundled (supported: system, performance, security, kubernetes)", ErrNotFound, name)
+		}
+	}
+	maxSize, err := ParseByteSize(flagString(cmd, "max-size", cfg.MaxSize, DefaultBundleMaxSize))
+	if err != nil {
+		return fmt.Errorf("invalid bundle max size: %w", err)
+	}
+	maxFileSize, err := ParseByteSize(flagString(cmd, "max-file-size", cfg.MaxFileSize, DefaultBundleMaxFileSize))
+	if err != nil {
+		return fmt.Errorf("invalid bundle max file size: %w", err)
+	}
+	redactor, err := NewRedactor(append(append([]string(nil), cfg.RedactPatterns...), flagSlice(cmd, "redact", nil, nil)...))
+	if err != nil {
+		return err
+	}
+
+	host, _ := os.Hostname()
+	now := time.Now().UTC()
+	manifest := &BundleManifest{CreatedAt: now, Host: host, Version: core.DefaultBuildInfo.Version}
+	entries, agent := collectBundle(ctx, cmd, mgr, diagnostics, logFiles, maxFileSize)
+	manifest.Agent = agent
+	opts := BundleOptions{
+		Include:      flagSlice(cmd, "include", cfg.Include, nil),
+		Exclude:      flagSlice(cmd, "exclude", cfg.Exclude, nil),
+		MaxFileSize:  maxFileSize,
+		MaxTotalSize: maxSize,
+		Redactor:     redactor,
+	}
+	path, err := WriteBundleFile(outputDir, BundleName(host, now), entries, opts, manifest)
+	if err != nil {
+		logger.Error("Support bundle failed", core.ZapError(err))
+		return fmt.Errorf("support bundle failed: %w", err)
+	}
+	logger.Info("Support bundle written", core.ZapString("path", path),
+		core.ZapInt("files", len(manifest.Files)), core.ZapInt("skipped", len(manifest.Skipped)))
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s (%d files, %d skipped)\n", path, len(manifest.Files), len(manifest.Skipped))
+	return err
+}
+
+// CLI_Diff is the entrypoint for 'srediag diagnose diff <baseline> [<report>]'.
+//
+// Each argument is a baseline name in the store (--baseline-dir, diagnostics.baseline.dir) or a report file written
+// with --output json. Without <report>, the baseline's diagnostic is run again and compared.
+//
+// Parameters:
+//   - ctx: Application context containing logger and configuration.
+//   - cmd: Cobra command instance (reads --threshold, --fail-on-drift, --baseline-dir, --output, --output-file).
+//   - args: Baseline and optional report.
+//
+// Returns:
+//   - error: If a report cannot be loaded or run, or a *DriftError with --fail-on-drift when the reports differ.
+func CLI_Diff(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("expected <baseline> [<report>]")
+	}
+	mgr, logger, err := newCLIManager(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	cfg := ctx.GetConfig().Diagnostics.Baseline
+	store := NewBaselineStore(flagString(cmd, "baseline-dir", cfg.Dir, ""))
+	base, err := resolveReport(store, args[0])
+	if err != nil {
+		return err
+	}
+
+	var current *Report
+	currentLabel := ""
+	if len(args) == 2 {
+		current, err = resolveReport(store, args[1])
+		if err != nil {
+			return err
+		}
+		currentLabel = args[1]
+	} else {
+		run, ok := namedDiagnostics[base.Diagnostic]
+		if !ok {
+			return fmt.Errorf("%w: %q cannot be re-run; pass a report file to compare", ErrNotFound, base.Diagnostic)
+		}
+		current, err = run(mgr, commandContext(cmd))
+		if err != nil {
+			logger.Error("Diagnostics failed", core.ZapString("diagnostic", base.Diagnostic), core.ZapError(err))
+			return fmt.Errorf("%s diagnostics failed: %w", base.Diagnostic, err)
+		}
+		currentLabel = "current run"
+	}
+
+	threshold := cfg.Threshold
+	if f := cmd.Flag("threshold"); f != nil && f.Changed {
+		threshold, err = strconv.ParseFloat(f.Value.String(), 64)
+		if err != nil {
+			return fmt.Errorf("invalid --threshold %q: %w", f.Value.String(), err)
+		}
+	}
+	if threshold < 0 {
+		return fmt.Errorf("invalid diff threshold %g: must be >= 0", threshold)
+	}
+	diff, err := DiffReports(base, current, DiffOptions{Threshold: threshold})
+	if err != nil {
+		return err
+	}
+	diff.Baseline, diff.Current = args[0], currentLabel
+
+	format, outputFile := outputSettings(ctx, cmd)
+	w := cmd.OutOrStdout()
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			return fmt.Errorf("failed to create output file %s: %w", outputFile, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := WriteDiff(w, diff, format); err != nil {
+		return err
+	}
+	logger.Info("Diff completed", core.ZapString("baseline", args[0]), core.ZapInt("changes", diff.Changes()))
+	if failOnDrift, _ := cmd.Flags().GetBool("fail-on-drift"); failOnDrift && diff.Changes() > 0 {
+		return &DriftError{Changes: diff.Changes()}
+	}
+	return nil
+}
+
+// CLI_Replay is the entrypoint for 'srediag diagnose replay <file.jsonl>'.
+//
+// Parameters:
+//   - ctx: Application context containing logger and configuration.
+//   - cmd: Cobra command instance (reads --speed, --no-color).
+//   - args: args[0] is a timeline written by --watch --record.
+//
+// Returns:
+//   - error: If the timeline cannot be read or rendered, returns a detailed error.
+func CLI_Replay(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
+	if len(args) != 1 || args[0] == "" {
+		return fmt.Errorf("a recorded timeline (.jsonl) is required")
+	}
+	speed, _ := cmd.Flags().GetFloat64("speed")
+	if speed < 0 {
+		return fmt.Errorf("invalid --speed %g: must be >= 0", speed)
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open timeline %s: %w", args[0], err)
+	}
+	defer f.Close()
+	out := cmd.OutOrStdout()
+	opts := ReplayOptions{Out: out, Speed: speed, ANSI: useANSI(cmd, out)}
+	n, err := Replay(commandContext(cmd), f, opts)
+	if err != nil {
+		return fmt.Errorf("replay of %s failed: %w", args[0], err)
+	}
+	if ctx.Logger != nil {
+		ctx.Logger.Info("Replay completed", core.ZapString("file", args[0]), core.ZapInt("frames", n))
+	}
+	return nil
+}
+
+// flagString returns the flag value when set, then the config value, then def.
+func flagString(cmd *cobra.Command, name, configured, def string) string {
+	if f := cmd.Flag(name); f != nil && f.Changed {
+		return f.Value.String()
+	}
+	if configured != "" {
+		return configured
+	}
+	return def
+}
+
+// flagSlice returns the slice flag value when set, then the config value, then def.
+func flagSlice(cmd *cobra.Command, name string, configured, def []string) []string {
+	if f := cmd.Flag(name); f != nil && f.Changed {
+		if v, ok := f.Value.(pflag.SliceValue); ok {
+			return v.GetSlice()
+		}
+	}
+	if len(configured) > 0 {
+		return configured
+	}
+	return def
+}
+
+// newCLIManager resolves the logger (falling back to a default one) and the run options for a CLI entrypoint,
+// and validates --fail-on before any diagnostic runs.
+//
+// Parameters:
+//   - ctx: Application context containing logger and configuration.
+//   - cmd: Cobra command instance (reads --timeout and --fail-on).
+//
+// Returns:
+//   - *DiagnoseManager: A manager configured with the effective timeout, max_retries and inventory sources.
+//   - *core.Logger: The logger to use.
+//   - error: If the logger cannot be created or a flag is invalid, returns a detailed error.
+func newCLIManager(ctx *core.AppContext, cmd *cobra.Command) (*DiagnoseManager, *core.Logger, error) {
 	logger := ctx.Logger
 	if logger == nil {
 		var err error
 		logger, err = core.NewLogger(nil)
 		if err != nil {
-			return fmt.Errorf("failed to create fallback logger: %w", err)
+			return nil, nil, fmt.Errorf("failed to create fallback logger: %w", err)
 		}
 	}
-	mgr := NewDiagnoseManager(logger)
-	if err := mgr.RunSecurity(); err != nil {
-		logger.Error("Security diagnostics failed", core.ZapError(err))
-		return fmt.Errorf("security diagnostics failed: %w", err)
+	opts, err := resolveRunOptions(ctx, cmd)
+	if err != nil {
+		return nil, nil, err
 	}
-	logger.Info("Security diagnostics completed successfully")
-	return nil
+	if _, err := failOnThreshold(cmd); err != nil {
+		return nil, nil, err
+	}
+	baseline := ctx.GetConfig().Diagnostics.Baseline
+	inventory := InventoryOptions{ConfigFiles: baseline.ConfigFiles, KernelParams: baseline.KernelParams}
+	return NewDiagnoseManager(logger, WithRunOptions(opts), WithInventory(inventory)), logger, nil
+}
+
+// resolveRunOptions computes the timeout (--timeout when set, then diagnostics.defaults.timeout, then the flag
+// default) and retries (diagnostics.defaults.max_retries).
+func resolveRunOptions(ctx *core.AppContext, cmd *cobra.Command) (RunOptions, error) {
+	var opts RunOptions
+	cfg := ctx.GetConfig().Diagnostics.Defaults
+	f := cmd.Flag("timeout")
+	switch {
+	case f != nil && f.Changed:
+		d, err := time.ParseDuration(f.Value.String())
+		if err != nil {
+			return opts, fmt.Errorf("invalid --timeout %q: %w", f.Value.String(), err)
+		}
+		opts.Timeout = d
+	case cfg.Timeout != "":
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return opts, fmt.Errorf("invalid diagnostics.defaults.timeout %q: %w", cfg.Timeout, err)
+		}
+		opts.Timeout = d
+	case f != nil:
+		d, _ := time.ParseDuration(f.Value.String())
+		opts.Timeout = d
+	}
+	if cfg.MaxRetries < 0 {
+		return opts, fmt.Errorf("invalid diagnostics.defaults.max_retries %d: must be >= 0", cfg.MaxRetries)
+	}
+	opts.MaxRetries = cfg.MaxRetries
+	return opts, nil
+}
+
+// commandContext returns the Cobra command context, or context.Background() when unset (e.g., in tests).
+func commandContext(cmd *cobra.Command) context.Context {
+	if c := cmd.Context(); c != nil {
+		return c
+	}
+	return context.Background()
+}
+
+// runOrWatch runs a diagnostic once and finishes its report, or, with --watch, refreshes it until the command
+// context is cancelled or --for elapses.
+//
+// In watch mode each refresh is rendered as a frame (--output and --fail-on do not apply), recorded to --record and
+// exported to --export. Interval, duration, record file and checks fall back to diagnostics.watch.
+//
+// Parameters:
+//   - ctx: Application context containing logger and configuration.
+//   - cmd: Cobra command instance (reads --watch, --for, --record, --checks).
+//   - run: Runs the diagnostic once.
+//
+// Returns:
+//   - error: The run or finishReport error in one-shot mode; option, record or render errors in watch mode.
+func runOrWatch(ctx *core.AppContext, cmd *cobra.Command, run func(context.Context) (*Report, error)) error {
+	f := cmd.Flag("watch")
+	if f == nil || !f.Changed {
+		report, err := run(commandContext(cmd))
+		if err != nil {
+			return err
+		}
+		return finishReport(ctx, cmd, report)
+	}
+
+	cfg := ctx.GetConfig().Diagnostics.Watch
+	opts := WatchOptions{
+		Out:    cmd.OutOrStdout(),
+		Checks: flagSlice(cmd, "checks", cfg.Checks, nil),
+	}
+	var err error
+	if opts.Interval, err = watchDuration(f.Value.String(), cfg.Interval, "diagnostics.watch.interval"); err != nil {
+		return err
+	}
+	if opts.Interval == 0 {
+		opts.Interval = DefaultWatchInterval
+	}
+	if opts.Duration, err = watchDuration(flagString(cmd, "for", "", ""), cfg.Duration, "diagnostics.watch.duration"); err != nil {
+		return err
+	}
+	opts.ANSI = useANSI(cmd, opts.Out)
+	if target := exportTarget(ctx, cmd); target != "" {
+		opts.OnFrame = func(runCtx context.Context, r *Report) error { return ExportReport(runCtx, target, r) }
+	}
+	if record := flagString(cmd, "record", cfg.Record, ""); record != "" {
+		file, err := os.Create(record)
+		if err != nil {
+			return fmt.Errorf("failed to create record file %s: %w", record, err)
+		}
+		defer file.Close()
+		opts.Record = file
+	}
+	return Watch(commandContext(cmd), opts, run)
+}
+
+// watchDuration parses a --watch/--for value, falling back to the configured value when the flag is empty or 0.
+func watchDuration(flagValue, configured, key string) (time.Duration, error) {
+	if flagValue != "" {
+		d, err := time.ParseDuration(flagValue)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", flagValue, err)
+		}
+		if d < 0 {
+			return 0, fmt.Errorf("invalid duration %q: must be >= 0", flagValue)
+		}
+		if d > 0 {
+			return d, nil
+		}
+	}
+	if configured == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(configured)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q", key, configured)
+	}
+	return d, nil
+}
+
+// useANSI reports whether w gets the terminal UI: a terminal, without --no-color or NO_COLOR.
+func useANSI(cmd *cobra.Command, w io.Writer) bool {
+	if f := cmd.Flag("no-color"); f != nil && f.Value.String() == "true" {
+		return false
+	}
+	return os.Getenv("NO_COLOR") == "" && isTerminal(w)
+}
+
+// exportTarget returns --export, falling back to diagnostics.export.endpoint.
+func exportTarget(ctx *core.AppContext, cmd *cobra.Command) string {
+	if f := cmd.Flag("export"); f != nil && f.Value.String() != "" {
+		return f.Value.String()
+	}
+	if ctx.Config != nil {
+		return ctx.Config.Diagnostics.Export.Endpoint
+	}
+	return ""
+}
+
+// finishReport renders the report, saves it as a baseline (--save-baseline), exports it (--export) and applies the
+// --fail-on threshold.
+//
+// Returns:
+//   - error: Rendering, baseline or export errors, or a *FindingsError when findings reach the --fail-on severity.
+func finishReport(ctx *core.AppContext, cmd *cobra.Command, report *Report) error {
+	if err := renderReport(ctx, cmd, report); err != nil {
+		return err
+	}
+	if f := cmd.Flag("save-baseline"); f != nil && f.Value.String() != "" {
+		store := NewBaselineStore(flagString(cmd, "baseline-dir", ctx.GetConfig().Diagnostics.Baseline.Dir, ""))
+		path, err := store.Save(f.Value.String(), report)
+		if err != nil {
+			return err
+		}
+		if ctx.Logger != nil {
+			ctx.Logger.Info("Baseline saved", core.ZapString("name", f.Value.String()), core.ZapString("path", path))
+		}
+	}
+	if err := ExportReport(commandContext(cmd), exportTarget(ctx, cmd), report); err != nil {
+		return fmt.Errorf("failed to export report: %w", err)
+	}
+	threshold, err := failOnThreshold(cmd)
+	if err != nil {
+		return err
+	}
+	return CheckFindings(report, threshold)
+}
+
+// exportRunMetrics exports the recorded diagnostics metrics to --metrics-export (falling back to
+// diagnostics.metrics.export) once a command has finished.
+//
+// Parameters:
+//   - ctx: Application context.
+//   - cmd: The finished command.
+//   - runErr: The command's error.
+//
+// Returns:
+//   - error: runErr if set (export failures are then only logged to stderr); otherwise the export error.
+func exportRunMetrics(ctx *core.AppContext, cmd *cobra.Command, runErr error) error {
+	target := ""
+	if f := cmd.Flag("metrics-export"); f != nil {
+		target = f.Value.String()
+	}
+	if target == "" && ctx.Config != nil {
+		target = ctx.Config.Diagnostics.Metrics.Export
+	}
+	recorder := recorderFromContext(commandContext(cmd))
+	if target == "" || recorder.Empty() {
+		return runErr
+	}
+	// Export even when the run was interrupted; ExportMetrics applies its own timeout.
+	err := ExportMetrics(context.WithoutCancel(commandContext(cmd)), target, recorder)
+	if runErr != nil {
+		if err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", err)
+		}
+		return runErr
+	}
+	return err
+}
+
+// failOnThreshold parses --fail-on; an unset flag returns "" (disabled).
+func failOnThreshold(cmd *cobra.Command) (Severity, error) {
+	f := cmd.Flag("fail-on")
+	if f == nil || f.Value.String() == "" {
+		return "", nil
+	}
+	threshold, err := ParseSeverity(f.Value.String())
+	if err != nil {
+		return "", fmt.Errorf("invalid --fail-on: %w", err)
+	}
+	return threshold, nil
+}
+
+// renderReport writes a report using the effective output format and destination.
+//
+// The format comes from --output when set explicitly, then diagnostics.defaults.output_format, then "table".
+// The destination is --output-file when set, otherwise the command's stdout.
+func renderReport(ctx *core.AppContext, cmd *cobra.Command, report *Report) error {
+	format, outputFile := outputSettings(ctx, cmd)
+	return writeReportOutput(cmd.OutOrStdout(), report, format, outputFile)
+}
+
+// outputSettings returns the effective output format and --output-file (see renderReport).
+func outputSettings(ctx *core.AppContext, cmd *cobra.Command) (format, outputFile string) {
+	if f := cmd.Flag("output"); f != nil && f.Changed {
+		format = f.Value.String()
+	}
+	if format == "" && ctx.Config != nil {
+		format = ctx.Config.Diagnostics.Defaults.OutputFormat
+	}
+	if f := cmd.Flag("output-file"); f != nil {
+		outputFile = f.Value.String()
+	}
+	return strings.ToLower(format), outputFile
 }
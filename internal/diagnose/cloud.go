@@ -0,0 +1,367 @@
+package diagnose
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file defines the CloudDiagnostics handler ('srediag diagnose cloud') and the CloudProvider interface: each
+// provider (cloudproviders.go) queries its cloud's instance metadata service for the instance type, zone, scheduled
+// maintenance, spot/preemption notices, attached volumes and the IAM identity, and reports risky settings.
+//
+// Usage:
+//   - Build a provider with NewCloudProvider, or detect one with DetectCloudProvider.
+//   - Instantiate with NewCloudDiagnostics and call Run to produce a Report.
+//
+// Best Practices:
+//   - Point the endpoint at an httptest server in tests; every provider takes the metadata base URL.
+//   - Keep the timeout short: outside a cloud the link-local address does not answer.
+
+// Defaults of cloud diagnostics.
+const (
+	// DefaultCloudEndpoint is the link-local instance metadata address shared by AWS, Azure, GCP and OCI.
+	DefaultCloudEndpoint = "http://169.254.169.254"
+	// DefaultCloudTimeout bounds each metadata request.
+	DefaultCloudTimeout = 2 * time.Second
+	// DefaultMaintenanceWarning is how close a maintenance event must be to be reported as critical.
+	DefaultMaintenanceWarning = 24 * time.Hour
+)
+
+// Cloud provider names accepted by NewCloudProvider.
+const (
+	CloudAuto  = "auto"
+	CloudAWS   = "aws"
+	CloudAzure = "azure"
+	CloudGCP   = "gcp"
+	CloudOCI   = "oci"
+)
+
+// cloudProviderOrder is the detection order of DetectCloudProvider.
+var cloudProviderOrder = []string{CloudAWS, CloudAzure, CloudGCP, CloudOCI}
+
+// CloudInstance is the provider-neutral view of the instance metadata.
+//
+// Fields:
+//   - Provider, ID, Type, Region, Zone: Identity and placement (Zone is the availability zone or domain).
+//   - Lifecycle: Purchase model as reported by the provider (on-demand, spot, preemptible, ...).
+//   - Spot: Whether the provider may reclaim the instance at short notice.
+//   - Role: IAM role, service account or managed identity in use; empty when none.
+//   - Volumes: Attached block devices.
+//   - Events: Scheduled maintenance events.
+//   - Interruptions: Pending spot/preemption notices.
+//   - Risks: Provider-specific risky settings found while reading the metadata.
+type CloudInstance struct {
+	Provider      string
+	ID            string
+	Type          string
+	Region        string
+	Zone          string
+	Lifecycle     string
+	Spot          bool
+	Role          string
+	Volumes       []CloudVolume
+	Events        []CloudEvent
+	Interruptions []CloudEvent
+	Risks         []Finding
+}
+
+// CloudVolume is a block device attached to the instance.
+//
+// Fields:
+//   - Name, Device: Volume name or ID and its device name, when known.
+//   - Type: Provider storage type (ebs, pd-ssd, Premium_LRS, ...).
+//   - SizeGB: Size when reported; 0 when unknown.
+//   - Ephemeral: Whether the data is lost when the instance stops (instance store, local SSD, resource disk).
+type CloudVolume struct {
+	Name      string
+	Device    string
+	Type      string
+	SizeGB    int
+	Ephemeral bool
+}
+
+// CloudEvent is a scheduled maintenance event or an interruption notice.
+//
+// Fields:
+//   - ID, Kind, Description, Status: Event identity, type (reboot, terminate, migrate, ...) and state.
+//   - NotBefore, NotAfter: Event window; zero when the provider gives no time.
+type CloudEvent struct {
+	ID          string
+	Kind        string
+	Description string
+	Status      string
+	NotBefore   time.Time
+	NotAfter    time.Time
+}
+
+// CloudProvider reads the instance metadata of one cloud.
+//
+// Usage:
+//   - Implemented by the AWS, Azure, GCP and OCI clients; tests point them at httptest servers.
+type CloudProvider interface {
+	// Name returns the provider name (CloudAWS, CloudAzure, ...).
+	Name() string
+	// Detect reports whether the metadata service of this provider answers.
+	Detect(ctx context.Context) bool
+	// Instance reads the instance metadata.
+	Instance(ctx context.Context) (*CloudInstance, error)
+}
+
+// NewCloudProvider creates a provider client.
+//
+// Parameters:
+//   - name: CloudAWS, CloudAzure, CloudGCP or CloudOCI.
+//   - endpoint: Metadata base URL; empty uses DefaultCloudEndpoint.
+//   - timeout: Per-request timeout; 0 uses DefaultCloudTimeout.
+//
+// Returns:
+//   - CloudProvider: The provider client.
+//   - error: If the provider is unknown, returns a detailed error.
+func NewCloudProvider(name, endpoint string, timeout time.Duration) (CloudProvider, error) {
+	md := newMetadataClient(endpoint, timeout)
+	switch name {
+	case CloudAWS:
+		return &awsProvider{md: md}, nil
+	case CloudAzure:
+		return &azureProvider{md: md}, nil
+	case CloudGCP:
+		return &gcpProvider{md: md}, nil
+	case CloudOCI:
+		return &ociProvider{md: md}, nil
+	default:
+		return nil, fmt.Errorf("unknown cloud provider %q (expected aws, azure, gcp, oci or auto)", name)
+	}
+}
+
+// DetectCloudProvider returns the first provider whose metadata service answers.
+//
+// Parameters:
+//   - ctx: Context for cancellation.
+//   - endpoint: Metadata base URL; empty uses DefaultCloudEndpoint.
+//   - timeout: Per-request timeout; 0 uses DefaultCloudTimeout.
+//
+// Returns:
+//   - CloudProvider: The detected provider.
+//   - error: If no metadata service answers (wraps ErrNotFound), returns a detailed error.
+func DetectCloudProvider(ctx context.Context, endpoint string, timeout time.Duration) (CloudProvider, error) {
+	for _, name := range cloudProviderOrder {
+		p, err := NewCloudProvider(name, endpoint, timeout)
+		if err != nil {
+			return nil, err
+		}
+		if p.Detect(ctx) {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("no cloud instance metadata service answered: %w", ErrNotFound)
+}
+
+// errMetadataMissing marks a metadata path that does not exist on this instance (HTTP 404).
+var errMetadataMissing = errors.New("metadata not found")
+
+// metadataClient issues requests against an instance metadata service.
+type metadataClient struct {
+	base   string
+	client *http.Client
+}
+
+// newMetadataClient creates a metadata client; requests never go through a proxy.
+func newMetadataClient(endpoint string, timeout time.Duration) *metadataClient {
+	if endpoint == "" {
+		endpoint = DefaultCloudEndpoint
+	}
+	if timeout <= 0 {
+		timeout = DefaultCloudTimeout
+	}
+	return &metadataClient{
+		base:   strings.TrimRight(endpoint, "/"),
+		client: &http.Client{Timeout: timeout, Transport: &http.Transport{Proxy: nil}},
+	}
+}
+
+// do sends a request and returns the body; 404 returns errMetadataMissing.
+func (m *metadataClient) do(ctx context.Context, method, p string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, m.base+p, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%s %s: %w", method, p, errMetadataMissing)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%s %s: %s: %s", method, p, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// getJSON decodes the JSON response of a GET request.
+func (m *metadataClient) getJSON(ctx context.Context, p string, header http.Header, out any) error {
+	body, err := m.do(ctx, http.MethodGet, p, header)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("GET %s: %w", p, err)
+	}
+	return nil
+}
+
+// CloudOptions configures a cloud diagnostics run.
+//
+// Fields:
+//   - MaintenanceWarning: Events starting within this window are critical; defaults to DefaultMaintenanceWarning.
+type CloudOptions struct {
+	MaintenanceWarning time.Duration
+}
+
+// CloudDiagnostics handles cloud instance metadata diagnostics.
+//
+// Usage:
+//   - Instantiate with NewCloudDiagnostics, providing a logger, a provider and options.
+//   - Call Run to execute cloud diagnostics.
+type CloudDiagnostics struct {
+	logger   *core.Logger
+	provider CloudProvider
+	opts     CloudOptions
+	now      func() time.Time
+}
+
+// NewCloudDiagnostics creates a new cloud diagnostics handler.
+//
+// Parameters:
+//   - logger: Logger for status and error reporting.
+//   - provider: Cloud provider client (real or pointed at a stand-in endpoint).
+//   - opts: Thresholds.
+//
+// Returns:
+//   - *CloudDiagnostics: A new cloud diagnostics handler.
+func NewCloudDiagnostics(logger *core.Logger, provider CloudProvider, opts CloudOptions) *CloudDiagnostics {
+	if opts.MaintenanceWarning <= 0 {
+		opts.MaintenanceWarning = DefaultMaintenanceWarning
+	}
+	return &CloudDiagnostics{logger: logger, provider: provider, opts: opts, now: time.Now}
+}
+
+// Run executes cloud diagnostics.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts.
+//
+// Returns:
+//   - *Report: Instance summary, maintenance, interruption, volume and identity findings.
+//   - error: If the metadata service cannot be read, returns a detailed error.
+func (d *CloudDiagnostics) Run(ctx context.Context) (*Report, error) {
+	d.logger.Info("Running cloud diagnostics", core.ZapString("provider", d.provider.Name()))
+	inst, err := d.provider.Instance(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s instance metadata: %w", d.provider.Name(), err)
+	}
+	report := NewReport("cloud")
+	resource := "instance/" + inst.ID
+	details := map[string]string{"provider": inst.Provider, "id": inst.ID, "type": inst.Type, "region": inst.Region,
+		"zone": inst.Zone, "lifecycle": inst.Lifecycle, "role": inst.Role}
+	for k, v := range details {
+		if v == "" {
+			delete(details, k)
+		}
+	}
+	report.Add(Finding{Check: "cloud.instance", Severity: SeverityInfo, Resource: resource,
+		Message: fmt.Sprintf("%s instance %s (%s) in %s", inst.Provider, inst.ID, inst.Type, inst.Zone), Details: details})
+
+	now := d.now()
+	for _, e := range inst.Interruptions {
+		report.Add(Finding{Check: "cloud.interruption", Severity: SeverityCritical, Resource: resource,
+			Message: fmt.Sprintf("%s notice: %s", e.Kind, eventWhen(e, now)), Details: eventDetails(e)})
+	}
+	if inst.Spot && len(inst.Interruptions) == 0 {
+		report.Add(Finding{Check: "cloud.spot", Severity: SeverityInfo, Resource: resource,
+			Message: fmt.Sprintf("instance is %s capacity and can be reclaimed at short notice", inst.Lifecycle)})
+	}
+	sort.Slice(inst.Events, func(i, j int) bool { return inst.Events[i].NotBefore.Before(inst.Events[j].NotBefore) })
+	for _, e := range inst.Events {
+		sev := SeverityWarning
+		if !e.NotBefore.IsZero() && e.NotBefore.Sub(now) < d.opts.MaintenanceWarning {
+			sev = SeverityCritical
+		}
+		msg := fmt.Sprintf("scheduled %s: %s", e.Kind, eventWhen(e, now))
+		if e.Description != "" {
+			msg += " (" + e.Description + ")"
+		}
+		report.Add(Finding{Check: "cloud.maintenance", Severity: sev, Resource: resource, Message: msg, Details: eventDetails(e)})
+	}
+	for _, v := range inst.Volumes {
+		if v.Ephemeral {
+			report.Add(Finding{Check: "cloud.volume", Severity: SeverityInfo, Resource: "volume/" + v.Name,
+				Message: fmt.Sprintf("%s is ephemeral (%s): its data is lost when the instance stops", v.Name, v.Type)})
+		}
+	}
+	for _, f := range inst.Risks {
+		if f.Resource == "" {
+			f.Resource = resource
+		}
+		report.Add(f)
+	}
+
+	report.SetMeasurement("cloud.volumes", float64(len(inst.Volumes)))
+	report.SetMeasurement("cloud.maintenance_events", float64(len(inst.Events)))
+	report.SetMeasurement("cloud.interruptions", float64(len(inst.Interruptions)))
+	spot := 0.0
+	if inst.Spot {
+		spot = 1
+	}
+	report.SetMeasurement("cloud.spot", spot)
+	report.Finish()
+	return report, nil
+}
+
+// eventWhen describes when an event starts relative to now.
+func eventWhen(e CloudEvent, now time.Time) string {
+	switch {
+	case e.NotBefore.IsZero():
+		return "time not announced"
+	case !e.NotBefore.After(now):
+		return "since " + e.NotBefore.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprintf("at %s (in %s)", e.NotBefore.UTC().Format(time.RFC3339), e.NotBefore.Sub(now).Round(time.Minute))
+	}
+}
+
+// eventDetails renders the non-empty fields of an event.
+func eventDetails(e CloudEvent) map[string]string {
+	details := map[string]string{}
+	for k, v := range map[string]string{"id": e.ID, "kind": e.Kind, "status": e.Status} {
+		if v != "" {
+			details[k] = v
+		}
+	}
+	if !e.NotBefore.IsZero() {
+		details["not_before"] = e.NotBefore.UTC().Format(time.RFC3339)
+	}
+	if !e.NotAfter.IsZero() {
+		details["not_after"] = e.NotAfter.UTC().Format(time.RFC3339)
+	}
+	return details
+}
@@ -0,0 +1,250 @@
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// metadataServer serves fixed metadata documents; GETs without the required header get 403, unknown paths 404.
+func metadataServer(t *testing.T, header, value string, docs map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		doc, ok := docs[r.Method+" "+r.URL.RequestURI()]
+		switch {
+		case !ok:
+			http.NotFound(w, r)
+		case header != "" && r.Method == http.MethodGet && r.Header.Get(header) != value:
+			http.Error(w, "missing "+header, http.StatusForbidden)
+		default:
+			_, _ = w.Write([]byte(doc))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// runCloud runs cloud diagnostics against a provider at a fixed time.
+func runCloud(t *testing.T, p CloudProvider) *Report {
+	t.Helper()
+	d := NewCloudDiagnostics(core.NewTestLogger(&bytes.Buffer{}), p, CloudOptions{})
+	d.now = func() time.Time { return time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC) }
+	r, err := d.Run(context.Background())
+	require.NoError(t, err)
+	return r
+}
+
+// awsDocs is an EC2 spot instance with a role, an instance store, one maintenance event and a termination notice.
+var awsDocs = map[string]string{
+	"PUT /latest/api/token":                                  "tok3n",
+	"GET /latest/meta-data/instance-id":                      "i-0abc",
+	"GET /latest/meta-data/instance-type":                    "m5.large",
+	"GET /latest/meta-data/placement/region":                 "eu-west-1",
+	"GET /latest/meta-data/placement/availability-zone":      "eu-west-1b",
+	"GET /latest/meta-data/instance-life-cycle":              "spot",
+	"GET /latest/meta-data/iam/security-credentials/":        "app-role",
+	"GET /latest/meta-data/block-device-mapping/":            "ami\nebs1\nephemeral0\nroot",
+	"GET /latest/meta-data/block-device-mapping/ami":         "/dev/xvda",
+	"GET /latest/meta-data/block-device-mapping/ebs1":        "/dev/xvdf",
+	"GET /latest/meta-data/block-device-mapping/ephemeral0":  "/dev/sdb",
+	"GET /latest/meta-data/spot/instance-action":             `{"action":"terminate","time":"2026-01-02T12:02:00Z"}`,
+	"GET /latest/meta-data/events/recommendations/rebalance": `{"noticeTime":"2026-01-02T11:50:00Z"}`,
+	"GET /latest/meta-data/events/maintenance/scheduled": `[
+		{"Code":"system-reboot","Description":"scheduled reboot","EventId":"instance-event-1","NotBefore":"3 Jan 2026 02:00:00 GMT","NotAfter":"3 Jan 2026 04:00:00 GMT","State":"active"},
+		{"Code":"instance-stop","EventId":"instance-event-0","NotBefore":"1 Dec 2025 02:00:00 GMT","State":"completed"}]`,
+}
+
+func TestCloudDiagnostics_AWS(t *testing.T) {
+	srv := metadataServer(t, "X-Aws-Ec2-Metadata-Token", "tok3n", awsDocs)
+	p, err := NewCloudProvider(CloudAWS, srv.URL, time.Second)
+	require.NoError(t, err)
+	inst, err := p.Instance(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "eu-west-1b", inst.Zone)
+	assert.Equal(t, "app-role", inst.Role)
+	assert.True(t, inst.Spot)
+	assert.Equal(t, []CloudVolume{
+		{Name: "ami", Device: "/dev/xvda", Type: "ebs"},
+		{Name: "ebs1", Device: "/dev/xvdf", Type: "ebs"},
+		{Name: "ephemeral0", Device: "/dev/sdb", Type: "instance-store", Ephemeral: true},
+	}, inst.Volumes)
+
+	r := runCloud(t, p)
+	instance := findingsByCheck(r, "cloud.instance")
+	require.Len(t, instance, 1)
+	assert.Equal(t, "instance/i-0abc", instance[0].Resource)
+	assert.Equal(t, "m5.large", instance[0].Details["type"])
+
+	interruption := findingsByCheck(r, "cloud.interruption")
+	require.Len(t, interruption, 1)
+	assert.Equal(t, "spot terminate notice: at 2026-01-02T12:02:00Z (in 2m0s)", interruption[0].Message)
+	assert.Empty(t, findingsByCheck(r, "cloud.spot"), "the interruption supersedes the spot notice")
+
+	maintenance := findingsByCheck(r, "cloud.maintenance")
+	require.Len(t, maintenance, 1, "completed events are skipped")
+	assert.Equal(t, SeverityCritical, maintenance[0].Severity, "starts within 24h")
+	assert.Equal(t, "2026-01-03T04:00:00Z", maintenance[0].Details["not_after"])
+
+	assert.Len(t, findingsByCheck(r, "cloud.rebalance"), 1)
+	assert.Len(t, findingsByCheck(r, "cloud.volume"), 1)
+	assert.Empty(t, findingsByCheck(r, "cloud.imds"), "the stand-in requires a token like IMDSv2-only instances")
+	assert.Equal(t, 3.0, r.Measurements["cloud.volumes"])
+	assert.Equal(t, 1.0, r.Measurements["cloud.spot"])
+
+	// Without a token requirement IMDSv1 is open, which exposes the role credentials.
+	srv = metadataServer(t, "", "", awsDocs)
+	p, _ = NewCloudProvider(CloudAWS, srv.URL, time.Second)
+	imds := findingsByCheck(runCloud(t, p), "cloud.imds")
+	require.Len(t, imds, 1)
+	assert.Equal(t, SeverityWarning, imds[0].Severity)
+	assert.Contains(t, imds[0].Message, "role app-role")
+}
+
+func TestCloudDiagnostics_Azure(t *testing.T) {
+	srv := metadataServer(t, "Metadata", "true", map[string]string{
+		"GET " + azureInstancePath: `{"compute":{"vmId":"0f1e","vmSize":"Standard_D4s_v5","location":"westeurope","zone":"2",
+			"priority":"Spot","storageProfile":{"osDisk":{"name":"os","diskSizeGB":"128","managedDisk":{"storageAccountType":"Premium_LRS"}},
+			"dataDisks":[{"name":"data","lun":"0","diskSizeGB":"512","managedDisk":{"storageAccountType":"StandardSSD_LRS"}}],
+			"resourceDisk":{"size":"16384"}}}}`,
+		"GET " + azureEventsPath: `{"DocumentIncarnation":2,"Events":[
+			{"EventId":"A1","EventType":"Freeze","EventStatus":"Scheduled","NotBefore":"Fri, 09 Jan 2026 10:00:00 GMT","Description":"host update"},
+			{"EventId":"B2","EventType":"Preempt","EventStatus":"Scheduled","NotBefore":"Fri, 02 Jan 2026 12:00:30 GMT"}]}`,
+		"GET " + azureIdentityPath: `{"tenantId":"t-1"}`,
+	})
+	p, err := DetectCloudProvider(context.Background(), srv.URL, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, CloudAzure, p.Name())
+
+	inst, err := p.Instance(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "westeurope-2", inst.Zone)
+	assert.Equal(t, "managed identity (tenant t-1)", inst.Role)
+	require.Len(t, inst.Volumes, 3)
+	assert.Equal(t, CloudVolume{Name: "data", Device: "lun0", Type: "StandardSSD_LRS", SizeGB: 512}, inst.Volumes[1])
+	assert.Equal(t, CloudVolume{Name: "resource-disk", Device: "temp", Type: "local", SizeGB: 16, Ephemeral: true}, inst.Volumes[2])
+
+	r := runCloud(t, p)
+	interruption := findingsByCheck(r, "cloud.interruption")
+	require.Len(t, interruption, 1)
+	assert.Contains(t, interruption[0].Message, "spot eviction notice")
+	maintenance := findingsByCheck(r, "cloud.maintenance")
+	require.Len(t, maintenance, 1)
+	assert.Equal(t, SeverityWarning, maintenance[0].Severity, "a week away")
+	assert.Equal(t, "scheduled freeze: at 2026-01-09T10:00:00Z (in 166h0m0s) (host update)", maintenance[0].Message)
+}
+
+func TestCloudDiagnostics_GCP(t *testing.T) {
+	srv := metadataServer(t, "Metadata-Flavor", "Google", map[string]string{
+		"GET /computeMetadata/v1/instance/id": "4520031799277581759",
+		"GET /computeMetadata/v1/instance/?recursive=true": `{"id":4520031799277581759,
+			"machineType":"projects/42/machineTypes/n2-standard-4","zone":"projects/42/zones/us-central1-a",
+			"scheduling":{"preemptible":"FALSE","onHostMaintenance":"TERMINATE"},"maintenanceEvent":"TERMINATE_ON_HOST_MAINTENANCE",
+			"upcomingMaintenance":{"type":"SCHEDULED","windowStartTime":"2026-01-05T00:00:00Z","windowEndTime":"2026-01-05T04:00:00Z","maintenanceStatus":"PENDING"},
+			"preempted":"FALSE",
+			"disks":[{"deviceName":"boot","index":0,"mode":"READ_WRITE","type":"PERSISTENT"},{"deviceName":"local-ssd-0","index":1,"mode":"READ_WRITE","type":"SCRATCH"}],
+			"serviceAccounts":{"default":{"email":"42-compute@developer.gserviceaccount.com","scopes":["https://www.googleapis.com/auth/cloud-platform"]},
+				"42-compute@developer.gserviceaccount.com":{"email":"42-compute@developer.gserviceaccount.com"}}}`,
+	})
+	p, err := DetectCloudProvider(context.Background(), srv.URL, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, CloudGCP, p.Name())
+
+	inst, err := p.Instance(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "4520031799277581759", inst.ID)
+	assert.Equal(t, "n2-standard-4", inst.Type)
+	assert.Equal(t, "us-central1", inst.Region)
+	assert.False(t, inst.Spot)
+
+	r := runCloud(t, p)
+	maintenance := findingsByCheck(r, "cloud.maintenance")
+	require.Len(t, maintenance, 2)
+	assert.Equal(t, "scheduled terminate_on_host_maintenance: time not announced", maintenance[0].Message)
+	assert.Equal(t, "scheduled scheduled maintenance: at 2026-01-05T00:00:00Z (in 60h0m0s)", maintenance[1].Message)
+	iam := findingsByCheck(r, "cloud.iam")
+	require.Len(t, iam, 1)
+	assert.Equal(t, "serviceaccount/42-compute@developer.gserviceaccount.com", iam[0].Resource)
+	assert.Len(t, findingsByCheck(r, "cloud.maintenance-policy"), 1)
+	volume := findingsByCheck(r, "cloud.volume")
+	require.Len(t, volume, 1)
+	assert.Equal(t, "volume/local-ssd-0", volume[0].Resource)
+}
+
+func TestCloudDiagnostics_OCI(t *testing.T) {
+	srv := metadataServer(t, "Authorization", "Bearer Oracle", map[string]string{
+		"GET /opc/v2/instance/id": "ocid1.instance.oc1..aaa",
+		"GET /opc/v2/instance/": `{"id":"ocid1.instance.oc1..aaa","shape":"VM.Standard.E4.Flex","region":"fra",
+			"canonicalRegionName":"eu-frankfurt-1","availabilityDomain":"Uocm:EU-FRANKFURT-1-AD-1","faultDomain":"FAULT-DOMAIN-2"}`,
+		"GET /opc/v2/volumeAttachments/": `[{"id":"att1","volumeId":"ocid1.volume.oc1..bbb","iqn":"iqn.2015-12.com.oracleiaas:x"}]`,
+	})
+	p, err := DetectCloudProvider(context.Background(), srv.URL, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, CloudOCI, p.Name())
+
+	r := runCloud(t, p)
+	instance := findingsByCheck(r, "cloud.instance")
+	require.Len(t, instance, 1)
+	assert.Equal(t, "eu-frankfurt-1", instance[0].Details["region"])
+	assert.Equal(t, "Uocm:EU-FRANKFURT-1-AD-1/FAULT-DOMAIN-2", instance[0].Details["zone"])
+	assert.NotContains(t, instance[0].Details, "role")
+	assert.Equal(t, 1.0, r.Measurements["cloud.volumes"])
+	assert.Len(t, r.Findings, 1)
+}
+
+func TestDetectCloudProvider_NotFound(t *testing.T) {
+	srv := metadataServer(t, "", "", nil)
+	_, err := DetectCloudProvider(context.Background(), srv.URL, time.Second)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = NewCloudProvider("ibm", srv.URL, 0)
+	assert.ErrorContains(t, err, `unknown cloud provider "ibm"`)
+}
+
+func TestCLI_CloudDiagnostics(t *testing.T) {
+	docs := map[string]string{}
+	for k, v := range awsDocs {
+		docs[k] = v
+	}
+	// The CLI runs on the real clock, so the event lies far ahead.
+	docs["GET /latest/meta-data/events/maintenance/scheduled"] = `[{"Code":"system-reboot","EventId":"e","NotBefore":"1 Jan 2099 00:00:00 GMT","State":"active"}]`
+	srv := metadataServer(t, "X-Aws-Ec2-Metadata-Token", "tok3n", docs)
+	cmd := &cobra.Command{Use: "cloud"}
+	cmd.Flags().String("provider", CloudAuto, "")
+	cmd.Flags().String("endpoint", DefaultCloudEndpoint, "")
+	cmd.Flags().Duration("metadata-timeout", DefaultCloudTimeout, "")
+	cmd.Flags().Duration("maintenance-warning", DefaultMaintenanceWarning, "")
+	cmd.Flags().String("output", "table", "")
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	require.NoError(t, cmd.Flags().Set("output", "json"))
+	require.NoError(t, cmd.Flags().Set("maintenance-warning", "1000000h"))
+
+	ctx := &core.AppContext{Logger: core.NewTestLogger(&bytes.Buffer{}), Config: core.NewConfig()}
+	ctx.Config.Diagnostics.Cloud.Provider = CloudAWS
+	ctx.Config.Diagnostics.Cloud.Endpoint = srv.URL
+	require.NoError(t, CLI_CloudDiagnostics(ctx, cmd, nil))
+
+	var r Report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &r))
+	assert.Equal(t, "cloud", r.Diagnostic)
+	maintenance := findingsByCheck(&r, "cloud.maintenance")
+	require.Len(t, maintenance, 1)
+	assert.Equal(t, SeverityCritical, maintenance[0].Severity, "--maintenance-warning covers the event")
+
+	ctx.Config.Diagnostics.Cloud.Provider = CloudAzure
+	err := CLI_CloudDiagnostics(ctx, cmd, nil)
+	assert.ErrorContains(t, err, "failed to read azure instance metadata")
+
+	ctx.Config.Diagnostics.Cloud.Timeout = "soon"
+	assert.ErrorContains(t, CLI_CloudDiagnostics(ctx, cmd, nil), `invalid metadata timeout "soon"`)
+}
@@ -0,0 +1,462 @@
+package diagnose
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file implements the CloudProvider clients for the instance metadata services of AWS (IMDSv2), Azure (IMDS and
+// Scheduled Events), GCP (computeMetadata/v1) and OCI (opc/v2). Each maps its provider's documents to a CloudInstance
+// and records provider-specific risks (IMDSv1 still enabled, the default GCP service account, ...).
+//
+// Usage:
+//   - Created through NewCloudProvider; all requests go to the metadata base URL of the client.
+//
+// Best Practices:
+//   - Treat metadata paths that return 404 as "not present on this instance", never as failures.
+
+// optional turns a missing metadata path into a nil error.
+func optional(err error) error {
+	if errors.Is(err, errMetadataMissing) {
+		return nil
+	}
+	return err
+}
+
+// awsProvider reads the EC2 instance metadata service (IMDSv2).
+type awsProvider struct {
+	md *metadataClient
+}
+
+// Name implements CloudProvider.
+func (p *awsProvider) Name() string { return CloudAWS }
+
+// token requests an IMDSv2 session token and returns the header that carries it.
+func (p *awsProvider) token(ctx context.Context) (http.Header, error) {
+	body, err := p.md.do(ctx, http.MethodPut, "/latest/api/token", http.Header{"X-Aws-Ec2-Metadata-Token-Ttl-Seconds": {"300"}})
+	if err != nil {
+		return nil, err
+	}
+	return http.Header{"X-Aws-Ec2-Metadata-Token": {strings.TrimSpace(string(body))}}, nil
+}
+
+// Detect implements CloudProvider.
+func (p *awsProvider) Detect(ctx context.Context) bool {
+	_, err := p.token(ctx)
+	return err == nil
+}
+
+// awsMaintenanceEvent is one entry of meta-data/events/maintenance/scheduled.
+type awsMaintenanceEvent struct {
+	Code        string `json:"Code"`
+	Description string `json:"Description"`
+	EventID     string `json:"EventId"`
+	NotBefore   string `json:"NotBefore"`
+	NotAfter    string `json:"NotAfter"`
+	State       string `json:"State"`
+}
+
+// awsEventTime is the time layout of EC2 scheduled events.
+const awsEventTime = "2 Jan 2006 15:04:05 GMT"
+
+// Instance implements CloudProvider.
+func (p *awsProvider) Instance(ctx context.Context) (*CloudInstance, error) {
+	header, err := p.token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IMDSv2 token: %w", err)
+	}
+	get := func(path string) (string, error) {
+		body, err := p.md.do(ctx, http.MethodGet, "/latest/meta-data/"+path, header)
+		return strings.TrimSpace(string(body)), err
+	}
+	inst := &CloudInstance{Provider: CloudAWS}
+	for path, dst := range map[string]*string{
+		"instance-id": &inst.ID, "instance-type": &inst.Type, "placement/region": &inst.Region,
+		"placement/availability-zone": &inst.Zone, "instance-life-cycle": &inst.Lifecycle,
+	} {
+		if *dst, err = get(path); optional(err) != nil {
+			return nil, err
+		}
+	}
+	inst.Spot = inst.Lifecycle == "spot"
+
+	roles, err := get("iam/security-credentials/")
+	if optional(err) != nil {
+		return nil, err
+	}
+	inst.Role, _, _ = strings.Cut(roles, "\n")
+
+	mappings, err := get("block-device-mapping/")
+	if optional(err) != nil {
+		return nil, err
+	}
+	for _, name := range strings.Fields(mappings) {
+		if name == "root" {
+			continue
+		}
+		device, err := get("block-device-mapping/" + name)
+		if optional(err) != nil {
+			return nil, err
+		}
+		v := CloudVolume{Name: name, Device: device, Type: "ebs"}
+		if strings.HasPrefix(name, "ephemeral") {
+			v.Type, v.Ephemeral = "instance-store", true
+		}
+		inst.Volumes = append(inst.Volumes, v)
+	}
+
+	scheduled, err := get("events/maintenance/scheduled")
+	if optional(err) != nil {
+		return nil, err
+	}
+	if scheduled != "" {
+		var events []awsMaintenanceEvent
+		if err := json.Unmarshal([]byte(scheduled), &events); err != nil {
+			return nil, fmt.Errorf("invalid scheduled events: %w", err)
+		}
+		for _, e := range events {
+			if e.State == "completed" || e.State == "canceled" {
+				continue
+			}
+			notBefore, _ := time.Parse(awsEventTime, e.NotBefore)
+			notAfter, _ := time.Parse(awsEventTime, e.NotAfter)
+			inst.Events = append(inst.Events, CloudEvent{ID: e.EventID, Kind: e.Code, Description: e.Description,
+				Status: e.State, NotBefore: notBefore, NotAfter: notAfter})
+		}
+	}
+
+	action, err := get("spot/instance-action")
+	if optional(err) != nil {
+		return nil, err
+	}
+	if action != "" {
+		var notice struct {
+			Action string    `json:"action"`
+			Time   time.Time `json:"time"`
+		}
+		if err := json.Unmarshal([]byte(action), &notice); err != nil {
+			return nil, fmt.Errorf("invalid spot instance action: %w", err)
+		}
+		inst.Interruptions = append(inst.Interruptions, CloudEvent{Kind: "spot " + notice.Action, NotBefore: notice.Time})
+	}
+	rebalance, err := get("events/recommendations/rebalance")
+	if optional(err) != nil {
+		return nil, err
+	}
+	if rebalance != "" {
+		var notice struct {
+			NoticeTime time.Time `json:"noticeTime"`
+		}
+		_ = json.Unmarshal([]byte(rebalance), &notice)
+		inst.Risks = append(inst.Risks, Finding{Check: "cloud.rebalance", Severity: SeverityWarning,
+			Message: "EC2 recommends rebalancing: this spot instance is at elevated risk of interruption",
+			Details: map[string]string{"notice_time": notice.NoticeTime.UTC().Format(time.RFC3339)}})
+	}
+
+	// A plain GET without a session token only succeeds when IMDSv1 is still allowed.
+	if _, err := p.md.do(ctx, http.MethodGet, "/latest/meta-data/instance-id", nil); err == nil {
+		f := Finding{Check: "cloud.imds", Severity: SeverityInfo,
+			Message: "IMDSv1 is enabled; require IMDSv2 (http-tokens=required)"}
+		if inst.Role != "" {
+			f.Severity = SeverityWarning
+			f.Message = fmt.Sprintf("IMDSv1 is enabled: an SSRF can read the credentials of role %s; require IMDSv2 (http-tokens=required)", inst.Role)
+		}
+		inst.Risks = append(inst.Risks, f)
+	}
+	return inst, nil
+}
+
+// azureProvider reads the Azure Instance Metadata Service and Scheduled Events.
+type azureProvider struct {
+	md *metadataClient
+}
+
+// azureHeader is required on every Azure IMDS request.
+var azureHeader = http.Header{"Metadata": {"true"}}
+
+// Azure IMDS paths.
+const (
+	azureInstancePath = "/metadata/instance?api-version=2021-02-01"
+	azureEventsPath   = "/metadata/scheduledevents?api-version=2020-07-01"
+	azureIdentityPath = "/metadata/identity/info?api-version=2018-02-01"
+)
+
+// Name implements CloudProvider.
+func (p *azureProvider) Name() string { return CloudAzure }
+
+// Detect implements CloudProvider.
+func (p *azureProvider) Detect(ctx context.Context) bool {
+	_, err := p.md.do(ctx, http.MethodGet, azureInstancePath, azureHeader)
+	return err == nil
+}
+
+// azureDisk is a disk of the compute.storageProfile document.
+type azureDisk struct {
+	Name        string `json:"name"`
+	Lun         string `json:"lun"`
+	DiskSizeGB  string `json:"diskSizeGB"`
+	ManagedDisk struct {
+		StorageAccountType string `json:"storageAccountType"`
+	} `json:"managedDisk"`
+}
+
+// azureInstance is the subset of the IMDS instance document used by diagnostics.
+type azureInstance struct {
+	Compute struct {
+		VMID           string `json:"vmId"`
+		VMSize         string `json:"vmSize"`
+		Location       string `json:"location"`
+		Zone           string `json:"zone"`
+		Priority       string `json:"priority"`
+		StorageProfile struct {
+			OSDisk       azureDisk   `json:"osDisk"`
+			DataDisks    []azureDisk `json:"dataDisks"`
+			ResourceDisk struct {
+				Size string `json:"size"`
+			} `json:"resourceDisk"`
+		} `json:"storageProfile"`
+	} `json:"compute"`
+}
+
+// azureScheduledEvents is the Scheduled Events document.
+type azureScheduledEvents struct {
+	Events []struct {
+		EventID     string `json:"EventId"`
+		EventType   string `json:"EventType"`
+		EventStatus string `json:"EventStatus"`
+		NotBefore   string `json:"NotBefore"`
+		Description string `json:"Description"`
+	} `json:"Events"`
+}
+
+// Instance implements CloudProvider.
+func (p *azureProvider) Instance(ctx context.Context) (*CloudInstance, error) {
+	var doc azureInstance
+	if err := p.md.getJSON(ctx, azureInstancePath, azureHeader, &doc); err != nil {
+		return nil, err
+	}
+	c := doc.Compute
+	inst := &CloudInstance{Provider: CloudAzure, ID: c.VMID, Type: c.VMSize, Region: c.Location, Zone: c.Location,
+		Lifecycle: strings.ToLower(c.Priority), Spot: strings.EqualFold(c.Priority, "Spot")}
+	if c.Zone != "" {
+		inst.Zone = c.Location + "-" + c.Zone
+	}
+	for _, d := range append([]azureDisk{c.StorageProfile.OSDisk}, c.StorageProfile.DataDisks...) {
+		if d.Name == "" {
+			continue
+		}
+		size, _ := strconv.Atoi(d.DiskSizeGB)
+		device := "os"
+		if d.Lun != "" {
+			device = "lun" + d.Lun
+		}
+		inst.Volumes = append(inst.Volumes, CloudVolume{Name: d.Name, Device: device, Type: d.ManagedDisk.StorageAccountType, SizeGB: size})
+	}
+	if mb, _ := strconv.Atoi(c.StorageProfile.ResourceDisk.Size); mb > 0 {
+		inst.Volumes = append(inst.Volumes, CloudVolume{Name: "resource-disk", Device: "temp", Type: "local", SizeGB: mb / 1024, Ephemeral: true})
+	}
+
+	var events azureScheduledEvents
+	if err := optional(p.md.getJSON(ctx, azureEventsPath, azureHeader, &events)); err != nil {
+		return nil, err
+	}
+	for _, e := range events.Events {
+		notBefore, _ := time.Parse(http.TimeFormat, e.NotBefore)
+		ev := CloudEvent{ID: e.EventID, Kind: strings.ToLower(e.EventType), Description: e.Description, Status: e.EventStatus, NotBefore: notBefore}
+		switch e.EventType {
+		case "Preempt":
+			ev.Kind = "spot eviction"
+			inst.Interruptions = append(inst.Interruptions, ev)
+		case "Terminate":
+			inst.Interruptions = append(inst.Interruptions, ev)
+		default:
+			inst.Events = append(inst.Events, ev)
+		}
+	}
+
+	// The identity endpoint answers only when a managed identity is assigned.
+	var identity struct {
+		TenantID string `json:"tenantId"`
+	}
+	if err := p.md.getJSON(ctx, azureIdentityPath, azureHeader, &identity); err == nil {
+		inst.Role = "managed identity (tenant " + identity.TenantID + ")"
+	}
+	return inst, nil
+}
+
+// gcpProvider reads the Compute Engine metadata server.
+type gcpProvider struct {
+	md *metadataClient
+}
+
+// gcpHeader is required on every GCP metadata request.
+var gcpHeader = http.Header{"Metadata-Flavor": {"Google"}}
+
+// gcpDefaultAccountSuffix identifies the Compute Engine default service account (project Editor by default).
+const gcpDefaultAccountSuffix = "-compute@developer.gserviceaccount.com"
+
+// gcpCloudPlatformScope grants every API the service account has IAM permissions for.
+const gcpCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// Name implements CloudProvider.
+func (p *gcpProvider) Name() string { return CloudGCP }
+
+// Detect implements CloudProvider.
+func (p *gcpProvider) Detect(ctx context.Context) bool {
+	_, err := p.md.do(ctx, http.MethodGet, "/computeMetadata/v1/instance/id", gcpHeader)
+	return err == nil
+}
+
+// gcpInstance is the subset of instance/?recursive=true used by diagnostics.
+type gcpInstance struct {
+	ID          json.Number `json:"id"`
+	MachineType string      `json:"machineType"`
+	Zone        string      `json:"zone"`
+	Scheduling  struct {
+		Preemptible       string `json:"preemptible"`
+		OnHostMaintenance string `json:"onHostMaintenance"`
+	} `json:"scheduling"`
+	MaintenanceEvent    string `json:"maintenanceEvent"`
+	UpcomingMaintenance *struct {
+		Type            string    `json:"type"`
+		WindowStartTime time.Time `json:"windowStartTime"`
+		WindowEndTime   time.Time `json:"windowEndTime"`
+		Status          string    `json:"maintenanceStatus"`
+	} `json:"upcomingMaintenance"`
+	Preempted string `json:"preempted"`
+	Disks     []struct {
+		DeviceName string `json:"deviceName"`
+		Index      int    `json:"index"`
+		Mode       string `json:"mode"`
+		Type       string `json:"type"`
+	} `json:"disks"`
+	ServiceAccounts map[string]struct {
+		Email  string   `json:"email"`
+		Scopes []string `json:"scopes"`
+	} `json:"serviceAccounts"`
+}
+
+// Instance implements CloudProvider.
+func (p *gcpProvider) Instance(ctx context.Context) (*CloudInstance, error) {
+	var doc gcpInstance
+	if err := p.md.getJSON(ctx, "/computeMetadata/v1/instance/?recursive=true", gcpHeader, &doc); err != nil {
+		return nil, err
+	}
+	zone := lastPathElem(doc.Zone)
+	inst := &CloudInstance{Provider: CloudGCP, ID: doc.ID.String(), Type: lastPathElem(doc.MachineType), Zone: zone,
+		Lifecycle: "standard", Spot: strings.EqualFold(doc.Scheduling.Preemptible, "TRUE")}
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		inst.Region = zone[:i]
+	}
+	if inst.Spot {
+		inst.Lifecycle = "preemptible"
+	}
+	for _, d := range doc.Disks {
+		v := CloudVolume{Name: d.DeviceName, Device: fmt.Sprintf("disk%d", d.Index), Type: strings.ToLower(d.Type)}
+		if d.Type == "SCRATCH" {
+			v.Type, v.Ephemeral = "local-ssd", true
+		}
+		inst.Volumes = append(inst.Volumes, v)
+	}
+
+	if strings.EqualFold(doc.Preempted, "TRUE") {
+		inst.Interruptions = append(inst.Interruptions, CloudEvent{Kind: "preemption"})
+	}
+	if doc.MaintenanceEvent != "" && doc.MaintenanceEvent != "NONE" {
+		inst.Events = append(inst.Events, CloudEvent{Kind: strings.ToLower(doc.MaintenanceEvent), Status: "started"})
+	}
+	if m := doc.UpcomingMaintenance; m != nil {
+		inst.Events = append(inst.Events, CloudEvent{Kind: strings.ToLower(m.Type) + " maintenance", Status: strings.ToLower(m.Status),
+			NotBefore: m.WindowStartTime, NotAfter: m.WindowEndTime})
+	}
+	if !inst.Spot && doc.Scheduling.OnHostMaintenance == "TERMINATE" {
+		inst.Risks = append(inst.Risks, Finding{Check: "cloud.maintenance-policy", Severity: SeverityInfo,
+			Message: "onHostMaintenance is TERMINATE: the instance is stopped instead of live-migrated during host maintenance"})
+	}
+
+	if sa, ok := doc.ServiceAccounts["default"]; ok {
+		inst.Role = sa.Email
+		if strings.HasSuffix(sa.Email, gcpDefaultAccountSuffix) {
+			for _, scope := range sa.Scopes {
+				if scope == gcpCloudPlatformScope {
+					inst.Risks = append(inst.Risks, Finding{Check: "cloud.iam", Severity: SeverityWarning, Resource: "serviceaccount/" + sa.Email,
+						Message: "the Compute Engine default service account is used with the cloud-platform scope; use a dedicated least-privilege account"})
+					break
+				}
+			}
+		}
+	}
+	return inst, nil
+}
+
+// lastPathElem returns the last element of a resource path ("projects/1/zones/us-central1-a" -> "us-central1-a").
+func lastPathElem(p string) string {
+	return p[strings.LastIndex(p, "/")+1:]
+}
+
+// ociProvider reads the OCI instance metadata service (v2).
+type ociProvider struct {
+	md *metadataClient
+}
+
+// ociHeader is required on every OCI IMDSv2 request.
+var ociHeader = http.Header{"Authorization": {"Bearer Oracle"}}
+
+// Name implements CloudProvider.
+func (p *ociProvider) Name() string { return CloudOCI }
+
+// Detect implements CloudProvider.
+func (p *ociProvider) Detect(ctx context.Context) bool {
+	_, err := p.md.do(ctx, http.MethodGet, "/opc/v2/instance/id", ociHeader)
+	return err == nil
+}
+
+// Instance implements CloudProvider. OCI metadata carries no maintenance or preemption notices.
+func (p *ociProvider) Instance(ctx context.Context) (*CloudInstance, error) {
+	var doc struct {
+		ID                  string `json:"id"`
+		Shape               string `json:"shape"`
+		Region              string `json:"region"`
+		CanonicalRegionName string `json:"canonicalRegionName"`
+		AvailabilityDomain  string `json:"availabilityDomain"`
+		FaultDomain         string `json:"faultDomain"`
+	}
+	if err := p.md.getJSON(ctx, "/opc/v2/instance/", ociHeader, &doc); err != nil {
+		return nil, err
+	}
+	inst := &CloudInstance{Provider: CloudOCI, ID: doc.ID, Type: doc.Shape, Region: doc.CanonicalRegionName,
+		Zone: doc.AvailabilityDomain, Lifecycle: "on-demand"}
+	if inst.Region == "" {
+		inst.Region = doc.Region
+	}
+	if doc.FaultDomain != "" {
+		inst.Zone += "/" + doc.FaultDomain
+	}
+
+	var attachments []struct {
+		ID       string `json:"id"`
+		VolumeID string `json:"volumeId"`
+		IQN      string `json:"iqn"`
+	}
+	if err := optional(p.md.getJSON(ctx, "/opc/v2/volumeAttachments/", ociHeader, &attachments)); err != nil {
+		return nil, err
+	}
+	for _, a := range attachments {
+		inst.Volumes = append(inst.Volumes, CloudVolume{Name: a.VolumeID, Device: a.IQN, Type: "block-volume"})
+	}
+
+	// The certificate identifies the instance principal; what it may do is set by dynamic-group policies, which the
+	// metadata does not show.
+	if _, err := p.md.do(ctx, http.MethodGet, "/opc/v2/identity/cert.pem", ociHeader); err == nil {
+		inst.Role = "instance principal"
+	}
+	return inst, nil
+}
@@ -0,0 +1,415 @@
+package diagnose
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file implements the containerd ContainerRuntime: the namespaces, containers, tasks and images services of the
+// containerd API are called over its unix socket with gRPC. The few messages needed are encoded and decoded directly
+// with protowire, which avoids depending on the containerd client and its generated API.
+//
+// Usage:
+//   - Build with NewContainerdRuntime (or NewContainerRuntime with RuntimeContainerd).
+//
+// Best Practices:
+//   - Restrict the namespaces on busy nodes ("k8s.io" for Kubernetes, "moby" for Docker).
+//   - Keep field numbers in sync with github.com/containerd/containerd/api when extending the decoders.
+
+// containerd API methods.
+const (
+	containerdNamespacesList = "/containerd.services.namespaces.v1.Namespaces/List"
+	containerdContainersList = "/containerd.services.containers.v1.Containers/List"
+	containerdTasksList      = "/containerd.services.tasks.v1.Tasks/List"
+	containerdImagesList     = "/containerd.services.images.v1.Images/List"
+
+	// containerdNamespaceHeader is the gRPC metadata key selecting the namespace of a call.
+	containerdNamespaceHeader = "containerd-namespace"
+	// criMetadataExtension holds the CRI container metadata (including the restart attempt).
+	criMetadataExtension = "io.cri-containerd.container.metadata"
+
+	// Labels and annotations set by the kubelet on CRI containers.
+	k8sContainerName = "io.kubernetes.container.name"
+	k8sPodName       = "io.kubernetes.pod.name"
+	k8sPodNamespace  = "io.kubernetes.pod.namespace"
+	k8sRestartCount  = "io.kubernetes.container.restartCount"
+)
+
+// containerdTaskStatus names the containerd.v1.types.Status values.
+var containerdTaskStatus = map[uint64]string{0: "unknown", 1: "created", 2: "running", 3: "exited", 4: "paused", 5: "pausing"}
+
+// rawCodec passes pre-encoded protobuf messages through gRPC unchanged.
+type rawCodec struct{}
+
+// Marshal implements encoding.Codec.
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("rawCodec: unexpected message type %T", v)
+	}
+	return *b, nil
+}
+
+// Unmarshal implements encoding.Codec.
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("rawCodec: unexpected message type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+// Name implements encoding.Codec; the wire content type stays application/grpc+proto.
+func (rawCodec) Name() string { return "proto" }
+
+// containerdRuntime talks to the containerd API over its unix socket.
+type containerdRuntime struct {
+	conn       *grpc.ClientConn
+	namespaces []string
+}
+
+// NewContainerdRuntime creates a containerd API client for a unix socket.
+//
+// Parameters:
+//   - socket: Path of the containerd socket.
+//   - namespaces: Namespaces to inspect; empty inspects all of them.
+//
+// Returns:
+//   - ContainerRuntime: The containerd client; the socket is dialled lazily.
+//   - error: If the client cannot be created, returns a detailed error.
+func NewContainerdRuntime(socket string, namespaces []string) (ContainerRuntime, error) {
+	conn, err := grpc.NewClient("unix://"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create containerd client for %s: %w", socket, err)
+	}
+	return &containerdRuntime{conn: conn, namespaces: namespaces}, nil
+}
+
+// Name implements ContainerRuntime.
+func (r *containerdRuntime) Name() string { return RuntimeContainerd }
+
+// Close implements ContainerRuntime.
+func (r *containerdRuntime) Close() error { return r.conn.Close() }
+
+// invoke calls a unary containerd method in a namespace with an empty request.
+func (r *containerdRuntime) invoke(ctx context.Context, namespace, method string) ([]byte, error) {
+	if namespace != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, containerdNamespaceHeader, namespace)
+	}
+	req, resp := []byte{}, []byte{}
+	if err := r.conn.Invoke(ctx, method, &req, &resp, grpc.ForceCodec(rawCodec{})); err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	return resp, nil
+}
+
+// Containers implements ContainerRuntime.
+func (r *containerdRuntime) Containers(ctx context.Context) ([]ContainerInfo, error) {
+	namespaces := r.namespaces
+	if len(namespaces) == 0 {
+		resp, err := r.invoke(ctx, "", containerdNamespacesList)
+		if err != nil {
+			return nil, err
+		}
+		// ListNamespacesResponse{repeated Namespace namespaces = 1}, Namespace{string name = 1}
+		err = pbFields(resp, func(num protowire.Number, data []byte, _ uint64) error {
+			if num == 1 {
+				return pbFields(data, func(num protowire.Number, data []byte, _ uint64) error {
+					if num == 1 {
+						namespaces = append(namespaces, string(data))
+					}
+					return nil
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode namespaces: %w", err)
+		}
+	}
+
+	var out []ContainerInfo
+	for _, ns := range namespaces {
+		containers, err := r.namespaceContainers(ctx, ns)
+		if err != nil {
+			return nil, fmt.Errorf("namespace %s: %w", ns, err)
+		}
+		out = append(out, containers...)
+	}
+	return out, nil
+}
+
+// containerdTask is the decoded subset of containerd.v1.types.Process.
+type containerdTask struct {
+	pid      int
+	status   string
+	exitCode int
+	exitedAt time.Time
+}
+
+// namespaceContainers lists the containers of one namespace with their tasks and images.
+func (r *containerdRuntime) namespaceContainers(ctx context.Context, ns string) ([]ContainerInfo, error) {
+	resp, err := r.invoke(ctx, ns, containerdTasksList)
+	if err != nil {
+		return nil, err
+	}
+	tasks := map[string]containerdTask{}
+	// ListTasksResponse{repeated Process tasks = 1}
+	// Process{container_id = 1, id = 2, pid = 3, status = 4, exit_status = 9, exited_at = 10}
+	err = pbFields(resp, func(num protowire.Number, data []byte, _ uint64) error {
+		if num != 1 {
+			return nil
+		}
+		var id string
+		var t containerdTask
+		err := pbFields(data, func(num protowire.Number, data []byte, v uint64) error {
+			switch num {
+			case 1:
+				id = string(data)
+			case 3:
+				t.pid = int(v)
+			case 4:
+				t.status = containerdTaskStatus[v]
+			case 9:
+				t.exitCode = int(v)
+			case 10:
+				t.exitedAt = pbTimestamp(data)
+			}
+			return nil
+		})
+		tasks[id] = t
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode tasks: %w", err)
+	}
+
+	resp, err = r.invoke(ctx, ns, containerdImagesList)
+	if err != nil {
+		return nil, err
+	}
+	images := map[string]time.Time{}
+	// ListImagesResponse{repeated Image images = 1}, Image{string name = 1, Timestamp created_at = 7}
+	err = pbFields(resp, func(num protowire.Number, data []byte, _ uint64) error {
+		if num != 1 {
+			return nil
+		}
+		var name string
+		var created time.Time
+		err := pbFields(data, func(num protowire.Number, data []byte, _ uint64) error {
+			switch num {
+			case 1:
+				name = string(data)
+			case 7:
+				created = pbTimestamp(data)
+			}
+			return nil
+		})
+		images[name] = created
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode images: %w", err)
+	}
+
+	resp, err = r.invoke(ctx, ns, containerdContainersList)
+	if err != nil {
+		return nil, err
+	}
+	var out []ContainerInfo
+	// ListContainersResponse{repeated Container containers = 1}
+	err = pbFields(resp, func(num protowire.Number, data []byte, _ uint64) error {
+		if num != 1 {
+			return nil
+		}
+		c, err := decodeContainerdContainer(data)
+		if err != nil {
+			return err
+		}
+		c.Namespace = ns
+		c.ImageCreated = images[c.Image]
+		c.State, c.ExitCode = "created", -1
+		if t, ok := tasks[c.ID]; ok {
+			c.State, c.Pid = t.status, t.pid
+			if t.status == "exited" {
+				c.ExitCode, c.FinishedAt = t.exitCode, t.exitedAt
+			}
+		}
+		out = append(out, c)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode containers: %w", err)
+	}
+	return out, nil
+}
+
+// ociSpec is the subset of the OCI runtime spec used by diagnostics.
+type ociSpec struct {
+	Annotations map[string]string `json:"annotations"`
+	Linux       struct {
+		CgroupsPath string `json:"cgroupsPath"`
+		Resources   struct {
+			Memory struct {
+				Limit int64 `json:"limit"`
+			} `json:"memory"`
+			CPU struct {
+				Quota  int64  `json:"quota"`
+				Period uint64 `json:"period"`
+			} `json:"cpu"`
+		} `json:"resources"`
+	} `json:"linux"`
+}
+
+// criMetadata is the subset of the CRI container metadata extension used by diagnostics.
+type criMetadata struct {
+	Metadata struct {
+		Config struct {
+			Metadata struct {
+				Attempt int `json:"attempt"`
+			} `json:"metadata"`
+		} `json:"Config"`
+	} `json:"Metadata"`
+}
+
+// decodeContainerdContainer decodes containerd.services.containers.v1.Container:
+// {id = 1, map<string,string> labels = 2, image = 3, Any spec = 5, created_at = 8, map<string,Any> extensions = 10}.
+func decodeContainerdContainer(data []byte) (ContainerInfo, error) {
+	var c ContainerInfo
+	labels := map[string]string{}
+	var spec ociSpec
+	err := pbFields(data, func(num protowire.Number, data []byte, _ uint64) error {
+		switch num {
+		case 1:
+			c.ID = string(data)
+		case 2:
+			k, v, err := pbMapEntry(data)
+			labels[k] = string(v)
+			return err
+		case 3:
+			c.Image = string(data)
+		case 5:
+			if value, err := pbAnyValue(data); err == nil && len(value) > 0 {
+				_ = json.Unmarshal(value, &spec) // a non-OCI spec leaves the limits unknown
+			}
+		case 8:
+			c.Created = pbTimestamp(data)
+		case 10:
+			k, v, err := pbMapEntry(data)
+			if err != nil || k != criMetadataExtension {
+				return err
+			}
+			value, err := pbAnyValue(v)
+			if err != nil {
+				return err
+			}
+			var meta criMetadata
+			if json.Unmarshal(value, &meta) == nil && c.Restarts == 0 {
+				c.Restarts = meta.Metadata.Config.Metadata.Attempt
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return c, err
+	}
+
+	c.Name = c.ID
+	if name := labels[k8sContainerName]; name != "" {
+		c.Name = labels[k8sPodNamespace] + "/" + labels[k8sPodName] + "/" + name
+	}
+	if n, err := strconv.Atoi(spec.Annotations[k8sRestartCount]); err == nil {
+		c.Restarts = n
+	}
+	c.CgroupPath = spec.Linux.CgroupsPath
+	c.MemoryLimit = spec.Linux.Resources.Memory.Limit
+	if cpu := spec.Linux.Resources.CPU; cpu.Quota > 0 && cpu.Period > 0 {
+		c.CPULimit = float64(cpu.Quota) / float64(cpu.Period)
+	}
+	return c, nil
+}
+
+// pbFields calls fn for every field of an encoded protobuf message: length-delimited fields get their bytes, varint
+// fields their value. Fixed-width fields are skipped.
+func pbFields(b []byte, fn func(num protowire.Number, data []byte, v uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		var err error
+		switch typ {
+		case protowire.VarintType:
+			var v uint64
+			if v, n = protowire.ConsumeVarint(b); n >= 0 {
+				err = fn(num, nil, v)
+			}
+		case protowire.BytesType:
+			var data []byte
+			if data, n = protowire.ConsumeBytes(b); n >= 0 {
+				err = fn(num, data, 0)
+			}
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		if err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// pbMapEntry decodes a map entry {key = 1, value = 2} with a string key.
+func pbMapEntry(b []byte) (string, []byte, error) {
+	var key string
+	var value []byte
+	err := pbFields(b, func(num protowire.Number, data []byte, _ uint64) error {
+		switch num {
+		case 1:
+			key = string(data)
+		case 2:
+			value = data
+		}
+		return nil
+	})
+	return key, value, err
+}
+
+// pbAnyValue returns the value of a google.protobuf.Any {type_url = 1, value = 2}.
+func pbAnyValue(b []byte) ([]byte, error) {
+	_, value, err := pbMapEntry(b) // same layout as a map entry
+	return value, err
+}
+
+// pbTimestamp decodes a google.protobuf.Timestamp {seconds = 1, nanos = 2}; malformed input yields the zero time.
+func pbTimestamp(b []byte) time.Time {
+	var secs, nanos uint64
+	if err := pbFields(b, func(num protowire.Number, _ []byte, v uint64) error {
+		switch num {
+		case 1:
+			secs = v
+		case 2:
+			nanos = v
+		}
+		return nil
+	}); err != nil || (secs == 0 && nanos == 0) {
+		return time.Time{}
+	}
+	return time.Unix(int64(secs), int64(nanos)).UTC()
+}
@@ -0,0 +1,588 @@
+package diagnose
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file defines the ContainerDiagnostics handler ('srediag diagnose containers'): containers are listed through
+// the Docker Engine API or the containerd API over their local sockets (see containerd.go), mapped to their cgroups
+// through /proc/<pid>/cgroup for CPU, memory and IO usage, and checked for restarts, OOM kills, failed exits, old
+// images and missing resource limits.
+//
+// Usage:
+//   - Build a runtime client with NewContainerRuntime (auto-detects the socket) or inject a fake ContainerRuntime.
+//   - Instantiate with NewContainerDiagnostics and call Run to produce a Report.
+//
+// Best Practices:
+//   - Run as a user allowed on the runtime socket (usually root or the docker group).
+//   - When srediag runs in a container, mount the host root and set the sockets relative to it.
+
+// Defaults and thresholds of container diagnostics.
+const (
+	DefaultDockerSocket     = "/var/run/docker.sock"
+	DefaultContainerdSocket = "/run/containerd/containerd.sock"
+	DefaultImageMaxAge      = 90 * 24 * time.Hour
+
+	// containerRestartWarn is the restart count from which a container is flagged as crash-looping.
+	containerRestartWarn = 3
+	// cgroupV1Unlimited is the smallest cgroup v1 memory limit treated as "no limit" (the kernel reports ~2^63).
+	cgroupV1Unlimited = 1 << 62
+)
+
+// Container runtime names accepted by NewContainerRuntime.
+const (
+	RuntimeAuto       = "auto"
+	RuntimeDocker     = "docker"
+	RuntimeContainerd = "containerd"
+)
+
+// ContainerInfo is the runtime view of one container.
+//
+// Fields:
+//   - ID, Name, Namespace: Identity; Name falls back to the ID and Namespace is the containerd namespace.
+//   - Image, ImageCreated: Image reference and its creation time (build time on Docker, pull time on containerd).
+//   - Created: Creation time of the container.
+//   - State: Runtime state (running, exited, created, paused, ...).
+//   - Pid: Main process on the host; 0 when not running.
+//   - ExitCode, FinishedAt: Last exit, when the container has exited (ExitCode is -1 when unknown).
+//   - Restarts: Restarts by the runtime or the kubelet.
+//   - OOMKilled: Whether the runtime recorded an OOM kill.
+//   - MemoryLimit, CPULimit: Limits configured in the runtime (bytes, cores); 0 means none.
+//   - CgroupPath: cgroup path from the runtime; used when the pid cannot be resolved.
+type ContainerInfo struct {
+	ID           string
+	Name         string
+	Namespace    string
+	Image        string
+	ImageCreated time.Time
+	Created      time.Time
+	State        string
+	Pid          int
+	ExitCode     int
+	FinishedAt   time.Time
+	Restarts     int
+	OOMKilled    bool
+	MemoryLimit  int64
+	CPULimit     float64
+	CgroupPath   string
+}
+
+// ContainerRuntime lists containers from a container runtime.
+//
+// Usage:
+//   - Implemented by the Docker and containerd clients; tests inject fakes or fake socket servers.
+type ContainerRuntime interface {
+	// Name returns the runtime name ("docker" or "containerd").
+	Name() string
+	// Containers lists all containers, running or not.
+	Containers(ctx context.Context) ([]ContainerInfo, error)
+	// Close releases the connection.
+	Close() error
+}
+
+// NewContainerRuntime connects to a container runtime over its local socket.
+//
+// Parameters:
+//   - kind: RuntimeDocker, RuntimeContainerd or RuntimeAuto ("" means auto: Docker if its socket exists, then
+//     containerd).
+//   - socket: Socket path; empty uses the runtime default under the host root.
+//   - namespaces: containerd namespaces to inspect; empty inspects all of them.
+//
+// Returns:
+//   - ContainerRuntime: The runtime client.
+//   - error: If the runtime is unknown or no socket is found (wraps ErrNotFound), returns a detailed error.
+func NewContainerRuntime(kind, socket string, namespaces []string) (ContainerRuntime, error) {
+	if kind == "" || kind == RuntimeAuto {
+		switch {
+		case socket != "" && strings.Contains(path.Base(socket), "containerd"):
+			kind = RuntimeContainerd
+		case socket != "":
+			kind = RuntimeDocker
+		case fileExists(hostPath(DefaultDockerSocket)):
+			kind = RuntimeDocker
+		case fileExists(hostPath(DefaultContainerdSocket)):
+			kind = RuntimeContainerd
+		default:
+			return nil, fmt.Errorf("no container runtime socket found (%s, %s): %w", DefaultDockerSocket, DefaultContainerdSocket, ErrNotFound)
+		}
+	}
+	switch kind {
+	case RuntimeDocker:
+		if socket == "" {
+			socket = hostPath(DefaultDockerSocket)
+		}
+		return NewDockerRuntime(socket), nil
+	case RuntimeContainerd:
+		if socket == "" {
+			socket = hostPath(DefaultContainerdSocket)
+		}
+		return NewContainerdRuntime(socket, namespaces)
+	default:
+		return nil, fmt.Errorf("unknown container runtime %q (expected docker, containerd or auto)", kind)
+	}
+}
+
+// fileExists reports whether p exists.
+func fileExists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+// ContainerOptions configures a container diagnostics run.
+//
+// Fields:
+//   - ImageMaxAge: Image age above which a container is flagged; defaults to DefaultImageMaxAge.
+type ContainerOptions struct {
+	ImageMaxAge time.Duration
+}
+
+// ContainerDiagnostics handles container runtime diagnostics.
+//
+// Usage:
+//   - Instantiate with NewContainerDiagnostics, providing a logger, a runtime and options.
+//   - Call Run to execute container diagnostics.
+type ContainerDiagnostics struct {
+	logger  *core.Logger
+	runtime ContainerRuntime
+	opts    ContainerOptions
+	now     func() time.Time
+}
+
+// NewContainerDiagnostics creates a new container diagnostics handler.
+//
+// Parameters:
+//   - logger: Logger for status and error reporting.
+//   - runtime: Container runtime client (real or fake).
+//   - opts: Thresholds.
+//
+// Returns:
+//   - *ContainerDiagnostics: A new container diagnostics handler.
+func NewContainerDiagnostics(logger *core.Logger, runtime ContainerRuntime, opts ContainerOptions) *ContainerDiagnostics {
+	if opts.ImageMaxAge <= 0 {
+		opts.ImageMaxAge = DefaultImageMaxAge
+	}
+	return &ContainerDiagnostics{logger: logger, runtime: runtime, opts: opts, now: time.Now}
+}
+
+// Run executes container diagnostics.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts.
+//
+// Returns:
+//   - *Report: Per-container findings and cgroup usage measurements.
+//   - error: If the runtime cannot be queried, returns a detailed error.
+func (d *ContainerDiagnostics) Run(ctx context.Context) (*Report, error) {
+	d.logger.Info("Running container diagnostics", core.ZapString("runtime", d.runtime.Name()))
+	containers, err := d.runtime.Containers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s containers: %w", d.runtime.Name(), err)
+	}
+	sort.Slice(containers, func(i, j int) bool { return containers[i].Name < containers[j].Name })
+
+	report := NewReport("containers")
+	running := 0
+	now := d.now()
+	for _, c := range containers {
+		// label qualifies the name with the containerd namespace; it names the resource and the measurements.
+		label := c.Name
+		if c.Namespace != "" {
+			label = c.Namespace + "/" + c.Name
+		}
+		resource := "container/" + label
+		if c.State == "running" {
+			running++
+		}
+
+		stats, err := readCgroupStats(c.Pid, c.CgroupPath)
+		switch {
+		case err == nil:
+			report.SetMeasurement("container.cpu_seconds/"+label, stats.cpuSeconds)
+			report.SetMeasurement("container.memory_bytes/"+label, float64(stats.memoryBytes))
+			report.SetMeasurement("container.io_read_bytes/"+label, float64(stats.ioRead))
+			report.SetMeasurement("container.io_write_bytes/"+label, float64(stats.ioWrite))
+			report.SetMeasurement("container.oom_kills/"+label, float64(stats.oomKills))
+			if stats.memoryLimit > 0 {
+				report.SetMeasurement("container.memory_limit_bytes/"+label, float64(stats.memoryLimit))
+				c.MemoryLimit = int64(stats.memoryLimit)
+			}
+			if stats.cpuLimit > 0 {
+				c.CPULimit = stats.cpuLimit
+			}
+		case c.State == "running":
+			d.logger.Warn("Failed to read container cgroup", core.ZapString("container", c.Name), core.ZapError(err))
+		}
+		report.SetMeasurement("container.restarts/"+label, float64(c.Restarts))
+
+		if c.OOMKilled || stats.oomKills > 0 {
+			report.Add(Finding{Check: "container.oom", Severity: SeverityCritical, Resource: resource,
+				Message: fmt.Sprintf("%s was OOM-killed (%d kill(s) in its cgroup, memory limit %s)", c.Name, stats.oomKills,
+					formatLimit(c.MemoryLimit)),
+				Details: map[string]string{"oom_kills": strconv.FormatUint(stats.oomKills, 10), "runtime_oom_killed": strconv.FormatBool(c.OOMKilled)}})
+		}
+		if c.Restarts >= containerRestartWarn {
+			report.Add(Finding{Check: "container.restarts", Severity: SeverityWarning, Resource: resource,
+				Message: fmt.Sprintf("%s restarted %d times", c.Name, c.Restarts)})
+		}
+		if c.State != "running" && c.ExitCode > 0 {
+			details := map[string]string{"exit_code": strconv.Itoa(c.ExitCode), "state": c.State}
+			if !c.FinishedAt.IsZero() {
+				details["finished_at"] = c.FinishedAt.UTC().Format(time.RFC3339)
+			}
+			msg := fmt.Sprintf("%s exited with code %d", c.Name, c.ExitCode)
+			if reason := exitReason(c.ExitCode); reason != "" {
+				msg += " (" + reason + ")"
+				details["reason"] = reason
+			}
+			report.Add(Finding{Check: "container.exit", Severity: SeverityWarning, Resource: resource, Message: msg, Details: details})
+		}
+		if !c.ImageCreated.IsZero() {
+			age := now.Sub(c.ImageCreated)
+			report.SetMeasurement("container.image_age_days/"+label, age.Hours()/24)
+			if age > d.opts.ImageMaxAge {
+				report.Add(Finding{Check: "container.image-age", Severity: SeverityWarning, Resource: resource,
+					Message: fmt.Sprintf("%s runs image %s created %d days ago", c.Name, c.Image, int(age.Hours()/24)),
+					Details: map[string]string{"image": c.Image, "image_created": c.ImageCreated.UTC().Format(time.RFC3339)}})
+			}
+		}
+		if c.State == "running" {
+			switch {
+			case c.MemoryLimit == 0:
+				missing := "memory"
+				if c.CPULimit == 0 {
+					missing = "memory and CPU"
+				}
+				report.Add(Finding{Check: "container.limits", Severity: SeverityWarning, Resource: resource,
+					Message: fmt.Sprintf("%s runs without %s limits", c.Name, missing)})
+			case c.CPULimit == 0:
+				report.Add(Finding{Check: "container.limits", Severity: SeverityInfo, Resource: resource,
+					Message: fmt.Sprintf("%s runs without a CPU limit", c.Name)})
+			}
+		}
+	}
+	report.SetMeasurement("containers.total", float64(len(containers)))
+	report.SetMeasurement("containers.running", float64(running))
+	report.Finish()
+	return report, nil
+}
+
+// exitReason explains well-known exit codes.
+func exitReason(code int) string {
+	switch code {
+	case 137:
+		return "SIGKILL: OOM kill or forced stop"
+	case 139:
+		return "SIGSEGV: segmentation fault"
+	case 143:
+		return "SIGTERM"
+	case 126:
+		return "command not executable"
+	case 127:
+		return "command not found"
+	}
+	return ""
+}
+
+// formatLimit renders a memory limit for messages.
+func formatLimit(bytes int64) string {
+	if bytes <= 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%d MiB", bytes>>20)
+}
+
+// cgroupStats is the usage of one container cgroup.
+type cgroupStats struct {
+	cpuSeconds      float64
+	memoryBytes     uint64
+	memoryLimit     uint64 // 0 means no limit
+	cpuLimit        float64
+	ioRead, ioWrite uint64
+	oomKills        uint64
+}
+
+// readCgroupStats reads the cgroup usage of a container from its pid (/proc/<pid>/cgroup), or from cgroupPath
+// (cgroup v2, relative to /sys/fs/cgroup) when the pid is unknown.
+func readCgroupStats(pid int, cgroupPath string) (cgroupStats, error) {
+	var stats cgroupStats
+	if pid <= 0 {
+		if !strings.HasPrefix(cgroupPath, "/") {
+			return stats, fmt.Errorf("no pid or cgroup path: %w", ErrNotFound)
+		}
+		return readCgroupV2(hostPath(path.Join("/sys/fs/cgroup", cgroupPath)))
+	}
+	f, err := os.Open(hostPath("/proc/" + strconv.Itoa(pid) + "/cgroup"))
+	if err != nil {
+		return stats, err
+	}
+	defer f.Close()
+	v1 := map[string]string{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		parts := strings.SplitN(sc.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			return readCgroupV2(hostPath(path.Join("/sys/fs/cgroup", parts[2])))
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			v1[controller] = hostPath(path.Join("/sys/fs/cgroup", parts[1], parts[2]))
+		}
+	}
+	if len(v1) == 0 {
+		return stats, fmt.Errorf("no cgroup found for pid %d: %w", pid, ErrNotFound)
+	}
+	return readCgroupV1(v1), nil
+}
+
+// readCgroupV2 reads a cgroup v2 directory.
+func readCgroupV2(dir string) (cgroupStats, error) {
+	var stats cgroupStats
+	if _, err := os.Stat(dir); err != nil {
+		return stats, err
+	}
+	keyed := func(file string) map[string]uint64 { return readKeyedFile(path.Join(dir, file)) }
+	stats.cpuSeconds = float64(keyed("cpu.stat")["usage_usec"]) / 1e6
+	stats.memoryBytes = readUintFile(path.Join(dir, "memory.current"))
+	stats.memoryLimit = readUintFile(path.Join(dir, "memory.max")) // "max" parses as 0
+	stats.oomKills = keyed("memory.events")["oom_kill"]
+	if data, err := os.ReadFile(path.Join(dir, "cpu.max")); err == nil {
+		f := strings.Fields(string(data))
+		if len(f) == 2 && f[0] != "max" {
+			quota, _ := strconv.ParseFloat(f[0], 64)
+			period, _ := strconv.ParseFloat(f[1], 64)
+			if period > 0 {
+				stats.cpuLimit = quota / period
+			}
+		}
+	}
+	if f, err := os.Open(path.Join(dir, "io.stat")); err == nil {
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			for _, kv := range strings.Fields(sc.Text())[1:] {
+				k, v, _ := strings.Cut(kv, "=")
+				n, _ := strconv.ParseUint(v, 10, 64)
+				switch k {
+				case "rbytes":
+					stats.ioRead += n
+				case "wbytes":
+					stats.ioWrite += n
+				}
+			}
+		}
+		f.Close()
+	}
+	return stats, nil
+}
+
+// readCgroupV1 reads the memory, cpu, cpuacct and blkio controllers of a cgroup v1 hierarchy.
+func readCgroupV1(dirs map[string]string) cgroupStats {
+	var stats cgroupStats
+	if dir, ok := dirs["cpuacct"]; ok {
+		stats.cpuSeconds = float64(readUintFile(path.Join(dir, "cpuacct.usage"))) / 1e9
+	}
+	if dir, ok := dirs["cpu"]; ok {
+		quota, _ := strconv.ParseFloat(readTrimmedFile(path.Join(dir, "cpu.cfs_quota_us")), 64)
+		period, _ := strconv.ParseFloat(readTrimmedFile(path.Join(dir, "cpu.cfs_period_us")), 64)
+		if quota > 0 && period > 0 {
+			stats.cpuLimit = quota / period
+		}
+	}
+	if dir, ok := dirs["memory"]; ok {
+		stats.memoryBytes = readUintFile(path.Join(dir, "memory.usage_in_bytes"))
+		if limit := readUintFile(path.Join(dir, "memory.limit_in_bytes")); limit < cgroupV1Unlimited {
+			stats.memoryLimit = limit
+		}
+		stats.oomKills = readKeyedFile(path.Join(dir, "memory.oom_control"))["oom_kill"]
+	}
+	if dir, ok := dirs["blkio"]; ok {
+		if f, err := os.Open(path.Join(dir, "blkio.throttle.io_service_bytes")); err == nil {
+			sc := bufio.NewScanner(f)
+			for sc.Scan() {
+				fields := strings.Fields(sc.Text())
+				if len(fields) != 3 {
+					continue
+				}
+				n, _ := strconv.ParseUint(fields[2], 10, 64)
+				switch fields[1] {
+				case "Read":
+					stats.ioRead += n
+				case "Write":
+					stats.ioWrite += n
+				}
+			}
+			f.Close()
+		}
+	}
+	return stats
+}
+
+// readTrimmedFile returns the trimmed content of a file, or "" when it cannot be read.
+func readTrimmedFile(p string) string {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readUintFile parses a single-value cgroup file; missing files and "max" read as 0.
+func readUintFile(p string) uint64 {
+	n, _ := strconv.ParseUint(readTrimmedFile(p), 10, 64)
+	return n
+}
+
+// readKeyedFile parses a "key value" per line cgroup file.
+func readKeyedFile(p string) map[string]uint64 {
+	out := map[string]uint64{}
+	for _, line := range strings.Split(readTrimmedFile(p), "\n") {
+		if k, v, ok := strings.Cut(line, " "); ok {
+			out[k], _ = strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+		}
+	}
+	return out
+}
+
+// dockerRuntime talks to the Docker Engine API over its unix socket.
+type dockerRuntime struct {
+	client *http.Client
+}
+
+// NewDockerRuntime creates a Docker Engine API client for a unix socket.
+//
+// Parameters:
+//   - socket: Path of the Docker socket.
+//
+// Returns:
+//   - ContainerRuntime: The Docker client; the socket is dialled per request.
+func NewDockerRuntime(socket string) ContainerRuntime {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}
+	return &dockerRuntime{client: &http.Client{Transport: transport}}
+}
+
+// Name implements ContainerRuntime.
+func (r *dockerRuntime) Name() string { return RuntimeDocker }
+
+// Close implements ContainerRuntime.
+func (r *dockerRuntime) Close() error {
+	r.client.CloseIdleConnections()
+	return nil
+}
+
+// get decodes the JSON response of a Docker API GET request.
+func (r *dockerRuntime) get(ctx context.Context, p string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://docker"+p, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GET %s: %s: %s", p, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// dockerContainer is the subset of GET /containers/{id}/json used by diagnostics.
+type dockerContainer struct {
+	ID           string    `json:"Id"`
+	Name         string    `json:"Name"`
+	Created      time.Time `json:"Created"`
+	Image        string    `json:"Image"`
+	RestartCount int       `json:"RestartCount"`
+	Config       struct {
+		Image string `json:"Image"`
+	} `json:"Config"`
+	State struct {
+		Status     string    `json:"Status"`
+		Pid        int       `json:"Pid"`
+		ExitCode   int       `json:"ExitCode"`
+		OOMKilled  bool      `json:"OOMKilled"`
+		FinishedAt time.Time `json:"FinishedAt"`
+	} `json:"State"`
+	HostConfig struct {
+		Memory    int64 `json:"Memory"`
+		NanoCpus  int64 `json:"NanoCpus"`
+		CPUQuota  int64 `json:"CpuQuota"`
+		CPUPeriod int64 `json:"CpuPeriod"`
+	} `json:"HostConfig"`
+}
+
+// Containers implements ContainerRuntime.
+func (r *dockerRuntime) Containers(ctx context.Context) ([]ContainerInfo, error) {
+	var list []struct {
+		ID string `json:"Id"`
+	}
+	if err := r.get(ctx, "/containers/json?all=true", &list); err != nil {
+		return nil, err
+	}
+	imageCreated := map[string]time.Time{}
+	out := make([]ContainerInfo, 0, len(list))
+	for _, item := range list {
+		var c dockerContainer
+		if err := r.get(ctx, "/containers/"+url.PathEscape(item.ID)+"/json", &c); err != nil {
+			return nil, err
+		}
+		if _, ok := imageCreated[c.Image]; !ok {
+			var img struct {
+				Created time.Time `json:"Created"`
+			}
+			err := r.get(ctx, "/images/"+url.PathEscape(c.Image)+"/json", &img)
+			if err != nil && ctx.Err() != nil {
+				return nil, err
+			}
+			imageCreated[c.Image] = img.Created // zero when the image was removed
+		}
+		info := ContainerInfo{
+			ID:           c.ID,
+			Name:         strings.TrimPrefix(c.Name, "/"),
+			Image:        c.Config.Image,
+			ImageCreated: imageCreated[c.Image],
+			Created:      c.Created,
+			State:        c.State.Status,
+			Pid:          c.State.Pid,
+			ExitCode:     c.State.ExitCode,
+			FinishedAt:   c.State.FinishedAt,
+			Restarts:     c.RestartCount,
+			OOMKilled:    c.State.OOMKilled,
+			MemoryLimit:  c.HostConfig.Memory,
+		}
+		switch {
+		case c.HostConfig.NanoCpus > 0:
+			info.CPULimit = float64(c.HostConfig.NanoCpus) / 1e9
+		case c.HostConfig.CPUQuota > 0 && c.HostConfig.CPUPeriod > 0:
+			info.CPULimit = float64(c.HostConfig.CPUQuota) / float64(c.HostConfig.CPUPeriod)
+		}
+		if info.Name == "" {
+			info.Name = c.ID
+		}
+		out = append(out, info)
+	}
+	return out, nil
+}
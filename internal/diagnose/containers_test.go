@@ -0,0 +1,307 @@
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// socketPath returns a unix socket path short enough for sun_path.
+func socketPath(t *testing.T, name string) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "srediag")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, name)
+}
+
+// cgroupFixtures writes a cgroup v2 container (pid 100) and a cgroup v1 container (pid 200) under a fake host root.
+func cgroupFixtures(t *testing.T) {
+	t.Helper()
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"proc/100/cgroup": "0::/system.slice/docker-web.scope\n",
+		"sys/fs/cgroup/system.slice/docker-web.scope/cpu.stat":       "usage_usec 2500000\nuser_usec 2000000\n",
+		"sys/fs/cgroup/system.slice/docker-web.scope/cpu.max":        "max 100000\n",
+		"sys/fs/cgroup/system.slice/docker-web.scope/memory.current": "104857600\n",
+		"sys/fs/cgroup/system.slice/docker-web.scope/memory.max":     "max\n",
+		"sys/fs/cgroup/system.slice/docker-web.scope/memory.events":  "low 0\nhigh 0\nmax 4\noom 2\noom_kill 2\n",
+		"sys/fs/cgroup/system.slice/docker-web.scope/io.stat":        "8:0 rbytes=4096 wbytes=8192 rios=1 wios=2\n8:16 rbytes=1024 wbytes=0\n",
+
+		"proc/200/cgroup": "4:memory:/docker/db\n3:cpu,cpuacct:/docker/db\n2:blkio:/docker/db\n1:name=systemd:/docker/db\n",
+		"sys/fs/cgroup/memory/docker/db/memory.usage_in_bytes":          "2097152\n",
+		"sys/fs/cgroup/memory/docker/db/memory.limit_in_bytes":          "536870912\n",
+		"sys/fs/cgroup/memory/docker/db/memory.oom_control":             "oom_kill_disable 0\nunder_oom 0\noom_kill 0\n",
+		"sys/fs/cgroup/cpu,cpuacct/docker/db/cpuacct.usage":             "3000000000\n",
+		"sys/fs/cgroup/cpu,cpuacct/docker/db/cpu.cfs_quota_us":          "50000\n",
+		"sys/fs/cgroup/cpu,cpuacct/docker/db/cpu.cfs_period_us":         "100000\n",
+		"sys/fs/cgroup/blkio/docker/db/blkio.throttle.io_service_bytes": "8:0 Read 100\n8:0 Write 200\n8:0 Total 300\nTotal 300\n",
+	})
+	orig := hostRoot
+	hostRoot = root
+	t.Cleanup(func() { hostRoot = orig })
+}
+
+// startFakeDocker serves the Docker Engine API endpoints used by dockerRuntime on a unix socket.
+func startFakeDocker(t *testing.T, socket string) {
+	t.Helper()
+	mux := http.NewServeMux()
+	reply := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte(body)) }
+	}
+	mux.HandleFunc("GET /containers/json", reply(`[{"Id":"web1"},{"Id":"db1"},{"Id":"job1"}]`))
+	mux.HandleFunc("GET /containers/web1/json", reply(`{"Id":"web1","Name":"/web","Image":"sha256:old",
+		"RestartCount":5,"Config":{"Image":"web:1.0"},"State":{"Status":"running","Pid":100,"OOMKilled":false},
+		"HostConfig":{"Memory":0,"NanoCpus":0}}`))
+	mux.HandleFunc("GET /containers/db1/json", reply(`{"Id":"db1","Name":"/db","Image":"sha256:new",
+		"Config":{"Image":"postgres:16"},"State":{"Status":"running","Pid":200},
+		"HostConfig":{"Memory":536870912,"NanoCpus":500000000}}`))
+	mux.HandleFunc("GET /containers/job1/json", reply(`{"Id":"job1","Name":"/job","Image":"sha256:new",
+		"Config":{"Image":"postgres:16"},"State":{"Status":"exited","ExitCode":137,"OOMKilled":true,
+		"FinishedAt":"2026-01-02T10:00:00Z"},"HostConfig":{"Memory":67108864}}`))
+	mux.HandleFunc("GET /images/sha256:old/json", reply(`{"Created":"2025-01-01T00:00:00Z"}`))
+	mux.HandleFunc("GET /images/sha256:new/json", reply(`{"Created":"2026-01-01T00:00:00Z"}`))
+
+	ln, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	srv := httptest.NewUnstartedServer(mux)
+	srv.Listener = ln
+	srv.Start()
+	t.Cleanup(srv.Close)
+}
+
+func TestContainerDiagnostics_Docker(t *testing.T) {
+	cgroupFixtures(t)
+	socket := socketPath(t, "docker.sock")
+	startFakeDocker(t, socket)
+
+	rt, err := NewContainerRuntime(RuntimeAuto, socket, nil)
+	require.NoError(t, err)
+	defer rt.Close()
+	assert.Equal(t, RuntimeDocker, rt.Name())
+
+	d := NewContainerDiagnostics(core.NewTestLogger(&bytes.Buffer{}), rt, ContainerOptions{})
+	d.now = func() time.Time { return time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC) }
+	r, err := d.Run(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 3.0, r.Measurements["containers.total"])
+	assert.Equal(t, 2.0, r.Measurements["containers.running"])
+	assert.Equal(t, 2.5, r.Measurements["container.cpu_seconds/web"])
+	assert.Equal(t, 104857600.0, r.Measurements["container.memory_bytes/web"])
+	assert.Equal(t, 5120.0, r.Measurements["container.io_read_bytes/web"])
+	assert.Equal(t, 8192.0, r.Measurements["container.io_write_bytes/web"])
+	assert.NotContains(t, r.Measurements, "container.memory_limit_bytes/web")
+	assert.Equal(t, 3.0, r.Measurements["container.cpu_seconds/db"])
+	assert.Equal(t, 536870912.0, r.Measurements["container.memory_limit_bytes/db"])
+	assert.Equal(t, 300.0, r.Measurements["container.io_write_bytes/db"]+r.Measurements["container.io_read_bytes/db"])
+	assert.Equal(t, 5.0, r.Measurements["container.restarts/web"])
+
+	oom := findingsByCheck(r, "container.oom")
+	require.Len(t, oom, 2, "cgroup oom_kill on web, runtime OOMKilled on job")
+	assert.Equal(t, "container/job", oom[0].Resource)
+	assert.Equal(t, "container/web", oom[1].Resource)
+	assert.Equal(t, "2", oom[1].Details["oom_kills"])
+
+	exits := findingsByCheck(r, "container.exit")
+	require.Len(t, exits, 1)
+	assert.Equal(t, "137", exits[0].Details["exit_code"])
+	assert.Contains(t, exits[0].Message, "SIGKILL")
+
+	restarts := findingsByCheck(r, "container.restarts")
+	require.Len(t, restarts, 1)
+	assert.Equal(t, "container/web", restarts[0].Resource)
+
+	images := findingsByCheck(r, "container.image-age")
+	require.Len(t, images, 1)
+	assert.Contains(t, images[0].Message, "web:1.0 created 366 days ago")
+
+	limits := findingsByCheck(r, "container.limits")
+	require.Len(t, limits, 1, "db has limits and job is not running")
+	assert.Equal(t, SeverityWarning, limits[0].Severity)
+	assert.Contains(t, limits[0].Message, "without memory and CPU limits")
+}
+
+// Protobuf helpers for the fake containerd server.
+func pbString(b []byte, num protowire.Number, s string) []byte {
+	return protowire.AppendBytes(protowire.AppendTag(b, num, protowire.BytesType), []byte(s))
+}
+
+func pbMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	return protowire.AppendBytes(protowire.AppendTag(b, num, protowire.BytesType), msg)
+}
+
+func pbVarint(b []byte, num protowire.Number, v uint64) []byte {
+	return protowire.AppendVarint(protowire.AppendTag(b, num, protowire.VarintType), v)
+}
+
+func pbTime(b []byte, num protowire.Number, t time.Time) []byte {
+	return pbMessage(b, num, pbVarint(pbVarint(nil, 1, uint64(t.Unix())), 2, uint64(t.Nanosecond())))
+}
+
+func pbAny(b []byte, num protowire.Number, typeURL string, value []byte) []byte {
+	return pbMessage(b, num, pbMessage(pbString(nil, 1, typeURL), 2, value))
+}
+
+// startFakeContainerd serves the containerd API methods used by containerdRuntime on a unix socket.
+func startFakeContainerd(t *testing.T, socket string) {
+	t.Helper()
+	spec := `{"annotations":{"io.kubernetes.container.restartCount":"4"},
+		"linux":{"cgroupsPath":"/system.slice/docker-web.scope","resources":{"memory":{"limit":268435456},"cpu":{"quota":200000,"period":100000}}}}`
+	app := pbString(nil, 1, "c-app")
+	for k, v := range map[string]string{
+		"io.kubernetes.container.name": "app", "io.kubernetes.pod.name": "web-0", "io.kubernetes.pod.namespace": "shop",
+	} {
+		app = pbMessage(app, 2, pbString(pbString(nil, 1, k), 2, v))
+	}
+	app = pbString(app, 3, "registry/app:2")
+	app = pbAny(app, 5, "types.containerd.io/opencontainers/runtime-spec/1/Spec", []byte(spec))
+	app = pbTime(app, 8, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	crashed := pbString(nil, 1, "c-crashed")
+	crashed = pbString(crashed, 3, "registry/app:2")
+	crashed = pbMessage(crashed, 10, pbAny(pbString(nil, 1, criMetadataExtension), 2, "types.containerd.io/cri/Metadata",
+		[]byte(`{"Version":"v1","Metadata":{"Config":{"metadata":{"name":"crashed","attempt":7}}}}`)))
+	responses := map[string][]byte{
+		containerdNamespacesList: pbMessage(nil, 1, pbString(nil, 1, "k8s.io")),
+		containerdContainersList: pbMessage(pbMessage(nil, 1, app), 1, crashed),
+		containerdTasksList: pbMessage(pbMessage(nil, 1,
+			pbVarint(pbVarint(pbString(nil, 1, "c-app"), 3, 0), 4, 2)), 1,
+			pbTime(pbVarint(pbVarint(pbString(nil, 1, "c-crashed"), 4, 3), 9, 139), 10, time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC))),
+		containerdImagesList: pbMessage(nil, 1, pbTime(pbString(nil, 1, "registry/app:2"), 7, time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC))),
+	}
+
+	srv := grpc.NewServer(grpc.ForceServerCodec(rawCodec{}), grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		if method != containerdNamespacesList {
+			md, _ := metadata.FromIncomingContext(stream.Context())
+			if got := md.Get(containerdNamespaceHeader); len(got) != 1 || got[0] != "k8s.io" {
+				return errors.New("missing namespace")
+			}
+		}
+		var req []byte
+		if err := stream.RecvMsg(&req); err != nil {
+			return err
+		}
+		resp := responses[method]
+		return stream.SendMsg(&resp)
+	}))
+	ln, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(srv.Stop)
+}
+
+func TestContainerDiagnostics_Containerd(t *testing.T) {
+	cgroupFixtures(t)
+	socket := socketPath(t, "containerd.sock")
+	startFakeContainerd(t, socket)
+
+	rt, err := NewContainerRuntime("", socket, nil)
+	require.NoError(t, err)
+	defer rt.Close()
+	assert.Equal(t, RuntimeContainerd, rt.Name())
+
+	containers, err := rt.Containers(context.Background())
+	require.NoError(t, err)
+	require.Len(t, containers, 2)
+	app := containers[0]
+	assert.Equal(t, "shop/web-0/app", app.Name)
+	assert.Equal(t, "k8s.io", app.Namespace)
+	assert.Equal(t, "running", app.State)
+	assert.Equal(t, 4, app.Restarts)
+	assert.Equal(t, int64(268435456), app.MemoryLimit)
+	assert.Equal(t, 2.0, app.CPULimit)
+	assert.Equal(t, time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC), app.ImageCreated)
+	crashed := containers[1]
+	assert.Equal(t, "c-crashed", crashed.Name)
+	assert.Equal(t, "exited", crashed.State)
+	assert.Equal(t, 139, crashed.ExitCode)
+	assert.Equal(t, 7, crashed.Restarts)
+
+	d := NewContainerDiagnostics(core.NewTestLogger(&bytes.Buffer{}), rt, ContainerOptions{ImageMaxAge: 24 * time.Hour})
+	r, err := d.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2.5, r.Measurements["container.cpu_seconds/k8s.io/shop/web-0/app"], "cgroup from the OCI spec without a pid")
+	assert.Len(t, findingsByCheck(r, "container.restarts"), 2)
+	assert.Len(t, findingsByCheck(r, "container.image-age"), 2)
+	exits := findingsByCheck(r, "container.exit")
+	require.Len(t, exits, 1)
+	assert.Equal(t, "container/k8s.io/c-crashed", exits[0].Resource)
+	assert.Contains(t, exits[0].Message, "SIGSEGV")
+	assert.Empty(t, findingsByCheck(r, "container.limits"))
+}
+
+// fakeRuntime is an in-memory ContainerRuntime.
+type fakeRuntime struct {
+	containers []ContainerInfo
+	err        error
+	closed     bool
+}
+
+func (f *fakeRuntime) Name() string { return "fake" }
+func (f *fakeRuntime) Containers(context.Context) ([]ContainerInfo, error) {
+	return f.containers, f.err
+}
+func (f *fakeRuntime) Close() error { f.closed = true; return nil }
+
+func TestCLI_ContainerDiagnostics(t *testing.T) {
+	rt := &fakeRuntime{containers: []ContainerInfo{{ID: "x", Name: "x", State: "exited", ExitCode: 1}}}
+	var gotKind, gotSocket string
+	var gotNamespaces []string
+	orig := newContainerRuntimeFunc
+	newContainerRuntimeFunc = func(kind, socket string, namespaces []string) (ContainerRuntime, error) {
+		gotKind, gotSocket, gotNamespaces = kind, socket, namespaces
+		return rt, nil
+	}
+	defer func() { newContainerRuntimeFunc = orig }()
+
+	cmd := &cobra.Command{Use: "containers"}
+	cmd.Flags().String("runtime", RuntimeAuto, "")
+	cmd.Flags().String("socket", "", "")
+	cmd.Flags().StringSlice("namespace", nil, "")
+	cmd.Flags().Duration("image-max-age", DefaultImageMaxAge, "")
+	cmd.Flags().String("output", "table", "")
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	require.NoError(t, cmd.Flags().Set("namespace", "moby"))
+	require.NoError(t, cmd.Flags().Set("output", "json"))
+
+	ctx := &core.AppContext{Logger: core.NewTestLogger(&bytes.Buffer{}), Config: core.NewConfig()}
+	ctx.Config.Diagnostics.Containers.Runtime = RuntimeContainerd
+	ctx.Config.Diagnostics.Containers.Socket = "/run/k3s/containerd/containerd.sock"
+	require.NoError(t, CLI_ContainerDiagnostics(ctx, cmd, nil))
+	assert.Equal(t, RuntimeContainerd, gotKind)
+	assert.Equal(t, "/run/k3s/containerd/containerd.sock", gotSocket)
+	assert.Equal(t, []string{"moby"}, gotNamespaces)
+	assert.True(t, rt.closed)
+
+	var r Report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &r))
+	assert.Equal(t, "containers", r.Diagnostic)
+	require.Len(t, r.Findings, 1)
+	assert.Equal(t, "container.exit", r.Findings[0].Check)
+
+	rt.err = errors.New("permission denied")
+	err := CLI_ContainerDiagnostics(ctx, cmd, nil)
+	assert.ErrorContains(t, err, "failed to list fake containers: permission denied")
+
+	ctx.Config.Diagnostics.Containers.ImageMaxAge = "old"
+	assert.ErrorContains(t, CLI_ContainerDiagnostics(ctx, cmd, nil), "invalid image max age")
+}
@@ -0,0 +1,439 @@
+package diagnose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file defines the optional eBPF tracing module of 'diagnose performance' (perfprofiler): the result model
+// and the conversion of a time-boxed trace into report findings and measurements. The probes are CO-RE programs
+// written in C under bpf/, compiled by bpf2go into the binary (go generate) and loaded in ebpf_linux.go:
+//   - offcpu: off-CPU time per process and its log2 histogram (sched/sched_switch).
+//   - biolatency: block IO latency histograms per device (block/block_rq_issue, block/block_rq_complete).
+//   - tcpretrans: TCP retransmits per destination (tcp/tcp_retransmit_skb).
+//   - tcpconnect: TCP connect latency and failures per destination (sock/inet_sock_set_state).
+//   - syscalls: syscall counts per process (raw_syscalls/sys_enter).
+//
+// The programs attach to BTF-enabled tracepoints (tp_btf) and read kernel structs through BTF relocations resolved
+// against /sys/kernel/btf/vmlinux at load time, so one binary runs on any kernel 5.5+ with BTF; no compiler, kernel
+// headers or tracefs are needed on the host.
+//
+// Usage:
+//   - Set PerformanceOptions.EBPF (or --ebpf) to run the probes for PerformanceOptions.Duration.
+//   - When BPF is not permitted (no CAP_BPF/CAP_PERFMON, lockdown, no kernel BTF) the report carries a
+//     perf.ebpf.unsupported finding instead of failing.
+//
+// Best Practices:
+//   - Keep the trace window short (seconds); the probes run on every context switch and syscall.
+
+// eBPF probe names (PerformanceOptions.Probes).
+const (
+	ProbeOffCPU     = "offcpu"
+	ProbeBlockIO    = "biolatency"
+	ProbeTCPRetrans = "tcpretrans"
+	ProbeTCPConnect = "tcpconnect"
+	ProbeSyscalls   = "syscalls"
+)
+
+// EBPFProbes lists every probe, in report order.
+var EBPFProbes = []string{ProbeOffCPU, ProbeBlockIO, ProbeTCPRetrans, ProbeTCPConnect, ProbeSyscalls}
+
+// Defaults and thresholds of the eBPF tracing module.
+const (
+	DefaultTraceDuration = 10 * time.Second
+	DefaultTraceTop      = 10
+
+	// blockIOWarning and blockIOCritical are p99 block IO latencies that raise a device's severity.
+	blockIOWarning  = 100 * time.Millisecond
+	blockIOCritical = time.Second
+	// connectWarning is the mean TCP connect latency that raises a destination's severity.
+	connectWarning = 500 * time.Millisecond
+	// retransmitWarning is the number of retransmits to one destination within the window that warns.
+	retransmitWarning = 10
+	// uninterruptibleWarning is the share of the window a process may spend in uninterruptible sleep.
+	uninterruptibleWarning = 0.25
+)
+
+// ErrEBPFUnsupported marks a host where eBPF tracing cannot run; the report gets a finding instead of an error.
+var ErrEBPFUnsupported = errors.New("eBPF tracing unsupported")
+
+// PerformanceOptions configures 'diagnose performance'.
+//
+// Fields:
+//   - EBPF: Run the eBPF tracing probes.
+//   - Duration: Trace window; defaults to DefaultTraceDuration and is shortened to fit the run timeout.
+//   - Probes: Probes to run (EBPFProbes names); empty runs all.
+//   - Top: Processes, devices or destinations reported per probe; defaults to DefaultTraceTop.
+type PerformanceOptions struct {
+	EBPF     bool
+	Duration time.Duration
+	Probes   []string
+	Top      int
+}
+
+// Log2Histogram counts latencies in power-of-two microsecond buckets: bucket i holds values in [2^i, 2^(i+1)) µs
+// (bucket 0 also holds values below 1 µs).
+type Log2Histogram [64]uint64
+
+// Count returns the number of samples.
+func (h *Log2Histogram) Count() uint64 {
+	var n uint64
+	for _, c := range h {
+		n += c
+	}
+	return n
+}
+
+// Percentile returns the upper bound of the bucket holding the p-th percentile (0 < p <= 100), or 0 without samples.
+func (h *Log2Histogram) Percentile(p float64) time.Duration {
+	total := h.Count()
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(float64(total)*p/100 + 0.5)
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	for i, c := range h {
+		seen += c
+		if seen >= rank {
+			return log2BucketBound(i)
+		}
+	}
+	return log2BucketBound(len(h) - 1)
+}
+
+// Add records a latency.
+func (h *Log2Histogram) Add(d time.Duration) {
+	us := uint64(d / time.Microsecond)
+	if us == 0 {
+		h[0]++
+		return
+	}
+	h[bits.Len64(us)-1]++
+}
+
+// Details renders the non-empty buckets as "le_<bound>" keys, the format used in finding details.
+func (h *Log2Histogram) Details(details map[string]string) {
+	for i, c := range h {
+		if c > 0 {
+			details["le_"+log2BucketBound(i).String()] = strconv.FormatUint(c, 10)
+		}
+	}
+}
+
+// log2BucketBound is the exclusive upper bound of bucket i.
+func log2BucketBound(i int) time.Duration {
+	if i >= 43 { // 2^44 µs overflows time.Duration
+		return time.Duration(1<<63 - 1)
+	}
+	return time.Duration(uint64(1)<<(i+1)) * time.Microsecond
+}
+
+// ProcessOffCPU is the off-CPU time of one process.
+type ProcessOffCPU struct {
+	PID             int
+	Comm            string
+	Switches        uint64
+	Total           time.Duration
+	Uninterruptible time.Duration
+}
+
+// BlockDeviceLatency is the block IO latency of one device.
+type BlockDeviceLatency struct {
+	Device    string
+	Histogram Log2Histogram
+	Total     time.Duration
+}
+
+// TCPDestination aggregates TCP events towards one address and port.
+type TCPDestination struct {
+	Address     string
+	Retransmits uint64
+	Connects    uint64
+	Failures    uint64
+	Total       time.Duration // Summed latency of the successful connects
+}
+
+// ProcessSyscalls counts the syscalls of one process.
+type ProcessSyscalls struct {
+	PID   int
+	Comm  string
+	Total uint64
+	ByNR  map[int]uint64
+}
+
+// TraceResult is the data collected by one eBPF trace.
+//
+// Fields:
+//   - Duration: Actual trace window.
+//   - Unsupported: Reason by probe for the probes that could not be attached.
+//   - OffCPU, OffCPUHistogram: Per-process off-CPU time and the latency of every switch-in.
+//   - BlockIO: Per-device latency histograms.
+//   - TCPRetransmits, TCPConnects: Per-destination counters; TCPConnectHistogram holds successful connect latency.
+//   - Syscalls: Per-process syscall counts; SyscallName names a syscall number (nil prints "nr_<n>").
+type TraceResult struct {
+	Duration            time.Duration
+	Unsupported         map[string]string
+	OffCPU              []ProcessOffCPU
+	OffCPUHistogram     Log2Histogram
+	BlockIO             []BlockDeviceLatency
+	TCPRetransmits      []TCPDestination
+	TCPConnects         []TCPDestination
+	TCPConnectHistogram Log2Histogram
+	Syscalls            []ProcessSyscalls
+	SyscallName         func(nr int) string
+}
+
+// traceEBPF runs the probes; patchable in tests.
+var traceEBPF = runEBPFTrace
+
+// selectedProbes validates the requested probes; empty selects all.
+func selectedProbes(probes []string) ([]string, error) {
+	if len(probes) == 0 {
+		return EBPFProbes, nil
+	}
+	for _, p := range probes {
+		if !slices.Contains(EBPFProbes, p) {
+			return nil, fmt.Errorf("unknown eBPF probe %q (available: %s)", p, strings.Join(EBPFProbes, ", "))
+		}
+	}
+	return probes, nil
+}
+
+// traceWindow shortens the trace window so that reading the maps fits before the run deadline.
+func traceWindow(ctx context.Context, d time.Duration) time.Duration {
+	if d <= 0 {
+		d = DefaultTraceDuration
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if budget := time.Until(deadline) * 8 / 10; budget < d {
+			d = budget
+		}
+	}
+	return d
+}
+
+// addTraceFindings converts a trace into findings and measurements.
+func addTraceFindings(report *Report, res *TraceResult, probes []string, top int) {
+	if top <= 0 {
+		top = DefaultTraceTop
+	}
+	report.SetMeasurement("perf.ebpf.duration_seconds", res.Duration.Seconds())
+	for _, p := range probes {
+		if reason, ok := res.Unsupported[p]; ok {
+			report.Add(unsupportedFinding(p, reason))
+		}
+	}
+	window := res.Duration
+	if window <= 0 {
+		window = time.Second
+	}
+	ran := func(p string) bool {
+		_, failed := res.Unsupported[p]
+		return slices.Contains(probes, p) && !failed
+	}
+
+	if ran(ProbeOffCPU) {
+		offcpu := append([]ProcessOffCPU(nil), res.OffCPU...)
+		sort.Slice(offcpu, func(i, j int) bool { return offcpu[i].Total > offcpu[j].Total })
+		report.SetMeasurement("perf.offcpu.switches", float64(res.OffCPUHistogram.Count()))
+		report.SetMeasurement("perf.offcpu.p99_us", float64(res.OffCPUHistogram.Percentile(99)/time.Microsecond))
+		for _, p := range offcpu[:min(top, len(offcpu))] {
+			sev := SeverityInfo
+			if float64(p.Uninterruptible) >= float64(window)*uninterruptibleWarning {
+				sev = SeverityWarning
+			}
+			report.Add(Finding{
+				Check:    "perf.offcpu",
+				Severity: sev,
+				Resource: processResource(p.PID, p.Comm),
+				Message: fmt.Sprintf("off-CPU %s over %d switches (%s uninterruptible) in %s",
+					p.Total.Round(time.Millisecond), p.Switches, p.Uninterruptible.Round(time.Millisecond), window.Round(time.Millisecond)),
+				Details: map[string]string{
+					"off_cpu_ms":         strconv.FormatInt(p.Total.Milliseconds(), 10),
+					"uninterruptible_ms": strconv.FormatInt(p.Uninterruptible.Milliseconds(), 10),
+					"switches":           strconv.FormatUint(p.Switches, 10),
+				},
+			})
+		}
+	}
+
+	if ran(ProbeBlockIO) {
+		var all Log2Histogram
+		devices := append([]BlockDeviceLatency(nil), res.BlockIO...)
+		sort.Slice(devices, func(i, j int) bool { return devices[i].Histogram.Count() > devices[j].Histogram.Count() })
+		for _, d := range devices {
+			for i, c := range d.Histogram {
+				all[i] += c
+			}
+		}
+		report.SetMeasurement("perf.biolatency.ios", float64(all.Count()))
+		report.SetMeasurement("perf.biolatency.p50_us", float64(all.Percentile(50)/time.Microsecond))
+		report.SetMeasurement("perf.biolatency.p99_us", float64(all.Percentile(99)/time.Microsecond))
+		for _, d := range devices[:min(top, len(devices))] {
+			n := d.Histogram.Count()
+			p99 := d.Histogram.Percentile(99)
+			sev := SeverityInfo
+			switch {
+			case p99 >= blockIOCritical:
+				sev = SeverityCritical
+			case p99 >= blockIOWarning:
+				sev = SeverityWarning
+			}
+			details := map[string]string{
+				"ios":     strconv.FormatUint(n, 10),
+				"p50_us":  strconv.FormatInt(d.Histogram.Percentile(50).Microseconds(), 10),
+				"p99_us":  strconv.FormatInt(p99.Microseconds(), 10),
+				"mean_us": strconv.FormatInt(meanDuration(d.Total, n).Microseconds(), 10),
+			}
+			d.Histogram.Details(details)
+			report.Add(Finding{
+				Check:    "perf.biolatency",
+				Severity: sev,
+				Resource: d.Device,
+				Message:  fmt.Sprintf("%d IOs, p50 < %s, p99 < %s", n, d.Histogram.Percentile(50), p99),
+				Details:  details,
+			})
+		}
+	}
+
+	if ran(ProbeTCPRetrans) {
+		dests := append([]TCPDestination(nil), res.TCPRetransmits...)
+		sort.Slice(dests, func(i, j int) bool { return dests[i].Retransmits > dests[j].Retransmits })
+		var total uint64
+		for _, d := range dests {
+			total += d.Retransmits
+		}
+		report.SetMeasurement("perf.tcp.retransmits", float64(total))
+		for _, d := range dests[:min(top, len(dests))] {
+			sev := SeverityInfo
+			if d.Retransmits >= retransmitWarning {
+				sev = SeverityWarning
+			}
+			report.Add(Finding{
+				Check:    "perf.tcp.retransmit",
+				Severity: sev,
+				Resource: d.Address,
+				Message:  fmt.Sprintf("%d TCP retransmits in %s", d.Retransmits, window.Round(time.Millisecond)),
+				Details:  map[string]string{"retransmits": strconv.FormatUint(d.Retransmits, 10)},
+			})
+		}
+	}
+
+	if ran(ProbeTCPConnect) {
+		dests := append([]TCPDestination(nil), res.TCPConnects...)
+		sort.Slice(dests, func(i, j int) bool {
+			return dests[i].Connects+dests[i].Failures > dests[j].Connects+dests[j].Failures
+		})
+		var failures uint64
+		for _, d := range dests {
+			failures += d.Failures
+		}
+		report.SetMeasurement("perf.tcp.connects", float64(res.TCPConnectHistogram.Count()))
+		report.SetMeasurement("perf.tcp.connect_failures", float64(failures))
+		report.SetMeasurement("perf.tcp.connect_p99_us", float64(res.TCPConnectHistogram.Percentile(99)/time.Microsecond))
+		for _, d := range dests[:min(top, len(dests))] {
+			mean := meanDuration(d.Total, d.Connects)
+			sev := SeverityInfo
+			if d.Failures > 0 || mean >= connectWarning {
+				sev = SeverityWarning
+			}
+			report.Add(Finding{
+				Check:    "perf.tcp.connect",
+				Severity: sev,
+				Resource: d.Address,
+				Message:  fmt.Sprintf("%d connects (mean %s), %d failed", d.Connects, mean.Round(time.Microsecond), d.Failures),
+				Details: map[string]string{
+					"connects": strconv.FormatUint(d.Connects, 10),
+					"failures": strconv.FormatUint(d.Failures, 10),
+					"mean_us":  strconv.FormatInt(mean.Microseconds(), 10),
+				},
+			})
+		}
+	}
+
+	if ran(ProbeSyscalls) {
+		procs := append([]ProcessSyscalls(nil), res.Syscalls...)
+		sort.Slice(procs, func(i, j int) bool { return procs[i].Total > procs[j].Total })
+		var total uint64
+		for _, p := range procs {
+			total += p.Total
+		}
+		report.SetMeasurement("perf.syscalls.total", float64(total))
+		report.SetMeasurement("perf.syscalls.rate", float64(total)/window.Seconds())
+		for _, p := range procs[:min(top, len(procs))] {
+			details := map[string]string{"total": strconv.FormatUint(p.Total, 10)}
+			nrs := make([]int, 0, len(p.ByNR))
+			for nr, c := range p.ByNR {
+				details["sys_"+syscallName(res.SyscallName, nr)] = strconv.FormatUint(c, 10)
+				nrs = append(nrs, nr)
+			}
+			sort.Slice(nrs, func(i, j int) bool {
+				if p.ByNR[nrs[i]] != p.ByNR[nrs[j]] {
+					return p.ByNR[nrs[i]] > p.ByNR[nrs[j]]
+				}
+				return nrs[i] < nrs[j]
+			})
+			var names []string
+			for _, nr := range nrs[:min(3, len(nrs))] {
+				names = append(names, syscallName(res.SyscallName, nr))
+			}
+			report.Add(Finding{
+				Check:    "perf.syscalls",
+				Severity: SeverityInfo,
+				Resource: processResource(p.PID, p.Comm),
+				Message: fmt.Sprintf("%d syscalls (%.0f/s), top: %s", p.Total, float64(p.Total)/window.Seconds(),
+					strings.Join(names, ", ")),
+				Details: details,
+			})
+		}
+	}
+}
+
+// unsupportedFinding reports a probe (or "ebpf" for the whole module) that could not run.
+func unsupportedFinding(probe, reason string) Finding {
+	return Finding{
+		Check:    "perf.ebpf.unsupported",
+		Severity: SeverityInfo,
+		Resource: probe,
+		Message:  fmt.Sprintf("eBPF %s tracing unsupported on this host: %s", probe, reason),
+		Details:  map[string]string{"reason": reason},
+	}
+}
+
+// processResource names a process in findings.
+func processResource(pid int, comm string) string {
+	if comm == "" {
+		return "pid " + strconv.Itoa(pid)
+	}
+	return fmt.Sprintf("pid %d (%s)", pid, comm)
+}
+
+// syscallName names a syscall number.
+func syscallName(names func(int) string, nr int) string {
+	if names != nil {
+		if n := names(nr); n != "" {
+			return n
+		}
+	}
+	return "nr_" + strconv.Itoa(nr)
+}
+
+// meanDuration divides total by n, or returns 0.
+func meanDuration(total time.Duration, n uint64) time.Duration {
+	if n == 0 {
+		return 0
+	}
+	return total / time.Duration(n)
+}
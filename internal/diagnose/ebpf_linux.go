@@ -0,0 +1,417 @@
+//go:build linux
+
+package diagnose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/rlimit"
+	"golang.org/x/sys/unix"
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -tags linux -target bpfel,bpfeb -cflags "-Wall -Werror -Ibpf/include" offcpu bpf/offcpu.bpf.c
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -tags linux -target bpfel,bpfeb -cflags "-Wall -Werror -Ibpf/include" biolatency bpf/biolatency.bpf.c
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -tags linux -target bpfel,bpfeb -cflags "-Wall -Werror -Ibpf/include" tcpretrans bpf/tcpretrans.bpf.c
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -tags linux -target bpfel,bpfeb -cflags "-Wall -Werror -Ibpf/include" tcpconnect bpf/tcpconnect.bpf.c
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -tags linux -target bpfel,bpfeb -cflags "-Wall -Werror -Ibpf/include" syscalls bpf/syscalls.bpf.c
+
+// runEBPFTrace attaches the probes, waits for the window and collects the maps.
+func runEBPFTrace(ctx context.Context, probes []string, window time.Duration) (*TraceResult, error) {
+	if err := rlimit.RemoveMemlock(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEBPFUnsupported, err)
+	}
+	// A one-entry map tells "BPF is not permitted here" apart from per-probe problems.
+	probe, err := ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.Array, KeySize: 4, ValueSize: 8, MaxEntries: 1})
+	if err != nil {
+		reason, _ := unsupportedReason(err)
+		return nil, fmt.Errorf("%w: %s", ErrEBPFUnsupported, reason)
+	}
+	probe.Close()
+
+	res := &TraceResult{Unsupported: make(map[string]string), SyscallName: linuxSyscallName}
+	builders := map[string]func() (*bpfProbe, error){
+		ProbeOffCPU:     newOffCPUProbe,
+		ProbeBlockIO:    newBlockIOProbe,
+		ProbeTCPRetrans: newTCPRetransProbe,
+		ProbeTCPConnect: newTCPConnectProbe,
+		ProbeSyscalls:   newSyscallsProbe,
+	}
+	var attached []*bpfProbe
+	defer func() {
+		for _, p := range attached {
+			p.Close()
+		}
+	}()
+	for _, name := range probes {
+		p, err := builders[name]()
+		if err != nil {
+			reason, ok := unsupportedReason(err)
+			if !ok {
+				return nil, fmt.Errorf("eBPF probe %s: %w", name, err)
+			}
+			res.Unsupported[name] = reason
+			continue
+		}
+		attached = append(attached, p)
+	}
+
+	start := time.Now()
+	if len(attached) > 0 {
+		timer := time.NewTimer(window)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	res.Duration = time.Since(start)
+	for _, p := range attached {
+		if err := p.collect(res); err != nil {
+			return nil, fmt.Errorf("failed to read eBPF maps: %w", err)
+		}
+	}
+	return res, nil
+}
+
+// unsupportedReason explains an error that means a probe cannot run on this host.
+func unsupportedReason(err error) (string, bool) {
+	var verr *ebpf.VerifierError
+	switch {
+	case errors.Is(err, os.ErrPermission):
+		return "permission denied (needs root, or CAP_BPF and CAP_PERFMON)", true
+	case errors.Is(err, ebpf.ErrNotSupported):
+		return "not supported by the kernel: " + err.Error(), true
+	case errors.Is(err, btf.ErrNotFound):
+		return "tracepoint or kernel type not found: " + err.Error(), true
+	case errors.Is(err, os.ErrNotExist):
+		return err.Error(), true
+	case errors.As(err, &verr):
+		return "program rejected by the kernel verifier: " + err.Error(), true
+	default:
+		return err.Error(), false
+	}
+}
+
+// bpfProbe owns the objects (maps and programs) and links of one probe.
+type bpfProbe struct {
+	objs    io.Closer
+	links   []link.Link
+	collect func(res *TraceResult) error
+}
+
+// Close detaches and releases everything.
+func (p *bpfProbe) Close() {
+	for _, l := range p.links {
+		l.Close()
+	}
+	p.objs.Close()
+}
+
+// attachProbe attaches the tp_btf programs of loaded objects; on error the objects are released.
+func attachProbe(objs io.Closer, progs ...*ebpf.Program) (*bpfProbe, error) {
+	p := &bpfProbe{objs: objs}
+	for _, prog := range progs {
+		l, err := link.AttachTracing(link.TracingOptions{Program: prog})
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("attach %s: %w", prog, err)
+		}
+		p.links = append(p.links, l)
+	}
+	return p, nil
+}
+
+// readHistogram reads a 64-entry array map into a Log2Histogram.
+func readHistogram(m *ebpf.Map, h *Log2Histogram) error {
+	for i := range h {
+		var v uint64
+		if err := m.Lookup(uint32(i), &v); err != nil {
+			return err
+		}
+		h[i] += v
+	}
+	return nil
+}
+
+// newSyscallsProbe counts syscalls by (tgid, nr) on sys_enter.
+func newSyscallsProbe() (*bpfProbe, error) {
+	var objs syscallsObjects
+	if err := loadSyscallsObjects(&objs, nil); err != nil {
+		return nil, err
+	}
+	p, err := attachProbe(&objs, objs.SysEnter)
+	if err != nil {
+		return nil, err
+	}
+	p.collect = func(res *TraceResult) error {
+		byPID := make(map[int]*ProcessSyscalls)
+		var key syscallsSyscallKey
+		var count uint64
+		iter := objs.Counts.Iterate()
+		for iter.Next(&key, &count) {
+			pid := int(key.Tgid)
+			ps, ok := byPID[pid]
+			if !ok {
+				ps = &ProcessSyscalls{PID: pid, Comm: procComm(pid), ByNR: make(map[int]uint64)}
+				byPID[pid] = ps
+			}
+			ps.ByNR[int(int32(key.Nr))] += count
+			ps.Total += count
+		}
+		for _, ps := range byPID {
+			res.Syscalls = append(res.Syscalls, *ps)
+		}
+		return iter.Err()
+	}
+	return p, nil
+}
+
+// newOffCPUProbe measures off-CPU time per thread on sched_switch.
+func newOffCPUProbe() (*bpfProbe, error) {
+	var objs offcpuObjects
+	if err := loadOffcpuObjects(&objs, nil); err != nil {
+		return nil, err
+	}
+	p, err := attachProbe(&objs, objs.SchedSwitch)
+	if err != nil {
+		return nil, err
+	}
+	p.collect = func(res *TraceResult) error {
+		byPID := make(map[int]*ProcessOffCPU)
+		var tid uint32
+		var v offcpuOffcpuStats
+		iter := objs.Stats.Iterate()
+		for iter.Next(&tid, &v) {
+			pid, comm := procThread(int(tid))
+			po, ok := byPID[pid]
+			if !ok {
+				po = &ProcessOffCPU{PID: pid, Comm: comm}
+				byPID[pid] = po
+			}
+			po.Total += time.Duration(v.Total)
+			po.Switches += v.Switches
+			po.Uninterruptible += time.Duration(v.Uninterruptible)
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
+		for _, po := range byPID {
+			res.OffCPU = append(res.OffCPU, *po)
+		}
+		return readHistogram(objs.Hist, &res.OffCPUHistogram)
+	}
+	return p, nil
+}
+
+// blockIOObjects are the biolatency objects with the block_rq_issue variant of the running kernel.
+type blockIOObjects struct {
+	biolatencyMaps
+	Issue    *ebpf.Program `ebpf:"block_rq_issue"`
+	Complete *ebpf.Program `ebpf:"block_rq_complete"`
+}
+
+// Close releases the programs and maps.
+func (o *blockIOObjects) Close() error {
+	return errors.Join(o.Issue.Close(), o.Complete.Close(), o.biolatencyMaps.Close())
+}
+
+// blockRQIssueTakesQueue reports whether block_rq_issue still passes the request queue (kernels before 5.11).
+func blockRQIssueTakesQueue() bool {
+	spec, err := btf.LoadKernelSpec()
+	if err != nil {
+		return false
+	}
+	var typedef *btf.Typedef
+	if err := spec.TypeByName("btf_trace_block_rq_issue", &typedef); err != nil {
+		return false
+	}
+	ptr, ok := typedef.Type.(*btf.Pointer)
+	if !ok {
+		return false
+	}
+	proto, ok := ptr.Target.(*btf.FuncProto)
+	// The first parameter is the tracepoint's private data.
+	return ok && len(proto.Params) == 3
+}
+
+// newBlockIOProbe measures request latency per device between block_rq_issue and block_rq_complete.
+func newBlockIOProbe() (*bpfProbe, error) {
+	spec, err := loadBiolatency()
+	if err != nil {
+		return nil, err
+	}
+	if blockRQIssueTakesQueue() {
+		spec.Programs["block_rq_issue"] = spec.Programs["block_rq_issue_q"]
+	}
+	var objs blockIOObjects
+	if err := spec.LoadAndAssign(&objs, nil); err != nil {
+		return nil, err
+	}
+	p, err := attachProbe(&objs, objs.Issue, objs.Complete)
+	if err != nil {
+		return nil, err
+	}
+	p.collect = func(res *TraceResult) error {
+		byDev := make(map[uint32]*BlockDeviceLatency)
+		device := func(dev uint32) *BlockDeviceLatency {
+			d, ok := byDev[dev]
+			if !ok {
+				d = &BlockDeviceLatency{Device: blockDeviceName(dev)}
+				byDev[dev] = d
+			}
+			return d
+		}
+		var key biolatencyDevBucket
+		var count uint64
+		iter := objs.Hist.Iterate()
+		for iter.Next(&key, &count) {
+			if key.Bucket < 64 {
+				device(key.Dev).Histogram[key.Bucket] += count
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
+		var dev uint32
+		var total uint64
+		iter = objs.Totals.Iterate()
+		for iter.Next(&dev, &total) {
+			device(dev).Total += time.Duration(total)
+		}
+		for _, d := range byDev {
+			res.BlockIO = append(res.BlockIO, *d)
+		}
+		return iter.Err()
+	}
+	return p, nil
+}
+
+// tcpAddress renders a TCP destination key (an IPv6 or v4-mapped address and a port in host order) as host:port.
+func tcpAddress(addr [16]uint8, port uint16) string {
+	return net.JoinHostPort(net.IP(addr[:]).String(), strconv.Itoa(int(port)))
+}
+
+// newTCPRetransProbe counts retransmits per destination on tcp_retransmit_skb.
+func newTCPRetransProbe() (*bpfProbe, error) {
+	var objs tcpretransObjects
+	if err := loadTcpretransObjects(&objs, nil); err != nil {
+		return nil, err
+	}
+	p, err := attachProbe(&objs, objs.TcpRetransmitSkb)
+	if err != nil {
+		return nil, err
+	}
+	p.collect = func(res *TraceResult) error {
+		var key tcpretransTcpKey
+		var count uint64
+		iter := objs.Counts.Iterate()
+		for iter.Next(&key, &count) {
+			res.TCPRetransmits = append(res.TCPRetransmits,
+				TCPDestination{Address: tcpAddress(key.Addr, key.Port), Retransmits: count})
+		}
+		return iter.Err()
+	}
+	return p, nil
+}
+
+// newTCPConnectProbe measures SYN_SENT -> ESTABLISHED latency (and failed connects) on inet_sock_set_state.
+func newTCPConnectProbe() (*bpfProbe, error) {
+	var objs tcpconnectObjects
+	if err := loadTcpconnectObjects(&objs, nil); err != nil {
+		return nil, err
+	}
+	p, err := attachProbe(&objs, objs.InetSockSetState)
+	if err != nil {
+		return nil, err
+	}
+	p.collect = func(res *TraceResult) error {
+		var key tcpconnectTcpKey
+		var v tcpconnectConnectStats
+		iter := objs.Dests.Iterate()
+		for iter.Next(&key, &v) {
+			res.TCPConnects = append(res.TCPConnects, TCPDestination{
+				Address: tcpAddress(key.Addr, key.Port), Connects: v.Connects, Failures: v.Failures,
+				Total: time.Duration(v.Total),
+			})
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
+		return readHistogram(objs.Hist, &res.TCPConnectHistogram)
+	}
+	return p, nil
+}
+
+// procComm returns the command name of a process, or "" once it has exited.
+func procComm(pid int) string {
+	data, err := os.ReadFile(hostPath(filepath.Join("/proc", strconv.Itoa(pid), "comm")))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// procThread maps a thread ID to its process ID and command name; an exited thread maps to itself.
+func procThread(tid int) (int, string) {
+	data, err := os.ReadFile(hostPath(filepath.Join("/proc", strconv.Itoa(tid), "status")))
+	if err != nil {
+		return tid, ""
+	}
+	pid := tid
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, ok := strings.CutPrefix(line, "Tgid:"); ok {
+			if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				pid = n
+			}
+			break
+		}
+	}
+	return pid, procComm(pid)
+}
+
+// blockDeviceName names a kernel dev_t (major << 20 | minor) via /sys/dev/block.
+func blockDeviceName(dev uint32) string {
+	majmin := fmt.Sprintf("%d:%d", dev>>20, dev&(1<<20-1))
+	if target, err := os.Readlink(filepath.Join("/sys/dev/block", majmin)); err == nil {
+		return filepath.Base(target)
+	}
+	return majmin
+}
+
+// linuxSyscallNames names the syscalls that usually dominate; others are reported by number.
+var linuxSyscallNames = map[int]string{
+	unix.SYS_READ: "read", unix.SYS_WRITE: "write", unix.SYS_OPENAT: "openat", unix.SYS_CLOSE: "close",
+	unix.SYS_FSTAT: "fstat", unix.SYS_LSEEK: "lseek", unix.SYS_MUNMAP: "munmap",
+	unix.SYS_MPROTECT: "mprotect", unix.SYS_BRK: "brk", unix.SYS_IOCTL: "ioctl", unix.SYS_PREAD64: "pread64",
+	unix.SYS_PWRITE64: "pwrite64", unix.SYS_READV: "readv", unix.SYS_WRITEV: "writev",
+	unix.SYS_SCHED_YIELD: "sched_yield", unix.SYS_MADVISE: "madvise", unix.SYS_NANOSLEEP: "nanosleep",
+	unix.SYS_GETPID: "getpid", unix.SYS_GETTID: "gettid", unix.SYS_SOCKET: "socket", unix.SYS_CONNECT: "connect",
+	unix.SYS_ACCEPT4: "accept4", unix.SYS_SENDTO: "sendto", unix.SYS_RECVFROM: "recvfrom",
+	unix.SYS_SENDMSG: "sendmsg", unix.SYS_RECVMSG: "recvmsg", unix.SYS_BIND: "bind", unix.SYS_LISTEN: "listen",
+	unix.SYS_CLONE: "clone", unix.SYS_EXECVE: "execve", unix.SYS_EXIT: "exit", unix.SYS_EXIT_GROUP: "exit_group",
+	unix.SYS_WAIT4: "wait4", unix.SYS_KILL: "kill", unix.SYS_TGKILL: "tgkill", unix.SYS_FCNTL: "fcntl",
+	unix.SYS_FSYNC: "fsync", unix.SYS_FUTEX: "futex", unix.SYS_EPOLL_CTL: "epoll_ctl",
+	unix.SYS_EPOLL_PWAIT: "epoll_pwait", unix.SYS_CLOCK_GETTIME: "clock_gettime",
+	unix.SYS_CLOCK_NANOSLEEP: "clock_nanosleep", unix.SYS_GETRANDOM: "getrandom", unix.SYS_PPOLL: "ppoll",
+	unix.SYS_PSELECT6: "pselect6", unix.SYS_RT_SIGACTION: "rt_sigaction", unix.SYS_RT_SIGPROCMASK: "rt_sigprocmask",
+	unix.SYS_RT_SIGRETURN: "rt_sigreturn", unix.SYS_GETDENTS64: "getdents64", unix.SYS_STATX: "statx",
+	unix.SYS_IO_URING_ENTER: "io_uring_enter", unix.SYS_PRCTL: "prctl", unix.SYS_BPF: "bpf",
+}
+
+// linuxSyscallName implements TraceResult.SyscallName.
+func linuxSyscallName(nr int) string {
+	return linuxSyscallNames[nr]
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package diagnose
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// runEBPFTrace reports eBPF tracing as unsupported outside Linux.
+func runEBPFTrace(context.Context, []string, time.Duration) (*TraceResult, error) {
+	return nil, fmt.Errorf("%w: eBPF tracing requires Linux", ErrEBPFUnsupported)
+}
@@ -0,0 +1,222 @@
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// patchTracer replaces the eBPF tracer for one test.
+func patchTracer(t *testing.T, fn func(ctx context.Context, probes []string, window time.Duration) (*TraceResult, error)) {
+	t.Helper()
+	orig := traceEBPF
+	traceEBPF = fn
+	t.Cleanup(func() { traceEBPF = orig })
+}
+
+func TestLog2Histogram(t *testing.T) {
+	var h Log2Histogram
+	assert.Zero(t, h.Percentile(99))
+	h.Add(500 * time.Nanosecond)
+	for range 97 {
+		h.Add(3 * time.Microsecond) // bucket 1: [2, 4) µs
+	}
+	h.Add(5 * time.Millisecond)  // bucket 12: [4096, 8192) µs
+	h.Add(20 * time.Millisecond) // bucket 14
+	assert.EqualValues(t, 100, h.Count())
+	assert.Equal(t, uint64(1), h[0])
+	assert.Equal(t, 4*time.Microsecond, h.Percentile(50))
+	assert.Equal(t, 8192*time.Microsecond, h.Percentile(99))
+	assert.Equal(t, 32768*time.Microsecond, h.Percentile(100))
+
+	details := map[string]string{}
+	h.Details(details)
+	assert.Equal(t, map[string]string{"le_2µs": "1", "le_4µs": "97", "le_8.192ms": "1", "le_32.768ms": "1"}, details)
+	assert.Equal(t, time.Duration(1<<63-1), log2BucketBound(63))
+}
+
+func TestSelectedProbesAndTraceWindow(t *testing.T) {
+	probes, err := selectedProbes(nil)
+	require.NoError(t, err)
+	assert.Equal(t, EBPFProbes, probes)
+	_, err = selectedProbes([]string{"offcpu", "profile"})
+	assert.ErrorContains(t, err, `unknown eBPF probe "profile"`)
+
+	assert.Equal(t, DefaultTraceDuration, traceWindow(context.Background(), 0))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	w := traceWindow(ctx, time.Minute)
+	assert.LessOrEqual(t, w, 4*time.Second, "the window leaves room to read the maps")
+	assert.Greater(t, w, 3*time.Second)
+}
+
+func TestPerformanceDiagnostics_EBPFFindings(t *testing.T) {
+	var slow Log2Histogram
+	for range 90 {
+		slow.Add(2 * time.Millisecond)
+	}
+	for range 10 {
+		slow.Add(1500 * time.Millisecond)
+	}
+	var fast Log2Histogram
+	fast.Add(100 * time.Microsecond)
+	var connects Log2Histogram
+	connects.Add(300 * time.Microsecond)
+
+	var gotProbes []string
+	var gotWindow time.Duration
+	patchTracer(t, func(_ context.Context, probes []string, window time.Duration) (*TraceResult, error) {
+		gotProbes, gotWindow = probes, window
+		return &TraceResult{
+			Duration:    2 * time.Second,
+			Unsupported: map[string]string{ProbeTCPRetrans: "tracepoint tcp/tcp_retransmit_skb: file does not exist"},
+			OffCPU: []ProcessOffCPU{
+				{PID: 10, Comm: "idle-ish", Switches: 5, Total: 100 * time.Millisecond},
+				{PID: 42, Comm: "postgres", Switches: 900, Total: 1800 * time.Millisecond, Uninterruptible: time.Second},
+			},
+			OffCPUHistogram: fast,
+			BlockIO: []BlockDeviceLatency{
+				{Device: "nvme0n1", Histogram: fast, Total: 100 * time.Microsecond},
+				{Device: "sdb", Histogram: slow, Total: 15180 * time.Millisecond},
+			},
+			TCPConnects: []TCPDestination{
+				{Address: "10.0.0.5:5432", Connects: 1, Failures: 2, Total: 300 * time.Microsecond},
+			},
+			TCPConnectHistogram: connects,
+			Syscalls: []ProcessSyscalls{
+				{PID: 42, Comm: "postgres", Total: 600, ByNR: map[int]uint64{0: 400, 1: 150, 999: 50}},
+			},
+			SyscallName: func(nr int) string { return map[int]string{0: "read", 1: "write"}[nr] },
+		}, nil
+	})
+
+	d := NewPerformanceDiagnostics(core.NewTestLogger(&bytes.Buffer{}),
+		PerformanceOptions{EBPF: true, Duration: 3 * time.Second, Top: 1})
+	r, err := d.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, EBPFProbes, gotProbes)
+	assert.Equal(t, 3*time.Second, gotWindow)
+
+	unsupported := findingsByCheck(r, "perf.ebpf.unsupported")
+	require.Len(t, unsupported, 1)
+	assert.Equal(t, ProbeTCPRetrans, unsupported[0].Resource)
+	assert.Empty(t, findingsByCheck(r, "perf.tcp.retransmit"))
+
+	offcpu := findingsByCheck(r, "perf.offcpu")
+	require.Len(t, offcpu, 1, "--top 1")
+	assert.Equal(t, "pid 42 (postgres)", offcpu[0].Resource)
+	assert.Equal(t, SeverityWarning, offcpu[0].Severity, "half the window in D state")
+
+	bio := findingsByCheck(r, "perf.biolatency")
+	require.Len(t, bio, 1)
+	assert.Equal(t, "sdb", bio[0].Resource)
+	assert.Equal(t, SeverityCritical, bio[0].Severity)
+	assert.Equal(t, "100", bio[0].Details["ios"])
+	assert.Equal(t, "90", bio[0].Details["le_2.048ms"])
+	assert.Equal(t, "151800", bio[0].Details["mean_us"])
+
+	connect := findingsByCheck(r, "perf.tcp.connect")
+	require.Len(t, connect, 1)
+	assert.Equal(t, SeverityWarning, connect[0].Severity, "failed connects")
+	assert.Equal(t, "2", connect[0].Details["failures"])
+
+	sys := findingsByCheck(r, "perf.syscalls")
+	require.Len(t, sys, 1)
+	assert.Contains(t, sys[0].Message, "top: read, write, nr_999")
+	assert.Equal(t, "400", sys[0].Details["sys_read"])
+
+	assert.Equal(t, 2.0, r.Measurements["perf.ebpf.duration_seconds"])
+	assert.Equal(t, 101.0, r.Measurements["perf.biolatency.ios"])
+	assert.Equal(t, 2.0, r.Measurements["perf.tcp.connect_failures"])
+	assert.Equal(t, 300.0, r.Measurements["perf.syscalls.rate"])
+}
+
+func TestPerformanceDiagnostics_EBPFUnsupported(t *testing.T) {
+	patchTracer(t, func(context.Context, []string, time.Duration) (*TraceResult, error) {
+		return nil, fmt.Errorf("%w: permission denied (needs root, or CAP_BPF and CAP_PERFMON)", ErrEBPFUnsupported)
+	})
+	d := NewPerformanceDiagnostics(core.NewTestLogger(&bytes.Buffer{}), PerformanceOptions{EBPF: true})
+	r, err := d.Run(context.Background())
+	require.NoError(t, err, "an unusable host is a finding, not a failure")
+	f := findingsByCheck(r, "perf.ebpf.unsupported")
+	require.Len(t, f, 1)
+	assert.Equal(t, "ebpf", f[0].Resource)
+	assert.Equal(t, "permission denied (needs root, or CAP_BPF and CAP_PERFMON)", f[0].Details["reason"])
+	assert.Equal(t, SeverityInfo, r.MaxSeverity())
+
+	patchTracer(t, func(context.Context, []string, time.Duration) (*TraceResult, error) {
+		return nil, errors.New("map iteration aborted")
+	})
+	_, err = d.Run(context.Background())
+	assert.ErrorContains(t, err, "eBPF tracing failed: map iteration aborted")
+
+	d = NewPerformanceDiagnostics(core.NewTestLogger(&bytes.Buffer{}), PerformanceOptions{})
+	r, err = d.Run(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, r.Findings, "no probes without EBPF")
+}
+
+func TestCLI_PerformanceDiagnostics(t *testing.T) {
+	var gotProbes []string
+	patchTracer(t, func(_ context.Context, probes []string, window time.Duration) (*TraceResult, error) {
+		gotProbes = probes
+		return &TraceResult{Duration: window, Unsupported: map[string]string{}}, nil
+	})
+	cmd := &cobra.Command{Use: "performance"}
+	cmd.Flags().Bool("ebpf", false, "")
+	cmd.Flags().Duration("trace-duration", DefaultTraceDuration, "")
+	cmd.Flags().StringSlice("probes", nil, "")
+	cmd.Flags().Int("top", DefaultTraceTop, "")
+	cmd.Flags().String("output", "table", "")
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	require.NoError(t, cmd.Flags().Set("output", "json"))
+	require.NoError(t, cmd.Flags().Set("trace-duration", "10ms"))
+
+	ctx := &core.AppContext{Logger: core.NewTestLogger(&bytes.Buffer{}), Config: core.NewConfig()}
+	ctx.Config.Diagnostics.Performance.EBPF = true
+	ctx.Config.Diagnostics.Performance.Probes = []string{ProbeSyscalls}
+	require.NoError(t, CLI_PerformanceDiagnostics(ctx, cmd, nil))
+	var r Report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &r))
+	assert.Equal(t, "performance", r.Diagnostic)
+	assert.Equal(t, []string{ProbeSyscalls}, gotProbes)
+	assert.Equal(t, 0.01, r.Measurements["perf.ebpf.duration_seconds"])
+
+	require.NoError(t, cmd.Flags().Set("probes", "offcpu,dns"))
+	assert.ErrorContains(t, CLI_PerformanceDiagnostics(ctx, cmd, nil), `unknown eBPF probe "dns"`)
+
+	plain := &cobra.Command{Use: "performance"}
+	ctx.Config.Diagnostics.Performance.Duration = "later"
+	assert.ErrorContains(t, CLI_PerformanceDiagnostics(ctx, plain, nil), `invalid trace duration "later"`)
+}
+
+// TestRunEBPFTrace loads and attaches the real probes; it skips where BPF is not permitted.
+func TestRunEBPFTrace(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	res, err := runEBPFTrace(ctx, EBPFProbes, 300*time.Millisecond)
+	if errors.Is(err, ErrEBPFUnsupported) {
+		t.Skip(err)
+	}
+	require.NoError(t, err)
+	for probe, reason := range res.Unsupported {
+		t.Logf("probe %s unsupported: %s", probe, reason)
+	}
+	if _, ok := res.Unsupported[ProbeSyscalls]; !ok {
+		assert.NotEmpty(t, res.Syscalls, "the test itself makes syscalls")
+	}
+	if _, ok := res.Unsupported[ProbeOffCPU]; !ok {
+		assert.NotZero(t, res.OffCPUHistogram.Count(), "sleeping switches off-CPU")
+	}
+}
@@ -0,0 +1,150 @@
+package diagnose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file defines the typed diagnostic errors and their process exit codes (docs/architecture/diagnose.md §7).
+//
+// Usage:
+//   - Wrap failures with the sentinel errors (fmt.Errorf("...: %w", ErrVerification)) so ExitCode can classify them.
+//   - main calls ExitCode on the error returned by the root command.
+//
+// Best Practices:
+//   - Keep codes stable; CI jobs and cron wrappers branch on them.
+
+// Exit codes returned by the srediag process.
+const (
+	ExitOK           = 0 // Success
+	ExitError        = 1 // Plugin Run() or generic error
+	ExitVerification = 2 // SHA-256 / cosign verification failed
+	ExitSandbox      = 3 // seccomp / rlimit triggered
+	ExitNotFound     = 4 // Diagnostic or plugin not found
+	ExitTimeout      = 5 // --timeout exceeded
+	ExitFindings     = 6 // Findings at or above --fail-on severity
+	ExitDrift        = 7 // 'diagnose diff --fail-on-drift' found differences
+)
+
+var (
+	// ErrVerification marks plugin integrity or signature verification failures.
+	ErrVerification = errors.New("plugin verification failed")
+	// ErrSandbox marks sandbox or resource-cap violations.
+	ErrSandbox = errors.New("sandbox violation")
+	// ErrNotFound marks an unknown diagnostic type or a missing plugin.
+	ErrNotFound = errors.New("diagnostic not found")
+	// ErrTimeout marks a diagnostic that exceeded its timeout.
+	ErrTimeout = errors.New("diagnostic timed out")
+)
+
+// FindingsError is returned when a report contains findings at or above the --fail-on threshold.
+//
+// Fields:
+//   - Threshold: The configured --fail-on severity.
+//   - Count: Number of findings at or above the threshold.
+type FindingsError struct {
+	Threshold Severity
+	Count     int
+}
+
+// Error implements error.
+func (e *FindingsError) Error() string {
+	return fmt.Sprintf("%d finding(s) at or above severity %s", e.Count, e.Threshold)
+}
+
+// DriftError is returned by 'diagnose diff --fail-on-drift' when the compared reports differ.
+//
+// Fields:
+//   - Changes: Number of differences found.
+type DriftError struct {
+	Changes int
+}
+
+// Error implements error.
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("%d change(s) since the baseline", e.Changes)
+}
+
+// CheckFindings returns a *FindingsError if the report has findings at or above threshold.
+//
+// Parameters:
+//   - r: Report to check.
+//   - threshold: Minimum severity that fails the run; empty disables the check.
+//
+// Returns:
+//   - error: nil if no finding reaches the threshold.
+func CheckFindings(r *Report, threshold Severity) error {
+	if threshold == "" || r == nil {
+		return nil
+	}
+	count := 0
+	for _, f := range r.Findings {
+		if f.Severity.Rank() >= threshold.Rank() {
+			count++
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	return &FindingsError{Threshold: threshold, Count: count}
+}
+
+// exitCoder is implemented by srediag errors of other packages that choose their own exit code. The method name
+// keeps out errors that merely carry an exit code, such as *exec.ExitError with the status of a child process.
+type exitCoder interface {
+	SrediagExitCode() int
+}
+
+// ExitCode maps an error returned by a command to the process exit code.
+//
+// Parameters:
+//   - err: Error returned by the root command (may be nil).
+//
+// Returns:
+//   - int: One of the Exit* constants.
+func ExitCode(err error) int {
+	var findings *FindingsError
+	var drift *DriftError
+	var coder exitCoder
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &coder):
+		return coder.SrediagExitCode()
+	case errors.As(err, &findings):
+		return ExitFindings
+	case errors.As(err, &drift):
+		return ExitDrift
+	case errors.Is(err, ErrTimeout):
+		// Only the --timeout of a diagnostic run wraps ErrTimeout; other deadlines (admin socket, API clients) are
+		// plain errors.
+		return ExitTimeout
+	case errors.Is(err, ErrNotFound):
+		return ExitNotFound
+	case errors.Is(err, ErrSandbox):
+		return ExitSandbox
+	case errors.Is(err, ErrVerification):
+		return ExitVerification
+	default:
+		return ExitError
+	}
+}
+
+// isRetryable reports whether a failed attempt may be retried.
+func isRetryable(err error) bool {
+	switch {
+	case errors.Is(err, ErrVerification), errors.Is(err, ErrSandbox), errors.Is(err, ErrNotFound),
+		errors.Is(err, core.ErrAccessDenied):
+		return false
+	case errors.Is(err, context.Canceled):
+		return false
+	default:
+		var findings *FindingsError
+		return !errors.As(err, &findings)
+	}
+}
@@ -0,0 +1,427 @@
+package diagnose
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/srediag/srediag/internal/core"
+	"github.com/srediag/srediag/internal/queue"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file implements diag_exporter (docs/architecture/diagnose.md §3): every finding of a Report becomes an OTLP
+// log record with structured attributes, and every measurement becomes an OTLP gauge. Both carry the host and
+// service resource attributes.
+//
+// Usage:
+//   - CLI mode: 'srediag diagnose <type> --export otlp://host:4317' calls ExportReport after rendering the report.
+//   - Service mode: create a DiagExporter (NewDiagExporterFactory / NewDiagExporter), start it with the service and
+//     pass reports to ConsumeReport. With 'queue.enabled', reports are written to a persistent queue first and sent
+//     by a background loop that retries with backoff, so they survive collector outages and agent restarts.
+//
+// Best Practices:
+//   - Keep finding attributes flat strings so log backends can index them.
+
+// Log attribute keys set on every finding record.
+const (
+	AttrEventName  = "event.name"
+	AttrDiagnostic = "srediag.diagnostic"
+	AttrCheck      = "srediag.finding.check"
+	AttrSeverity   = "srediag.finding.severity"
+	AttrResource   = "srediag.finding.resource"
+	AttrDetail     = "srediag.finding.detail." // prefix; one attribute per Details key
+	AttrCodeFile   = "code.filepath"
+	AttrCodeLine   = "code.lineno"
+)
+
+// findingEventName is the event.name of finding log records.
+const findingEventName = "srediag.diagnostic.finding"
+
+// DiagExporterType is the component type of the service-scope diagnostics exporter.
+var DiagExporterType = component.MustNewType("diag_exporter")
+
+// Retry backoff of the queue sender; variables so tests can shorten them.
+var (
+	queueRetryMin = time.Second
+	queueRetryMax = time.Minute
+)
+
+// ReportLogs converts the findings of a report into OTLP logs, one record per finding.
+//
+// Parameters:
+//   - r: Report to convert.
+//
+// Returns:
+//   - plog.Logs: Logs with the srediag resource attributes.
+func ReportLogs(r *Report) plog.Logs {
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	putResourceAttributes(rl.Resource())
+	sl := rl.ScopeLogs().AppendEmpty()
+	setScope(sl.Scope())
+
+	ts := pcommon.NewTimestampFromTime(reportEnd(r))
+	observed := pcommon.NewTimestampFromTime(time.Now())
+	for _, f := range r.Findings {
+		lr := sl.LogRecords().AppendEmpty()
+		lr.SetTimestamp(ts)
+		lr.SetObservedTimestamp(observed)
+		lr.SetSeverityNumber(severityNumber(f.Severity))
+		lr.SetSeverityText(strings.ToUpper(string(f.Severity)))
+		lr.Body().SetStr(f.Message)
+
+		attrs := lr.Attributes()
+		attrs.PutStr(AttrEventName, findingEventName)
+		attrs.PutStr(AttrDiagnostic, r.Diagnostic)
+		attrs.PutStr(AttrCheck, f.Check)
+		attrs.PutStr(AttrSeverity, string(f.Severity))
+		if f.Resource != "" {
+			attrs.PutStr(AttrResource, f.Resource)
+		}
+		if f.Location != nil {
+			attrs.PutStr(AttrCodeFile, f.Location.File)
+			if f.Location.Line > 0 {
+				attrs.PutInt(AttrCodeLine, int64(f.Location.Line))
+			}
+		}
+		keys := make([]string, 0, len(f.Details))
+		for k := range f.Details {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			attrs.PutStr(AttrDetail+k, f.Details[k])
+		}
+	}
+	return ld
+}
+
+// ReportMetrics converts the measurements of a report into OTLP gauges.
+//
+// Parameters:
+//   - r: Report to convert.
+//
+// Returns:
+//   - pmetric.Metrics: One gauge per measurement, labelled with the diagnostic name.
+func ReportMetrics(r *Report) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	putResourceAttributes(rm.Resource())
+	sm := rm.ScopeMetrics().AppendEmpty()
+	setScope(sm.Scope())
+
+	names := make([]string, 0, len(r.Measurements))
+	for name := range r.Measurements {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ts := pcommon.NewTimestampFromTime(reportEnd(r))
+	for _, name := range names {
+		m := sm.Metrics().AppendEmpty()
+		m.SetName(name)
+		dp := m.SetEmptyGauge().DataPoints().AppendEmpty()
+		dp.SetTimestamp(ts)
+		dp.SetDoubleValue(r.Measurements[name])
+		dp.Attributes().PutStr(AttrDiagnostic, r.Diagnostic)
+	}
+	return md
+}
+
+// ExportReport sends a report's findings (logs) and measurements (metrics) to an OTLP endpoint.
+//
+// Parameters:
+//   - ctx: Context for cancellation.
+//   - target: otlp://, otlps://, http:// or https:// endpoint; empty disables export.
+//   - r: Report to export.
+//
+// Returns:
+//   - error: If the target is invalid or either export fails, returns a detailed error.
+func ExportReport(ctx context.Context, target string, r *Report) error {
+	if target == "" || r == nil {
+		return nil
+	}
+	client, err := newOTLPClient(target)
+	if err != nil {
+		return err
+	}
+	defer client.close()
+	ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+	defer cancel()
+	return exportReport(ctx, client, r)
+}
+
+// exportReport sends r over an existing client; empty signals are skipped.
+func exportReport(ctx context.Context, client *otlpClient, r *Report) error {
+	if len(r.Findings) > 0 {
+		if err := client.exportLogs(ctx, ReportLogs(r)); err != nil {
+			return err
+		}
+	}
+	if len(r.Measurements) > 0 {
+		if err := client.exportMetrics(ctx, ReportMetrics(r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportEnd returns the time the report finished.
+func reportEnd(r *Report) time.Time {
+	if r.StartedAt.IsZero() {
+		return time.Now()
+	}
+	return r.StartedAt.Add(r.Duration)
+}
+
+// severityNumber maps a finding severity to the OTLP log severity number.
+func severityNumber(s Severity) plog.SeverityNumber {
+	switch s {
+	case SeverityCritical:
+		return plog.SeverityNumberError
+	case SeverityWarning:
+		return plog.SeverityNumberWarn
+	case SeverityInfo:
+		return plog.SeverityNumberInfo
+	default:
+		return plog.SeverityNumberUnspecified
+	}
+}
+
+// DiagExporterConfig configures the service-scope diagnostics exporter.
+//
+// Fields:
+//   - Endpoint: OTLP endpoint (otlp://, otlps://, http:// or https://).
+//   - Timeout: Timeout per export request; defaults to 10s.
+//   - Queue: Persistent queue in front of the endpoint (off by default).
+type DiagExporterConfig struct {
+	Endpoint string        `mapstructure:"endpoint" yaml:"endpoint"`
+	Timeout  time.Duration `mapstructure:"timeout" yaml:"timeout"`
+	Queue    queue.Config  `mapstructure:"queue" yaml:"queue"`
+}
+
+// diagExporterFactory is the component.Factory of diag_exporter.
+type diagExporterFactory struct{}
+
+// NewDiagExporterFactory returns the component factory of diag_exporter.
+func NewDiagExporterFactory() component.Factory {
+	return diagExporterFactory{}
+}
+
+// Type implements component.Factory.
+func (diagExporterFactory) Type() component.Type { return DiagExporterType }
+
+// CreateDefaultConfig implements component.Factory.
+func (diagExporterFactory) CreateDefaultConfig() component.Config {
+	return &DiagExporterConfig{Timeout: exportTimeout}
+}
+
+// DiagExporter is the service-scope component that ships diagnostic reports as OTLP logs and metrics.
+//
+// Usage:
+//   - Create with NewDiagExporter, Start it with the service, call ConsumeReport for each finished report, and
+//     Shutdown on service stop.
+type DiagExporter struct {
+	cfg    DiagExporterConfig
+	logger *core.Logger
+
+	mu     sync.Mutex
+	client *otlpClient
+	queue  *queue.Queue
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+var _ component.Component = (*DiagExporter)(nil)
+
+// NewDiagExporter validates cfg and creates a DiagExporter.
+//
+// Parameters:
+//   - cfg: Exporter configuration.
+//   - logger: Logger for status and error reporting.
+//
+// Returns:
+//   - *DiagExporter: The exporter (not yet started).
+//   - error: If the endpoint or the queue settings are invalid, returns a detailed error.
+func NewDiagExporter(cfg *DiagExporterConfig, logger *core.Logger) (*DiagExporter, error) {
+	if cfg == nil || cfg.Endpoint == "" {
+		return nil, fmt.Errorf("%s: endpoint is required", DiagExporterType)
+	}
+	u, err := parseExportTarget(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", DiagExporterType, err)
+	}
+	if u.Scheme == "file" {
+		return nil, fmt.Errorf("%s: endpoint %q must be an OTLP endpoint", DiagExporterType, cfg.Endpoint)
+	}
+	if _, err := cfg.Queue.Options(); err != nil {
+		return nil, fmt.Errorf("%s: queue: %w", DiagExporterType, err)
+	}
+	c := *cfg
+	if c.Timeout <= 0 {
+		c.Timeout = exportTimeout
+	}
+	return &DiagExporter{cfg: c, logger: logger}, nil
+}
+
+// Start implements component.Component; it creates the OTLP client and, with a queue, opens it and starts the
+// sender loop, which first delivers what an earlier run left behind.
+func (e *DiagExporter) Start(ctx context.Context, _ component.Host) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.client != nil {
+		return nil
+	}
+	client, err := newOTLPClient(e.cfg.Endpoint)
+	if err != nil {
+		return fmt.Errorf("%s: %w", DiagExporterType, err)
+	}
+	if e.cfg.Queue.Enabled {
+		opts, err := e.cfg.Queue.Options()
+		if err != nil {
+			client.close()
+			return fmt.Errorf("%s: queue: %w", DiagExporterType, err)
+		}
+		q, err := queue.Open(e.cfg.Queue.Directory(DiagExporterType.String()), opts)
+		if err != nil {
+			client.close()
+			return fmt.Errorf("%s: %w", DiagExporterType, err)
+		}
+		runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		e.queue, e.cancel, e.done = q, cancel, make(chan struct{})
+		go e.send(runCtx, client, q, e.done)
+	}
+	e.client = client
+	if e.logger != nil {
+		e.logger.Info("Diagnostics exporter started", core.ZapString("endpoint", e.cfg.Endpoint),
+			core.ZapBool("queue", e.queue != nil))
+	}
+	return nil
+}
+
+// send delivers queued reports in order until ctx is cancelled, retrying a failed export with exponential backoff.
+func (e *DiagExporter) send(ctx context.Context, client *otlpClient, q *queue.Queue, done chan struct{}) {
+	defer close(done)
+	backoff := queueRetryMin
+	for {
+		rec, err := q.Peek(ctx)
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, queue.ErrClosed) && e.logger != nil {
+				e.logger.Error("Diagnostics queue read failed", core.ZapError(err))
+			}
+			return
+		}
+		var r Report
+		if err := json.Unmarshal(rec.Data, &r); err != nil {
+			if e.logger != nil {
+				e.logger.Warn("Dropping undecodable queued report", core.ZapError(err))
+			}
+			_ = q.Ack(rec.Seq)
+			continue
+		}
+		exportCtx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+		err = exportReport(exportCtx, client, &r)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if e.logger != nil {
+				e.logger.Warn("Diagnostics export failed; retrying", core.ZapError(err),
+					core.ZapString("retry_in", backoff.String()))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, queueRetryMax)
+			continue
+		}
+		backoff = queueRetryMin
+		if err := q.Ack(rec.Seq); err != nil && e.logger != nil {
+			e.logger.Warn("Failed to acknowledge queued report", core.ZapError(err))
+		}
+	}
+}
+
+// Shutdown implements component.Component; it stops the sender loop, syncs and closes the queue (undelivered
+// reports stay on disk for the next start) and closes the OTLP client. It only fails if the queue cannot be synced,
+// that is, when queued reports may be lost.
+func (e *DiagExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var err error
+	if e.queue != nil {
+		e.cancel()
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			// The report in flight is still queued; it is sent again on the next start.
+			if e.logger != nil {
+				e.logger.Warn("Diagnostics queue sender did not stop in time")
+			}
+		}
+		if cerr := e.queue.Close(); cerr != nil {
+			err = fmt.Errorf("%s: %w", DiagExporterType, cerr)
+		}
+		e.queue = nil
+	}
+	if e.client != nil {
+		e.client.close()
+		e.client = nil
+	}
+	return err
+}
+
+// Queue returns the persistent queue of a started exporter, or nil when it has none.
+func (e *DiagExporter) Queue() *queue.Queue {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.queue
+}
+
+// ConsumeReport exports a finished report, or queues it when the exporter has a queue.
+//
+// Parameters:
+//   - ctx: Context for cancellation (bounds the wait of the 'block' full policy).
+//   - r: Report to export.
+//
+// Returns:
+//   - error: If the exporter is not started, the queue rejects the report or the export fails, returns a detailed
+//     error.
+func (e *DiagExporter) ConsumeReport(ctx context.Context, r *Report) error {
+	e.mu.Lock()
+	client, q := e.client, e.queue
+	e.mu.Unlock()
+	if client == nil {
+		return fmt.Errorf("%s is not started", DiagExporterType)
+	}
+	if r == nil {
+		return nil
+	}
+	if q != nil {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		if err := q.Put(ctx, data); err != nil {
+			return fmt.Errorf("failed to queue report: %w", err)
+		}
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+	return exportReport(ctx, client, r)
+}
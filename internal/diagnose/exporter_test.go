@@ -0,0 +1,183 @@
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"google.golang.org/grpc"
+
+	"github.com/srediag/srediag/internal/core"
+	"github.com/srediag/srediag/internal/queue"
+)
+
+// logsReceiver is an in-process OTLP/gRPC logs receiver.
+type logsReceiver struct {
+	plogotlp.UnimplementedGRPCServer
+	got chan plog.Logs
+}
+
+func (l *logsReceiver) Export(_ context.Context, req plogotlp.ExportRequest) (plogotlp.ExportResponse, error) {
+	l.got <- req.Logs()
+	return plogotlp.NewExportResponse(), nil
+}
+
+// startOTLPReceiver serves OTLP/gRPC logs and metrics on a loopback port and returns its otlp:// endpoint.
+func startOTLPReceiver(t *testing.T) (string, *logsReceiver, *metricsReceiver) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	logs := &logsReceiver{got: make(chan plog.Logs, 4)}
+	metrics := &metricsReceiver{got: make(chan pmetric.Metrics, 4)}
+	plogotlp.RegisterGRPCServer(srv, logs)
+	pmetricotlp.RegisterGRPCServer(srv, metrics)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return "otlp://" + lis.Addr().String(), logs, metrics
+}
+
+func sampleReport() *Report {
+	r := NewReport("kubernetes")
+	r.Add(Finding{
+		Check:    "k8s.node.ready",
+		Severity: SeverityCritical,
+		Resource: "node/worker-1",
+		Message:  "node is NotReady",
+		Details:  map[string]string{"reason": "KubeletNotReady"},
+	})
+	r.Add(Finding{Check: "iac.tf.public_bucket", Severity: SeverityWarning, Message: "bucket is public",
+		Location: &Location{File: "main.tf", Line: 7}})
+	r.SetMeasurement("k8s.nodes", 3)
+	r.Finish()
+	return r
+}
+
+func TestReportLogs(t *testing.T) {
+	ld := ReportLogs(sampleReport())
+	require.Equal(t, 2, ld.LogRecordCount())
+	rl := ld.ResourceLogs().At(0)
+	_, ok := rl.Resource().Attributes().Get("host.name")
+	assert.True(t, ok)
+
+	lr := rl.ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, "node is NotReady", lr.Body().Str())
+	assert.Equal(t, plog.SeverityNumberError, lr.SeverityNumber())
+	assert.Equal(t, map[string]any{
+		AttrEventName:         findingEventName,
+		AttrDiagnostic:        "kubernetes",
+		AttrCheck:             "k8s.node.ready",
+		AttrSeverity:          "critical",
+		AttrResource:          "node/worker-1",
+		AttrDetail + "reason": "KubeletNotReady",
+	}, lr.Attributes().AsRaw())
+
+	loc := rl.ScopeLogs().At(0).LogRecords().At(1).Attributes()
+	line, _ := loc.Get(AttrCodeLine)
+	assert.Equal(t, int64(7), line.Int())
+}
+
+func TestExportReport_OTLPGRPC(t *testing.T) {
+	endpoint, logs, metrics := startOTLPReceiver(t)
+	require.NoError(t, ExportReport(context.Background(), endpoint, sampleReport()))
+
+	ld := <-logs.got
+	assert.Equal(t, 2, ld.LogRecordCount())
+	service, _ := ld.ResourceLogs().At(0).Resource().Attributes().Get("service.name")
+	assert.Equal(t, "srediag", service.Str())
+
+	md := <-metrics.got
+	gauge := findMetric(t, md, "k8s.nodes").Gauge().DataPoints()
+	require.Equal(t, 1, gauge.Len())
+	assert.Equal(t, 3.0, gauge.At(0).DoubleValue())
+
+	assert.ErrorContains(t, ExportReport(context.Background(), filepath.Join(t.TempDir(), "x.prom"), sampleReport()),
+		"expected otlp://")
+}
+
+func TestCLI_Export(t *testing.T) {
+	endpoint, logs, _ := startOTLPReceiver(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(iacTerraform), 0o600))
+
+	cmd := &cobra.Command{Use: "iac"}
+	cmd.Flags().String("export", endpoint, "")
+	cmd.SetOut(&bytes.Buffer{})
+	ctx := &core.AppContext{Logger: core.NewTestLogger(&bytes.Buffer{})}
+	require.NoError(t, CLI_IaCDiagnostics(ctx, cmd, []string{dir}))
+
+	select {
+	case ld := <-logs.got:
+		assert.Positive(t, ld.LogRecordCount())
+	case <-time.After(5 * time.Second):
+		t.Fatal("no logs received")
+	}
+}
+
+func TestDiagExporter(t *testing.T) {
+	_, err := NewDiagExporter(&DiagExporterConfig{}, nil)
+	assert.ErrorContains(t, err, "endpoint is required")
+
+	endpoint, logs, _ := startOTLPReceiver(t)
+	exp, err := NewDiagExporter(&DiagExporterConfig{Endpoint: endpoint}, core.NewTestLogger(&bytes.Buffer{}))
+	require.NoError(t, err)
+	assert.ErrorContains(t, exp.ConsumeReport(context.Background(), sampleReport()), "not started")
+
+	require.NoError(t, exp.Start(context.Background(), nil))
+	require.NoError(t, exp.ConsumeReport(context.Background(), sampleReport()))
+	assert.Equal(t, 2, (<-logs.got).LogRecordCount())
+	require.NoError(t, exp.Shutdown(context.Background()))
+	assert.Equal(t, DiagExporterType, NewDiagExporterFactory().Type())
+}
+
+func TestDiagExporter_Queue(t *testing.T) {
+	minRetry, maxRetry := queueRetryMin, queueRetryMax
+	t.Cleanup(func() { queueRetryMin, queueRetryMax = minRetry, maxRetry })
+	queueRetryMin, queueRetryMax = 10*time.Millisecond, 20*time.Millisecond
+	dir := t.TempDir()
+
+	_, err := NewDiagExporter(&DiagExporterConfig{Endpoint: "otlp://127.0.0.1:1",
+		Queue: queue.Config{Enabled: true, FullPolicy: "spill"}}, nil)
+	assert.ErrorContains(t, err, "queue: invalid full_policy")
+
+	// The collector is down: the report is kept on disk across the restart of the exporter.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	down := "otlp://" + lis.Addr().String()
+	require.NoError(t, lis.Close())
+	cfg := &DiagExporterConfig{Endpoint: down, Timeout: 100 * time.Millisecond, Queue: queue.Config{Enabled: true, Dir: dir}}
+	exp, err := NewDiagExporter(cfg, core.NewTestLogger(&bytes.Buffer{}))
+	require.NoError(t, err)
+	require.NoError(t, exp.Start(context.Background(), nil))
+	require.NoError(t, exp.ConsumeReport(context.Background(), sampleReport()))
+	assert.Equal(t, uint64(1), exp.Queue().Stats().Depth)
+	require.NoError(t, exp.Shutdown(context.Background()))
+	assert.Nil(t, exp.Queue())
+
+	endpoint, logs, metrics := startOTLPReceiver(t)
+	cfg.Endpoint = endpoint
+	exp, err = NewDiagExporter(cfg, core.NewTestLogger(&bytes.Buffer{}))
+	require.NoError(t, err)
+	require.NoError(t, exp.Start(context.Background(), nil))
+	defer func() { _ = exp.Shutdown(context.Background()) }()
+	select {
+	case ld := <-logs.got:
+		assert.Equal(t, 2, ld.LogRecordCount())
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued report was not delivered after restart")
+	}
+	gauge := findMetric(t, <-metrics.got, "k8s.nodes").Gauge().DataPoints()
+	assert.Equal(t, 3.0, gauge.At(0).DoubleValue())
+	require.Eventually(t, func() bool { return exp.Queue().Stats().Depth == 0 }, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,647 @@
+package diagnose
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"gopkg.in/yaml.v3"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file defines the IaCDiagnostics handler, a static analyzer for Terraform HCL, plain Kubernetes manifests,
+// and rendered Helm charts (roadmap item D-04).
+//
+// Usage:
+//   - Instantiate with NewIaCDiagnostics, providing a logger and a file or directory path.
+//   - Call Run to walk the path and produce a Report whose findings carry file and line locations.
+//
+// Best Practices:
+//   - Analyze rendered Helm output (`helm template ... > rendered.yaml`); raw templates are not valid YAML.
+//   - Use --output sarif to surface findings in code review.
+
+// workloadKinds lists Kubernetes kinds whose pod templates are analyzed.
+var workloadKinds = map[string]bool{
+	"Pod": true, "Deployment": true, "StatefulSet": true, "DaemonSet": true,
+	"ReplicaSet": true, "Job": true, "CronJob": true,
+}
+
+// publicS3ACLs lists canned ACLs that expose a bucket publicly.
+var publicS3ACLs = map[string]bool{"public-read": true, "public-read-write": true, "website": true}
+
+// IaCDiagnostics handles static analysis of Infrastructure-as-Code files.
+//
+// Usage:
+//   - Instantiate with NewIaCDiagnostics, providing a logger and the path to scan.
+//   - Call Run to execute IaC diagnostics.
+type IaCDiagnostics struct {
+	logger *core.Logger
+	path   string
+}
+
+// NewIaCDiagnostics creates a new IaC diagnostics handler.
+//
+// Parameters:
+//   - logger: Logger for status and error reporting.
+//   - path: File or directory to analyze.
+//
+// Returns:
+//   - *IaCDiagnostics: A new IaC diagnostics handler.
+func NewIaCDiagnostics(logger *core.Logger, path string) *IaCDiagnostics {
+	return &IaCDiagnostics{logger: logger, path: path}
+}
+
+// Run walks the configured path and analyzes every Terraform (*.tf) and YAML (*.yaml, *.yml) file.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts.
+//
+// Returns:
+//   - *Report: Findings with file and line locations.
+//   - error: If the path cannot be read, returns a detailed error. Unparseable files become findings, not errors.
+func (d *IaCDiagnostics) Run(ctx context.Context) (*Report, error) {
+	d.logger.Info("Running IaC diagnostics", core.ZapString("path", d.path))
+	report := NewReport("iac")
+
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", d.path, err)
+	}
+	root := d.path
+	if !info.IsDir() {
+		root = filepath.Dir(d.path)
+	}
+
+	var k8sDocs []manifestDoc
+	files := 0
+	walkErr := filepath.WalkDir(d.path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if entry.IsDir() {
+			name := entry.Name()
+			if path != d.path && (strings.HasPrefix(name, ".") || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			rel = path
+		}
+		switch ext := strings.ToLower(filepath.Ext(path)); ext {
+		case ".tf":
+			files++
+			d.analyzeTerraform(report, path, rel)
+		case ".yaml", ".yml":
+			if isUnrenderedTemplate(path) {
+				report.Add(Finding{
+					Check:    "iac.helm.unrendered",
+					Severity: SeverityInfo,
+					Message:  "Helm template is not rendered; run `helm template` and analyze the output",
+					Location: &Location{File: rel},
+				})
+				return nil
+			}
+			docs, parseErr := parseManifests(path, rel)
+			if parseErr != nil {
+				report.Add(Finding{
+					Check:    "iac.parse",
+					Severity: SeverityWarning,
+					Message:  fmt.Sprintf("failed to parse YAML: %v", parseErr),
+					Location: &Location{File: rel},
+				})
+				return nil
+			}
+			if len(docs) > 0 {
+				files++
+			}
+			k8sDocs = append(k8sDocs, docs...)
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", d.path, walkErr)
+	}
+
+	analyzeManifests(report, k8sDocs)
+	report.SetMeasurement("iac.files", float64(files))
+	report.SetMeasurement("iac.k8s_objects", float64(len(k8sDocs)))
+	report.Finish()
+	return report, nil
+}
+
+// --- Kubernetes manifests and rendered Helm charts ---
+
+// manifestDoc is a single Kubernetes object decoded from a YAML document.
+type manifestDoc struct {
+	file       string
+	helmSource string
+	kind       string
+	name       string
+	namespace  string
+	node       *yaml.Node
+}
+
+// location returns a Location for a node within this document.
+func (m manifestDoc) location(n *yaml.Node) *Location {
+	line := 0
+	if n != nil {
+		line = n.Line
+	}
+	return &Location{File: m.file, Line: line}
+}
+
+// resource returns a "kind/namespace/name" identifier for findings.
+func (m manifestDoc) resource() string {
+	ns := m.namespace
+	if ns == "" {
+		ns = "default"
+	}
+	return strings.ToLower(m.kind) + "/" + ns + "/" + m.name
+}
+
+// details returns finding details, including the Helm template source when known.
+func (m manifestDoc) details(extra map[string]string) map[string]string {
+	if m.helmSource == "" && len(extra) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(extra)+1)
+	for k, v := range extra {
+		out[k] = v
+	}
+	if m.helmSource != "" {
+		out["helm_source"] = m.helmSource
+	}
+	return out
+}
+
+// parseManifests decodes every Kubernetes object in a (possibly multi-document) YAML file.
+// Documents without apiVersion/kind are ignored so unrelated YAML files do not produce noise.
+func parseManifests(path, rel string) ([]manifestDoc, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sources := helmSources(data)
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var docs []manifestDoc
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		obj := doc.Content[0]
+		kind := scalar(mapGet(obj, "kind"))
+		if kind == "" || scalar(mapGet(obj, "apiVersion")) == "" {
+			continue
+		}
+		meta := mapGet(obj, "metadata")
+		docs = append(docs, manifestDoc{
+			file:       rel,
+			helmSource: sourceFor(sources, obj.Line),
+			kind:       kind,
+			name:       scalar(mapGet(meta, "name")),
+			namespace:  scalar(mapGet(meta, "namespace")),
+			node:       obj,
+		})
+	}
+	return docs, nil
+}
+
+// helmSourceLine records a "# Source: chart/templates/x.yaml" marker emitted by `helm template`.
+type helmSourceLine struct {
+	line   int
+	source string
+}
+
+// helmSources extracts Helm "# Source:" markers with their line numbers.
+func helmSources(data []byte) []helmSourceLine {
+	var out []helmSourceLine
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if src, ok := strings.CutPrefix(strings.TrimSpace(sc.Text()), "# Source:"); ok {
+			out = append(out, helmSourceLine{line: line, source: strings.TrimSpace(src)})
+		}
+	}
+	return out
+}
+
+// sourceFor returns the closest Helm source marker above the given line.
+func sourceFor(sources []helmSourceLine, line int) string {
+	src := ""
+	for _, s := range sources {
+		if s.line >= line {
+			break
+		}
+		src = s.source
+	}
+	return src
+}
+
+// isUnrenderedTemplate reports whether a YAML file is a raw Helm template (under templates/ and containing actions).
+func isUnrenderedTemplate(path string) bool {
+	if filepath.Base(filepath.Dir(path)) != "templates" {
+		return false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return bytes.Contains(data, []byte("{{"))
+}
+
+// analyzeManifests runs all Kubernetes checks over the decoded objects.
+func analyzeManifests(report *Report, docs []manifestDoc) {
+	var pdbs []manifestDoc
+	for _, doc := range docs {
+		if doc.kind == "PodDisruptionBudget" {
+			pdbs = append(pdbs, doc)
+		}
+	}
+	for _, doc := range docs {
+		if !workloadKinds[doc.kind] {
+			continue
+		}
+		podSpec, podLabels := podTemplate(doc)
+		if podSpec == nil {
+			continue
+		}
+		checkReplicas(report, doc, podLabels, pdbs)
+		checkHostSettings(report, doc, podSpec)
+		for _, list := range []string{"initContainers", "containers"} {
+			containers := mapGet(podSpec, list)
+			if containers == nil || containers.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, c := range containers.Content {
+				checkContainer(report, doc, c, list == "initContainers")
+			}
+		}
+	}
+}
+
+// podTemplate returns the pod spec node and pod template labels for a workload.
+func podTemplate(doc manifestDoc) (*yaml.Node, map[string]string) {
+	switch doc.kind {
+	case "Pod":
+		return mapGet(doc.node, "spec"), stringMap(mapGet(mapGet(doc.node, "metadata"), "labels"))
+	case "CronJob":
+		tmpl := mapGet(mapGet(mapGet(mapGet(doc.node, "spec"), "jobTemplate"), "spec"), "template")
+		return mapGet(tmpl, "spec"), stringMap(mapGet(mapGet(tmpl, "metadata"), "labels"))
+	default:
+		tmpl := mapGet(mapGet(doc.node, "spec"), "template")
+		return mapGet(tmpl, "spec"), stringMap(mapGet(mapGet(tmpl, "metadata"), "labels"))
+	}
+}
+
+// checkReplicas flags single-replica Deployments/StatefulSets that have no matching PodDisruptionBudget.
+func checkReplicas(report *Report, doc manifestDoc, labels map[string]string, pdbs []manifestDoc) {
+	if doc.kind != "Deployment" && doc.kind != "StatefulSet" {
+		return
+	}
+	spec := mapGet(doc.node, "spec")
+	replicasNode := mapGet(spec, "replicas")
+	replicas := "1"
+	if replicasNode != nil {
+		replicas = replicasNode.Value
+	}
+	if replicas != "1" && replicas != "0" {
+		return
+	}
+	for _, pdb := range pdbs {
+		if pdb.namespace != doc.namespace {
+			continue
+		}
+		selector := stringMap(mapGet(mapGet(mapGet(pdb.node, "spec"), "selector"), "matchLabels"))
+		if len(selector) > 0 && labelsMatch(selector, labels) {
+			return
+		}
+	}
+	anchor := replicasNode
+	if anchor == nil {
+		anchor = doc.node
+	}
+	report.Add(Finding{
+		Check:    "iac.k8s.single-replica",
+		Severity: SeverityWarning,
+		Resource: doc.resource(),
+		Message:  fmt.Sprintf("%s runs a single replica and has no PodDisruptionBudget; any disruption causes downtime", doc.kind),
+		Details:  doc.details(map[string]string{"replicas": replicas}),
+		Location: doc.location(anchor),
+	})
+}
+
+// checkHostSettings flags pod-level host namespace sharing.
+func checkHostSettings(report *Report, doc manifestDoc, podSpec *yaml.Node) {
+	for _, key := range []string{"hostNetwork", "hostPID", "hostIPC"} {
+		if n := mapGet(podSpec, key); n != nil && n.Value == "true" {
+			report.Add(Finding{
+				Check:    "iac.k8s.host-namespace",
+				Severity: SeverityWarning,
+				Resource: doc.resource(),
+				Message:  fmt.Sprintf("pod shares the host namespace (%s: true)", key),
+				Details:  doc.details(nil),
+				Location: doc.location(n),
+			})
+		}
+	}
+}
+
+// checkContainer runs per-container checks: limits, probes, image tags, and privilege.
+func checkContainer(report *Report, doc manifestDoc, c *yaml.Node, initContainer bool) {
+	name := scalar(mapGet(c, "name"))
+	resource := doc.resource() + "/" + name
+
+	resources := mapGet(c, "resources")
+	limits := mapGet(resources, "limits")
+	if limits == nil || mapGet(limits, "memory") == nil || mapGet(limits, "cpu") == nil {
+		anchor := resources
+		if anchor == nil {
+			anchor = c
+		}
+		missing := []string{}
+		if mapGet(limits, "cpu") == nil {
+			missing = append(missing, "cpu")
+		}
+		if mapGet(limits, "memory") == nil {
+			missing = append(missing, "memory")
+		}
+		report.Add(Finding{
+			Check:    "iac.k8s.resource-limits",
+			Severity: SeverityWarning,
+			Resource: resource,
+			Message:  fmt.Sprintf("container has no %s limit", strings.Join(missing, "/")),
+			Details:  doc.details(nil),
+			Location: doc.location(anchor),
+		})
+	}
+
+	if !initContainer && doc.kind != "Job" && doc.kind != "CronJob" {
+		var missing []string
+		for _, probe := range []string{"livenessProbe", "readinessProbe"} {
+			if mapGet(c, probe) == nil {
+				missing = append(missing, probe)
+			}
+		}
+		if len(missing) > 0 {
+			report.Add(Finding{
+				Check:    "iac.k8s.probes",
+				Severity: SeverityWarning,
+				Resource: resource,
+				Message:  fmt.Sprintf("container is missing %s", strings.Join(missing, " and ")),
+				Details:  doc.details(nil),
+				Location: doc.location(c),
+			})
+		}
+	}
+
+	if img := mapGet(c, "image"); img != nil {
+		if tag, floating := imageTag(img.Value); floating {
+			report.Add(Finding{
+				Check:    "iac.k8s.image-tag",
+				Severity: SeverityWarning,
+				Resource: resource,
+				Message:  fmt.Sprintf("image %q uses a floating tag (%s); pin a version or digest", img.Value, tag),
+				Details:  doc.details(nil),
+				Location: doc.location(img),
+			})
+		}
+	}
+
+	sc := mapGet(c, "securityContext")
+	if n := mapGet(sc, "privileged"); n != nil && n.Value == "true" {
+		report.Add(Finding{
+			Check:    "iac.k8s.privileged",
+			Severity: SeverityCritical,
+			Resource: resource,
+			Message:  "container runs privileged; it has full access to the host",
+			Details:  doc.details(nil),
+			Location: doc.location(n),
+		})
+	}
+}
+
+// imageTag returns the effective tag of an image reference and whether it is floating ("latest" or missing).
+func imageTag(image string) (string, bool) {
+	if strings.Contains(image, "@sha256:") {
+		return "digest", false
+	}
+	last := image[strings.LastIndex(image, "/")+1:]
+	i := strings.LastIndex(last, ":")
+	if i < 0 {
+		return "implicit latest", true
+	}
+	tag := last[i+1:]
+	return tag, tag == "latest"
+}
+
+// labelsMatch reports whether every selector label is present in labels.
+func labelsMatch(selector, labels map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// mapGet returns the value node for key in a YAML mapping node, or nil.
+func mapGet(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// scalar returns the value of a scalar node, or "".
+func scalar(n *yaml.Node) string {
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return n.Value
+}
+
+// stringMap converts a mapping node of scalars into a map.
+func stringMap(n *yaml.Node) map[string]string {
+	out := map[string]string{}
+	if n == nil || n.Kind != yaml.MappingNode {
+		return out
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		out[n.Content[i].Value] = n.Content[i+1].Value
+	}
+	return out
+}
+
+// --- Terraform ---
+
+// analyzeTerraform parses one .tf file and runs Terraform checks on its resource blocks.
+func (d *IaCDiagnostics) analyzeTerraform(report *Report, path, rel string) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		report.Add(Finding{Check: "iac.parse", Severity: SeverityWarning, Message: fmt.Sprintf("failed to read file: %v", err), Location: &Location{File: rel}})
+		return
+	}
+	file, diags := hclsyntax.ParseConfig(src, rel, hcl.InitialPos)
+	if diags.HasErrors() {
+		line := 0
+		if len(diags) > 0 && diags[0].Subject != nil {
+			line = diags[0].Subject.Start.Line
+		}
+		report.Add(Finding{Check: "iac.parse", Severity: SeverityWarning, Message: fmt.Sprintf("failed to parse HCL: %s", diags.Error()), Location: &Location{File: rel, Line: line}})
+		return
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return
+	}
+	for _, block := range body.Blocks {
+		if block.Type != "resource" || len(block.Labels) != 2 {
+			continue
+		}
+		checkTerraformResource(report, rel, block)
+	}
+}
+
+// checkTerraformResource flags public S3 buckets and world-open security group ingress.
+func checkTerraformResource(report *Report, rel string, block *hclsyntax.Block) {
+	typ, name := block.Labels[0], block.Labels[1]
+	resource := typ + "." + name
+	switch typ {
+	case "aws_s3_bucket", "aws_s3_bucket_acl":
+		if attr, ok := block.Body.Attributes["acl"]; ok {
+			if v, known := staticString(attr.Expr); known && publicS3ACLs[v] {
+				report.Add(Finding{
+					Check:    "iac.tf.s3-public",
+					Severity: SeverityCritical,
+					Resource: resource,
+					Message:  fmt.Sprintf("S3 bucket ACL %q makes objects publicly readable", v),
+					Location: &Location{File: rel, Line: attr.SrcRange.Start.Line},
+				})
+			}
+		}
+	case "aws_s3_bucket_public_access_block":
+		for _, key := range []string{"block_public_acls", "block_public_policy", "ignore_public_acls", "restrict_public_buckets"} {
+			attr, ok := block.Body.Attributes[key]
+			if !ok {
+				continue
+			}
+			v, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() || !v.IsKnown() || v.IsNull() || v.Type() != cty.Bool {
+				continue
+			}
+			if v.False() {
+				report.Add(Finding{
+					Check:    "iac.tf.s3-public",
+					Severity: SeverityCritical,
+					Resource: resource,
+					Message:  fmt.Sprintf("public access block disables %s", key),
+					Location: &Location{File: rel, Line: attr.SrcRange.Start.Line},
+				})
+			}
+		}
+	case "aws_security_group", "aws_security_group_rule", "aws_vpc_security_group_ingress_rule":
+		checkOpenIngress(report, rel, resource, typ, block)
+	}
+}
+
+// checkOpenIngress flags ingress rules that allow 0.0.0.0/0 or ::/0.
+func checkOpenIngress(report *Report, rel, resource, typ string, block *hclsyntax.Block) {
+	rules := []*hclsyntax.Body{}
+	switch typ {
+	case "aws_security_group":
+		for _, b := range block.Body.Blocks {
+			if b.Type == "ingress" {
+				rules = append(rules, b.Body)
+			}
+		}
+	case "aws_security_group_rule":
+		if attr, ok := block.Body.Attributes["type"]; ok {
+			if v, known := staticString(attr.Expr); !known || v != "ingress" {
+				return
+			}
+		}
+		rules = append(rules, block.Body)
+	default:
+		rules = append(rules, block.Body)
+	}
+	for _, body := range rules {
+		for _, key := range []string{"cidr_blocks", "ipv6_cidr_blocks", "cidr_ipv4", "cidr_ipv6"} {
+			attr, ok := body.Attributes[key]
+			if !ok {
+				continue
+			}
+			for _, cidr := range staticStrings(attr.Expr) {
+				if cidr == "0.0.0.0/0" || cidr == "::/0" {
+					report.Add(Finding{
+						Check:    "iac.tf.open-ingress",
+						Severity: SeverityWarning,
+						Resource: resource,
+						Message:  fmt.Sprintf("ingress is open to the internet (%s)", cidr),
+						Location: &Location{File: rel, Line: attr.SrcRange.Start.Line},
+					})
+				}
+			}
+		}
+	}
+}
+
+// staticString evaluates an expression without variables and returns its string value if known.
+func staticString(expr hclsyntax.Expression) (string, bool) {
+	v, diags := expr.Value(nil)
+	if diags.HasErrors() || !v.IsKnown() || v.IsNull() || v.Type() != cty.String {
+		return "", false
+	}
+	return v.AsString(), true
+}
+
+// staticStrings evaluates a list/tuple or string expression without variables and returns its known strings.
+func staticStrings(expr hclsyntax.Expression) []string {
+	v, diags := expr.Value(nil)
+	if diags.HasErrors() || !v.IsKnown() || v.IsNull() {
+		return nil
+	}
+	if v.Type() == cty.String {
+		return []string{v.AsString()}
+	}
+	if !v.CanIterateElements() {
+		return nil
+	}
+	var out []string
+	for it := v.ElementIterator(); it.Next(); {
+		_, el := it.Element()
+		if el.IsKnown() && !el.IsNull() && el.Type() == cty.String {
+			out = append(out, el.AsString())
+		}
+	}
+	return out
+}
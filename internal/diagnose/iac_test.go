@@ -0,0 +1,238 @@
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+const iacTerraform = `resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+  acl    = "public-read"
+}
+
+resource "aws_security_group" "web" {
+  ingress {
+    from_port   = 22
+    to_port     = 22
+    protocol    = "tcp"
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+}
+`
+
+const iacDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: prod
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: api
+  template:
+    metadata:
+      labels:
+        app: api
+    spec:
+      containers:
+        - name: api
+          image: example.com/api:latest
+          securityContext:
+            privileged: true
+`
+
+const iacHelmRendered = `---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+        - name: web
+          image: example.com/web@sha256:0123456789abcdef
+          resources:
+            limits:
+              cpu: 500m
+              memory: 256Mi
+          readinessProbe:
+            httpGet:
+              path: /healthz
+              port: 8080
+          livenessProbe:
+            httpGet:
+              path: /healthz
+              port: 8080
+`
+
+func writeIaCFixtures(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.tf":                        iacTerraform,
+		"k8s/deployment.yaml":            iacDeployment,
+		"rendered.yaml":                  iacHelmRendered,
+		"chart/templates/configmap.yaml": "data:\n  name: {{ .Values.name }}\n",
+		".git/ignored.yaml":              "kind: [unterminated",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	return dir
+}
+
+func TestIaCDiagnostics_Run(t *testing.T) {
+	dir := writeIaCFixtures(t)
+	d := NewIaCDiagnostics(core.NewTestLogger(&bytes.Buffer{}), dir)
+	report, err := d.Run(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "iac", report.Diagnostic)
+	assert.Empty(t, findingsByCheck(report, "iac.parse"), "dot-directories must be skipped")
+
+	s3 := findingsByCheck(report, "iac.tf.s3-public")
+	require.Len(t, s3, 1)
+	assert.Equal(t, "aws_s3_bucket.logs", s3[0].Resource)
+	assert.Equal(t, &Location{File: "main.tf", Line: 3}, s3[0].Location)
+
+	ingress := findingsByCheck(report, "iac.tf.open-ingress")
+	require.Len(t, ingress, 1)
+	assert.Equal(t, 11, ingress[0].Location.Line)
+
+	privileged := findingsByCheck(report, "iac.k8s.privileged")
+	require.Len(t, privileged, 1)
+	assert.Equal(t, SeverityCritical, privileged[0].Severity)
+	assert.Equal(t, filepath.Join("k8s", "deployment.yaml"), privileged[0].Location.File)
+
+	tags := findingsByCheck(report, "iac.k8s.image-tag")
+	require.Len(t, tags, 1, "digest-pinned images must not be flagged")
+	assert.Equal(t, 18, tags[0].Location.Line)
+
+	replicas := findingsByCheck(report, "iac.k8s.single-replica")
+	require.Len(t, replicas, 1)
+	assert.Contains(t, replicas[0].Resource, "api")
+
+	// Only the api deployment lacks limits and probes; the rendered chart is compliant.
+	for _, check := range []string{"iac.k8s.resource-limits", "iac.k8s.probes"} {
+		for _, f := range findingsByCheck(report, check) {
+			assert.NotEqual(t, "rendered.yaml", f.Location.File, check)
+		}
+		assert.NotEmpty(t, findingsByCheck(report, check), check)
+	}
+
+	assert.Len(t, findingsByCheck(report, "iac.helm.unrendered"), 1)
+	assert.Equal(t, float64(3), report.Measurements["iac.files"])
+	assert.Equal(t, float64(2), report.Measurements["iac.k8s_objects"])
+}
+
+func TestIaCDiagnostics_HelmSource(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rendered.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+        - name: web
+          image: web
+`), 0o600))
+
+	report, err := NewIaCDiagnostics(core.NewTestLogger(&bytes.Buffer{}), path).Run(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, report.Findings)
+	for _, f := range report.Findings {
+		assert.Equal(t, "web/templates/deployment.yaml", f.Details["helm_source"], f.Check)
+		assert.Equal(t, "rendered.yaml", f.Location.File, f.Check)
+	}
+}
+
+func TestIaCDiagnostics_InvalidInput(t *testing.T) {
+	logger := core.NewTestLogger(&bytes.Buffer{})
+	_, err := NewIaCDiagnostics(logger, filepath.Join(t.TempDir(), "missing")).Run(context.Background())
+	assert.Error(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.tf"), []byte("resource \"a\" {\n"), 0o600))
+	report, err := NewIaCDiagnostics(logger, dir).Run(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, findingsByCheck(report, "iac.parse"), 1)
+}
+
+func TestWriteReport_SARIF(t *testing.T) {
+	report, err := NewIaCDiagnostics(core.NewTestLogger(&bytes.Buffer{}), writeIaCFixtures(t)).Run(context.Background())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteReport(&buf, report, "sarif"))
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	assert.Equal(t, "srediag", log.Runs[0].Tool.Driver.Name)
+	assert.Len(t, log.Runs[0].Results, len(report.Findings))
+
+	var found bool
+	for _, r := range log.Runs[0].Results {
+		if r.RuleID == "iac.tf.s3-public" {
+			found = true
+			assert.Equal(t, "error", r.Level)
+			require.Len(t, r.Locations, 1)
+			assert.Equal(t, "main.tf", r.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+			assert.Equal(t, 3, r.Locations[0].PhysicalLocation.Region.StartLine)
+		}
+	}
+	assert.True(t, found)
+}
@@ -0,0 +1,246 @@
+package diagnose
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file discovers installed diagnostic plugins and adapts them to the DiagPlugin contract. Each plugin lives in
+// <plugins.dir>/diagnostics/<name>/ with a manifest.yaml and an executable entrypoint:
+//
+//	name: fsmonitor
+//	type: diagnostic
+//	version: 0.2.0
+//	description: Filesystem diagnostics
+//	entrypoint: ./fsmonitor
+//	sha256: "<hex digest of the entrypoint>"   # optional, verified before every run
+//	capabilities: [diag/fs]
+//	commands:
+//	  - name: inode-usage
+//	    short: Report inode usage per filesystem
+//
+// Usage:
+//   - 'srediag diagnose inode-usage --threshold 90' runs '<entrypoint> inode-usage --threshold 90'; the plugin parses
+//     its own flags, inherits stdio, and receives SREDIAG_DIAG_PLUGIN and SREDIAG_DIAG_COMMAND in its environment.
+//
+// Best Practices:
+//   - Pin sha256 in the manifest; a mismatch makes Health fail and the command refuses to run.
+//
+// TODO(architecture/security.md §3): Verify cosign signatures and run entrypoints inside the diagnostics sandbox.
+
+// installedPluginsSubdir is the directory under plugins.dir that holds diagnostic plugins.
+const installedPluginsSubdir = "diagnostics"
+
+// manifestFile is the manifest file name inside each plugin directory.
+const manifestFile = "manifest.yaml"
+
+// PluginManifest is the manifest.yaml of an installed diagnostic plugin.
+//
+// Fields:
+//   - Name: Plugin ID; must match the directory name.
+//   - Type: Must be "diagnostic".
+//   - Version: Plugin version.
+//   - Description: Human-readable summary.
+//   - Entrypoint: Executable path, relative to the plugin directory.
+//   - SHA256: Optional hex-encoded SHA-256 of the entrypoint.
+//   - Capabilities: RBAC capabilities the plugin exercises.
+//   - Commands: Subcommands to expose; defaults to a single command named after the plugin.
+type PluginManifest struct {
+	Name         string            `yaml:"name"`
+	Type         string            `yaml:"type"`
+	Version      string            `yaml:"version"`
+	Description  string            `yaml:"description"`
+	Entrypoint   string            `yaml:"entrypoint"`
+	SHA256       string            `yaml:"sha256"`
+	Capabilities []string          `yaml:"capabilities"`
+	Commands     []ManifestCommand `yaml:"commands"`
+}
+
+// ManifestCommand describes a subcommand exposed by an installed plugin.
+type ManifestCommand struct {
+	Name    string   `yaml:"name"`
+	Aliases []string `yaml:"aliases"`
+	Short   string   `yaml:"short"`
+	Long    string   `yaml:"long"`
+}
+
+// execPlugin is a DiagPlugin backed by an installed executable.
+type execPlugin struct {
+	manifest   PluginManifest
+	dir        string
+	entrypoint string
+}
+
+// DiscoverInstalledPlugins loads every diagnostic plugin under <pluginsDir>/diagnostics.
+//
+// Parameters:
+//   - pluginsDir: The configured plugins.dir.
+//
+// Returns:
+//   - []DiagPlugin: Valid plugins, sorted by name.
+//   - []error: One error per invalid plugin directory; a missing diagnostics directory is not an error.
+func DiscoverInstalledPlugins(pluginsDir string) ([]DiagPlugin, []error) {
+	root := filepath.Join(pluginsDir, installedPluginsSubdir)
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, []error{fmt.Errorf("failed to read diagnostic plugin directory %s: %w", root, err)}
+	}
+	var plugins []DiagPlugin
+	var errs []error
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		p, err := loadInstalledPlugin(filepath.Join(root, entry.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		plugins = append(plugins, p)
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Info().Name < plugins[j].Info().Name })
+	return plugins, errs
+}
+
+// loadInstalledPlugin parses and validates the manifest in dir.
+func loadInstalledPlugin(dir string) (*execPlugin, error) {
+	path := filepath.Join(dir, manifestFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin manifest %s: %w", path, err)
+	}
+	var m PluginManifest
+	if err := core.StrictYAMLUnmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid plugin manifest %s: %w", path, err)
+	}
+	if m.Name != filepath.Base(dir) {
+		return nil, fmt.Errorf("invalid plugin manifest %s: name %q does not match directory", path, m.Name)
+	}
+	if m.Type != "diagnostic" {
+		return nil, fmt.Errorf("invalid plugin manifest %s: type %q is not \"diagnostic\"", path, m.Type)
+	}
+	if m.Entrypoint == "" {
+		return nil, fmt.Errorf("invalid plugin manifest %s: entrypoint is required", path)
+	}
+	entrypoint := filepath.Join(dir, m.Entrypoint)
+	if rel, err := filepath.Rel(dir, entrypoint); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return nil, fmt.Errorf("invalid plugin manifest %s: entrypoint must be inside the plugin directory", path)
+	}
+	for _, c := range m.Commands {
+		if c.Name == "" {
+			return nil, fmt.Errorf("invalid plugin manifest %s: command name is required", path)
+		}
+	}
+	return &execPlugin{manifest: m, dir: dir, entrypoint: entrypoint}, nil
+}
+
+// Info returns the plugin identity from the manifest.
+func (p *execPlugin) Info() PluginInfo {
+	return PluginInfo{
+		Name:        p.manifest.Name,
+		Version:     p.manifest.Version,
+		Description: p.manifest.Description,
+		Source:      PluginSourceInstalled,
+	}
+}
+
+// Capabilities returns the capabilities declared in the manifest.
+func (p *execPlugin) Capabilities() []string { return p.manifest.Capabilities }
+
+// Register adds one pass-through command per manifest command to the private branch.
+func (p *execPlugin) Register(branch *cobra.Command) error {
+	commands := p.manifest.Commands
+	if len(commands) == 0 {
+		commands = []ManifestCommand{{Name: p.manifest.Name, Short: p.manifest.Description}}
+	}
+	for _, mc := range commands {
+		short := mc.Short
+		if short == "" {
+			short = fmt.Sprintf("Run %s (%s plugin)", mc.Name, p.manifest.Name)
+		}
+		branch.AddCommand(&cobra.Command{
+			Use:                mc.Name,
+			Aliases:            mc.Aliases,
+			Short:              short,
+			Long:               mc.Long,
+			DisableFlagParsing: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return p.run(cmd, mc.Name, args)
+			},
+		})
+	}
+	return nil
+}
+
+// Health verifies that the entrypoint is an executable regular file matching the manifest digest.
+func (p *execPlugin) Health(_ context.Context) error {
+	info, err := os.Stat(p.entrypoint)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: entrypoint %s is missing", ErrNotFound, p.entrypoint)
+	}
+	if err != nil {
+		return fmt.Errorf("entrypoint unavailable: %w", err)
+	}
+	if !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+		return fmt.Errorf("entrypoint %s is not an executable file", p.entrypoint)
+	}
+	if p.manifest.SHA256 == "" {
+		return nil
+	}
+	f, err := os.Open(p.entrypoint)
+	if err != nil {
+		return fmt.Errorf("failed to open entrypoint: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to hash entrypoint: %w", err)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(sum, p.manifest.SHA256) {
+		return fmt.Errorf("%w: entrypoint checksum mismatch: manifest %s, actual %s", ErrVerification, p.manifest.SHA256, sum)
+	}
+	return nil
+}
+
+// run executes the plugin entrypoint for a single command invocation.
+func (p *execPlugin) run(cmd *cobra.Command, name string, args []string) (err error) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	metricsRun := NewRun(ctx, p.manifest.Name)
+	defer func() { metricsRun.End(err) }()
+	if err := p.Health(ctx); err != nil {
+		return fmt.Errorf("diagnostic plugin %s is unhealthy: %w", p.manifest.Name, err)
+	}
+	c := exec.CommandContext(ctx, p.entrypoint, append([]string{name}, args...)...)
+	c.Stdin = cmd.InOrStdin()
+	c.Stdout = cmd.OutOrStdout()
+	c.Stderr = cmd.ErrOrStderr()
+	c.Env = append(os.Environ(),
+		"SREDIAG_DIAG_PLUGIN="+p.manifest.Name,
+		"SREDIAG_DIAG_COMMAND="+name,
+	)
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("diagnostic plugin %s failed: %w", p.manifest.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,380 @@
+package diagnose
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file collects the host inventory attached to system reports (Report.Inventory): hashes of config files,
+// installed packages, listening sockets and kernel parameters. The inventory is what 'srediag diagnose diff' compares
+// to show configuration drift between a baseline and a later run.
+//
+// Usage:
+//   - SystemDiagnostics calls CollectInventory with the configured InventoryOptions.
+//   - Sections are keyed by the Inventory* constants; keys and values are plain strings.
+//
+// Best Practices:
+//   - Only collect stable values; counters such as fs.file-nr would show up as drift on every run.
+//   - Treat every source as optional: missing files or permissions reduce the inventory, they never fail the run.
+
+// Inventory sections of a report.
+const (
+	InventoryConfigFiles    = "config_files"    // path -> sha256 of the file contents
+	InventoryPackages       = "packages"        // package name -> version
+	InventoryListeningPorts = "listening_ports" // proto/address:port -> "uid=<uid>"
+	InventoryKernelParams   = "kernel_params"   // sysctl key -> value
+)
+
+// DefaultInventoryConfigFiles are the config files hashed when diagnostics.baseline.config_files is empty.
+var DefaultInventoryConfigFiles = []string{
+	"/etc/srediag/*.yaml",
+	"/etc/sysctl.conf",
+	"/etc/sysctl.d/*.conf",
+	"/etc/ssh/sshd_config",
+	"/etc/fstab",
+	"/etc/hosts",
+	"/etc/resolv.conf",
+}
+
+// DefaultKernelParamPrefixes are the /proc/sys subtrees collected when diagnostics.baseline.kernel_params is empty.
+var DefaultKernelParamPrefixes = []string{"kernel", "vm", "fs", "net.core", "net.ipv4"}
+
+// volatileKernelParams are kernel parameters that change on their own and are never collected.
+var volatileKernelParams = []string{
+	"fs.dentry-state", "fs.file-nr", "fs.inode-nr", "fs.inode-state", "fs.aio-nr", "fs.quota",
+	"kernel.random", "kernel.ns_last_pid", "kernel.pty.nr", "kernel.tainted",
+	"net.netfilter.nf_conntrack_count",
+}
+
+// hostRoot is prepended to every host path read by diagnostics (/etc, /proc, /var/lib); patchable in tests.
+var hostRoot = "/"
+
+// listRPMPackagesFunc lists RPM packages as "name version" lines; patchable in tests.
+var listRPMPackagesFunc = func(ctx context.Context) ([]byte, error) {
+	if _, err := exec.LookPath("rpm"); err != nil {
+		return nil, err
+	}
+	return exec.CommandContext(ctx, "rpm", "-qa", "--queryformat", "%{NAME} %{VERSION}-%{RELEASE}\n").Output()
+}
+
+// InventoryOptions selects what CollectInventory gathers.
+//
+// Fields:
+//   - ConfigFiles: Config file paths or glob patterns to hash; empty uses DefaultInventoryConfigFiles.
+//   - KernelParams: sysctl key prefixes (e.g., "net.ipv4") to collect; empty uses DefaultKernelParamPrefixes.
+type InventoryOptions struct {
+	ConfigFiles  []string
+	KernelParams []string
+}
+
+// CollectInventory gathers the host inventory.
+//
+// Parameters:
+//   - ctx: Context for cancellation.
+//   - opts: Sources to collect.
+//
+// Returns:
+//   - map[string]map[string]string: Inventory keyed by section, then by item.
+//   - []error: Sources that could not be read; the inventory is still usable.
+func CollectInventory(ctx context.Context, opts InventoryOptions) (map[string]map[string]string, []error) {
+	configFiles := opts.ConfigFiles
+	if len(configFiles) == 0 {
+		configFiles = DefaultInventoryConfigFiles
+	}
+	kernelParams := opts.KernelParams
+	if len(kernelParams) == 0 {
+		kernelParams = DefaultKernelParamPrefixes
+	}
+
+	inv := map[string]map[string]string{}
+	var errs []error
+	collect := func(section string, fn func() (map[string]string, error)) {
+		if ctx.Err() != nil {
+			return
+		}
+		items, err := fn()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", section, err))
+		}
+		if len(items) > 0 {
+			inv[section] = items
+		}
+	}
+	collect(InventoryConfigFiles, func() (map[string]string, error) { return hashConfigFiles(configFiles) })
+	collect(InventoryPackages, func() (map[string]string, error) { return installedPackages(ctx) })
+	collect(InventoryListeningPorts, listeningPorts)
+	collect(InventoryKernelParams, func() (map[string]string, error) { return kernelParameters(kernelParams) })
+	return inv, errs
+}
+
+// hostPath maps an absolute host path below hostRoot.
+func hostPath(p string) string {
+	return filepath.Join(hostRoot, filepath.FromSlash(p))
+}
+
+// hashConfigFiles returns the SHA-256 of every regular file matched by patterns, keyed by host path.
+func hashConfigFiles(patterns []string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(hostPath(pattern))
+		if err != nil {
+			return out, fmt.Errorf("invalid config file pattern %q: %w", pattern, err)
+		}
+		for _, m := range matches {
+			info, err := os.Stat(m)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			sum, err := hashFile(m)
+			if err != nil {
+				continue
+			}
+			rel, _ := filepath.Rel(hostRoot, m)
+			out["/"+filepath.ToSlash(rel)] = sum
+		}
+	}
+	return out, nil
+}
+
+// hashFile returns the hex SHA-256 of a file.
+func hashFile(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// installedPackages reads the dpkg or apk database, falling back to rpm.
+func installedPackages(ctx context.Context) (map[string]string, error) {
+	if f, err := os.Open(hostPath("/var/lib/dpkg/status")); err == nil {
+		defer f.Close()
+		return parseDpkgStatus(f)
+	}
+	if f, err := os.Open(hostPath("/lib/apk/db/installed")); err == nil {
+		defer f.Close()
+		return parseAPKInstalled(f)
+	}
+	data, err := listRPMPackagesFunc(ctx)
+	if err != nil {
+		return nil, nil // no supported package manager
+	}
+	out := map[string]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if name, version, ok := strings.Cut(strings.TrimSpace(line), " "); ok {
+			out[name] = version
+		}
+	}
+	return out, nil
+}
+
+// parseDpkgStatus returns the installed packages of a dpkg status file (name -> version).
+func parseDpkgStatus(r io.Reader) (map[string]string, error) {
+	out := map[string]string{}
+	var name, arch, version, status string
+	flush := func() {
+		if name != "" && strings.HasSuffix(status, " installed") {
+			if _, dup := out[name]; dup && arch != "" {
+				name += ":" + arch // multiarch: keep both entries
+			}
+			out[name] = version
+		}
+		name, arch, version, status = "", "", "", ""
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Package":
+			name = value
+		case "Architecture":
+			arch = value
+		case "Version":
+			version = value
+		case "Status":
+			status = value
+		}
+	}
+	flush()
+	if err := sc.Err(); err != nil {
+		return out, fmt.Errorf("failed to read dpkg status: %w", err)
+	}
+	return out, nil
+}
+
+// parseAPKInstalled returns the packages of an apk installed database (name -> version).
+func parseAPKInstalled(r io.Reader) (map[string]string, error) {
+	out := map[string]string{}
+	var name, version string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case line == "":
+			if name != "" {
+				out[name] = version
+			}
+			name, version = "", ""
+		case strings.HasPrefix(line, "P:"):
+			name = line[2:]
+		case strings.HasPrefix(line, "V:"):
+			version = line[2:]
+		}
+	}
+	if name != "" {
+		out[name] = version
+	}
+	if err := sc.Err(); err != nil {
+		return out, fmt.Errorf("failed to read apk database: %w", err)
+	}
+	return out, nil
+}
+
+// listeningPorts returns listening TCP sockets and bound UDP sockets from /proc/net.
+func listeningPorts() (map[string]string, error) {
+	out := map[string]string{}
+	var firstErr error
+	for _, proto := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		f, err := os.Open(hostPath("/proc/net/" + proto))
+		if err != nil {
+			if firstErr == nil && !os.IsNotExist(err) {
+				firstErr = err
+			}
+			continue
+		}
+		err = parseProcNet(f, strings.TrimSuffix(proto, "6"), out)
+		f.Close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return out, firstErr
+}
+
+// parseProcNet adds the listening sockets of a /proc/net/{tcp,udp}[6] table to out.
+func parseProcNet(r io.Reader, proto string, out map[string]string) error {
+	// TCP_LISTEN is 0A; unconnected UDP sockets are reported as TCP_CLOSE (07).
+	state := "0A"
+	if proto == "udp" {
+		state = "07"
+	}
+	sc := bufio.NewScanner(r)
+	for first := true; sc.Scan(); first = false {
+		fields := strings.Fields(sc.Text())
+		if first || len(fields) < 8 || fields[3] != state {
+			continue
+		}
+		addr, err := parseProcNetAddr(fields[1])
+		if err != nil {
+			continue
+		}
+		out[proto+"/"+addr] = "uid=" + fields[7]
+	}
+	return sc.Err()
+}
+
+// parseProcNetAddr decodes a /proc/net address ("0100007F:0016") into "127.0.0.1:22".
+func parseProcNetAddr(s string) (string, error) {
+	hexIP, hexPort, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", fmt.Errorf("invalid address %q", s)
+	}
+	raw, err := hex.DecodeString(hexIP)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return "", fmt.Errorf("invalid address %q", s)
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return "", fmt.Errorf("invalid port in %q", s)
+	}
+	// The kernel prints the address as host-order 32-bit words.
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	return net.JoinHostPort(ip.String(), strconv.FormatUint(port, 10)), nil
+}
+
+// kernelParameters reads the /proc/sys subtrees named by prefixes (sysctl notation), skipping volatile keys.
+func kernelParameters(prefixes []string) (map[string]string, error) {
+	out := map[string]string{}
+	root := hostPath("/proc/sys")
+	for _, prefix := range prefixes {
+		start := filepath.Join(root, filepath.FromSlash(strings.ReplaceAll(prefix, ".", "/")))
+		err := filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil // missing or unreadable subtrees are skipped
+			}
+			rel, _ := filepath.Rel(root, p)
+			key := strings.ReplaceAll(filepath.ToSlash(rel), "/", ".")
+			if isVolatileKernelParam(key) {
+				return nil
+			}
+			if info, err := d.Info(); err != nil || info.Mode().Perm()&0o444 == 0 {
+				return nil
+			}
+			value, err := readSysctl(p)
+			if err != nil {
+				return nil
+			}
+			out[key] = value
+			return nil
+		})
+		if err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// readSysctl reads a /proc/sys file and normalises its whitespace.
+func readSysctl(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, 4096))
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(string(data)), " "), nil
+}
+
+// isVolatileKernelParam reports whether key is, or is below, a volatile kernel parameter.
+func isVolatileKernelParam(key string) bool {
+	for _, v := range volatileKernelParams {
+		if key == v || strings.HasPrefix(key, v+".") {
+			return true
+		}
+	}
+	return false
+}
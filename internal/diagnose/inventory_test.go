@@ -0,0 +1,106 @@
+package diagnose
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const procNetTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:0CEA 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0 100 0 0 10 0
+   1: 0100007F:0CEA 0100007F:9C40 01 00000000:00000000 00:00000000 00000000  1000        0 1002 1 0 20 4 30 10 -1
+`
+
+const procNetTCP6 = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000001000000:1F90 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000   998        0 2001 1 0 100 0 0 10 0
+`
+
+const dpkgStatus = `Package: openssl
+Status: install ok installed
+Architecture: amd64
+Version: 3.0.13-0ubuntu3
+
+Package: removed-pkg
+Status: deinstall ok config-files
+Version: 1.0
+
+Package: libc6
+Status: install ok installed
+Architecture: amd64
+Version: 2.39-0ubuntu8
+
+Package: libc6
+Status: install ok installed
+Architecture: i386
+Version: 2.39-0ubuntu8
+`
+
+// writeFiles creates files (relative path -> contents) under root.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for p, body := range files {
+		full := filepath.Join(root, filepath.FromSlash(p))
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0o755))
+		require.NoError(t, os.WriteFile(full, []byte(body), 0o644))
+	}
+}
+
+func TestParseProcNetAddr(t *testing.T) {
+	cases := map[string]string{
+		"0100007F:0016":                         "127.0.0.1:22",
+		"00000000:0035":                         "0.0.0.0:53",
+		"00000000000000000000000001000000:1F90": "[::1]:8080",
+	}
+	for in, want := range cases {
+		got, err := parseProcNetAddr(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	_, err := parseProcNetAddr("zz:0016")
+	assert.Error(t, err)
+}
+
+func TestCollectInventory(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"etc/app/a.conf":                     "a=1\n",
+		"etc/app/b.conf":                     "b=2\n",
+		"var/lib/dpkg/status":                dpkgStatus,
+		"proc/net/tcp":                       procNetTCP,
+		"proc/net/tcp6":                      procNetTCP6,
+		"proc/sys/vm/swappiness":             "60\n",
+		"proc/sys/net/core/somaxconn":        "4096\n",
+		"proc/sys/fs/file-nr":                "1 0 100\n",
+		"proc/sys/kernel/random/boot_id":     "abc\n",
+		"proc/sys/kernel/sched_rr_timeslice": "100\n",
+	})
+	orig := hostRoot
+	hostRoot = root
+	defer func() { hostRoot = orig }()
+
+	inv, errs := CollectInventory(context.Background(), InventoryOptions{
+		ConfigFiles:  []string{"/etc/app/*.conf", "/etc/missing.conf"},
+		KernelParams: []string{"vm", "net.core", "fs", "kernel"},
+	})
+	assert.Empty(t, errs)
+	assert.Len(t, inv[InventoryConfigFiles], 2)
+	assert.Len(t, inv[InventoryConfigFiles]["/etc/app/a.conf"], 64)
+	assert.Equal(t, map[string]string{
+		"openssl": "3.0.13-0ubuntu3", "libc6": "2.39-0ubuntu8", "libc6:i386": "2.39-0ubuntu8",
+	}, inv[InventoryPackages])
+	assert.Equal(t, map[string]string{"tcp/127.0.0.1:3306": "uid=0", "tcp/[::1]:8080": "uid=998"}, inv[InventoryListeningPorts])
+	assert.Equal(t, map[string]string{
+		"vm.swappiness": "60", "net.core.somaxconn": "4096", "kernel.sched_rr_timeslice": "100",
+	}, inv[InventoryKernelParams], "volatile parameters are skipped")
+}
+
+func TestParseAPKInstalled(t *testing.T) {
+	pkgs, err := parseAPKInstalled(strings.NewReader("C:Q1abc=\nP:musl\nV:1.2.4-r2\n\nP:busybox\nV:1.36.1-r5\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"musl": "1.2.4-r2", "busybox": "1.36.1-r5"}, pkgs)
+}
@@ -0,0 +1,758 @@
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file defines the remote diagnostics JobRunner (docs/architecture/diagnose.md §6): the long-running service
+// polls a command source (an HTTP endpoint or a watched file) for signed job commands such as
+// "network tcp=db:5432 probe-timeout=2s", verifies each signature against trusted ed25519 keys, enforces freshness,
+// RBAC (execute:<capability> for the job's role) and a rate limit, runs the diagnostic through a JobExecutor (the
+// sandboxed child process of sandbox.go) and uploads the artifacts and a result document to an ArtifactSink
+// (jobsink.go).
+//
+// Usage:
+//   - Build a runner from diagnostics.remote with NewRemoteJobRunner, or from parts with NewJobRunner.
+//   - Call Start to poll in the background and Stop to shut it down; Poll runs a single iteration.
+//
+// Best Practices:
+//   - Keep the signing key offline; the agent only needs the public half.
+//   - Give commands a short expires_at: a job is accepted once per ID and never after it expires.
+
+// Defaults of the remote job runner.
+const (
+	DefaultJobPollInterval = 30 * time.Second
+	DefaultJobMaxAge       = 15 * time.Minute
+	DefaultJobTimeout      = 5 * time.Minute
+	DefaultJobsPerHour     = 12
+	DefaultJobBurst        = 3
+	DefaultJobMemoryMiB    = 128
+
+	// jobClockSkew is how far in the future issued_at may be.
+	jobClockSkew = time.Minute
+)
+
+// Job result states (JobResult.Status) and the control-plane verdict (JobResult.Result).
+const (
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobRejected  = "rejected"
+
+	JobResultOK   = "OK"
+	JobResultWarn = "WARN"
+	JobResultFail = "FAIL"
+)
+
+// RemoteDiagnostic describes a diagnostic that can be requested remotely.
+//
+// Fields:
+//   - Plugin: Built-in plugin ID (metrics label and RBAC owner).
+//   - Capability: Capability authorized as execute:<capability>.
+//   - DirFlag: Flag that receives the job directory when the diagnostic writes files of its own (e.g., bundle).
+type RemoteDiagnostic struct {
+	Plugin     string
+	Capability string
+	DirFlag    string
+}
+
+// RemoteDiagnostics lists the diagnose subcommands a job may run.
+var RemoteDiagnostics = map[string]RemoteDiagnostic{
+	"system":      {Plugin: BuiltinSystemSnapshot, Capability: CapabilitySystem},
+	"performance": {Plugin: BuiltinPerfProfiler, Capability: CapabilityPerf},
+	"security":    {Plugin: BuiltinCISBaseline, Capability: CapabilitySecurity},
+	"kubernetes":  {Plugin: BuiltinKubernetes, Capability: CapabilityKubernetes},
+	"network":     {Plugin: BuiltinNetwork, Capability: CapabilityNetwork},
+	"logs":        {Plugin: BuiltinLogs, Capability: CapabilityLogs},
+	"containers":  {Plugin: BuiltinContainers, Capability: CapabilityContainers},
+	"cloud":       {Plugin: BuiltinCloud, Capability: CapabilityCloud},
+	"bundle":      {Plugin: BuiltinSupportBundle, Capability: CapabilityBundle, DirFlag: "output-dir"},
+}
+
+// deniedJobFlags are flags a job may not set: they choose where output goes or what the agent trusts.
+var deniedJobFlags = map[string]bool{
+	"config": true, "output": true, "output-file": true, "output-dir": true, "export": true, "metrics-export": true,
+	"save-baseline": true, "baseline-dir": true, "watch": true, "for": true, "record": true, "checks": true,
+}
+
+// jobFlagName is the syntax of a job argument key.
+var jobFlagName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// JobCommand is the signed payload of a remote diagnostics job.
+//
+// Fields:
+//   - ID: Unique job ID; a job runs at most once per ID.
+//   - Command: "<diagnostic> [flag=value ...]", e.g. "network tcp=db:5432 probe-timeout=2s".
+//   - Role: RBAC role the job runs as; empty uses security.rbac.default_role.
+//   - Host: Target host name; empty targets every agent reading the source.
+//   - IssuedAt, ExpiresAt: Validity window; IssuedAt is required.
+type JobCommand struct {
+	ID        string    `json:"id"`
+	Command   string    `json:"command"`
+	Role      string    `json:"role,omitempty"`
+	Host      string    `json:"host,omitempty"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+// SignedJob is the envelope served by a command source.
+//
+// Fields:
+//   - Payload: Base64 of the JobCommand JSON.
+//   - Signature: Base64 ed25519 signature over the decoded payload bytes.
+//   - KeyID: Optional name of the trusted key (the key file name without extension).
+type SignedJob struct {
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+	KeyID     string `json:"key_id,omitempty"`
+}
+
+// Job is a verified and parsed job command.
+//
+// Fields:
+//   - JobCommand: The signed payload.
+//   - Diagnostic: The diagnose subcommand to run.
+//   - Args: Command-line flags built from the flag=value pairs, in command order.
+type Job struct {
+	JobCommand
+	Diagnostic string
+	Args       []string
+}
+
+// ParseJobCommand splits a command into the diagnostic and its flags.
+//
+// Parameters:
+//   - command: "<diagnostic> [flag=value ...]".
+//
+// Returns:
+//   - string: The diagnostic (a RemoteDiagnostics key).
+//   - []string: "--flag=value" arguments.
+//   - error: If the diagnostic is not remotely runnable or an argument is malformed or denied, returns a detailed error.
+func ParseJobCommand(command string) (string, []string, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return "", nil, errors.New("empty job command")
+	}
+	diagnostic := fields[0]
+	if _, ok := RemoteDiagnostics[diagnostic]; !ok {
+		return "", nil, fmt.Errorf("diagnostic %q cannot be run remotely: %w", diagnostic, ErrNotFound)
+	}
+	var args []string
+	for _, f := range fields[1:] {
+		key, value, ok := strings.Cut(f, "=")
+		switch {
+		case !ok || !jobFlagName.MatchString(key):
+			return "", nil, fmt.Errorf("invalid job argument %q (expected flag=value)", f)
+		case deniedJobFlags[key]:
+			return "", nil, fmt.Errorf("job argument %q is not allowed", key)
+		}
+		args = append(args, "--"+key+"="+value)
+	}
+	return diagnostic, args, nil
+}
+
+// LoadJobKeys reads trusted ed25519 public keys (PEM "PUBLIC KEY" blocks).
+//
+// Parameters:
+//   - paths: Key files; each key is named after its file name without extension.
+//
+// Returns:
+//   - map[string]ed25519.PublicKey: Keys by name.
+//   - error: If a file cannot be read or holds no ed25519 key, returns a detailed error.
+func LoadJobKeys(paths []string) (map[string]ed25519.PublicKey, error) {
+	keys := make(map[string]ed25519.PublicKey, len(paths))
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read job key: %w", err)
+		}
+		block, _ := pem.Decode(data)
+		if block == nil || block.Type != "PUBLIC KEY" {
+			return nil, fmt.Errorf("job key %s: no PEM PUBLIC KEY block", p)
+		}
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("job key %s: %w", p, err)
+		}
+		key, ok := pub.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("job key %s: %T is not an ed25519 key", p, pub)
+		}
+		keys[strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))] = key
+	}
+	return keys, nil
+}
+
+// VerifyJob checks the signature of an envelope and parses its command.
+//
+// Parameters:
+//   - env: The signed envelope.
+//   - keys: Trusted keys by name.
+//
+// Returns:
+//   - *Job: The verified job.
+//   - error: If the signature matches no trusted key (wraps ErrVerification) or the payload is invalid, returns a
+//     detailed error.
+func VerifyJob(env SignedJob, keys map[string]ed25519.PublicKey) (*Job, error) {
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: job payload is not base64: %v", ErrVerification, err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(env.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: job signature is not base64: %v", ErrVerification, err)
+	}
+	verified := false
+	for name, key := range keys {
+		if (env.KeyID == "" || env.KeyID == name) && ed25519.Verify(key, payload, sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("%w: job signature does not match any trusted key", ErrVerification)
+	}
+	job := &Job{}
+	if err := json.Unmarshal(payload, &job.JobCommand); err != nil {
+		return nil, fmt.Errorf("invalid job payload: %w", err)
+	}
+	if job.ID == "" || job.IssuedAt.IsZero() {
+		return nil, errors.New("invalid job payload: id and issued_at are required")
+	}
+	return job, nil
+}
+
+// JobSource returns the signed jobs currently published for the agent.
+//
+// Usage:
+//   - Implemented by the HTTP and file sources of NewJobSource; tests serve envelopes from httptest or a temp file.
+type JobSource interface {
+	// Fetch returns the published envelopes; the runner skips job IDs it has already handled.
+	Fetch(ctx context.Context) ([]SignedJob, error)
+}
+
+// NewJobSource creates a command source.
+//
+// Parameters:
+//   - spec: http(s):// URL polled with GET, or a file path (optionally file://) holding a JSON array or JSON Lines of
+//     envelopes; the file is re-read when it changes.
+//   - host: Agent host name, sent to HTTP sources as X-Srediag-Host.
+//
+// Returns:
+//   - JobSource: The source.
+//   - error: If spec is empty, returns an error.
+func NewJobSource(spec, host string) (JobSource, error) {
+	switch {
+	case spec == "":
+		return nil, errors.New("no job source configured (diagnostics.remote.source)")
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		return &httpJobSource{url: spec, host: host, client: &http.Client{Timeout: 30 * time.Second}}, nil
+	default:
+		return &fileJobSource{path: strings.TrimPrefix(spec, "file://")}, nil
+	}
+}
+
+// httpJobSource polls an HTTP endpoint.
+type httpJobSource struct {
+	url    string
+	host   string
+	client *http.Client
+}
+
+// Fetch implements JobSource; 204 No Content means no jobs.
+func (s *httpJobSource) Fetch(ctx context.Context) ([]SignedJob, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Srediag-Host", s.host)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch jobs: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return decodeSignedJobs(resp.Body)
+	case http.StatusNoContent:
+		return nil, nil
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("failed to fetch jobs: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+}
+
+// fileJobSource re-reads a file whenever its modification time or size changes.
+type fileJobSource struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// Fetch implements JobSource; a missing file means no jobs.
+func (s *fileJobSource) Fetch(context.Context) ([]SignedJob, error) {
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jobs: %w", err)
+	}
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return nil, nil
+	}
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jobs: %w", err)
+	}
+	defer f.Close()
+	jobs, err := decodeSignedJobs(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	s.modTime, s.size = info.ModTime(), info.Size()
+	return jobs, nil
+}
+
+// decodeSignedJobs reads a JSON array or a stream of JSON objects (JSON Lines).
+func decodeSignedJobs(r io.Reader) ([]SignedJob, error) {
+	data, err := io.ReadAll(io.LimitReader(r, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	var jobs []SignedJob
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &jobs); err != nil {
+			return nil, fmt.Errorf("invalid job list: %w", err)
+		}
+		return jobs, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var job SignedJob
+		if err := dec.Decode(&job); err != nil {
+			return nil, fmt.Errorf("invalid job list: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// JobResult is the result document uploaded with a job's artifacts.
+//
+// Fields:
+//   - ID, Command, Host: The job and the agent that handled it.
+//   - Status: JobSucceeded, JobFailed or JobRejected.
+//   - Result: Control-plane verdict: JobResultOK, JobResultWarn (warning or critical findings) or JobResultFail.
+//   - Error: Failure or rejection reason.
+//   - Findings: Number of findings by severity.
+//   - Artifacts: Names of the uploaded files.
+//   - Started, Finished: Run window (zero for rejected jobs).
+type JobResult struct {
+	ID        string           `json:"id"`
+	Command   string           `json:"command"`
+	Host      string           `json:"host"`
+	Status    string           `json:"status"`
+	Result    string           `json:"result"`
+	Error     string           `json:"error,omitempty"`
+	Findings  map[Severity]int `json:"findings,omitempty"`
+	Artifacts []string         `json:"artifacts,omitempty"`
+	Started   time.Time        `json:"started,omitzero"`
+	Finished  time.Time        `json:"finished,omitzero"`
+}
+
+// JobRunnerOptions configures a JobRunner.
+//
+// Fields:
+//   - Keys: Trusted signing keys; at least one is required.
+//   - PollInterval: Interval between polls; defaults to DefaultJobPollInterval.
+//   - MaxAge: How long after issued_at a job is accepted; defaults to DefaultJobMaxAge.
+//   - JobsPerHour, Burst: Rate limit of started jobs; default DefaultJobsPerHour and DefaultJobBurst.
+//   - WorkDir: Parent of the per-job artifact directories; defaults to <state dir>/jobs.
+//   - Security: Security config used for RBAC.
+//   - RBAC: Capability registry; the remote diagnostics are registered in it.
+//   - Host: Agent host name; defaults to os.Hostname.
+type JobRunnerOptions struct {
+	Keys         map[string]ed25519.PublicKey
+	PollInterval time.Duration
+	MaxAge       time.Duration
+	JobsPerHour  int
+	Burst        int
+	WorkDir      string
+	Security     core.SecurityConfig
+	RBAC         *core.RBAC
+	Host         string
+}
+
+// JobRunner polls a command source and runs the verified jobs one at a time.
+//
+// Usage:
+//   - Instantiate with NewJobRunner or NewRemoteJobRunner; call Start and Stop with the service.
+type JobRunner struct {
+	logger   *core.Logger
+	source   JobSource
+	executor JobExecutor
+	sink     ArtifactSink
+	opts     JobRunnerOptions
+	limiter  *rate.Limiter
+	now      func() time.Time
+
+	mu     sync.Mutex
+	seen   map[string]time.Time // job ID -> time after which the ID can be forgotten
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewJobRunner creates a job runner.
+//
+// Parameters:
+//   - logger: Logger for status and error reporting.
+//   - source: Command source.
+//   - executor: Runs a job's diagnostic into its artifact directory.
+//   - sink: Receives the artifacts and the result document.
+//   - opts: Keys, limits and RBAC settings.
+//
+// Returns:
+//   - *JobRunner: The runner.
+//   - error: If no signing key is configured or the remote diagnostics cannot be registered with RBAC, returns a
+//     detailed error.
+func NewJobRunner(logger *core.Logger, source JobSource, executor JobExecutor, sink ArtifactSink, opts JobRunnerOptions) (*JobRunner, error) {
+	if len(opts.Keys) == 0 {
+		return nil, fmt.Errorf("%w: remote jobs need at least one trusted key (diagnostics.remote.public_keys)", ErrVerification)
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultJobPollInterval
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = DefaultJobMaxAge
+	}
+	if opts.JobsPerHour <= 0 {
+		opts.JobsPerHour = DefaultJobsPerHour
+	}
+	if opts.Burst <= 0 {
+		opts.Burst = DefaultJobBurst
+	}
+	if opts.WorkDir == "" {
+		opts.WorkDir = filepath.Join(core.DefaultStateDir(), "jobs")
+	}
+	if opts.Host == "" {
+		opts.Host, _ = os.Hostname()
+	}
+	if opts.RBAC == nil {
+		opts.RBAC = core.NewRBAC()
+	}
+	for _, d := range RemoteDiagnostics {
+		if err := opts.RBAC.RegisterCapabilities(d.Plugin, []string{d.Capability}); err != nil {
+			return nil, err
+		}
+	}
+	return &JobRunner{
+		logger:   logger,
+		source:   source,
+		executor: executor,
+		sink:     sink,
+		opts:     opts,
+		limiter:  rate.NewLimiter(rate.Every(time.Hour/time.Duration(opts.JobsPerHour)), opts.Burst),
+		now:      time.Now,
+		seen:     make(map[string]time.Time),
+	}, nil
+}
+
+// Start polls the source in the background until Stop is called.
+//
+// Parameters:
+//   - ctx: Parent context of the polling loop and of the jobs.
+func (r *JobRunner) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	r.mu.Lock()
+	r.cancel, r.done = cancel, make(chan struct{})
+	r.mu.Unlock()
+	r.logger.Info("Remote diagnostics job runner started", core.ZapString("interval", r.opts.PollInterval.String()))
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.opts.PollInterval)
+		defer ticker.Stop()
+		for {
+			if err := r.Poll(ctx); err != nil && ctx.Err() == nil {
+				r.logger.Warn("Remote job poll failed", core.ZapError(err))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels the polling loop (and a running job) and waits for it to exit.
+//
+// Parameters:
+//   - ctx: Bounds the wait.
+//
+// Returns:
+//   - error: ctx.Err() if the loop did not exit in time.
+func (r *JobRunner) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	cancel, done := r.cancel, r.done
+	r.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Poll fetches the source once and handles every new job.
+//
+// Parameters:
+//   - ctx: Context for cancellation.
+//
+// Returns:
+//   - error: If the source cannot be read, returns a detailed error; job failures are reported to the sink instead.
+func (r *JobRunner) Poll(ctx context.Context) error {
+	envs, err := r.source.Fetch(ctx)
+	if err != nil {
+		return err
+	}
+	r.forgetExpired()
+	for _, env := range envs {
+		if ctx.Err() != nil {
+			return nil
+		}
+		job, err := VerifyJob(env, r.opts.Keys)
+		if err != nil {
+			r.logger.Warn("Rejected unverified remote job", core.ZapError(err))
+			continue
+		}
+		if r.seenJob(job.ID) {
+			continue
+		}
+		if job.Host != "" && job.Host != r.opts.Host {
+			r.markSeen(job)
+			continue
+		}
+		if err := r.admit(job); err != nil {
+			r.markSeen(job)
+			r.reject(ctx, job, err)
+			continue
+		}
+		if !r.limiter.Allow() {
+			// Left unseen so that a later poll picks it up while it is still fresh.
+			r.logger.Warn("Remote job rate limited", core.ZapString("job", job.ID))
+			continue
+		}
+		r.markSeen(job)
+		r.run(ctx, job)
+	}
+	return nil
+}
+
+// admit checks freshness, the command and RBAC.
+func (r *JobRunner) admit(job *Job) error {
+	now := r.now()
+	switch {
+	case job.IssuedAt.After(now.Add(jobClockSkew)):
+		return fmt.Errorf("job issued in the future (%s)", job.IssuedAt.UTC().Format(time.RFC3339))
+	case now.Sub(job.IssuedAt) > r.opts.MaxAge, !job.ExpiresAt.IsZero() && now.After(job.ExpiresAt):
+		return errors.New("job expired")
+	}
+	diagnostic, args, err := ParseJobCommand(job.Command)
+	if err != nil {
+		return err
+	}
+	job.Diagnostic, job.Args = diagnostic, args
+	return r.opts.RBAC.Authorize(r.opts.Security, job.Role, "execute", RemoteDiagnostics[diagnostic].Capability)
+}
+
+// run executes a job and uploads its artifacts.
+func (r *JobRunner) run(ctx context.Context, job *Job) {
+	r.logger.Info("Running remote job", core.ZapString("job", job.ID), core.ZapString("command", job.Command))
+	result := &JobResult{ID: job.ID, Command: job.Command, Host: r.opts.Host, Started: r.now()}
+	dir := filepath.Join(r.opts.WorkDir, jobDirName(job.ID))
+	defer os.RemoveAll(dir)
+
+	run := NewRun(ctx, RemoteDiagnostics[job.Diagnostic].Plugin)
+	err := os.MkdirAll(dir, 0o700)
+	if err == nil {
+		err = r.executor.Execute(ctx, job, dir)
+	}
+	if errors.Is(err, ErrSandbox) {
+		run.SandboxViolation(sandboxResource(err))
+	}
+	run.End(err)
+	result.Finished = r.now()
+
+	result.Status, result.Result = JobSucceeded, JobResultOK
+	if data, readErr := os.ReadFile(filepath.Join(dir, JobReportFile)); readErr == nil {
+		var report Report
+		if json.Unmarshal(data, &report) == nil && len(report.Findings) > 0 {
+			result.Findings = make(map[Severity]int)
+			for _, f := range report.Findings {
+				result.Findings[f.Severity]++
+			}
+			if report.MaxSeverity().Rank() >= SeverityWarning.Rank() {
+				result.Result = JobResultWarn
+			}
+		}
+	}
+	if err != nil {
+		result.Status, result.Result, result.Error = JobFailed, JobResultFail, err.Error()
+		r.logger.Warn("Remote job failed", core.ZapString("job", job.ID), core.ZapError(err))
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if e.Type().IsRegular() {
+			result.Artifacts = append(result.Artifacts, e.Name())
+		}
+	}
+	sort.Strings(result.Artifacts)
+	if err := r.sink.Upload(ctx, result, dir); err != nil {
+		r.logger.Error("Failed to upload remote job artifacts", core.ZapString("job", job.ID), core.ZapError(err))
+		return
+	}
+	r.logger.Info("Remote job finished", core.ZapString("job", job.ID), core.ZapString("result", result.Result))
+}
+
+// reject reports a verified job that may not run.
+func (r *JobRunner) reject(ctx context.Context, job *Job, reason error) {
+	r.logger.Warn("Rejected remote job", core.ZapString("job", job.ID), core.ZapError(reason))
+	result := &JobResult{ID: job.ID, Command: job.Command, Host: r.opts.Host, Status: JobRejected, Result: JobResultFail,
+		Error: reason.Error()}
+	if err := r.sink.Upload(ctx, result, ""); err != nil {
+		r.logger.Error("Failed to upload remote job rejection", core.ZapString("job", job.ID), core.ZapError(err))
+	}
+}
+
+// seenJob reports whether a job ID has been handled.
+func (r *JobRunner) seenJob(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.seen[id]
+	return ok
+}
+
+// markSeen records a handled job until it can no longer be accepted.
+func (r *JobRunner) markSeen(job *Job) {
+	until := job.IssuedAt.Add(r.opts.MaxAge + jobClockSkew)
+	if job.ExpiresAt.After(until) {
+		until = job.ExpiresAt
+	}
+	r.mu.Lock()
+	r.seen[job.ID] = until
+	r.mu.Unlock()
+}
+
+// forgetExpired drops job IDs that would be rejected as expired anyway.
+func (r *JobRunner) forgetExpired() {
+	now := r.now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, until := range r.seen {
+		if now.After(until) {
+			delete(r.seen, id)
+		}
+	}
+}
+
+// jobDirName makes a job ID safe as a directory name.
+func jobDirName(id string) string {
+	return strings.Map(func(c rune) rune {
+		if c == '-' || c == '_' || c == '.' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') {
+			return c
+		}
+		return '_'
+	}, id)
+}
+
+// NewRemoteJobRunner builds a job runner from diagnostics.remote.
+//
+// Parameters:
+//   - logger: Logger for status and error reporting.
+//   - cfg: Configuration (diagnostics.remote and security).
+//   - rbac: Capability registry of the process.
+//   - configFile: Config file passed to the diagnostic child processes; empty uses their default discovery.
+//
+// Returns:
+//   - *JobRunner: The runner, or nil when diagnostics.remote.enabled is false.
+//   - error: If the settings are invalid or a key cannot be loaded, returns a detailed error.
+func NewRemoteJobRunner(logger *core.Logger, cfg *core.Config, rbac *core.RBAC, configFile string) (*JobRunner, error) {
+	rc := cfg.Diagnostics.Remote
+	if !rc.Enabled {
+		return nil, nil
+	}
+	opts := JobRunnerOptions{JobsPerHour: rc.MaxJobsPerHour, Burst: rc.Burst, WorkDir: rc.WorkDir, Security: cfg.Security, RBAC: rbac}
+	limits := SandboxLimits{MemoryMiB: rc.MemoryLimitMiB}
+	if limits.MemoryMiB == 0 {
+		limits.MemoryMiB = cfg.Security.Runtime.MemGuardMiB
+	}
+	if limits.MemoryMiB == 0 {
+		limits.MemoryMiB = DefaultJobMemoryMiB
+	}
+	durations := []struct {
+		value, key string
+		dst        *time.Duration
+	}{
+		{rc.PollInterval, "poll_interval", &opts.PollInterval},
+		{rc.MaxAge, "max_age", &opts.MaxAge},
+		{rc.Timeout, "timeout", &limits.Timeout},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid diagnostics.remote.%s %q: %w", d.key, d.value, err)
+		}
+		*d.dst = v
+	}
+	keys, err := LoadJobKeys(rc.PublicKeys)
+	if err != nil {
+		return nil, err
+	}
+	opts.Keys = keys
+	host, _ := os.Hostname()
+	source, err := NewJobSource(rc.Source, host)
+	if err != nil {
+		return nil, err
+	}
+	sink, err := NewArtifactSink(rc.Sink.URL, rc.Sink.Headers)
+	if err != nil {
+		return nil, err
+	}
+	executor, err := NewSandboxExecutor(configFile, limits)
+	if err != nil {
+		return nil, err
+	}
+	return NewJobRunner(logger, source, executor, sink, opts)
+}
@@ -0,0 +1,401 @@
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// signJob builds a signed envelope for cmd.
+func signJob(t *testing.T, key ed25519.PrivateKey, cmd JobCommand) SignedJob {
+	t.Helper()
+	payload, err := json.Marshal(cmd)
+	require.NoError(t, err)
+	return SignedJob{
+		Payload:   base64.StdEncoding.EncodeToString(payload),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload)),
+	}
+}
+
+// fakeExecutor records jobs and writes a report with one finding of the configured severity.
+type fakeExecutor struct {
+	mu       sync.Mutex
+	jobs     []*Job
+	severity Severity
+	err      error
+}
+
+func (f *fakeExecutor) Execute(_ context.Context, job *Job, dir string) error {
+	f.mu.Lock()
+	f.jobs = append(f.jobs, job)
+	f.mu.Unlock()
+	r := NewReport(job.Diagnostic)
+	if f.severity != "" {
+		r.Add(Finding{Check: "fake.check", Severity: f.severity, Message: "fake"})
+	}
+	data, _ := json.Marshal(r)
+	if err := os.WriteFile(filepath.Join(dir, JobReportFile), data, 0o600); err != nil {
+		return err
+	}
+	return f.err
+}
+
+func (f *fakeExecutor) ids() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ids []string
+	for _, j := range f.jobs {
+		ids = append(ids, j.ID)
+	}
+	return ids
+}
+
+// newTestRunner creates a runner over source with a fake executor and a directory sink.
+func newTestRunner(t *testing.T, source JobSource, opts JobRunnerOptions) (*JobRunner, *fakeExecutor, string, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	opts.Keys = map[string]ed25519.PublicKey{"ops": pub}
+	opts.WorkDir = t.TempDir()
+	if opts.Host == "" {
+		opts.Host = "node-1"
+	}
+	sinkDir := t.TempDir()
+	sink, err := NewArtifactSink(sinkDir, nil)
+	require.NoError(t, err)
+	exec := &fakeExecutor{}
+	r, err := NewJobRunner(core.NewTestLogger(&bytes.Buffer{}), source, exec, sink, opts)
+	require.NoError(t, err)
+	return r, exec, sinkDir, priv
+}
+
+// readResult reads the uploaded result of a job from a directory sink.
+func readResult(t *testing.T, sinkDir, id string) JobResult {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(sinkDir, id, JobResultFile))
+	require.NoError(t, err)
+	var res JobResult
+	require.NoError(t, json.Unmarshal(data, &res))
+	return res
+}
+
+// staticSource serves a fixed envelope list.
+type staticSource struct{ jobs []SignedJob }
+
+func (s *staticSource) Fetch(context.Context) ([]SignedJob, error) { return s.jobs, nil }
+
+func TestParseJobCommand(t *testing.T) {
+	diag, args, err := ParseJobCommand("network tcp=db:5432 probe-timeout=2s")
+	require.NoError(t, err)
+	assert.Equal(t, "network", diag)
+	assert.Equal(t, []string{"--tcp=db:5432", "--probe-timeout=2s"}, args)
+
+	_, _, err = ParseJobCommand("plugin run x")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, _, err = ParseJobCommand("logs output-file=/etc/passwd")
+	assert.ErrorContains(t, err, "not allowed")
+	_, _, err = ParseJobCommand("logs --since=1h")
+	assert.ErrorContains(t, err, "invalid job argument")
+	_, _, err = ParseJobCommand("  ")
+	assert.Error(t, err)
+}
+
+func TestVerifyJob(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keys := map[string]ed25519.PublicKey{"ops": pub}
+	cmd := JobCommand{ID: "j1", Command: "system", IssuedAt: time.Now()}
+
+	env := signJob(t, priv, cmd)
+	job, err := VerifyJob(env, keys)
+	require.NoError(t, err)
+	assert.Equal(t, "j1", job.ID)
+
+	env.KeyID = "other"
+	_, err = VerifyJob(env, map[string]ed25519.PublicKey{"ops": pub, "other": otherPub})
+	assert.ErrorIs(t, err, ErrVerification)
+
+	tampered := signJob(t, priv, cmd)
+	payload, _ := json.Marshal(JobCommand{ID: "j1", Command: "bundle", IssuedAt: cmd.IssuedAt})
+	tampered.Payload = base64.StdEncoding.EncodeToString(payload)
+	_, err = VerifyJob(tampered, keys)
+	assert.ErrorIs(t, err, ErrVerification)
+
+	_, err = VerifyJob(signJob(t, priv, JobCommand{ID: "j2", Command: "system"}), keys)
+	assert.ErrorContains(t, err, "issued_at")
+}
+
+func TestLoadJobKeys(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "ops.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	keys, err := LoadJobKeys([]string{path})
+	require.NoError(t, err)
+	assert.True(t, keys["ops"].Equal(pub))
+
+	bad := filepath.Join(t.TempDir(), "bad.pem")
+	require.NoError(t, os.WriteFile(bad, []byte("not a key"), 0o600))
+	_, err = LoadJobKeys([]string{bad})
+	assert.Error(t, err)
+}
+
+func TestJobRunnerFileSourceAndDirSink(t *testing.T) {
+	jobsFile := filepath.Join(t.TempDir(), "jobs.jsonl")
+	source, err := NewJobSource("file://"+jobsFile, "node-1")
+	require.NoError(t, err)
+	r, exec, sinkDir, priv := newTestRunner(t, source, JobRunnerOptions{})
+	exec.severity = SeverityWarning
+	now := time.Now()
+
+	// A missing file means no jobs.
+	require.NoError(t, r.Poll(context.Background()))
+	assert.Empty(t, exec.ids())
+
+	_, forged, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	envs := []SignedJob{
+		signJob(t, priv, JobCommand{ID: "ok", Command: "network tcp=db:5432", IssuedAt: now}),
+		signJob(t, priv, JobCommand{ID: "elsewhere", Command: "system", Host: "node-2", IssuedAt: now}),
+		signJob(t, priv, JobCommand{ID: "expired", Command: "system", IssuedAt: now.Add(-time.Hour)}),
+		signJob(t, priv, JobCommand{ID: "denied-flag", Command: "logs output-file=/tmp/x", IssuedAt: now}),
+		signJob(t, forged, JobCommand{ID: "forged", Command: "system", IssuedAt: now}),
+	}
+	var buf bytes.Buffer
+	for _, env := range envs {
+		require.NoError(t, json.NewEncoder(&buf).Encode(env))
+	}
+	require.NoError(t, os.WriteFile(jobsFile, buf.Bytes(), 0o600))
+	require.NoError(t, r.Poll(context.Background()))
+
+	assert.Equal(t, []string{"ok"}, exec.ids())
+	assert.Equal(t, []string{"--tcp=db:5432"}, exec.jobs[0].Args)
+
+	res := readResult(t, sinkDir, "ok")
+	assert.Equal(t, JobSucceeded, res.Status)
+	assert.Equal(t, JobResultWarn, res.Result)
+	assert.Equal(t, "node-1", res.Host)
+	assert.Equal(t, 1, res.Findings[SeverityWarning])
+	assert.Equal(t, []string{JobReportFile}, res.Artifacts)
+	assert.FileExists(t, filepath.Join(sinkDir, "ok", JobReportFile))
+
+	for id, reason := range map[string]string{"expired": "expired", "denied-flag": "not allowed"} {
+		res := readResult(t, sinkDir, id)
+		assert.Equal(t, JobRejected, res.Status, id)
+		assert.Equal(t, JobResultFail, res.Result, id)
+		assert.Contains(t, res.Error, reason, id)
+	}
+	assert.NoDirExists(t, filepath.Join(sinkDir, "elsewhere"))
+	assert.NoDirExists(t, filepath.Join(sinkDir, "forged"))
+
+	// The per-job work directory is removed after upload.
+	entries, err := os.ReadDir(r.opts.WorkDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestJobRunnerRBAC(t *testing.T) {
+	source := &staticSource{}
+	var security core.SecurityConfig
+	security.RBAC.Enabled = true
+	security.RBAC.DefaultRole = "viewer"
+	security.RBAC.Roles = map[string][]string{"viewer": {"read:*"}, "sre": {"execute:diag/network"}}
+	r, exec, sinkDir, priv := newTestRunner(t, source, JobRunnerOptions{Security: security, RBAC: core.NewRBAC()})
+	now := time.Now()
+	source.jobs = []SignedJob{
+		signJob(t, priv, JobCommand{ID: "sre-network", Command: "network", Role: "sre", IssuedAt: now}),
+		signJob(t, priv, JobCommand{ID: "sre-bundle", Command: "bundle", Role: "sre", IssuedAt: now}),
+		signJob(t, priv, JobCommand{ID: "default-role", Command: "network", IssuedAt: now}),
+	}
+	require.NoError(t, r.Poll(context.Background()))
+
+	assert.Equal(t, []string{"sre-network"}, exec.ids())
+	for _, id := range []string{"sre-bundle", "default-role"} {
+		res := readResult(t, sinkDir, id)
+		assert.Equal(t, JobRejected, res.Status, id)
+		assert.Contains(t, res.Error, core.ErrAccessDenied.Error(), id)
+	}
+}
+
+func TestJobRunnerRateLimitAndDedupe(t *testing.T) {
+	source := &staticSource{}
+	r, exec, sinkDir, priv := newTestRunner(t, source, JobRunnerOptions{JobsPerHour: 1, Burst: 1})
+	exec.err = errors.New("boom")
+	now := time.Now()
+	source.jobs = []SignedJob{
+		signJob(t, priv, JobCommand{ID: "a", Command: "system", IssuedAt: now}),
+		signJob(t, priv, JobCommand{ID: "b", Command: "system", IssuedAt: now}),
+	}
+	require.NoError(t, r.Poll(context.Background()))
+	assert.Equal(t, []string{"a"}, exec.ids())
+	res := readResult(t, sinkDir, "a")
+	assert.Equal(t, JobFailed, res.Status)
+	assert.Equal(t, JobResultFail, res.Result)
+	assert.Equal(t, "boom", res.Error)
+
+	// "b" was rate limited, not consumed: it runs once the limiter allows it; "a" never runs twice.
+	r.limiter = rate.NewLimiter(rate.Inf, 1)
+	require.NoError(t, r.Poll(context.Background()))
+	require.NoError(t, r.Poll(context.Background()))
+	assert.Equal(t, []string{"a", "b"}, exec.ids())
+}
+
+func TestJobRunnerHTTPSourceAndSink(t *testing.T) {
+	var envs []SignedJob
+	var mu sync.Mutex
+	uploads := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == http.MethodGet && req.URL.Path == "/jobs":
+			assert.Equal(t, "node-1", req.Header.Get("X-Srediag-Host"))
+			_ = json.NewEncoder(w).Encode(envs)
+		case req.Method == http.MethodPut && strings.HasPrefix(req.URL.Path, "/artifacts/"):
+			if req.Header.Get("Authorization") != "Bearer s3cret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			body, _ := io.ReadAll(req.Body)
+			mu.Lock()
+			uploads[strings.TrimPrefix(req.URL.Path, "/artifacts/")] = body
+			mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	source, err := NewJobSource(srv.URL+"/jobs", "node-1")
+	require.NoError(t, err)
+	t.Setenv("SINK_TOKEN", "s3cret")
+	sink, err := NewArtifactSink(srv.URL+"/artifacts/", map[string]string{"Authorization": "Bearer ${SINK_TOKEN}"})
+	require.NoError(t, err)
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	exec := &fakeExecutor{}
+	r, err := NewJobRunner(core.NewTestLogger(&bytes.Buffer{}), source, exec, sink, JobRunnerOptions{
+		Keys: map[string]ed25519.PublicKey{"ops": pub}, WorkDir: t.TempDir(), Host: "node-1", PollInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	envs = []SignedJob{signJob(t, priv, JobCommand{ID: "h1", Command: "cloud", IssuedAt: time.Now()})}
+
+	r.Start(context.Background())
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return uploads["h1/"+JobResultFile] != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, r.Stop(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, uploads, "h1/"+JobReportFile)
+	var res JobResult
+	require.NoError(t, json.Unmarshal(uploads["h1/"+JobResultFile], &res))
+	assert.Equal(t, JobSucceeded, res.Status)
+	assert.Equal(t, JobResultOK, res.Result)
+	assert.Equal(t, []string{"h1"}, exec.ids())
+}
+
+func TestSandboxExecutor(t *testing.T) {
+	// Stand-in for the srediag binary: logs its arguments, writes a report and exits with $FAKE_EXIT.
+	script := filepath.Join(t.TempDir(), "srediag")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+echo "args: $*"
+echo '{"diagnostic":"network","findings":[]}' > report.json
+[ -n "$FAKE_SLEEP" ] && sleep "$FAKE_SLEEP"
+echo "exiting $FAKE_EXIT" >&2
+exit "${FAKE_EXIT:-0}"
+`), 0o755))
+	e := &SandboxExecutor{binary: script, configFile: "/etc/srediag/srediag.yaml", limits: SandboxLimits{Timeout: 5 * time.Second, CPUSeconds: 10}}
+	job := &Job{JobCommand: JobCommand{ID: "s1"}, Diagnostic: "bundle", Args: []string{"--include-logs=false"}}
+
+	run := func(exit, sleep string) (string, error) {
+		t.Setenv("FAKE_EXIT", exit)
+		t.Setenv("FAKE_SLEEP", sleep)
+		dir := t.TempDir()
+		err := e.Execute(context.Background(), job, dir)
+		log, _ := os.ReadFile(filepath.Join(dir, JobLogFile))
+		return string(log), err
+	}
+
+	log, err := run("0", "")
+	require.NoError(t, err)
+	assert.Contains(t, log, "args: --config /etc/srediag/srediag.yaml diagnose bundle --output json --output-file ")
+	assert.Contains(t, log, "--output-dir=")
+	assert.Contains(t, log, "--include-logs=false")
+
+	_, err = run("6", "")
+	assert.NoError(t, err, "findings above --fail-on are still a completed run")
+
+	_, err = run("3", "")
+	assert.ErrorIs(t, err, ErrSandbox)
+	assert.Equal(t, "sandbox", sandboxResource(err))
+
+	_, err = run("1", "")
+	assert.ErrorContains(t, err, "exiting 1")
+
+	e.limits.Timeout = 200 * time.Millisecond
+	_, err = run("0", "10")
+	assert.ErrorIs(t, err, ErrTimeout)
+}
+
+func TestNewRemoteJobRunner(t *testing.T) {
+	cfg := core.NewConfig()
+	r, err := NewRemoteJobRunner(core.NewTestLogger(&bytes.Buffer{}), cfg, core.NewRBAC(), "")
+	require.NoError(t, err)
+	assert.Nil(t, r)
+
+	cfg.Diagnostics.Remote.Enabled = true
+	cfg.Diagnostics.Remote.Source = filepath.Join(t.TempDir(), "jobs.json")
+	cfg.Diagnostics.Remote.Sink.URL = t.TempDir()
+	_, err = NewRemoteJobRunner(core.NewTestLogger(&bytes.Buffer{}), cfg, core.NewRBAC(), "")
+	assert.ErrorIs(t, err, ErrVerification, "a runner without trusted keys is refused")
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "ops.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+	cfg.Diagnostics.Remote.PublicKeys = []string{keyFile}
+	cfg.Diagnostics.Remote.PollInterval = "1m"
+	cfg.Security.Runtime.MemGuardMiB = 256
+	rbac := core.NewRBAC()
+	r, err = NewRemoteJobRunner(core.NewTestLogger(&bytes.Buffer{}), cfg, rbac, "")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, r.opts.PollInterval)
+	assert.Equal(t, DefaultJobMaxAge, r.opts.MaxAge)
+	assert.Equal(t, 256, r.executor.(*SandboxExecutor).limits.MemoryMiB)
+	assert.Contains(t, rbac.Capabilities(), CapabilityNetwork)
+
+	cfg.Diagnostics.Remote.MaxAge = "soon"
+	_, err = NewRemoteJobRunner(core.NewTestLogger(&bytes.Buffer{}), cfg, core.NewRBAC(), "")
+	assert.ErrorContains(t, err, "diagnostics.remote.max_age")
+}
@@ -0,0 +1,151 @@
+package diagnose
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file defines the ArtifactSinks that receive remote job results: a directory (path or file:// URL) or an HTTP
+// endpoint that accepts one PUT per file under <url>/<job id>/. Each upload ends with JobResultFile, so a control
+// plane can treat its arrival as "job complete" and annotate diag.result from it.
+//
+// Usage:
+//   - Instantiate with NewArtifactSink from diagnostics.remote.sink.
+//
+// Best Practices:
+//   - Use an HTTPS sink with an authorization header from the environment (${VAR} expansion) rather than a literal.
+
+// JobResultFile is the result document uploaded last for every job.
+const JobResultFile = "result.json"
+
+// ArtifactSink stores the artifacts and the result of remote jobs.
+type ArtifactSink interface {
+	// Upload stores every regular file of dir (dir may be empty for rejected jobs) followed by the result document.
+	Upload(ctx context.Context, result *JobResult, dir string) error
+}
+
+// NewArtifactSink creates a sink.
+//
+// Parameters:
+//   - spec: http(s):// URL, or a directory path (optionally file://).
+//   - headers: Extra request headers for HTTP sinks; values are expanded with os.ExpandEnv.
+//
+// Returns:
+//   - ArtifactSink: The sink.
+//   - error: If spec is empty, returns an error.
+func NewArtifactSink(spec string, headers map[string]string) (ArtifactSink, error) {
+	switch {
+	case spec == "":
+		return nil, errors.New("no artifact sink configured (diagnostics.remote.sink.url)")
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		h := make(map[string]string, len(headers))
+		for k, v := range headers {
+			h[k] = os.ExpandEnv(v)
+		}
+		return &httpSink{url: strings.TrimSuffix(spec, "/"), headers: h, client: &http.Client{Timeout: 5 * time.Minute}}, nil
+	default:
+		return &dirSink{dir: strings.TrimPrefix(spec, "file://")}, nil
+	}
+}
+
+// dirSink copies artifacts to <dir>/<job id>/.
+type dirSink struct {
+	dir string
+}
+
+// Upload implements ArtifactSink.
+func (s *dirSink) Upload(_ context.Context, result *JobResult, dir string) error {
+	dst := filepath.Join(s.dir, jobDirName(result.ID))
+	if err := os.MkdirAll(dst, 0o750); err != nil {
+		return fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	for _, name := range result.Artifacts {
+		if err := copyFile(filepath.Join(dir, name), filepath.Join(dst, name)); err != nil {
+			return fmt.Errorf("failed to store artifact %s: %w", name, err)
+		}
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dst, JobResultFile), data, 0o640)
+}
+
+// copyFile copies src to dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// httpSink PUTs each artifact to <url>/<job id>/<name>.
+type httpSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// Upload implements ArtifactSink.
+func (s *httpSink) Upload(ctx context.Context, result *JobResult, dir string) error {
+	for _, name := range result.Artifacts {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("failed to read artifact %s: %w", name, err)
+		}
+		err = s.put(ctx, result.ID, name, "application/octet-stream", f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return s.put(ctx, result.ID, JobResultFile, "application/json", strings.NewReader(string(data)))
+}
+
+// put uploads one file.
+func (s *httpSink) put(ctx context.Context, id, name, contentType string, body io.Reader) error {
+	target := s.url + "/" + url.PathEscape(id) + "/" + url.PathEscape(name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to upload %s: %s: %s", name, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
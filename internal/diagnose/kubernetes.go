@@ -0,0 +1,499 @@
+package diagnose
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file defines the KubernetesDiagnostics handler for cluster, node, and pod health (roadmap item D-02).
+//
+// Usage:
+//   - Build clients with NewKubernetesClients (kubeconfig/context) or inject fakes from client-go for tests.
+//   - Instantiate with NewKubernetesDiagnostics and call Run to produce a Report.
+//
+// Best Practices:
+//   - Scope runs to a namespace on large clusters to keep API load low.
+//   - Treat a missing metrics API as informational; usage checks are skipped, not failed.
+
+// memoryUsageWarnRatio is the usage/limit ratio above which a container is flagged as close to OOM.
+const memoryUsageWarnRatio = 0.9
+
+// cpuUsageWarnRatio is the usage/limit ratio above which a container is flagged as CPU-throttled.
+const cpuUsageWarnRatio = 0.9
+
+// KubernetesOptions configures a Kubernetes diagnostics run.
+//
+// Fields:
+//   - Namespace: Namespace to inspect; empty means all namespaces.
+//   - EventWindow: How far back to look for warning events.
+type KubernetesOptions struct {
+	Namespace   string
+	EventWindow time.Duration
+}
+
+// KubernetesDiagnostics handles Kubernetes cluster, node, and pod diagnostics.
+//
+// Usage:
+//   - Instantiate with NewKubernetesDiagnostics, providing a logger and clients.
+//   - Call Run to execute Kubernetes diagnostics.
+type KubernetesDiagnostics struct {
+	logger  *core.Logger
+	client  kubernetes.Interface
+	metrics metricsclient.Interface
+	opts    KubernetesOptions
+	now     func() time.Time
+}
+
+// NewKubernetesClients builds a core clientset and a metrics clientset from kubeconfig loading rules.
+//
+// Parameters:
+//   - kubeconfig: Explicit kubeconfig path; empty uses KUBECONFIG/~/.kube/config or in-cluster config.
+//   - kubeContext: Context name to use; empty uses the current context.
+//
+// Returns:
+//   - kubernetes.Interface: Core API clientset.
+//   - metricsclient.Interface: metrics.k8s.io clientset.
+//   - error: If the client configuration cannot be resolved, returns a detailed error.
+func NewKubernetesClients(kubeconfig, kubeContext string) (kubernetes.Interface, metricsclient.Interface, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfig != "" {
+		rules.ExplicitPath = kubeconfig
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	restCfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	metrics, err := metricsclient.NewForConfig(restCfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create metrics client: %w", err)
+	}
+	return client, metrics, nil
+}
+
+// NewKubernetesDiagnostics creates a new Kubernetes diagnostics handler.
+//
+// Parameters:
+//   - logger: Logger for status and error reporting.
+//   - client: Core API clientset (real or fake).
+//   - metrics: metrics.k8s.io clientset; may be nil to skip usage checks.
+//   - opts: Namespace and event window options.
+//
+// Returns:
+//   - *KubernetesDiagnostics: A new Kubernetes diagnostics handler.
+func NewKubernetesDiagnostics(logger *core.Logger, client kubernetes.Interface, metrics metricsclient.Interface, opts KubernetesOptions) *KubernetesDiagnostics {
+	if opts.EventWindow <= 0 {
+		opts.EventWindow = time.Hour
+	}
+	return &KubernetesDiagnostics{
+		logger:  logger,
+		client:  client,
+		metrics: metrics,
+		opts:    opts,
+		now:     time.Now,
+	}
+}
+
+// Run executes Kubernetes diagnostics.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts.
+//
+// Returns:
+//   - *Report: Findings for nodes, pods, containers, PVCs, and events.
+//   - error: If the API server cannot be queried, returns a detailed error.
+func (d *KubernetesDiagnostics) Run(ctx context.Context) (*Report, error) {
+	d.logger.Info("Running Kubernetes diagnostics", core.ZapString("namespace", d.opts.Namespace))
+	report := NewReport("kubernetes")
+
+	nodes, err := d.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	d.checkNodes(report, nodes.Items)
+
+	pods, err := d.client.CoreV1().Pods(d.opts.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	usage := d.podUsage(ctx, report)
+	d.checkPods(report, pods.Items, usage)
+
+	pvcs, err := d.client.CoreV1().PersistentVolumeClaims(d.opts.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list persistent volume claims: %w", err)
+	}
+	d.checkPVCs(report, pvcs.Items)
+
+	events, err := d.client.CoreV1().Events(d.opts.Namespace).List(ctx, metav1.ListOptions{FieldSelector: "type=" + corev1.EventTypeWarning})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+	d.checkEvents(report, events.Items)
+
+	report.SetMeasurement("k8s.nodes", float64(len(nodes.Items)))
+	report.SetMeasurement("k8s.pods", float64(len(pods.Items)))
+	report.Finish()
+	return report, nil
+}
+
+// checkNodes reports nodes that are not ready, under pressure, or cordoned.
+func (d *KubernetesDiagnostics) checkNodes(report *Report, nodes []corev1.Node) {
+	notReady := 0
+	for _, node := range nodes {
+		resource := "node/" + node.Name
+		for _, cond := range node.Status.Conditions {
+			switch cond.Type {
+			case corev1.NodeReady:
+				if cond.Status != corev1.ConditionTrue {
+					notReady++
+					report.Add(Finding{
+						Check:    "k8s.node.ready",
+						Severity: SeverityCritical,
+						Resource: resource,
+						Message:  fmt.Sprintf("node is not ready: %s", conditionText(cond.Reason, cond.Message)),
+						Details:  map[string]string{"reason": cond.Reason, "status": string(cond.Status)},
+					})
+				}
+			case corev1.NodeMemoryPressure, corev1.NodeDiskPressure, corev1.NodePIDPressure:
+				if cond.Status == corev1.ConditionTrue {
+					report.Add(Finding{
+						Check:    "k8s.node.pressure",
+						Severity: SeverityWarning,
+						Resource: resource,
+						Message:  fmt.Sprintf("%s: %s", cond.Type, conditionText(cond.Reason, cond.Message)),
+						Details:  map[string]string{"condition": string(cond.Type), "reason": cond.Reason},
+					})
+				}
+			case corev1.NodeNetworkUnavailable:
+				if cond.Status == corev1.ConditionTrue {
+					report.Add(Finding{
+						Check:    "k8s.node.network",
+						Severity: SeverityCritical,
+						Resource: resource,
+						Message:  fmt.Sprintf("node network unavailable: %s", conditionText(cond.Reason, cond.Message)),
+						Details:  map[string]string{"reason": cond.Reason},
+					})
+				}
+			}
+		}
+		if node.Spec.Unschedulable {
+			report.Add(Finding{
+				Check:    "k8s.node.cordoned",
+				Severity: SeverityInfo,
+				Resource: resource,
+				Message:  "node is cordoned (unschedulable)",
+			})
+		}
+	}
+	report.SetMeasurement("k8s.nodes.not_ready", float64(notReady))
+}
+
+// checkPods reports pending pods, crash-looping or OOM-killed containers, failing readiness, and resource issues.
+func (d *KubernetesDiagnostics) checkPods(report *Report, pods []corev1.Pod, usage map[string]map[string]corev1.ResourceList) {
+	for _, pod := range pods {
+		resource := "pod/" + pod.Namespace + "/" + pod.Name
+		if pod.Status.Phase == corev1.PodPending {
+			reason, msg := pendingReason(pod)
+			report.Add(Finding{
+				Check:    "k8s.pod.pending",
+				Severity: SeverityWarning,
+				Resource: resource,
+				Message:  fmt.Sprintf("pod is pending: %s", conditionText(reason, msg)),
+				Details:  map[string]string{"reason": reason},
+			})
+		}
+
+		specs := make(map[string]corev1.Container, len(pod.Spec.Containers))
+		for _, c := range pod.Spec.Containers {
+			specs[c.Name] = c
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			cResource := resource + "/" + cs.Name
+			if w := cs.State.Waiting; w != nil && w.Reason == "CrashLoopBackOff" {
+				details := map[string]string{"restarts": fmt.Sprint(cs.RestartCount)}
+				if t := cs.LastTerminationState.Terminated; t != nil {
+					details["last_reason"] = t.Reason
+					details["last_exit_code"] = fmt.Sprint(t.ExitCode)
+				}
+				report.Add(Finding{
+					Check:    "k8s.container.crashloop",
+					Severity: SeverityCritical,
+					Resource: cResource,
+					Message:  fmt.Sprintf("container is in CrashLoopBackOff (%d restarts)", cs.RestartCount),
+					Details:  details,
+				})
+			}
+			if isOOMKilled(cs) {
+				report.Add(Finding{
+					Check:    "k8s.container.oomkilled",
+					Severity: SeverityCritical,
+					Resource: cResource,
+					Message:  "container was OOMKilled; memory limit is too low or the workload leaks",
+					Details:  map[string]string{"restarts": fmt.Sprint(cs.RestartCount)},
+				})
+			}
+			spec, ok := specs[cs.Name]
+			if ok && cs.State.Running != nil && !cs.Ready && spec.ReadinessProbe != nil {
+				report.Add(Finding{
+					Check:    "k8s.container.probe",
+					Severity: SeverityWarning,
+					Resource: cResource,
+					Message:  "container is running but its readiness probe is failing",
+					Details:  map[string]string{"probe": "readiness"},
+				})
+			}
+		}
+
+		for _, c := range pod.Spec.Containers {
+			d.checkContainerResources(report, resource+"/"+c.Name, c, usage[pod.Namespace+"/"+pod.Name][c.Name])
+		}
+	}
+}
+
+// checkContainerResources compares requests, limits, and (when available) actual usage for one container.
+func (d *KubernetesDiagnostics) checkContainerResources(report *Report, resource string, c corev1.Container, used corev1.ResourceList) {
+	req, lim := c.Resources.Requests, c.Resources.Limits
+	if len(req) == 0 && len(lim) == 0 {
+		report.Add(Finding{
+			Check:    "k8s.container.resources",
+			Severity: SeverityWarning,
+			Resource: resource,
+			Message:  "container has no resource requests or limits (BestEffort)",
+		})
+		return
+	}
+	if _, ok := lim[corev1.ResourceMemory]; !ok {
+		report.Add(Finding{
+			Check:    "k8s.container.resources",
+			Severity: SeverityWarning,
+			Resource: resource,
+			Message:  "container has no memory limit",
+		})
+	}
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		r, hasReq := req[name]
+		l, hasLim := lim[name]
+		if hasReq && hasLim && r.Cmp(l) > 0 {
+			report.Add(Finding{
+				Check:    "k8s.container.resources",
+				Severity: SeverityWarning,
+				Resource: resource,
+				Message:  fmt.Sprintf("%s request %s exceeds limit %s", name, r.String(), l.String()),
+			})
+		}
+	}
+	if used == nil {
+		return
+	}
+	if l, ok := lim[corev1.ResourceMemory]; ok && l.Value() > 0 {
+		u := used[corev1.ResourceMemory]
+		if ratio := float64(u.Value()) / float64(l.Value()); ratio >= memoryUsageWarnRatio {
+			report.Add(Finding{
+				Check:    "k8s.container.usage",
+				Severity: SeverityWarning,
+				Resource: resource,
+				Message:  fmt.Sprintf("memory usage %s is %.0f%% of limit %s", u.String(), ratio*100, l.String()),
+				Details:  map[string]string{"resource": "memory"},
+			})
+		}
+	}
+	if l, ok := lim[corev1.ResourceCPU]; ok && l.MilliValue() > 0 {
+		u := used[corev1.ResourceCPU]
+		if ratio := float64(u.MilliValue()) / float64(l.MilliValue()); ratio >= cpuUsageWarnRatio {
+			report.Add(Finding{
+				Check:    "k8s.container.usage",
+				Severity: SeverityWarning,
+				Resource: resource,
+				Message:  fmt.Sprintf("CPU usage %s is %.0f%% of limit %s (likely throttled)", u.String(), ratio*100, l.String()),
+				Details:  map[string]string{"resource": "cpu"},
+			})
+		}
+	}
+	if r, ok := req[corev1.ResourceMemory]; ok && r.Value() > 0 {
+		u := used[corev1.ResourceMemory]
+		if u.Cmp(r) > 0 {
+			report.Add(Finding{
+				Check:    "k8s.container.usage",
+				Severity: SeverityInfo,
+				Resource: resource,
+				Message:  fmt.Sprintf("memory usage %s exceeds request %s; pod is at risk under node pressure", u.String(), r.String()),
+				Details:  map[string]string{"resource": "memory"},
+			})
+		}
+	}
+}
+
+// checkPVCs reports persistent volume claims that are not bound.
+func (d *KubernetesDiagnostics) checkPVCs(report *Report, pvcs []corev1.PersistentVolumeClaim) {
+	for _, pvc := range pvcs {
+		if pvc.Status.Phase == corev1.ClaimBound {
+			continue
+		}
+		sev := SeverityWarning
+		if pvc.Status.Phase == corev1.ClaimLost {
+			sev = SeverityCritical
+		}
+		report.Add(Finding{
+			Check:    "k8s.pvc.unbound",
+			Severity: sev,
+			Resource: "pvc/" + pvc.Namespace + "/" + pvc.Name,
+			Message:  fmt.Sprintf("persistent volume claim is %s", pvc.Status.Phase),
+			Details:  map[string]string{"phase": string(pvc.Status.Phase)},
+		})
+	}
+}
+
+// checkEvents reports recent warning events; probe failures ("Unhealthy") are reported as probe findings.
+func (d *KubernetesDiagnostics) checkEvents(report *Report, events []corev1.Event) {
+	cutoff := d.now().Add(-d.opts.EventWindow)
+	for _, ev := range events {
+		if ev.Type != corev1.EventTypeWarning {
+			continue
+		}
+		ts := eventTime(ev)
+		if ts.Before(cutoff) {
+			continue
+		}
+		obj := ev.InvolvedObject
+		resource := fmt.Sprintf("%s/%s/%s", lowerKind(obj.Kind), obj.Namespace, obj.Name)
+		details := map[string]string{
+			"reason":    ev.Reason,
+			"count":     fmt.Sprint(eventCount(ev)),
+			"last_seen": ts.UTC().Format(time.RFC3339),
+		}
+		check := "k8s.event.warning"
+		if ev.Reason == "Unhealthy" {
+			check = "k8s.container.probe"
+		}
+		report.Add(Finding{
+			Check:    check,
+			Severity: SeverityWarning,
+			Resource: resource,
+			Message:  fmt.Sprintf("%s: %s", ev.Reason, ev.Message),
+			Details:  details,
+		})
+	}
+}
+
+// podUsage fetches per-container usage from metrics.k8s.io keyed by "namespace/pod" then container name.
+// A missing metrics API is reported as an informational finding and yields nil.
+func (d *KubernetesDiagnostics) podUsage(ctx context.Context, report *Report) map[string]map[string]corev1.ResourceList {
+	if d.metrics == nil {
+		return nil
+	}
+	list, err := d.metrics.MetricsV1beta1().PodMetricses(d.opts.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		d.logger.Debug("Pod metrics unavailable", core.ZapError(err))
+		report.Add(Finding{
+			Check:    "k8s.metrics.available",
+			Severity: SeverityInfo,
+			Message:  "metrics.k8s.io is unavailable; usage checks were skipped",
+		})
+		return nil
+	}
+	return usageByContainer(list.Items)
+}
+
+// usageByContainer indexes pod metrics by "namespace/pod" and container name.
+func usageByContainer(items []metricsv1beta1.PodMetrics) map[string]map[string]corev1.ResourceList {
+	out := make(map[string]map[string]corev1.ResourceList, len(items))
+	for _, pm := range items {
+		containers := make(map[string]corev1.ResourceList, len(pm.Containers))
+		for _, c := range pm.Containers {
+			containers[c.Name] = c.Usage
+		}
+		out[pm.Namespace+"/"+pm.Name] = containers
+	}
+	return out
+}
+
+// pendingReason extracts the most useful reason for a pending pod.
+func pendingReason(pod corev1.Pod) (string, string) {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse {
+			return cond.Reason, cond.Message
+		}
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if w := cs.State.Waiting; w != nil && w.Reason != "" {
+			return w.Reason, w.Message
+		}
+	}
+	return pod.Status.Reason, pod.Status.Message
+}
+
+// isOOMKilled reports whether the container's current or last termination was an OOM kill.
+func isOOMKilled(cs corev1.ContainerStatus) bool {
+	if t := cs.State.Terminated; t != nil && t.Reason == "OOMKilled" {
+		return true
+	}
+	if t := cs.LastTerminationState.Terminated; t != nil && t.Reason == "OOMKilled" {
+		return true
+	}
+	return false
+}
+
+// eventTime returns the most recent timestamp recorded on an event.
+func eventTime(ev corev1.Event) time.Time {
+	switch {
+	case ev.Series != nil && !ev.Series.LastObservedTime.IsZero():
+		return ev.Series.LastObservedTime.Time
+	case !ev.LastTimestamp.IsZero():
+		return ev.LastTimestamp.Time
+	case !ev.EventTime.IsZero():
+		return ev.EventTime.Time
+	default:
+		return ev.CreationTimestamp.Time
+	}
+}
+
+// eventCount returns how many times an event was observed.
+func eventCount(ev corev1.Event) int32 {
+	if ev.Series != nil && ev.Series.Count > 0 {
+		return ev.Series.Count
+	}
+	if ev.Count > 0 {
+		return ev.Count
+	}
+	return 1
+}
+
+// conditionText joins a reason and message for display, tolerating empty parts.
+func conditionText(reason, message string) string {
+	switch {
+	case reason != "" && message != "":
+		return reason + " (" + message + ")"
+	case reason != "":
+		return reason
+	case message != "":
+		return message
+	default:
+		return "no reason reported"
+	}
+}
+
+// lowerKind returns a lower-cased Kubernetes kind for resource identifiers.
+func lowerKind(kind string) string {
+	if kind == "" {
+		return "object"
+	}
+	return strings.ToLower(kind)
+}
@@ -0,0 +1,176 @@
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+func findingsByCheck(r *Report, check string) []Finding {
+	var out []Finding
+	for _, f := range r.Findings {
+		if f.Check == check {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func limitedContainer(name, cpu, mem string) corev1.Container {
+	return corev1.Container{
+		Name: name,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu), corev1.ResourceMemory: resource.MustParse(mem)},
+			Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu), corev1.ResourceMemory: resource.MustParse(mem)},
+		},
+	}
+}
+
+func TestKubernetesDiagnostics_Run(t *testing.T) {
+	now := time.Now()
+	client := fake.NewSimpleClientset(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-1"},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionFalse, Reason: "KubeletNotReady"},
+				{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionTrue, Reason: "KubeletHasInsufficientMemory"},
+			}},
+		},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-2"},
+			Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-0", Namespace: "prod"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{limitedContainer("api", "500m", "256Mi")}},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:                 "api",
+					RestartCount:         7,
+					State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+					LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}},
+				}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "batch-0", Namespace: "prod"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "job"}}},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodPending,
+				Conditions: []corev1.PodCondition{{
+					Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: "Unschedulable", Message: "0/2 nodes are available",
+				}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "prod"},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{func() corev1.Container {
+				c := limitedContainer("web", "100m", "100Mi")
+				c.ReadinessProbe = &corev1.Probe{}
+				return c
+			}()}},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  "web",
+					Ready: false,
+					State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+				}},
+			},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "prod"},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+		},
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "ev-1", Namespace: "prod"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "prod", Name: "web-0"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "Unhealthy",
+			Message:        "Readiness probe failed: HTTP probe failed with statuscode: 503",
+			Count:          12,
+			LastTimestamp:  metav1.NewTime(now.Add(-5 * time.Minute)),
+		},
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "ev-2", Namespace: "prod"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "prod", Name: "api-0"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "BackOff",
+			Message:        "Back-off restarting failed container",
+			LastTimestamp:  metav1.NewTime(now.Add(-3 * time.Hour)),
+		},
+	)
+	// The generated metrics fake lists PodMetrics under the "pods" resource, so seed the tracker directly.
+	metrics := metricsfake.NewSimpleClientset()
+	require.NoError(t, metrics.Tracker().Create(metricsv1beta1.SchemeGroupVersion.WithResource("pods"), &metricsv1beta1.PodMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "prod"},
+		Containers: []metricsv1beta1.ContainerMetrics{{
+			Name:  "web",
+			Usage: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("20m"), corev1.ResourceMemory: resource.MustParse("98Mi")},
+		}},
+	}, "prod"))
+
+	d := NewKubernetesDiagnostics(core.NewTestLogger(&bytes.Buffer{}), client, metrics, KubernetesOptions{EventWindow: time.Hour})
+	report, err := d.Run(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "kubernetes", report.Diagnostic)
+	assert.Equal(t, SeverityCritical, report.MaxSeverity())
+	assert.Len(t, findingsByCheck(report, "k8s.node.ready"), 1)
+	assert.Len(t, findingsByCheck(report, "k8s.node.pressure"), 1)
+	assert.Len(t, findingsByCheck(report, "k8s.container.crashloop"), 1)
+	assert.Len(t, findingsByCheck(report, "k8s.container.oomkilled"), 1)
+	pending := findingsByCheck(report, "k8s.pod.pending")
+	require.Len(t, pending, 1)
+	assert.Equal(t, "Unschedulable", pending[0].Details["reason"])
+	// Running-but-unready container plus the Unhealthy event.
+	assert.Len(t, findingsByCheck(report, "k8s.container.probe"), 2)
+	assert.Len(t, findingsByCheck(report, "k8s.pvc.unbound"), 1)
+	// The BackOff event is outside the one-hour window.
+	assert.Empty(t, findingsByCheck(report, "k8s.event.warning"))
+
+	usage := findingsByCheck(report, "k8s.container.usage")
+	require.NotEmpty(t, usage)
+	assert.Equal(t, "pod/prod/web-0/web", usage[0].Resource)
+
+	resources := findingsByCheck(report, "k8s.container.resources")
+	require.Len(t, resources, 1)
+	assert.Equal(t, "pod/prod/batch-0/job", resources[0].Resource)
+
+	assert.Equal(t, float64(1), report.Measurements["k8s.nodes.not_ready"])
+}
+
+func TestKubernetesDiagnostics_NoMetricsAPI(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	d := NewKubernetesDiagnostics(core.NewTestLogger(&bytes.Buffer{}), client, nil, KubernetesOptions{})
+	report, err := d.Run(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, report.Findings)
+}
+
+func TestWriteReport_Formats(t *testing.T) {
+	r := NewReport("test")
+	r.Add(Finding{Check: "a.b", Severity: SeverityWarning, Resource: "x", Message: "hello"})
+	r.SetMeasurement("m", 1.5)
+	r.Finish()
+
+	for _, format := range []string{"table", "json", "yaml"} {
+		var buf bytes.Buffer
+		require.NoError(t, WriteReport(&buf, r, format), format)
+		assert.Contains(t, buf.String(), "hello", format)
+	}
+	assert.Error(t, WriteReport(&bytes.Buffer{}, r, "xml"))
+}
@@ -0,0 +1,633 @@
+package diagnose
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file defines the LogDiagnostics handler ('srediag diagnose logs'): it scans plain log files and journald
+// export streams ('journalctl -o export'), clusters error messages into signatures (variable tokens such as
+// numbers, addresses and IDs are normalised, then similar messages are merged token by token) and detects known
+// failure patterns (OOM killer, full disks, segfaults, TLS handshake failures, connection refused storms). Every
+// signature and pattern is reported with its count, first and last occurrence and per-window frequencies.
+//
+// Usage:
+//   - Instantiate with NewLogDiagnostics, providing a logger and LogOptions (usually diagnostics.logs).
+//   - Call Run to scan the sources and produce a Report.
+//
+// Best Practices:
+//   - Bound the scan with Since on large hosts; rotated .gz files are read transparently.
+//   - Extend DefaultLogPatterns through diagnostics.logs.patterns; a pattern with a built-in name replaces it.
+
+// Defaults of log analysis.
+const (
+	DefaultLogWindow        = time.Hour
+	DefaultLogTopSignatures = 20
+
+	// logSimilarity is the minimum fraction of equal tokens for a message to join a signature.
+	logSimilarity = 0.7
+	// logWildcard replaces the tokens that differ between messages of one signature.
+	logWildcard = "<*>"
+	// logSampleLen bounds the sample message kept per signature and pattern.
+	logSampleLen = 300
+	// logMaxLine bounds a single log line or journal field.
+	logMaxLine = 1 << 20
+	// logMaxWindows bounds the per-window frequencies rendered into finding details (latest windows are kept).
+	logMaxWindows = 48
+)
+
+// DefaultLogFiles are scanned when no files or journal exports are configured; missing ones are skipped.
+var DefaultLogFiles = []string{"/var/log/syslog", "/var/log/messages", "/var/log/kern.log"}
+
+// LogPattern is a known failure pattern matched against every log message.
+//
+// Fields:
+//   - Name: Pattern identifier; the finding check is "logs.pattern.<name>".
+//   - Match: Regular expression matched against the message.
+//   - Severity: Severity of the finding.
+//   - Threshold: Minimum occurrences within one window before the pattern is reported (0 reports any occurrence).
+//   - Description: What a match means, used in the finding message.
+type LogPattern struct {
+	Name        string
+	Match       string
+	Severity    Severity
+	Threshold   int
+	Description string
+}
+
+// DefaultLogPatterns are the built-in failure patterns.
+var DefaultLogPatterns = []LogPattern{
+	{Name: "oom-killer", Match: `(?i)out of memory: kill|invoked oom-killer|oom-kill:|memory cgroup out of memory`,
+		Severity: SeverityCritical, Description: "the OOM killer terminated processes"},
+	{Name: "disk-full", Match: `(?i)no space left on device|\bENOSPC\b|(disk|file ?system) (is )?full`,
+		Severity: SeverityCritical, Description: "writes failed on a full filesystem"},
+	{Name: "segfault", Match: `(?i)segfault at|segmentation fault|general protection fault|\bSIGSEGV\b`,
+		Severity: SeverityCritical, Description: "processes crashed"},
+	{Name: "tls-handshake", Match: `(?i)tls handshake (error|fail|timeout)|handshake failure|ssl_do_handshake|x509: certificate`,
+		Severity: SeverityWarning, Description: "TLS handshakes failed"},
+	{Name: "connection-refused", Match: `(?i)connection refused|\bECONNREFUSED\b`,
+		Severity: SeverityWarning, Threshold: 10, Description: "connections were refused repeatedly"},
+}
+
+// MergeLogPatterns returns the defaults followed by extra patterns; an extra pattern with a default's name replaces it.
+//
+// Parameters:
+//   - defaults: Built-in patterns.
+//   - extra: Configured patterns.
+//
+// Returns:
+//   - []LogPattern: The merged patterns.
+func MergeLogPatterns(defaults, extra []LogPattern) []LogPattern {
+	out := append([]LogPattern(nil), defaults...)
+	for _, p := range extra {
+		replaced := false
+		for i := range out {
+			if out[i].Name == p.Name {
+				out[i], replaced = p, true
+			}
+		}
+		if !replaced {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// LogOptions configures a log analysis run.
+//
+// Fields:
+//   - Files: Plain log files or glob patterns; "-" reads stdin and ".gz" files are decompressed.
+//   - Journal: journald export files ('journalctl -o export'); "-" reads stdin.
+//   - Since: Only entries newer than this are analysed; zero analyses everything. Undated entries are always kept.
+//   - Window: Width of the frequency windows; defaults to DefaultLogWindow.
+//   - Top: Number of error signatures reported; defaults to DefaultLogTopSignatures.
+//   - Patterns: Failure patterns; nil uses DefaultLogPatterns.
+type LogOptions struct {
+	Files    []string
+	Journal  []string
+	Since    time.Duration
+	Window   time.Duration
+	Top      int
+	Patterns []LogPattern
+}
+
+// LogDiagnostics handles log analysis diagnostics.
+//
+// Usage:
+//   - Instantiate with NewLogDiagnostics, providing a logger and options.
+//   - Call Run to execute log analysis.
+type LogDiagnostics struct {
+	logger *core.Logger
+	opts   LogOptions
+	now    func() time.Time
+}
+
+// NewLogDiagnostics creates a new log diagnostics handler.
+//
+// Parameters:
+//   - logger: Logger for status and error reporting.
+//   - opts: Sources, time range and patterns.
+//
+// Returns:
+//   - *LogDiagnostics: A new log diagnostics handler.
+func NewLogDiagnostics(logger *core.Logger, opts LogOptions) *LogDiagnostics {
+	if opts.Window <= 0 {
+		opts.Window = DefaultLogWindow
+	}
+	if opts.Top <= 0 {
+		opts.Top = DefaultLogTopSignatures
+	}
+	if opts.Patterns == nil {
+		opts.Patterns = DefaultLogPatterns
+	}
+	return &LogDiagnostics{logger: logger, opts: opts, now: time.Now}
+}
+
+// logStdin is read for the "-" source; patchable in tests.
+var logStdin io.Reader = os.Stdin
+
+// logEntry is one parsed log record.
+type logEntry struct {
+	time    time.Time
+	source  string
+	message string
+	error   bool
+}
+
+// compiledLogPattern is a LogPattern with its expression compiled and its occurrences.
+type compiledLogPattern struct {
+	LogPattern
+	re    *regexp.Regexp
+	stats logStats
+}
+
+// Run executes log analysis.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts.
+//
+// Returns:
+//   - *Report: Pattern and signature findings with counts, occurrences and windows.
+//   - error: If a pattern is invalid, no source can be read, or the run is cancelled, returns a detailed error.
+func (d *LogDiagnostics) Run(ctx context.Context) (*Report, error) {
+	patterns := make([]*compiledLogPattern, 0, len(d.opts.Patterns))
+	for _, p := range d.opts.Patterns {
+		if p.Match == "" {
+			return nil, fmt.Errorf("invalid log pattern %q: empty match", p.Name)
+		}
+		re, err := regexp.Compile(p.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log pattern %q: %w", p.Name, err)
+		}
+		patterns = append(patterns, &compiledLogPattern{LogPattern: p, re: re})
+	}
+	d.logger.Info("Running log analysis", core.ZapInt("files", len(d.opts.Files)), core.ZapInt("journal", len(d.opts.Journal)))
+	report := NewReport("logs")
+
+	var cutoff time.Time
+	if d.opts.Since > 0 {
+		cutoff = d.now().Add(-d.opts.Since)
+	}
+	clusters := &logClusterer{}
+	entries, errEntries := 0, 0
+	emit := func(e logEntry) {
+		if !e.time.IsZero() && e.time.Before(cutoff) {
+			return
+		}
+		entries++
+		for _, p := range patterns {
+			if p.re.MatchString(e.message) {
+				p.stats.observe(e, d.opts.Window)
+			}
+		}
+		if e.error {
+			errEntries++
+			clusters.add(e, d.opts.Window)
+		}
+	}
+
+	type source struct {
+		path    string
+		journal bool
+	}
+	var sources []source
+	files, journal, optional := d.opts.Files, d.opts.Journal, false
+	if len(files) == 0 && len(journal) == 0 {
+		files, optional = DefaultLogFiles, true
+	}
+	for _, p := range expandLogSources(files) {
+		sources = append(sources, source{p, false})
+	}
+	for _, p := range expandLogSources(journal) {
+		sources = append(sources, source{p, true})
+	}
+	read := 0
+	for _, s := range sources {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		err := d.readSource(s.path, s.journal, emit)
+		switch {
+		case err == nil:
+			read++
+		case optional && errors.Is(err, os.ErrNotExist):
+		default:
+			d.logger.Warn("Failed to read log source", core.ZapString("source", s.path), core.ZapError(err))
+			report.Add(Finding{Check: "logs.source", Severity: SeverityWarning, Resource: "file/" + s.path,
+				Message: fmt.Sprintf("cannot read %s: %v", s.path, err)})
+		}
+	}
+	if read == 0 {
+		return nil, fmt.Errorf("no readable log sources: %w", ErrNotFound)
+	}
+
+	for _, p := range patterns {
+		report.SetMeasurement("logs.pattern."+p.Name, float64(p.stats.count))
+		_, peak := p.stats.peak()
+		if p.stats.count == 0 || peak < p.Threshold {
+			continue
+		}
+		desc := p.Description
+		if desc == "" {
+			desc = "pattern " + p.Name + " matched"
+		}
+		report.Add(Finding{Check: "logs.pattern." + p.Name, Severity: p.Severity, Resource: "logs",
+			Message: fmt.Sprintf("%s: %d occurrence(s) %s", desc, p.stats.count, p.stats.span()),
+			Details: p.stats.details()})
+	}
+
+	sigs := clusters.all
+	sort.SliceStable(sigs, func(i, j int) bool { return sigs[i].stats.count > sigs[j].stats.count })
+	for i, c := range sigs {
+		if i == d.opts.Top {
+			break
+		}
+		signature := strings.Join(c.tokens, " ")
+		sum := sha256.Sum256([]byte(signature))
+		details := c.stats.details()
+		details["signature"] = signature
+		details["sources"] = strings.Join(sortedKeys(c.sources), ",")
+		report.Add(Finding{Check: "logs.signature", Severity: SeverityInfo, Resource: "signature/" + hex.EncodeToString(sum[:4]),
+			Message: fmt.Sprintf("%s (%d occurrence(s))", signature, c.stats.count), Details: details})
+	}
+	report.SetMeasurement("logs.sources", float64(read))
+	report.SetMeasurement("logs.entries", float64(entries))
+	report.SetMeasurement("logs.error_entries", float64(errEntries))
+	report.SetMeasurement("logs.signatures", float64(len(sigs)))
+	report.Finish()
+	return report, nil
+}
+
+// expandLogSources expands glob patterns; a pattern matching nothing is kept so that the failure is reported.
+func expandLogSources(patterns []string) []string {
+	var out []string
+	for _, p := range patterns {
+		matches, err := filepath.Glob(p)
+		if p == "-" || err != nil || len(matches) == 0 {
+			out = append(out, p)
+			continue
+		}
+		out = append(out, matches...)
+	}
+	return out
+}
+
+// readSource parses one log file or journal export and emits its entries.
+func (d *LogDiagnostics) readSource(path string, journal bool, emit func(logEntry)) error {
+	var r io.Reader = logStdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+		if strings.HasSuffix(path, ".gz") {
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				return err
+			}
+			defer gz.Close()
+			r = gz
+		}
+	}
+	if journal {
+		return readJournalExport(r, emit)
+	}
+	return readPlainLog(r, path, d.now(), emit)
+}
+
+// errorWordRe flags plain log lines that report an error.
+var errorWordRe = regexp.MustCompile(`(?i)\b(err(or)?|fatal|panic|crit(ical)?|emerg(ency)?|alert|fail(ed|ure|ing)?|exception|denied|refused|timed? ?out|unable|cannot|segfault)\b`)
+
+// syslogPrefixRe matches the "host program[pid]: " prefix that follows a syslog timestamp.
+var syslogPrefixRe = regexp.MustCompile(`^\S+ (\S+?)(\[\d+\])?: `)
+
+// readPlainLog parses a plain text log; now anchors the year of syslog timestamps.
+func readPlainLog(r io.Reader, source string, now time.Time, emit func(logEntry)) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), logMaxLine)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		t, msg, syslog := parseLogTime(line, now)
+		src := source
+		if syslog {
+			// Drop the host name; keep the program (and pid, normalised later) in the message.
+			if m := syslogPrefixRe.FindStringSubmatch(msg); m != nil {
+				src = m[1]
+				_, msg, _ = strings.Cut(msg, " ")
+			}
+		}
+		emit(logEntry{time: t, source: src, message: msg, error: errorWordRe.MatchString(msg)})
+	}
+	return sc.Err()
+}
+
+// parseLogTime extracts a leading RFC 3339, "2006-01-02 15:04:05" or syslog ("Jan _2 15:04:05") timestamp.
+// It returns the time (zero when absent), the rest of the line and whether the line is in syslog format.
+func parseLogTime(line string, now time.Time) (time.Time, string, bool) {
+	first, rest, _ := strings.Cut(line, " ")
+	if t, err := time.Parse(time.RFC3339Nano, first); err == nil {
+		return t, strings.TrimSpace(rest), false
+	}
+	if len(line) >= 19 {
+		stamp := strings.Replace(line[:19], "T", " ", 1)
+		if t, err := time.ParseInLocation("2006-01-02 15:04:05", stamp, time.Local); err == nil {
+			rest := line[19:]
+			// Skip fractional seconds (",123" or ".123").
+			if len(rest) > 0 && (rest[0] == ',' || rest[0] == '.') {
+				i := 1
+				for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+					i++
+				}
+				rest = rest[i:]
+			}
+			return t, strings.TrimSpace(rest), false
+		}
+	}
+	if len(line) >= 15 {
+		if t, err := time.ParseInLocation(time.Stamp, line[:15], time.Local); err == nil {
+			t = t.AddDate(now.Year(), 0, 0)
+			if t.After(now.Add(24 * time.Hour)) {
+				t = t.AddDate(-1, 0, 0) // last year's entries in a file spanning New Year
+			}
+			return t, strings.TrimSpace(line[15:]), true
+		}
+	}
+	return time.Time{}, line, false
+}
+
+// readJournalExport parses the journal export format: "FIELD=value" lines, binary fields as a name line followed
+// by a little-endian uint64 length, the data and a newline, and entries separated by an empty line.
+func readJournalExport(r io.Reader, emit func(logEntry)) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+	fields := map[string]string{}
+	flush := func() {
+		if msg := fields["MESSAGE"]; msg != "" {
+			e := logEntry{message: msg, source: fields["_SYSTEMD_UNIT"]}
+			if e.source == "" {
+				e.source = fields["SYSLOG_IDENTIFIER"]
+			}
+			if us, err := strconv.ParseInt(fields["__REALTIME_TIMESTAMP"], 10, 64); err == nil {
+				e.time = time.UnixMicro(us).UTC()
+			}
+			if prio, err := strconv.Atoi(fields["PRIORITY"]); err == nil {
+				e.error = prio <= 3 // emerg, alert, crit, err
+			} else {
+				e.error = errorWordRe.MatchString(msg)
+			}
+			emit(e)
+		}
+		fields = map[string]string{}
+	}
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && line == "" {
+			if errors.Is(err, io.EOF) {
+				flush()
+				return nil
+			}
+			return err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			flush()
+			continue
+		}
+		if name, value, ok := strings.Cut(line, "="); ok {
+			fields[name] = value
+			continue
+		}
+		var size uint64
+		if err := binary.Read(br, binary.LittleEndian, &size); err != nil {
+			return fmt.Errorf("truncated journal field %s: %w", line, err)
+		}
+		if size > logMaxLine {
+			return fmt.Errorf("journal field %s too large (%d bytes)", line, size)
+		}
+		data := make([]byte, size+1) // data plus the trailing newline
+		if _, err := io.ReadFull(br, data); err != nil {
+			return fmt.Errorf("truncated journal field %s: %w", line, err)
+		}
+		fields[line] = string(data[:size])
+	}
+}
+
+// logNormalizers replace the variable parts of a message, in order.
+var logNormalizers = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "<uuid>"},
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`), "<ip>"},
+	{regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b`), "<hex>"},
+	{regexp.MustCompile(`(?i)\b(?:[0-9a-f]*\d[0-9a-f]*[a-f]|[0-9a-f]*[a-f][0-9a-f]*\d)[0-9a-f]*\b`), "<hex>"},
+	{regexp.MustCompile(`\b\d+(\.\d+)?([a-zA-Z]{1,3})?\b`), "<n>"},
+}
+
+// normalizeLogMessage replaces variable tokens with placeholders and splits the message into tokens.
+func normalizeLogMessage(msg string) []string {
+	for _, n := range logNormalizers {
+		msg = n.re.ReplaceAllString(msg, n.repl)
+	}
+	return strings.Fields(msg)
+}
+
+// logStats tracks the occurrences of a signature or pattern.
+type logStats struct {
+	count       int
+	first, last time.Time
+	sample      string
+	windows     map[time.Time]int
+}
+
+// observe records one occurrence in its window.
+func (s *logStats) observe(e logEntry, window time.Duration) {
+	s.count++
+	if s.sample == "" {
+		s.sample = e.message
+		if len(s.sample) > logSampleLen {
+			s.sample = s.sample[:logSampleLen] + "…"
+		}
+	}
+	if s.windows == nil {
+		s.windows = map[time.Time]int{}
+	}
+	if e.time.IsZero() {
+		s.windows[time.Time{}]++
+		return
+	}
+	s.windows[e.time.Truncate(window)]++
+	if s.first.IsZero() || e.time.Before(s.first) {
+		s.first = e.time
+	}
+	if e.time.After(s.last) {
+		s.last = e.time
+	}
+}
+
+// peak returns the busiest window and its count; undated occurrences count as one window.
+func (s *logStats) peak() (time.Time, int) {
+	var at time.Time
+	n := 0
+	for w, c := range s.windows {
+		if c > n || (c == n && w.Before(at)) {
+			at, n = w, c
+		}
+	}
+	return at, n
+}
+
+// span describes the first and last occurrence for finding messages.
+func (s *logStats) span() string {
+	if s.first.IsZero() {
+		return "(undated)"
+	}
+	return fmt.Sprintf("between %s and %s", s.first.UTC().Format(time.RFC3339), s.last.UTC().Format(time.RFC3339))
+}
+
+// details renders the statistics as finding details; windows are "<start>=<count>" pairs, oldest first.
+func (s *logStats) details() map[string]string {
+	keys := make([]time.Time, 0, len(s.windows))
+	for w := range s.windows {
+		keys = append(keys, w)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Before(keys[j]) })
+	if len(keys) > logMaxWindows {
+		keys = keys[len(keys)-logMaxWindows:]
+	}
+	windows := make([]string, len(keys))
+	for i, w := range keys {
+		label := "undated"
+		if !w.IsZero() {
+			label = w.UTC().Format(time.RFC3339)
+		}
+		windows[i] = fmt.Sprintf("%s=%d", label, s.windows[w])
+	}
+	peakAt, peak := s.peak()
+	d := map[string]string{
+		"count":      strconv.Itoa(s.count),
+		"peak_count": strconv.Itoa(peak),
+		"windows":    strings.Join(windows, " "),
+		"sample":     s.sample,
+	}
+	if !s.first.IsZero() {
+		d["first"] = s.first.UTC().Format(time.RFC3339)
+		d["last"] = s.last.UTC().Format(time.RFC3339)
+	}
+	if !peakAt.IsZero() {
+		d["peak_window"] = peakAt.UTC().Format(time.RFC3339)
+	}
+	return d
+}
+
+// logCluster is an error signature: normalised tokens with wildcards where its messages differ.
+type logCluster struct {
+	tokens  []string
+	stats   logStats
+	sources map[string]bool
+}
+
+// logClusterer groups messages into signatures by token similarity.
+type logClusterer struct {
+	byLen map[int][]*logCluster
+	all   []*logCluster
+}
+
+// add assigns an entry to the first compatible signature or starts a new one.
+func (c *logClusterer) add(e logEntry, window time.Duration) {
+	tokens := normalizeLogMessage(e.message)
+	if len(tokens) == 0 {
+		return
+	}
+	if c.byLen == nil {
+		c.byLen = map[int][]*logCluster{}
+	}
+	var match *logCluster
+	for _, cl := range c.byLen[len(tokens)] {
+		if tokenSimilarity(cl.tokens, tokens) >= logSimilarity {
+			match = cl
+			break
+		}
+	}
+	if match == nil {
+		match = &logCluster{tokens: tokens, sources: map[string]bool{}}
+		c.byLen[len(tokens)] = append(c.byLen[len(tokens)], match)
+		c.all = append(c.all, match)
+	} else {
+		for i, t := range tokens {
+			if match.tokens[i] != t {
+				match.tokens[i] = logWildcard
+			}
+		}
+	}
+	match.stats.observe(e, window)
+	if e.source != "" {
+		match.sources[e.source] = true
+	}
+}
+
+// tokenSimilarity returns the fraction of equal tokens (wildcards match anything); the first token must be equal.
+func tokenSimilarity(sig, tokens []string) float64 {
+	if sig[0] != tokens[0] && sig[0] != logWildcard {
+		return 0
+	}
+	same := 0
+	for i, t := range tokens {
+		if sig[i] == t || sig[i] == logWildcard {
+			same++
+		}
+	}
+	return float64(same) / float64(len(tokens))
+}
+
+// sortedKeys returns the keys of a set in order.
+func sortedKeys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
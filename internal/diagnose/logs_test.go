@@ -0,0 +1,221 @@
+package diagnose
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+func TestNormalizeLogMessage(t *testing.T) {
+	got := strings.Join(normalizeLogMessage(
+		"worker[4242]: request 3f2c1a9e-0b7d-4c5e-9a11-22b3c4d5e6f7 to 10.0.0.7:5432 failed after 1500ms (ptr 0x7ffd, id deadbeef42)"), " ")
+	assert.Equal(t, "worker[<n>]: request <uuid> to <ip> failed after <n> (ptr <hex>, id <hex>)", got)
+}
+
+func TestParseLogTime(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.Local)
+	ts, rest, syslog := parseLogTime("2026-01-02T10:00:00.5Z ERROR boom", now)
+	assert.Equal(t, time.Date(2026, 1, 2, 10, 0, 0, 5e8, time.UTC), ts.UTC())
+	assert.Equal(t, "ERROR boom", rest)
+	assert.False(t, syslog)
+
+	ts, rest, _ = parseLogTime("2026-01-02 09:30:00,123 [main] error", now)
+	assert.Equal(t, time.Date(2026, 1, 2, 9, 30, 0, 0, time.Local), ts)
+	assert.Equal(t, "[main] error", rest)
+
+	ts, rest, syslog = parseLogTime("Dec 31 23:59:59 host kernel: boom", now)
+	assert.Equal(t, 2025, ts.Year(), "syslog entries after now belong to last year")
+	assert.Equal(t, "host kernel: boom", rest)
+	assert.True(t, syslog)
+
+	ts, rest, _ = parseLogTime("no timestamp here", now)
+	assert.True(t, ts.IsZero())
+	assert.Equal(t, "no timestamp here", rest)
+}
+
+// journalEntry renders one journal export entry; fields with a newline use the binary encoding.
+func journalEntry(fields map[string]string) string {
+	var b bytes.Buffer
+	for k, v := range fields {
+		if strings.Contains(v, "\n") {
+			b.WriteString(k + "\n")
+			_ = binary.Write(&b, binary.LittleEndian, uint64(len(v)))
+			b.WriteString(v + "\n")
+			continue
+		}
+		b.WriteString(k + "=" + v + "\n")
+	}
+	return b.String() + "\n"
+}
+
+func TestReadJournalExport(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	data := journalEntry(map[string]string{"__REALTIME_TIMESTAMP": fmt.Sprint(ts.UnixMicro()), "PRIORITY": "3",
+		"_SYSTEMD_UNIT": "api.service", "MESSAGE": "panic: boom\ngoroutine 1"}) +
+		journalEntry(map[string]string{"PRIORITY": "6", "SYSLOG_IDENTIFIER": "kernel", "MESSAGE": "eth0: link up"}) +
+		"MESSAGE=no trailing separator"
+
+	var got []logEntry
+	require.NoError(t, readJournalExport(strings.NewReader(data), func(e logEntry) { got = append(got, e) }))
+	require.Len(t, got, 3)
+	assert.Equal(t, logEntry{time: ts, source: "api.service", message: "panic: boom\ngoroutine 1", error: true}, got[0])
+	assert.Equal(t, logEntry{source: "kernel", message: "eth0: link up"}, got[1])
+	assert.Equal(t, "no trailing separator", got[2].message)
+
+	err := readJournalExport(strings.NewReader("MESSAGE\n\x05\x00"), func(logEntry) {})
+	assert.ErrorContains(t, err, "truncated journal field MESSAGE")
+}
+
+func TestLogDiagnostics_Run(t *testing.T) {
+	dir := t.TempDir()
+	var app []string
+	for i := 0; i < 12; i++ {
+		app = append(app, fmt.Sprintf("2026-01-02T10:%02d:00Z ERROR dial tcp 10.0.0.%d:5432: connect: connection refused", i, i))
+	}
+	app = append(app,
+		"2026-01-02T11:10:00Z ERROR upload 3f2c1a9e-0b7d-4c5e-9a11-22b3c4d5e6f7 failed: write /data/blob: no space left on device",
+		"2026-01-02T11:20:00Z INFO request served in 12ms",
+		"2026-01-01T00:00:00Z ERROR too old for --since",
+	)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.log"), []byte(strings.Join(app, "\n")+"\n"), 0o600))
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, _ = zw.Write([]byte("Jan  2 10:30:00 web01 kernel: Out of memory: Killed process 4242 (java)\n" +
+		"Jan  2 10:31:00 web01 kernel: java[4243]: segfault at 0 ip 00007f sp 00007ffd error 4\n"))
+	require.NoError(t, zw.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "kern.log.1.gz"), gz.Bytes(), 0o600))
+
+	journal := journalEntry(map[string]string{"__REALTIME_TIMESTAMP": fmt.Sprint(time.Date(2026, 1, 2, 10, 45, 0, 0, time.UTC).UnixMicro()),
+		"PRIORITY": "3", "_SYSTEMD_UNIT": "api.service", "MESSAGE": "payment gateway returned 502"})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "api.export"), []byte(journal), 0o600))
+
+	d := NewLogDiagnostics(core.NewTestLogger(&bytes.Buffer{}), LogOptions{
+		Files:   []string{filepath.Join(dir, "*.log"), filepath.Join(dir, "*.gz"), filepath.Join(dir, "missing.log")},
+		Journal: []string{filepath.Join(dir, "api.export")},
+		Since:   24 * time.Hour,
+		Window:  10 * time.Minute,
+		Patterns: MergeLogPatterns(DefaultLogPatterns, []LogPattern{
+			{Name: "payment", Match: `payment gateway returned 5\d\d`, Severity: SeverityCritical},
+		}),
+	})
+	d.now = func() time.Time { return time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC) }
+	r, err := d.Run(context.Background())
+	require.NoError(t, err)
+
+	refused := findingsByCheck(r, "logs.pattern.connection-refused")
+	require.Len(t, refused, 1)
+	assert.Equal(t, SeverityWarning, refused[0].Severity)
+	assert.Equal(t, "12", refused[0].Details["count"])
+	assert.Equal(t, "10", refused[0].Details["peak_count"], "threshold is met within one 10m window")
+	assert.Equal(t, "2026-01-02T10:00:00Z", refused[0].Details["first"])
+	assert.Equal(t, "2026-01-02T10:11:00Z", refused[0].Details["last"])
+	assert.Equal(t, "2026-01-02T10:00:00Z=10 2026-01-02T10:10:00Z=2", refused[0].Details["windows"])
+
+	for _, check := range []string{"logs.pattern.disk-full", "logs.pattern.oom-killer", "logs.pattern.segfault", "logs.pattern.payment"} {
+		require.Len(t, findingsByCheck(r, check), 1, check)
+		assert.Equal(t, SeverityCritical, findingsByCheck(r, check)[0].Severity, check)
+	}
+	assert.Contains(t, findingsByCheck(r, "logs.pattern.payment")[0].Message, "pattern payment matched: 1 occurrence(s)")
+	assert.Empty(t, findingsByCheck(r, "logs.pattern.tls-handshake"))
+	assert.Equal(t, 0.0, r.Measurements["logs.pattern.tls-handshake"])
+
+	source := findingsByCheck(r, "logs.source")
+	require.Len(t, source, 1)
+	assert.Equal(t, "file/"+filepath.Join(dir, "missing.log"), source[0].Resource)
+
+	sigs := findingsByCheck(r, "logs.signature")
+	require.NotEmpty(t, sigs)
+	assert.Equal(t, "ERROR dial tcp <ip>: connect: connection refused", sigs[0].Details["signature"])
+	assert.Equal(t, "12", sigs[0].Details["count"])
+	var kernel bool
+	for _, s := range sigs {
+		if s.Details["sources"] == "kernel" {
+			kernel = true
+			assert.Equal(t, "kernel: java[<n>]: segfault at <n> ip <hex> sp <hex> error <n>", s.Details["signature"])
+		}
+	}
+	assert.True(t, kernel, "syslog lines are attributed to their program")
+
+	assert.Equal(t, 17.0, r.Measurements["logs.entries"])
+	assert.Equal(t, 3.0, r.Measurements["logs.sources"])
+}
+
+func TestLogClusterer(t *testing.T) {
+	c := &logClusterer{}
+	for _, msg := range []string{
+		"backend api-1 unhealthy: dial timeout",
+		"backend cache unhealthy: dial timeout",
+		"backend search unhealthy: dial timeout",
+		"user alice login failed",
+	} {
+		c.add(logEntry{message: msg, error: true}, time.Hour)
+	}
+	require.Len(t, c.all, 2)
+	assert.Equal(t, "backend <*> unhealthy: dial timeout", strings.Join(c.all[0].tokens, " "))
+	assert.Equal(t, 3, c.all[0].stats.count)
+	assert.Equal(t, "undated=3", c.all[0].stats.details()["windows"])
+}
+
+func TestLogDiagnostics_Errors(t *testing.T) {
+	logger := core.NewTestLogger(&bytes.Buffer{})
+	_, err := NewLogDiagnostics(logger, LogOptions{Files: []string{"/nonexistent/x.log"}}).Run(context.Background())
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = NewLogDiagnostics(logger, LogOptions{Patterns: []LogPattern{{Name: "bad", Match: "("}}}).Run(context.Background())
+	assert.ErrorContains(t, err, `invalid log pattern "bad"`)
+}
+
+func TestLogOptions(t *testing.T) {
+	cmd := &cobra.Command{Use: "logs"}
+	cmd.Flags().StringSlice("file", nil, "")
+	cmd.Flags().StringSlice("journal", nil, "")
+	cmd.Flags().Duration("since", 0, "")
+	cmd.Flags().Duration("window", DefaultLogWindow, "")
+	cmd.Flags().Int("top", DefaultLogTopSignatures, "")
+
+	ctx := &core.AppContext{Logger: core.NewTestLogger(&bytes.Buffer{}), Config: core.NewConfig()}
+	cfg := &ctx.Config.Diagnostics.Logs
+	cfg.Files = []string{"/var/log/app.log"}
+	cfg.Window = "5m"
+	cfg.Top = 5
+	cfg.Patterns = []core.LogPatternConfig{
+		{Name: "segfault", Match: "core dumped", Severity: "warn"},
+		{Name: "deadlock", Match: "(?i)deadlock detected"},
+	}
+	require.NoError(t, cmd.Flags().Set("since", "2h"))
+	require.NoError(t, cmd.Flags().Set("top", "3"))
+
+	opts, err := logOptions(ctx, cmd)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/var/log/app.log"}, opts.Files)
+	assert.Equal(t, 2*time.Hour, opts.Since)
+	assert.Equal(t, 5*time.Minute, opts.Window)
+	assert.Equal(t, 3, opts.Top)
+	require.Len(t, opts.Patterns, len(DefaultLogPatterns)+1)
+	for _, p := range opts.Patterns {
+		switch p.Name {
+		case "segfault":
+			assert.Equal(t, LogPattern{Name: "segfault", Match: "core dumped", Severity: SeverityWarning}, p)
+		case "deadlock":
+			assert.Equal(t, SeverityWarning, p.Severity)
+		}
+	}
+
+	cfg.Patterns = []core.LogPatternConfig{{Name: "x", Match: "x", Severity: "loud"}}
+	_, err = logOptions(ctx, cmd)
+	assert.ErrorContains(t, err, `invalid severity of log pattern "x"`)
+}
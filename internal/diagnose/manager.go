@@ -2,6 +2,12 @@ package diagnose
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
 
 	"github.com/srediag/srediag/internal/core"
 )
@@ -11,64 +17,270 @@ import (
 // This file defines the DiagnoseManager, which orchestrates all diagnostic operations.
 //
 // Usage:
-//   - Use DiagnoseManager to coordinate system, performance, and security diagnostics.
-//   - Instantiate with NewDiagnoseManager, providing a logger.
-//   - Call RunSystem, RunPerformance, or RunSecurity to execute diagnostics.
+//   - Use DiagnoseManager to coordinate system, performance, security, and Kubernetes diagnostics.
+//   - Instantiate with NewDiagnoseManager, providing a logger and optional RunOptions (timeout, retries).
+//   - Call RunSystem, RunPerformance, RunSecurity, RunKubernetes, RunNetwork, RunLogs, RunContainers, RunCloud,
+//     or RunIaC to execute diagnostics and obtain a Report.
+//   - Every Run* method applies the timeout per attempt and retries failed attempts up to MaxRetries times.
+//   - Every Run* method records one srediag_diag_* run (see metrics.go) in the Recorder carried by ctx.
 //
 // Best Practices:
+//   - Pass the Cobra command context so Ctrl-C cancels in-flight diagnostics.
 //   - Always check for errors from diagnostic methods.
 //   - Use logger for all error and status reporting.
 //
-// TODO: Add support for context.Context to all methods for cancellation and timeouts.
-// TODO: Implement plugin manager logic to inject CLI subcommands for diagnostics (see docs/architecture/diagnose.md §2)
 // TODO: Implement plugin execution in the main process, with optional cmdhelper for heavy collectors (see docs/architecture/diagnose.md §2)
-// TODO: Implement control-plane feedback loop for remote diagnostics (see docs/architecture/diagnose.md §6)
-// TODO: Enforce error and exit-code semantics for diagnostics (see docs/architecture/diagnose.md §7)
-// TODO: Implement diagnostics metrics contract for all diagnostic operations (see docs/architecture/diagnose.md §8)
 
-// DiagnoseManager orchestrates all diagnostic operations (system, performance, security).
+// retryBackoff is the delay before retry n (multiplied by n); patchable in tests.
+var retryBackoff = time.Second
+
+// RunOptions controls timeouts and retries for diagnostic runs.
+//
+// Fields:
+//   - Timeout: Hard timeout per attempt; zero disables it.
+//   - MaxRetries: Number of additional attempts after a retryable failure.
+type RunOptions struct {
+	Timeout    time.Duration
+	MaxRetries int
+}
+
+// ManagerOption configures a DiagnoseManager.
+type ManagerOption func(*DiagnoseManager)
+
+// WithRunOptions sets the timeout and retry policy for all diagnostic runs.
+func WithRunOptions(o RunOptions) ManagerOption {
+	return func(m *DiagnoseManager) { m.opts = o }
+}
+
+// WithInventory sets the inventory sources collected by system diagnostics.
+func WithInventory(o InventoryOptions) ManagerOption {
+	return func(m *DiagnoseManager) { m.inventory = o }
+}
+
+// DiagnoseManager orchestrates all diagnostic operations (system, performance, security, kubernetes, iac).
 //
 // Usage:
 //   - Instantiate with NewDiagnoseManager, providing a logger.
-//   - Call RunSystem, RunPerformance, or RunSecurity to execute diagnostics.
+//   - Call RunSystem, RunPerformance, RunSecurity, RunKubernetes, RunNetwork, RunLogs, RunContainers, RunCloud, or
+//     RunIaC to execute diagnostics.
 type DiagnoseManager struct {
-	logger *core.Logger
+	logger    *core.Logger
+	opts      RunOptions
+	inventory InventoryOptions
 }
 
 // NewDiagnoseManager creates a new DiagnoseManager.
 //
 // Parameters:
 //   - logger: Logger for status and error reporting.
+//   - opts: Optional ManagerOption(s), e.g. WithRunOptions or WithInventory.
 //
 // Returns:
 //   - *DiagnoseManager: A new DiagnoseManager instance.
-func NewDiagnoseManager(logger *core.Logger) *DiagnoseManager {
-	return &DiagnoseManager{logger: logger}
+func NewDiagnoseManager(logger *core.Logger, opts ...ManagerOption) *DiagnoseManager {
+	m := &DiagnoseManager{logger: logger}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// run executes fn with the manager's timeout and retry policy.
+//
+// Parameters:
+//   - ctx: Parent context; cancelling it stops retries.
+//   - plugin: Plugin ID used as the metrics "plugin" label.
+//   - name: Diagnostic name for logging and errors.
+//   - fn: The diagnostic to run.
+//
+// Returns:
+//   - *Report: The report from the first successful attempt.
+//   - error: The last attempt's error; timeouts wrap ErrTimeout.
+func (m *DiagnoseManager) run(ctx context.Context, plugin, name string, fn func(context.Context) (*Report, error)) (report *Report, err error) {
+	metricsRun := NewRun(ctx, plugin)
+	defer func() { metricsRun.End(err) }()
+
+	var lastErr error
+	for attempt := 0; attempt <= m.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			m.logger.Warn("Retrying diagnostic",
+				core.ZapString("diagnostic", name),
+				core.ZapInt("attempt", attempt+1),
+				core.ZapError(lastErr))
+			select {
+			case <-ctx.Done():
+				return nil, lastErr
+			case <-time.After(time.Duration(attempt) * retryBackoff):
+			}
+		}
+		report, err := m.attempt(ctx, name, fn)
+		if err == nil {
+			return report, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil || !isRetryable(err) {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// attempt runs fn once under the per-attempt timeout.
+func (m *DiagnoseManager) attempt(ctx context.Context, name string, fn func(context.Context) (*Report, error)) (*Report, error) {
+	attemptCtx := ctx
+	if m.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, m.opts.Timeout)
+		defer cancel()
+	}
+	report, err := fn(attemptCtx)
+	if errors.Is(attemptCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		return nil, fmt.Errorf("%w: %s exceeded %s", ErrTimeout, name, m.opts.Timeout)
+	}
+	return report, err
 }
 
 // RunSystem runs system diagnostics.
 //
+// Parameters:
+//   - ctx: Context for cancellation; the manager adds the per-attempt timeout.
+//
 // Returns:
+//   - *Report: The system diagnostics report.
 //   - error: If system diagnostics fail, returns a detailed error.
-func (m *DiagnoseManager) RunSystem() error {
-	d := NewSystemDiagnostics(m.logger)
-	return d.Run(context.Background())
+func (m *DiagnoseManager) RunSystem(ctx context.Context) (*Report, error) {
+	d := NewSystemDiagnostics(m.logger, m.inventory)
+	return m.run(ctx, BuiltinSystemSnapshot, "system", d.Run)
 }
 
 // RunPerformance runs performance diagnostics.
 //
+// Parameters:
+//   - ctx: Context for cancellation; the manager adds the per-attempt timeout.
+//   - opts: eBPF tracing options.
+//
 // Returns:
+//   - *Report: The performance diagnostics report.
 //   - error: If performance diagnostics fail, returns a detailed error.
-func (m *DiagnoseManager) RunPerformance() error {
-	d := NewPerformanceDiagnostics(m.logger)
-	return d.Run(context.Background())
+func (m *DiagnoseManager) RunPerformance(ctx context.Context, opts PerformanceOptions) (*Report, error) {
+	d := NewPerformanceDiagnostics(m.logger, opts)
+	return m.run(ctx, BuiltinPerfProfiler, "performance", d.Run)
 }
 
 // RunSecurity runs security diagnostics.
 //
+// Parameters:
+//   - ctx: Context for cancellation; the manager adds the per-attempt timeout.
+//
 // Returns:
+//   - *Report: The security diagnostics report.
 //   - error: If security diagnostics fail, returns a detailed error.
-func (m *DiagnoseManager) RunSecurity() error {
+func (m *DiagnoseManager) RunSecurity(ctx context.Context) (*Report, error) {
 	d := NewSecurityDiagnostics(m.logger)
-	return d.Run(context.Background())
+	return m.run(ctx, BuiltinCISBaseline, "security", d.Run)
+}
+
+// RunKubernetes runs Kubernetes cluster, node, and pod diagnostics.
+//
+// Parameters:
+//   - ctx: Context for cancellation; the manager adds the per-attempt timeout.
+//   - client: Core API clientset.
+//   - metrics: metrics.k8s.io clientset; may be nil to skip usage checks.
+//   - opts: Namespace and event window options.
+//
+// Returns:
+//   - *Report: The Kubernetes diagnostics report.
+//   - error: If Kubernetes diagnostics fail, returns a detailed error.
+func (m *DiagnoseManager) RunKubernetes(ctx context.Context, client kubernetes.Interface, metrics metricsclient.Interface, opts KubernetesOptions) (*Report, error) {
+	d := NewKubernetesDiagnostics(m.logger, client, metrics, opts)
+	return m.run(ctx, BuiltinKubernetes, "kubernetes", d.Run)
+}
+
+// RunNetwork runs network probes and local network stack checks.
+//
+// Parameters:
+//   - ctx: Context for cancellation; the manager adds the per-attempt timeout.
+//   - opts: Probe targets, timeouts and thresholds.
+//
+// Returns:
+//   - *Report: The network diagnostics report.
+//   - error: If network diagnostics fail, returns a detailed error.
+func (m *DiagnoseManager) RunNetwork(ctx context.Context, opts NetworkOptions) (*Report, error) {
+	d := NewNetworkDiagnostics(m.logger, opts)
+	return m.run(ctx, BuiltinNetwork, "network", d.Run)
+}
+
+// RunLogs runs log analysis (error signatures and failure patterns).
+//
+// Parameters:
+//   - ctx: Context for cancellation; the manager adds the per-attempt timeout.
+//   - opts: Log sources, time range and patterns.
+//
+// Returns:
+//   - *Report: The log analysis report.
+//   - error: If log analysis fails, returns a detailed error.
+func (m *DiagnoseManager) RunLogs(ctx context.Context, opts LogOptions) (*Report, error) {
+	d := NewLogDiagnostics(m.logger, opts)
+	return m.run(ctx, BuiltinLogs, "logs", d.Run)
+}
+
+// RunContainers runs container runtime diagnostics.
+//
+// Parameters:
+//   - ctx: Context for cancellation; the manager adds the per-attempt timeout.
+//   - runtime: Container runtime client.
+//   - opts: Thresholds.
+//
+// Returns:
+//   - *Report: The container diagnostics report.
+//   - error: If container diagnostics fail, returns a detailed error.
+func (m *DiagnoseManager) RunContainers(ctx context.Context, runtime ContainerRuntime, opts ContainerOptions) (*Report, error) {
+	d := NewContainerDiagnostics(m.logger, runtime, opts)
+	return m.run(ctx, BuiltinContainers, "containers", d.Run)
+}
+
+// RunCloud runs cloud instance metadata diagnostics.
+//
+// Parameters:
+//   - ctx: Context for cancellation; the manager adds the per-attempt timeout.
+//   - provider: Cloud provider client.
+//   - opts: Thresholds.
+//
+// Returns:
+//   - *Report: The cloud diagnostics report.
+//   - error: If cloud diagnostics fail, returns a detailed error.
+func (m *DiagnoseManager) RunCloud(ctx context.Context, provider CloudProvider, opts CloudOptions) (*Report, error) {
+	d := NewCloudDiagnostics(m.logger, provider, opts)
+	return m.run(ctx, BuiltinCloud, "cloud", d.Run)
+}
+
+// RunIaC runs Infrastructure-as-Code static analysis.
+//
+// Parameters:
+//   - ctx: Context for cancellation; the manager adds the per-attempt timeout.
+//   - path: File or directory containing Terraform, Kubernetes manifests, or rendered Helm charts.
+//
+// Returns:
+//   - *Report: The IaC diagnostics report.
+//   - error: If IaC diagnostics fail, returns a detailed error.
+func (m *DiagnoseManager) RunIaC(ctx context.Context, path string) (*Report, error) {
+	d := NewIaCDiagnostics(m.logger, path)
+	return m.run(ctx, BuiltinIaC, "iac", d.Run)
+}
+
+// namedDiagnostics maps diagnostic names to manager runs that need no arguments beyond the context; used by
+// 'diagnose bundle' (diagnostics.bundle.diagnostics) and 'diagnose diff' (re-running a baseline's diagnostic).
+var namedDiagnostics = map[string]func(mgr *DiagnoseManager, ctx context.Context) (*Report, error){
+	"system": (*DiagnoseManager).RunSystem,
+	"performance": func(mgr *DiagnoseManager, ctx context.Context) (*Report, error) {
+		return mgr.RunPerformance(ctx, PerformanceOptions{})
+	},
+	"security": (*DiagnoseManager).RunSecurity,
+	"kubernetes": func(mgr *DiagnoseManager, ctx context.Context) (*Report, error) {
+		client, metrics, err := newKubernetesClientsFunc("", "")
+		if err != nil {
+			return nil, err
+		}
+		return mgr.RunKubernetes(ctx, client, metrics, KubernetesOptions{})
+	},
 }
@@ -0,0 +1,133 @@
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+func TestDiagnoseManager_Timeout(t *testing.T) {
+	mgr := NewDiagnoseManager(core.NewTestLogger(&bytes.Buffer{}), WithRunOptions(RunOptions{Timeout: 20 * time.Millisecond}))
+	_, err := mgr.run(context.Background(), "test", "slow", func(ctx context.Context) (*Report, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Equal(t, ExitTimeout, ExitCode(err))
+}
+
+func TestDiagnoseManager_Retries(t *testing.T) {
+	orig := retryBackoff
+	retryBackoff = time.Millisecond
+	defer func() { retryBackoff = orig }()
+
+	mgr := NewDiagnoseManager(core.NewTestLogger(&bytes.Buffer{}), WithRunOptions(RunOptions{MaxRetries: 2}))
+	attempts := 0
+	report, err := mgr.run(context.Background(), "test", "flaky", func(context.Context) (*Report, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("transient")
+		}
+		return NewReport("flaky"), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "flaky", report.Diagnostic)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	_, err = mgr.run(context.Background(), "test", "tampered", func(context.Context) (*Report, error) {
+		attempts++
+		return nil, fmt.Errorf("bad digest: %w", ErrVerification)
+	})
+	assert.Equal(t, ExitVerification, ExitCode(err))
+	assert.Equal(t, 1, attempts, "verification failures must not be retried")
+}
+
+// exitCodeError is an error that chooses its own exit code.
+type exitCodeError int
+
+func (e exitCodeError) Error() string        { return "coded" }
+func (e exitCodeError) SrediagExitCode() int { return int(e) }
+
+func TestExitCode(t *testing.T) {
+	cases := map[error]int{
+		nil:                                  ExitOK,
+		errors.New("boom"):                   ExitError,
+		fmt.Errorf("x: %w", ErrVerification): ExitVerification,
+		fmt.Errorf("x: %w", ErrSandbox):      ExitSandbox,
+		fmt.Errorf("x: %w", ErrNotFound):     ExitNotFound,
+		fmt.Errorf("x: %w", ErrTimeout):      ExitTimeout,
+		context.DeadlineExceeded:             ExitError, // a client deadline, not --timeout
+		fmt.Errorf("GET http://srediag/v1/status: %w", context.DeadlineExceeded): ExitError,
+		&FindingsError{Threshold: SeverityWarning, Count: 1}:                     ExitFindings,
+		fmt.Errorf("x: %w", exitCodeError(ExitVerification)):                     ExitVerification,
+	}
+	for err, want := range cases {
+		assert.Equal(t, want, ExitCode(err), "%v", err)
+	}
+
+	// The status of a child process is not passed through: exit 3 is not a sandbox violation, a signal not 255.
+	for _, script := range []string{"exit 3", "kill -9 $$"} {
+		err := exec.Command("sh", "-c", script).Run()
+		require.Error(t, err)
+		assert.Equal(t, ExitError, ExitCode(fmt.Errorf("systemctl start srediag.service: %w", err)), script)
+	}
+}
+
+func TestCLI_FailOn(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(iacTerraform), 0o600))
+
+	newCmd := func(failOn string) *cobra.Command {
+		cmd := &cobra.Command{Use: "iac"}
+		cmd.Flags().Duration("timeout", 30*time.Second, "")
+		cmd.Flags().String("fail-on", failOn, "")
+		cmd.SetOut(&bytes.Buffer{})
+		return cmd
+	}
+	ctx := &core.AppContext{Logger: core.NewTestLogger(&bytes.Buffer{})}
+
+	err := CLI_IaCDiagnostics(ctx, newCmd("critical"), []string{dir})
+	var findings *FindingsError
+	require.ErrorAs(t, err, &findings)
+	assert.Equal(t, 1, findings.Count)
+	assert.Equal(t, ExitFindings, ExitCode(err))
+
+	assert.NoError(t, CLI_IaCDiagnostics(ctx, newCmd(""), []string{dir}))
+	assert.ErrorContains(t, CLI_IaCDiagnostics(ctx, newCmd("fatal"), []string{dir}), "invalid --fail-on")
+}
+
+func TestResolveRunOptions(t *testing.T) {
+	cfg := core.NewConfig()
+	cfg.Diagnostics.Defaults.Timeout = "2m"
+	cfg.Diagnostics.Defaults.MaxRetries = 3
+	ctx := &core.AppContext{Config: cfg}
+
+	cmd := &cobra.Command{Use: "x"}
+	cmd.Flags().Duration("timeout", 30*time.Second, "")
+	opts, err := resolveRunOptions(ctx, cmd)
+	require.NoError(t, err)
+	assert.Equal(t, RunOptions{Timeout: 2 * time.Minute, MaxRetries: 3}, opts)
+
+	require.NoError(t, cmd.Flags().Set("timeout", "5s"))
+	opts, err = resolveRunOptions(ctx, cmd)
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, opts.Timeout)
+
+	cfg.Diagnostics.Defaults.Timeout = "soon"
+	_, err = resolveRunOptions(ctx, &cobra.Command{Use: "y"})
+	assert.Error(t, err)
+}
@@ -1,3 +1,380 @@
-// TODO: Implement diagnostics metrics contract to track diagnostic runs, durations, and sandbox violations (see docs/architecture/diagnose.md §8)
-// TODO: Ensure all plugins emit srediag_diag_runs_total, srediag_diag_duration_seconds, and srediag_diag_sandbox_violations_total metrics (see docs/architecture/diagnose.md §8)
 package diagnose
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file implements the diagnostics metrics contract (docs/architecture/diagnose.md §8):
+//
+//	srediag_diag_runs_total{plugin,result}                counter
+//	srediag_diag_duration_seconds{plugin}                 histogram
+//	srediag_diag_sandbox_violations_total{plugin,syscall} counter
+//
+// Usage:
+//   - Wrap every diagnostic run with NewRun(ctx, plugin) and call End(err) when it finishes.
+//   - CLI mode: the Recorder keeps the values in memory; ExportMetrics writes them on exit to a Prometheus textfile
+//     or an OTLP endpoint (--metrics-export / diagnostics.metrics.export).
+//   - Service mode: UseMeterProvider mirrors every observation to the agent's own telemetry.
+//
+// Best Practices:
+//   - Use the plugin ID (or built-in diagnostic name) as the plugin label; never put unbounded values in labels.
+
+// Metric names of the diagnostics metrics contract.
+const (
+	MetricRuns              = "srediag_diag_runs_total"
+	MetricDuration          = "srediag_diag_duration_seconds"
+	MetricSandboxViolations = "srediag_diag_sandbox_violations_total"
+)
+
+// durationBuckets are the explicit upper bounds (seconds) of srediag_diag_duration_seconds.
+var durationBuckets = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// defaultRecorder receives runs when the context carries no Recorder.
+var defaultRecorder = NewRecorder()
+
+// recorderKey is the context key for a Recorder.
+type recorderKey struct{}
+
+type runKey struct{ plugin, result string }
+
+type violationKey struct{ plugin, syscall string }
+
+// durationHistogram holds per-bucket (non-cumulative) counts; the last slot is the +Inf bucket.
+type durationHistogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Recorder aggregates diagnostics metrics in memory and optionally mirrors them to an OTel meter.
+//
+// Usage:
+//   - Most callers use DefaultRecorder; tests create their own with NewRecorder and ContextWithRecorder.
+//   - Thread-safe for concurrent runs.
+type Recorder struct {
+	mu         sync.Mutex
+	start      time.Time
+	runs       map[runKey]uint64
+	durations  map[string]*durationHistogram
+	violations map[violationKey]uint64
+
+	otelRuns       metric.Int64Counter
+	otelDuration   metric.Float64Histogram
+	otelViolations metric.Int64Counter
+}
+
+// NewRecorder creates an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		start:      time.Now(),
+		runs:       make(map[runKey]uint64),
+		durations:  make(map[string]*durationHistogram),
+		violations: make(map[violationKey]uint64),
+	}
+}
+
+// DefaultRecorder returns the process-wide Recorder.
+func DefaultRecorder() *Recorder {
+	return defaultRecorder
+}
+
+// ContextWithRecorder returns a context whose diagnostic runs are recorded in r.
+func ContextWithRecorder(ctx context.Context, r *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, r)
+}
+
+// recorderFromContext returns the Recorder carried by ctx, or the default one.
+func recorderFromContext(ctx context.Context) *Recorder {
+	if ctx != nil {
+		if r, ok := ctx.Value(recorderKey{}).(*Recorder); ok && r != nil {
+			return r
+		}
+	}
+	return defaultRecorder
+}
+
+// UseMeterProvider mirrors the default Recorder to the given meter provider (service mode).
+//
+// Parameters:
+//   - mp: The agent's meter provider (component.TelemetrySettings.MeterProvider).
+//
+// Returns:
+//   - error: If an instrument cannot be created, returns a detailed error.
+func UseMeterProvider(mp metric.MeterProvider) error {
+	return defaultRecorder.UseMeterProvider(mp)
+}
+
+// UseMeterProvider mirrors r to the given meter provider.
+func (r *Recorder) UseMeterProvider(mp metric.MeterProvider) error {
+	if mp == nil {
+		return nil
+	}
+	meter := mp.Meter("github.com/srediag/srediag/internal/diagnose")
+	runs, err := meter.Int64Counter(MetricRuns, metric.WithDescription("Finished diagnostic runs"))
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", MetricRuns, err)
+	}
+	duration, err := meter.Float64Histogram(MetricDuration,
+		metric.WithDescription("Wall-clock duration of diagnostic runs"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...))
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", MetricDuration, err)
+	}
+	violations, err := meter.Int64Counter(MetricSandboxViolations, metric.WithDescription("Sandbox violations by diagnostic plugins"))
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", MetricSandboxViolations, err)
+	}
+	r.mu.Lock()
+	r.otelRuns, r.otelDuration, r.otelViolations = runs, duration, violations
+	r.mu.Unlock()
+	return nil
+}
+
+// RecordRun records a finished run.
+//
+// Parameters:
+//   - ctx: Context for the OTel instruments.
+//   - plugin: Plugin or diagnostic name.
+//   - result: Run result label (see RunResult).
+//   - d: Wall-clock duration.
+func (r *Recorder) RecordRun(ctx context.Context, plugin, result string, d time.Duration) {
+	seconds := d.Seconds()
+	r.mu.Lock()
+	r.runs[runKey{plugin, result}]++
+	h, ok := r.durations[plugin]
+	if !ok {
+		h = &durationHistogram{counts: make([]uint64, len(durationBuckets)+1)}
+		r.durations[plugin] = h
+	}
+	h.counts[sort.SearchFloat64s(durationBuckets, seconds)]++
+	h.sum += seconds
+	h.count++
+	runs, duration := r.otelRuns, r.otelDuration
+	r.mu.Unlock()
+
+	if runs != nil {
+		runs.Add(ctx, 1, metric.WithAttributes(attribute.String("plugin", plugin), attribute.String("result", result)))
+		duration.Record(ctx, seconds, metric.WithAttributes(attribute.String("plugin", plugin)))
+	}
+}
+
+// RecordSandboxViolation records a sandbox violation.
+//
+// Parameters:
+//   - ctx: Context for the OTel instruments.
+//   - plugin: Plugin or diagnostic name.
+//   - syscall: The blocked syscall or resource (e.g., "perf_event_open", "rlimit_as").
+func (r *Recorder) RecordSandboxViolation(ctx context.Context, plugin, syscall string) {
+	r.mu.Lock()
+	r.violations[violationKey{plugin, syscall}]++
+	violations := r.otelViolations
+	r.mu.Unlock()
+
+	if violations != nil {
+		violations.Add(ctx, 1, metric.WithAttributes(attribute.String("plugin", plugin), attribute.String("syscall", syscall)))
+	}
+}
+
+// Empty reports whether nothing has been recorded.
+func (r *Recorder) Empty() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.runs) == 0 && len(r.violations) == 0
+}
+
+// Run tracks a single diagnostic run (the SDK helper "diagmetrics.NewRun" of the design doc).
+type Run struct {
+	ctx      context.Context
+	recorder *Recorder
+	plugin   string
+	start    time.Time
+	once     sync.Once
+}
+
+// NewRun starts tracking a diagnostic run.
+//
+// Parameters:
+//   - ctx: Context; a Recorder attached with ContextWithRecorder is used, otherwise DefaultRecorder.
+//   - plugin: Plugin or diagnostic name.
+//
+// Returns:
+//   - *Run: Call End exactly once when the run finishes.
+func NewRun(ctx context.Context, plugin string) *Run {
+	return &Run{ctx: ctx, recorder: recorderFromContext(ctx), plugin: plugin, start: time.Now()}
+}
+
+// End records the run result and duration; calls after the first are ignored.
+func (r *Run) End(err error) {
+	r.once.Do(func() {
+		r.recorder.RecordRun(r.ctx, r.plugin, RunResult(err), time.Since(r.start))
+	})
+}
+
+// SandboxViolation records a sandbox violation for this run's plugin.
+func (r *Run) SandboxViolation(syscall string) {
+	r.recorder.RecordSandboxViolation(r.ctx, r.plugin, syscall)
+}
+
+// RunResult maps a run error to the "result" label of srediag_diag_runs_total.
+func RunResult(err error) string {
+	switch ExitCode(err) {
+	case ExitOK, ExitFindings:
+		return "success"
+	case ExitTimeout:
+		return "timeout"
+	case ExitNotFound:
+		return "not_found"
+	case ExitSandbox:
+		return "sandbox"
+	case ExitVerification:
+		return "verification_failed"
+	default:
+		return "error"
+	}
+}
+
+// WritePrometheus writes the recorded metrics in the Prometheus text exposition format.
+//
+// Parameters:
+//   - w: Destination writer.
+//
+// Returns:
+//   - error: If writing fails, returns the write error.
+func (r *Recorder) WritePrometheus(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# HELP %s Finished diagnostic runs.\n# TYPE %s counter\n", MetricRuns, MetricRuns)
+	runKeys := make([]runKey, 0, len(r.runs))
+	for k := range r.runs {
+		runKeys = append(runKeys, k)
+	}
+	sort.Slice(runKeys, func(i, j int) bool {
+		if runKeys[i].plugin != runKeys[j].plugin {
+			return runKeys[i].plugin < runKeys[j].plugin
+		}
+		return runKeys[i].result < runKeys[j].result
+	})
+	for _, k := range runKeys {
+		fmt.Fprintf(&b, "%s{plugin=%q,result=%q} %d\n", MetricRuns, k.plugin, k.result, r.runs[k])
+	}
+
+	fmt.Fprintf(&b, "# HELP %s Wall-clock duration of diagnostic runs.\n# TYPE %s histogram\n", MetricDuration, MetricDuration)
+	plugins := make([]string, 0, len(r.durations))
+	for p := range r.durations {
+		plugins = append(plugins, p)
+	}
+	sort.Strings(plugins)
+	for _, p := range plugins {
+		h := r.durations[p]
+		var cumulative uint64
+		for i, bound := range durationBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&b, "%s_bucket{plugin=%q,le=%q} %d\n", MetricDuration, p, formatFloat(bound), cumulative)
+		}
+		fmt.Fprintf(&b, "%s_bucket{plugin=%q,le=\"+Inf\"} %d\n", MetricDuration, p, h.count)
+		fmt.Fprintf(&b, "%s_sum{plugin=%q} %s\n", MetricDuration, p, formatFloat(h.sum))
+		fmt.Fprintf(&b, "%s_count{plugin=%q} %d\n", MetricDuration, p, h.count)
+	}
+
+	fmt.Fprintf(&b, "# HELP %s Sandbox violations by diagnostic plugins.\n# TYPE %s counter\n", MetricSandboxViolations, MetricSandboxViolations)
+	violationKeys := make([]violationKey, 0, len(r.violations))
+	for k := range r.violations {
+		violationKeys = append(violationKeys, k)
+	}
+	sort.Slice(violationKeys, func(i, j int) bool {
+		if violationKeys[i].plugin != violationKeys[j].plugin {
+			return violationKeys[i].plugin < violationKeys[j].plugin
+		}
+		return violationKeys[i].syscall < violationKeys[j].syscall
+	})
+	for _, k := range violationKeys {
+		fmt.Fprintf(&b, "%s{plugin=%q,syscall=%q} %d\n", MetricSandboxViolations, k.plugin, k.syscall, r.violations[k])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// AppendTo appends the recorded metrics as cumulative OTLP metrics to sm.
+//
+// Parameters:
+//   - sm: Scope metrics to append to.
+//   - now: Timestamp of the data points.
+func (r *Recorder) AppendTo(sm pmetric.ScopeMetrics, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	start := pcommon.NewTimestampFromTime(r.start)
+	ts := pcommon.NewTimestampFromTime(now)
+
+	runs := sm.Metrics().AppendEmpty()
+	runs.SetName(MetricRuns)
+	runs.SetDescription("Finished diagnostic runs")
+	sum := runs.SetEmptySum()
+	sum.SetIsMonotonic(true)
+	sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	for k, v := range r.runs {
+		dp := sum.DataPoints().AppendEmpty()
+		dp.SetStartTimestamp(start)
+		dp.SetTimestamp(ts)
+		dp.SetIntValue(int64(v))
+		dp.Attributes().PutStr("plugin", k.plugin)
+		dp.Attributes().PutStr("result", k.result)
+	}
+
+	duration := sm.Metrics().AppendEmpty()
+	duration.SetName(MetricDuration)
+	duration.SetDescription("Wall-clock duration of diagnostic runs")
+	duration.SetUnit("s")
+	hist := duration.SetEmptyHistogram()
+	hist.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	for p, h := range r.durations {
+		dp := hist.DataPoints().AppendEmpty()
+		dp.SetStartTimestamp(start)
+		dp.SetTimestamp(ts)
+		dp.SetCount(h.count)
+		dp.SetSum(h.sum)
+		dp.ExplicitBounds().FromRaw(durationBuckets)
+		dp.BucketCounts().FromRaw(h.counts)
+		dp.Attributes().PutStr("plugin", p)
+	}
+
+	violations := sm.Metrics().AppendEmpty()
+	violations.SetName(MetricSandboxViolations)
+	violations.SetDescription("Sandbox violations by diagnostic plugins")
+	vsum := violations.SetEmptySum()
+	vsum.SetIsMonotonic(true)
+	vsum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	for k, v := range r.violations {
+		dp := vsum.DataPoints().AppendEmpty()
+		dp.SetStartTimestamp(start)
+		dp.SetTimestamp(ts)
+		dp.SetIntValue(int64(v))
+		dp.Attributes().PutStr("plugin", k.plugin)
+		dp.Attributes().PutStr("syscall", k.syscall)
+	}
+}
+
+// formatFloat renders a float for the Prometheus text format.
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return fmt.Sprintf("%g", f)
+}
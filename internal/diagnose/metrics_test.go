@@ -0,0 +1,182 @@
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/otel/metric/noop"
+	"google.golang.org/grpc"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+func TestRecorder_Prometheus(t *testing.T) {
+	r := NewRecorder()
+	ctx := ContextWithRecorder(context.Background(), r)
+	require.NoError(t, r.UseMeterProvider(noop.NewMeterProvider()))
+
+	NewRun(ctx, "systemsnapshot").End(nil)
+	run := NewRun(ctx, "systemsnapshot")
+	run.End(fmt.Errorf("x: %w", ErrTimeout))
+	run.End(nil) // ignored
+	run = NewRun(ctx, "fsmonitor")
+	run.SandboxViolation("ptrace")
+	run.End(fmt.Errorf("x: %w", ErrSandbox))
+
+	var out bytes.Buffer
+	require.NoError(t, r.WritePrometheus(&out))
+	text := out.String()
+	assert.Contains(t, text, `srediag_diag_runs_total{plugin="systemsnapshot",result="success"} 1`)
+	assert.Contains(t, text, `srediag_diag_runs_total{plugin="systemsnapshot",result="timeout"} 1`)
+	assert.Contains(t, text, `srediag_diag_runs_total{plugin="fsmonitor",result="sandbox"} 1`)
+	assert.Contains(t, text, `srediag_diag_duration_seconds_bucket{plugin="systemsnapshot",le="0.1"} 2`)
+	assert.Contains(t, text, `srediag_diag_duration_seconds_count{plugin="systemsnapshot"} 2`)
+	assert.Contains(t, text, `srediag_diag_sandbox_violations_total{plugin="fsmonitor",syscall="ptrace"} 1`)
+	assert.True(t, NewRecorder().Empty())
+}
+
+func TestDiagnoseManager_RecordsOncePerRun(t *testing.T) {
+	orig := retryBackoff
+	retryBackoff = time.Millisecond
+	defer func() { retryBackoff = orig }()
+
+	r := NewRecorder()
+	ctx := ContextWithRecorder(context.Background(), r)
+	mgr := NewDiagnoseManager(core.NewTestLogger(&bytes.Buffer{}), WithRunOptions(RunOptions{MaxRetries: 2}))
+	_, err := mgr.run(ctx, "flakyplugin", "flaky", func(context.Context) (*Report, error) {
+		return nil, errors.New("boom")
+	})
+	require.Error(t, err)
+
+	md := exportedMetrics(r)
+	runs := findMetric(t, md, MetricRuns).Sum().DataPoints()
+	require.Equal(t, 1, runs.Len(), "retries must not be counted as separate runs")
+	assert.Equal(t, int64(1), runs.At(0).IntValue())
+	result, _ := runs.At(0).Attributes().Get("result")
+	assert.Equal(t, "error", result.Str())
+}
+
+func TestExportMetrics_Textfile(t *testing.T) {
+	r := NewRecorder()
+	NewRun(ContextWithRecorder(context.Background(), r), "iacanalyzer").End(nil)
+
+	path := filepath.Join(t.TempDir(), "srediag.prom")
+	require.NoError(t, ExportMetrics(context.Background(), path, r))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `srediag_diag_runs_total{plugin="iacanalyzer",result="success"} 1`)
+
+	require.NoError(t, ExportMetrics(context.Background(), "file://"+path, r))
+	assert.ErrorContains(t, ExportMetrics(context.Background(), "ftp://host", r), "unsupported scheme")
+}
+
+// metricsReceiver is an in-process OTLP/gRPC metrics receiver.
+type metricsReceiver struct {
+	pmetricotlp.UnimplementedGRPCServer
+	got chan pmetric.Metrics
+}
+
+func (m *metricsReceiver) Export(_ context.Context, req pmetricotlp.ExportRequest) (pmetricotlp.ExportResponse, error) {
+	m.got <- req.Metrics()
+	return pmetricotlp.NewExportResponse(), nil
+}
+
+func TestExportMetrics_OTLPGRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	recv := &metricsReceiver{got: make(chan pmetric.Metrics, 1)}
+	pmetricotlp.RegisterGRPCServer(srv, recv)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	r := NewRecorder()
+	NewRun(ContextWithRecorder(context.Background(), r), "k8sclusterdiagnostics").End(nil)
+	require.NoError(t, ExportMetrics(context.Background(), "otlp://"+lis.Addr().String(), r))
+
+	md := <-recv.got
+	service, _ := md.ResourceMetrics().At(0).Resource().Attributes().Get("service.name")
+	assert.Equal(t, "srediag", service.Str())
+	hist := findMetric(t, md, MetricDuration).Histogram().DataPoints()
+	require.Equal(t, 1, hist.Len())
+	assert.Equal(t, uint64(1), hist.At(0).Count())
+	assert.Equal(t, len(durationBuckets)+1, hist.At(0).BucketCounts().Len())
+}
+
+func TestExportMetrics_OTLPHTTP(t *testing.T) {
+	got := make(chan pmetric.Metrics, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/v1/metrics", req.URL.Path)
+		body, _ := io.ReadAll(req.Body)
+		er := pmetricotlp.NewExportRequest()
+		assert.NoError(t, er.UnmarshalProto(body))
+		got <- er.Metrics()
+	}))
+	defer srv.Close()
+
+	r := NewRecorder()
+	NewRun(ContextWithRecorder(context.Background(), r), "perfprofiler").End(nil)
+	require.NoError(t, ExportMetrics(context.Background(), srv.URL, r))
+	runs := findMetric(t, <-got, MetricRuns).Sum().DataPoints()
+	require.Equal(t, 1, runs.Len())
+	plugin, _ := runs.At(0).Attributes().Get("plugin")
+	assert.Equal(t, "perfprofiler", plugin.Str())
+}
+
+func TestAttachPlugins_ExportsMetrics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "diag.prom")
+	parent := &cobra.Command{Use: "diagnose", SilenceUsage: true, SilenceErrors: true}
+	parent.PersistentFlags().String("metrics-export", "", "")
+	_, errs := AttachPlugins(&core.AppContext{}, parent, nil, []DiagPlugin{
+		&fakePlugin{name: "probe", caps: []string{"diag/probe"}, register: func(b *cobra.Command) error {
+			b.AddCommand(&cobra.Command{Use: "probe", RunE: func(cmd *cobra.Command, _ []string) error {
+				NewRun(cmd.Context(), "probe").End(nil)
+				return nil
+			}})
+			return nil
+		}},
+	})
+	require.Empty(t, errs)
+
+	r := NewRecorder()
+	parent.SetArgs([]string{"probe", "--metrics-export", path})
+	require.NoError(t, parent.ExecuteContext(ContextWithRecorder(context.Background(), r)))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `srediag_diag_runs_total{plugin="probe",result="success"} 1`)
+}
+
+// exportedMetrics converts a recorder into pmetric.Metrics.
+func exportedMetrics(r *Recorder) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	r.AppendTo(md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty(), time.Now())
+	return md
+}
+
+// findMetric returns the named metric from the first scope.
+func findMetric(t *testing.T, md pmetric.Metrics, name string) pmetric.Metric {
+	t.Helper()
+	metrics := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < metrics.Len(); i++ {
+		if metrics.At(i).Name() == name {
+			return metrics.At(i)
+		}
+	}
+	t.Fatalf("metric %s not found", name)
+	return pmetric.NewMetric()
+}
@@ -0,0 +1,561 @@
+package diagnose
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file defines the NetworkDiagnostics handler: active probes against configured targets (TCP connect, DNS
+// resolution through the system resolver and specific servers, TLS handshake inspection and HTTP requests with a
+// timing breakdown) and passive inspection of the local network stack from /proc/net (socket states, TCP
+// retransmits and interface errors).
+//
+// Usage:
+//   - Instantiate with NewNetworkDiagnostics, providing a logger and NetworkOptions (usually diagnostics.network).
+//   - Call Run to probe every target concurrently and produce a Report.
+//
+// Best Practices:
+//   - Keep probe timeouts well below --timeout; probes run in parallel but the run waits for the slowest one.
+//   - Per-target timings are measurements named "<metric>/<target>" so 'diagnose diff' and --watch can track them.
+
+// Defaults and thresholds of network diagnostics.
+const (
+	DefaultProbeTimeout      = 5 * time.Second
+	DefaultCertExpiryWarning = 30 * 24 * time.Hour
+	DefaultCertExpiryCrit    = 7 * 24 * time.Hour
+
+	// networkProbeConcurrency bounds the number of probes in flight.
+	networkProbeConcurrency = 8
+	// closeWaitWarn is the number of CLOSE_WAIT sockets above which a leak is suspected.
+	closeWaitWarn = 100
+	// retransWarnRatio is the retransmitted/sent TCP segment ratio above which retransmits are flagged.
+	retransWarnRatio = 0.02
+	// ifaceErrorWarnRatio is the errored/total packet ratio above which an interface is flagged.
+	ifaceErrorWarnRatio = 0.001
+)
+
+// tcpStates names the socket states of /proc/net/tcp (include/net/tcp_states.h).
+var tcpStates = map[string]string{
+	"01": "established", "02": "syn_sent", "03": "syn_recv", "04": "fin_wait1", "05": "fin_wait2",
+	"06": "time_wait", "07": "close", "08": "close_wait", "09": "last_ack", "0A": "listen", "0B": "closing",
+}
+
+// systemResolver is the resolver reported as "system"; patchable in tests.
+var systemResolver = net.DefaultResolver
+
+// NetworkOptions configures a network diagnostics run.
+//
+// Fields:
+//   - Timeout: Timeout of each probe; defaults to DefaultProbeTimeout.
+//   - TCP: host:port targets for TCP connect probes.
+//   - DNSNames: Names to resolve.
+//   - DNSServers: DNS servers (host or host:port) queried in addition to the system resolver.
+//   - TLS: host[:port] targets for TLS handshake inspection (port 443 by default).
+//   - HTTP: URLs for HTTP probes.
+//   - CertExpiryWarning, CertExpiryCritical: Remaining certificate validity that raises a warning or critical finding.
+//   - RootCAs: Trust anchors for TLS and HTTPS probes; nil uses the system pool.
+//   - SkipLocal: Skip the /proc/net inspection.
+type NetworkOptions struct {
+	Timeout            time.Duration
+	TCP                []string
+	DNSNames           []string
+	DNSServers         []string
+	TLS                []string
+	HTTP               []string
+	CertExpiryWarning  time.Duration
+	CertExpiryCritical time.Duration
+	RootCAs            *x509.CertPool
+	SkipLocal          bool
+}
+
+// NetworkDiagnostics handles network connectivity and local network stack diagnostics.
+//
+// Usage:
+//   - Instantiate with NewNetworkDiagnostics, providing a logger and options.
+//   - Call Run to execute network diagnostics.
+type NetworkDiagnostics struct {
+	logger *core.Logger
+	opts   NetworkOptions
+	now    func() time.Time
+}
+
+// NewNetworkDiagnostics creates a new network diagnostics handler.
+//
+// Parameters:
+//   - logger: Logger for status and error reporting.
+//   - opts: Probe targets, timeouts and thresholds.
+//
+// Returns:
+//   - *NetworkDiagnostics: A new network diagnostics handler.
+func NewNetworkDiagnostics(logger *core.Logger, opts NetworkOptions) *NetworkDiagnostics {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultProbeTimeout
+	}
+	if opts.CertExpiryWarning <= 0 {
+		opts.CertExpiryWarning = DefaultCertExpiryWarning
+	}
+	if opts.CertExpiryCritical <= 0 {
+		opts.CertExpiryCritical = DefaultCertExpiryCrit
+	}
+	return &NetworkDiagnostics{logger: logger, opts: opts, now: time.Now}
+}
+
+// probeResult collects the findings and measurements of one probe.
+type probeResult struct {
+	findings     []Finding
+	measurements map[string]float64
+}
+
+// add appends a finding.
+func (p *probeResult) add(f Finding) { p.findings = append(p.findings, f) }
+
+// measure records a per-target measurement named "<metric>/<target>".
+func (p *probeResult) measure(metric, target string, v float64) {
+	if p.measurements == nil {
+		p.measurements = map[string]float64{}
+	}
+	p.measurements[metric+"/"+target] = v
+}
+
+// Run executes network diagnostics.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts.
+//
+// Returns:
+//   - *Report: Findings and timings of every probe, plus local socket, retransmit and interface statistics.
+//   - error: If the run is cancelled, returns the context error.
+func (d *NetworkDiagnostics) Run(ctx context.Context) (*Report, error) {
+	d.logger.Info("Running network diagnostics",
+		core.ZapInt("tcp", len(d.opts.TCP)), core.ZapInt("dns", len(d.opts.DNSNames)),
+		core.ZapInt("tls", len(d.opts.TLS)), core.ZapInt("http", len(d.opts.HTTP)))
+	report := NewReport("network")
+
+	var probes []func(context.Context) probeResult
+	for _, target := range d.opts.TCP {
+		probes = append(probes, func(ctx context.Context) probeResult { return d.probeTCP(ctx, target) })
+	}
+	for _, name := range d.opts.DNSNames {
+		probes = append(probes, func(ctx context.Context) probeResult { return d.probeDNS(ctx, name) })
+	}
+	for _, target := range d.opts.TLS {
+		probes = append(probes, func(ctx context.Context) probeResult { return d.probeTLS(ctx, target) })
+	}
+	for _, target := range d.opts.HTTP {
+		probes = append(probes, func(ctx context.Context) probeResult { return d.probeHTTP(ctx, target) })
+	}
+
+	results := make([]probeResult, len(probes))
+	sem := make(chan struct{}, networkProbeConcurrency)
+	var wg sync.WaitGroup
+	for i, probe := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			probeCtx, cancel := context.WithTimeout(ctx, d.opts.Timeout)
+			defer cancel()
+			results[i] = probe(probeCtx)
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		for _, f := range r.findings {
+			report.Add(f)
+		}
+		for name, v := range r.measurements {
+			report.SetMeasurement(name, v)
+		}
+	}
+	if !d.opts.SkipLocal {
+		d.checkLocal(report)
+	}
+	report.Finish()
+	return report, nil
+}
+
+// probeTCP connects to a host:port target.
+func (d *NetworkDiagnostics) probeTCP(ctx context.Context, target string) probeResult {
+	var res probeResult
+	resource := "tcp/" + target
+	start := time.Now()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", target)
+	if err != nil {
+		res.add(Finding{Check: "net.tcp.connect", Severity: SeverityCritical, Resource: resource,
+			Message: fmt.Sprintf("TCP connect to %s failed: %v", target, err)})
+		return res
+	}
+	elapsed := time.Since(start)
+	_ = conn.Close()
+	res.measure("net.tcp.connect_ms", target, millis(elapsed))
+	return res
+}
+
+// probeDNS resolves a name through the system resolver and every configured server, and flags differing answers.
+func (d *NetworkDiagnostics) probeDNS(ctx context.Context, name string) probeResult {
+	var res probeResult
+	type namedResolver struct {
+		label    string
+		resolver *net.Resolver
+	}
+	resolvers := []namedResolver{{"system", systemResolver}}
+	for _, server := range d.opts.DNSServers {
+		addr := server
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			addr = net.JoinHostPort(server, "53")
+		}
+		resolvers = append(resolvers, namedResolver{server, &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		}})
+	}
+
+	answers := map[string]string{}
+	for _, r := range resolvers {
+		resource := "dns/" + name + "@" + r.label
+		start := time.Now()
+		addrs, err := r.resolver.LookupHost(ctx, name)
+		if err != nil {
+			res.add(Finding{Check: "net.dns.resolve", Severity: SeverityCritical, Resource: resource,
+				Message: fmt.Sprintf("resolving %s via %s failed: %v", name, r.label, err)})
+			continue
+		}
+		res.measure("net.dns.lookup_ms", name+"@"+r.label, millis(time.Since(start)))
+		sort.Strings(addrs)
+		answers[r.label] = strings.Join(addrs, ",")
+	}
+	distinct := map[string]bool{}
+	for _, a := range answers {
+		distinct[a] = true
+	}
+	if len(distinct) > 1 {
+		details := make(map[string]string, len(answers))
+		for label, a := range answers {
+			details[label] = a
+		}
+		res.add(Finding{Check: "net.dns.mismatch", Severity: SeverityWarning, Resource: "dns/" + name,
+			Message: fmt.Sprintf("resolvers return different answers for %s", name), Details: details})
+	}
+	return res
+}
+
+// probeTLS performs a TLS handshake and inspects the protocol, cipher and certificate chain.
+func (d *NetworkDiagnostics) probeTLS(ctx context.Context, target string) probeResult {
+	var res probeResult
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		target = net.JoinHostPort(target, "443")
+	}
+	host, _, _ := net.SplitHostPort(target)
+	resource := "tls/" + target
+
+	start := time.Now()
+	// Verification is done below so that certificates can be inspected even when the chain is invalid.
+	cfg := &tls.Config{InsecureSkipVerify: true}
+	if net.ParseIP(host) == nil {
+		cfg.ServerName = host
+	}
+	dialer := &tls.Dialer{Config: cfg}
+	conn, err := dialer.DialContext(ctx, "tcp", target)
+	if err != nil {
+		res.add(Finding{Check: "net.tls.handshake", Severity: SeverityCritical, Resource: resource,
+			Message: fmt.Sprintf("TLS handshake with %s failed: %v", target, err)})
+		return res
+	}
+	defer conn.Close()
+	res.measure("net.tls.handshake_ms", target, millis(time.Since(start)))
+	state := conn.(*tls.Conn).ConnectionState()
+	d.inspectTLS(&res, resource, target, host, state)
+	return res
+}
+
+// inspectTLS reports the protocol, cipher, chain, SANs and expiry of a TLS connection and verifies its chain.
+func (d *NetworkDiagnostics) inspectTLS(res *probeResult, resource, target, host string, state tls.ConnectionState) {
+	if len(state.PeerCertificates) == 0 {
+		res.add(Finding{Check: "net.tls.handshake", Severity: SeverityCritical, Resource: resource,
+			Message: fmt.Sprintf("%s presented no certificate", target)})
+		return
+	}
+	leaf := state.PeerCertificates[0]
+	chain := make([]string, len(state.PeerCertificates))
+	for i, c := range state.PeerCertificates {
+		chain[i] = c.Subject.String()
+	}
+	sans := append([]string(nil), leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	protocol := tls.VersionName(state.Version)
+	res.add(Finding{Check: "net.tls.handshake", Severity: SeverityInfo, Resource: resource,
+		Message: fmt.Sprintf("%s %s, certificate %q valid until %s", protocol, tls.CipherSuiteName(state.CipherSuite),
+			leaf.Subject.CommonName, leaf.NotAfter.UTC().Format(time.RFC3339)),
+		Details: map[string]string{
+			"protocol":  protocol,
+			"cipher":    tls.CipherSuiteName(state.CipherSuite),
+			"chain":     strings.Join(chain, " <- "),
+			"sans":      strings.Join(sans, ","),
+			"issuer":    leaf.Issuer.String(),
+			"not_after": leaf.NotAfter.UTC().Format(time.RFC3339),
+		}})
+	if state.Version < tls.VersionTLS12 {
+		res.add(Finding{Check: "net.tls.protocol", Severity: SeverityWarning, Resource: resource,
+			Message: fmt.Sprintf("%s negotiated %s; TLS 1.2 or later is expected", target, protocol)})
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range state.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+	now := d.now()
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: d.opts.RootCAs, Intermediates: intermediates, CurrentTime: now}); err != nil {
+		res.add(Finding{Check: "net.tls.verify", Severity: SeverityCritical, Resource: resource,
+			Message: fmt.Sprintf("certificate of %s does not verify: %v", target, err)})
+	}
+
+	// The earliest expiry in the chain is what breaks clients first.
+	expiring := leaf
+	for _, c := range state.PeerCertificates[1:] {
+		if c.NotAfter.Before(expiring.NotAfter) {
+			expiring = c
+		}
+	}
+	left := expiring.NotAfter.Sub(now)
+	res.measure("net.tls.cert_days_left", target, left.Hours()/24)
+	var sev Severity
+	switch {
+	case left < d.opts.CertExpiryCritical:
+		sev = SeverityCritical
+	case left < d.opts.CertExpiryWarning:
+		sev = SeverityWarning
+	}
+	if sev != "" {
+		msg := fmt.Sprintf("certificate %q of %s expires in %d day(s)", expiring.Subject.CommonName, target, int(left.Hours()/24))
+		if left <= 0 {
+			msg = fmt.Sprintf("certificate %q of %s expired on %s", expiring.Subject.CommonName, target,
+				expiring.NotAfter.UTC().Format(time.RFC3339))
+		}
+		res.add(Finding{Check: "net.tls.expiry", Severity: sev, Resource: resource, Message: msg,
+			Details: map[string]string{"not_after": expiring.NotAfter.UTC().Format(time.RFC3339)}})
+	}
+}
+
+// probeHTTP sends a GET request and records the DNS, connect, TLS, time-to-first-byte and total timings.
+func (d *NetworkDiagnostics) probeHTTP(ctx context.Context, target string) probeResult {
+	var res probeResult
+	resource := "http/" + target
+	var start, dnsStart, connStart, tlsStart time.Time
+	timings := map[string]time.Duration{}
+	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:           func(httptrace.DNSDoneInfo) { timings["dns"] = time.Since(dnsStart) },
+		ConnectStart:      func(string, string) { connStart = time.Now() },
+		ConnectDone:       func(string, string, error) { timings["connect"] = time.Since(connStart) },
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { timings["tls"] = time.Since(tlsStart) },
+		GotFirstResponseByte: func() {
+			timings["ttfb"] = time.Since(start)
+		},
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, target, nil)
+	if err != nil {
+		res.add(Finding{Check: "net.http.probe", Severity: SeverityCritical, Resource: resource,
+			Message: fmt.Sprintf("invalid HTTP target %q: %v", target, err)})
+		return res
+	}
+	transport := &http.Transport{
+		Proxy:             http.ProxyFromEnvironment,
+		DisableKeepAlives: true,
+		TLSClientConfig:   &tls.Config{RootCAs: d.opts.RootCAs, MinVersion: tls.VersionTLS12},
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{Transport: transport}
+
+	start = time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		res.add(Finding{Check: "net.http.probe", Severity: SeverityCritical, Resource: resource,
+			Message: fmt.Sprintf("GET %s failed: %v", target, err)})
+		return res
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	_ = resp.Body.Close()
+	timings["total"] = time.Since(start)
+
+	details := map[string]string{"status": resp.Status}
+	for _, phase := range []string{"dns", "connect", "tls", "ttfb", "total"} {
+		if t, ok := timings[phase]; ok {
+			res.measure("net.http."+phase+"_ms", target, millis(t))
+			details[phase+"_ms"] = strconv.FormatFloat(millis(t), 'f', 1, 64)
+		}
+	}
+	res.measure("net.http.status", target, float64(resp.StatusCode))
+	sev := SeverityInfo
+	switch {
+	case resp.StatusCode >= 500:
+		sev = SeverityCritical
+	case resp.StatusCode >= 400:
+		sev = SeverityWarning
+	}
+	res.add(Finding{Check: "net.http.probe", Severity: sev, Resource: resource,
+		Message: fmt.Sprintf("GET %s returned %s in %s", target, resp.Status, timings["total"].Round(time.Millisecond)),
+		Details: details})
+	return res
+}
+
+// checkLocal inspects socket states, TCP retransmits and interface errors from /proc/net.
+func (d *NetworkDiagnostics) checkLocal(report *Report) {
+	states := map[string]int{}
+	for _, proto := range []string{"tcp", "tcp6"} {
+		f, err := os.Open(hostPath("/proc/net/" + proto))
+		if err != nil {
+			continue
+		}
+		countSocketStates(f, states)
+		f.Close()
+	}
+	for _, state := range tcpStates {
+		report.SetMeasurement("net.sockets."+state, float64(states[state]))
+	}
+	if n := states["close_wait"]; n > closeWaitWarn {
+		report.Add(Finding{Check: "net.sockets.close_wait", Severity: SeverityWarning, Resource: "host",
+			Message: fmt.Sprintf("%d sockets in CLOSE_WAIT; an application is not closing its connections", n)})
+	}
+
+	if f, err := os.Open(hostPath("/proc/net/snmp")); err == nil {
+		tcp := parseSNMP(f)["Tcp"]
+		f.Close()
+		retrans, sent := tcp["RetransSegs"], tcp["OutSegs"]
+		report.SetMeasurement("net.tcp.retrans_segs", retrans)
+		report.SetMeasurement("net.tcp.out_segs", sent)
+		if sent > 0 {
+			ratio := retrans / sent
+			report.SetMeasurement("net.tcp.retrans_ratio", ratio)
+			if ratio > retransWarnRatio {
+				report.Add(Finding{Check: "net.tcp.retransmits", Severity: SeverityWarning, Resource: "host",
+					Message: fmt.Sprintf("%.1f%% of TCP segments were retransmitted since boot", ratio*100)})
+			}
+		}
+	}
+
+	if f, err := os.Open(hostPath("/proc/net/dev")); err == nil {
+		ifaces := parseNetDev(f)
+		f.Close()
+		names := make([]string, 0, len(ifaces))
+		for name := range ifaces {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			s := ifaces[name]
+			report.SetMeasurement("net.if."+name+".rx_errors", float64(s.rxErrors))
+			report.SetMeasurement("net.if."+name+".rx_dropped", float64(s.rxDropped))
+			report.SetMeasurement("net.if."+name+".tx_errors", float64(s.txErrors))
+			report.SetMeasurement("net.if."+name+".tx_dropped", float64(s.txDropped))
+			bad := s.rxErrors + s.rxDropped + s.txErrors + s.txDropped
+			total := s.rxPackets + s.txPackets
+			if total > 0 && float64(bad)/float64(total) > ifaceErrorWarnRatio {
+				report.Add(Finding{Check: "net.if.errors", Severity: SeverityWarning, Resource: "iface/" + name,
+					Message: fmt.Sprintf("%s: %d errors and %d drops in %d packets since boot", name,
+						s.rxErrors+s.txErrors, s.rxDropped+s.txDropped, total)})
+			}
+		}
+	}
+}
+
+// countSocketStates adds the per-state socket counts of a /proc/net/tcp[6] table to counts.
+func countSocketStates(r io.Reader, counts map[string]int) {
+	sc := bufio.NewScanner(r)
+	for first := true; sc.Scan(); first = false {
+		fields := strings.Fields(sc.Text())
+		if first || len(fields) < 4 {
+			continue
+		}
+		if state, ok := tcpStates[fields[3]]; ok {
+			counts[state]++
+		}
+	}
+}
+
+// parseSNMP parses /proc/net/snmp into protocol -> counter -> value.
+func parseSNMP(r io.Reader) map[string]map[string]float64 {
+	out := map[string]map[string]float64{}
+	headers := map[string][]string{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		proto, rest, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		// Each protocol has a header line followed by a value line.
+		header, seen := headers[proto]
+		if !seen {
+			headers[proto] = fields
+			continue
+		}
+		values := map[string]float64{}
+		for i, name := range header {
+			if i < len(fields) {
+				v, _ := strconv.ParseFloat(fields[i], 64)
+				values[name] = v
+			}
+		}
+		out[proto] = values
+	}
+	return out
+}
+
+// ifaceStats are the /proc/net/dev counters used by network diagnostics.
+type ifaceStats struct {
+	rxPackets, rxErrors, rxDropped uint64
+	txPackets, txErrors, txDropped uint64
+}
+
+// parseNetDev parses /proc/net/dev into per-interface counters.
+func parseNetDev(r io.Reader) map[string]ifaceStats {
+	out := map[string]ifaceStats{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		name, rest, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue
+		}
+		f := strings.Fields(rest)
+		if len(f) < 12 {
+			continue
+		}
+		n := func(i int) uint64 { v, _ := strconv.ParseUint(f[i], 10, 64); return v }
+		out[strings.TrimSpace(name)] = ifaceStats{
+			rxPackets: n(1), rxErrors: n(2), rxDropped: n(3),
+			txPackets: n(9), txErrors: n(10), txDropped: n(11),
+		}
+	}
+	return out
+}
+
+// millis converts a duration to fractional milliseconds.
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
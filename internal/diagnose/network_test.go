@@ -0,0 +1,253 @@
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// startDNSStub serves A queries for every name with addr over UDP; other types get an empty answer.
+func startDNSStub(t *testing.T, addr string) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { pc.Close() })
+	ip := net.ParseIP(addr).To4()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var msg dnsmessage.Message
+			if err := msg.Unpack(buf[:n]); err != nil || len(msg.Questions) == 0 {
+				continue
+			}
+			q := msg.Questions[0]
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: msg.ID, Response: true, Authoritative: true, RecursionAvailable: true},
+				Questions: msg.Questions,
+			}
+			if q.Type == dnsmessage.TypeA {
+				var a [4]byte
+				copy(a[:], ip)
+				resp.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &dnsmessage.AResource{A: a},
+				}}
+			}
+			out, err := resp.Pack()
+			if err != nil {
+				continue
+			}
+			_, _ = pc.WriteTo(out, from)
+		}
+	}()
+	return pc.LocalAddr().String()
+}
+
+func newNetworkTest(opts NetworkOptions) *NetworkDiagnostics {
+	opts.SkipLocal = true
+	opts.Timeout = 3 * time.Second
+	return NewNetworkDiagnostics(core.NewTestLogger(&bytes.Buffer{}), opts)
+}
+
+func TestNetworkDiagnostics_TCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	r, err := newNetworkTest(NetworkOptions{TCP: []string{ln.Addr().String(), closedAddr}}).Run(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, r.Measurements, "net.tcp.connect_ms/"+ln.Addr().String())
+	failed := findingsByCheck(r, "net.tcp.connect")
+	require.Len(t, failed, 1)
+	assert.Equal(t, SeverityCritical, failed[0].Severity)
+	assert.Equal(t, "tcp/"+closedAddr, failed[0].Resource)
+}
+
+func TestNetworkDiagnostics_DNS(t *testing.T) {
+	good := startDNSStub(t, "10.0.0.1")
+	other := startDNSStub(t, "10.0.0.2")
+	orig := systemResolver
+	systemResolver = &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, good)
+	}}
+	defer func() { systemResolver = orig }()
+
+	name := "svc.srediag.test."
+	r, err := newNetworkTest(NetworkOptions{DNSNames: []string{name}, DNSServers: []string{good, other}}).Run(context.Background())
+	require.NoError(t, err)
+	for _, label := range []string{"system", good, other} {
+		assert.Contains(t, r.Measurements, "net.dns.lookup_ms/"+name+"@"+label)
+	}
+	assert.Empty(t, findingsByCheck(r, "net.dns.resolve"))
+	require.Len(t, findingsByCheck(r, "net.dns.mismatch"), 1)
+	mismatch := findingsByCheck(r, "net.dns.mismatch")[0]
+	assert.Equal(t, SeverityWarning, mismatch.Severity)
+	assert.Equal(t, "10.0.0.1", mismatch.Details["system"])
+	assert.Equal(t, "10.0.0.2", mismatch.Details[other])
+
+	r, err = newNetworkTest(NetworkOptions{DNSNames: []string{name}, DNSServers: []string{good}}).Run(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, r.Findings, "identical answers are not reported")
+}
+
+func TestNetworkDiagnostics_TLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	defer srv.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	target := srv.Listener.Addr().String()
+
+	d := newNetworkTest(NetworkOptions{TLS: []string{target}, RootCAs: roots})
+	r, err := d.Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, findingsByCheck(r, "net.tls.handshake"), 1)
+	hs := findingsByCheck(r, "net.tls.handshake")[0]
+	assert.Equal(t, SeverityInfo, hs.Severity)
+	assert.Equal(t, "TLS 1.3", hs.Details["protocol"])
+	assert.Contains(t, hs.Details["sans"], "127.0.0.1")
+	assert.NotEmpty(t, hs.Details["cipher"])
+	assert.Empty(t, findingsByCheck(r, "net.tls.verify"))
+	assert.Empty(t, findingsByCheck(r, "net.tls.expiry"))
+	assert.Contains(t, r.Measurements, "net.tls.handshake_ms/"+target)
+	assert.Greater(t, r.Measurements["net.tls.cert_days_left/"+target], 365.0)
+
+	// Three days before expiry the certificate still verifies but is critical.
+	notAfter := srv.Certificate().NotAfter
+	d.now = func() time.Time { return notAfter.Add(-72 * time.Hour) }
+	r, err = d.Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, findingsByCheck(r, "net.tls.expiry"), 1)
+	assert.Equal(t, SeverityCritical, findingsByCheck(r, "net.tls.expiry")[0].Severity)
+	assert.Contains(t, findingsByCheck(r, "net.tls.expiry")[0].Message, "expires in 3 day(s)")
+	assert.Empty(t, findingsByCheck(r, "net.tls.verify"))
+
+	// Without the test CA the chain does not verify.
+	r, err = newNetworkTest(NetworkOptions{TLS: []string{target}}).Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, findingsByCheck(r, "net.tls.verify"), 1)
+}
+
+func TestNetworkDiagnostics_HTTP(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer secure.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(secure.Certificate())
+
+	r, err := newNetworkTest(NetworkOptions{HTTP: []string{failing.URL, secure.URL}, RootCAs: roots}).Run(context.Background())
+	require.NoError(t, err)
+	probes := findingsByCheck(r, "net.http.probe")
+	require.Len(t, probes, 2)
+	assert.Equal(t, SeverityCritical, probes[0].Severity)
+	assert.Equal(t, "500 Internal Server Error", probes[0].Details["status"])
+	assert.Equal(t, SeverityInfo, probes[1].Severity)
+
+	assert.Equal(t, 500.0, r.Measurements["net.http.status/"+failing.URL])
+	for _, phase := range []string{"connect", "ttfb", "total"} {
+		assert.Contains(t, r.Measurements, "net.http."+phase+"_ms/"+failing.URL)
+	}
+	assert.NotContains(t, r.Measurements, "net.http.tls_ms/"+failing.URL)
+	assert.Contains(t, r.Measurements, "net.http.tls_ms/"+secure.URL)
+}
+
+func TestNetworkDiagnostics_Local(t *testing.T) {
+	tcp := []string{"  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode"}
+	for i := 0; i < closeWaitWarn+1; i++ {
+		tcp = append(tcp, fmt.Sprintf("%4d: 0100007F:1F90 0100007F:%04X 08 00000000:00000000 00:00000000 00000000  1000 0 %d", i, 40000+i, 1000+i))
+	}
+	tcp = append(tcp, " 200: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0 0 1")
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"proc/net/tcp": strings.Join(tcp, "\n") + "\n",
+		"proc/net/snmp": `Ip: Forwarding DefaultTTL
+Ip: 1 64
+Tcp: RtoAlgorithm ActiveOpens OutSegs RetransSegs
+Tcp: 1 10 1000 50
+Udp: InDatagrams OutDatagrams
+Udp: 5 5
+`,
+		"proc/net/dev": `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo: 1000 10 0 0 0 0 0 0 1000 10 0 0 0 0 0 0
+  eth0: 9000 1000 7 3 0 0 0 0 9000 1000 0 0 0 0 0 0
+`,
+	})
+	orig := hostRoot
+	hostRoot = root
+	defer func() { hostRoot = orig }()
+
+	d := NewNetworkDiagnostics(core.NewTestLogger(&bytes.Buffer{}), NetworkOptions{})
+	r, err := d.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, float64(closeWaitWarn+1), r.Measurements["net.sockets.close_wait"])
+	assert.Equal(t, 1.0, r.Measurements["net.sockets.listen"])
+	assert.Equal(t, 0.0, r.Measurements["net.sockets.established"])
+	assert.Equal(t, 50.0, r.Measurements["net.tcp.retrans_segs"])
+	assert.InDelta(t, 0.05, r.Measurements["net.tcp.retrans_ratio"], 1e-9)
+	assert.Equal(t, 7.0, r.Measurements["net.if.eth0.rx_errors"])
+	assert.Equal(t, 3.0, r.Measurements["net.if.eth0.rx_dropped"])
+
+	assert.Len(t, findingsByCheck(r, "net.sockets.close_wait"), 1)
+	assert.Len(t, findingsByCheck(r, "net.tcp.retransmits"), 1)
+	require.Len(t, findingsByCheck(r, "net.if.errors"), 1)
+	assert.Equal(t, "iface/eth0", findingsByCheck(r, "net.if.errors")[0].Resource)
+}
+
+func TestNetworkOptions(t *testing.T) {
+	cmd := &cobra.Command{Use: "network"}
+	cmd.Flags().StringSlice("tcp", nil, "")
+	cmd.Flags().StringSlice("dns", nil, "")
+	cmd.Flags().StringSlice("dns-server", nil, "")
+	cmd.Flags().StringSlice("tls", nil, "")
+	cmd.Flags().StringSlice("http", nil, "")
+	cmd.Flags().Duration("probe-timeout", DefaultProbeTimeout, "")
+	cmd.Flags().Bool("skip-local", false, "")
+
+	ctx := &core.AppContext{Logger: core.NewTestLogger(&bytes.Buffer{}), Config: core.NewConfig()}
+	cfg := &ctx.Config.Diagnostics.Network
+	cfg.TCP = []string{"db:5432"}
+	cfg.DNS.Names = []string{"example.com"}
+	cfg.Timeout = "2s"
+	cfg.CertExpiryWarning = "240h"
+	require.NoError(t, cmd.Flags().Set("tcp", "cache:6379"))
+	require.NoError(t, cmd.Flags().Set("skip-local", "true"))
+
+	opts, err := networkOptions(ctx, cmd)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cache:6379"}, opts.TCP, "flags override the config")
+	assert.Equal(t, []string{"example.com"}, opts.DNSNames)
+	assert.Equal(t, 2*time.Second, opts.Timeout)
+	assert.Equal(t, 240*time.Hour, opts.CertExpiryWarning)
+	assert.True(t, opts.SkipLocal)
+
+	cfg.CertExpiryCritical = "soon"
+	_, err = networkOptions(ctx, cmd)
+	assert.ErrorContains(t, err, "diagnostics.network.cert_expiry_critical")
+}
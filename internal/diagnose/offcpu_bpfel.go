@@ -0,0 +1,150 @@
+// Code generated by bpf2go; DO NOT EDIT.
+//go:build (386 || amd64 || arm || arm64 || loong64 || mips64le || mipsle || ppc64le || riscv64) && linux
+
+package diagnose
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+
+	"github.com/cilium/ebpf"
+)
+
+type offcpuOffcpuStats struct {
+	Total           uint64
+	Switches        uint64
+	Uninterruptible uint64
+}
+
+type offcpuSwitchOut struct {
+	Ts              uint64
+	Uninterruptible uint64
+}
+
+// loadOffcpu returns the embedded CollectionSpec for offcpu.
+func loadOffcpu() (*ebpf.CollectionSpec, error) {
+	reader := bytes.NewReader(_OffcpuBytes)
+	spec, err := ebpf.LoadCollectionSpecFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't load offcpu: %w", err)
+	}
+
+	return spec, err
+}
+
+// loadOffcpuObjects loads offcpu and converts it into a struct.
+//
+// The following types are suitable as obj argument:
+//
+//	*offcpuObjects
+//	*offcpuPrograms
+//	*offcpuMaps
+//
+// See ebpf.CollectionSpec.LoadAndAssign documentation for details.
+func loadOffcpuObjects(obj interface{}, opts *ebpf.CollectionOptions) error {
+	spec, err := loadOffcpu()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+
+// offcpuSpecs contains maps and programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type offcpuSpecs struct {
+	offcpuProgramSpecs
+	offcpuMapSpecs
+	offcpuVariableSpecs
+}
+
+// offcpuProgramSpecs contains programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type offcpuProgramSpecs struct {
+	SchedSwitch *ebpf.ProgramSpec `ebpf:"sched_switch"`
+}
+
+// offcpuMapSpecs contains maps before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type offcpuMapSpecs struct {
+	Hist  *ebpf.MapSpec `ebpf:"hist"`
+	Start *ebpf.MapSpec `ebpf:"start"`
+	Stats *ebpf.MapSpec `ebpf:"stats"`
+}
+
+// offcpuVariableSpecs contains global variables before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type offcpuVariableSpecs struct {
+}
+
+// offcpuObjects contains all objects after they have been loaded into the kernel.
+//
+// It can be passed to loadOffcpuObjects or ebpf.CollectionSpec.LoadAndAssign.
+type offcpuObjects struct {
+	offcpuPrograms
+	offcpuMaps
+	offcpuVariables
+}
+
+func (o *offcpuObjects) Close() error {
+	return _OffcpuClose(
+		&o.offcpuPrograms,
+		&o.offcpuMaps,
+	)
+}
+
+// offcpuMaps contains all maps after they have been loaded into the kernel.
+//
+// It can be passed to loadOffcpuObjects or ebpf.CollectionSpec.LoadAndAssign.
+type offcpuMaps struct {
+	Hist  *ebpf.Map `ebpf:"hist"`
+	Start *ebpf.Map `ebpf:"start"`
+	Stats *ebpf.Map `ebpf:"stats"`
+}
+
+func (m *offcpuMaps) Close() error {
+	return _OffcpuClose(
+		m.Hist,
+		m.Start,
+		m.Stats,
+	)
+}
+
+// offcpuVariables contains all global variables after they have been loaded into the kernel.
+//
+// It can be passed to loadOffcpuObjects or ebpf.CollectionSpec.LoadAndAssign.
+type offcpuVariables struct {
+}
+
+// offcpuPrograms contains all programs after they have been loaded into the kernel.
+//
+// It can be passed to loadOffcpuObjects or ebpf.CollectionSpec.LoadAndAssign.
+type offcpuPrograms struct {
+	SchedSwitch *ebpf.Program `ebpf:"sched_switch"`
+}
+
+func (p *offcpuPrograms) Close() error {
+	return _OffcpuClose(
+		p.SchedSwitch,
+	)
+}
+
+func _OffcpuClose(closers ...io.Closer) error {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do not access this directly.
+//
+//go:embed offcpu_bpfel.o
+var _OffcpuBytes []byte
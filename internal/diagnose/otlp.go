@@ -0,0 +1,251 @@
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file implements the OTLP client used to ship diagnostics telemetry: run metrics (ExportMetrics), and
+// findings and measurements (ExportReport, DiagExporter).
+//
+// Supported export targets:
+//   - otlp://host:port    OTLP/gRPC without TLS
+//   - otlps://host:port   OTLP/gRPC with TLS
+//   - http(s)://host/base OTLP/HTTP (protobuf); "/v1/metrics" or "/v1/logs" is appended to the base path
+//   - file:///path, /path Prometheus textfile (node_exporter textfile collector format), written atomically
+//
+// Best Practices:
+//   - Keep the export timeout short; a slow collector must not hold up CI jobs.
+
+// exportTimeout bounds a single export request.
+const exportTimeout = 10 * time.Second
+
+// scopeName is the instrumentation scope of telemetry produced by diagnostics.
+const scopeName = "github.com/srediag/srediag/internal/diagnose"
+
+// ExportMetrics writes the metrics held by r to target.
+//
+// Parameters:
+//   - ctx: Context for cancellation.
+//   - target: Export target (see the file header); empty disables export.
+//   - r: Recorder to export.
+//
+// Returns:
+//   - error: If the target is invalid or the export fails, returns a detailed error.
+func ExportMetrics(ctx context.Context, target string, r *Recorder) error {
+	if target == "" || r == nil {
+		return nil
+	}
+	u, err := parseExportTarget(target)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "file" {
+		return writePrometheusFile(u.Path, r)
+	}
+
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	putResourceAttributes(rm.Resource())
+	sm := rm.ScopeMetrics().AppendEmpty()
+	setScope(sm.Scope())
+	r.AppendTo(sm, time.Now())
+
+	client, err := dialOTLP(u)
+	if err != nil {
+		return err
+	}
+	defer client.close()
+	ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+	defer cancel()
+	return client.exportMetrics(ctx, md)
+}
+
+// otlpClient sends OTLP requests to a single otlp://, otlps:// or http(s):// endpoint.
+type otlpClient struct {
+	target *url.URL
+	conn   *grpc.ClientConn // nil for OTLP/HTTP
+}
+
+// newOTLPClient creates a client for an OTLP endpoint; file targets are rejected.
+func newOTLPClient(target string) (*otlpClient, error) {
+	u, err := parseExportTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "file" {
+		return nil, fmt.Errorf("invalid export target %q: expected otlp://, otlps://, http:// or https://", target)
+	}
+	return dialOTLP(u)
+}
+
+// dialOTLP creates the gRPC connection for otlp:// and otlps:// targets; the connection is established lazily.
+func dialOTLP(u *url.URL) (*otlpClient, error) {
+	c := &otlpClient{target: u}
+	if u.Scheme != "otlp" && u.Scheme != "otlps" {
+		return c, nil
+	}
+	creds := insecure.NewCredentials()
+	if u.Scheme == "otlps" {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	conn, err := grpc.NewClient(u.Host, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", u.Host, err)
+	}
+	c.conn = conn
+	return c, nil
+}
+
+// exportMetrics sends md to the endpoint.
+func (c *otlpClient) exportMetrics(ctx context.Context, md pmetric.Metrics) error {
+	req := pmetricotlp.NewExportRequestFromMetrics(md)
+	if c.conn != nil {
+		if _, err := pmetricotlp.NewGRPCClient(c.conn).Export(ctx, req); err != nil {
+			return fmt.Errorf("failed to export metrics to %s: %w", c.target.Host, err)
+		}
+		return nil
+	}
+	body, err := req.MarshalProto()
+	if err != nil {
+		return fmt.Errorf("failed to encode metrics: %w", err)
+	}
+	return postOTLP(ctx, c.target, "/v1/metrics", body)
+}
+
+// exportLogs sends ld to the endpoint.
+func (c *otlpClient) exportLogs(ctx context.Context, ld plog.Logs) error {
+	req := plogotlp.NewExportRequestFromLogs(ld)
+	if c.conn != nil {
+		if _, err := plogotlp.NewGRPCClient(c.conn).Export(ctx, req); err != nil {
+			return fmt.Errorf("failed to export logs to %s: %w", c.target.Host, err)
+		}
+		return nil
+	}
+	body, err := req.MarshalProto()
+	if err != nil {
+		return fmt.Errorf("failed to encode logs: %w", err)
+	}
+	return postOTLP(ctx, c.target, "/v1/logs", body)
+}
+
+// close releases the gRPC connection, if any.
+func (c *otlpClient) close() {
+	if c.conn != nil {
+		_ = c.conn.Close()
+	}
+}
+
+// parseExportTarget validates an export target and normalises plain paths to file:// URLs.
+func parseExportTarget(target string) (*url.URL, error) {
+	if !strings.Contains(target, "://") {
+		abs, err := filepath.Abs(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid export target %q: %w", target, err)
+		}
+		return &url.URL{Scheme: "file", Path: abs}, nil
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid export target %q: %w", target, err)
+	}
+	switch u.Scheme {
+	case "file":
+		if u.Path == "" {
+			return nil, fmt.Errorf("invalid export target %q: missing path", target)
+		}
+	case "otlp", "otlps", "http", "https":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid export target %q: missing host", target)
+		}
+	default:
+		return nil, fmt.Errorf("invalid export target %q: unsupported scheme %q", target, u.Scheme)
+	}
+	return u, nil
+}
+
+// putResourceAttributes sets the host and process attributes identifying this srediag instance.
+func putResourceAttributes(res pcommon.Resource) {
+	attrs := res.Attributes()
+	attrs.PutStr("service.name", "srediag")
+	attrs.PutStr("service.version", core.DefaultBuildInfo.Version)
+	attrs.PutStr("os.type", runtime.GOOS)
+	attrs.PutStr("host.arch", runtime.GOARCH)
+	if host, err := os.Hostname(); err == nil {
+		attrs.PutStr("host.name", host)
+	}
+}
+
+// setScope sets the instrumentation scope of diagnostics telemetry.
+func setScope(scope pcommon.InstrumentationScope) {
+	scope.SetName(scopeName)
+	scope.SetVersion(core.DefaultBuildInfo.Version)
+}
+
+// postOTLP sends an OTLP/HTTP protobuf request to the target base path joined with signalPath.
+func postOTLP(ctx context.Context, u *url.URL, signalPath string, body []byte) error {
+	endpoint := *u
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/") + signalPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build OTLP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export to %s: %w", endpoint.Redacted(), err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to export to %s: %s: %s", endpoint.Redacted(), resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// writePrometheusFile writes r to path via a temporary file and rename, so scrapers never see a partial file.
+func writePrometheusFile(path string, r *Recorder) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create metrics file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if err := r.WritePrometheus(tmp); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	return nil
+}
@@ -2,17 +2,21 @@ package diagnose
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/srediag/srediag/internal/core"
 )
 
 // Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
 //
-// This file defines the PerformanceDiagnostics handler for performance-related diagnostics.
+// This file defines the PerformanceDiagnostics handler for performance-related diagnostics. With
+// PerformanceOptions.EBPF set it runs the time-boxed eBPF probes of ebpf.go and turns the trace into findings.
 //
 // Usage:
 //   - Use PerformanceDiagnostics to run performance diagnostics for the system.
-//   - Instantiate with NewPerformanceDiagnostics, providing a logger.
+//   - Instantiate with NewPerformanceDiagnostics, providing a logger and options.
 //   - Call Run to execute diagnostics.
 //
 // Best Practices:
@@ -20,40 +24,63 @@ import (
 //   - Use logger for all error and status reporting.
 //
 // TODO:
-//   - Implement actual performance diagnostics logic.
-//   - Add context.Context support for cancellation and timeouts.
+//   - Add sampling (non-eBPF) CPU and memory profiling.
 
 // PerformanceDiagnostics handles performance-related diagnostics.
 //
 // Usage:
-//   - Instantiate with NewPerformanceDiagnostics, providing a logger.
+//   - Instantiate with NewPerformanceDiagnostics, providing a logger and options.
 //   - Call Run to execute performance diagnostics.
 type PerformanceDiagnostics struct {
 	logger *core.Logger
+	opts   PerformanceOptions
 }
 
 // NewPerformanceDiagnostics creates a new performance diagnostics handler.
 //
 // Parameters:
 //   - logger: Logger for status and error reporting.
+//   - opts: eBPF tracing options; the zero value runs no probes.
 //
 // Returns:
 //   - *PerformanceDiagnostics: A new performance diagnostics handler.
-func NewPerformanceDiagnostics(logger *core.Logger) *PerformanceDiagnostics {
+func NewPerformanceDiagnostics(logger *core.Logger, opts PerformanceOptions) *PerformanceDiagnostics {
 	return &PerformanceDiagnostics{
 		logger: logger,
+		opts:   opts,
 	}
 }
 
 // Run executes performance diagnostics.
 //
 // Parameters:
-//   - ctx: Context for cancellation and timeouts.
+//   - ctx: Context for cancellation and timeouts; the trace window is shortened to fit its deadline.
 //
 // Returns:
-//   - error: If diagnostics fail, returns a detailed error.
-func (d *PerformanceDiagnostics) Run(ctx context.Context) error {
+//   - *Report: The performance diagnostics report; an unusable eBPF host yields a perf.ebpf.unsupported finding.
+//   - error: If the probe selection is invalid or tracing fails unexpectedly, returns a detailed error.
+func (d *PerformanceDiagnostics) Run(ctx context.Context) (*Report, error) {
 	d.logger.Info("Running performance diagnostics")
-	// TODO: Implement performance diagnostics
-	return nil
+	report := NewReport("performance")
+	if d.opts.EBPF {
+		probes, err := selectedProbes(d.opts.Probes)
+		if err != nil {
+			return nil, err
+		}
+		window := traceWindow(ctx, d.opts.Duration)
+		d.logger.Info("Tracing with eBPF", core.ZapString("probes", strings.Join(probes, ",")),
+			core.ZapString("duration", window.String()))
+		res, err := traceEBPF(ctx, probes, window)
+		switch {
+		case errors.Is(err, ErrEBPFUnsupported):
+			d.logger.Warn("eBPF tracing unsupported", core.ZapError(err))
+			report.Add(unsupportedFinding("ebpf", strings.TrimPrefix(err.Error(), ErrEBPFUnsupported.Error()+": ")))
+		case err != nil:
+			return nil, fmt.Errorf("eBPF tracing failed: %w", err)
+		default:
+			addTraceFindings(report, res, probes, d.opts.Top)
+		}
+	}
+	report.Finish()
+	return report, nil
 }
@@ -1,3 +1,251 @@
-// TODO: Implement DiagPlugin interface contract (Register, Capabilities, Health) (see docs/architecture/diagnose.md §1)
-// TODO: Enforce plugin registration via private Cobra branch (see docs/architecture/diagnose.md §1)
 package diagnose
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file defines the DiagPlugin contract (docs/architecture/diagnose.md §1) and AttachPlugins, which injects each
+// plugin's Cobra subcommands under 'srediag diagnose'.
+//
+// Usage:
+//   - Built-in diagnostics are wrapped with NewBuiltinPlugin; installed ones are found with DiscoverInstalledPlugins.
+//   - The CLI passes all plugins to AttachPlugins once, while building the command tree.
+//
+// Best Practices:
+//   - Register only adds subcommands to the private branch it receives; it must not add flags to the branch itself
+//     or redefine global (root persistent) flags.
+//   - Report every capability the plugin exercises so RBAC can authorize it.
+
+// pluginAnnotation is the Cobra annotation key holding the name of the plugin that contributed a command.
+const pluginAnnotation = "srediag.io/diag-plugin"
+
+// PluginInfo identifies a diagnostic plugin.
+//
+// Fields:
+//   - Name: Unique plugin identifier (e.g., "systemsnapshot").
+//   - Version: Plugin version.
+//   - Description: Human-readable summary.
+//   - Source: "builtin" or "installed".
+type PluginInfo struct {
+	Name        string `json:"name" yaml:"name"`
+	Version     string `json:"version" yaml:"version"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Source      string `json:"source" yaml:"source"`
+}
+
+// Plugin sources reported in PluginInfo.Source.
+const (
+	PluginSourceBuiltin   = "builtin"
+	PluginSourceInstalled = "installed"
+)
+
+// DiagPlugin is the contract every diagnostic plugin implements.
+//
+// Usage:
+//   - Implementations are attached to the CLI with AttachPlugins.
+type DiagPlugin interface {
+	// Info returns the plugin identity.
+	Info() PluginInfo
+	// Register adds the plugin's subcommands to branch, a private Cobra command that is not part of the CLI tree.
+	//
+	// Parameters:
+	//   - branch: Private Cobra branch; its children are moved under 'srediag diagnose' after validation.
+	//
+	// Returns:
+	//   - error: If the plugin cannot register its commands, returns a detailed error.
+	Register(branch *cobra.Command) error
+	// Capabilities returns the RBAC capabilities the plugin exercises (e.g., "diag/system").
+	Capabilities() []string
+	// Health reports whether the plugin is ready to run.
+	//
+	// Parameters:
+	//   - ctx: Context for cancellation and timeouts.
+	//
+	// Returns:
+	//   - error: nil if healthy; otherwise a detailed error.
+	Health(ctx context.Context) error
+}
+
+// AttachPlugins validates each plugin's private branch and moves its subcommands under parent.
+//
+// A plugin is rejected as a whole (and reported in the returned errors) if Register fails, it adds flags to its
+// branch, one of its commands collides with an existing subcommand name or alias, one of its flags shadows a
+// reserved (global) flag, or its capabilities are invalid. Attached commands authorize every plugin capability
+// with the "execute" verb before running and export the srediag_diag_* metrics when they finish.
+//
+// Parameters:
+//   - ctx: Application context; capabilities are registered with ctx.GetRBAC() and authorized against ctx.Config.
+//   - parent: The 'diagnose' command.
+//   - reserved: Global flags plugins must not redefine (may be nil).
+//   - plugins: Plugins to attach, in order; earlier plugins win name collisions.
+//
+// Returns:
+//   - []PluginInfo: Plugins that were attached.
+//   - []error: One error per rejected plugin.
+func AttachPlugins(ctx *core.AppContext, parent *cobra.Command, reserved *pflag.FlagSet, plugins []DiagPlugin) ([]PluginInfo, []error) {
+	var attached []PluginInfo
+	var errs []error
+	for _, p := range plugins {
+		info := p.Info()
+		if err := attachPlugin(ctx, parent, reserved, p, info); err != nil {
+			errs = append(errs, fmt.Errorf("diagnostic plugin %q rejected: %w", info.Name, err))
+			continue
+		}
+		attached = append(attached, info)
+	}
+	return attached, errs
+}
+
+// attachPlugin registers a single plugin; it mutates parent only once every check has passed.
+func attachPlugin(ctx *core.AppContext, parent *cobra.Command, reserved *pflag.FlagSet, p DiagPlugin, info PluginInfo) error {
+	if info.Name == "" {
+		return fmt.Errorf("plugin name is empty")
+	}
+	branch := &cobra.Command{Use: info.Name}
+	if err := p.Register(branch); err != nil {
+		return fmt.Errorf("register failed: %w", err)
+	}
+	if branch.Flags().HasFlags() || branch.PersistentFlags().HasFlags() {
+		return fmt.Errorf("plugins must not add flags to their branch")
+	}
+	children := branch.Commands()
+	if len(children) == 0 {
+		return fmt.Errorf("plugin registered no commands")
+	}
+	taken := make(map[string]string)
+	for _, c := range parent.Commands() {
+		for _, n := range append([]string{c.Name()}, c.Aliases...) {
+			taken[n] = c.Name()
+		}
+	}
+	for _, child := range children {
+		for _, n := range append([]string{child.Name()}, child.Aliases...) {
+			if owner, ok := taken[n]; ok {
+				return fmt.Errorf("command %q collides with existing command %q", n, owner)
+			}
+			taken[n] = child.Name()
+		}
+		if err := checkReservedFlags(child, reserved); err != nil {
+			return err
+		}
+	}
+	capabilities := p.Capabilities()
+	if err := ctx.GetRBAC().RegisterCapabilities(info.Name, capabilities); err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		branch.RemoveCommand(child)
+		walkCommands(child, func(c *cobra.Command) {
+			if c.Annotations == nil {
+				c.Annotations = map[string]string{}
+			}
+			c.Annotations[pluginAnnotation] = info.Name
+			wrapCommand(ctx, c, capabilities)
+		})
+		parent.AddCommand(child)
+	}
+	return nil
+}
+
+// checkReservedFlags returns an error if any command in the subtree redefines a reserved flag name or shorthand.
+func checkReservedFlags(root *cobra.Command, reserved *pflag.FlagSet) error {
+	if reserved == nil {
+		return nil
+	}
+	var err error
+	walkCommands(root, func(c *cobra.Command) {
+		for _, fs := range []*pflag.FlagSet{c.Flags(), c.PersistentFlags()} {
+			fs.VisitAll(func(f *pflag.Flag) {
+				if err != nil {
+					return
+				}
+				if reserved.Lookup(f.Name) != nil {
+					err = fmt.Errorf("command %q redefines global flag --%s", c.CommandPath(), f.Name)
+				} else if f.Shorthand != "" && reserved.ShorthandLookup(f.Shorthand) != nil {
+					err = fmt.Errorf("command %q redefines global flag -%s", c.CommandPath(), f.Shorthand)
+				}
+			})
+		}
+	})
+	return err
+}
+
+// wrapCommand wraps a runnable command so it authorizes the plugin capabilities before running and exports the
+// diagnostics run metrics (--metrics-export) after it.
+func wrapCommand(ctx *core.AppContext, c *cobra.Command, capabilities []string) {
+	run, runE := c.Run, c.RunE
+	if run == nil && runE == nil {
+		return
+	}
+	c.Run = nil
+	c.RunE = func(cmd *cobra.Command, args []string) error {
+		security := ctx.GetConfig().Security
+		for _, capability := range capabilities {
+			if err := ctx.GetRBAC().Authorize(security, "", "execute", capability); err != nil {
+				return err
+			}
+		}
+		var err error
+		if runE != nil {
+			err = runE(cmd, args)
+		} else {
+			run(cmd, args)
+		}
+		return exportRunMetrics(ctx, cmd, err)
+	}
+}
+
+// walkCommands calls fn for c and every descendant.
+func walkCommands(c *cobra.Command, fn func(*cobra.Command)) {
+	fn(c)
+	for _, child := range c.Commands() {
+		walkCommands(child, fn)
+	}
+}
+
+// PluginCommandsHelp renders the "Available diagnostic types" section of 'srediag diagnose --help'.
+//
+// Parameters:
+//   - parent: The 'diagnose' command after AttachPlugins.
+//   - plugins: Attached plugins, used to annotate each command with its source.
+//
+// Returns:
+//   - string: One line per diagnostic subcommand, sorted by name.
+func PluginCommandsHelp(parent *cobra.Command, plugins []PluginInfo) string {
+	byName := make(map[string]PluginInfo, len(plugins))
+	for _, p := range plugins {
+		byName[p.Name] = p
+	}
+	cmds := append([]*cobra.Command(nil), parent.Commands()...)
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name() < cmds[j].Name() })
+	var b strings.Builder
+	for _, c := range cmds {
+		owner, ok := c.Annotations[pluginAnnotation]
+		if !ok {
+			continue
+		}
+		line := fmt.Sprintf("  - %s: %s", c.Name(), c.Short)
+		if p, ok := byName[owner]; ok && p.Source == PluginSourceInstalled {
+			line += fmt.Sprintf(" [%s %s, installed]", p.Name, p.Version)
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// IsPluginCommand reports whether cmd was contributed by a diagnostic plugin.
+func IsPluginCommand(cmd *cobra.Command) bool {
+	_, ok := cmd.Annotations[pluginAnnotation]
+	return ok
+}
@@ -0,0 +1,187 @@
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// fakePlugin is a DiagPlugin whose Register is supplied by the test.
+type fakePlugin struct {
+	name     string
+	caps     []string
+	register func(branch *cobra.Command) error
+}
+
+func (p *fakePlugin) Info() PluginInfo                     { return PluginInfo{Name: p.name, Source: PluginSourceBuiltin} }
+func (p *fakePlugin) Register(branch *cobra.Command) error { return p.register(branch) }
+func (p *fakePlugin) Capabilities() []string               { return p.caps }
+func (p *fakePlugin) Health(context.Context) error         { return nil }
+
+func reservedFlags() *pflag.FlagSet {
+	fs := pflag.NewFlagSet("global", pflag.ContinueOnError)
+	fs.StringP("output", "o", "table", "")
+	return fs
+}
+
+func addCmd(name string, ran *bool) func(*cobra.Command) error {
+	return func(branch *cobra.Command) error {
+		branch.AddCommand(&cobra.Command{Use: name, Short: name + " check", Run: func(*cobra.Command, []string) { *ran = true }})
+		return nil
+	}
+}
+
+func TestAttachPlugins_Validation(t *testing.T) {
+	ctx := &core.AppContext{}
+	parent := &cobra.Command{Use: "diagnose"}
+	var ran bool
+
+	plugins := []DiagPlugin{
+		&fakePlugin{name: "good", caps: []string{"diag/good"}, register: addCmd("good", &ran)},
+		&fakePlugin{name: "dup", caps: []string{"diag/dup"}, register: addCmd("good", &ran)},
+		&fakePlugin{name: "global", caps: []string{"diag/x"}, register: func(b *cobra.Command) error {
+			c := &cobra.Command{Use: "x", Run: func(*cobra.Command, []string) {}}
+			c.Flags().String("output", "", "")
+			b.AddCommand(c)
+			return nil
+		}},
+		&fakePlugin{name: "branchflag", caps: []string{"diag/y"}, register: func(b *cobra.Command) error {
+			b.PersistentFlags().Bool("verbose", false, "")
+			b.AddCommand(&cobra.Command{Use: "y", Run: func(*cobra.Command, []string) {}})
+			return nil
+		}},
+		&fakePlugin{name: "badcap", caps: []string{"diag bad"}, register: addCmd("z", &ran)},
+		&fakePlugin{name: "failing", register: func(*cobra.Command) error { return errors.New("boom") }},
+	}
+	attached, errs := AttachPlugins(ctx, parent, reservedFlags(), plugins)
+	require.Len(t, attached, 1)
+	assert.Equal(t, "good", attached[0].Name)
+	assert.Len(t, errs, 5)
+
+	var names []string
+	for _, c := range parent.Commands() {
+		names = append(names, c.Name())
+	}
+	assert.Equal(t, []string{"good"}, names, "rejected plugins must not leave commands behind")
+	assert.Equal(t, map[string][]string{"diag/good": {"good"}}, ctx.GetRBAC().Capabilities())
+	assert.Contains(t, PluginCommandsHelp(parent, attached), "good: good check")
+
+	parent.SetArgs([]string{"good"})
+	require.NoError(t, parent.Execute())
+	assert.True(t, ran)
+}
+
+func TestAttachPlugins_RBAC(t *testing.T) {
+	cfg := core.NewConfig()
+	cfg.Security.RBAC.Enabled = true
+	cfg.Security.RBAC.DefaultRole = "viewer"
+	ctx := &core.AppContext{Config: cfg}
+	parent := &cobra.Command{Use: "diagnose", SilenceUsage: true, SilenceErrors: true}
+	var ran bool
+	_, errs := AttachPlugins(ctx, parent, nil, []DiagPlugin{
+		&fakePlugin{name: "sys", caps: []string{"diag/system"}, register: addCmd("system", &ran)},
+	})
+	require.Empty(t, errs)
+
+	parent.SetArgs([]string{"system"})
+	err := parent.Execute()
+	require.Error(t, err)
+	assert.ErrorIs(t, err, core.ErrAccessDenied)
+	assert.False(t, ran)
+
+	cfg.Security.RBAC.DefaultRole = "operator"
+	parent.SetArgs([]string{"system"})
+	require.NoError(t, parent.Execute())
+	assert.True(t, ran)
+}
+
+func writePlugin(t *testing.T, pluginsDir, name, manifest, script string) {
+	t.Helper()
+	dir := filepath.Join(pluginsDir, "diagnostics", name)
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(manifest), 0o600))
+	if script != "" {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755))
+	}
+}
+
+func TestDiscoverInstalledPlugins(t *testing.T) {
+	pluginsDir := t.TempDir()
+	script := "#!/bin/sh\necho \"$SREDIAG_DIAG_PLUGIN $*\"\n"
+	sum := sha256.Sum256([]byte(script))
+	writePlugin(t, pluginsDir, "fsmonitor", fmt.Sprintf(`name: fsmonitor
+type: diagnostic
+version: 0.2.0
+entrypoint: ./fsmonitor
+sha256: %s
+capabilities: [diag/fs]
+commands:
+  - name: inode-usage
+    short: Report inode usage
+`, hex.EncodeToString(sum[:])), script)
+	writePlugin(t, pluginsDir, "wrongname", "name: other\ntype: diagnostic\nentrypoint: ./x\n", "")
+	writePlugin(t, pluginsDir, "escape", "name: escape\ntype: diagnostic\nentrypoint: ../../outside\n", "")
+	writePlugin(t, pluginsDir, "unknownkey", "name: unknownkey\ntype: diagnostic\nentrypoint: ./x\nfoo: bar\n", "")
+
+	plugins, errs := DiscoverInstalledPlugins(pluginsDir)
+	assert.Len(t, errs, 3)
+	require.Len(t, plugins, 1)
+	info := plugins[0].Info()
+	assert.Equal(t, "fsmonitor", info.Name)
+	assert.Equal(t, PluginSourceInstalled, info.Source)
+	require.NoError(t, plugins[0].Health(context.Background()))
+
+	ctx := &core.AppContext{}
+	parent := &cobra.Command{Use: "diagnose"}
+	attached, attachErrs := AttachPlugins(ctx, parent, reservedFlags(), plugins)
+	require.Empty(t, attachErrs)
+	assert.Contains(t, PluginCommandsHelp(parent, attached), "inode-usage: Report inode usage [fsmonitor 0.2.0, installed]")
+
+	var out bytes.Buffer
+	parent.SetOut(&out)
+	parent.SetArgs([]string{"inode-usage", "--threshold", "90", "-o", "json"})
+	require.NoError(t, parent.Execute())
+	assert.Equal(t, "fsmonitor inode-usage --threshold 90 -o json\n", out.String())
+}
+
+func TestInstalledPlugin_ChecksumMismatch(t *testing.T) {
+	pluginsDir := t.TempDir()
+	writePlugin(t, pluginsDir, "tampered", `name: tampered
+type: diagnostic
+entrypoint: ./tampered
+sha256: 0000000000000000000000000000000000000000000000000000000000000000
+`, "#!/bin/sh\necho ran\n")
+
+	plugins, errs := DiscoverInstalledPlugins(pluginsDir)
+	require.Empty(t, errs)
+	require.Len(t, plugins, 1)
+	assert.ErrorContains(t, plugins[0].Health(context.Background()), "checksum mismatch")
+
+	parent := &cobra.Command{Use: "diagnose", SilenceUsage: true, SilenceErrors: true}
+	_, attachErrs := AttachPlugins(&core.AppContext{}, parent, nil, plugins)
+	require.Empty(t, attachErrs)
+	var out bytes.Buffer
+	parent.SetOut(&out)
+	parent.SetArgs([]string{"tampered"})
+	assert.ErrorContains(t, parent.Execute(), "unhealthy")
+	assert.Empty(t, out.String())
+}
+
+func TestDiscoverInstalledPlugins_MissingDir(t *testing.T) {
+	plugins, errs := DiscoverInstalledPlugins(filepath.Join(t.TempDir(), "missing"))
+	assert.Empty(t, plugins)
+	assert.Empty(t, errs)
+}
@@ -0,0 +1,270 @@
+package diagnose
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file defines the canonical report model (Report, Finding, Severity) shared by every diagnostic, and the
+// renderers that turn a Report into table, JSON, YAML, or SARIF output.
+//
+// Usage:
+//   - Diagnostics create a Report with NewReport, append findings with Add, and record raw values with SetMeasurement.
+//   - CLI entrypoints render reports with WriteReport using the resolved output format.
+//
+// Best Practices:
+//   - Use stable Check identifiers (e.g., "k8s.node.ready") so reports can be compared across runs.
+//   - Keep Message human-readable; put machine-readable context in Details.
+
+// Severity classifies how urgent a finding is.
+type Severity string
+
+const (
+	// SeverityInfo marks an informational finding that needs no action.
+	SeverityInfo Severity = "info"
+	// SeverityWarning marks a finding that may degrade reliability and should be reviewed.
+	SeverityWarning Severity = "warning"
+	// SeverityCritical marks a finding that is actively causing or about to cause an outage.
+	SeverityCritical Severity = "critical"
+)
+
+// Rank returns the ordering weight of the severity (higher is more severe).
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityCritical:
+		return 3
+	case SeverityWarning:
+		return 2
+	case SeverityInfo:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ParseSeverity converts a string (case-insensitive) into a Severity.
+//
+// Parameters:
+//   - s: Severity name ("info", "warning"/"warn", "critical"/"crit").
+//
+// Returns:
+//   - Severity: The parsed severity.
+//   - error: If the name is unknown, returns a detailed error.
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "info":
+		return SeverityInfo, nil
+	case "warning", "warn":
+		return SeverityWarning, nil
+	case "critical", "crit":
+		return SeverityCritical, nil
+	default:
+		return "", fmt.Errorf("unknown severity %q (expected info, warning, or critical)", s)
+	}
+}
+
+// Location points at the source line a finding refers to (used by static analyzers).
+//
+// Fields:
+//   - File: Path of the analyzed file, relative to the scan root when possible.
+//   - Line: 1-based line number; 0 when unknown.
+type Location struct {
+	File string `json:"file" yaml:"file"`
+	Line int    `json:"line,omitempty" yaml:"line,omitempty"`
+}
+
+// String renders the location as "file:line".
+func (l Location) String() string {
+	if l.Line > 0 {
+		return fmt.Sprintf("%s:%d", l.File, l.Line)
+	}
+	return l.File
+}
+
+// Finding is a single observation produced by a diagnostic check.
+//
+// Fields:
+//   - Check: Stable identifier of the check that produced the finding.
+//   - Severity: How urgent the finding is.
+//   - Resource: The affected object (e.g., "node/worker-1", "pod/default/api-0").
+//   - Message: Human-readable description.
+//   - Details: Optional machine-readable key/value context.
+//   - Location: Optional source location (file and line) for static analysis findings.
+type Finding struct {
+	Check    string            `json:"check" yaml:"check"`
+	Severity Severity          `json:"severity" yaml:"severity"`
+	Resource string            `json:"resource,omitempty" yaml:"resource,omitempty"`
+	Message  string            `json:"message" yaml:"message"`
+	Details  map[string]string `json:"details,omitempty" yaml:"details,omitempty"`
+	Location *Location         `json:"location,omitempty" yaml:"location,omitempty"`
+}
+
+// Report is the result of a single diagnostic run.
+//
+// Fields:
+//   - Diagnostic: Name of the diagnostic that produced the report (e.g., "kubernetes").
+//   - StartedAt: Wall-clock time the run started.
+//   - Duration: Wall-clock duration of the run.
+//   - Findings: Observations produced by the run.
+//   - Measurements: Raw numeric values collected during the run, keyed by metric name.
+//   - Inventory: Host state compared by 'diagnose diff', keyed by section (see inventory.go), then by item.
+type Report struct {
+	Diagnostic   string                       `json:"diagnostic" yaml:"diagnostic"`
+	StartedAt    time.Time                    `json:"started_at" yaml:"started_at"`
+	Duration     time.Duration                `json:"duration" yaml:"duration"`
+	Findings     []Finding                    `json:"findings" yaml:"findings"`
+	Measurements map[string]float64           `json:"measurements,omitempty" yaml:"measurements,omitempty"`
+	Inventory    map[string]map[string]string `json:"inventory,omitempty" yaml:"inventory,omitempty"`
+}
+
+// NewReport creates an empty report for the named diagnostic, stamped with the current time.
+//
+// Parameters:
+//   - diagnostic: Name of the diagnostic producing the report.
+//
+// Returns:
+//   - *Report: A new, empty Report.
+func NewReport(diagnostic string) *Report {
+	return &Report{
+		Diagnostic: diagnostic,
+		StartedAt:  time.Now().UTC(),
+		Findings:   []Finding{},
+	}
+}
+
+// Add appends a finding to the report.
+func (r *Report) Add(f Finding) {
+	r.Findings = append(r.Findings, f)
+}
+
+// SetMeasurement records a raw numeric value on the report.
+func (r *Report) SetMeasurement(name string, value float64) {
+	if r.Measurements == nil {
+		r.Measurements = make(map[string]float64)
+	}
+	r.Measurements[name] = value
+}
+
+// Finish stamps the report duration relative to StartedAt and sorts findings by severity.
+func (r *Report) Finish() {
+	r.Duration = time.Since(r.StartedAt)
+	sort.SliceStable(r.Findings, func(i, j int) bool {
+		return r.Findings[i].Severity.Rank() > r.Findings[j].Severity.Rank()
+	})
+}
+
+// MaxSeverity returns the highest severity among the report's findings, or "" if there are none.
+func (r *Report) MaxSeverity() Severity {
+	var max Severity
+	for _, f := range r.Findings {
+		if f.Severity.Rank() > max.Rank() {
+			max = f.Severity
+		}
+	}
+	return max
+}
+
+// WriteReport renders a report in the requested format.
+//
+// Parameters:
+//   - w: Destination writer.
+//   - r: Report to render.
+//   - format: One of "table" (default), "json", "yaml", or "sarif".
+//
+// Returns:
+//   - error: If the format is unknown or encoding fails, returns a detailed error.
+func WriteReport(w io.Writer, r *Report, format string) error {
+	switch strings.ToLower(format) {
+	case "", "table":
+		return writeReportTable(w, r)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("failed to encode report as YAML: %w", err)
+		}
+		return enc.Close()
+	case "sarif":
+		return writeReportSARIF(w, r)
+	default:
+		return fmt.Errorf("unsupported output format %q (expected table, json, yaml, or sarif)", format)
+	}
+}
+
+// writeReportTable renders a report as an aligned, human-readable table.
+func writeReportTable(w io.Writer, r *Report) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "DIAGNOSTIC: %s\tSTARTED: %s\tDURATION: %s\n\n",
+		r.Diagnostic, r.StartedAt.Format(time.RFC3339), r.Duration.Round(time.Millisecond))
+	if len(r.Findings) == 0 {
+		fmt.Fprintln(tw, "No findings.")
+	} else {
+		fmt.Fprintln(tw, "SEVERITY\tCHECK\tRESOURCE\tMESSAGE")
+		for _, f := range r.Findings {
+			resource := f.Resource
+			if f.Location != nil {
+				resource = strings.TrimSpace(resource + " " + f.Location.String())
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", strings.ToUpper(string(f.Severity)), f.Check, resource, f.Message)
+		}
+	}
+	if len(r.Inventory) > 0 {
+		sections := make([]string, 0, len(r.Inventory))
+		for section := range r.Inventory {
+			sections = append(sections, section)
+		}
+		sort.Strings(sections)
+		fmt.Fprintln(tw, "\nINVENTORY\tITEMS")
+		for _, section := range sections {
+			fmt.Fprintf(tw, "%s\t%d\n", section, len(r.Inventory[section]))
+		}
+	}
+	if len(r.Measurements) > 0 {
+		names := make([]string, 0, len(r.Measurements))
+		for name := range r.Measurements {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintln(tw, "\nMEASUREMENT\tVALUE")
+		for _, name := range names {
+			fmt.Fprintf(tw, "%s\t%g\n", name, r.Measurements[name])
+		}
+	}
+	return tw.Flush()
+}
+
+// writeReportOutput renders a report to the configured destination (stdout or --output-file).
+//
+// Parameters:
+//   - w: Default destination (usually the command's stdout).
+//   - r: Report to render.
+//   - format: Output format (see WriteReport).
+//   - outputFile: Optional file path; when set, output is written there instead of w.
+//
+// Returns:
+//   - error: If the file cannot be created or rendering fails, returns a detailed error.
+func writeReportOutput(w io.Writer, r *Report, format, outputFile string) error {
+	if outputFile == "" {
+		return WriteReport(w, r, format)
+	}
+	f, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create output file %s: %w", outputFile, err)
+	}
+	defer f.Close()
+	return WriteReport(f, r, format)
+}
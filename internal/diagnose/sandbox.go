@@ -1,3 +1,215 @@
-// TODO: Enforce sandbox/resource caps: seccomp, mem, cpu, file IO (see docs/architecture/diagnose.md §4)
-// TODO: Increment srediag_diag_sandbox_violations_total on violations (see docs/architecture/diagnose.md §4, §8)
 package diagnose
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file defines the SandboxExecutor used by the remote JobRunner: every job runs as a child
+// 'srediag diagnose <diagnostic>' process in its own process group and working directory, under a wall-clock timeout
+// and, on Linux, RLIMIT_DATA/RLIMIT_CPU caps (sandbox_linux.go). Cap violations surface as *SandboxError (ErrSandbox)
+// and are counted in srediag_diag_sandbox_violations_total by the runner.
+//
+// Usage:
+//   - Instantiate with NewSandboxExecutor and pass it to NewJobRunner.
+//
+// Best Practices:
+//   - Size MemoryMiB for the largest remote diagnostic (support bundles); the Go runtime aborts when it hits the cap.
+
+// TODO: Enforce seccomp and file IO caps on the child process (see docs/architecture/diagnose.md §4)
+
+// Artifact files written by the SandboxExecutor into the job directory.
+const (
+	JobReportFile = "report.json"
+	JobLogFile    = "stderr.log"
+)
+
+// SandboxLimits caps a sandboxed diagnostic.
+//
+// Fields:
+//   - Timeout: Wall-clock limit, also passed as --timeout; defaults to DefaultJobTimeout.
+//   - MemoryMiB: Data-segment (heap) limit in MiB; 0 disables it.
+//   - CPUSeconds: CPU-time limit; defaults to the timeout in seconds.
+type SandboxLimits struct {
+	Timeout    time.Duration
+	MemoryMiB  int
+	CPUSeconds int
+}
+
+// SandboxError reports a diagnostic stopped by a sandbox cap; errors.Is(err, ErrSandbox) holds.
+//
+// Fields:
+//   - Resource: The cap that was hit ("memory", "cpu" or "sandbox" when the child reported it itself).
+//   - Detail: Exit status or signal of the child.
+type SandboxError struct {
+	Resource string
+	Detail   string
+}
+
+// Error implements error.
+func (e *SandboxError) Error() string {
+	return fmt.Sprintf("%v: %s limit (%s)", ErrSandbox, e.Resource, e.Detail)
+}
+
+// Is makes errors.Is(err, ErrSandbox) match.
+func (e *SandboxError) Is(target error) bool {
+	return target == ErrSandbox
+}
+
+// sandboxResource returns the cap named by a sandbox error, used as the violation metric label.
+func sandboxResource(err error) string {
+	var se *SandboxError
+	if errors.As(err, &se) {
+		return se.Resource
+	}
+	return "sandbox"
+}
+
+// JobExecutor runs a verified job's diagnostic.
+//
+// Usage:
+//   - Execute writes JobReportFile (and any other artifacts) into dir; tests substitute a fake executor.
+type JobExecutor interface {
+	Execute(ctx context.Context, job *Job, dir string) error
+}
+
+// SandboxExecutor runs jobs as sandboxed srediag child processes.
+type SandboxExecutor struct {
+	binary     string
+	configFile string
+	limits     SandboxLimits
+}
+
+// NewSandboxExecutor creates a SandboxExecutor that re-executes the running srediag binary.
+//
+// Parameters:
+//   - configFile: Config file passed with --config; empty uses the child's default discovery.
+//   - limits: Resource caps.
+//
+// Returns:
+//   - *SandboxExecutor: The executor.
+//   - error: If the running executable cannot be resolved, returns a detailed error.
+func NewSandboxExecutor(configFile string, limits SandboxLimits) (*SandboxExecutor, error) {
+	binary, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve srediag executable: %w", err)
+	}
+	if limits.Timeout <= 0 {
+		limits.Timeout = DefaultJobTimeout
+	}
+	if limits.CPUSeconds <= 0 {
+		limits.CPUSeconds = int(limits.Timeout.Seconds()) + 1
+	}
+	return &SandboxExecutor{binary: binary, configFile: configFile, limits: limits}, nil
+}
+
+// Execute implements JobExecutor.
+//
+// Parameters:
+//   - ctx: Context for cancellation; the timeout is applied on top of it.
+//   - job: Verified job.
+//   - dir: Job directory; receives JobReportFile and JobLogFile.
+//
+// Returns:
+//   - error: nil when the diagnostic completed (findings above --fail-on are not a failure here); *SandboxError when a
+//     cap was hit; ErrTimeout when the timeout expired; otherwise a detailed error.
+func (e *SandboxExecutor) Execute(ctx context.Context, job *Job, dir string) error {
+	ctx, cancel := context.WithTimeout(ctx, e.limits.Timeout)
+	defer cancel()
+
+	var args []string
+	if e.configFile != "" {
+		args = append(args, "--config", e.configFile)
+	}
+	args = append(args, "diagnose", job.Diagnostic, "--output", "json",
+		"--output-file", filepath.Join(dir, JobReportFile), "--timeout", e.limits.Timeout.String())
+	if flag := RemoteDiagnostics[job.Diagnostic].DirFlag; flag != "" {
+		args = append(args, "--"+flag+"="+dir)
+	}
+	args = append(args, job.Args...)
+
+	logFile, err := os.Create(filepath.Join(dir, JobLogFile))
+	if err != nil {
+		return fmt.Errorf("failed to create job log: %w", err)
+	}
+	defer logFile.Close()
+	var tail bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.binary, args...)
+	cmd.Dir = dir
+	cmd.Stdout = logFile
+	cmd.Stderr = &tailWriter{w: logFile, tail: &tail}
+	cmd.Env = append(os.Environ(), "SREDIAG_JOB_ID="+job.ID)
+	if e.limits.MemoryMiB > 0 {
+		// Let the child's GC work harder before it reaches the hard cap.
+		cmd.Env = append(cmd.Env, fmt.Sprintf("GOMEMLIMIT=%dMiB", e.limits.MemoryMiB*9/10))
+	}
+	cmd.WaitDelay = 5 * time.Second
+	prepareSandbox(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start diagnostic: %w", err)
+	}
+	if err := limitProcess(cmd.Process.Pid, e.limits); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return fmt.Errorf("failed to apply sandbox limits: %w", err)
+	}
+	err = cmd.Wait()
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() == context.DeadlineExceeded:
+		return fmt.Errorf("%w after %s", ErrTimeout, e.limits.Timeout)
+	case ctx.Err() != nil:
+		return ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return fmt.Errorf("diagnostic failed: %w", err)
+	}
+	if resource := exceededLimit(exitErr.ProcessState); resource != "" {
+		return &SandboxError{Resource: resource, Detail: exitErr.ProcessState.String()}
+	}
+	if strings.Contains(tail.String(), "out of memory") && e.limits.MemoryMiB > 0 {
+		return &SandboxError{Resource: "memory", Detail: exitErr.ProcessState.String()}
+	}
+	switch exitErr.ExitCode() {
+	case ExitFindings:
+		return nil
+	case ExitSandbox:
+		return &SandboxError{Resource: "sandbox", Detail: exitErr.ProcessState.String()}
+	case ExitTimeout:
+		return fmt.Errorf("%w: %s", ErrTimeout, lastLine(tail.String()))
+	}
+	return fmt.Errorf("diagnostic failed (%s): %s", exitErr.ProcessState, lastLine(tail.String()))
+}
+
+// tailWriter copies to w and keeps the last 4 KiB written.
+type tailWriter struct {
+	w    *os.File
+	tail *bytes.Buffer
+}
+
+// Write implements io.Writer.
+func (t *tailWriter) Write(p []byte) (int, error) {
+	t.tail.Write(p)
+	if over := t.tail.Len() - 4096; over > 0 {
+		t.tail.Next(over)
+	}
+	return t.w.Write(p)
+}
+
+// lastLine returns the last non-empty line of s.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return lines[len(lines)-1]
+}
@@ -0,0 +1,46 @@
+//go:build linux
+
+package diagnose
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// prepareSandbox starts the child in its own process group so that a timeout kills its helpers too.
+func prepareSandbox(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Pdeathsig: syscall.SIGKILL}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// limitProcess applies the memory and CPU caps to a started child. Memory is capped with RLIMIT_DATA rather than
+// RLIMIT_AS: the Go runtime reserves far more address space than it uses and would not even start under RLIMIT_AS.
+func limitProcess(pid int, limits SandboxLimits) error {
+	if limits.MemoryMiB > 0 {
+		mem := uint64(limits.MemoryMiB) << 20
+		if err := unix.Prlimit(pid, unix.RLIMIT_DATA, &unix.Rlimit{Cur: mem, Max: mem}, nil); err != nil {
+			return err
+		}
+	}
+	if limits.CPUSeconds > 0 {
+		cpu := uint64(limits.CPUSeconds)
+		if err := unix.Prlimit(pid, unix.RLIMIT_CPU, &unix.Rlimit{Cur: cpu, Max: cpu + 1}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exceededLimit names the cap that killed the child, if any.
+func exceededLimit(state *os.ProcessState) string {
+	ws, ok := state.Sys().(syscall.WaitStatus)
+	if ok && ws.Signaled() && ws.Signal() == syscall.SIGXCPU {
+		return "cpu"
+	}
+	return ""
+}
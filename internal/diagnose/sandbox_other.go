@@ -0,0 +1,17 @@
+//go:build !linux
+
+package diagnose
+
+import (
+	"os"
+	"os/exec"
+)
+
+// prepareSandbox is a no-op outside Linux.
+func prepareSandbox(*exec.Cmd) {}
+
+// limitProcess is a no-op outside Linux; only the timeout applies.
+func limitProcess(int, SandboxLimits) error { return nil }
+
+// exceededLimit always reports no cap outside Linux.
+func exceededLimit(*os.ProcessState) string { return "" }
@@ -0,0 +1,141 @@
+package diagnose
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file renders reports as SARIF 2.1.0 so static-analysis findings (e.g., IaC) can be uploaded to code review tools.
+//
+// Usage:
+//   - Select with --output sarif; WriteReport dispatches here.
+//
+// Best Practices:
+//   - Populate Finding.Location for every static-analysis finding; results without a location are still emitted
+//     but cannot be annotated inline.
+
+// sarifVersion and sarifSchema identify the SARIF dialect written by writeReportSARIF.
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// sarifLevel maps a Severity to a SARIF result level.
+func sarifLevel(s Severity) string {
+	switch s {
+	case SeverityCritical:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// writeReportSARIF renders a report as a single-run SARIF 2.1.0 log.
+func writeReportSARIF(w io.Writer, r *Report) error {
+	rules := map[string]string{}
+	results := make([]sarifResult, 0, len(r.Findings))
+	for _, f := range r.Findings {
+		if _, ok := rules[f.Check]; !ok {
+			rules[f.Check] = f.Message
+		}
+		res := sarifResult{
+			RuleID:  f.Check,
+			Level:   sarifLevel(f.Severity),
+			Message: sarifMessage{Text: f.Message},
+		}
+		if f.Resource != "" || len(f.Details) > 0 {
+			res.Properties = map[string]string{}
+			for k, v := range f.Details {
+				res.Properties[k] = v
+			}
+			if f.Resource != "" {
+				res.Properties["resource"] = f.Resource
+			}
+		}
+		if f.Location != nil {
+			loc := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(f.Location.File)}}
+			if f.Location.Line > 0 {
+				loc.Region = &sarifRegion{StartLine: f.Location.Line}
+			}
+			res.Locations = []sarifLocation{{PhysicalLocation: loc}}
+		}
+		results = append(results, res)
+	}
+	ids := make([]string, 0, len(rules))
+	for id := range rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	driver := sarifDriver{Name: "srediag", InformationURI: "https://github.com/srediag/srediag", Rules: make([]sarifRule, 0, len(ids))}
+	for _, id := range ids {
+		driver.Rules = append(driver.Rules, sarifRule{ID: id, ShortDescription: sarifMessage{Text: rules[id]}})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	})
+}
@@ -51,9 +51,12 @@ func NewSecurityDiagnostics(logger *core.Logger) *SecurityDiagnostics {
 //   - ctx: Context for cancellation and timeouts.
 //
 // Returns:
+//   - *Report: The security diagnostics report.
 //   - error: If diagnostics fail, returns a detailed error.
-func (d *SecurityDiagnostics) Run(ctx context.Context) error {
+func (d *SecurityDiagnostics) Run(ctx context.Context) (*Report, error) {
 	d.logger.Info("Running security diagnostics")
+	report := NewReport("security")
 	// TODO: Implement security diagnostics
-	return nil
+	report.Finish()
+	return report, nil
 }
@@ -12,8 +12,8 @@ import (
 //
 // Usage:
 //   - Use SystemDiagnostics to run system diagnostics for the host.
-//   - Instantiate with NewSystemDiagnostics, providing a logger.
-//   - Call Run to execute diagnostics.
+//   - Instantiate with NewSystemDiagnostics, providing a logger and the inventory sources.
+//   - Call Run to execute diagnostics; the report carries the host inventory (see inventory.go).
 //
 // Best Practices:
 //   - Always check for errors from Run.
@@ -29,19 +29,22 @@ import (
 //   - Instantiate with NewSystemDiagnostics, providing a logger.
 //   - Call Run to execute system diagnostics.
 type SystemDiagnostics struct {
-	logger *core.Logger
+	logger    *core.Logger
+	inventory InventoryOptions
 }
 
 // NewSystemDiagnostics creates a new system diagnostics handler.
 //
 // Parameters:
 //   - logger: Logger for status and error reporting.
+//   - inventory: Config files and kernel parameters to include in the report inventory.
 //
 // Returns:
 //   - *SystemDiagnostics: A new system diagnostics handler.
-func NewSystemDiagnostics(logger *core.Logger) *SystemDiagnostics {
+func NewSystemDiagnostics(logger *core.Logger, inventory InventoryOptions) *SystemDiagnostics {
 	return &SystemDiagnostics{
-		logger: logger,
+		logger:    logger,
+		inventory: inventory,
 	}
 }
 
@@ -51,9 +54,17 @@ func NewSystemDiagnostics(logger *core.Logger) *SystemDiagnostics {
 //   - ctx: Context for cancellation and timeouts.
 //
 // Returns:
+//   - *Report: The system diagnostics report.
 //   - error: If diagnostics fail, returns a detailed error.
-func (d *SystemDiagnostics) Run(ctx context.Context) error {
+func (d *SystemDiagnostics) Run(ctx context.Context) (*Report, error) {
 	d.logger.Info("Running system diagnostics")
+	report := NewReport("system")
 	// TODO: Implement system diagnostics
-	return nil
+	inventory, errs := CollectInventory(ctx, d.inventory)
+	for _, err := range errs {
+		d.logger.Warn("Inventory source unavailable", core.ZapError(err))
+	}
+	report.Inventory = inventory
+	report.Finish()
+	return report, nil
 }
@@ -0,0 +1,151 @@
+// Code generated by bpf2go; DO NOT EDIT.
+//go:build (mips || mips64 || ppc64 || s390x) && linux
+
+package diagnose
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+
+	"github.com/cilium/ebpf"
+)
+
+type tcpconnectConnectStats struct {
+	Connects uint64
+	Total    uint64
+	Failures uint64
+}
+
+type tcpconnectTcpKey struct {
+	Addr [16]uint8
+	Port uint16
+	Pad  [6]uint8
+}
+
+// loadTcpconnect returns the embedded CollectionSpec for tcpconnect.
+func loadTcpconnect() (*ebpf.CollectionSpec, error) {
+	reader := bytes.NewReader(_TcpconnectBytes)
+	spec, err := ebpf.LoadCollectionSpecFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't load tcpconnect: %w", err)
+	}
+
+	return spec, err
+}
+
+// loadTcpconnectObjects loads tcpconnect and converts it into a struct.
+//
+// The following types are suitable as obj argument:
+//
+//	*tcpconnectObjects
+//	*tcpconnectPrograms
+//	*tcpconnectMaps
+//
+// See ebpf.CollectionSpec.LoadAndAssign documentation for details.
+func loadTcpconnectObjects(obj interface{}, opts *ebpf.CollectionOptions) error {
+	spec, err := loadTcpconnect()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+
+// tcpconnectSpecs contains maps and programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type tcpconnectSpecs struct {
+	tcpconnectProgramSpecs
+	tcpconnectMapSpecs
+	tcpconnectVariableSpecs
+}
+
+// tcpconnectProgramSpecs contains programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type tcpconnectProgramSpecs struct {
+	InetSockSetState *ebpf.ProgramSpec `ebpf:"inet_sock_set_state"`
+}
+
+// tcpconnectMapSpecs contains maps before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type tcpconnectMapSpecs struct {
+	Dests *ebpf.MapSpec `ebpf:"dests"`
+	Hist  *ebpf.MapSpec `ebpf:"hist"`
+	Start *ebpf.MapSpec `ebpf:"start"`
+}
+
+// tcpconnectVariableSpecs contains global variables before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type tcpconnectVariableSpecs struct {
+}
+
+// tcpconnectObjects contains all objects after they have been loaded into the kernel.
+//
+// It can be passed to loadTcpconnectObjects or ebpf.CollectionSpec.LoadAndAssign.
+type tcpconnectObjects struct {
+	tcpconnectPrograms
+	tcpconnectMaps
+	tcpconnectVariables
+}
+
+func (o *tcpconnectObjects) Close() error {
+	return _TcpconnectClose(
+		&o.tcpconnectPrograms,
+		&o.tcpconnectMaps,
+	)
+}
+
+// tcpconnectMaps contains all maps after they have been loaded into the kernel.
+//
+// It can be passed to loadTcpconnectObjects or ebpf.CollectionSpec.LoadAndAssign.
+type tcpconnectMaps struct {
+	Dests *ebpf.Map `ebpf:"dests"`
+	Hist  *ebpf.Map `ebpf:"hist"`
+	Start *ebpf.Map `ebpf:"start"`
+}
+
+func (m *tcpconnectMaps) Close() error {
+	return _TcpconnectClose(
+		m.Dests,
+		m.Hist,
+		m.Start,
+	)
+}
+
+// tcpconnectVariables contains all global variables after they have been loaded into the kernel.
+//
+// It can be passed to loadTcpconnectObjects or ebpf.CollectionSpec.LoadAndAssign.
+type tcpconnectVariables struct {
+}
+
+// tcpconnectPrograms contains all programs after they have been loaded into the kernel.
+//
+// It can be passed to loadTcpconnectObjects or ebpf.CollectionSpec.LoadAndAssign.
+type tcpconnectPrograms struct {
+	InetSockSetState *ebpf.Program `ebpf:"inet_sock_set_state"`
+}
+
+func (p *tcpconnectPrograms) Close() error {
+	return _TcpconnectClose(
+		p.InetSockSetState,
+	)
+}
+
+func _TcpconnectClose(closers ...io.Closer) error {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do not access this directly.
+//
+//go:embed tcpconnect_bpfeb.o
+var _TcpconnectBytes []byte
@@ -0,0 +1,361 @@
+package diagnose
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Package diagnose provides diagnostic operations for SREDIAG, including system, performance, and security diagnostics.
+//
+// This file implements watch mode ('srediag diagnose <type> --watch 5s'): the diagnostic is re-run at a fixed
+// interval and each refresh is rendered as a frame that highlights findings fired and measurements changed since the
+// previous refresh. Frames can be recorded as JSON Lines (--record) and replayed with 'srediag diagnose replay'.
+//
+// Usage:
+//   - CLI entrypoints call runOrWatch, which either runs once or calls Watch.
+//   - Watch stops when ctx is cancelled (Ctrl-C) or after WatchOptions.Duration; both are a clean exit.
+//
+// Best Practices:
+//   - A failed refresh is shown in its frame and does not stop the watch; incidents are when collectors flake.
+//   - Keep recorded frames small: the inventory is not repeated in every frame.
+
+// DefaultWatchInterval is the refresh interval when --watch 0 is given and diagnostics.watch.interval is unset.
+const DefaultWatchInterval = 5 * time.Second
+
+// ANSI sequences used by the terminal UI.
+const (
+	ansiClear  = "\x1b[H\x1b[2J"
+	ansiReset  = "\x1b[0m"
+	ansiNew    = "\x1b[1;31m" // bold red: finding fired since the last refresh
+	ansiChange = "\x1b[33m"   // yellow: measurement changed since the last refresh
+)
+
+// Row markers of the rendered frame; they select the highlight and are kept in plain output.
+const (
+	markNew     = '+'
+	markChanged = '~'
+)
+
+// WatchOptions configures Watch.
+//
+// Fields:
+//   - Interval: Time between refreshes (must be > 0).
+//   - Duration: Total watch time; zero runs until ctx is cancelled.
+//   - Checks: Check and measurement name filters (globs or dotted prefixes); empty keeps everything.
+//   - Out: Destination of rendered frames.
+//   - ANSI: Clear the screen and colour highlights (terminal output).
+//   - Record: Optional JSON Lines destination for the timeline.
+//   - OnFrame: Optional hook called after each frame is rendered (e.g., OTLP export); errors are shown, not fatal.
+type WatchOptions struct {
+	Interval time.Duration
+	Duration time.Duration
+	Checks   []string
+	Out      io.Writer
+	ANSI     bool
+	Record   io.Writer
+	OnFrame  func(ctx context.Context, r *Report) error
+}
+
+// WatchFrame is one refresh of a watch timeline (one line of a --record file).
+//
+// Fields:
+//   - Seq: 1-based refresh number.
+//   - Time: Time the refresh started.
+//   - Interval: Configured refresh interval.
+//   - Report: Filtered report of this refresh (nil when it failed).
+//   - Error: Failure of this refresh, if any.
+//   - NewFindings: Indexes into Report.Findings of findings not present in the previous refresh.
+//   - Previous: Previous value of every measurement that changed since the last refresh.
+type WatchFrame struct {
+	Seq         int                `json:"seq"`
+	Time        time.Time          `json:"time"`
+	Interval    time.Duration      `json:"interval"`
+	Report      *Report            `json:"report,omitempty"`
+	Error       string             `json:"error,omitempty"`
+	NewFindings []int              `json:"new_findings,omitempty"`
+	Previous    map[string]float64 `json:"previous,omitempty"`
+}
+
+// Watch re-runs a diagnostic every opts.Interval and renders each refresh.
+//
+// Parameters:
+//   - ctx: Context; cancelling it (Ctrl-C) stops the watch.
+//   - opts: Interval, duration, filters and destinations.
+//   - run: Runs the diagnostic once.
+//
+// Returns:
+//   - error: nil when stopped by cancellation or after Duration; rendering or recording errors otherwise.
+func Watch(ctx context.Context, opts WatchOptions, run func(context.Context) (*Report, error)) error {
+	if opts.Interval <= 0 {
+		return fmt.Errorf("invalid watch interval %s: must be > 0", opts.Interval)
+	}
+	if opts.Out == nil {
+		opts.Out = io.Discard
+	}
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+	var rec *json.Encoder
+	if opts.Record != nil {
+		rec = json.NewEncoder(opts.Record)
+	}
+
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	var prev *Report
+	for seq := 1; ; seq++ {
+		frame := WatchFrame{Seq: seq, Time: time.Now().UTC(), Interval: opts.Interval}
+		report, err := run(ctx)
+		if ctx.Err() != nil {
+			return nil // interrupted mid-refresh; the partial result is dropped
+		}
+		if err != nil {
+			frame.Error = err.Error()
+		} else {
+			report = filterReport(report, opts.Checks)
+			frame.Report = report
+			frame.NewFindings, frame.Previous = compareFrames(prev, report)
+			prev = report
+		}
+		if opts.OnFrame != nil && frame.Report != nil {
+			if err := opts.OnFrame(ctx, frame.Report); err != nil && frame.Error == "" {
+				frame.Error = err.Error()
+			}
+		}
+		if err := writeWatchFrame(opts.Out, &frame, opts.ANSI, watchFooter(opts)); err != nil {
+			return err
+		}
+		if rec != nil {
+			if err := rec.Encode(&frame); err != nil {
+				return fmt.Errorf("failed to record watch frame: %w", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchFooter describes how the watch ends.
+func watchFooter(opts WatchOptions) string {
+	if opts.Duration > 0 {
+		return fmt.Sprintf("every %s for %s (Ctrl-C to stop)", opts.Interval, opts.Duration)
+	}
+	return fmt.Sprintf("every %s (Ctrl-C to stop)", opts.Interval)
+}
+
+// filterReport returns a copy of r limited to the checks and measurements matching patterns; the inventory is dropped.
+func filterReport(r *Report, patterns []string) *Report {
+	out := &Report{Diagnostic: r.Diagnostic, StartedAt: r.StartedAt, Duration: r.Duration, Findings: []Finding{}}
+	for _, f := range r.Findings {
+		if matchCheck(patterns, f.Check) {
+			out.Findings = append(out.Findings, f)
+		}
+	}
+	for name, v := range r.Measurements {
+		if matchCheck(patterns, name) {
+			out.SetMeasurement(name, v)
+		}
+	}
+	return out
+}
+
+// matchCheck reports whether name matches any pattern: a path.Match glob or a dotted prefix ("sys.disk").
+func matchCheck(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok || name == p || strings.HasPrefix(name, p+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// compareFrames returns the indexes of findings in cur that were not in prev and the previous values of changed
+// measurements. The first frame has nothing to compare against.
+func compareFrames(prev, cur *Report) ([]int, map[string]float64) {
+	if prev == nil {
+		return nil, nil
+	}
+	seen := make(map[string]bool, len(prev.Findings))
+	for _, f := range prev.Findings {
+		seen[findingKey(f)] = true
+	}
+	var fired []int
+	for i, f := range cur.Findings {
+		if !seen[findingKey(f)] {
+			fired = append(fired, i)
+		}
+	}
+	var previous map[string]float64
+	for name, v := range cur.Measurements {
+		if old, ok := prev.Measurements[name]; ok && old != v {
+			if previous == nil {
+				previous = map[string]float64{}
+			}
+			previous[name] = old
+		}
+	}
+	return fired, previous
+}
+
+// writeWatchFrame renders one frame; with ansi the screen is cleared and highlighted rows are coloured.
+func writeWatchFrame(w io.Writer, frame *WatchFrame, ansi bool, footer string) error {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	diagnostic := "-"
+	if frame.Report != nil {
+		diagnostic = frame.Report.Diagnostic
+	}
+	fmt.Fprintf(tw, " DIAGNOSTIC: %s\tREFRESH: #%d\tTIME: %s\n", diagnostic, frame.Seq, frame.Time.Format(time.RFC3339))
+	if frame.Error != "" {
+		fmt.Fprintf(tw, " ERROR: %s\n", frame.Error)
+	}
+	if r := frame.Report; r != nil {
+		fired := make(map[int]bool, len(frame.NewFindings))
+		for _, i := range frame.NewFindings {
+			fired[i] = true
+		}
+		fmt.Fprintln(tw)
+		if len(r.Findings) == 0 {
+			fmt.Fprintln(tw, " No findings.")
+		} else {
+			fmt.Fprintln(tw, " SEVERITY\tCHECK\tRESOURCE\tMESSAGE")
+			for i, f := range r.Findings {
+				mark := ' '
+				if fired[i] {
+					mark = markNew
+				}
+				fmt.Fprintf(tw, "%c%s\t%s\t%s\t%s\n", mark, strings.ToUpper(string(f.Severity)), f.Check, f.Resource, f.Message)
+			}
+		}
+		if len(r.Measurements) > 0 {
+			names := make([]string, 0, len(r.Measurements))
+			for name := range r.Measurements {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			fmt.Fprintln(tw, "\n MEASUREMENT\tVALUE\tPREVIOUS")
+			for _, name := range names {
+				if old, ok := frame.Previous[name]; ok {
+					fmt.Fprintf(tw, "%c%s\t%g\t%g\n", markChanged, name, r.Measurements[name], old)
+				} else {
+					fmt.Fprintf(tw, " %s\t%g\t\n", name, r.Measurements[name])
+				}
+			}
+		}
+	}
+	if footer != "" {
+		fmt.Fprintf(tw, "\n %s\n", footer)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	var out strings.Builder
+	if ansi {
+		out.WriteString(ansiClear)
+	} else if frame.Seq > 1 {
+		out.WriteString("\n")
+	}
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		line := sc.Text()
+		color := ""
+		if ansi && line != "" {
+			switch line[0] {
+			case markNew:
+				color = ansiNew
+			case markChanged:
+				color = ansiChange
+			}
+		}
+		if color != "" {
+			out.WriteString(color + line + ansiReset + "\n")
+		} else {
+			out.WriteString(line + "\n")
+		}
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// ReplayOptions configures Replay.
+//
+// Fields:
+//   - Out: Destination of rendered frames.
+//   - ANSI: Clear the screen and colour highlights.
+//   - Speed: Playback speed relative to the recording; 0 renders all frames without waiting.
+type ReplayOptions struct {
+	Out   io.Writer
+	ANSI  bool
+	Speed float64
+}
+
+// Replay renders a timeline recorded with --record.
+//
+// Parameters:
+//   - ctx: Context; cancelling it stops the replay.
+//   - r: JSON Lines timeline.
+//   - opts: Destination and playback speed.
+//
+// Returns:
+//   - int: Number of frames rendered.
+//   - error: If the timeline cannot be decoded or rendered, returns a detailed error.
+func Replay(ctx context.Context, r io.Reader, opts ReplayOptions) (int, error) {
+	dec := json.NewDecoder(r)
+	var last time.Time
+	n := 0
+	for {
+		var frame WatchFrame
+		if err := dec.Decode(&frame); err != nil {
+			if errors.Is(err, io.EOF) {
+				return n, nil
+			}
+			return n, fmt.Errorf("failed to decode frame %d: %w", n+1, err)
+		}
+		if opts.Speed > 0 && !last.IsZero() {
+			wait := time.Duration(float64(frame.Time.Sub(last)) / opts.Speed)
+			select {
+			case <-ctx.Done():
+				return n, nil
+			case <-time.After(wait):
+			}
+		}
+		last = frame.Time
+		if err := writeWatchFrame(opts.Out, &frame, opts.ANSI, fmt.Sprintf("replay of refresh #%d", frame.Seq)); err != nil {
+			return n, err
+		}
+		n++
+		if ctx.Err() != nil {
+			return n, nil
+		}
+	}
+}
+
+// isTerminal reports whether w is a terminal; patchable in tests.
+var isTerminal = func(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
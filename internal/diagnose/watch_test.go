@@ -0,0 +1,138 @@
+package diagnose
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// decodeFrames reads a JSON Lines timeline.
+func decodeFrames(t *testing.T, r io.Reader) []WatchFrame {
+	t.Helper()
+	var frames []WatchFrame
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var f WatchFrame
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &f))
+		frames = append(frames, f)
+	}
+	require.NoError(t, sc.Err())
+	return frames
+}
+
+func TestWatch(t *testing.T) {
+	calls := 0
+	run := func(context.Context) (*Report, error) {
+		calls++
+		r := NewReport("system")
+		r.Add(Finding{Check: "sys.ntp", Severity: SeverityInfo, Message: "clock synchronised"})
+		r.SetMeasurement("sys.load1", float64(calls))
+		r.SetMeasurement("other.value", 1)
+		switch calls {
+		case 2:
+			r.Add(Finding{Check: "sys.oom", Severity: SeverityCritical, Message: "OOM kill"})
+		case 3:
+			return nil, errors.New("collector flaked")
+		}
+		return r, nil
+	}
+	var out, rec bytes.Buffer
+	opts := WatchOptions{Interval: 20 * time.Millisecond, Duration: 150 * time.Millisecond, Checks: []string{"sys"},
+		Out: &out, Record: &rec}
+	require.NoError(t, Watch(context.Background(), opts, run))
+
+	frames := decodeFrames(t, &rec)
+	require.GreaterOrEqual(t, len(frames), 4)
+	assert.Nil(t, frames[0].NewFindings, "the first refresh has nothing to compare against")
+	assert.NotContains(t, frames[0].Report.Measurements, "other.value", "filtered by --checks")
+
+	assert.Equal(t, []int{1}, frames[1].NewFindings)
+	assert.Equal(t, map[string]float64{"sys.load1": 1}, frames[1].Previous)
+	assert.Equal(t, "collector flaked", frames[2].Error)
+	assert.Empty(t, frames[3].NewFindings)
+	assert.Equal(t, map[string]float64{"sys.load1": 2}, frames[3].Previous, "compared with the last good refresh")
+
+	assert.Contains(t, out.String(), "+CRITICAL  sys.oom")
+	assert.Contains(t, out.String(), "~sys.load1")
+	assert.Contains(t, out.String(), "ERROR: collector flaked")
+	assert.NotContains(t, out.String(), "\x1b[", "no escape codes outside a terminal")
+
+	assert.ErrorContains(t, Watch(context.Background(), WatchOptions{}, run), "invalid watch interval")
+}
+
+func TestWatch_CancelStops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := 0
+	run := func(context.Context) (*Report, error) {
+		runs++
+		if runs == 2 {
+			cancel()
+		}
+		return NewReport("system"), nil
+	}
+	done := make(chan error, 1)
+	go func() { done <- Watch(ctx, WatchOptions{Interval: 10 * time.Millisecond}, run) }()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not stop on cancellation")
+	}
+	assert.Equal(t, 2, runs)
+}
+
+func TestCLI_WatchRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(iacTerraform), 0o600))
+	record := filepath.Join(dir, "timeline.jsonl")
+
+	cmd := &cobra.Command{Use: "iac"}
+	cmd.Flags().Duration("watch", 0, "")
+	cmd.Flags().Duration("for", 0, "")
+	cmd.Flags().String("record", "", "")
+	cmd.Flags().StringSlice("checks", nil, "")
+	cmd.Flags().String("fail-on", "critical", "")
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	for flag, value := range map[string]string{"watch": "20ms", "for": "70ms", "record": record} {
+		require.NoError(t, cmd.Flags().Set(flag, value))
+	}
+	ctx := &core.AppContext{Logger: core.NewTestLogger(&bytes.Buffer{})}
+	require.NoError(t, CLI_IaCDiagnostics(ctx, cmd, []string{dir}), "--fail-on does not apply to watch mode")
+	assert.Contains(t, out.String(), "REFRESH: #2")
+
+	f, err := os.Open(record)
+	require.NoError(t, err)
+	frames := decodeFrames(t, f)
+	require.NoError(t, f.Close())
+	require.GreaterOrEqual(t, len(frames), 2)
+	assert.Equal(t, "iac", frames[0].Report.Diagnostic)
+
+	orig := isTerminal
+	isTerminal = func(io.Writer) bool { return true }
+	defer func() { isTerminal = orig }()
+	replay := &cobra.Command{Use: "replay"}
+	replay.Flags().Float64("speed", 0, "")
+	out.Reset()
+	replay.SetOut(&out)
+	require.NoError(t, CLI_Replay(ctx, replay, []string{record}))
+	assert.Equal(t, len(frames), strings.Count(out.String(), ansiClear))
+	assert.Contains(t, out.String(), "replay of refresh #2")
+
+	assert.Error(t, CLI_Replay(ctx, replay, []string{filepath.Join(dir, "missing.jsonl")}))
+}
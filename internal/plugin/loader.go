@@ -3,8 +3,11 @@ package plugin
 import (
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
 
 	"go.opentelemetry.io/collector/component"
 
@@ -18,6 +21,7 @@ import (
 // Usage:
 //   - Use Loader to discover and load plugins from a specified directory.
 //   - Use GetFactories to retrieve loaded component factories grouped by type.
+//   - Use Prepare on reload to start new and replaced plugin binaries, then Commit or Discard the change.
 //
 // Best Practices:
 //   - Always check for errors from LoadPlugins.
@@ -34,9 +38,33 @@ import (
 //   - Instantiate with NewLoader, providing a logger and plugin manager.
 //   - Call LoadPlugins to discover and load plugins from a directory.
 //   - Call GetFactories to retrieve loaded component factories grouped by type.
+//   - Call Prepare to reconcile the loaded plugins with the plugin directory.
 type Loader struct {
-	logger  *core.Logger
-	manager *PluginManager
+	logger   *core.Logger
+	manager  *PluginManager
+	binaries map[string]pluginBinary
+}
+
+// pluginBinary identifies the plugin binary a loaded plugin was started from.
+type pluginBinary struct {
+	typ     core.ComponentType
+	size    int64
+	modTime time.Time
+}
+
+// SyncResult lists the plugins changed by a PendingSync, by name.
+type SyncResult struct {
+	Loaded   []string
+	Unloaded []string
+	Swapped  []string
+}
+
+// Changed reports whether the change loads, unloads or swaps any plugin.
+//
+// Returns:
+//   - bool: True if the set of running plugins changes.
+func (r *SyncResult) Changed() bool {
+	return len(r.Loaded)+len(r.Unloaded)+len(r.Swapped) > 0
 }
 
 // NewLoader creates a new plugin loader.
@@ -49,8 +77,9 @@ type Loader struct {
 //   - *Loader: A new Loader instance.
 func NewLoader(logger *core.Logger, manager *PluginManager) *Loader {
 	return &Loader{
-		logger:  logger,
-		manager: manager,
+		logger:   logger,
+		manager:  manager,
+		binaries: make(map[string]pluginBinary),
 	}
 }
 
@@ -69,16 +98,165 @@ func NewLoader(logger *core.Logger, manager *PluginManager) *Loader {
 func (l *Loader) LoadPlugins(ctx context.Context, pluginDir string) error {
 	l.logger.Info("Loading plugins", core.ZapString("dir", pluginDir))
 
-	// Ensure plugin directory exists
-	if err := os.MkdirAll(pluginDir, 0755); err != nil {
-		return fmt.Errorf("failed to create plugin directory: %w", err)
+	binaries, err := l.scan(pluginDir)
+	if err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(binaries)) {
+		l.load(ctx, name, binaries[name])
+	}
+
+	return nil
+}
+
+// Prepare compares the loaded plugins with the binaries in pluginDir and starts the new binaries and the ones that
+// changed (size or modification time) next to the loaded plugins, which keep serving until the change is committed.
+// Plugins whose binary was removed, or whose new binary fails to start, are unloaded on Commit.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts.
+//   - pluginDir: Directory containing plugin binaries (the one passed to LoadPlugins).
+//
+// Returns:
+//   - *PendingSync: The prepared change; call Commit or Discard.
+//   - error: If the directory cannot be read, returns a detailed error.
+//
+// Side Effects:
+//   - Starts plugin processes. A plugin that fails to start is logged and left out, as in LoadPlugins.
+func (l *Loader) Prepare(ctx context.Context, pluginDir string) (*PendingSync, error) {
+	binaries, err := l.scan(pluginDir)
+	if err != nil {
+		return nil, err
+	}
+	p := &PendingSync{loader: l, staged: make(map[string]stagedPlugin)}
+	for _, name := range slices.Sorted(maps.Keys(l.binaries)) {
+		bin, ok := binaries[name]
+		if ok && bin == l.binaries[name] {
+			continue
+		}
+		if ok && p.stage(ctx, name, bin) {
+			p.Swapped = append(p.Swapped, name)
+			continue
+		}
+		p.drop = append(p.drop, name)
+		p.Unloaded = append(p.Unloaded, name)
+	}
+	for _, name := range slices.Sorted(maps.Keys(binaries)) {
+		if _, loaded := l.binaries[name]; loaded {
+			continue
+		}
+		if p.stage(ctx, name, binaries[name]) {
+			p.Loaded = append(p.Loaded, name)
+		}
+	}
+	return p, nil
+}
+
+// PendingSync is a plugin change prepared by Loader.Prepare. The new processes run next to the loaded plugins
+// until Commit replaces them, or Discard stops the new processes and leaves the loaded plugins untouched.
+//
+// Usage:
+//   - Build and validate the components of the new plugins with Factories, then Commit, or Discard on failure.
+type PendingSync struct {
+	SyncResult
+	loader *Loader
+	// staged are the started processes of new and changed binaries, by plugin name.
+	staged map[string]stagedPlugin
+	// drop are the loaded plugins to unload on Commit.
+	drop []string
+}
+
+// stagedPlugin is a plugin process started by Prepare and the binary it was started from.
+type stagedPlugin struct {
+	bin    pluginBinary
+	plugin *pluginInstance
+}
+
+// stage starts a plugin binary without registering it; failures are logged, as a bad plugin must not stop the
+// others.
+func (p *PendingSync) stage(ctx context.Context, name string, bin pluginBinary) bool {
+	l := p.loader
+	l.logger.Info("Loading plugin",
+		core.ZapString("type", string(bin.typ)),
+		core.ZapString("name", name))
+
+	plugin, err := l.manager.start(ctx, bin.typ, name)
+	if err != nil {
+		l.logger.Error("Failed to load plugin",
+			core.ZapString("type", string(bin.typ)),
+			core.ZapString("name", name),
+			core.ZapError(err))
+		return false
+	}
+	p.staged[name] = stagedPlugin{bin: bin, plugin: plugin}
+	return true
+}
+
+// Factories returns the component factories of the plugin set after Commit, grouped by type.
+//
+// Returns:
+//   - receivers, processors, exporters, extensions: Component factories by component type, as GetFactories.
+func (p *PendingSync) Factories() (
+	receivers map[component.Type]component.Factory,
+	processors map[component.Type]component.Factory,
+	exporters map[component.Type]component.Factory,
+	extensions map[component.Type]component.Factory,
+) {
+	plugins := p.loader.loaded()
+	for _, name := range p.drop {
+		delete(plugins, name)
+	}
+	for name, s := range p.staged {
+		plugins[name] = loadedPlugin{metadata: s.plugin.metadata, instance: s.plugin.client()}
+	}
+	return p.loader.factories(plugins)
+}
+
+// Commit applies the change: the new processes replace the loaded plugins of the same name, and removed plugins
+// are unloaded. Calling Commit or Discard again does nothing.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts; it bounds how long replaced plugins get to exit.
+//
+// Side Effects:
+//   - Stops the replaced and removed plugin processes.
+func (p *PendingSync) Commit(ctx context.Context) {
+	l := p.loader
+	for _, name := range p.drop {
+		if err := l.manager.Unload(ctx, name); err != nil {
+			l.logger.Warn("Failed to unload plugin", core.ZapString("name", name), core.ZapError(err))
+		}
+		delete(l.binaries, name)
+	}
+	for _, name := range slices.Sorted(maps.Keys(p.staged)) {
+		l.manager.replace(ctx, name, p.staged[name].plugin)
+		l.binaries[name] = p.staged[name].bin
+	}
+	p.staged, p.drop = nil, nil
+}
+
+// Discard abandons the change: the processes started by Prepare are stopped and the loaded plugins keep running.
+// Calling Commit or Discard again does nothing.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts.
+//
+// Side Effects:
+//   - Stops the plugin processes started by Prepare.
+func (p *PendingSync) Discard(ctx context.Context) {
+	for name, s := range p.staged {
+		p.loader.manager.stop(ctx, name, s.plugin)
 	}
+	p.staged, p.drop = nil, nil
+}
 
-	// Walk through plugin directories
-	for _, typ := range []string{"receivers", "processors", "exporters", "extensions"} {
-		typeDir := filepath.Join(pluginDir, typ)
+// scan lists the plugin binaries below pluginDir/<type>s, creating the directories if needed.
+func (l *Loader) scan(pluginDir string) (map[string]pluginBinary, error) {
+	binaries := make(map[string]pluginBinary)
+	for _, typ := range []core.ComponentType{core.TypeReceiver, core.TypeProcessor, core.TypeExporter, core.TypeExtension} {
+		typeDir := filepath.Join(pluginDir, string(typ)+"s")
 		if err := os.MkdirAll(typeDir, 0755); err != nil {
-			return fmt.Errorf("failed to create plugin type directory: %w", err)
+			return nil, fmt.Errorf("failed to create plugin type directory: %w", err)
 		}
 
 		entries, err := os.ReadDir(typeDir)
@@ -86,30 +264,38 @@ func (l *Loader) LoadPlugins(ctx context.Context, pluginDir string) error {
 			if os.IsNotExist(err) {
 				continue
 			}
-			return fmt.Errorf("failed to read plugin directory: %w", err)
+			return nil, fmt.Errorf("failed to read plugin directory: %w", err)
 		}
 
 		for _, entry := range entries {
 			if entry.IsDir() {
 				continue
 			}
-
-			pluginName := entry.Name()
-			l.logger.Info("Loading plugin",
-				core.ZapString("type", typ),
-				core.ZapString("name", pluginName))
-
-			if err := l.manager.Load(ctx, core.ComponentType(typ), pluginName); err != nil {
-				l.logger.Error("Failed to load plugin",
-					core.ZapString("type", typ),
-					core.ZapString("name", pluginName),
-					core.ZapError(err))
+			info, err := entry.Info()
+			if err != nil {
 				continue
 			}
+			binaries[entry.Name()] = pluginBinary{typ: typ, size: info.Size(), modTime: info.ModTime()}
 		}
 	}
+	return binaries, nil
+}
 
-	return nil
+// load starts one plugin and records its binary; failures are logged, as a bad plugin must not stop the others.
+func (l *Loader) load(ctx context.Context, name string, bin pluginBinary) bool {
+	l.logger.Info("Loading plugin",
+		core.ZapString("type", string(bin.typ)),
+		core.ZapString("name", name))
+
+	if err := l.manager.Load(ctx, bin.typ, name); err != nil {
+		l.logger.Error("Failed to load plugin",
+			core.ZapString("type", string(bin.typ)),
+			core.ZapString("name", name),
+			core.ZapError(err))
+		return false
+	}
+	l.binaries[name] = bin
+	return true
 }
 
 // GetFactories returns all loaded component factories grouped by type.
@@ -124,20 +310,41 @@ func (l *Loader) GetFactories() (
 	processors map[component.Type]component.Factory,
 	exporters map[component.Type]component.Factory,
 	extensions map[component.Type]component.Factory,
+) {
+	return l.factories(l.loaded())
+}
+
+// loadedPlugin is a plugin and its metadata.
+type loadedPlugin struct {
+	metadata PluginMetadata
+	instance IPluginInstance
+}
+
+// loaded returns the loaded plugins by name.
+func (l *Loader) loaded() map[string]loadedPlugin {
+	plugins := make(map[string]loadedPlugin)
+	for _, meta := range l.manager.List() {
+		if plugin, ok := l.manager.Get(meta.Name); ok {
+			plugins[meta.Name] = loadedPlugin{metadata: meta, instance: plugin}
+		}
+	}
+	return plugins
+}
+
+// factories returns the component factories served by plugins, grouped by type.
+func (l *Loader) factories(plugins map[string]loadedPlugin) (
+	receivers map[component.Type]component.Factory,
+	processors map[component.Type]component.Factory,
+	exporters map[component.Type]component.Factory,
+	extensions map[component.Type]component.Factory,
 ) {
 	receivers = make(map[component.Type]component.Factory)
 	processors = make(map[component.Type]component.Factory)
 	exporters = make(map[component.Type]component.Factory)
 	extensions = make(map[component.Type]component.Factory)
 
-	// Get all loaded plugins
-	plugins := l.manager.List()
-	for _, meta := range plugins {
-		plugin, ok := l.manager.Get(meta.Name)
-		if !ok {
-			continue
-		}
-
+	for _, name := range slices.Sorted(maps.Keys(plugins)) {
+		meta, plugin := plugins[name].metadata, plugins[name].instance
 		factory, err := plugin.Factory()
 		if err != nil {
 			l.logger.Error("Failed to get factory",
@@ -146,14 +353,12 @@ func (l *Loader) GetFactories() (
 			continue
 		}
 
-		// Create component type
-		compType, err := component.NewType(string(meta.Type) + "/" + meta.Name)
-		if err != nil {
-			l.logger.Error("Failed to create component type",
-				core.ZapString("plugin", meta.Name),
-				core.ZapError(err))
+		// Plugins that do not serve a factory yet cannot join collector pipelines.
+		if factory == nil {
+			l.logger.Warn("Plugin provides no component factory", core.ZapString("plugin", meta.Name))
 			continue
 		}
+		compType := factory.Type()
 
 		switch meta.Type {
 		case core.TypeReceiver:
@@ -31,6 +31,8 @@ import (
 	"path/filepath"
 	"runtime"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/cloudwego/shmipc-go"
@@ -48,6 +50,8 @@ type PluginManager struct {
 	pluginDir string
 	plugins   map[string]*pluginInstance
 	mu        sync.RWMutex
+	// starts numbers the plugin processes started, for their IPC addresses.
+	starts atomic.Uint64
 }
 
 // NewManager creates a new plugin manager.
@@ -89,16 +93,27 @@ func (m *PluginManager) Load(ctx context.Context, pluginType core.ComponentType,
 	if _, exists := m.plugins[name]; exists {
 		return fmt.Errorf("plugin already loaded")
 	}
+	plugin, err := m.start(ctx, pluginType, name)
+	if err != nil {
+		return err
+	}
+	m.plugins[name] = plugin
+	return nil
+}
 
+// start launches a plugin process and initializes it over IPC without registering it, so a replacement can run
+// next to the loaded plugin of the same name until it is registered (see replace).
+func (m *PluginManager) start(ctx context.Context, pluginType core.ComponentType, name string) (*pluginInstance, error) {
 	pluginPath := filepath.Join(m.pluginDir, string(pluginType)+"s", name)
 	if _, err := os.Stat(pluginPath); err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("plugin not found")
+			return nil, fmt.Errorf("plugin not found")
 		}
-		return fmt.Errorf("failed to check plugin: %w", err)
+		return nil, fmt.Errorf("failed to check plugin: %w", err)
 	}
 
-	shmPath := fmt.Sprintf("/tmp/srediag-%s-%s.ipc", pluginType, name)
+	// Each process gets its own address, as a replacement starts before the plugin it replaces stops.
+	shmPath := fmt.Sprintf("/tmp/srediag-%s-%s-%d.ipc", pluginType, name, m.starts.Add(1))
 	conf := shmipc.DefaultSessionManagerConfig()
 	if runtime.GOOS == "darwin" {
 		conf.ShareMemoryPathPrefix = "/tmp/srediag-plugin-ipc"
@@ -111,21 +126,21 @@ func (m *PluginManager) Load(ctx context.Context, pluginType core.ComponentType,
 
 	sessionManager, err := shmipc.NewSessionManager(conf)
 	if err != nil {
-		return fmt.Errorf("failed to create session manager: %w", err)
+		return nil, fmt.Errorf("failed to create session manager: %w", err)
 	}
 
 	// Start the plugin process, passing the ipc address as argumento
 	cmd := exec.Command(pluginPath, "--ipc", shmPath)
 	if err := cmd.Start(); err != nil {
 		sessionManager.Close()
-		return fmt.Errorf("failed to start plugin: %w", err)
+		return nil, fmt.Errorf("failed to start plugin: %w", err)
 	}
 
 	// Obtain a stream from the session manager for comunicação.
 	stream, err := sessionManager.GetStream()
 	if err != nil {
 		sessionManager.Close()
-		return fmt.Errorf("failed to get stream: %w", err)
+		return nil, fmt.Errorf("failed to get stream: %w", err)
 	}
 	// Após uso, o stream será devolvido automaticamente pelo sessionManager, portanto não chamamos PutBack aqui.
 
@@ -134,19 +149,19 @@ func (m *PluginManager) Load(ctx context.Context, pluginType core.ComponentType,
 	reqData, err := json.Marshal(initReq)
 	if err != nil {
 		sessionManager.Close()
-		return fmt.Errorf("failed to marshal initialization request: %w", err)
+		return nil, fmt.Errorf("failed to marshal initialization request: %w", err)
 	}
 
 	writer := stream.BufferWriter()
 	if err := writer.WriteString(string(reqData)); err != nil {
 		sessionManager.Close()
-		return fmt.Errorf("failed to write initialization request: %w", err)
+		return nil, fmt.Errorf("failed to write initialization request: %w", err)
 	}
 
 	// Flush the buffer to send the data to the plugin.
 	if err := stream.Flush(true); err != nil {
 		sessionManager.Close()
-		return fmt.Errorf("failed to flush stream: %w", err)
+		return nil, fmt.Errorf("failed to flush stream: %w", err)
 	}
 
 	// Read the response from the plugin.
@@ -155,27 +170,25 @@ func (m *PluginManager) Load(ctx context.Context, pluginType core.ComponentType,
 	respData, err := reader.ReadBytes(512)
 	if err != nil {
 		sessionManager.Close()
-		return fmt.Errorf("failed to read initialization response: %w", err)
+		return nil, fmt.Errorf("failed to read initialization response: %w", err)
 	}
 
 	var resp IPCResponse
 	if err := json.Unmarshal(respData, &resp); err != nil {
 		sessionManager.Close()
-		return fmt.Errorf("bad response: %w", err)
+		return nil, fmt.Errorf("bad response: %w", err)
 	}
 	if resp.Error != "" {
 		sessionManager.Close()
-		return fmt.Errorf("plugin initialization error: %s", resp.Error)
+		return nil, fmt.Errorf("plugin initialization error: %s", resp.Error)
 	}
 
 	// Armazena a instância do plugin junto com o session manager ativo.
-	m.plugins[name] = &pluginInstance{
+	return &pluginInstance{
 		metadata: PluginMetadata{Name: name, Type: pluginType},
 		ch:       sessionManager,
 		cmd:      cmd,
-	}
-
-	return nil
+	}, nil
 }
 
 // Get returns a loaded plugin instance by name.
@@ -195,10 +208,15 @@ func (m *PluginManager) Get(name string) (IPluginInstance, bool) {
 		return nil, false
 	}
 
+	return plugin.client(), true
+}
+
+// client returns the IPluginInstance view of a plugin process.
+func (p *pluginInstance) client() IPluginInstance {
 	return &clientInstance{
-		metadata: plugin.metadata,
-		ch:       plugin.ch,
-	}, true
+		metadata: p.metadata,
+		ch:       p.ch,
+	}
 }
 
 // List returns metadata for all loaded plugins.
@@ -364,35 +382,95 @@ func (m *PluginManager) GetFactory(typ component.Type) (component.Factory, error
 	return nil, fmt.Errorf("no factory found for type %s", typ)
 }
 
+// pluginStopGrace bounds how long Unload waits for a plugin process to exit on SIGTERM before killing it.
+const pluginStopGrace = 5 * time.Second
+
 // Unload stops and removes a loaded plugin by name.
 //
 // Parameters:
-//   - ctx: Context for cancellation and timeouts.
+//   - ctx: Context for cancellation and timeouts; its deadline shortens the grace period.
 //   - name: The name of the plugin to unload.
 //
 // Returns:
 //   - error: If unloading fails, returns a detailed error.
 //
 // Side Effects:
-//   - Stops plugin processes and closes IPC sessions.
+//   - Closes the IPC session, sends SIGTERM to the plugin process and kills it if it has not exited within
+//     pluginStopGrace (or by the deadline of ctx).
 func (m *PluginManager) Unload(ctx context.Context, name string) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	plugin, exists := m.plugins[name]
+	delete(m.plugins, name)
+	m.mu.Unlock()
 	if !exists {
 		return fmt.Errorf("plugin not found")
 	}
+	m.stop(ctx, name, plugin)
+	return nil
+}
 
-	plugin.ch.Close()
-	if plugin.cmd != nil && plugin.cmd.Process != nil {
-		err := plugin.cmd.Process.Kill() // Best effort
-		if err != nil {
-			m.logger.Warn("Failed to kill plugin process", core.ZapString("name", name), core.ZapError(err))
-		}
+// replace registers a started plugin under name and stops the plugin it replaces, if any.
+func (m *PluginManager) replace(ctx context.Context, name string, plugin *pluginInstance) {
+	m.mu.Lock()
+	old, exists := m.plugins[name]
+	m.plugins[name] = plugin
+	m.mu.Unlock()
+	if exists {
+		m.stop(ctx, name, old)
 	}
+}
 
-	delete(m.plugins, name)
+// UnloadAll unloads every loaded plugin concurrently, giving each the same grace period.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts.
+//
+// Side Effects:
+//   - Stops all plugin processes and closes their IPC sessions.
+func (m *PluginManager) UnloadAll(ctx context.Context) {
+	m.mu.Lock()
+	plugins := m.plugins
+	m.plugins = make(map[string]*pluginInstance)
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for name, plugin := range plugins {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.stop(ctx, name, plugin)
+		}()
+	}
+	wg.Wait()
+}
 
-	return nil
+// stop closes the IPC session of a plugin and terminates its process, first with SIGTERM, then with SIGKILL.
+func (m *PluginManager) stop(ctx context.Context, name string, plugin *pluginInstance) {
+	if plugin.ch != nil {
+		plugin.ch.Close()
+	}
+	if plugin.cmd == nil || plugin.cmd.Process == nil {
+		return
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = plugin.cmd.Wait()
+		close(exited)
+	}()
+	if err := plugin.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		m.logger.Debug("Failed to signal plugin process", core.ZapString("name", name), core.ZapError(err))
+	}
+	grace := time.NewTimer(pluginStopGrace)
+	defer grace.Stop()
+	select {
+	case <-exited:
+		return
+	case <-grace.C:
+	case <-ctx.Done():
+	}
+	m.logger.Warn("Plugin did not exit in time; killing it", core.ZapString("name", name))
+	if err := plugin.cmd.Process.Kill(); err != nil {
+		m.logger.Warn("Failed to kill plugin process", core.ZapString("name", name), core.ZapError(err))
+	}
+	<-exited
 }
@@ -0,0 +1,90 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// startProcess registers a running process as a loaded plugin.
+func startProcess(t *testing.T, m *PluginManager, name, script string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command("sh", "-c", script)
+	require.NoError(t, cmd.Start())
+	// Wait until the script has exec'd sleep, so its signal disposition is in place.
+	comm := fmt.Sprintf("/proc/%d/comm", cmd.Process.Pid)
+	require.Eventually(t, func() bool {
+		data, err := os.ReadFile(comm)
+		return err == nil && strings.TrimSpace(string(data)) == "sleep"
+	}, 5*time.Second, 5*time.Millisecond)
+	m.plugins[name] = &pluginInstance{metadata: PluginMetadata{Name: name}, cmd: cmd}
+	return cmd
+}
+
+func TestPluginManager_UnloadAll(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("needs sh")
+	}
+	if _, err := os.Stat("/proc/self/comm"); err != nil {
+		t.Skip("needs /proc")
+	}
+	m := NewManager(core.NewTestLogger(&bytes.Buffer{}), t.TempDir())
+	polite := startProcess(t, m, "polite", "exec sleep 60")
+	// SIGTERM is ignored, and the disposition survives exec.
+	stubborn := startProcess(t, m, "stubborn", `trap "" TERM; exec sleep 60`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	m.UnloadAll(ctx)
+	assert.Less(t, time.Since(start), pluginStopGrace, "the deadline of ctx shortens the grace period")
+	assert.Empty(t, m.List())
+	assert.Equal(t, "signal: terminated", polite.ProcessState.String())
+	assert.Equal(t, "signal: killed", stubborn.ProcessState.String())
+
+	assert.EqualError(t, m.Unload(context.Background(), "polite"), "plugin not found")
+}
+
+func TestLoader_PrepareDiscardCommit(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("needs sh")
+	}
+	if _, err := os.Stat("/proc/self/comm"); err != nil {
+		t.Skip("needs /proc")
+	}
+	dir := t.TempDir()
+	m := NewManager(core.NewTestLogger(&bytes.Buffer{}), dir)
+	l := NewLoader(core.NewTestLogger(&bytes.Buffer{}), m)
+	running := startProcess(t, m, "broken", "exec sleep 60")
+	l.binaries["broken"] = pluginBinary{typ: core.TypeReceiver, size: 1}
+	// The replacement binary does not speak the plugin protocol, so it fails to start.
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "receivers"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "receivers", "broken"), []byte("#!/bin/sh\nexit 1\n"), 0755))
+
+	pending, err := l.Prepare(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"broken"}, pending.Unloaded)
+	pending.Discard(context.Background())
+	assert.Len(t, m.List(), 1, "a discarded change keeps the loaded plugin")
+	assert.Nil(t, running.ProcessState)
+	assert.Contains(t, l.binaries, "broken")
+
+	pending, err = l.Prepare(context.Background(), dir)
+	require.NoError(t, err)
+	pending.Commit(context.Background())
+	assert.Empty(t, m.List())
+	assert.Equal(t, "signal: terminated", running.ProcessState.String())
+	assert.NotContains(t, l.binaries, "broken")
+	pending.Discard(context.Background())
+}
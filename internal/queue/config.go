@@ -0,0 +1,85 @@
+package queue
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package queue provides a persistent, disk-backed FIFO queue for the service-scope exporters of SREDIAG.
+//
+// This file defines Config, the 'queue' section of an exporter in the collector YAML, and its translation into the
+// Options of Open.
+//
+// Usage:
+//   - Embed Config in an exporter configuration under the mapstructure key "queue" and call Options and Directory when
+//     the exporter starts.
+//
+// Best Practices:
+//   - Leave Dir empty so every exporter gets its own directory below the state directory.
+
+// Config configures the persistent queue of an exporter.
+//
+// Fields:
+//   - Enabled: Queue records on disk before they are sent.
+//   - Dir: Queue directory; defaults to <state dir>/queue/<exporter>.
+//   - MaxSizeMiB: Cap on the queued data in MiB (default 10240).
+//   - SegmentSizeMiB: Size of a segment file in MiB (default 64).
+//   - MaxAge: Records older than this are dropped instead of sent; 0 keeps them until the size cap.
+//   - FullPolicy: drop_oldest (default), drop_newest or block.
+//   - SyncInterval: Minimum time between fsyncs; 0 syncs every record.
+type Config struct {
+	Enabled        bool          `mapstructure:"enabled" yaml:"enabled"`
+	Dir            string        `mapstructure:"dir" yaml:"dir"`
+	MaxSizeMiB     int64         `mapstructure:"max_size_mib" yaml:"max_size_mib"`
+	SegmentSizeMiB int64         `mapstructure:"segment_size_mib" yaml:"segment_size_mib"`
+	MaxAge         time.Duration `mapstructure:"max_age" yaml:"max_age"`
+	FullPolicy     string        `mapstructure:"full_policy" yaml:"full_policy"`
+	SyncInterval   time.Duration `mapstructure:"sync_interval" yaml:"sync_interval"`
+}
+
+// Options validates the configuration and returns the Options for Open.
+//
+// Returns:
+//   - Options: The queue options; unset fields take the defaults of Open.
+//   - error: If a size or duration is negative, the segment is larger than the queue or the policy is unknown.
+func (c Config) Options() (Options, error) {
+	switch {
+	case c.MaxSizeMiB < 0:
+		return Options{}, fmt.Errorf("max_size_mib must not be negative")
+	case c.SegmentSizeMiB < 0:
+		return Options{}, fmt.Errorf("segment_size_mib must not be negative")
+	case c.MaxSizeMiB > 0 && c.SegmentSizeMiB > c.MaxSizeMiB:
+		return Options{}, fmt.Errorf("segment_size_mib (%d) must not exceed max_size_mib (%d)", c.SegmentSizeMiB, c.MaxSizeMiB)
+	case c.MaxAge < 0:
+		return Options{}, fmt.Errorf("max_age must not be negative")
+	case c.SyncInterval < 0:
+		return Options{}, fmt.Errorf("sync_interval must not be negative")
+	case c.FullPolicy != "" && !slices.Contains([]string{DropOldest, DropNewest, Block}, c.FullPolicy):
+		return Options{}, fmt.Errorf("invalid full_policy %q (want %s, %s or %s)", c.FullPolicy, DropOldest, DropNewest, Block)
+	}
+	return Options{
+		MaxBytes:     c.MaxSizeMiB << 20,
+		SegmentBytes: c.SegmentSizeMiB << 20,
+		MaxAge:       c.MaxAge,
+		FullPolicy:   c.FullPolicy,
+		SyncInterval: c.SyncInterval,
+	}, nil
+}
+
+// Directory returns the queue directory of the named exporter.
+//
+// Parameters:
+//   - name: Exporter name (e.g., "diag_exporter").
+//
+// Returns:
+//   - string: Config.Dir, or <state dir>/queue/<name> when it is empty.
+func (c Config) Directory(name string) string {
+	if c.Dir != "" {
+		return c.Dir
+	}
+	return filepath.Join(core.DefaultStateDir(), "queue", name)
+}
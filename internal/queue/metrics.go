@@ -0,0 +1,71 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Package queue provides a persistent, disk-backed FIFO queue for the service-scope exporters of SREDIAG.
+//
+// This file exposes the Stats of a Queue as OTel instruments on the agent's own telemetry.
+//
+// Usage:
+//   - Call RegisterMetrics once after Open; Close unregisters the instruments.
+
+// Instrument names of a queue.
+const (
+	MetricDepth   = "srediag_queue_depth"
+	MetricBytes   = "srediag_queue_bytes"
+	MetricDropped = "srediag_queue_dropped_total"
+)
+
+// RegisterMetrics reports the depth, size and drops of the queue to mp, labelled queue=name.
+//
+// Parameters:
+//   - mp: The agent's meter provider; nil registers nothing.
+//   - name: Value of the "queue" attribute (e.g., the exporter name).
+//
+// Returns:
+//   - error: If an instrument or the callback cannot be registered, returns a detailed error.
+func (q *Queue) RegisterMetrics(mp metric.MeterProvider, name string) error {
+	if mp == nil {
+		return nil
+	}
+	meter := mp.Meter("github.com/srediag/srediag/internal/queue")
+	depth, err := meter.Int64ObservableGauge(MetricDepth, metric.WithDescription("Records waiting in the persistent queue"))
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", MetricDepth, err)
+	}
+	size, err := meter.Int64ObservableGauge(MetricBytes, metric.WithDescription("Bytes waiting in the persistent queue"),
+		metric.WithUnit("By"))
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", MetricBytes, err)
+	}
+	dropped, err := meter.Int64ObservableCounter(MetricDropped,
+		metric.WithDescription("Records dropped by the size or age cap of the persistent queue"))
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", MetricDropped, err)
+	}
+	attrs := metric.WithAttributes(attribute.String("queue", name))
+	reg, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		s := q.Stats()
+		o.ObserveInt64(depth, int64(s.Depth), attrs)
+		o.ObserveInt64(size, s.Bytes, attrs)
+		o.ObserveInt64(dropped, int64(s.Dropped), attrs)
+		return nil
+	}, depth, size, dropped)
+	if err != nil {
+		return fmt.Errorf("failed to register queue metrics: %w", err)
+	}
+	q.mu.Lock()
+	old := q.metrics
+	q.metrics = reg
+	q.mu.Unlock()
+	if old != nil {
+		_ = old.Unregister()
+	}
+	return nil
+}
@@ -0,0 +1,709 @@
+package queue
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Package queue provides a persistent, disk-backed FIFO queue for the service-scope exporters of SREDIAG.
+//
+// This file defines Queue, a segmented write-ahead log. Records are appended to numbered segment files
+// (<first sequence>.seg), each framed with its length, a CRC-32C checksum and its enqueue time; the position of the
+// consumer is kept in a cursor file. On Open, a torn or corrupt tail left by a crash (kill -9, power loss) is cut
+// off at the last intact record, so the queue never hands out partial data and keeps its order across restarts.
+// Delivery is at-least-once: a record is only gone once it is acknowledged (or dropped by a cap).
+//
+// Usage:
+//   - Open a directory with Options (see Config.Options), Put records from the producers and let one consumer loop
+//     over Peek, deliver and Ack.
+//   - Call Sync on graceful shutdown and Close when done.
+//
+// Best Practices:
+//   - Use one queue directory per exporter; a directory must not be opened twice.
+//   - Keep SyncInterval at 0 (fsync every record) where losing the last records on power loss is not acceptable.
+//   - Exporters in collector pipelines keep the sending_queue of exporterhelper; this queue is for the exporters the
+//     service runs itself (the diag_exporter).
+
+// Full policies applied when a Put would exceed Options.MaxBytes.
+const (
+	DropOldest = "drop_oldest" // Drop the oldest records until the new one fits
+	DropNewest = "drop_newest" // Reject the new record with ErrFull
+	Block      = "block"       // Wait until the consumer frees enough space
+)
+
+const (
+	// recordHeaderSize is the frame of every record: length (uint32), CRC-32C (uint32) of time and data, and the
+	// enqueue time (int64 Unix nanoseconds), little-endian.
+	recordHeaderSize = 16
+	segmentExt       = ".seg"
+	cursorFile       = "cursor"
+)
+
+// Default limits of Options; DefaultMaxBytes is the 10 GiB offline buffer of roadmap item F-9.
+const (
+	DefaultMaxBytes     = 10 << 30
+	DefaultSegmentBytes = 64 << 20
+)
+
+var (
+	// ErrFull is returned by Put under DropNewest when the record does not fit.
+	ErrFull = errors.New("queue is full")
+	// ErrClosed is returned by every operation once the queue is closed.
+	ErrClosed = errors.New("queue is closed")
+	// ErrTooLarge is returned by Put for a record larger than the queue.
+	ErrTooLarge = errors.New("record is larger than the queue")
+)
+
+// castagnoli is the CRC-32C table of the record checksums.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Options configures a Queue.
+//
+// Fields:
+//   - MaxBytes: Cap on the bytes of unacknowledged records, framing included (default DefaultMaxBytes).
+//   - SegmentBytes: Size at which a new segment file is started (default DefaultSegmentBytes).
+//   - MaxAge: Records older than this are dropped instead of delivered; 0 for no age cap.
+//   - FullPolicy: DropOldest (default), DropNewest or Block.
+//   - SyncInterval: Minimum time between fsyncs of appended records; 0 syncs after every Put.
+type Options struct {
+	MaxBytes     int64
+	SegmentBytes int64
+	MaxAge       time.Duration
+	FullPolicy   string
+	SyncInterval time.Duration
+}
+
+// Record is one queued record.
+//
+// Fields:
+//   - Seq: Position in the queue; pass it to Ack.
+//   - Time: When the record was enqueued.
+//   - Data: The payload.
+type Record struct {
+	Seq  uint64
+	Time time.Time
+	Data []byte
+}
+
+// Stats describes the content of a Queue.
+//
+// Fields:
+//   - Depth: Unacknowledged records.
+//   - Bytes: Bytes of the unacknowledged records, framing included.
+//   - Segments: Segment files on disk.
+//   - Dropped: Records dropped by the size or age cap or rejected by DropNewest since Open.
+//   - Corrupted: Bytes discarded because they failed the checksum since Open.
+type Stats struct {
+	Depth     uint64 `json:"depth" yaml:"depth"`
+	Bytes     int64  `json:"bytes" yaml:"bytes"`
+	Segments  int    `json:"segments" yaml:"segments"`
+	Dropped   uint64 `json:"dropped" yaml:"dropped"`
+	Corrupted int64  `json:"corrupted_bytes" yaml:"corrupted_bytes"`
+}
+
+// segment is one segment file; its records have the sequence numbers first to first+count-1.
+type segment struct {
+	first  uint64
+	path   string
+	count  uint64
+	size   int64
+	newest time.Time
+}
+
+// Queue is a persistent FIFO of byte records; it is safe for concurrent producers and one consumer.
+type Queue struct {
+	dir  string
+	opts Options
+	now  func() time.Time
+
+	mu        sync.Mutex
+	segs      []*segment // oldest first; the last one is appended to
+	w         *os.File   // the last segment
+	r         *os.File   // the first segment, for reads
+	rpath     string
+	head      uint64 // sequence of the oldest unacknowledged record
+	hoff      int64  // offset of head in segs[0]
+	next      uint64 // sequence of the next record
+	bytes     int64
+	dropped   uint64
+	corrupted int64
+	synced    time.Time
+	dirty     bool // appended records are not synced yet
+	changed   chan struct{}
+	closed    bool
+	metrics   metric.Registration
+}
+
+// Open opens (or creates) the queue in dir and recovers it from an interrupted run.
+//
+// Parameters:
+//   - dir: Queue directory (created with mode 0700 if missing).
+//   - opts: Limits and policies; zero values take the defaults.
+//
+// Returns:
+//   - *Queue: The queue, positioned at the oldest unacknowledged record.
+//   - error: If the options are invalid or the directory cannot be read or repaired.
+func Open(dir string, opts Options) (*Queue, error) {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+	if opts.SegmentBytes <= 0 {
+		opts.SegmentBytes = min(DefaultSegmentBytes, opts.MaxBytes)
+	}
+	if opts.FullPolicy == "" {
+		opts.FullPolicy = DropOldest
+	}
+	if !slices.Contains([]string{DropOldest, DropNewest, Block}, opts.FullPolicy) {
+		return nil, fmt.Errorf("invalid full policy %q (want %s, %s or %s)", opts.FullPolicy, DropOldest, DropNewest, Block)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %w", err)
+	}
+	q := &Queue{dir: dir, opts: opts, now: time.Now, changed: make(chan struct{})}
+	if err := q.recover(); err != nil {
+		q.closeFiles()
+		return nil, err
+	}
+	q.synced = q.now()
+	return q, nil
+}
+
+// recover loads the segments and the cursor, cutting off a corrupt tail.
+func (q *Queue) recover() error {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read queue directory: %w", err)
+	}
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), segmentExt)
+		if !ok || e.IsDir() {
+			continue
+		}
+		first, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		q.segs = append(q.segs, &segment{first: first, path: filepath.Join(q.dir, e.Name())})
+	}
+	slices.SortFunc(q.segs, func(a, b *segment) int { return cmp.Compare(a.first, b.first) })
+	for i, seg := range q.segs {
+		// Only the last segment can have been cut short by a crash; the others were synced when they were
+		// rolled, so their checksums are verified when they are read.
+		if err := q.scan(seg, i == len(q.segs)-1); err != nil {
+			return err
+		}
+		if i+1 < len(q.segs) && seg.first+seg.count > q.segs[i+1].first {
+			return fmt.Errorf("queue segments %s and %s overlap", filepath.Base(seg.path), filepath.Base(q.segs[i+1].path))
+		}
+	}
+
+	cursor := q.readCursor()
+	if len(q.segs) == 0 {
+		q.next = cursor
+		if err := q.roll(); err != nil {
+			return err
+		}
+	}
+	last := q.segs[len(q.segs)-1]
+	q.next = last.first + last.count
+	q.head = min(max(cursor, q.segs[0].first), q.next)
+	for len(q.segs) > 1 && q.segs[0].first+q.segs[0].count <= q.head {
+		if err := q.removeFirst(); err != nil {
+			return err
+		}
+	}
+	if q.hoff, err = q.offsetOf(q.segs[0], q.head); err != nil {
+		return err
+	}
+	for _, seg := range q.segs {
+		q.bytes += seg.size
+	}
+	q.bytes -= q.hoff
+	if q.w == nil {
+		if q.w, err = os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0o600); err != nil {
+			return fmt.Errorf("failed to open queue segment: %w", err)
+		}
+	}
+	return nil
+}
+
+// scan counts the records of a segment; with verify it checks every checksum and truncates the file at the first
+// torn or corrupt record.
+func (q *Queue) scan(seg *segment, verify bool) error {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return fmt.Errorf("failed to open queue segment: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat queue segment: %w", err)
+	}
+	br := bufio.NewReaderSize(f, 1<<16)
+	var off int64
+	var header [recordHeaderSize]byte
+	for off < info.Size() {
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			break
+		}
+		n, sum, ts := decodeHeader(header[:])
+		end := off + recordHeaderSize + int64(n)
+		if end > info.Size() {
+			break
+		}
+		if verify {
+			crc := crc32.Update(0, castagnoli, header[8:])
+			if _, err := io.CopyN(crcWriter{&crc}, br, int64(n)); err != nil || crc != sum {
+				break
+			}
+		} else if _, err := br.Discard(int(n)); err != nil {
+			break
+		}
+		seg.count++
+		seg.newest = ts
+		off = end
+	}
+	seg.size = off
+	if off < info.Size() {
+		// A record was cut short or fails its checksum: drop it and everything after it.
+		q.corrupted += info.Size() - off
+		if err := os.Truncate(seg.path, off); err != nil {
+			return fmt.Errorf("failed to truncate corrupt queue segment: %w", err)
+		}
+	}
+	return nil
+}
+
+// crcWriter feeds written bytes into a CRC-32C.
+type crcWriter struct{ crc *uint32 }
+
+func (w crcWriter) Write(p []byte) (int, error) {
+	*w.crc = crc32.Update(*w.crc, castagnoli, p)
+	return len(p), nil
+}
+
+// offsetOf returns the offset of record seq in seg by walking the record headers.
+func (q *Queue) offsetOf(seg *segment, seq uint64) (int64, error) {
+	if seq == seg.first {
+		return 0, nil
+	}
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open queue segment: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	var off int64
+	var header [recordHeaderSize]byte
+	for i := seg.first; i < seq && off < seg.size; i++ {
+		if _, err := f.ReadAt(header[:], off); err != nil {
+			return 0, fmt.Errorf("failed to read queue segment: %w", err)
+		}
+		n, _, _ := decodeHeader(header[:])
+		off += recordHeaderSize + int64(n)
+	}
+	return off, nil
+}
+
+// decodeHeader splits a record header into the data length, the checksum and the enqueue time.
+func decodeHeader(h []byte) (uint32, uint32, time.Time) {
+	return binary.LittleEndian.Uint32(h[0:4]), binary.LittleEndian.Uint32(h[4:8]),
+		time.Unix(0, int64(binary.LittleEndian.Uint64(h[8:16])))
+}
+
+// readCursor returns the persisted head sequence, or 0 if there is none.
+func (q *Queue) readCursor() uint64 {
+	data, err := os.ReadFile(filepath.Join(q.dir, cursorFile))
+	if err != nil {
+		return 0
+	}
+	seq, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return seq
+}
+
+// writeCursor persists the head sequence atomically.
+func (q *Queue) writeCursor() error {
+	tmp := filepath.Join(q.dir, cursorFile+".tmp")
+	if err := os.WriteFile(tmp, []byte(strconv.FormatUint(q.head, 10)+"\n"), 0o600); err != nil {
+		return fmt.Errorf("failed to write queue cursor: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(q.dir, cursorFile)); err != nil {
+		return fmt.Errorf("failed to write queue cursor: %w", err)
+	}
+	return nil
+}
+
+// roll starts a new segment at the next sequence number.
+func (q *Queue) roll() error {
+	if q.w != nil {
+		if err := q.w.Sync(); err != nil {
+			return fmt.Errorf("failed to sync queue segment: %w", err)
+		}
+		if err := q.w.Close(); err != nil {
+			return fmt.Errorf("failed to close queue segment: %w", err)
+		}
+		q.w, q.dirty = nil, false
+	}
+	seg := &segment{first: q.next, path: filepath.Join(q.dir, fmt.Sprintf("%020d%s", q.next, segmentExt))}
+	f, err := os.OpenFile(seg.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create queue segment: %w", err)
+	}
+	q.segs = append(q.segs, seg)
+	q.w = f
+	// Sync the directory so the new segment survives a power loss.
+	if d, err := os.Open(q.dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+	return nil
+}
+
+// removeFirst deletes the oldest segment, whose records are all acknowledged or dropped.
+func (q *Queue) removeFirst() error {
+	seg := q.segs[0]
+	if q.r != nil && q.rpath == seg.path {
+		_ = q.r.Close()
+		q.r, q.rpath = nil, ""
+	}
+	if err := os.Remove(seg.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove queue segment: %w", err)
+	}
+	q.segs = q.segs[1:]
+	q.head, q.hoff = max(q.head, q.segs[0].first), 0
+	return nil
+}
+
+// Put appends a record, applying the full policy when it does not fit.
+//
+// Parameters:
+//   - ctx: Bounds the wait under the Block policy.
+//   - data: The payload.
+//
+// Returns:
+//   - error: ErrFull (DropNewest), ErrTooLarge, ErrClosed, ctx.Err() (Block) or a write error.
+func (q *Queue) Put(ctx context.Context, data []byte) error {
+	size := int64(recordHeaderSize + len(data))
+	if size > q.opts.MaxBytes || len(data) > 1<<31 {
+		return ErrTooLarge
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		if q.closed {
+			return ErrClosed
+		}
+		// An empty queue always takes the record.
+		if q.bytes+size <= q.opts.MaxBytes || q.head == q.next {
+			break
+		}
+		switch q.opts.FullPolicy {
+		case DropNewest:
+			q.dropped++
+			return ErrFull
+		case DropOldest:
+			if err := q.dropHead(); err != nil {
+				return err
+			}
+		case Block:
+			if err := q.wait(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	last := q.segs[len(q.segs)-1]
+	if last.count > 0 && last.size+size > q.opts.SegmentBytes {
+		if err := q.roll(); err != nil {
+			return err
+		}
+		last = q.segs[len(q.segs)-1]
+	}
+	now := q.now()
+	buf := make([]byte, size)
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(data)))
+	binary.LittleEndian.PutUint64(buf[8:16], uint64(now.UnixNano()))
+	copy(buf[recordHeaderSize:], data)
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.Checksum(buf[8:], castagnoli))
+	if _, err := q.w.Write(buf); err != nil {
+		// Cut a partial record off so the next one starts on a record boundary.
+		_ = q.w.Truncate(last.size)
+		return fmt.Errorf("failed to append to queue: %w", err)
+	}
+	last.count++
+	last.size += size
+	last.newest = now
+	q.next++
+	q.bytes += size
+	q.dirty = true
+	if q.opts.SyncInterval == 0 || now.Sub(q.synced) >= q.opts.SyncInterval {
+		if err := q.syncLocked(); err != nil {
+			return err
+		}
+	}
+	q.notify()
+	return nil
+}
+
+// Peek returns the oldest unacknowledged record, waiting until there is one.
+//
+// Parameters:
+//   - ctx: Bounds the wait.
+//
+// Returns:
+//   - Record: The record; it stays queued until Ack(record.Seq).
+//   - error: ErrClosed, ctx.Err() or a read error.
+func (q *Queue) Peek(ctx context.Context) (Record, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		if q.closed {
+			return Record{}, ErrClosed
+		}
+		if q.head == q.next {
+			if err := q.wait(ctx); err != nil {
+				return Record{}, err
+			}
+			continue
+		}
+		rec, size, err := q.readHead(true)
+		if err != nil {
+			return Record{}, err
+		}
+		if size == 0 {
+			continue
+		}
+		if q.opts.MaxAge > 0 && q.now().Sub(rec.Time) > q.opts.MaxAge {
+			q.dropped++
+			if err := q.advance(size); err != nil {
+				return Record{}, err
+			}
+			continue
+		}
+		return rec, nil
+	}
+}
+
+// readHead reads the head record and returns its size on disk; a size of 0 means the head moved instead (the rest
+// of a corrupt segment was skipped) and must be read again.
+func (q *Queue) readHead(withData bool) (Record, int64, error) {
+	seg := q.segs[0]
+	if q.hoff >= seg.size {
+		// The segment ended early (a gap after a corrupt tail); continue with the next one.
+		if len(q.segs) == 1 {
+			q.head = q.next
+			return Record{}, 0, nil
+		}
+		return Record{}, 0, q.removeFirst()
+	}
+	if q.rpath != seg.path {
+		if q.r != nil {
+			_ = q.r.Close()
+		}
+		f, err := os.Open(seg.path)
+		if err != nil {
+			return Record{}, 0, fmt.Errorf("failed to open queue segment: %w", err)
+		}
+		q.r, q.rpath = f, seg.path
+	}
+	var header [recordHeaderSize]byte
+	if _, err := q.r.ReadAt(header[:], q.hoff); err != nil {
+		return Record{}, 0, q.skipCorrupt()
+	}
+	n, sum, ts := decodeHeader(header[:])
+	size := recordHeaderSize + int64(n)
+	if q.hoff+size > seg.size {
+		return Record{}, 0, q.skipCorrupt()
+	}
+	rec := Record{Seq: q.head, Time: ts}
+	if withData {
+		rec.Data = make([]byte, n)
+		if _, err := q.r.ReadAt(rec.Data, q.hoff+recordHeaderSize); err != nil {
+			return Record{}, 0, q.skipCorrupt()
+		}
+		if crc32.Update(crc32.Checksum(header[8:], castagnoli), castagnoli, rec.Data) != sum {
+			return Record{}, 0, q.skipCorrupt()
+		}
+	}
+	return rec, size, nil
+}
+
+// skipCorrupt discards the rest of the first segment after a record failed its checksum.
+func (q *Queue) skipCorrupt() error {
+	seg := q.segs[0]
+	q.corrupted += seg.size - q.hoff
+	q.bytes -= seg.size - q.hoff
+	q.dropped += seg.first + seg.count - q.head
+	if len(q.segs) == 1 {
+		// The damaged records are the newest ones; cut them off so appends continue on a record boundary.
+		if err := os.Truncate(seg.path, q.hoff); err != nil {
+			return fmt.Errorf("failed to truncate corrupt queue segment: %w", err)
+		}
+		seg.size, seg.count = q.hoff, q.head-seg.first
+		q.next = q.head
+		q.notify()
+		return nil
+	}
+	return q.removeFirst()
+}
+
+// Ack removes the record seq, which must be the one last returned by Peek; acknowledging a record that was
+// already dropped by a cap is a no-op.
+//
+// Parameters:
+//   - seq: Record.Seq of the delivered record.
+//
+// Returns:
+//   - error: ErrClosed or an error persisting the cursor.
+func (q *Queue) Ack(seq uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	if seq != q.head || q.head == q.next {
+		return nil
+	}
+	_, size, err := q.readHead(false)
+	if err != nil || size == 0 {
+		return err
+	}
+	if err := q.advance(size); err != nil {
+		return err
+	}
+	return q.writeCursor()
+}
+
+// dropHead drops the oldest record to make room.
+func (q *Queue) dropHead() error {
+	_, size, err := q.readHead(false)
+	if err != nil || size == 0 {
+		return err
+	}
+	q.dropped++
+	return q.advance(size)
+}
+
+// advance moves the head past a record of size bytes and removes the first segment once it is consumed.
+func (q *Queue) advance(size int64) error {
+	q.head++
+	q.hoff += size
+	q.bytes -= size
+	if len(q.segs) > 1 && q.head >= q.segs[0].first+q.segs[0].count {
+		if err := q.removeFirst(); err != nil {
+			return err
+		}
+	}
+	q.notify()
+	return nil
+}
+
+// wait releases the lock until the queue changes or ctx is done.
+func (q *Queue) wait(ctx context.Context) error {
+	changed := q.changed
+	q.mu.Unlock()
+	defer q.mu.Lock()
+	select {
+	case <-changed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// notify wakes every waiter.
+func (q *Queue) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// Sync flushes appended records and the consumer position to disk.
+//
+// Returns:
+//   - error: ErrClosed or an fsync error.
+func (q *Queue) Sync() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	if err := q.syncLocked(); err != nil {
+		return err
+	}
+	return q.writeCursor()
+}
+
+// syncLocked fsyncs the last segment if it has unsynced records.
+func (q *Queue) syncLocked() error {
+	if q.dirty && q.w != nil {
+		if err := q.w.Sync(); err != nil {
+			return fmt.Errorf("failed to sync queue segment: %w", err)
+		}
+	}
+	q.dirty, q.synced = false, q.now()
+	return nil
+}
+
+// Stats returns the current depth, size and drop counters.
+//
+// Returns:
+//   - Stats: A snapshot.
+func (q *Queue) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return Stats{Depth: q.next - q.head, Bytes: q.bytes, Segments: len(q.segs), Dropped: q.dropped, Corrupted: q.corrupted}
+}
+
+// Close syncs the queue, wakes blocked callers with ErrClosed and releases the files.
+//
+// Returns:
+//   - error: If the final sync fails.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	err := q.syncLocked()
+	if cerr := q.writeCursor(); err == nil {
+		err = cerr
+	}
+	q.closed = true
+	q.notify()
+	q.closeFiles()
+	reg := q.metrics
+	q.metrics = nil
+	q.mu.Unlock()
+	// Unregister outside the lock: a collection in progress runs the callback, which takes it.
+	if reg != nil {
+		_ = reg.Unregister()
+	}
+	return err
+}
+
+// closeFiles closes the segment handles.
+func (q *Queue) closeFiles() {
+	for _, f := range []*os.File{q.w, q.r} {
+		if f != nil {
+			_ = f.Close()
+		}
+	}
+	q.w, q.r, q.rpath = nil, nil, ""
+}
@@ -0,0 +1,341 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// recordSize is the size on disk of a record with a 10-byte payload.
+const recordSize = recordHeaderSize + 10
+
+func openQueue(t *testing.T, dir string, opts Options) *Queue {
+	t.Helper()
+	q, err := Open(dir, opts)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = q.Close() })
+	return q
+}
+
+func put(t *testing.T, q *Queue, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		require.NoError(t, q.Put(context.Background(), fmt.Appendf(nil, "record-%03d", i)))
+	}
+}
+
+// take peeks and acknowledges the head record and returns its payload.
+func take(t *testing.T, q *Queue) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rec, err := q.Peek(ctx)
+	require.NoError(t, err)
+	require.NoError(t, q.Ack(rec.Seq))
+	return string(rec.Data)
+}
+
+func segments(t *testing.T, dir string) []string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.NoError(t, err)
+	return paths
+}
+
+func TestQueue_OrderAcrossReopen(t *testing.T) {
+	dir := t.TempDir()
+	q := openQueue(t, dir, Options{})
+	put(t, q, 0, 10)
+	assert.Equal(t, "record-000", take(t, q))
+	assert.Equal(t, "record-001", take(t, q))
+
+	// A record that was peeked but not acknowledged is delivered again.
+	rec, err := q.Peek(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "record-002", string(rec.Data))
+	require.NoError(t, q.Close())
+	assert.ErrorIs(t, q.Put(context.Background(), []byte("x")), ErrClosed)
+
+	q = openQueue(t, dir, Options{})
+	assert.Equal(t, Stats{Depth: 8, Bytes: 8 * recordSize, Segments: 1}, q.Stats())
+	put(t, q, 10, 12)
+	for i := 2; i < 12; i++ {
+		assert.Equal(t, fmt.Sprintf("record-%03d", i), take(t, q))
+	}
+	assert.Equal(t, uint64(0), q.Stats().Depth)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = q.Peek(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "Peek waits for a record")
+}
+
+func TestQueue_PeekWakesOnPut(t *testing.T) {
+	q := openQueue(t, t.TempDir(), Options{})
+	got := make(chan string, 1)
+	go func() {
+		rec, err := q.Peek(context.Background())
+		if err == nil {
+			got <- string(rec.Data)
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	put(t, q, 0, 1)
+	select {
+	case data := <-got:
+		assert.Equal(t, "record-000", data)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Peek did not wake up")
+	}
+}
+
+func TestQueue_SegmentRolling(t *testing.T) {
+	dir := t.TempDir()
+	q := openQueue(t, dir, Options{SegmentBytes: 3 * recordSize})
+	put(t, q, 0, 10)
+	assert.Len(t, segments(t, dir), 4)
+	for range 7 {
+		take(t, q)
+	}
+	assert.Len(t, segments(t, dir), 2, "consumed segments are removed")
+	require.NoError(t, q.Close())
+
+	q = openQueue(t, dir, Options{SegmentBytes: 3 * recordSize})
+	assert.Equal(t, uint64(3), q.Stats().Depth)
+	assert.Equal(t, "record-007", take(t, q))
+}
+
+func TestQueue_FullPolicies(t *testing.T) {
+	opts := Options{MaxBytes: 3 * recordSize, FullPolicy: DropOldest}
+	q := openQueue(t, t.TempDir(), opts)
+	put(t, q, 0, 5)
+	assert.Equal(t, uint64(3), q.Stats().Depth)
+	assert.Equal(t, uint64(2), q.Stats().Dropped)
+	assert.Equal(t, "record-002", take(t, q))
+	assert.ErrorIs(t, q.Put(context.Background(), make([]byte, 3*recordSize)), ErrTooLarge)
+
+	opts.FullPolicy = DropNewest
+	q = openQueue(t, t.TempDir(), opts)
+	put(t, q, 0, 3)
+	assert.ErrorIs(t, q.Put(context.Background(), []byte("record-003")), ErrFull)
+	assert.Equal(t, "record-000", take(t, q))
+	put(t, q, 4, 5)
+	assert.Equal(t, Stats{Depth: 3, Bytes: 3 * recordSize, Segments: 2, Dropped: 1}, q.Stats(), "the segment size defaults to the queue size")
+
+	opts.FullPolicy = Block
+	q = openQueue(t, t.TempDir(), opts)
+	put(t, q, 0, 3)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, q.Put(ctx, []byte("record-003")), context.DeadlineExceeded)
+	done := make(chan error, 1)
+	go func() { done <- q.Put(context.Background(), []byte("record-003")) }()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, "record-000", take(t, q))
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Put did not unblock after Ack")
+	}
+	assert.Equal(t, Stats{Depth: 3, Bytes: 3 * recordSize, Segments: 2}, q.Stats())
+
+	_, err := Open(t.TempDir(), Options{FullPolicy: "spill"})
+	assert.ErrorContains(t, err, `invalid full policy "spill"`)
+}
+
+func TestQueue_MaxAge(t *testing.T) {
+	q := openQueue(t, t.TempDir(), Options{MaxAge: time.Hour})
+	now := time.Now()
+	q.now = func() time.Time { return now }
+	put(t, q, 0, 2)
+	now = now.Add(30 * time.Minute)
+	put(t, q, 2, 3)
+	now = now.Add(45 * time.Minute)
+	assert.Equal(t, "record-002", take(t, q))
+	assert.Equal(t, uint64(2), q.Stats().Dropped)
+}
+
+func TestQueue_TornTail(t *testing.T) {
+	dir := t.TempDir()
+	q := openQueue(t, dir, Options{})
+	put(t, q, 0, 5)
+	require.NoError(t, q.Close())
+	seg := segments(t, dir)[0]
+	require.NoError(t, os.Truncate(seg, 5*recordSize-4))
+
+	q = openQueue(t, dir, Options{})
+	assert.Equal(t, Stats{Depth: 4, Bytes: 4 * recordSize, Segments: 1, Corrupted: recordSize - 4}, q.Stats())
+	put(t, q, 5, 6)
+	for _, want := range []int{0, 1, 2, 3, 5} {
+		assert.Equal(t, fmt.Sprintf("record-%03d", want), take(t, q))
+	}
+}
+
+// flipByte corrupts the payload of record i of a segment made of 10-byte records.
+func flipByte(t *testing.T, path string, i int) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[i*recordSize+recordHeaderSize+3] ^= 0xff
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func TestQueue_Corruption(t *testing.T) {
+	// In the last segment, a bad checksum cuts the tail off on Open.
+	dir := t.TempDir()
+	q := openQueue(t, dir, Options{})
+	put(t, q, 0, 5)
+	require.NoError(t, q.Close())
+	flipByte(t, segments(t, dir)[0], 3)
+	q = openQueue(t, dir, Options{})
+	assert.Equal(t, Stats{Depth: 3, Bytes: 3 * recordSize, Segments: 1, Corrupted: 2 * recordSize}, q.Stats())
+
+	// In an older segment, it is found on read and the rest of that segment is skipped.
+	dir = t.TempDir()
+	opts := Options{SegmentBytes: 3 * recordSize}
+	q = openQueue(t, dir, opts)
+	put(t, q, 0, 6)
+	require.NoError(t, q.Close())
+	flipByte(t, segments(t, dir)[0], 1)
+	q = openQueue(t, dir, opts)
+	assert.Equal(t, "record-000", take(t, q))
+	assert.Equal(t, "record-003", take(t, q))
+	stats := q.Stats()
+	assert.Equal(t, uint64(2), stats.Dropped)
+	assert.Equal(t, int64(2*recordSize), stats.Corrupted)
+	assert.Equal(t, uint64(2), stats.Depth)
+}
+
+func TestConfig_Options(t *testing.T) {
+	opts, err := Config{MaxSizeMiB: 10, SegmentSizeMiB: 2, FullPolicy: Block, MaxAge: time.Hour}.Options()
+	require.NoError(t, err)
+	assert.Equal(t, Options{MaxBytes: 10 << 20, SegmentBytes: 2 << 20, FullPolicy: Block, MaxAge: time.Hour}, opts)
+
+	for _, c := range []Config{
+		{MaxSizeMiB: -1}, {SegmentSizeMiB: -1}, {MaxSizeMiB: 1, SegmentSizeMiB: 2},
+		{MaxAge: -time.Second}, {SyncInterval: -time.Second}, {FullPolicy: "spill"},
+	} {
+		_, err := c.Options()
+		assert.Error(t, err, c)
+	}
+	assert.Equal(t, "/var/q", Config{Dir: "/var/q"}.Directory("diag_exporter"))
+	assert.Equal(t, "diag_exporter", filepath.Base(Config{}.Directory("diag_exporter")))
+}
+
+func TestQueue_RegisterMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	q, err := Open(t.TempDir(), Options{MaxBytes: 2 * recordSize})
+	require.NoError(t, err)
+	require.NoError(t, q.RegisterMetrics(mp, "diag_exporter"))
+	put(t, q, 0, 3)
+
+	collect := func() map[string]int64 {
+		var rm metricdata.ResourceMetrics
+		require.NoError(t, reader.Collect(context.Background(), &rm))
+		out := make(map[string]int64)
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				var points []metricdata.DataPoint[int64]
+				switch data := m.Data.(type) {
+				case metricdata.Gauge[int64]:
+					points = data.DataPoints
+				case metricdata.Sum[int64]:
+					points = data.DataPoints
+				}
+				for _, p := range points {
+					name, _ := p.Attributes.Value("queue")
+					assert.Equal(t, "diag_exporter", name.AsString())
+					out[m.Name] = p.Value
+				}
+			}
+		}
+		return out
+	}
+	assert.Equal(t, map[string]int64{MetricDepth: 2, MetricBytes: 2 * recordSize, MetricDropped: 1}, collect())
+	require.NoError(t, q.Close())
+	assert.Empty(t, collect(), "Close unregisters the instruments")
+}
+
+// crashDirEnv makes the test binary run as the producer of TestQueue_CrashRecovery.
+const crashDirEnv = "SREDIAG_QUEUE_CRASH_DIR"
+
+// TestCrashProducer is not a test: run by TestQueue_CrashRecovery, it appends records tagged with its round until it
+// is killed and prints the index of every record once Put has returned.
+func TestCrashProducer(t *testing.T) {
+	dir := os.Getenv(crashDirEnv)
+	if dir == "" {
+		t.Skip("helper process of TestQueue_CrashRecovery")
+	}
+	q, err := Open(dir, Options{SegmentBytes: 64 << 10})
+	if err != nil {
+		fmt.Println("error:", err)
+		os.Exit(1)
+	}
+	round := os.Getenv(crashDirEnv + "_ROUND")
+	for i := 0; ; i++ {
+		// Vary the size so records straddle page and segment boundaries.
+		payload := fmt.Sprintf("%s-%d-%s", round, i, strings.Repeat("x", (i*37)%4000))
+		if err := q.Put(context.Background(), []byte(payload)); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+		fmt.Println(i)
+	}
+}
+
+func TestQueue_CrashRecovery(t *testing.T) {
+	if testing.Short() {
+		t.Skip("spawns and kills producer processes")
+	}
+	dir := t.TempDir()
+	for round := range 5 {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestCrashProducer$")
+		cmd.Env = append(os.Environ(), crashDirEnv+"="+dir, crashDirEnv+"_ROUND="+strconv.Itoa(round))
+		stdout, err := cmd.StdoutPipe()
+		require.NoError(t, err)
+		require.NoError(t, cmd.Start())
+
+		// Kill the producer at a different point in every round.
+		confirmed := -1
+		lines := bufio.NewScanner(stdout)
+		for confirmed < 50+round*40 && lines.Scan() {
+			n, err := strconv.Atoi(lines.Text())
+			require.NoError(t, err, lines.Text())
+			confirmed = n
+		}
+		require.NoError(t, cmd.Process.Signal(syscall.SIGKILL))
+		for lines.Scan() {
+			if n, err := strconv.Atoi(lines.Text()); err == nil {
+				confirmed = n
+			}
+		}
+		_ = cmd.Wait()
+
+		// Every confirmed record is back, intact and in order; at most the one in flight follows.
+		q, err := Open(dir, Options{SegmentBytes: 64 << 10})
+		require.NoError(t, err)
+		depth := int(q.Stats().Depth)
+		require.GreaterOrEqual(t, depth, confirmed+1, "round %d", round)
+		require.LessOrEqual(t, depth, confirmed+2, "round %d", round)
+		for i := range depth {
+			want := fmt.Sprintf("%d-%d-%s", round, i, strings.Repeat("x", (i*37)%4000))
+			require.Equal(t, want, take(t, q), "round %d record %d", round, i)
+		}
+		require.NoError(t, q.Close())
+	}
+}
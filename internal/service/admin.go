@@ -0,0 +1,478 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/srediag/srediag/internal/admin"
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package service provides the core service orchestration, lifecycle management, and CLI stubs for the SREDIAG collector service.
+//
+// This file defines the local admin API of the agent: JSON over HTTP on a Unix socket (service.socket). Access is
+// controlled by file permissions: the socket is created with mode 0600, so only the agent's user (and root) can
+// connect. The 'srediag service' subcommands talk to a running agent through AdminClient, which adds the typed
+// endpoints to the shared admin.Client transport.
+//
+// Endpoints:
+//   - GET  /v1/status: StatusReport of the agent.
+//   - POST /v1/stop:   Graceful shutdown; the agent stops after answering.
+//   - POST /v1/reload: Live reload (Service.Reload); answers with the ReloadReport, 422 if the new config is
+//     invalid and 500 if it failed to start and was rolled back.
+//   - GET  /v1/profile/{type}?seconds=N: A profile of the agent (see WriteProfile); 400 for an unknown type or
+//     duration and 409 if a profile of the same kind is already being captured.
+//   - GET  /v1/logs?level=&component=&field=k=v&since=&lines=&follow=true: Recent log entries as JSON lines (see
+//     LogQuery); with follow the stream stays open until the client disconnects or the agent stops.
+//
+// Usage:
+//   - In the agent: NewAdminServer, SetLogBuffer, Start, wait on StopRequested, Shutdown.
+//   - In clients: NewAdminClient(socket).Status / Stop / Reload / Profile / Logs; ErrNotRunning means no agent
+//     listens on the socket.
+//
+// Best Practices:
+//   - Keep handlers cheap and non-blocking; long operations belong in the agent's main loop.
+
+// adminRequestTimeout bounds one admin API request, so a hung agent cannot block its clients.
+const adminRequestTimeout = admin.RequestTimeout
+
+// adminSocketMode is the permission of the admin socket; it is the API's only access control.
+const adminSocketMode = 0o600
+
+// ErrNotRunning is returned by AdminClient when no agent listens on the admin socket.
+var ErrNotRunning = admin.ErrNotRunning
+
+// AdminServer serves the admin API of a running agent.
+type AdminServer struct {
+	logger   *core.Logger
+	socket   string
+	svc      *Service
+	server   *http.Server
+	listener net.Listener
+	logs     *core.LogBuffer
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	closing  chan struct{} // Closed by Shutdown to end streaming requests
+}
+
+// NewAdminServer creates the admin API server of a service.
+//
+// Parameters:
+//   - logger: Logger for status and error reporting.
+//   - socket: Unix socket path (service.socket).
+//   - svc: The service whose status is served.
+//
+// Returns:
+//   - *AdminServer: The server; call Start to listen.
+func NewAdminServer(logger *core.Logger, socket string, svc *Service) *AdminServer {
+	a := &AdminServer{logger: logger, socket: socket, svc: svc, stop: make(chan struct{}), closing: make(chan struct{})}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", a.handleStatus)
+	mux.HandleFunc("POST /v1/stop", a.handleStop)
+	mux.HandleFunc("POST /v1/reload", a.handleReload)
+	mux.HandleFunc("GET /v1/profile/{type}", a.handleProfile)
+	mux.HandleFunc("GET /v1/logs", a.handleLogs)
+	a.server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	var closeOnce sync.Once
+	a.server.RegisterOnShutdown(func() { closeOnce.Do(func() { close(a.closing) }) })
+	return a
+}
+
+// SetLogBuffer sets the buffer of recent log entries served by GET /v1/logs; call it before Start.
+//
+// Parameters:
+//   - buf: The buffer the agent's logger is teed into (core.Logger.Tee), or nil to disable the endpoint.
+func (a *AdminServer) SetLogBuffer(buf *core.LogBuffer) {
+	a.logs = buf
+}
+
+// Start listens on the admin socket and serves requests in the background.
+//
+// A leftover socket of a crashed agent is replaced; a socket with a live agent behind it is not.
+//
+// Returns:
+//   - error: ErrAlreadyRunning if another agent answers on the socket, or a detailed error.
+func (a *AdminServer) Start() error {
+	if err := os.MkdirAll(filepath.Dir(a.socket), 0o700); err != nil {
+		return fmt.Errorf("failed to create admin socket directory: %w", err)
+	}
+	if _, err := os.Stat(a.socket); err == nil {
+		if conn, err := net.DialTimeout("unix", a.socket, time.Second); err == nil {
+			_ = conn.Close()
+			return fmt.Errorf("%w (admin socket %s)", ErrAlreadyRunning, a.socket)
+		}
+		if err := os.Remove(a.socket); err != nil {
+			return fmt.Errorf("failed to remove stale admin socket: %w", err)
+		}
+	}
+	ln, err := listenPrivate(a.socket)
+	if err != nil {
+		return err
+	}
+	a.listener = ln
+	go func() {
+		if err := a.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.logger.Error("Admin API stopped", core.ZapError(err))
+		}
+	}()
+	a.logger.Info("Admin API listening", core.ZapString("socket", a.socket))
+	return nil
+}
+
+// listenPrivate listens on a Unix socket that no other user can connect to at any point: the socket is bound in a
+// fresh 0700 directory next to path, restricted to adminSocketMode and only then renamed to path. Restricting it
+// in place would leave it open to others between bind and chmod under a permissive umask.
+func listenPrivate(path string) (net.Listener, error) {
+	staging, err := os.MkdirTemp(filepath.Dir(path), ".srediag-sock-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin socket directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(staging) }()
+	bound := filepath.Join(staging, "s")
+	ln, err := net.Listen("unix", bound)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on admin socket: %w", err)
+	}
+	// The socket is removed by Shutdown under its final name.
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(bound, adminSocketMode); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("failed to restrict admin socket: %w", err)
+	}
+	if err := os.Rename(bound, path); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("failed to publish admin socket: %w", err)
+	}
+	return ln, nil
+}
+
+// StopRequested returns a channel closed when a client asks the agent to stop.
+//
+// Returns:
+//   - <-chan struct{}: The stop request channel.
+func (a *AdminServer) StopRequested() <-chan struct{} {
+	return a.stop
+}
+
+// Shutdown stops serving and removes the socket.
+//
+// Parameters:
+//   - ctx: Context bounding the wait for in-flight requests.
+//
+// Returns:
+//   - error: If the server cannot be shut down, returns a detailed error.
+func (a *AdminServer) Shutdown(ctx context.Context) error {
+	if a.listener == nil {
+		return nil
+	}
+	err := a.server.Shutdown(ctx)
+	if rerr := os.Remove(a.socket); rerr != nil && !errors.Is(rerr, os.ErrNotExist) && err == nil {
+		err = rerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stop admin API: %w", err)
+	}
+	return nil
+}
+
+// handleStatus serves GET /v1/status.
+func (a *AdminServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.svc.Status(r.Context()))
+}
+
+// handleStop serves POST /v1/stop.
+func (a *AdminServer) handleStop(w http.ResponseWriter, _ *http.Request) {
+	a.logger.Info("Stop requested over the admin API")
+	a.stopOnce.Do(func() { close(a.stop) })
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "stopping"})
+}
+
+// handleReload serves POST /v1/reload.
+func (a *AdminServer) handleReload(w http.ResponseWriter, r *http.Request) {
+	a.logger.Info("Reload requested over the admin API")
+	report, err := a.svc.Reload(r.Context())
+	var reloadErr *ReloadError
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, report)
+	case errors.As(err, &reloadErr) && reloadErr.Invalid:
+		writeJSON(w, http.StatusUnprocessableEntity, report)
+	case report != nil:
+		writeJSON(w, http.StatusInternalServerError, report)
+	default:
+		http.Error(w, err.Error(), http.StatusConflict)
+	}
+}
+
+// handleProfile serves GET /v1/profile/{type}. The profile is buffered so a failed capture can still be reported
+// with an error status.
+func (a *AdminServer) handleProfile(w http.ResponseWriter, r *http.Request) {
+	kind := r.PathValue("type")
+	if !slices.Contains(ProfileTypes, kind) {
+		http.Error(w, fmt.Sprintf("unknown profile type %q (want one of %s)", kind, strings.Join(ProfileTypes, ", ")), http.StatusBadRequest)
+		return
+	}
+	var d time.Duration
+	if s := r.URL.Query().Get("seconds"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || time.Duration(n)*time.Second > MaxProfileDuration {
+			http.Error(w, fmt.Sprintf("invalid seconds %q (1 to %d)", s, int(MaxProfileDuration.Seconds())), http.StatusBadRequest)
+			return
+		}
+		d = time.Duration(n) * time.Second
+	}
+	a.logger.Info("Profile requested over the admin API", core.ZapString("type", kind))
+	var buf bytes.Buffer
+	if err := WriteProfile(r.Context(), &buf, kind, d); err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrProfileBusy) {
+			code = http.StatusConflict
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	_, _ = buf.WriteTo(w)
+}
+
+// handleLogs serves GET /v1/logs. It does not log itself, so following the logs adds nothing to them.
+func (a *AdminServer) handleLogs(w http.ResponseWriter, r *http.Request) {
+	if a.logs == nil {
+		http.Error(w, "the agent keeps no log buffer", http.StatusNotFound)
+		return
+	}
+	q, err := parseLogQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	backlog := a.logs.Entries()
+	var sub *core.LogSubscription
+	if q.Follow {
+		backlog, sub = a.logs.Subscribe()
+		defer sub.Close()
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	for _, e := range filterLogEntries(backlog, q) {
+		if err := enc.Encode(e); err != nil {
+			return
+		}
+	}
+	if sub == nil {
+		return
+	}
+	rc := http.NewResponseController(w)
+	var dropped uint64
+	for {
+		if err := rc.Flush(); err != nil {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-a.closing:
+			return
+		case e := <-sub.C():
+			if n := sub.Dropped(); n > dropped {
+				_ = enc.Encode(core.LogEntry{
+					Time: time.Now(), Level: "warn", Component: "admin",
+					Message: fmt.Sprintf("log stream fell behind, %d entries dropped", n-dropped),
+				})
+				dropped = n
+			}
+			if q.Match(e) {
+				if err := enc.Encode(e); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// writeJSON writes v as the JSON response body.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// AdminClient talks to the admin API of a running agent; it adds the typed endpoints to admin.Client.
+type AdminClient struct {
+	api *admin.Client
+}
+
+// NewAdminClient creates an admin API client for a Unix socket.
+//
+// Parameters:
+//   - socket: Path of the agent's admin socket (service.socket).
+//
+// Returns:
+//   - *AdminClient: The client; the socket is dialled per request.
+func NewAdminClient(socket string) *AdminClient {
+	return &AdminClient{api: admin.NewClient(socket)}
+}
+
+// Status returns the status of the running agent.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts.
+//
+// Returns:
+//   - *StatusReport: The agent's status.
+//   - error: ErrNotRunning if no agent listens, or a detailed error.
+func (c *AdminClient) Status(ctx context.Context) (*StatusReport, error) {
+	var out StatusReport
+	if err := c.api.Do(ctx, http.MethodGet, "/v1/status", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Stop asks the running agent to shut down; it returns once the request is accepted.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts.
+//
+// Returns:
+//   - error: ErrNotRunning if no agent listens, or a detailed error.
+func (c *AdminClient) Stop(ctx context.Context) error {
+	return c.api.Do(ctx, http.MethodPost, "/v1/stop", nil)
+}
+
+// Reload asks the running agent to reload its configuration and waits for the outcome; starting components can
+// take longer than adminRequestTimeout, so only ctx bounds the wait.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts.
+//
+// Returns:
+//   - *ReloadReport: What the reload changed, also when it failed.
+//   - error: A *ReloadError if the reload failed, ErrNotRunning if no agent listens, or a detailed error.
+func (c *AdminClient) Reload(ctx context.Context) (*ReloadReport, error) {
+	resp, err := c.api.Send(ctx, http.MethodPost, "/v1/reload")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var report ReloadReport
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusUnprocessableEntity, http.StatusInternalServerError:
+		if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+			return nil, fmt.Errorf("failed to decode admin API response: %w", err)
+		}
+	default:
+		return nil, admin.StatusError(http.MethodPost, "/v1/reload", resp)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &report, &ReloadError{Invalid: resp.StatusCode == http.StatusUnprocessableEntity, Err: errors.New(report.Error)}
+	}
+	return &report, nil
+}
+
+// Profile captures a profile of the running agent and copies it to w; timed profiles are bounded by ctx and the
+// duration rather than adminRequestTimeout.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts.
+//   - w: Destination of the profile.
+//   - kind: One of ProfileTypes.
+//   - d: Capture duration of cpu, trace, mutex and block profiles; 0 uses the agent's default.
+//
+// Returns:
+//   - int64: Bytes written to w.
+//   - error: ErrProfileBusy if the agent is already capturing one, ErrNotRunning if no agent listens, or a
+//     detailed error.
+func (c *AdminClient) Profile(ctx context.Context, w io.Writer, kind string, d time.Duration) (int64, error) {
+	path := "/v1/profile/" + url.PathEscape(kind)
+	wait := adminRequestTimeout
+	if timedProfile(kind) {
+		if d <= 0 {
+			d = DefaultProfileDuration
+		}
+		path += "?seconds=" + strconv.Itoa(max(1, int(d.Round(time.Second)/time.Second)))
+		wait += d
+	}
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	resp, err := c.api.Send(ctx, http.MethodGet, path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		err := admin.StatusError(http.MethodGet, path, resp)
+		if resp.StatusCode == http.StatusConflict {
+			err = fmt.Errorf("%w: %w", ErrProfileBusy, err)
+		}
+		return 0, err
+	}
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("failed to read profile: %w", err)
+	}
+	return n, nil
+}
+
+// Logs streams the matching log entries of the running agent to fn. Without q.Follow it returns after the
+// buffered entries, bounded by adminRequestTimeout; with q.Follow it runs until ctx is cancelled or the agent
+// stops.
+//
+// Parameters:
+//   - ctx: Context for cancellation; cancel it to stop following.
+//   - q: Which entries to return.
+//   - fn: Called for every entry, in order; an error stops the stream and is returned.
+//
+// Returns:
+//   - error: ErrNotRunning if no agent listens, ctx.Err() when following is cancelled, or a detailed error.
+func (c *AdminClient) Logs(ctx context.Context, q LogQuery, fn func(core.LogEntry) error) error {
+	if !q.Follow {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, adminRequestTimeout)
+		defer cancel()
+	}
+	path := "/v1/logs"
+	if v := q.values(); len(v) > 0 {
+		path += "?" + v.Encode()
+	}
+	resp, err := c.api.Send(ctx, http.MethodGet, path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return admin.StatusError(http.MethodGet, "/v1/logs", resp)
+	}
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var e core.LogEntry
+		if err := dec.Decode(&e); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to read log stream: %w", err)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
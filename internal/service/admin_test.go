@@ -0,0 +1,208 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// daemonConfig returns an agent config whose PID file and admin socket live in a temp dir.
+func daemonConfig(t *testing.T) *core.Config {
+	t.Helper()
+	// Unix socket paths are limited to ~100 bytes; t.TempDir can exceed that.
+	dir, err := os.MkdirTemp("", "srediag")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	cfg := core.NewConfig()
+	cfg.Service.PIDFile = filepath.Join(dir, "srediag.pid")
+	cfg.Service.Socket = filepath.Join(dir, "srediag.sock")
+	cfg.Service.LogFile = filepath.Join(dir, "srediag.log")
+	return cfg
+}
+
+// startAdmin runs a started service behind an admin server on the config's socket.
+func startAdmin(t *testing.T, cfg *core.Config) (*Service, *AdminServer) {
+	t.Helper()
+	logger := core.NewTestLogger(&bytes.Buffer{})
+	svc := NewService(logger, nil, nil, nil, nil)
+	svc.SetAgentInfo(core.BuildInfo{Version: "v1.2.3", Commit: "abc"}, cfg)
+	require.NoError(t, svc.Start(context.Background()))
+	admin := NewAdminServer(logger, cfg.Service.Socket, svc)
+	require.NoError(t, admin.Start())
+	t.Cleanup(func() { _ = admin.Shutdown(context.Background()) })
+	return svc, admin
+}
+
+func TestPIDFile_Lifecycle(t *testing.T) {
+	path := daemonConfig(t).Service.PIDFile
+
+	pf, err := AcquirePIDFile(path)
+	require.NoError(t, err)
+	pid, err := ReadPIDFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), pid)
+	assert.True(t, PIDFileLocked(path))
+
+	_, err = AcquirePIDFile(path)
+	assert.ErrorIs(t, err, ErrAlreadyRunning)
+
+	require.NoError(t, pf.Release())
+	assert.NoFileExists(t, path)
+	assert.False(t, PIDFileLocked(path))
+}
+
+func TestPIDFile_Stale(t *testing.T) {
+	path := daemonConfig(t).Service.PIDFile
+	require.NoError(t, os.WriteFile(path, []byte("999999\n"), 0o644))
+	assert.False(t, PIDFileLocked(path), "an unlocked file is left over by a dead agent")
+
+	pf, err := AcquirePIDFile(path)
+	require.NoError(t, err)
+	defer func() { _ = pf.Release() }()
+	pid, err := ReadPIDFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), pid)
+}
+
+func TestAdmin_StatusAndStop(t *testing.T) {
+	cfg := daemonConfig(t)
+	_, admin := startAdmin(t, cfg)
+
+	info, err := os.Stat(cfg.Service.Socket)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(adminSocketMode), info.Mode().Perm())
+	entries, err := os.ReadDir(filepath.Dir(cfg.Service.Socket))
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.NotContains(t, e.Name(), ".srediag-sock-", "the staging directory is removed")
+	}
+
+	client := NewAdminClient(cfg.Service.Socket)
+	st, err := client.Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, StateRunning, st.State)
+	assert.Equal(t, os.Getpid(), st.PID)
+	assert.Equal(t, "v1.2.3", st.Version)
+	assert.Contains(t, st.ConfigDigest, "sha256:")
+	assert.False(t, st.Collector.Enabled)
+
+	require.NoError(t, client.Stop(context.Background()))
+	select {
+	case <-admin.StopRequested():
+	case <-time.After(time.Second):
+		t.Fatal("stop request not delivered")
+	}
+
+	require.NoError(t, admin.Shutdown(context.Background()))
+	_, err = client.Status(context.Background())
+	assert.ErrorIs(t, err, ErrNotRunning)
+}
+
+func TestAdmin_SocketInUse(t *testing.T) {
+	cfg := daemonConfig(t)
+	startAdmin(t, cfg)
+
+	second := NewAdminServer(core.NewTestLogger(&bytes.Buffer{}), cfg.Service.Socket, nil)
+	assert.ErrorIs(t, second.Start(), ErrAlreadyRunning)
+}
+
+func TestAdmin_StaleSocket(t *testing.T) {
+	cfg := daemonConfig(t)
+	ln, err := net.Listen("unix", cfg.Service.Socket)
+	require.NoError(t, err)
+	// Leave the socket file behind, as a crashed agent would.
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, ln.Close())
+
+	_, err = NewAdminClient(cfg.Service.Socket).Status(context.Background())
+	assert.ErrorIs(t, err, ErrNotRunning)
+	startAdmin(t, cfg)
+}
+
+func TestConfigDigest(t *testing.T) {
+	cfg := core.NewConfig()
+	a := configDigest(cfg, map[string]any{"receivers": map[string]any{"otlp": nil, "nop": nil}})
+	b := configDigest(cfg, map[string]any{"receivers": map[string]any{"nop": nil, "otlp": nil}})
+	assert.Equal(t, a, b)
+	cfg.Service.Name = "other"
+	assert.NotEqual(t, a, configDigest(cfg, map[string]any{"receivers": map[string]any{"otlp": nil, "nop": nil}}))
+}
+
+func TestWriteStatus(t *testing.T) {
+	st := &StatusReport{
+		State: StateRunning, PID: 42, Version: "v1", StartedAt: time.Now(), Uptime: "1m0s", ConfigDigest: "sha256:00",
+		Collector: CollectorStatus{Enabled: true, State: "Running", Config: "/etc/srediag/srediag-service.yaml",
+			Pipelines: []PipelineStatus{{Name: "traces", Receivers: []string{"otlp"}, Exporters: []string{"debug"}}}},
+		Plugins: []PluginStatus{{Name: "journald", Type: "receiver", State: "healthy"}},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, WriteStatus(&buf, st, "table"))
+	for _, want := range []string{"running", "sha256:00", "traces", "otlp", "journald"} {
+		assert.Contains(t, buf.String(), want)
+	}
+
+	buf.Reset()
+	require.NoError(t, WriteStatus(&buf, st, "json"))
+	var decoded StatusReport
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, "traces", decoded.Collector.Pipelines[0].Name)
+
+	buf.Reset()
+	require.NoError(t, WriteStatus(&buf, st, "yaml"))
+	assert.Contains(t, buf.String(), "config_digest: sha256:00")
+
+	assert.Error(t, WriteStatus(&buf, st, "sarif"))
+}
+
+func TestCLI_StatusAndStop(t *testing.T) {
+	cfg := daemonConfig(t)
+	appCtx := &core.AppContext{Config: cfg}
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("output", "json", "")
+	cmd.Flags().Duration("timeout", 2*time.Second, "")
+	cmd.SetContext(context.Background())
+	assert.ErrorIs(t, CLI_Status(appCtx, cmd, nil), ErrNotRunning)
+	assert.ErrorIs(t, CLI_Stop(appCtx, cmd, nil), ErrNotRunning)
+
+	_, admin := startAdmin(t, cfg)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	require.NoError(t, CLI_Status(appCtx, cmd, nil))
+	assert.Contains(t, out.String(), `"state": "running"`)
+
+	// Emulate the agent's main loop: shut down once asked to.
+	go func() {
+		<-admin.StopRequested()
+		_ = admin.Shutdown(context.Background())
+	}()
+	require.NoError(t, CLI_Stop(appCtx, cmd, nil))
+	assert.Contains(t, out.String(), "SREDIAG service stopped")
+}
+
+func TestDetachArgs(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("config", "", "")
+	cmd.Flags().String("log-level", "", "")
+	require.NoError(t, cmd.Flags().Set("config", "srediag.yaml"))
+	require.NoError(t, cmd.Flags().Set("log-level", "debug"))
+
+	abs, err := filepath.Abs("srediag.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"service", "start", "--config", abs, "--log-level", "debug"}, detachArgs(cmd))
+
+	cmd.Flags().Duration("shutdown-timeout", 0, "")
+	require.NoError(t, cmd.Flags().Set("shutdown-timeout", "1m"))
+	assert.Equal(t, []string{"service", "start", "--config", abs, "--log-level", "debug", "--shutdown-timeout", "1m0s"}, detachArgs(cmd))
+}
@@ -1,11 +1,25 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/collector/component"
 
 	"github.com/srediag/srediag/internal/core"
+	"github.com/srediag/srediag/internal/diagnose"
+	"github.com/srediag/srediag/internal/plugin"
 )
 
 // Package service provides service lifecycle management and CLI entrypoints for the SREDIAG collector service.
@@ -22,19 +36,35 @@ import (
 //   - Use context-aware logging and error handling for better diagnostics.
 //
 // TODO:
-//   - Add context.Context support for cancellation and timeouts.
-//   - Implement actual service management logic for each command.
+//   - Implement actual service management logic for the remaining commands.
+
+// stopTimeout is the default service.shutdown_timeout, how long 'srediag service start' lets the components drain
+// on shutdown, and the default --timeout of the client commands.
+const stopTimeout = 30 * time.Second
 
 // CLI_Start is the entrypoint for 'srediag service start'.
 //
+// It runs the service in the foreground: the embedded collector (when collector.enabled), the diag_exporter, the
+// remote job runner and the admin API on service.socket, holding service.pid_file, until SIGINT/SIGTERM, a stop
+// request over the admin API or until the collector fails on its own. SIGHUP, 'POST /v1/reload' and, with
+// collector.watch, edits of the config files reload the configuration (see Service.Reload). With --detach it runs
+// CLI_Detach instead.
+//
+// On shutdown, Service.Stop drains the pipelines and buffers in order within --shutdown-timeout
+// (service.shutdown_timeout); a second SIGINT/SIGTERM abandons the drain. The logger is flushed last.
+//
 // Parameters:
 //   - ctx: Application context containing logger and configuration.
 //   - cmd: Cobra command instance.
 //   - args: Command-line arguments.
 //
 // Returns:
-//   - error: If service start fails or is not implemented, returns a detailed error.
+//   - error: If service start fails or a component exits with an error, returns a detailed error; it wraps
+//     ErrDataDropped if data had to be dropped on shutdown, so the process exits non-zero.
 func CLI_Start(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
+	if detach, _ := cmd.Flags().GetBool("detach"); detach {
+		return CLI_Detach(ctx, cmd, args)
+	}
 	logger := ctx.Logger
 	if logger == nil {
 		var err error
@@ -43,118 +73,369 @@ func CLI_Start(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("failed to create fallback logger: %w", err)
 		}
 	}
-	logger.Info("Service start not yet implemented")
-	return fmt.Errorf("service start not yet implemented")
+	// Runs last, after the PID file and the admin API are released.
+	defer func() { _ = logger.Shutdown() }()
+	// Recent entries are kept for 'srediag service tail-logs'.
+	logs := core.NewLogBuffer(LogBufferSize)
+	logger.Tee(logs)
+	defer logger.Tee(nil)
+	cfg := ctx.GetConfig()
+	shutdownTimeout, err := ShutdownTimeout(cfg)
+	if err != nil {
+		return err
+	}
+	if f := cmd.Flags().Lookup("shutdown-timeout"); f != nil && f.Changed {
+		if shutdownTimeout, err = cmd.Flags().GetDuration("shutdown-timeout"); err != nil || shutdownTimeout <= 0 {
+			return fmt.Errorf("invalid --shutdown-timeout %q", f.Value.String())
+		}
+	}
+	pidPath, socket, _ := daemonPaths(cfg)
+	pidFile, err := AcquirePIDFile(pidPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := pidFile.Release(); err != nil {
+			logger.Warn("Failed to release PID file", core.ZapError(err))
+		}
+	}()
+
+	svc, err := newServiceFromContext(ctx, cmd, logger)
+	if err != nil {
+		return err
+	}
+	admin := NewAdminServer(logger, socket, svc)
+	admin.SetLogBuffer(logs)
+	if err := admin.Start(); err != nil {
+		return err
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), adminRequestTimeout)
+		defer cancel()
+		if err := admin.Shutdown(shutdownCtx); err != nil {
+			logger.Warn("Failed to stop admin API", core.ZapError(err))
+		}
+	}()
+
+	runCtx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	var changes <-chan struct{}
+	if cfg.Collector.Watch {
+		watcher, err := NewConfigWatcher(logger, watchPaths(cfg, configFileFlag(cmd)), watchDebounce)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = watcher.Close() }()
+		changes = watcher.Changes()
+	}
+
+	if err := svc.Start(runCtx); err != nil {
+		return fmt.Errorf("failed to start service: %w", err)
+	}
+loop:
+	for {
+		select {
+		case <-runCtx.Done():
+			logger.Info("Shutdown requested")
+			break loop
+		case <-admin.StopRequested():
+			break loop
+		case <-svc.Done():
+			logger.Warn("Collector failed", core.ZapError(svc.Err()))
+			break loop
+		case <-hangup:
+			logger.Info("Received SIGHUP")
+			// Reload logs its outcome; a failed reload leaves the previous config running.
+			_, _ = svc.Reload(runCtx)
+		case <-changes:
+			logger.Info("Config files changed")
+			_, _ = svc.Reload(runCtx)
+		}
+	}
+
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer stopCancel()
+	// The first signal was consumed by runCtx; another one abandons the drain.
+	force := make(chan os.Signal, 1)
+	signal.Notify(force, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(force)
+	go func() {
+		select {
+		case <-force:
+			logger.Warn("Second signal received; abandoning graceful shutdown")
+			stopCancel()
+		case <-stopCtx.Done():
+		}
+	}()
+	if err := svc.Stop(stopCtx); err != nil {
+		if errors.Is(err, ErrDataDropped) {
+			logger.Error("Shutdown dropped data", core.ZapError(err))
+		}
+		return fmt.Errorf("failed to stop service: %w", err)
+	}
+	return nil
+}
+
+// timeoutFlag returns --timeout, or stopTimeout when the command has no such flag.
+func timeoutFlag(cmd *cobra.Command) time.Duration {
+	if d, err := cmd.Flags().GetDuration("timeout"); err == nil && d > 0 {
+		return d
+	}
+	return stopTimeout
+}
+
+// stopWait returns how long the client commands wait for the agent to exit: --timeout when it is given, otherwise
+// enough for the agent's shutdown deadline (service.shutdown_timeout) as well.
+func stopWait(cmd *cobra.Command, cfg *core.Config) time.Duration {
+	wait := timeoutFlag(cmd)
+	if f := cmd.Flags().Lookup("timeout"); f != nil && f.Changed {
+		return wait
+	}
+	if d, err := ShutdownTimeout(cfg); err == nil {
+		wait = max(wait, d+adminRequestTimeout)
+	}
+	return wait
+}
+
+// configFileFlag returns the agent config file given by --config or SREDIAG_CONFIG.
+func configFileFlag(cmd *cobra.Command) string {
+	if f := cmd.Flag("config"); f != nil && f.Value.String() != "" {
+		return f.Value.String()
+	}
+	return os.Getenv("SREDIAG_CONFIG")
+}
+
+// newServiceFromContext builds the service of the application context: the component factories of the
+// ComponentManager and the plugins in plugins.exec_dir, the collector pipelines of collector.config_path, the
+// diag_exporter configured there, the remote job runner of diagnostics.remote, the profiler of service.profiling,
+// and how a reload re-reads all of it.
+func newServiceFromContext(ctx *core.AppContext, cmd *cobra.Command, logger *core.Logger) (*Service, error) {
+	cfg := ctx.GetConfig()
+	factories := componentFactories(ctx)
+	svc := NewService(logger, factories["receiver"], factories["processor"], factories["exporter"], factories["extension"])
+	svc.SetConnectors(factories["connector"])
+	svc.SetTelemetry(ctx.TelemetrySettings)
+	svc.SetAgentInfo(ctx.BuildInfo, cfg)
+	svc.SetConfigLoader(agentConfigLoader(cmd))
+
+	if cfg.Collector.Enabled && cfg.Plugins.ExecDir != "" {
+		plugins := plugin.NewManager(logger, cfg.Plugins.ExecDir)
+		loader := plugin.NewLoader(logger, plugins)
+		if err := loader.LoadPlugins(cmd.Context(), cfg.Plugins.ExecDir); err != nil {
+			return nil, fmt.Errorf("failed to load plugins: %w", err)
+		}
+		svc.SetPlugins(plugins)
+		svc.SetPluginLoader(loader, cfg.Plugins.ExecDir)
+	}
+
+	if cfg.Collector.Enabled {
+		conf, err := LoadCollectorConfig(cfg.Collector.ConfigPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load collector config: %w", err)
+		}
+		extensions, _ := conf["extensions"].(map[string]any)
+		exporterCfg, err := DiagExporterConfig(&ServiceConfig{Extensions: extensions})
+		if err != nil {
+			return nil, err
+		}
+		if exporterCfg != nil {
+			exporter, err := diagnose.NewDiagExporter(exporterCfg, logger)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create %s: %w", diagnose.DiagExporterType, err)
+			}
+			svc.SetDiagExporter(exporter)
+		}
+		svc.SetCollector(cfg.Collector.ConfigPath, component.BuildInfo{
+			Command:     "srediag",
+			Description: "SREDIAG agent",
+			Version:     ctx.BuildInfo.Version,
+		})
+	}
+
+	runner, err := diagnose.NewRemoteJobRunner(logger, cfg, ctx.GetRBAC(), configFileFlag(cmd))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create remote job runner: %w", err)
+	}
+	if runner != nil {
+		svc.SetJobRunner(runner)
+	}
+	profiler, err := NewProfiler(logger, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if profiler != nil {
+		svc.SetProfiler(profiler)
+	}
+	gc, err := NewGCRunner(logger, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if gc != nil {
+		svc.SetGCRunner(gc)
+	}
+	return svc, nil
 }
 
 // CLI_Stop is the entrypoint for 'srediag service stop'.
 //
+// It asks the running agent to shut down over the admin API and waits up to --timeout (by default, long enough for
+// service.shutdown_timeout) until it has exited.
+//
 // Parameters:
 //   - ctx: Application context containing logger and configuration.
 //   - cmd: Cobra command instance.
 //   - args: Command-line arguments.
 //
 // Returns:
-//   - error: If service stop fails or is not implemented, returns a detailed error.
+//   - error: ErrNotRunning if no agent runs, or if the agent does not stop in time, returns a detailed error.
 func CLI_Stop(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
-	logger := ctx.Logger
-	if logger == nil {
-		var err error
-		logger, err = core.NewLogger(nil)
-		if err != nil {
-			return fmt.Errorf("failed to create fallback logger: %w", err)
+	if err := stopAgent(cmd.Context(), ctx.GetConfig(), stopWait(cmd, ctx.GetConfig())); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "SREDIAG service stopped")
+	return nil
+}
+
+// componentFactories returns copies of the built-in component factories of the ComponentManager, by kind.
+func componentFactories(ctx *core.AppContext) map[string]map[component.Type]component.Factory {
+	factories := map[string]map[component.Type]component.Factory{}
+	for _, kind := range []string{"receiver", "processor", "exporter", "extension", "connector"} {
+		factories[kind] = make(map[component.Type]component.Factory)
+		if ctx.ComponentManager != nil {
+			maps.Copy(factories[kind], ctx.ComponentManager.GetFactories(kind))
+		}
+	}
+	return factories
+}
+
+// agentConfigLoader returns a loader that re-reads the agent config the way the root command loads it at start-up,
+// with the command's string flags as overrides.
+func agentConfigLoader(cmd *cobra.Command) func() (*core.Config, error) {
+	flags := make(map[string]string)
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Value.Type() == "string" {
+			flags[f.Name] = f.Value.String()
+		}
+	})
+	return func() (*core.Config, error) {
+		var cfg core.Config
+		if err := core.LoadConfigWithOverlay(&cfg, flags); err != nil {
+			return nil, fmt.Errorf("failed to load config: %w", err)
 		}
+		if err := core.ValidateConfig(&cfg); err != nil {
+			return nil, fmt.Errorf("invalid config: %w", err)
+		}
+		return &cfg, nil
 	}
-	logger.Info("Service stop not yet implemented")
-	return fmt.Errorf("service stop not yet implemented")
+}
+
+// stopAgent requests a graceful shutdown of the running agent and waits until it is gone.
+func stopAgent(ctx context.Context, cfg *core.Config, timeout time.Duration) error {
+	pidFile, socket, _ := daemonPaths(cfg)
+	client := NewAdminClient(socket)
+	if err := client.Stop(ctx); err != nil {
+		return err
+	}
+	return waitStopped(ctx, client, pidFile, timeout)
 }
 
 // CLI_Restart is the entrypoint for 'srediag service restart'.
 //
+// It stops the running agent (if any) and starts it again in the background, as CLI_Detach does.
+//
 // Parameters:
 //   - ctx: Application context containing logger and configuration.
 //   - cmd: Cobra command instance.
 //   - args: Command-line arguments.
 //
 // Returns:
-//   - error: If service restart fails or is not implemented, returns a detailed error.
+//   - error: If stopping or starting the agent fails, returns a detailed error.
 func CLI_Restart(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
-	logger := ctx.Logger
-	if logger == nil {
-		var err error
-		logger, err = core.NewLogger(nil)
-		if err != nil {
-			return fmt.Errorf("failed to create fallback logger: %w", err)
-		}
+	if err := stopAgent(cmd.Context(), ctx.GetConfig(), stopWait(cmd, ctx.GetConfig())); err != nil && !errors.Is(err, ErrNotRunning) {
+		return err
 	}
-	logger.Info("Service restart not yet implemented")
-	return fmt.Errorf("service restart not yet implemented")
+	return CLI_Detach(ctx, cmd, args)
 }
 
 // CLI_Reload is the entrypoint for 'srediag service reload'.
 //
+// It asks the running agent to reload its configuration over the admin API and prints the ReloadReport in the
+// --output format (table, json or yaml): what was added, removed and changed, and which pipelines were restarted.
+//
 // Parameters:
 //   - ctx: Application context containing logger and configuration.
 //   - cmd: Cobra command instance.
 //   - args: Command-line arguments.
 //
 // Returns:
-//   - error: If service reload fails or is not implemented, returns a detailed error.
+//   - error: ErrNotRunning if no agent runs, a *ReloadError (exit code 2 if the new config is invalid) if the reload
+//     failed, or a detailed error.
 func CLI_Reload(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
-	logger := ctx.Logger
-	if logger == nil {
-		var err error
-		logger, err = core.NewLogger(nil)
-		if err != nil {
-			return fmt.Errorf("failed to create fallback logger: %w", err)
+	_, socket, _ := daemonPaths(ctx.GetConfig())
+	reqCtx, cancel := context.WithTimeout(cmd.Context(), timeoutFlag(cmd))
+	defer cancel()
+	report, err := NewAdminClient(socket).Reload(reqCtx)
+	if report != nil {
+		format := ""
+		if f := cmd.Flag("output"); f != nil {
+			format = f.Value.String()
+		}
+		if werr := WriteReloadReport(cmd.OutOrStdout(), report, format); werr != nil && err == nil {
+			err = werr
 		}
 	}
-	logger.Info("Service reload not yet implemented")
-	return fmt.Errorf("service reload not yet implemented")
+	return err
 }
 
 // CLI_Detach is the entrypoint for 'srediag service detach'.
 //
+// It starts the agent in the background (see Daemonize), with its output appended to service.log_file, and waits
+// up to --timeout until it reports running.
+//
 // Parameters:
 //   - ctx: Application context containing logger and configuration.
 //   - cmd: Cobra command instance.
 //   - args: Command-line arguments.
 //
 // Returns:
-//   - error: If service detach fails or is not implemented, returns a detailed error.
+//   - error: If the agent is already running or fails to start, returns a detailed error.
 func CLI_Detach(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
-	logger := ctx.Logger
-	if logger == nil {
-		var err error
-		logger, err = core.NewLogger(nil)
-		if err != nil {
-			return fmt.Errorf("failed to create fallback logger: %w", err)
-		}
+	cfg := ctx.GetConfig()
+	st, err := Daemonize(cmd.Context(), cmd, cfg, timeoutFlag(cmd))
+	if err != nil {
+		return err
 	}
-	logger.Info("Service detach not yet implemented")
-	return fmt.Errorf("service detach not yet implemented")
+	_, _, logFile := daemonPaths(cfg)
+	fmt.Fprintf(cmd.OutOrStdout(), "SREDIAG service started (pid %d, log %s)\n", st.PID, logFile)
+	return nil
 }
 
 // CLI_Status is the entrypoint for 'srediag service status'.
 //
+// It prints the running agent's state, uptime, version, config digest, pipelines and plugins in the --output
+// format (table, json or yaml).
+//
 // Parameters:
 //   - ctx: Application context containing logger and configuration.
 //   - cmd: Cobra command instance.
 //   - args: Command-line arguments.
 //
 // Returns:
-//   - error: If service status fails or is not implemented, returns a detailed error.
+//   - error: ErrNotRunning if no agent runs, or a detailed error.
 func CLI_Status(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
-	logger := ctx.Logger
-	if logger == nil {
-		var err error
-		logger, err = core.NewLogger(nil)
-		if err != nil {
-			return fmt.Errorf("failed to create fallback logger: %w", err)
-		}
+	_, socket, _ := daemonPaths(ctx.GetConfig())
+	st, err := NewAdminClient(socket).Status(cmd.Context())
+	if err != nil {
+		return err
 	}
-	logger.Info("Service status not yet implemented")
-	return fmt.Errorf("service status not yet implemented")
+	format := ""
+	if f := cmd.Flag("output"); f != nil {
+		format = f.Value.String()
+	}
+	return WriteStatus(cmd.OutOrStdout(), st, format)
 }
 
 // CLI_Health is the entrypoint for 'srediag service health'.
@@ -181,57 +462,136 @@ func CLI_Health(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
 
 // CLI_Profile is the entrypoint for 'srediag service profile'.
 //
+// It fetches a --type profile of the running agent over the admin API, capturing cpu, trace, mutex and block
+// profiles for --duration, and writes it to --output-file ("-" for stdout; default
+// srediag-<type>-<timestamp>.pb.gz in the current directory).
+//
 // Parameters:
 //   - ctx: Application context containing logger and configuration.
 //   - cmd: Cobra command instance.
 //   - args: Command-line arguments.
 //
 // Returns:
-//   - error: If service profile fails or is not implemented, returns a detailed error.
+//   - error: ErrNotRunning if no agent is running, ErrProfileBusy, or a detailed error.
 func CLI_Profile(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
-	logger := ctx.Logger
-	if logger == nil {
-		var err error
-		logger, err = core.NewLogger(nil)
-		if err != nil {
-			return fmt.Errorf("failed to create fallback logger: %w", err)
-		}
+	kind, _ := cmd.Flags().GetString("type")
+	if kind == "" {
+		kind = "cpu"
+	}
+	if !slices.Contains(ProfileTypes, kind) {
+		return fmt.Errorf("unknown profile type %q (want one of %s)", kind, strings.Join(ProfileTypes, ", "))
+	}
+	d, _ := cmd.Flags().GetDuration("duration")
+	if d <= 0 {
+		d = DefaultProfileDuration
+	}
+	if d > MaxProfileDuration {
+		return fmt.Errorf("--duration %s exceeds the maximum of %s", d, MaxProfileDuration)
+	}
+	path, _ := cmd.Flags().GetString("output-file")
+	if path == "" {
+		path = "srediag-" + kind + "-" + time.Now().Format("20060102T150405") + ProfileExt(kind)
+	}
+
+	_, socket, _ := daemonPaths(ctx.GetConfig())
+	client := NewAdminClient(socket)
+	if path == "-" {
+		_, err := client.Profile(cmd.Context(), cmd.OutOrStdout(), kind, d)
+		return err
+	}
+	if timedProfile(kind) {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Capturing %s profile for %s...\n", kind, d)
 	}
-	logger.Info("Service profile not yet implemented")
-	return fmt.Errorf("service profile not yet implemented")
+	// Buffered so a failed capture leaves no partial file behind.
+	var buf bytes.Buffer
+	n, err := client.Profile(cmd.Context(), &buf, kind, d)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s profile (%d bytes) to %s\n", kind, n, path)
+	return nil
 }
 
 // CLI_TailLogs is the entrypoint for 'srediag service tail-logs'.
 //
+// It prints the recent log entries of the running agent that match --since, --level, --component and --field,
+// the last --lines of them, as console lines or, with --output json, JSON lines. --follow keeps streaming new
+// entries until Ctrl-C.
+//
 // Parameters:
 //   - ctx: Application context containing logger and configuration.
 //   - cmd: Cobra command instance.
 //   - args: Command-line arguments.
 //
 // Returns:
-//   - error: If service tail-logs fails or is not implemented, returns a detailed error.
+//   - error: ErrNotRunning if no agent is running, an invalid filter, or a detailed error.
 func CLI_TailLogs(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
-	logger := ctx.Logger
-	if logger == nil {
-		var err error
-		logger, err = core.NewLogger(nil)
-		if err != nil {
-			return fmt.Errorf("failed to create fallback logger: %w", err)
+	q, err := logQueryFromFlags(cmd, time.Now())
+	if err != nil {
+		return err
+	}
+	format := "console"
+	if f := cmd.Flag("output"); f != nil {
+		switch f.Value.String() {
+		case "json":
+			format = "json"
+		case "", "table", "console":
+		default:
+			return fmt.Errorf("unsupported output format %q for tail-logs (want console or json)", f.Value.String())
+		}
+	}
+
+	_, socket, _ := daemonPaths(ctx.GetConfig())
+	runCtx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	out := cmd.OutOrStdout()
+	err = NewAdminClient(socket).Logs(runCtx, q, func(e core.LogEntry) error { return WriteLogEntry(out, e, format) })
+	if q.Follow && runCtx.Err() != nil && cmd.Context().Err() == nil {
+		// Ctrl-C ends --follow normally.
+		return nil
+	}
+	return err
+}
+
+// logQueryFromFlags builds the LogQuery of 'tail-logs'; --since is a duration before now or an RFC 3339 time.
+func logQueryFromFlags(cmd *cobra.Command, now time.Time) (LogQuery, error) {
+	var q LogQuery
+	q.Follow, _ = cmd.Flags().GetBool("follow")
+	q.Lines, _ = cmd.Flags().GetInt("lines")
+	q.Components, _ = cmd.Flags().GetStringSlice("component")
+	level, _ := cmd.Flags().GetString("level")
+	var err error
+	if q.Level, err = normalizeLogLevel(level); err != nil {
+		return q, err
+	}
+	if since, _ := cmd.Flags().GetString("since"); since != "" {
+		if d, err := time.ParseDuration(since); err == nil {
+			q.Since = now.Add(-d)
+		} else if q.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return q, fmt.Errorf("invalid --since %q: want a duration (10m) or an RFC 3339 time", since)
 		}
 	}
-	logger.Info("Service tail-logs not yet implemented")
-	return fmt.Errorf("service tail-logs not yet implemented")
+	fields, _ := cmd.Flags().GetStringArray("field")
+	q.Fields, err = parseFieldFilters(fields)
+	return q, err
 }
 
 // CLI_Validate is the entrypoint for 'srediag service validate'.
 //
+// It validates the collector config given as argument, or collector.config_path, offline against the built-in
+// components and the plugins in plugins.exec_dir (see Service.ValidateCollectorConfig), and prints every issue with
+// file, line and field path in the --output format (table, json or yaml).
+//
 // Parameters:
 //   - ctx: Application context containing logger and configuration.
 //   - cmd: Cobra command instance.
-//   - args: Command-line arguments.
+//   - args: Command-line arguments: an optional collector config file.
 //
 // Returns:
-//   - error: If service validate fails or is not implemented, returns a detailed error.
+//   - error: A *ValidationError (exit code 2) if the config is invalid, or a detailed error.
 func CLI_Validate(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
 	logger := ctx.Logger
 	if logger == nil {
@@ -241,11 +601,48 @@ func CLI_Validate(ctx *core.AppContext, cmd *cobra.Command, args []string) error
 			return fmt.Errorf("failed to create fallback logger: %w", err)
 		}
 	}
-	logger.Info("Service validate not yet implemented")
-	return fmt.Errorf("service validate not yet implemented")
+	cfg := ctx.GetConfig()
+	path := cfg.Collector.ConfigPath
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if path == "" {
+		return fmt.Errorf("no collector config to validate: pass a file or set collector.config_path")
+	}
+
+	factories := componentFactories(ctx)
+	svc := NewService(logger, factories["receiver"], factories["processor"], factories["exporter"], factories["extension"])
+	svc.SetConnectors(factories["connector"])
+	if cfg.Plugins.ExecDir != "" {
+		plugins := plugin.NewManager(logger, cfg.Plugins.ExecDir)
+		loader := plugin.NewLoader(logger, plugins)
+		if err := loader.LoadPlugins(cmd.Context(), cfg.Plugins.ExecDir); err != nil {
+			return fmt.Errorf("failed to load plugins: %w", err)
+		}
+		defer func() {
+			for _, meta := range plugins.List() {
+				if err := plugins.Unload(context.WithoutCancel(cmd.Context()), meta.Name); err != nil {
+					logger.Warn("Failed to unload plugin", core.ZapString("name", meta.Name), core.ZapError(err))
+				}
+			}
+		}()
+		svc.SetPluginLoader(loader, cfg.Plugins.ExecDir)
+	}
+
+	report := svc.ValidateCollectorConfig(cmd.Context(), path)
+	format := ""
+	if f := cmd.Flag("output"); f != nil {
+		format = f.Value.String()
+	}
+	if err := WriteValidationReport(cmd.OutOrStdout(), report, format); err != nil {
+		return err
+	}
+	return report.Err()
 }
 
-// CLI_InstallUnit is the entrypoint for 'srediag service install-unit'.
+// CLI_InstallUnit is the entrypoint for 'srediag service install-unit'. It writes a hardened srediag.service for
+// the current binary and --config, runs daemon-reload and enables it; --user installs a user unit and --dry-run
+// only prints the unit.
 //
 // Parameters:
 //   - ctx: Application context containing logger and configuration.
@@ -253,21 +650,34 @@ func CLI_Validate(ctx *core.AppContext, cmd *cobra.Command, args []string) error
 //   - args: Command-line arguments.
 //
 // Returns:
-//   - error: If service install-unit fails or is not implemented, returns a detailed error.
+//   - error: If the unit cannot be written or systemctl fails, returns a detailed error.
 func CLI_InstallUnit(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
-	logger := ctx.Logger
-	if logger == nil {
-		var err error
-		logger, err = core.NewLogger(nil)
-		if err != nil {
-			return fmt.Errorf("failed to create fallback logger: %w", err)
-		}
+	userScope, _ := cmd.Flags().GetBool("user")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	start, _ := cmd.Flags().GetBool("now")
+	runAs, _ := cmd.Flags().GetString("run-as")
+	if runAs != "" && userScope {
+		return fmt.Errorf("--run-as only applies to system units")
+	}
+	opts, err := NewUnitOptions(ctx.GetConfig(), configFileFlag(cmd), userScope)
+	if err != nil {
+		return err
+	}
+	opts.RunAs = runAs
+	if dryRun {
+		_, err := fmt.Fprint(cmd.OutOrStdout(), RenderUnit(opts))
+		return err
 	}
-	logger.Info("Service install-unit not yet implemented")
-	return fmt.Errorf("service install-unit not yet implemented")
+	path, err := InstallSystemdUnit(cmd.Context(), opts, start)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Installed and enabled %s\n", path)
+	return nil
 }
 
-// CLI_UninstallUnit is the entrypoint for 'srediag service uninstall-unit'.
+// CLI_UninstallUnit is the entrypoint for 'srediag service uninstall-unit'. It stops and disables the unit,
+// removes it and runs daemon-reload; --dry-run only prints what would be done.
 //
 // Parameters:
 //   - ctx: Application context containing logger and configuration.
@@ -275,21 +685,33 @@ func CLI_InstallUnit(ctx *core.AppContext, cmd *cobra.Command, args []string) er
 //   - args: Command-line arguments.
 //
 // Returns:
-//   - error: If service uninstall-unit fails or is not implemented, returns a detailed error.
+//   - error: If the unit is not installed, or it cannot be removed or systemctl fails, returns a detailed error.
 func CLI_UninstallUnit(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
-	logger := ctx.Logger
-	if logger == nil {
-		var err error
-		logger, err = core.NewLogger(nil)
+	userScope, _ := cmd.Flags().GetBool("user")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	if dryRun {
+		path, err := UnitPath(userScope)
 		if err != nil {
-			return fmt.Errorf("failed to create fallback logger: %w", err)
+			return err
 		}
+		command := "systemctl"
+		if userScope {
+			command += " --user"
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s disable --now %s\nrm %s\n%s daemon-reload\n", command, unitName, path, command)
+		return nil
+	}
+	path, err := UninstallSystemdUnit(cmd.Context(), userScope)
+	if err != nil {
+		return err
 	}
-	logger.Info("Service uninstall-unit not yet implemented")
-	return fmt.Errorf("service uninstall-unit not yet implemented")
+	fmt.Fprintf(cmd.OutOrStdout(), "Removed %s\n", path)
+	return nil
 }
 
-// CLI_Gc is the entrypoint for 'srediag service gc'.
+// CLI_Gc is the entrypoint for 'srediag service gc'. It lists the state srediag owns, skips what running
+// processes use and deletes the rest according to service.gc.retention; --kind limits the kinds, --retention
+// overrides every max_age and --dry-run only reports.
 //
 // Parameters:
 //   - ctx: Application context containing logger and configuration.
@@ -297,16 +719,41 @@ func CLI_UninstallUnit(ctx *core.AppContext, cmd *cobra.Command, args []string)
 //   - args: Command-line arguments.
 //
 // Returns:
-//   - error: If service gc fails or is not implemented, returns a detailed error.
+//   - error: If a flag or policy is invalid, the files in use cannot be listed or items cannot be deleted.
 func CLI_Gc(ctx *core.AppContext, cmd *cobra.Command, args []string) error {
-	logger := ctx.Logger
-	if logger == nil {
-		var err error
-		logger, err = core.NewLogger(nil)
+	cfg := ctx.GetConfig()
+	opts, err := NewGCOptions(cfg)
+	if err != nil {
+		return err
+	}
+	opts.DryRun, _ = cmd.Flags().GetBool("dry-run")
+	opts.Kinds, _ = cmd.Flags().GetStringSlice("kind")
+	if retention, _ := cmd.Flags().GetString("retention"); retention != "" {
+		age, err := ParseRetention(retention)
 		if err != nil {
-			return fmt.Errorf("failed to create fallback logger: %w", err)
+			return fmt.Errorf("invalid --retention: %w", err)
 		}
+		for kind, p := range opts.Policies {
+			// The runtime kind has no age: its items are stale as soon as the agent is gone.
+			if kind != GCKindRuntime {
+				p.MaxAge = age
+				opts.Policies[kind] = p
+			}
+		}
+	}
+	report, err := CollectGarbage(cmd.Context(), cfg, opts)
+	if err != nil {
+		return err
+	}
+	format := ""
+	if f := cmd.Flag("output"); f != nil {
+		format = f.Value.String()
+	}
+	if err := WriteGCReport(cmd.OutOrStdout(), report, format); err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d items could not be deleted", report.Failed)
 	}
-	logger.Info("Service gc not yet implemented")
-	return fmt.Errorf("service gc not yet implemented")
+	return nil
 }
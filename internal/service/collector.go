@@ -0,0 +1,350 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/provider/envprovider"
+	"go.opentelemetry.io/collector/confmap/xconfmap"
+	"go.opentelemetry.io/collector/connector"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/extension"
+	"go.opentelemetry.io/collector/otelcol"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/receiver"
+	otelservice "go.opentelemetry.io/collector/service"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+
+	"github.com/srediag/srediag/internal/diagnose"
+)
+
+// Package service provides the core service orchestration, lifecycle management, and CLI stubs for the SREDIAG collector service.
+//
+// This file defines how the embedded OpenTelemetry Collector is configured and built: LoadCollectorConfig assembles the
+// pipeline config from collector.config_path (resolving its '!include' tags) and the per-component
+// plugins.d/<kind>/<name>@service.yaml files, and resolveCollectorConfig and collectorServiceSettings turn the config
+// of one collector instance (see instances.go) and the service's factory maps into a runnable otelcol service.
+//
+// Usage:
+//   - Call Service.SetCollector with the config path; Start runs the collector, Reload applies edits and Stop shuts it
+//     down.
+//   - Use LoadCollectorConfig to inspect or validate the merged config without starting anything.
+//
+// Best Practices:
+//   - Keep component defaults in plugins.d and only overrides in the pipeline file; inline settings win.
+
+// collectorConfigScheme is the confmap scheme of the collector instance configs ("srediag:<instance>").
+const collectorConfigScheme = "srediag"
+
+// collectorSections are the component sections of a collector config, also the plugins.d sub-directories.
+var collectorSections = []string{"receivers", "processors", "exporters", "extensions", "connectors"}
+
+// LoadCollectorConfig reads a collector pipeline config and merges in the per-component plugin configs.
+//
+// Every component referenced by the config, in its section or by a pipeline, gets
+// <config dir>/plugins.d/<section>/<type>@service.yaml (if present) as its base settings; the inline settings are
+// merged over it. Components only named by a pipeline are added to their section. '!include <file>' tags are replaced by the YAML of the file, relative to the including file.
+//
+// Parameters:
+//   - path: Collector config file (collector.config_path).
+//
+// Returns:
+//   - map[string]any: The merged collector config.
+//   - error: If a file cannot be read or parsed, returns a detailed error.
+func LoadCollectorConfig(path string) (map[string]any, error) {
+	var conf map[string]any
+	if err := decodeYAMLFile(path, &conf); err != nil {
+		return nil, err
+	}
+	if conf == nil {
+		conf = make(map[string]any)
+	}
+	pluginsDir := filepath.Join(filepath.Dir(path), "plugins.d")
+	for _, section := range collectorSections {
+		components, _ := conf[section].(map[string]any)
+		if components == nil {
+			components = make(map[string]any)
+		}
+		for _, id := range referencedComponents(conf, section, components) {
+			typ, _, _ := strings.Cut(id, "/")
+			file := filepath.Join(pluginsDir, section, typ+"@service.yaml")
+			if _, err := os.Stat(file); err != nil {
+				// Referenced without settings anywhere: the component runs with its defaults.
+				if _, ok := components[id]; !ok {
+					components[id] = nil
+				}
+				continue
+			}
+			var base any
+			if err := decodeYAMLFile(file, &base); err != nil {
+				return nil, err
+			}
+			components[id] = mergeConfig(base, components[id])
+		}
+		if len(components) > 0 {
+			conf[section] = components
+		}
+	}
+	return conf, nil
+}
+
+// referencedComponents lists the component IDs of a section: its own keys plus those named by service.pipelines
+// (receivers, processors, exporters) or service.extensions. Connectors named as a pipeline's receiver or exporter
+// stay in the connectors section.
+func referencedComponents(conf map[string]any, section string, components map[string]any) []string {
+	ids := slices.Collect(maps.Keys(components))
+	connectors, _ := conf["connectors"].(map[string]any)
+	add := func(list any) {
+		items, _ := list.([]any)
+		for _, item := range items {
+			id, ok := item.(string)
+			if _, connector := connectors[id]; connector && section != "connectors" {
+				continue
+			}
+			if ok && !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	svc, _ := conf["service"].(map[string]any)
+	if section == "extensions" {
+		add(svc["extensions"])
+	}
+	pipelines, _ := svc["pipelines"].(map[string]any)
+	for _, p := range pipelines {
+		if pipeline, ok := p.(map[string]any); ok {
+			add(pipeline[section])
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// mergeConfig deep-merges override over base; maps merge key by key, anything else is replaced.
+func mergeConfig(base, override any) any {
+	b, ok := base.(map[string]any)
+	o, ok2 := override.(map[string]any)
+	switch {
+	case override == nil:
+		return base
+	case !ok || !ok2:
+		return override
+	}
+	out := maps.Clone(b)
+	for k, v := range o {
+		out[k] = mergeConfig(b[k], v)
+	}
+	return out
+}
+
+// decodeYAMLFile decodes a YAML file into out after resolving its '!include' tags.
+func decodeYAMLFile(path string, out any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read collector config: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := resolveIncludes(&doc, filepath.Dir(path), []string{path}, nil); err != nil {
+		return err
+	}
+	if err := doc.Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
+
+// resolveIncludes replaces '!include <file>' scalars by the document of the file, detecting include cycles. If files
+// is not nil, it records the file each replaced node was read from.
+func resolveIncludes(node *yaml.Node, dir string, chain []string, files map[*yaml.Node]string) error {
+	if node.Tag == "!include" && node.Kind == yaml.ScalarNode {
+		file := node.Value
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		if slices.Contains(chain, file) {
+			return fmt.Errorf("include cycle: %s -> %s", strings.Join(chain, " -> "), file)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read included file: %w", err)
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		if len(doc.Content) == 0 {
+			*node = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+			return nil
+		}
+		*node = *doc.Content[0]
+		if files != nil {
+			files[node] = file
+		}
+		return resolveIncludes(node, filepath.Dir(file), append(chain, file), files)
+	}
+	for _, child := range node.Content {
+		if err := resolveIncludes(child, dir, chain, files); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withoutDiagExporter drops the diag_exporter extension, which the service runs itself rather than the collector.
+func withoutDiagExporter(conf map[string]any) map[string]any {
+	name := diagnose.DiagExporterType.String()
+	out := maps.Clone(conf)
+	if exts, ok := out["extensions"].(map[string]any); ok {
+		exts = maps.Clone(exts)
+		delete(exts, name)
+		out["extensions"] = exts
+	}
+	if svc, ok := out["service"].(map[string]any); ok {
+		svc = maps.Clone(svc)
+		if list, ok := svc["extensions"].([]any); ok {
+			svc["extensions"] = slices.DeleteFunc(slices.Clone(list), func(v any) bool { return v == name })
+		}
+		out["service"] = svc
+	}
+	return out
+}
+
+// collectorConfigProvider serves the config of one collector instance for "srediag:<instance>" URIs.
+type collectorConfigProvider struct {
+	conf map[string]any
+}
+
+// newCollectorConfigProviderFactory returns the confmap provider factory of collectorConfigScheme for conf.
+func newCollectorConfigProviderFactory(conf map[string]any) confmap.ProviderFactory {
+	return confmap.NewProviderFactory(func(confmap.ProviderSettings) confmap.Provider {
+		return collectorConfigProvider{conf: conf}
+	})
+}
+
+// Retrieve implements confmap.Provider; ${env:...} references in the config are still resolved by the resolver.
+func (p collectorConfigProvider) Retrieve(_ context.Context, uri string, _ confmap.WatcherFunc) (*confmap.Retrieved, error) {
+	if !strings.HasPrefix(uri, collectorConfigScheme+":") {
+		return nil, fmt.Errorf("%q uri is not supported by %s provider", uri, collectorConfigScheme)
+	}
+	return confmap.NewRetrieved(p.conf)
+}
+
+// Scheme implements confmap.Provider.
+func (collectorConfigProvider) Scheme() string { return collectorConfigScheme }
+
+// Shutdown implements confmap.Provider.
+func (collectorConfigProvider) Shutdown(context.Context) error { return nil }
+
+// typedFactories narrows a factory map to the factory interface of one component kind.
+func typedFactories[F component.Factory](kind string, in map[component.Type]component.Factory) (map[component.Type]F, error) {
+	out := make(map[component.Type]F, len(in))
+	for typ, f := range in {
+		typed, ok := f.(F)
+		if !ok {
+			return nil, fmt.Errorf("%s factory %s does not implement the %s interface", kind, typ, kind)
+		}
+		out[typ] = typed
+	}
+	return out, nil
+}
+
+// collectorFactories converts the service's factory maps into otelcol.Factories.
+func (s *Service) collectorFactories() (otelcol.Factories, error) {
+	s.mu.Lock()
+	receivers, processors, exporters, extensions, connectors := s.receivers, s.processors, s.exporters, s.extensions, s.connectors
+	s.mu.Unlock()
+	var f otelcol.Factories
+	var err error
+	if f.Receivers, err = typedFactories[receiver.Factory]("receiver", receivers); err != nil {
+		return f, err
+	}
+	if f.Processors, err = typedFactories[processor.Factory]("processor", processors); err != nil {
+		return f, err
+	}
+	if f.Exporters, err = typedFactories[exporter.Factory]("exporter", exporters); err != nil {
+		return f, err
+	}
+	if f.Extensions, err = typedFactories[extension.Factory]("extension", extensions); err != nil {
+		return f, err
+	}
+	if _, ok := f.Extensions[agentExtensionType]; !ok {
+		f.Extensions[agentExtensionType] = agentExtensionFactory()
+	}
+	if f.Connectors, err = typedFactories[connector.Factory]("connector", connectors); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// resolveCollectorConfig turns the raw config of a collector instance into the typed config of its components,
+// resolving ${env:...} references and validating every component config.
+func (s *Service) resolveCollectorConfig(ctx context.Context, name string, conf map[string]any) (*otelcol.Config, otelcol.Factories, error) {
+	factories, err := s.collectorFactories()
+	if err != nil {
+		return nil, factories, err
+	}
+	provider, err := otelcol.NewConfigProvider(otelcol.ConfigProviderSettings{
+		ResolverSettings: confmap.ResolverSettings{
+			URIs:              []string{collectorConfigScheme + ":" + name},
+			ProviderFactories: []confmap.ProviderFactory{newCollectorConfigProviderFactory(conf), envprovider.NewFactory()},
+		},
+	})
+	if err != nil {
+		return nil, factories, fmt.Errorf("failed to create config provider: %w", err)
+	}
+	defer func() { _ = provider.Shutdown(ctx) }()
+	cfg, err := provider.Get(ctx, factories)
+	if err != nil {
+		return nil, factories, fmt.Errorf("failed to get config: %w", err)
+	}
+	if err := xconfmap.Validate(cfg); err != nil {
+		return nil, factories, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, factories, nil
+}
+
+// collectorServiceSettings returns the settings of the otelcol service that runs a resolved config. The agent
+// logger receives the collector's logs; fatal component errors go to asyncErrors.
+func (s *Service) collectorServiceSettings(cfg *otelcol.Config, factories otelcol.Factories, asyncErrors chan error) (otelservice.Settings, error) {
+	conf := confmap.New()
+	if err := conf.Marshal(cfg); err != nil {
+		return otelservice.Settings{}, fmt.Errorf("could not marshal configuration: %w", err)
+	}
+	set := otelservice.Settings{
+		BuildInfo:           s.buildInfo,
+		CollectorConf:       conf,
+		ReceiversConfigs:    cfg.Receivers,
+		ReceiversFactories:  factories.Receivers,
+		ProcessorsConfigs:   cfg.Processors,
+		ProcessorsFactories: factories.Processors,
+		ExportersConfigs:    cfg.Exporters,
+		ExportersFactories:  factories.Exporters,
+		ConnectorsConfigs:   cfg.Connectors,
+		ConnectorsFactories: factories.Connectors,
+		ExtensionsConfigs:   cfg.Extensions,
+		ExtensionsFactories: factories.Extensions,
+		AsyncErrorChannel:   asyncErrors,
+	}
+	if z := s.logger.UnderlyingZap(); z != nil {
+		set.LoggingOptions = []zap.Option{zap.WrapCore(func(zapcore.Core) zapcore.Core { return z.Core() })}
+	}
+	return set, nil
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// writeFile writes a test file below dir, creating its parent directories.
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+const nopPipelineConfig = `
+receivers:
+  nop:
+exporters:
+  nop:
+extensions:
+  diag_exporter:
+    endpoint: localhost:4317
+service:
+  extensions: [diag_exporter]
+  telemetry:
+    metrics:
+      level: none
+  pipelines:
+    traces:
+      receivers: [nop]
+      exporters: [nop]
+`
+
+func TestLoadCollectorConfig_IncludesAndPlugins(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "plugins.d/receivers/otlp@service.yaml", `
+protocols:
+  grpc:
+    endpoint: localhost:4317
+  http:
+    endpoint: localhost:4318
+`)
+	writeFile(t, dir, "plugins.d/processors/batch@service.yaml", "timeout: 1s\nsend_batch_size: 1024\n")
+	writeFile(t, dir, "pipelines/traces.yaml", "receivers: [otlp]\nprocessors: [batch/fast]\nexporters: [debug]\n")
+	path := writeFile(t, dir, "service.yaml", `
+receivers:
+  otlp:
+    protocols:
+      grpc:
+        endpoint: 0.0.0.0:4317
+processors:
+  batch/fast:
+    timeout: 100ms
+service:
+  pipelines:
+    traces: !include pipelines/traces.yaml
+`)
+
+	conf, err := LoadCollectorConfig(path)
+	require.NoError(t, err)
+
+	otlp := conf["receivers"].(map[string]any)["otlp"].(map[string]any)["protocols"].(map[string]any)
+	assert.Equal(t, "0.0.0.0:4317", otlp["grpc"].(map[string]any)["endpoint"], "inline settings win")
+	assert.Equal(t, "localhost:4318", otlp["http"].(map[string]any)["endpoint"], "plugin defaults fill the rest")
+
+	batch := conf["processors"].(map[string]any)["batch/fast"].(map[string]any)
+	assert.Equal(t, "100ms", batch["timeout"])
+	assert.Equal(t, 1024, batch["send_batch_size"], "named IDs use the plugin config of their type")
+
+	exporters := conf["exporters"].(map[string]any)
+	assert.Contains(t, exporters, "debug", "components referenced only by a pipeline are listed")
+	assert.Nil(t, exporters["debug"])
+}
+
+func TestLoadCollectorConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+	_, err := LoadCollectorConfig(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+
+	path := writeFile(t, dir, "loop.yaml", "service: !include loop.yaml\n")
+	_, err = LoadCollectorConfig(path)
+	assert.ErrorContains(t, err, "include cycle")
+}
+
+func TestWithoutDiagExporter(t *testing.T) {
+	conf := map[string]any{
+		"extensions": map[string]any{"diag_exporter": map[string]any{}, "zpages": nil},
+		"service":    map[string]any{"extensions": []any{"zpages", "diag_exporter"}},
+	}
+	out := withoutDiagExporter(conf)
+	assert.Equal(t, map[string]any{"zpages": nil}, out["extensions"])
+	assert.Equal(t, []any{"zpages"}, out["service"].(map[string]any)["extensions"])
+	assert.Contains(t, conf["extensions"], "diag_exporter", "the input is left untouched")
+}
+
+// newNopService returns a service whose collector only knows the nop receiver and exporter.
+func newNopService(t *testing.T, configPath string, logs *bytes.Buffer) *Service {
+	t.Helper()
+	receiver, exporter := receivertest.NewNopFactory(), exportertest.NewNopFactory()
+	svc := NewService(core.NewTestLogger(logs),
+		map[component.Type]component.Factory{receiver.Type(): receiver},
+		nil,
+		map[component.Type]component.Factory{exporter.Type(): exporter},
+		nil)
+	svc.SetCollector(configPath, component.BuildInfo{Command: "srediag", Version: "test"})
+	return svc
+}
+
+func TestService_CollectorLifecycle(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "service.yaml", nopPipelineConfig)
+	var logs bytes.Buffer
+	svc := newNopService(t, path, &logs)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	require.NoError(t, svc.Start(ctx))
+	select {
+	case <-svc.Done():
+		t.Fatalf("collector exited early: %v", svc.Err())
+	default:
+	}
+
+	require.NoError(t, svc.Stop(ctx))
+	select {
+	case <-svc.Done():
+	default:
+		t.Fatal("collector still running after Stop")
+	}
+	assert.Contains(t, logs.String(), "Everything is ready", "collector logs go to the agent logger")
+}
+
+func TestService_CollectorUnknownComponent(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "service.yaml", `
+receivers:
+  otlp:
+exporters:
+  nop:
+service:
+  telemetry:
+    metrics:
+      level: none
+  pipelines:
+    traces:
+      receivers: [otlp]
+      exporters: [nop]
+`)
+	svc := newNopService(t, path, &bytes.Buffer{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := svc.Start(ctx)
+	assert.ErrorContains(t, err, "otlp")
+}
+
+func TestTypedFactories_WrongKind(t *testing.T) {
+	svc := newNopService(t, "", &bytes.Buffer{})
+	svc.processors = map[component.Type]component.Factory{component.MustNewType("nop"): receivertest.NewNopFactory()}
+	_, err := svc.collectorFactories()
+	assert.ErrorContains(t, err, "processor factory nop")
+}
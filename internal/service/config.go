@@ -3,7 +3,10 @@ package service
 import (
 	"fmt"
 
+	"github.com/go-viper/mapstructure/v2"
+
 	"github.com/srediag/srediag/internal/core"
+	"github.com/srediag/srediag/internal/diagnose"
 )
 
 // Package service provides service configuration management and helpers for the SREDIAG collector service.
@@ -13,6 +16,7 @@ import (
 // Usage:
 //   - Use ServiceConfig to represent the canonical collector config structure for SREDIAG.
 //   - Use LoadServiceConfig to load and overlay service config from YAML, environment, and CLI flags.
+//   - Use DiagExporterConfig to read the diag_exporter extension settings.
 //
 // Best Practices:
 //   - Always validate required fields after loading config.
@@ -60,3 +64,37 @@ func LoadServiceConfig(cliFlags map[string]string) (*ServiceConfig, error) {
 	}
 	return &cfg, nil
 }
+
+// DiagExporterConfig returns the settings of the diag_exporter extension, or nil when it is not configured.
+//
+// Parameters:
+//   - cfg: Loaded service configuration.
+//
+// Returns:
+//   - *diagnose.DiagExporterConfig: The exporter settings, or nil if 'extensions.diag_exporter' is absent.
+//   - error: If the section cannot be decoded, returns a detailed error.
+func DiagExporterConfig(cfg *ServiceConfig) (*diagnose.DiagExporterConfig, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	raw, ok := cfg.Extensions[diagnose.DiagExporterType.String()]
+	if !ok {
+		return nil, nil
+	}
+	out, ok := diagnose.NewDiagExporterFactory().CreateDefaultConfig().(*diagnose.DiagExporterConfig)
+	if !ok {
+		return nil, fmt.Errorf("unexpected %s config type", diagnose.DiagExporterType)
+	}
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:  mapstructure.StringToTimeDurationHookFunc(),
+		ErrorUnused: true,
+		Result:      out,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s config decoder: %w", diagnose.DiagExporterType, err)
+	}
+	if err := dec.Decode(raw); err != nil {
+		return nil, fmt.Errorf("invalid extensions.%s: %w", diagnose.DiagExporterType, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package service provides the core service orchestration, lifecycle management, and CLI stubs for the SREDIAG collector service.
+//
+// This file defines how the agent is daemonized and how clients wait for it: Daemonize re-executes the binary as
+// 'srediag service start' in a new session with stdout and stderr appended to service.log_file, then waits until the
+// agent answers on its admin socket; waitStopped waits until it is gone.
+//
+// Usage:
+//   - Call Daemonize from 'srediag service detach' and 'start --detach'; waitStopped after a stop request.
+//
+// Best Practices:
+//   - Pass an absolute --config; the background agent keeps the working directory but not the caller's flags.
+
+// daemonPollInterval is how often clients poll the admin socket while waiting for the agent.
+const daemonPollInterval = 100 * time.Millisecond
+
+// daemonExecutable returns the binary re-executed by Daemonize; patchable for tests.
+var daemonExecutable = os.Executable
+
+// daemonPaths returns the PID file, admin socket and log file of the agent (service.*), falling back to the
+// defaults when the config leaves them empty.
+func daemonPaths(cfg *core.Config) (pidFile, socket, logFile string) {
+	pidFile, socket, logFile = cfg.Service.PIDFile, cfg.Service.Socket, cfg.Service.LogFile
+	if pidFile == "" {
+		pidFile = filepath.Join(core.DefaultRuntimeDir(), "srediag.pid")
+	}
+	if socket == "" {
+		socket = core.DefaultAdminSocket()
+	}
+	if logFile == "" {
+		logFile = filepath.Join(core.DefaultLogDir(), "srediag.log")
+	}
+	return pidFile, socket, logFile
+}
+
+// detachArgs returns the arguments of the background 'service start', forwarding the global flags that select
+// the config and the log format, and --shutdown-timeout.
+func detachArgs(cmd *cobra.Command) []string {
+	args := []string{"service", "start"}
+	for _, name := range []string{"config", "log-level", "log-format"} {
+		f := cmd.Flag(name)
+		if f == nil || f.Value.String() == "" {
+			continue
+		}
+		value := f.Value.String()
+		if name == "config" {
+			if abs, err := filepath.Abs(value); err == nil {
+				value = abs
+			}
+		}
+		args = append(args, "--"+name, value)
+	}
+	if f := cmd.Flags().Lookup("shutdown-timeout"); f != nil && f.Changed {
+		args = append(args, "--shutdown-timeout", f.Value.String())
+	}
+	return args
+}
+
+// Daemonize starts the agent in the background and waits until it runs.
+//
+// Parameters:
+//   - ctx: Context for cancellation.
+//   - cmd: The invoking command; its global flags are forwarded.
+//   - cfg: Effective agent config (service.socket, service.log_file).
+//   - timeout: How long to wait for the agent to report running.
+//
+// Returns:
+//   - *StatusReport: The status of the started agent.
+//   - error: ErrAlreadyRunning, a start-up failure (see the log file) or a timeout.
+func Daemonize(ctx context.Context, cmd *cobra.Command, cfg *core.Config, timeout time.Duration) (*StatusReport, error) {
+	_, socket, logFile := daemonPaths(cfg)
+	client := NewAdminClient(socket)
+	if st, err := client.Status(ctx); err == nil {
+		return nil, fmt.Errorf("%w (pid %d)", ErrAlreadyRunning, st.PID)
+	}
+
+	exe, err := daemonExecutable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate srediag binary: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(logFile), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	logOut, err := os.OpenFile(logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer func() { _ = logOut.Close() }()
+
+	child := exec.Command(exe, detachArgs(cmd)...)
+	child.Stdout, child.Stderr = logOut, logOut
+	child.SysProcAttr = detachAttr()
+	if err := child.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start background service: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- child.Wait() }()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(daemonPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-exited:
+			return nil, fmt.Errorf("background service exited during start-up (see %s): %v", logFile, err)
+		case <-deadline.C:
+			return nil, fmt.Errorf("timed out after %s waiting for the service to start (see %s)", timeout, logFile)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+			st, err := client.Status(ctx)
+			if err == nil && st.State == StateRunning {
+				return st, nil
+			}
+		}
+	}
+}
+
+// waitStopped waits until no agent answers on the admin socket and the PID file is unlocked.
+func waitStopped(ctx context.Context, client *AdminClient, pidFile string, timeout time.Duration) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(daemonPollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := client.Status(ctx); errors.Is(err, ErrNotRunning) && !PIDFileLocked(pidFile) {
+			return nil
+		}
+		select {
+		case <-deadline.C:
+			return fmt.Errorf("timed out after %s waiting for the service to stop", timeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
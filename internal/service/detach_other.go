@@ -0,0 +1,10 @@
+//go:build !unix
+
+package service
+
+import "syscall"
+
+// detachAttr uses the default process attributes; the child already outlives its parent.
+func detachAttr() *syscall.SysProcAttr {
+	return nil
+}
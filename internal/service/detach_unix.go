@@ -0,0 +1,10 @@
+//go:build unix
+
+package service
+
+import "syscall"
+
+// detachAttr starts the background agent in its own session, so it survives the terminal that launched it.
+func detachAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,711 @@
+package service
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package service provides the core service orchestration, lifecycle management, and CLI stubs for the SREDIAG collector service.
+//
+// This file defines the garbage collector of 'srediag service gc': it lists every kind of state srediag leaves on
+// disk (stale PID files and sockets, shmipc files, rotated logs, build artefacts, leftover plugin binaries, support
+// bundles, remote job spill directories and profiles), skips items a running process still uses (open files,
+// mappings, executables, working directories and bound sockets from /proc), and deletes the rest according to a
+// per-kind retention policy. With service.gc.interval the agent runs it periodically.
+//
+// Usage:
+//   - Call NewGCOptions for the configured policies and CollectGarbage to list and delete; render with WriteGCReport.
+//   - Set a GCRunner (NewGCRunner) on the Service for periodic collection.
+//
+// Best Practices:
+//   - Run gc as root or as the agent's user; files of processes whose /proc entries cannot be read are not seen as in use.
+//   - Preview with DryRun before changing retention policies.
+
+// Kinds of state collected by CollectGarbage.
+const (
+	GCKindRuntime  = "runtime"  // PID file and admin socket of an agent that is no longer running
+	GCKindIPC      = "ipc"      // shmipc shared memory files, queues and sockets of plugins
+	GCKindLogs     = "logs"     // Rotated copies of service.log_file
+	GCKindBuild    = "build"    // srediag artefacts in build.output_dir
+	GCKindPlugins  = "plugins"  // Backup and temporary plugin binaries, exec copies of removed plugins
+	GCKindBundles  = "bundles"  // Support bundles in diagnostics.bundle.output_dir
+	GCKindJobs     = "jobs"     // Spill directories of remote jobs in diagnostics.remote.work_dir
+	GCKindProfiles = "profiles" // Profiles in service.profiling.dir
+)
+
+// GCKinds lists the kinds of state in collection order.
+var GCKinds = []string{GCKindRuntime, GCKindIPC, GCKindLogs, GCKindBuild, GCKindPlugins, GCKindBundles, GCKindJobs, GCKindProfiles}
+
+// GCPolicy is the retention policy of one kind of state.
+//
+// Fields:
+//   - MaxAge: Unused items last modified longer ago than this are deleted; 0 deletes every unused item.
+//   - Keep: The newest Keep items are kept regardless of age.
+type GCPolicy struct {
+	MaxAge time.Duration
+	Keep   int
+}
+
+// defaultGCPolicies are the retention policies used unless service.gc.retention overrides them.
+var defaultGCPolicies = map[string]GCPolicy{
+	GCKindRuntime:  {},
+	GCKindIPC:      {MaxAge: time.Hour},
+	GCKindLogs:     {MaxAge: 14 * 24 * time.Hour},
+	GCKindBuild:    {MaxAge: 30 * 24 * time.Hour, Keep: 3},
+	GCKindPlugins:  {MaxAge: 7 * 24 * time.Hour},
+	GCKindBundles:  {MaxAge: 30 * 24 * time.Hour, Keep: 5},
+	GCKindJobs:     {MaxAge: time.Hour},
+	GCKindProfiles: {MaxAge: 30 * 24 * time.Hour},
+}
+
+// ipcPatterns are the shmipc files of plugin sessions (see plugin.PluginManager.Load).
+var ipcPatterns = []string{"/dev/shm/srediag-plugin-ipc*", "/tmp/srediag-plugin-ipc*", "/tmp/srediag-*.ipc"}
+
+// buildArtefactPatterns are the entries 'srediag build' writes to build.output_dir: agent binaries, the plugin
+// tree and plugin objects. Anything else in the directory belongs to other tools and is left alone.
+var buildArtefactPatterns = []string{"srediag", "srediag-*", "plugins", "*.so"}
+
+// pluginBackupSuffixes mark copies of plugin binaries left behind by upgrades.
+var pluginBackupSuffixes = []string{".old", ".bak", ".prev", ".orig", "~"}
+
+// pathsInUse lists the paths used by running processes; tests replace it.
+var pathsInUse = procPathsInUse
+
+// GCOptions selects what CollectGarbage collects.
+//
+// Fields:
+//   - Kinds: Kinds to collect; empty for all.
+//   - Policies: Retention policy per kind.
+//   - DryRun: Only report what would be deleted.
+type GCOptions struct {
+	Kinds    []string
+	Policies map[string]GCPolicy
+	DryRun   bool
+}
+
+// NewGCOptions returns the default policies with the overrides of service.gc.retention.
+//
+// Parameters:
+//   - cfg: Agent configuration.
+//
+// Returns:
+//   - GCOptions: Options collecting every kind.
+//   - error: If a retention entry names an unknown kind or has an invalid max_age or keep.
+func NewGCOptions(cfg *core.Config) (GCOptions, error) {
+	opts := GCOptions{Policies: make(map[string]GCPolicy, len(defaultGCPolicies))}
+	for kind, p := range defaultGCPolicies {
+		opts.Policies[kind] = p
+	}
+	for kind, rc := range cfg.Service.GC.Retention {
+		p, ok := opts.Policies[kind]
+		if !ok {
+			return opts, fmt.Errorf("unknown kind %q in service.gc.retention (want one of %s)", kind, strings.Join(GCKinds, ", "))
+		}
+		if rc.MaxAge != "" {
+			age, err := ParseRetention(rc.MaxAge)
+			if err != nil {
+				return opts, fmt.Errorf("invalid service.gc.retention.%s.max_age: %w", kind, err)
+			}
+			p.MaxAge = age
+		}
+		if rc.Keep != nil {
+			if *rc.Keep < 0 {
+				return opts, fmt.Errorf("invalid service.gc.retention.%s.keep %d", kind, *rc.Keep)
+			}
+			p.Keep = *rc.Keep
+		}
+		opts.Policies[kind] = p
+	}
+	return opts, nil
+}
+
+// ParseRetention parses a Go duration or a number of days ("14d").
+//
+// Parameters:
+//   - s: The retention.
+//
+// Returns:
+//   - time.Duration: The parsed retention.
+//   - error: If s is malformed or negative.
+func ParseRetention(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid retention %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid retention %q", s)
+	}
+	return d, nil
+}
+
+// GCItem is one piece of state found by CollectGarbage.
+//
+// Fields:
+//   - Kind: One of GCKinds.
+//   - Path: File or directory.
+//   - Size: Bytes on disk (for directories, of every file below).
+//   - ModTime: Last modification (for directories, of the newest file below).
+//   - Deleted: True if the item was (or, in a dry run, would be) deleted.
+//   - Reason: Why the item is kept or deleted.
+//   - Error: Why deleting failed.
+type GCItem struct {
+	Kind    string    `json:"kind" yaml:"kind"`
+	Path    string    `json:"path" yaml:"path"`
+	Size    int64     `json:"size" yaml:"size"`
+	ModTime time.Time `json:"mod_time" yaml:"mod_time"`
+	Deleted bool      `json:"deleted" yaml:"deleted"`
+	Reason  string    `json:"reason" yaml:"reason"`
+	Error   string    `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// GCReport is the outcome of CollectGarbage.
+//
+// Fields:
+//   - DryRun: True if nothing was deleted.
+//   - Items: Every item found, by kind and newest first.
+//   - Deleted: Number of items deleted.
+//   - Reclaimed: Bytes freed by the deleted items.
+//   - Failed: Number of items that could not be deleted.
+type GCReport struct {
+	DryRun    bool     `json:"dry_run" yaml:"dry_run"`
+	Items     []GCItem `json:"items" yaml:"items"`
+	Deleted   int      `json:"deleted" yaml:"deleted"`
+	Reclaimed int64    `json:"reclaimed_bytes" yaml:"reclaimed_bytes"`
+	Failed    int      `json:"failed" yaml:"failed"`
+}
+
+// gcCandidate is an item listed for a kind before the retention policy is applied.
+type gcCandidate struct {
+	path string
+	// ranked items count towards the policy's Keep; temporary files do not.
+	ranked bool
+	// stale forces deletion of an unused item regardless of age, with this reason.
+	stale string
+	// held keeps the item regardless of the policy, with this reason.
+	held string
+}
+
+// CollectGarbage lists the state of the selected kinds and deletes unused items outside their retention policy.
+//
+// Parameters:
+//   - ctx: Context for cancellation.
+//   - cfg: Agent configuration (locations of the state).
+//   - opts: Kinds, policies and dry run.
+//
+// Returns:
+//   - *GCReport: Every item found and what happened to it.
+//   - error: If a kind is unknown, the files in use cannot be determined or ctx is cancelled.
+func CollectGarbage(ctx context.Context, cfg *core.Config, opts GCOptions) (*GCReport, error) {
+	kinds := opts.Kinds
+	if len(kinds) == 0 {
+		kinds = GCKinds
+	}
+	for _, kind := range kinds {
+		if !slices.Contains(GCKinds, kind) {
+			return nil, fmt.Errorf("unknown kind %q (want one of %s)", kind, strings.Join(GCKinds, ", "))
+		}
+	}
+	inUse, err := pathsInUse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files in use: %w", err)
+	}
+	now := time.Now()
+	report := &GCReport{DryRun: opts.DryRun}
+	for _, kind := range GCKinds {
+		if !slices.Contains(kinds, kind) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		items := evaluateGC(kind, gcCandidates(cfg, kind), opts.Policies[kind], inUse, now)
+		for i := range items {
+			if items[i].Deleted && !opts.DryRun {
+				if err := os.RemoveAll(items[i].Path); err != nil {
+					items[i].Deleted, items[i].Error = false, err.Error()
+					report.Failed++
+					continue
+				}
+			}
+			if items[i].Deleted {
+				report.Deleted++
+				report.Reclaimed += items[i].Size
+			}
+		}
+		report.Items = append(report.Items, items...)
+	}
+	return report, nil
+}
+
+// evaluateGC stats the candidates of one kind and decides, newest first, which ones to delete.
+func evaluateGC(kind string, candidates []gcCandidate, policy GCPolicy, inUse openPaths, now time.Time) []GCItem {
+	type entry struct {
+		gcCandidate
+		item GCItem
+	}
+	var entries []entry
+	for _, c := range candidates {
+		size, modTime, err := diskUsage(c.path)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry{c, GCItem{Kind: kind, Path: c.path, Size: size, ModTime: modTime}})
+	}
+	slices.SortStableFunc(entries, func(a, b entry) int { return b.item.ModTime.Compare(a.item.ModTime) })
+
+	var items []GCItem
+	ranked := 0
+	for _, e := range entries {
+		item := e.item
+		age := now.Sub(item.ModTime)
+		if e.ranked {
+			ranked++
+		}
+		switch {
+		case e.held != "":
+			item.Reason = e.held
+		case inUse.covers(item.Path):
+			item.Reason = "in use by a running process"
+		case e.stale != "":
+			item.Deleted, item.Reason = true, e.stale
+		case e.ranked && ranked <= policy.Keep:
+			item.Reason = fmt.Sprintf("one of the %d newest", policy.Keep)
+		case age < policy.MaxAge:
+			item.Reason = "newer than " + formatRetention(policy.MaxAge)
+		case policy.MaxAge == 0:
+			item.Deleted, item.Reason = true, "unused"
+		default:
+			item.Deleted, item.Reason = true, "unused for more than "+formatRetention(policy.MaxAge)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// formatRetention renders a retention in whole days or hours where possible.
+func formatRetention(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d >= time.Hour && d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return d.String()
+}
+
+// diskUsage returns the size of a file, or of every file below a directory, and the newest modification time.
+func diskUsage(path string) (int64, time.Time, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if !info.IsDir() {
+		return info.Size(), info.ModTime(), nil
+	}
+	var size int64
+	modTime := info.ModTime()
+	err = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() {
+			size += info.Size()
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+		return nil
+	})
+	return size, modTime, err
+}
+
+// gcCandidates lists the items of one kind.
+func gcCandidates(cfg *core.Config, kind string) []gcCandidate {
+	switch kind {
+	case GCKindRuntime:
+		return runtimeCandidates(cfg)
+	case GCKindIPC:
+		var out []gcCandidate
+		for _, pattern := range ipcPatterns {
+			matches, _ := filepath.Glob(pattern)
+			for _, m := range matches {
+				out = append(out, gcCandidate{path: m, ranked: true})
+			}
+		}
+		return out
+	case GCKindLogs:
+		_, _, logFile := daemonPaths(cfg)
+		var out []gcCandidate
+		for _, pattern := range []string{logFile + ".*", logFile + "-*"} {
+			matches, _ := filepath.Glob(pattern)
+			for _, m := range matches {
+				out = append(out, gcCandidate{path: m, ranked: true})
+			}
+		}
+		return out
+	case GCKindBuild:
+		dir := cmp.Or(cfg.Build.OutputDir, core.DefaultBuildOutputDir())
+		var out []gcCandidate
+		for _, pattern := range buildArtefactPatterns {
+			out = append(out, dirCandidates(dir, pattern)...)
+		}
+		return out
+	case GCKindPlugins:
+		return pluginCandidates(cfg)
+	case GCKindBundles:
+		if cfg.Diagnostics.Bundle.OutputDir == "" {
+			return nil
+		}
+		// .srediag-bundle-* are bundles being written (see diagnose.WriteBundleFile).
+		return append(dirCandidates(cfg.Diagnostics.Bundle.OutputDir, "srediag-bundle-*.tar.gz"),
+			unranked(dirCandidates(cfg.Diagnostics.Bundle.OutputDir, ".srediag-bundle-*"))...)
+	case GCKindJobs:
+		return dirCandidates(cmp.Or(cfg.Diagnostics.Remote.WorkDir, filepath.Join(core.DefaultStateDir(), "jobs")), "*")
+	case GCKindProfiles:
+		return dirCandidates(cmp.Or(cfg.Service.Profiling.Dir, filepath.Join(core.DefaultStateDir(), "profiles")), "*")
+	}
+	return nil
+}
+
+// dirCandidates lists the entries of dir matching pattern.
+func dirCandidates(dir, pattern string) []gcCandidate {
+	matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+	out := make([]gcCandidate, 0, len(matches))
+	for _, m := range matches {
+		out = append(out, gcCandidate{path: m, ranked: true})
+	}
+	return out
+}
+
+// unranked marks candidates as not counting towards Keep.
+func unranked(candidates []gcCandidate) []gcCandidate {
+	for i := range candidates {
+		candidates[i].ranked = false
+	}
+	return candidates
+}
+
+// runtimeCandidates lists the PID file and admin socket; both are stale once no agent holds the PID file's lock.
+func runtimeCandidates(cfg *core.Config) []gcCandidate {
+	pidFile, socket, _ := daemonPaths(cfg)
+	running := PIDFileLocked(pidFile)
+	var out []gcCandidate
+	for _, path := range []string{pidFile, socket} {
+		if _, err := os.Lstat(path); err != nil {
+			continue
+		}
+		c := gcCandidate{path: path, stale: "agent not running"}
+		if running {
+			c.held = "agent running"
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// pluginCandidates lists backup and temporary binaries in the plugin directories and, when plugins.exec_dir
+// differs from plugins.dir, exec copies of plugins that were removed from plugins.dir.
+func pluginCandidates(cfg *core.Config) []gcCandidate {
+	var dirs []string
+	for _, dir := range []string{cfg.Plugins.Dir, cfg.Plugins.ExecDir} {
+		if dir != "" && !slices.Contains(dirs, filepath.Clean(dir)) {
+			dirs = append(dirs, filepath.Clean(dir))
+		}
+	}
+	var out []gcCandidate
+	for _, dir := range dirs {
+		for _, typ := range []core.ComponentType{core.TypeReceiver, core.TypeProcessor, core.TypeExporter, core.TypeExtension} {
+			typeDir := filepath.Join(dir, string(typ)+"s")
+			entries, err := os.ReadDir(typeDir)
+			if err != nil {
+				continue
+			}
+			for _, e := range entries {
+				name := e.Name()
+				path := filepath.Join(typeDir, name)
+				switch {
+				case strings.HasPrefix(name, "."):
+					out = append(out, gcCandidate{path: path})
+				case slices.ContainsFunc(pluginBackupSuffixes, func(s string) bool { return strings.HasSuffix(name, s) }):
+					out = append(out, gcCandidate{path: path, ranked: true})
+				case len(dirs) == 2 && dir == dirs[1] && !e.IsDir():
+					if _, err := os.Lstat(filepath.Join(dirs[0], string(typ)+"s", name)); errors.Is(err, fs.ErrNotExist) {
+						out = append(out, gcCandidate{path: path, stale: "removed from plugins.dir"})
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// openPaths is a sorted list of paths used by running processes.
+type openPaths []string
+
+// covers reports whether path, or a file below it, is in use.
+func (p openPaths) covers(path string) bool {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	i, found := slices.BinarySearch(p, path)
+	if found {
+		return true
+	}
+	return i < len(p) && strings.HasPrefix(p[i], path+string(filepath.Separator))
+}
+
+// procPathsInUse collects the open files, memory mappings, executables and working directories of every process
+// it may inspect, plus the paths of bound unix sockets.
+func procPathsInUse() (openPaths, error) {
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	add := func(p string) {
+		if p = strings.TrimSuffix(p, " (deleted)"); filepath.IsAbs(p) {
+			seen[p] = true
+		}
+	}
+	for _, proc := range procs {
+		if _, err := strconv.Atoi(proc.Name()); err != nil {
+			continue
+		}
+		dir := filepath.Join("/proc", proc.Name())
+		for _, link := range []string{"exe", "cwd"} {
+			if target, err := os.Readlink(filepath.Join(dir, link)); err == nil {
+				add(target)
+			}
+		}
+		if fds, err := os.ReadDir(filepath.Join(dir, "fd")); err == nil {
+			for _, fd := range fds {
+				if target, err := os.Readlink(filepath.Join(dir, "fd", fd.Name())); err == nil {
+					add(target)
+				}
+			}
+		}
+		scanProcFile(filepath.Join(dir, "maps"), 5, add)
+	}
+	scanProcFile("/proc/net/unix", 7, add)
+	return openPaths(slices.Sorted(maps.Keys(seen))), nil
+}
+
+// scanProcFile passes the text from the field-th whitespace separated field to the end of each line to fn.
+func scanProcFile(path string, field int, fn func(string)) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > field {
+			fn(strings.Join(fields[field:], " "))
+		}
+	}
+}
+
+// WriteGCReport renders a garbage collection report.
+//
+// Parameters:
+//   - w: Destination writer.
+//   - r: Report to render.
+//   - format: One of "table" (default), "json" or "yaml".
+//
+// Returns:
+//   - error: If the format is unknown or encoding fails, returns a detailed error.
+func WriteGCReport(w io.Writer, r *GCReport, format string) error {
+	switch strings.ToLower(format) {
+	case "", "table":
+		return writeGCTable(w, r)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("failed to encode gc report as YAML: %w", err)
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("unsupported output format %q (expected table, json, or yaml)", format)
+	}
+}
+
+// writeGCTable renders a report as aligned text followed by a summary line.
+func writeGCTable(w io.Writer, r *GCReport) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if len(r.Items) > 0 {
+		fmt.Fprintln(tw, "KIND\tPATH\tSIZE\tMODIFIED\tACTION\tREASON")
+	}
+	for _, item := range r.Items {
+		action := "keep"
+		switch {
+		case item.Error != "":
+			action, item.Reason = "failed", item.Error
+		case item.Deleted && r.DryRun:
+			action = "would delete"
+		case item.Deleted:
+			action = "deleted"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", item.Kind, item.Path, formatBytes(item.Size),
+			item.ModTime.Local().Format(time.DateTime), action, item.Reason)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	verb := "Reclaimed"
+	if r.DryRun {
+		verb = "Would reclaim"
+	}
+	_, err := fmt.Fprintf(w, "%s %s from %d of %d items", verb, formatBytes(r.Reclaimed), r.Deleted, len(r.Items))
+	if err == nil && r.Failed > 0 {
+		_, err = fmt.Fprintf(w, "; %d could not be deleted", r.Failed)
+	}
+	if err == nil {
+		_, err = fmt.Fprintln(w)
+	}
+	return err
+}
+
+// formatBytes renders a size with a binary unit.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// GCRunner runs CollectGarbage periodically in the agent.
+type GCRunner struct {
+	logger   *core.Logger
+	cfg      *core.Config
+	opts     GCOptions
+	interval time.Duration
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewGCRunner creates the periodic garbage collector of the agent config (service.gc).
+//
+// Parameters:
+//   - logger: Logger for collection summaries and errors.
+//   - cfg: Agent configuration.
+//
+// Returns:
+//   - *GCRunner: The runner, or nil when service.gc.interval is not set.
+//   - error: If the interval or a retention policy is invalid.
+func NewGCRunner(logger *core.Logger, cfg *core.Config) (*GCRunner, error) {
+	if cfg.Service.GC.Interval == "" {
+		return nil, nil
+	}
+	interval, err := time.ParseDuration(cfg.Service.GC.Interval)
+	if err != nil || interval <= 0 {
+		return nil, fmt.Errorf("invalid service.gc.interval %q", cfg.Service.GC.Interval)
+	}
+	opts, err := NewGCOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &GCRunner{logger: logger, cfg: cfg, opts: opts, interval: interval}, nil
+}
+
+// Start collects garbage every interval in the background until Stop; the first run is one interval after Start.
+//
+// Parameters:
+//   - ctx: Context of the background loop.
+func (g *GCRunner) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	g.mu.Lock()
+	g.cancel, g.done = cancel, done
+	g.mu.Unlock()
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(g.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				g.run(ctx)
+			}
+		}
+	}()
+}
+
+// run collects garbage once and logs the outcome.
+func (g *GCRunner) run(ctx context.Context) {
+	report, err := CollectGarbage(ctx, g.cfg, g.opts)
+	if err != nil {
+		if ctx.Err() == nil {
+			g.logger.Warn("Garbage collection failed", core.ZapError(err))
+		}
+		return
+	}
+	g.logger.Info("Garbage collection finished",
+		core.ZapInt("deleted", report.Deleted),
+		core.ZapInt("failed", report.Failed),
+		core.ZapString("reclaimed", formatBytes(report.Reclaimed)))
+}
+
+// Stop cancels the background loop and waits for a running collection to finish.
+//
+// Parameters:
+//   - ctx: Context bounding the wait.
+//
+// Returns:
+//   - error: ctx.Err() if the loop did not exit in time.
+func (g *GCRunner) Stop(ctx context.Context) error {
+	g.mu.Lock()
+	cancel, done := g.cancel, g.done
+	g.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
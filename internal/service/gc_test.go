@@ -0,0 +1,273 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+func TestParseRetention(t *testing.T) {
+	for in, want := range map[string]time.Duration{"14d": 14 * 24 * time.Hour, "90m": 90 * time.Minute, "0": 0} {
+		got, err := ParseRetention(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	for _, in := range []string{"", "d", "-1d", "-5m", "week"} {
+		_, err := ParseRetention(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestNewGCOptions(t *testing.T) {
+	cfg := core.NewConfig()
+	keep := 0
+	cfg.Service.GC.Retention = map[string]core.GCRetentionConfig{
+		GCKindBundles: {MaxAge: "7d", Keep: &keep},
+		GCKindLogs:    {MaxAge: "48h"},
+	}
+	opts, err := NewGCOptions(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, GCPolicy{MaxAge: 7 * 24 * time.Hour}, opts.Policies[GCKindBundles])
+	assert.Equal(t, GCPolicy{MaxAge: 48 * time.Hour}, opts.Policies[GCKindLogs])
+	assert.Equal(t, defaultGCPolicies[GCKindBuild], opts.Policies[GCKindBuild])
+
+	cfg.Service.GC.Retention = map[string]core.GCRetentionConfig{"caches": {MaxAge: "1h"}}
+	_, err = NewGCOptions(cfg)
+	assert.ErrorContains(t, err, `unknown kind "caches"`)
+	cfg.Service.GC.Retention = map[string]core.GCRetentionConfig{GCKindLogs: {MaxAge: "soon"}}
+	_, err = NewGCOptions(cfg)
+	assert.ErrorContains(t, err, "service.gc.retention.logs.max_age")
+}
+
+// gcFixture creates state of every kind in temp dirs; names ending in "old" are 60 days old.
+func gcFixture(t *testing.T) *core.Config {
+	t.Helper()
+	cfg := daemonConfig(t)
+	dir := t.TempDir()
+	cfg.Build.OutputDir = filepath.Join(dir, "build")
+	cfg.Diagnostics.Bundle.OutputDir = filepath.Join(dir, "bundles")
+	cfg.Diagnostics.Remote.WorkDir = filepath.Join(dir, "jobs")
+	cfg.Service.Profiling.Dir = filepath.Join(dir, "profiles")
+	cfg.Plugins.Dir = filepath.Join(dir, "plugins")
+	cfg.Plugins.ExecDir = filepath.Join(dir, "exec")
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	for _, name := range []string{
+		cfg.Service.PIDFile,
+		cfg.Service.LogFile,
+		cfg.Service.LogFile + ".1.gz-old",
+		"ipc/srediag-receiver-otlp.ipc-old",
+		"build/srediag-a-old", "build/srediag-b-old", "build/srediag-c-old", "build/srediag-d-old", "build/srediag-e-old",
+		"build/other-tool.tar-old",
+		"bundles/srediag-bundle-host-20260101T000000Z.tar.gz",
+		"bundles/.srediag-bundle-host-20260101T000000Z.123-old",
+		"jobs/job-1-old/report.json",
+		"jobs/job-2/report.json",
+		"profiles/heap-20260101T000000.000Z.pb.gz-old",
+		"plugins/receivers/otlp",
+		"plugins/receivers/otlp-old.bak",
+		"exec/receivers/otlp",
+		"exec/receivers/removed",
+	} {
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, name)
+		}
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("0123456789"), 0o644))
+		if strings.Contains(name, "-old") {
+			require.NoError(t, os.Chtimes(path, old, old))
+		}
+	}
+	// The job directory itself was modified when its report was written.
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "jobs", "job-1-old"), old, old))
+	ipc := ipcPatterns
+	t.Cleanup(func() { ipcPatterns = ipc })
+	ipcPatterns = []string{filepath.Join(dir, "ipc", "srediag-*.ipc*")}
+	return cfg
+}
+
+// stubPathsInUse makes paths the only files in use.
+func stubPathsInUse(t *testing.T, paths ...string) {
+	t.Helper()
+	old := pathsInUse
+	t.Cleanup(func() { pathsInUse = old })
+	pathsInUse = func() (openPaths, error) { return openPaths(paths), nil }
+}
+
+// gcActions maps the base name of every reported item to whether it is deleted.
+func gcActions(r *GCReport) map[string]bool {
+	out := make(map[string]bool)
+	for _, item := range r.Items {
+		out[filepath.Base(item.Path)] = item.Deleted
+	}
+	return out
+}
+
+func TestCollectGarbage(t *testing.T) {
+	cfg := gcFixture(t)
+	stubPathsInUse(t, filepath.Join(cfg.Build.OutputDir, "srediag-e-old"))
+	opts, err := NewGCOptions(cfg)
+	require.NoError(t, err)
+	opts.DryRun = true
+
+	report, err := CollectGarbage(context.Background(), cfg, opts)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{
+		"srediag.pid":                   true, // no agent holds the lock
+		"srediag-receiver-otlp.ipc-old": true,
+		"srediag.log.1.gz-old":          true,
+		"srediag-a-old":                 false, // newest three are kept
+		"srediag-b-old":                 false,
+		"srediag-c-old":                 false,
+		"srediag-d-old":                 true,
+		"srediag-e-old":                 false, // in use
+		"srediag-bundle-host-20260101T000000Z.tar.gz":   false,
+		".srediag-bundle-host-20260101T000000Z.123-old": true,
+		"job-1-old":                           true,
+		"job-2":                               false,
+		"heap-20260101T000000.000Z.pb.gz-old": true,
+		"otlp-old.bak":                        true,
+		"removed":                             true, // exec copy of a plugin removed from plugins.dir
+	}, gcActions(report))
+	assert.Equal(t, 9, report.Deleted)
+	assert.Equal(t, int64(90), report.Reclaimed)
+	assert.FileExists(t, cfg.Service.PIDFile, "a dry run deletes nothing")
+
+	var out bytes.Buffer
+	require.NoError(t, WriteGCReport(&out, report, "table"))
+	assert.Contains(t, out.String(), "would delete")
+	assert.Contains(t, out.String(), "Would reclaim 90 B from 9 of 15 items")
+
+	opts.DryRun, opts.Kinds = false, []string{GCKindRuntime, GCKindJobs}
+	report, err = CollectGarbage(context.Background(), cfg, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Deleted)
+	assert.NoFileExists(t, cfg.Service.PIDFile)
+	assert.NoDirExists(t, filepath.Join(cfg.Diagnostics.Remote.WorkDir, "job-1-old"))
+	assert.DirExists(t, filepath.Join(cfg.Diagnostics.Remote.WorkDir, "job-2"))
+	assert.FileExists(t, filepath.Join(cfg.Build.OutputDir, "srediag-d-old"), "only the selected kinds are collected")
+
+	opts.Kinds = []string{"caches"}
+	_, err = CollectGarbage(context.Background(), cfg, opts)
+	assert.ErrorContains(t, err, `unknown kind "caches"`)
+}
+
+func TestCollectGarbage_BuildKeepsForeignFiles(t *testing.T) {
+	cfg := gcFixture(t)
+	stubPathsInUse(t)
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	for _, name := range []string{"srediag", "plugins/receiver/otlp/otlp.so", "otlp.so", "go-build-cache/x"} {
+		path := filepath.Join(cfg.Build.OutputDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("0123456789"), 0o644))
+	}
+	for _, name := range []string{"srediag", "plugins", "plugins/receiver/otlp/otlp.so", "otlp.so", "go-build-cache", "go-build-cache/x"} {
+		require.NoError(t, os.Chtimes(filepath.Join(cfg.Build.OutputDir, name), old, old))
+	}
+
+	report, err := CollectGarbage(context.Background(), cfg, GCOptions{
+		Kinds:    []string{GCKindBuild},
+		Policies: map[string]GCPolicy{GCKindBuild: {}},
+	})
+	require.NoError(t, err)
+	assert.NotContains(t, gcActions(report), "other-tool.tar-old")
+	assert.NotContains(t, gcActions(report), "go-build-cache")
+	assert.Equal(t, 8, report.Deleted, "srediag-{a..e}-old, srediag, plugins and otlp.so")
+	assert.FileExists(t, filepath.Join(cfg.Build.OutputDir, "other-tool.tar-old"), "files of other tools survive")
+	assert.FileExists(t, filepath.Join(cfg.Build.OutputDir, "go-build-cache", "x"))
+	assert.NoFileExists(t, filepath.Join(cfg.Build.OutputDir, "srediag"))
+	assert.NoDirExists(t, filepath.Join(cfg.Build.OutputDir, "plugins"))
+}
+
+func TestCollectGarbage_RunningAgent(t *testing.T) {
+	cfg := gcFixture(t)
+	stubPathsInUse(t)
+	require.NoError(t, os.Remove(cfg.Service.PIDFile))
+	pidFile, err := AcquirePIDFile(cfg.Service.PIDFile)
+	require.NoError(t, err)
+	defer func() { _ = pidFile.Release() }()
+	opts, err := NewGCOptions(cfg)
+	require.NoError(t, err)
+	opts.Kinds = []string{GCKindRuntime}
+
+	report, err := CollectGarbage(context.Background(), cfg, opts)
+	require.NoError(t, err)
+	require.Len(t, report.Items, 1)
+	assert.False(t, report.Items[0].Deleted)
+	assert.Equal(t, "agent running", report.Items[0].Reason)
+}
+
+func TestProcPathsInUse(t *testing.T) {
+	if _, err := os.Stat("/proc/self/fd"); err != nil {
+		t.Skip("needs /proc")
+	}
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "open"))
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	paths, err := procPathsInUse()
+	require.NoError(t, err)
+	assert.True(t, paths.covers(f.Name()))
+	assert.True(t, paths.covers(dir), "a directory is in use when a file below it is")
+	assert.False(t, paths.covers(filepath.Join(dir, "closed")))
+}
+
+func TestCLI_Gc(t *testing.T) {
+	cfg := gcFixture(t)
+	stubPathsInUse(t)
+	cmd := &cobra.Command{}
+	cmd.Flags().String("output", "table", "")
+	cmd.Flags().Bool("dry-run", true, "")
+	cmd.Flags().StringSlice("kind", []string{GCKindBuild}, "")
+	cmd.Flags().String("retention", "90d", "")
+	cmd.SetContext(context.Background())
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	require.NoError(t, CLI_Gc(&core.AppContext{Config: cfg}, cmd, nil))
+	assert.Contains(t, out.String(), "Would reclaim 0 B from 0 of 5 items")
+
+	require.NoError(t, cmd.Flags().Set("retention", "1d"))
+	require.NoError(t, cmd.Flags().Set("dry-run", "false"))
+	out.Reset()
+	require.NoError(t, CLI_Gc(&core.AppContext{Config: cfg}, cmd, nil))
+	assert.Contains(t, out.String(), "Reclaimed 20 B from 2 of 5 items")
+
+	require.NoError(t, cmd.Flags().Set("retention", "soon"))
+	assert.ErrorContains(t, CLI_Gc(&core.AppContext{Config: cfg}, cmd, nil), "invalid --retention")
+}
+
+func TestGCRunner(t *testing.T) {
+	cfg := gcFixture(t)
+	stubPathsInUse(t)
+	g, err := NewGCRunner(core.NewTestLogger(&bytes.Buffer{}), cfg)
+	require.NoError(t, err)
+	assert.Nil(t, g, "off without service.gc.interval")
+
+	cfg.Service.GC.Interval = "never"
+	_, err = NewGCRunner(core.NewTestLogger(&bytes.Buffer{}), cfg)
+	assert.ErrorContains(t, err, "service.gc.interval")
+
+	cfg.Service.GC.Interval = "10ms"
+	g, err = NewGCRunner(core.NewTestLogger(&bytes.Buffer{}), cfg)
+	require.NoError(t, err)
+	g.Start(context.Background())
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(cfg.Service.PIDFile)
+		return os.IsNotExist(err)
+	}, 5*time.Second, 10*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, g.Stop(ctx))
+}
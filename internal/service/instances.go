@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension"
+	"go.opentelemetry.io/collector/featuregate"
+	otelservice "go.opentelemetry.io/collector/service"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package service provides the core service orchestration, lifecycle management, and CLI stubs for the SREDIAG collector service.
+//
+// This file defines how the embedded collector is split into independently restartable instances, so that a reload
+// only restarts the pipelines whose config changed. Pipelines that share a receiver, exporter or connector, or an
+// extension named in a component's settings (e.g. 'storage: file_storage'), must run in the same otelcol service and
+// form one group; every group runs as its own instance. A separate extensions instance runs the remaining extensions
+// and the internal telemetry of service.telemetry; the group instances do not serve internal metrics, so that they do
+// not compete for the same listen address.
+//
+// Usage:
+//   - Call splitCollectorConfig on a merged collector config (LoadCollectorConfig) to get the instance configs.
+//   - Use Service.startInstance and Service.stopInstance to run them.
+//
+// Best Practices:
+//   - Compare instances by digest; an unchanged digest means the running instance can be kept as is.
+
+// extensionsInstance is the key of the instance that runs the extensions not tied to a pipeline group.
+const extensionsInstance = ""
+
+// agentExtensionType is a placeholder extension that keeps the extensions instance non-empty, as otelcol refuses to
+// run a config without components. It is defined but never listed in service.extensions, so it is never started.
+var agentExtensionType = component.MustNewType("srediag_agent")
+
+// agentExtensionFactory returns the factory of agentExtensionType.
+func agentExtensionFactory() extension.Factory {
+	return extension.NewFactory(agentExtensionType,
+		func() component.Config { return &struct{}{} },
+		func(context.Context, extension.Settings, component.Config) (extension.Extension, error) {
+			return struct {
+				component.StartFunc
+				component.ShutdownFunc
+			}{}, nil
+		},
+		component.StabilityLevelStable)
+}
+
+// allowNoPipelines enables the otelcol feature gate that lets the extensions instance run without pipelines.
+var allowNoPipelines = sync.OnceValue(func() error {
+	return featuregate.GlobalRegistry().Set("service.AllowNoPipelines", true)
+})
+
+// collectorInstance is one otelcol service of the embedded collector.
+//
+// Fields:
+//   - key: Sorted pipeline names joined by ",", or extensionsInstance.
+//   - conf: The instance's collector config.
+//   - digest: Hash of conf; instances with equal keys and digests are interchangeable.
+//   - types: The component types used, as "<kind>/<type>", to find the instances affected by a plugin swap.
+//   - svc, asyncErrors, stopped: The running otelcol service, its fatal error channel and its stop signal.
+type collectorInstance struct {
+	key    string
+	conf   map[string]any
+	digest string
+	types  []string
+
+	svc         *otelservice.Service
+	asyncErrors chan error
+	stopped     chan struct{}
+}
+
+// name returns the instance's name for logs and reload reports.
+func (i *collectorInstance) name() string {
+	if i.key == extensionsInstance {
+		return "extensions"
+	}
+	return i.key
+}
+
+// uses reports whether the instance uses any of the given component types ("<kind>/<type>").
+func (i *collectorInstance) uses(types map[string]bool) bool {
+	return slices.ContainsFunc(i.types, func(t string) bool { return types[t] })
+}
+
+// splitCollectorConfig splits a merged collector config into the configs of its instances, keyed by
+// collectorInstance.key. The diag_exporter is left out; the service runs it itself.
+func splitCollectorConfig(conf map[string]any) map[string]*collectorInstance {
+	conf = withoutDiagExporter(conf)
+	svcConf, _ := conf["service"].(map[string]any)
+	pipelines, _ := svcConf["pipelines"].(map[string]any)
+	extensions, _ := conf["extensions"].(map[string]any)
+	connectors, _ := conf["connectors"].(map[string]any)
+
+	// Union-find over pipelines: two pipelines join when they claim the same component.
+	parent := make(map[string]string, len(pipelines))
+	var find func(string) string
+	find = func(p string) string {
+		if parent[p] != p {
+			parent[p] = find(parent[p])
+		}
+		return parent[p]
+	}
+	owner := make(map[string]string)
+	claim := func(component, pipeline string) {
+		if first, ok := owner[component]; ok {
+			parent[find(pipeline)] = find(first)
+			return
+		}
+		owner[component] = pipeline
+	}
+	members := make(map[string]map[string][]string, len(pipelines))
+	for _, name := range slices.Sorted(maps.Keys(pipelines)) {
+		parent[name] = name
+		pipeline, _ := pipelines[name].(map[string]any)
+		members[name] = make(map[string][]string)
+		for _, list := range []string{"receivers", "processors", "exporters"} {
+			for _, id := range stringList(pipeline[list]) {
+				section := list
+				if _, ok := connectors[id]; ok && list != "processors" {
+					section = "connectors"
+				}
+				members[name][section] = append(members[name][section], id)
+				if section != "processors" {
+					// Each pipeline gets its own processor instances, so only shared receivers, exporters and
+					// connectors tie pipelines together.
+					claim(section+"/"+id, name)
+				}
+				settings, _ := conf[section].(map[string]any)
+				for _, ext := range referencedExtensions(settings[id], extensions) {
+					claim("extensions/"+ext, name)
+				}
+			}
+		}
+	}
+
+	groups := make(map[string][]string)
+	for name := range pipelines {
+		root := find(name)
+		groups[root] = append(groups[root], name)
+	}
+	listed := stringList(svcConf["extensions"])
+	telemetry, _ := svcConf["telemetry"].(map[string]any)
+	instances := make(map[string]*collectorInstance, len(groups)+1)
+	assigned := make(map[string]bool)
+	for root, names := range groups {
+		slices.Sort(names)
+		inst := map[string]any{}
+		groupPipelines := make(map[string]any, len(names))
+		for _, name := range names {
+			groupPipelines[name] = pipelines[name]
+			for section, ids := range members[name] {
+				addComponents(inst, conf, section, ids)
+			}
+		}
+		var exts []string
+		for component, pipeline := range owner {
+			if ext, ok := strings.CutPrefix(component, "extensions/"); ok && find(pipeline) == root {
+				exts = append(exts, ext)
+				assigned[ext] = true
+			}
+		}
+		slices.Sort(exts)
+		addComponents(inst, conf, "extensions", exts)
+		inst["service"] = map[string]any{
+			"extensions": toAnyList(slices.DeleteFunc(slices.Clone(listed), func(e string) bool { return !slices.Contains(exts, e) })),
+			"pipelines":  groupPipelines,
+			"telemetry":  groupTelemetry(telemetry),
+		}
+		key := strings.Join(names, ",")
+		instances[key] = newCollectorInstance(key, inst)
+	}
+
+	extras := map[string]any{}
+	var rest []string
+	for ext := range extensions {
+		if !assigned[ext] {
+			rest = append(rest, ext)
+		}
+	}
+	slices.Sort(rest)
+	addComponents(extras, conf, "extensions", rest)
+	if extras["extensions"] == nil {
+		extras["extensions"] = map[string]any{agentExtensionType.String(): nil}
+	}
+	extrasService := map[string]any{
+		"extensions": toAnyList(slices.DeleteFunc(slices.Clone(listed), func(e string) bool { return assigned[e] })),
+		"pipelines":  map[string]any{},
+	}
+	if telemetry != nil {
+		extrasService["telemetry"] = telemetry
+	}
+	extras["service"] = extrasService
+	instances[extensionsInstance] = newCollectorInstance(extensionsInstance, extras)
+	return instances
+}
+
+// newCollectorInstance returns a (not yet started) instance for an instance config.
+func newCollectorInstance(key string, conf map[string]any) *collectorInstance {
+	inst := &collectorInstance{key: key, conf: conf, digest: configDigest(nil, conf)}
+	for _, section := range collectorSections {
+		components, _ := conf[section].(map[string]any)
+		for id := range components {
+			typ, _, _ := strings.Cut(id, "/")
+			inst.types = append(inst.types, strings.TrimSuffix(section, "s")+"/"+typ)
+		}
+	}
+	slices.Sort(inst.types)
+	inst.types = slices.Compact(inst.types)
+	return inst
+}
+
+// addComponents copies the settings of the given components of a section from conf into inst.
+func addComponents(inst, conf map[string]any, section string, ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	src, _ := conf[section].(map[string]any)
+	dst, _ := inst[section].(map[string]any)
+	if dst == nil {
+		dst = make(map[string]any)
+		inst[section] = dst
+	}
+	for _, id := range ids {
+		dst[id] = src[id]
+	}
+}
+
+// referencedExtensions lists the extensions whose ID appears as a string value in a component's settings, such as
+// an authenticator or a storage extension.
+func referencedExtensions(settings any, extensions map[string]any) []string {
+	var out []string
+	var walk func(any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case string:
+			if _, ok := extensions[v]; ok && !slices.Contains(out, v) {
+				out = append(out, v)
+			}
+		case map[string]any:
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(settings)
+	return out
+}
+
+// groupTelemetry returns service.telemetry for a pipeline group: the configured logs and traces settings, but no
+// internal metrics, which the extensions instance serves.
+func groupTelemetry(telemetry map[string]any) map[string]any {
+	out := maps.Clone(telemetry)
+	if out == nil {
+		out = make(map[string]any)
+	}
+	out["metrics"] = map[string]any{"level": "none"}
+	return out
+}
+
+// toAnyList converts a string list back to its decoded YAML form.
+func toAnyList(items []string) []any {
+	out := make([]any, 0, len(items))
+	for _, item := range items {
+		out = append(out, item)
+	}
+	return out
+}
+
+// validateInstance checks an instance config as far as possible without starting it: component configs, pipeline
+// graph and factories.
+func (s *Service) validateInstance(ctx context.Context, inst *collectorInstance) error {
+	if err := allowNoPipelines(); err != nil {
+		return err
+	}
+	cfg, factories, err := s.resolveCollectorConfig(ctx, inst.name(), inst.conf)
+	if err != nil {
+		return err
+	}
+	set, err := s.collectorServiceSettings(cfg, factories, nil)
+	if err != nil {
+		return err
+	}
+	return otelservice.Validate(ctx, set, cfg.Service)
+}
+
+// startInstance builds and starts the otelcol service of an instance. A fatal component error reported later stops
+// the agent (see Done), as it would stop a standalone collector.
+func (s *Service) startInstance(ctx context.Context, inst *collectorInstance) error {
+	if err := allowNoPipelines(); err != nil {
+		return err
+	}
+	cfg, factories, err := s.resolveCollectorConfig(ctx, inst.name(), inst.conf)
+	if err != nil {
+		return err
+	}
+	asyncErrors := make(chan error)
+	set, err := s.collectorServiceSettings(cfg, factories, asyncErrors)
+	if err != nil {
+		return err
+	}
+	svc, err := otelservice.New(ctx, set, cfg.Service)
+	if err != nil {
+		return err
+	}
+	if err := svc.Start(ctx); err != nil {
+		return errors.Join(err, svc.Shutdown(context.WithoutCancel(ctx)))
+	}
+	inst.svc, inst.asyncErrors, inst.stopped = svc, asyncErrors, make(chan struct{})
+	go func(stopped <-chan struct{}) {
+		select {
+		case err := <-asyncErrors:
+			s.logger.Error("Asynchronous collector error, stopping the agent",
+				core.ZapString("instance", inst.name()), core.ZapError(err))
+			s.collectorFailed(err)
+		case <-stopped:
+		}
+	}(inst.stopped)
+	s.logger.Debug("Collector instance running", core.ZapString("instance", inst.name()))
+	return nil
+}
+
+// stopInstance shuts down the otelcol service of a running instance.
+func (s *Service) stopInstance(ctx context.Context, inst *collectorInstance) error {
+	if inst.svc == nil {
+		return nil
+	}
+	close(inst.stopped)
+	err := inst.svc.Shutdown(ctx)
+	inst.svc = nil
+	if err != nil {
+		return fmt.Errorf("failed to stop collector instance %s: %w", inst.name(), err)
+	}
+	return nil
+}
+
+// sortedInstances returns instances in start order: the extensions instance first, then the groups by key.
+func sortedInstances(instances []*collectorInstance) []*collectorInstance {
+	return slices.SortedFunc(slices.Values(instances), func(a, b *collectorInstance) int { return strings.Compare(a.key, b.key) })
+}
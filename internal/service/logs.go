@@ -0,0 +1,224 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package service provides the core service orchestration, lifecycle management, and CLI stubs for the SREDIAG collector service.
+//
+// This file defines how the recent logs of a running agent are queried: the agent tees its logger into a
+// core.LogBuffer, the admin API streams matching entries as JSON lines (GET /v1/logs), and 'srediag service
+// tail-logs' renders them as console lines or JSON.
+//
+// Usage:
+//   - Build a LogQuery, call AdminClient.Logs and render each entry with WriteLogEntry.
+//
+// Best Practices:
+//   - Filter in the query rather than in the client; the agent then skips non-matching entries before sending.
+
+// LogBufferSize is how many recent log entries the agent keeps for 'srediag service tail-logs'.
+const LogBufferSize = 2000
+
+// logLevels lists the log levels from least to most severe.
+var logLevels = []string{"debug", "info", "warn", "error", "dpanic", "panic", "fatal"}
+
+// LogQuery selects log entries of the running agent.
+//
+// Fields:
+//   - Since: Only entries logged at or after this time; zero for all.
+//   - Level: Minimum level (debug, info, warn, error, ...); empty for all.
+//   - Components: Component names, kinds ("receiver" matches "receiver/otlp") or globs; empty for all.
+//   - Fields: Required field values ("msg", "logger", "caller" or a structured field); values may be globs.
+//   - Lines: Only the last Lines matching buffered entries; 0 for all.
+//   - Follow: Keep streaming new entries until the request is cancelled.
+type LogQuery struct {
+	Since      time.Time
+	Level      string
+	Components []string
+	Fields     map[string]string
+	Lines      int
+	Follow     bool
+}
+
+// values encodes the query as admin API URL parameters.
+func (q LogQuery) values() url.Values {
+	v := url.Values{}
+	if !q.Since.IsZero() {
+		v.Set("since", q.Since.Format(time.RFC3339Nano))
+	}
+	if q.Level != "" {
+		v.Set("level", q.Level)
+	}
+	for _, c := range q.Components {
+		v.Add("component", c)
+	}
+	for _, key := range slices.Sorted(maps.Keys(q.Fields)) {
+		v.Add("field", key+"="+q.Fields[key])
+	}
+	if q.Lines > 0 {
+		v.Set("lines", strconv.Itoa(q.Lines))
+	}
+	if q.Follow {
+		v.Set("follow", "true")
+	}
+	return v
+}
+
+// parseLogQuery decodes and validates admin API URL parameters.
+func parseLogQuery(v url.Values) (LogQuery, error) {
+	var q LogQuery
+	if s := v.Get("since"); s != "" {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return q, fmt.Errorf("invalid since %q: %w", s, err)
+		}
+		q.Since = t
+	}
+	level, err := normalizeLogLevel(v.Get("level"))
+	if err != nil {
+		return q, err
+	}
+	q.Level = level
+	q.Components = v["component"]
+	if q.Fields, err = parseFieldFilters(v["field"]); err != nil {
+		return q, err
+	}
+	if s := v.Get("lines"); s != "" {
+		if q.Lines, err = strconv.Atoi(s); err != nil || q.Lines < 0 {
+			return q, fmt.Errorf("invalid lines %q", s)
+		}
+	}
+	q.Follow = v.Get("follow") == "true"
+	return q, nil
+}
+
+// parseFieldFilters parses key=value field filters.
+func parseFieldFilters(filters []string) (map[string]string, error) {
+	var fields map[string]string
+	for _, kv := range filters {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid field filter %q: want key=value", kv)
+		}
+		if fields == nil {
+			fields = map[string]string{}
+		}
+		fields[key] = value
+	}
+	return fields, nil
+}
+
+// normalizeLogLevel validates a level name and maps "warning" to "warn".
+func normalizeLogLevel(level string) (string, error) {
+	level = strings.ToLower(level)
+	if level == "warning" {
+		level = "warn"
+	}
+	if level != "" && !slices.Contains(logLevels, level) {
+		return "", fmt.Errorf("invalid level %q (want one of %s)", level, strings.Join(logLevels, ", "))
+	}
+	return level, nil
+}
+
+// Match reports whether an entry is selected by the query; Lines and Follow are not considered.
+//
+// Parameters:
+//   - e: The log entry.
+//
+// Returns:
+//   - bool: True if the entry passes every filter.
+func (q LogQuery) Match(e core.LogEntry) bool {
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if q.Level != "" && slices.Index(logLevels, e.Level) < slices.Index(logLevels, q.Level) {
+		return false
+	}
+	if len(q.Components) > 0 && !slices.ContainsFunc(q.Components, func(c string) bool {
+		return e.Component == c || strings.HasPrefix(e.Component, c+"/") || globMatch(c, e.Component)
+	}) {
+		return false
+	}
+	for key, want := range q.Fields {
+		var got string
+		switch key {
+		case "msg", "message":
+			got = e.Message
+		case "logger":
+			got = e.Logger
+		case "caller":
+			got = e.Caller
+		default:
+			v, ok := e.Fields[key]
+			if !ok {
+				return false
+			}
+			got = fmt.Sprint(v)
+		}
+		if got != want && !globMatch(want, got) {
+			return false
+		}
+	}
+	return true
+}
+
+// globMatch reports whether s matches the glob pattern; an invalid pattern matches nothing.
+func globMatch(pattern, s string) bool {
+	ok, err := path.Match(pattern, s)
+	return err == nil && ok
+}
+
+// filterLogEntries returns the entries selected by q, keeping the last q.Lines.
+func filterLogEntries(entries []core.LogEntry, q LogQuery) []core.LogEntry {
+	var out []core.LogEntry
+	for _, e := range entries {
+		if q.Match(e) {
+			out = append(out, e)
+		}
+	}
+	if q.Lines > 0 && len(out) > q.Lines {
+		out = out[len(out)-q.Lines:]
+	}
+	return out
+}
+
+// WriteLogEntry renders one log entry.
+//
+// Parameters:
+//   - w: Destination.
+//   - e: The entry.
+//   - format: "json" for one JSON object per line; anything else for a console line
+//     (time, level, component, message, fields).
+//
+// Returns:
+//   - error: If writing fails.
+func WriteLogEntry(w io.Writer, e core.LogEntry, format string) error {
+	if format == "json" {
+		return json.NewEncoder(w).Encode(e)
+	}
+	parts := []string{e.Time.Local().Format("2006-01-02T15:04:05.000Z0700"), strings.ToUpper(e.Level)}
+	if e.Component != "" {
+		parts = append(parts, e.Component)
+	}
+	parts = append(parts, e.Message)
+	if len(e.Fields) > 0 {
+		fields, err := json.Marshal(e.Fields)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, string(fields))
+	}
+	_, err := fmt.Fprintln(w, strings.Join(parts, "\t"))
+	return err
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+func TestLogQuery_Match(t *testing.T) {
+	now := time.Now()
+	entry := core.LogEntry{
+		Time: now, Level: "warn", Component: "receiver/otlp", Message: "connection timeout",
+		Fields: map[string]any{"plugin": "otlp", "attempt": int64(3)},
+	}
+	for name, tc := range map[string]struct {
+		q    LogQuery
+		want bool
+	}{
+		"empty":             {LogQuery{}, true},
+		"since":             {LogQuery{Since: now.Add(-time.Minute)}, true},
+		"too old":           {LogQuery{Since: now.Add(time.Minute)}, false},
+		"level below":       {LogQuery{Level: "info"}, true},
+		"level above":       {LogQuery{Level: "error"}, false},
+		"component":         {LogQuery{Components: []string{"receiver/otlp"}}, true},
+		"component kind":    {LogQuery{Components: []string{"plugin", "receiver"}}, true},
+		"component glob":    {LogQuery{Components: []string{"*/otlp"}}, true},
+		"other component":   {LogQuery{Components: []string{"exporter"}}, false},
+		"field":             {LogQuery{Fields: map[string]string{"plugin": "otlp", "attempt": "3"}}, true},
+		"message glob":      {LogQuery{Fields: map[string]string{"msg": "*timeout*"}}, true},
+		"field mismatch":    {LogQuery{Fields: map[string]string{"plugin": "batch"}}, false},
+		"field not present": {LogQuery{Fields: map[string]string{"pipeline": "*"}}, false},
+	} {
+		assert.Equal(t, tc.want, tc.q.Match(entry), name)
+	}
+}
+
+func TestLogQuery_Values(t *testing.T) {
+	q := LogQuery{
+		Since: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), Level: "warn", Components: []string{"receiver"},
+		Fields: map[string]string{"plugin": "otlp"}, Lines: 10, Follow: true,
+	}
+	got, err := parseLogQuery(q.values())
+	require.NoError(t, err)
+	assert.Equal(t, q, got)
+
+	_, err = parseLogQuery(map[string][]string{"level": {"loud"}})
+	assert.ErrorContains(t, err, "invalid level")
+	_, err = parseLogQuery(map[string][]string{"field": {"plugin"}})
+	assert.ErrorContains(t, err, "want key=value")
+}
+
+func TestWriteLogEntry(t *testing.T) {
+	e := core.LogEntry{Time: time.Now(), Level: "info", Component: "plugin", Message: "loaded", Fields: map[string]any{"n": 2}}
+	var out bytes.Buffer
+	require.NoError(t, WriteLogEntry(&out, e, "console"))
+	assert.True(t, strings.HasSuffix(out.String(), "\tINFO\tplugin\tloaded\t{\"n\":2}\n"), out.String())
+
+	out.Reset()
+	require.NoError(t, WriteLogEntry(&out, e, "json"))
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, "loaded", decoded["msg"])
+}
+
+// startLogAdmin runs an admin server serving the log buffer of a teed logger.
+func startLogAdmin(t *testing.T, cfg *core.Config) (*core.Logger, *AdminServer) {
+	t.Helper()
+	logger := core.NewTestLogger(&bytes.Buffer{})
+	logs := core.NewLogBuffer(100)
+	logger.Tee(logs)
+	svc := NewService(logger, nil, nil, nil, nil)
+	require.NoError(t, svc.Start(context.Background()))
+	admin := NewAdminServer(logger, cfg.Service.Socket, svc)
+	admin.SetLogBuffer(logs)
+	require.NoError(t, admin.Start())
+	t.Cleanup(func() { _ = admin.Shutdown(context.Background()) })
+	return logger, admin
+}
+
+// newTailLogsCmd returns a command with the flags of 'tail-logs'.
+func newTailLogsCmd(out *syncBuffer) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("output", "table", "")
+	cmd.Flags().Bool("follow", false, "")
+	cmd.Flags().String("since", "", "")
+	cmd.Flags().String("level", "", "")
+	cmd.Flags().StringSlice("component", nil, "")
+	cmd.Flags().StringArray("field", nil, "")
+	cmd.Flags().Int("lines", 100, "")
+	cmd.SetContext(context.Background())
+	cmd.SetOut(out)
+	return cmd
+}
+
+// syncBuffer is a bytes.Buffer safe for a writer and a concurrent reader.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestCLI_TailLogs(t *testing.T) {
+	cfg := daemonConfig(t)
+	appCtx := &core.AppContext{Config: cfg}
+	out := &syncBuffer{}
+	cmd := newTailLogsCmd(out)
+	assert.ErrorIs(t, CLI_TailLogs(appCtx, cmd, nil), ErrNotRunning)
+
+	logger, _ := startLogAdmin(t, cfg)
+	logger.WithComponent("plugin").Warn("plugin crashed", core.ZapString("plugin", "otlp"))
+	logger.WithComponent("plugin").Warn("plugin crashed", core.ZapString("plugin", "batch"))
+	require.NoError(t, cmd.Flags().Set("level", "warn"))
+	require.NoError(t, cmd.Flags().Set("field", "plugin=otlp"))
+	require.NoError(t, cmd.Flags().Set("since", "1h"))
+	require.NoError(t, CLI_TailLogs(appCtx, cmd, nil))
+	assert.Contains(t, out.String(), "\tWARN\tplugin\tplugin crashed\t{\"plugin\":\"otlp\"}\n")
+	assert.NotContains(t, out.String(), "batch")
+
+	require.NoError(t, cmd.Flags().Set("since", "yesterday"))
+	assert.ErrorContains(t, CLI_TailLogs(appCtx, cmd, nil), "invalid --since")
+	require.NoError(t, cmd.Flags().Set("since", ""))
+	require.NoError(t, cmd.Flags().Set("output", "yaml"))
+	assert.ErrorContains(t, CLI_TailLogs(appCtx, cmd, nil), "unsupported output format")
+}
+
+func TestCLI_TailLogsFollow(t *testing.T) {
+	cfg := daemonConfig(t)
+	logger, admin := startLogAdmin(t, cfg)
+	logger.Info("before")
+	out := &syncBuffer{}
+	cmd := newTailLogsCmd(out)
+	require.NoError(t, cmd.Flags().Set("follow", "true"))
+	require.NoError(t, cmd.Flags().Set("output", "json"))
+
+	done := make(chan error, 1)
+	go func() { done <- CLI_TailLogs(&core.AppContext{Config: cfg}, cmd, nil) }()
+	require.Eventually(t, func() bool { return strings.Contains(out.String(), `"msg":"before"`) }, 5*time.Second, 10*time.Millisecond)
+	logger.Info("after")
+	require.Eventually(t, func() bool { return strings.Contains(out.String(), `"msg":"after"`) }, 5*time.Second, 10*time.Millisecond)
+
+	// Stopping the agent ends the stream instead of blocking its shutdown.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, admin.Shutdown(ctx))
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("tail-logs --follow did not return")
+	}
+}
@@ -2,11 +2,19 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/spf13/cobra"
 	"go.opentelemetry.io/collector/component"
 
 	"github.com/srediag/srediag/internal/core"
+	"github.com/srediag/srediag/internal/diagnose"
+	"github.com/srediag/srediag/internal/plugin"
 )
 
 // Package service provides the core service orchestration, lifecycle management, and CLI stubs for the SREDIAG collector service.
@@ -17,6 +25,14 @@ import (
 //   - Use Service to represent the running SREDIAG collector, including all loaded component factories.
 //   - Use NewService to instantiate a new service with the required component factories.
 //   - Use Start and Stop to manage the service lifecycle.
+//   - Use SetTelemetry before Start so diagnostics run metrics (srediag_diag_*) go to the agent's own telemetry.
+//   - Use SetDiagExporter before Start to ship diagnostic reports as OTLP logs and metrics (ExportReport).
+//   - Use SetJobRunner before Start to run signed remote diagnostics jobs (diagnostics.remote).
+//   - Use SetProfiler before Start to keep recent profiles on disk (service.profiling) and profile memory spikes.
+//   - Use SetCollector before Start to run the telemetry pipelines of collector.config_path in the embedded collector.
+//   - Use Done to learn when the embedded collector fails on its own.
+//   - Use SetAgentInfo and SetPlugins before Start so Status reports version, config digest and plugin states.
+//   - Use SetConfigLoader and SetPluginLoader before Start so Reload re-reads the agent config and plugin binaries.
 //
 // Best Practices:
 //   - Always check for errors from Start and Stop.
@@ -24,8 +40,7 @@ import (
 //   - Pass context.Context for cancellation and timeouts.
 //
 // TODO:
-//   - Implement full component initialization and shutdown in Start/Stop.
-//   - Add health, reload, and other lifecycle hooks as needed.
+//   - Add health and other lifecycle hooks as needed.
 
 // Service represents the SREDIAG collector service and its loaded component factories.
 //
@@ -35,12 +50,60 @@ import (
 //   - processors: Map of processor component factories.
 //   - exporters: Map of exporter component factories.
 //   - extensions: Map of extension component factories.
+//   - connectors: Map of connector component factories.
+//   - telemetry: The agent's own telemetry providers.
+//   - diagExporter: Optional service-scope diag_exporter.
+//   - jobRunner: Optional remote diagnostics job runner.
+//   - profiler: Optional continuous and memory guard profiler.
+//   - gc: Optional periodic garbage collector.
+//   - collectorConfig: Collector pipeline config path; empty disables the embedded collector.
+//   - buildInfo: Build information reported by the embedded collector.
+//   - instances: The running collector instances (see splitCollectorConfig), by key.
+//   - collectorState: State of the embedded collector (Starting, Running, Closing, Closed).
+//   - done, doneOnce: Closed when a collector instance fails on its own or at the end of Stop; runErr holds the error.
+//   - collectorConf: The merged collector config the collector runs.
+//   - agentInfo, agentConfig: Build information and effective config of the agent, for Status.
+//   - plugins, pluginManager: Loaded plugins, for Status, and their manager, which Stop unloads.
+//   - configLoader, pluginLoader, pluginDir, pluginTypes: What Reload re-reads (see reload.go).
+//   - state, startedAt: Lifecycle state (State* constants) and start time.
+//   - reloadMu: Serializes Reload.
 type Service struct {
 	logger     *core.Logger
 	receivers  map[component.Type]component.Factory
 	processors map[component.Type]component.Factory
 	exporters  map[component.Type]component.Factory
 	extensions map[component.Type]component.Factory
+	connectors map[component.Type]component.Factory
+	telemetry  component.TelemetrySettings
+
+	diagExporter *diagnose.DiagExporter
+	jobRunner    *diagnose.JobRunner
+	profiler     *Profiler
+	gc           *GCRunner
+
+	collectorConfig string
+	buildInfo       component.BuildInfo
+	instances       map[string]*collectorInstance
+	collectorState  string
+	done            chan struct{}
+	doneOnce        sync.Once
+	runErr          error
+	collectorConf   map[string]any
+
+	agentInfo     core.BuildInfo
+	agentConfig   *core.Config
+	plugins       pluginStatusSource
+	pluginManager *plugin.PluginManager
+
+	configLoader func() (*core.Config, error)
+	pluginLoader *plugin.Loader
+	pluginDir    string
+	pluginTypes  map[string][]component.Type
+
+	state     string
+	startedAt time.Time
+	mu        sync.Mutex
+	reloadMu  sync.Mutex
 }
 
 // NewService creates a new service instance with the provided component factories.
@@ -67,7 +130,146 @@ func NewService(
 		processors: processors,
 		exporters:  exporters,
 		extensions: extensions,
+		done:       make(chan struct{}),
+	}
+}
+
+// SetConnectors sets the connector component factories of the embedded collector.
+//
+// Parameters:
+//   - connectors: Map of connector component factories.
+func (s *Service) SetConnectors(connectors map[component.Type]component.Factory) {
+	s.connectors = connectors
+}
+
+// SetCollector enables the embedded OpenTelemetry Collector; Start runs the pipelines of configPath (merged with
+// its plugins.d component configs, see LoadCollectorConfig) until Stop, and Reload applies edits of it.
+//
+// Parameters:
+//   - configPath: Collector pipeline config (collector.config_path), or empty to disable the collector.
+//   - info: Build information the collector reports in its own telemetry.
+func (s *Service) SetCollector(configPath string, info component.BuildInfo) {
+	s.collectorConfig = configPath
+	s.buildInfo = info
+}
+
+// SetAgentInfo sets the build information and effective config reported by Status.
+//
+// Parameters:
+//   - info: Build information of the agent binary.
+//   - cfg: Effective agent config; it is part of the config digest.
+func (s *Service) SetAgentInfo(info core.BuildInfo, cfg *core.Config) {
+	s.agentInfo = info
+	s.agentConfig = cfg
+}
+
+// SetPlugins sets the plugin manager whose plugins Status reports and Stop unloads.
+//
+// Parameters:
+//   - plugins: The plugin manager (see plugin.NewManager), or nil.
+func (s *Service) SetPlugins(plugins *plugin.PluginManager) {
+	if plugins != nil {
+		s.plugins = plugins
+		s.pluginManager = plugins
+	}
+}
+
+// setState records a lifecycle state change.
+func (s *Service) setState(state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = state
+	if state == StateRunning {
+		s.startedAt = time.Now()
+	}
+}
+
+// Done returns a channel closed when the embedded collector fails on its own (a fatal component error, or a reload
+// whose rollback failed) and at the end of Stop; Err then reports why.
+//
+// Returns:
+//   - <-chan struct{}: The completion channel.
+func (s *Service) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the error the embedded collector failed with, if any.
+//
+// Returns:
+//   - error: The collector's run error, or nil.
+func (s *Service) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.runErr
+}
+
+// collectorFailed records a collector failure and closes Done.
+func (s *Service) collectorFailed(err error) {
+	s.mu.Lock()
+	if s.runErr == nil {
+		s.runErr = err
+	}
+	s.mu.Unlock()
+	s.doneOnce.Do(func() { close(s.done) })
+}
+
+// SetTelemetry sets the agent's own telemetry providers.
+//
+// Parameters:
+//   - telemetry: Telemetry settings; its MeterProvider receives the srediag_diag_* metrics and the srediag_queue_*
+//     metrics of the diag_exporter queue once the service starts.
+func (s *Service) SetTelemetry(telemetry component.TelemetrySettings) {
+	s.telemetry = telemetry
+}
+
+// SetDiagExporter sets the service-scope diag_exporter; it is started and stopped with the service.
+//
+// Parameters:
+//   - e: The exporter, or nil to disable report export.
+func (s *Service) SetDiagExporter(e *diagnose.DiagExporter) {
+	s.diagExporter = e
+}
+
+// SetJobRunner sets the remote diagnostics job runner; it polls from Start until Stop.
+//
+// Parameters:
+//   - r: The runner (see diagnose.NewRemoteJobRunner), or nil to disable remote jobs.
+func (s *Service) SetJobRunner(r *diagnose.JobRunner) {
+	s.jobRunner = r
+}
+
+// SetProfiler sets the continuous and memory guard profiler; it runs from Start until Stop.
+//
+// Parameters:
+//   - p: The profiler (see NewProfiler), or nil to disable background profiling.
+func (s *Service) SetProfiler(p *Profiler) {
+	s.profiler = p
+}
+
+// SetGCRunner sets the periodic garbage collector; it runs from Start until Stop.
+//
+// Parameters:
+//   - g: The runner (see NewGCRunner), or nil to disable periodic garbage collection.
+func (s *Service) SetGCRunner(g *GCRunner) {
+	s.gc = g
+}
+
+// ExportReport ships a finished diagnostic report through the diag_exporter.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts.
+//   - r: The finished report.
+//
+// Returns:
+//   - error: If the export fails, returns a detailed error; nil when no exporter is configured.
+func (s *Service) ExportReport(ctx context.Context, r *diagnose.Report) error {
+	if s.diagExporter == nil {
+		return nil
 	}
+	if err := s.diagExporter.ConsumeReport(ctx, r); err != nil {
+		return fmt.Errorf("failed to export diagnostic report: %w", err)
+	}
+	return nil
 }
 
 // Start starts the SREDIAG service and all loaded components.
@@ -79,32 +281,143 @@ func NewService(
 //   - error: If startup fails, returns a detailed error.
 //
 // Side Effects:
-//   - Initializes and starts all loaded components (TODO).
+//   - Starts the diag_exporter, the embedded collector's pipelines, the remote job runner, the profiler and the
+//     periodic garbage collector.
 func (s *Service) Start(ctx context.Context) error {
 	s.logger.Info("Starting SREDIAG service",
 		core.ZapInt("receivers", len(s.receivers)),
 		core.ZapInt("processors", len(s.processors)),
 		core.ZapInt("exporters", len(s.exporters)),
-		core.ZapInt("extensions", len(s.extensions)))
+		core.ZapInt("extensions", len(s.extensions)),
+		core.ZapInt("connectors", len(s.connectors)))
+	s.setState(StateStarting)
 
-	// TODO: Initialize and start components
+	if s.telemetry.MeterProvider != nil {
+		if err := diagnose.UseMeterProvider(s.telemetry.MeterProvider); err != nil {
+			return fmt.Errorf("failed to bind diagnostics metrics: %w", err)
+		}
+	}
+	if s.diagExporter != nil {
+		if err := s.diagExporter.Start(ctx, nil); err != nil {
+			return fmt.Errorf("failed to start %s: %w", diagnose.DiagExporterType, err)
+		}
+		if q := s.diagExporter.Queue(); q != nil {
+			if err := q.RegisterMetrics(s.telemetry.MeterProvider, diagnose.DiagExporterType.String()); err != nil {
+				s.logger.Warn("Failed to register queue metrics", core.ZapError(err))
+			}
+		}
+	}
+	if s.collectorConfig != "" {
+		if err := s.startCollector(ctx); err != nil {
+			if s.diagExporter != nil {
+				_ = s.diagExporter.Shutdown(context.WithoutCancel(ctx))
+			}
+			s.setState(StateStopped)
+			return err
+		}
+	}
+	if s.jobRunner != nil {
+		// The runner outlives the start-up context; Stop cancels it.
+		s.jobRunner.Start(context.WithoutCancel(ctx))
+	}
+	if s.profiler != nil {
+		s.profiler.Start(context.WithoutCancel(ctx))
+	}
+	if s.gc != nil {
+		s.gc.Start(context.WithoutCancel(ctx))
+	}
+	s.setState(StateRunning)
+	return nil
+}
+
+// startCollector starts the collector instances of the merged collector config.
+func (s *Service) startCollector(ctx context.Context) error {
+	conf, err := LoadCollectorConfig(s.collectorConfig)
+	if err != nil {
+		return fmt.Errorf("failed to load collector config: %w", err)
+	}
+	s.setCollectorState("Starting")
+	instances := splitCollectorConfig(conf)
+	var started []*collectorInstance
+	for _, inst := range sortedInstances(slices.Collect(maps.Values(instances))) {
+		if err := s.startInstance(ctx, inst); err != nil {
+			for _, prev := range slices.Backward(started) {
+				_ = s.stopInstance(context.WithoutCancel(ctx), prev)
+			}
+			s.setCollectorState("Closed")
+			return fmt.Errorf("failed to start collector: %s: %w", inst.name(), err)
+		}
+		started = append(started, inst)
+	}
+	s.mu.Lock()
+	s.instances, s.collectorConf, s.collectorState = instances, conf, "Running"
+	s.mu.Unlock()
+	s.logger.Info("Collector pipelines running",
+		core.ZapString("config", s.collectorConfig), core.ZapInt("instances", len(instances)))
 	return nil
 }
 
-// Stop stops the SREDIAG service and all loaded components.
+// setCollectorState records the state of the embedded collector.
+func (s *Service) setCollectorState(state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.collectorState = state
+}
+
+// Stop stops the SREDIAG service and all loaded components in order, within the deadline of ctx (see shutdown.go).
 //
 // Parameters:
-//   - ctx: Context for cancellation and timeouts.
+//   - ctx: Context for cancellation and timeouts; its deadline is the shutdown deadline.
 //
 // Returns:
-//   - error: If shutdown fails, returns a detailed error.
+//   - error: If shutdown fails, returns a detailed error; it wraps ErrDataDropped if a pipeline did not drain in
+//     time or the diag_exporter queue could not be synced.
 //
 // Side Effects:
-//   - Stops all loaded components (TODO).
+//   - Stops the garbage collector, the profiler, the remote job runner, the embedded collector's instances (pipelines
+//     first, then extensions), the diag_exporter and the plugin processes, then closes Done. A component that fails
+//     to stop does not keep the later ones from stopping.
 func (s *Service) Stop(ctx context.Context) error {
 	s.logger.Info("Stopping SREDIAG service")
-	// TODO: Stop components
-	return nil
+	s.setState(StateStopping)
+	defer s.setState(StateStopped)
+	started := time.Now()
+	var errs []error
+	if s.gc != nil {
+		if err := s.gc.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop garbage collector: %w", err))
+		}
+	}
+	if s.profiler != nil {
+		if err := s.profiler.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop profiler: %w", err))
+		}
+	}
+	if s.jobRunner != nil {
+		if err := s.jobRunner.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop remote job runner: %w", err))
+		}
+	}
+	if s.collectorConfig != "" {
+		if err := s.stopCollector(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if s.diagExporter != nil {
+		// Undelivered reports stay in the queue for the next start; only a failed sync loses them.
+		if err := s.diagExporter.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%w: failed to stop %s: %w", ErrDataDropped, diagnose.DiagExporterType, err))
+		}
+	}
+	if s.pluginManager != nil {
+		s.pluginManager.UnloadAll(ctx)
+	}
+	s.doneOnce.Do(func() { close(s.done) })
+	err := errors.Join(errs...)
+	if err == nil {
+		s.logger.Info("SREDIAG service stopped", core.ZapString("took", time.Since(started).Round(time.Millisecond).String()))
+	}
+	return err
 }
 
 // CLI stub implementations for all service subcommands
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Package service provides the core service orchestration, lifecycle management, and CLI stubs for the SREDIAG collector service.
+//
+// This file defines the PID/lock file of the running agent. The file holds the agent's PID and stays locked for the
+// agent's lifetime, so a second agent with the same service.pid_file refuses to start and a stale file (from a
+// crashed agent) is detected by the lock being free.
+//
+// Usage:
+//   - Call AcquirePIDFile in 'srediag service start' and Release it on exit.
+//   - Use ReadPIDFile from clients (stop, status, gc) to find the agent's PID.
+//
+// Best Practices:
+//   - Keep the PID file on a local, per-boot filesystem (/run or $XDG_RUNTIME_DIR).
+
+// ErrAlreadyRunning is returned by AcquirePIDFile when another agent holds the PID file.
+var ErrAlreadyRunning = errors.New("service is already running")
+
+// PIDFile is a PID file locked by the current process.
+type PIDFile struct {
+	path string
+	file *os.File
+}
+
+// AcquirePIDFile creates (or takes over a stale) PID file, locks it and writes the current PID.
+//
+// Parameters:
+//   - path: PID file path (service.pid_file).
+//
+// Returns:
+//   - *PIDFile: The locked PID file; call Release on exit.
+//   - error: ErrAlreadyRunning (wrapped with the holder's PID) if another process holds the lock, or a detailed error.
+func AcquirePIDFile(path string) (*PIDFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create PID file directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PID file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		_ = f.Close()
+		if pid, perr := ReadPIDFile(path); perr == nil {
+			return nil, fmt.Errorf("%w (pid %d, %s)", ErrAlreadyRunning, pid, path)
+		}
+		return nil, fmt.Errorf("%w (%s)", ErrAlreadyRunning, path)
+	}
+	if err := f.Truncate(0); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to truncate PID file: %w", err)
+	}
+	if _, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to write PID file: %w", err)
+	}
+	return &PIDFile{path: path, file: f}, nil
+}
+
+// Release removes the PID file and drops its lock.
+//
+// Returns:
+//   - error: If the file cannot be removed, returns a detailed error.
+func (p *PIDFile) Release() error {
+	err := os.Remove(p.path)
+	if cerr := p.file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to release PID file: %w", err)
+	}
+	return nil
+}
+
+// ReadPIDFile returns the PID recorded in a PID file.
+//
+// Parameters:
+//   - path: PID file path.
+//
+// Returns:
+//   - int: The recorded PID.
+//   - error: If the file is missing or malformed, returns a detailed error.
+func ReadPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read PID file: %w", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("malformed PID file %s", path)
+	}
+	return pid, nil
+}
+
+// PIDFileLocked reports whether a live process holds the PID file's lock; a missing file is not locked.
+//
+// Parameters:
+//   - path: PID file path.
+//
+// Returns:
+//   - bool: True while the agent that wrote the file is running.
+func PIDFileLocked(path string) bool {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+	if err := lockFile(f); err != nil {
+		return true
+	}
+	unlockFile(f)
+	return false
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package service
+
+import "os"
+
+// lockFile is a no-op without flock; a PID file then only records the PID.
+func lockFile(*os.File) error { return nil }
+
+// unlockFile is a no-op without flock.
+func unlockFile(*os.File) {}
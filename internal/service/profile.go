@@ -0,0 +1,403 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package service provides the core service orchestration, lifecycle management, and CLI stubs for the SREDIAG collector service.
+//
+// This file defines runtime profiling of the agent: WriteProfile captures a CPU, heap, allocs, goroutine, mutex,
+// block or execution trace profile of the current process, served by the admin API (GET /v1/profile/{type}) and
+// fetched by 'srediag service profile'. Profiler keeps a ring buffer of recent profiles on disk
+// (service.profiling.continuous) and captures a heap profile when the agent's RSS crosses
+// security.runtime.mem_guard_mib.
+//
+// Usage:
+//   - Call NewProfiler with the agent config and pass it to Service.SetProfiler; it runs from Start until Stop.
+//   - Open the profiles with 'go tool pprof' and execution traces with 'go tool trace'.
+//
+// Best Practices:
+//   - Keep continuous CPU profiles short relative to the interval; profiling costs a few percent of CPU while on.
+
+// ProfileTypes lists the profiles WriteProfile can capture.
+var ProfileTypes = []string{"cpu", "heap", "allocs", "goroutine", "mutex", "block", "trace"}
+
+const (
+	// DefaultProfileDuration is how long CPU, mutex, block and trace profiles are captured by default.
+	DefaultProfileDuration = 30 * time.Second
+	// MaxProfileDuration bounds a profile requested over the admin API.
+	MaxProfileDuration = 10 * time.Minute
+
+	defaultProfileInterval    = 5 * time.Minute
+	defaultProfileCPUDuration = 10 * time.Second
+	defaultProfileKeep        = 12
+	// memGuardCheckInterval is how often the Profiler compares the RSS with the memory guard.
+	memGuardCheckInterval = 10 * time.Second
+	// memGuardRearmPct is the share of the memory guard the RSS must fall below before another heap profile is taken.
+	memGuardRearmPct = 90
+)
+
+// ErrProfileBusy is returned when a CPU profile, execution trace, or mutex or block profile is already being
+// captured; the Go runtime supports only one at a time.
+var ErrProfileBusy = errors.New("a profile of this type is already being captured")
+
+// contentionProfileMu serializes mutex and block profiles, which change process-wide sampling rates.
+var contentionProfileMu sync.Mutex
+
+// timedProfile reports whether a profile type is captured over a duration rather than as a snapshot.
+func timedProfile(kind string) bool {
+	return kind == "cpu" || kind == "trace" || kind == "mutex" || kind == "block"
+}
+
+// ProfileExt returns the file extension for a profile type: .trace for execution traces, .pb.gz otherwise.
+func ProfileExt(kind string) string {
+	if kind == "trace" {
+		return ".trace"
+	}
+	return ".pb.gz"
+}
+
+// WriteProfile captures a profile of the current process and writes it to w in pprof (gzipped protobuf) or
+// execution trace format.
+//
+// Parameters:
+//   - ctx: Cancels a timed profile early; what was captured so far is written.
+//   - w: Destination of the profile.
+//   - kind: One of ProfileTypes.
+//   - d: Capture duration of cpu, trace, mutex and block profiles (DefaultProfileDuration if 0); snapshots
+//     ignore it.
+//
+// Returns:
+//   - error: If kind is unknown, ErrProfileBusy, or a detailed error.
+func WriteProfile(ctx context.Context, w io.Writer, kind string, d time.Duration) error {
+	if !slices.Contains(ProfileTypes, kind) {
+		return fmt.Errorf("unknown profile type %q (want one of %s)", kind, strings.Join(ProfileTypes, ", "))
+	}
+	if d <= 0 {
+		d = DefaultProfileDuration
+	}
+	wait := func() {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+	}
+	switch kind {
+	case "cpu":
+		if err := pprof.StartCPUProfile(w); err != nil {
+			return fmt.Errorf("%w: %v", ErrProfileBusy, err)
+		}
+		wait()
+		pprof.StopCPUProfile()
+		return nil
+	case "trace":
+		if err := trace.Start(w); err != nil {
+			return fmt.Errorf("%w: %v", ErrProfileBusy, err)
+		}
+		wait()
+		trace.Stop()
+		return nil
+	case "mutex", "block":
+		if !contentionProfileMu.TryLock() {
+			return ErrProfileBusy
+		}
+		defer contentionProfileMu.Unlock()
+		if kind == "mutex" {
+			prev := runtime.SetMutexProfileFraction(5)
+			defer runtime.SetMutexProfileFraction(prev)
+		} else {
+			runtime.SetBlockProfileRate(int(time.Millisecond))
+			defer runtime.SetBlockProfileRate(0)
+		}
+		wait()
+	}
+	if err := pprof.Lookup(kind).WriteTo(w, 0); err != nil {
+		return fmt.Errorf("failed to write %s profile: %w", kind, err)
+	}
+	return nil
+}
+
+// processRSS returns the resident set size of the agent; patchable for tests. Outside Linux it falls back to the
+// memory obtained from the OS by the Go runtime.
+var processRSS = func() (uint64, error) {
+	if data, err := os.ReadFile("/proc/self/statm"); err == nil {
+		if fields := strings.Fields(string(data)); len(fields) > 1 {
+			pages, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("failed to parse /proc/self/statm: %w", err)
+			}
+			return pages * uint64(os.Getpagesize()), nil
+		}
+	}
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.Sys, nil
+}
+
+// ProfilerOptions configures a Profiler.
+//
+// Fields:
+//   - Dir: Directory of the ring buffer.
+//   - Continuous: Whether to capture CPU, heap and goroutine profiles every Interval.
+//   - Interval, CPUDuration: Time between continuous captures and length of each CPU profile.
+//   - Keep: Profiles kept per type (and per memory guard heap profiles); older ones are deleted.
+//   - MemGuardMiB: RSS that triggers a heap profile; 0 disables the trigger.
+type ProfilerOptions struct {
+	Dir         string
+	Continuous  bool
+	Interval    time.Duration
+	CPUDuration time.Duration
+	Keep        int
+	MemGuardMiB int
+}
+
+// Profiler captures profiles of the agent into a ring buffer on disk.
+type Profiler struct {
+	logger *core.Logger
+	opts   ProfilerOptions
+	now    func() time.Time
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewProfiler creates the profiler of the agent config (service.profiling and security.runtime.mem_guard_mib).
+//
+// Parameters:
+//   - logger: Logger for captures and errors.
+//   - cfg: Agent configuration.
+//
+// Returns:
+//   - *Profiler: The profiler, or nil when continuous profiling is off and no memory guard is set.
+//   - error: If a duration in service.profiling is invalid.
+func NewProfiler(logger *core.Logger, cfg *core.Config) (*Profiler, error) {
+	pc := cfg.Service.Profiling
+	opts := ProfilerOptions{
+		Dir:         pc.Dir,
+		Continuous:  pc.Continuous,
+		Interval:    defaultProfileInterval,
+		CPUDuration: defaultProfileCPUDuration,
+		Keep:        pc.Keep,
+		MemGuardMiB: cfg.Security.Runtime.MemGuardMiB,
+	}
+	if !opts.Continuous && opts.MemGuardMiB <= 0 {
+		return nil, nil
+	}
+	durations := []struct {
+		value, key string
+		dst        *time.Duration
+	}{
+		{pc.Interval, "interval", &opts.Interval},
+		{pc.CPUDuration, "cpu_duration", &opts.CPUDuration},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("invalid service.profiling.%s %q", d.key, d.value)
+		}
+		*d.dst = v
+	}
+	if opts.CPUDuration >= opts.Interval {
+		return nil, fmt.Errorf("service.profiling.cpu_duration (%s) must be shorter than the interval (%s)", opts.CPUDuration, opts.Interval)
+	}
+	if opts.Dir == "" {
+		opts.Dir = filepath.Join(core.DefaultStateDir(), "profiles")
+	}
+	if opts.Keep <= 0 {
+		opts.Keep = defaultProfileKeep
+	}
+	return &Profiler{logger: logger, opts: opts, now: time.Now}, nil
+}
+
+// Start runs continuous captures and the memory guard in the background until Stop.
+//
+// Parameters:
+//   - ctx: Context of the background loop.
+func (p *Profiler) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	p.mu.Lock()
+	p.cancel, p.done = cancel, make(chan struct{})
+	p.mu.Unlock()
+	p.logger.Info("Profiler started",
+		core.ZapString("dir", p.opts.Dir),
+		core.ZapBool("continuous", p.opts.Continuous),
+		core.ZapInt("mem_guard_mib", p.opts.MemGuardMiB))
+
+	var wg sync.WaitGroup
+	if p.opts.Continuous {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.runContinuous(ctx)
+		}()
+	}
+	if p.opts.MemGuardMiB > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.runMemGuard(ctx)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(p.done)
+	}()
+}
+
+// Stop cancels the background loops (and a running capture) and waits for them to exit.
+//
+// Parameters:
+//   - ctx: Context bounding the wait.
+//
+// Returns:
+//   - error: ctx.Err() if the loops did not exit in time.
+func (p *Profiler) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	cancel, done := p.cancel, p.done
+	p.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runContinuous captures a CPU, heap and goroutine profile every interval.
+func (p *Profiler) runContinuous(ctx context.Context) {
+	ticker := time.NewTicker(p.opts.Interval)
+	defer ticker.Stop()
+	for {
+		for _, kind := range []string{"cpu", "heap", "goroutine"} {
+			if _, err := p.Capture(ctx, kind, ""); err != nil && ctx.Err() == nil {
+				p.logger.Warn("Continuous profile failed", core.ZapString("type", kind), core.ZapError(err))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runMemGuard captures a heap profile each time the RSS crosses the memory guard; the trigger re-arms once the
+// RSS falls below memGuardRearmPct of the guard.
+func (p *Profiler) runMemGuard(ctx context.Context) {
+	ticker := time.NewTicker(memGuardCheckInterval)
+	defer ticker.Stop()
+	guard := uint64(p.opts.MemGuardMiB) << 20
+	armed := true
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		armed = p.checkMemGuard(ctx, guard, armed)
+	}
+}
+
+// checkMemGuard compares the RSS with guard once and returns whether the trigger is armed afterwards.
+func (p *Profiler) checkMemGuard(ctx context.Context, guard uint64, armed bool) bool {
+	rss, err := processRSS()
+	if err != nil {
+		p.logger.Warn("Failed to read RSS", core.ZapError(err))
+		return armed
+	}
+	switch {
+	case armed && rss >= guard:
+		path, err := p.Capture(ctx, "heap", "memguard")
+		if err != nil {
+			p.logger.Warn("Memory guard heap profile failed", core.ZapError(err))
+			return armed
+		}
+		p.logger.Warn("RSS crossed the memory guard, captured a heap profile",
+			core.ZapInt("rss_mib", int(rss>>20)), core.ZapInt("mem_guard_mib", p.opts.MemGuardMiB),
+			core.ZapString("profile", path))
+		return false
+	case !armed && rss < guard/100*memGuardRearmPct:
+		return true
+	}
+	return armed
+}
+
+// Capture writes one profile into the ring buffer as <type>[-<reason>]-<UTC timestamp><ext> and deletes the
+// oldest profiles of the same type and reason beyond Keep.
+//
+// Parameters:
+//   - ctx: Cancels a timed capture early.
+//   - kind: One of ProfileTypes; CPU profiles last CPUDuration.
+//   - reason: Optional tag, e.g. "memguard"; profiles with different tags are rotated separately.
+//
+// Returns:
+//   - string: Path of the written profile.
+//   - error: If the profile cannot be captured or written.
+func (p *Profiler) Capture(ctx context.Context, kind, reason string) (string, error) {
+	var buf bytes.Buffer
+	if err := WriteProfile(ctx, &buf, kind, p.opts.CPUDuration); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(p.opts.Dir, 0o750); err != nil {
+		return "", fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	prefix := kind + "-"
+	if reason != "" {
+		prefix += reason + "-"
+	}
+	path := filepath.Join(p.opts.Dir, prefix+p.now().UTC().Format("20060102T150405.000Z")+ProfileExt(kind))
+	if err := os.WriteFile(path, buf.Bytes(), 0o640); err != nil {
+		return "", fmt.Errorf("failed to write profile: %w", err)
+	}
+	p.prune(prefix, ProfileExt(kind))
+	return path, nil
+}
+
+// prune deletes the oldest profiles named <prefix><timestamp><ext> beyond Keep; the timestamps sort by name.
+func (p *Profiler) prune(prefix, ext string) {
+	entries, err := os.ReadDir(p.opts.Dir)
+	if err != nil {
+		return
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		// "heap-" also prefixes "heap-memguard-"; a timestamp always starts with a digit.
+		if rest, ok := strings.CutPrefix(name, prefix); ok && strings.HasSuffix(name, ext) && rest != "" && rest[0] >= '0' && rest[0] <= '9' {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for len(names) > p.opts.Keep {
+		if err := os.Remove(filepath.Join(p.opts.Dir, names[0])); err != nil {
+			p.logger.Warn("Failed to delete old profile", core.ZapString("profile", names[0]), core.ZapError(err))
+		}
+		names = names[1:]
+	}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// gzipMagic starts every pprof profile.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+func TestWriteProfile(t *testing.T) {
+	for _, kind := range []string{"heap", "goroutine", "cpu", "mutex", "trace"} {
+		t.Run(kind, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, WriteProfile(context.Background(), &buf, kind, 50*time.Millisecond))
+			require.NotZero(t, buf.Len())
+			if kind != "trace" {
+				assert.Equal(t, gzipMagic, buf.Bytes()[:2])
+			}
+		})
+	}
+
+	assert.ErrorContains(t, WriteProfile(context.Background(), io.Discard, "threads", 0), "unknown profile type")
+
+	require.NoError(t, pprof.StartCPUProfile(io.Discard))
+	err := WriteProfile(context.Background(), io.Discard, "cpu", time.Millisecond)
+	pprof.StopCPUProfile()
+	assert.ErrorIs(t, err, ErrProfileBusy)
+}
+
+func TestNewProfiler(t *testing.T) {
+	logger := core.NewTestLogger(&bytes.Buffer{})
+	cfg := core.NewConfig()
+	p, err := NewProfiler(logger, cfg)
+	require.NoError(t, err)
+	assert.Nil(t, p, "nothing to do without continuous profiling or a memory guard")
+
+	cfg.Security.Runtime.MemGuardMiB = 256
+	p, err = NewProfiler(logger, cfg)
+	require.NoError(t, err)
+	require.NotNil(t, p)
+	assert.Equal(t, filepath.Join(core.DefaultStateDir(), "profiles"), p.opts.Dir)
+	assert.Equal(t, defaultProfileKeep, p.opts.Keep)
+
+	cfg.Service.Profiling.Interval = "soon"
+	_, err = NewProfiler(logger, cfg)
+	assert.ErrorContains(t, err, "service.profiling.interval")
+	cfg.Service.Profiling.Interval, cfg.Service.Profiling.CPUDuration = "10s", "1m"
+	_, err = NewProfiler(logger, cfg)
+	assert.ErrorContains(t, err, "must be shorter than the interval")
+}
+
+// newTestProfiler returns a profiler writing into a temp dir with a clock that advances one second per capture.
+func newTestProfiler(t *testing.T, keep int) *Profiler {
+	t.Helper()
+	cfg := core.NewConfig()
+	cfg.Service.Profiling.Continuous = true
+	cfg.Service.Profiling.Dir = t.TempDir()
+	cfg.Service.Profiling.Keep = keep
+	cfg.Service.Profiling.CPUDuration = "50ms"
+	cfg.Security.Runtime.MemGuardMiB = 100
+	p, err := NewProfiler(core.NewTestLogger(&bytes.Buffer{}), cfg)
+	require.NoError(t, err)
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	p.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	return p
+}
+
+func TestProfiler_CaptureRotates(t *testing.T) {
+	p := newTestProfiler(t, 2)
+	for range 3 {
+		_, err := p.Capture(context.Background(), "heap", "")
+		require.NoError(t, err)
+	}
+	guard, err := p.Capture(context.Background(), "heap", "memguard")
+	require.NoError(t, err)
+	assert.Equal(t, "heap-memguard-20260102T030409.000Z.pb.gz", filepath.Base(guard))
+
+	entries, err := os.ReadDir(p.opts.Dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{
+		"heap-20260102T030407.000Z.pb.gz",
+		"heap-20260102T030408.000Z.pb.gz",
+		"heap-memguard-20260102T030409.000Z.pb.gz",
+	}, names, "the oldest heap profile was rotated out; memory guard profiles rotate separately")
+}
+
+func TestProfiler_MemGuard(t *testing.T) {
+	p := newTestProfiler(t, 5)
+	rss := uint64(50 << 20)
+	old := processRSS
+	t.Cleanup(func() { processRSS = old })
+	processRSS = func() (uint64, error) { return rss, nil }
+	guard := uint64(p.opts.MemGuardMiB) << 20
+	count := func() int {
+		matches, _ := filepath.Glob(filepath.Join(p.opts.Dir, "heap-memguard-*.pb.gz"))
+		return len(matches)
+	}
+
+	armed := p.checkMemGuard(context.Background(), guard, true)
+	assert.True(t, armed)
+	assert.Zero(t, count())
+
+	rss = 120 << 20
+	armed = p.checkMemGuard(context.Background(), guard, armed)
+	assert.False(t, armed)
+	assert.Equal(t, 1, count())
+	armed = p.checkMemGuard(context.Background(), guard, armed)
+	assert.Equal(t, 1, count(), "no new profile while RSS stays above the guard")
+
+	rss = 95 << 20
+	armed = p.checkMemGuard(context.Background(), guard, armed)
+	assert.False(t, armed, "re-armed only below 90% of the guard")
+	rss = 80 << 20
+	assert.True(t, p.checkMemGuard(context.Background(), guard, armed))
+}
+
+func TestProfiler_StartStop(t *testing.T) {
+	p := newTestProfiler(t, 5)
+	p.Start(context.Background())
+	require.Eventually(t, func() bool {
+		matches, _ := filepath.Glob(filepath.Join(p.opts.Dir, "goroutine-*.pb.gz"))
+		return len(matches) == 1
+	}, 5*time.Second, 20*time.Millisecond, "a continuous capture runs at start")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, p.Stop(ctx))
+	assert.FileExists(t, filepath.Join(p.opts.Dir, "cpu-20260102T030406.000Z.pb.gz"))
+}
+
+func TestCLI_Profile(t *testing.T) {
+	cfg := daemonConfig(t)
+	appCtx := &core.AppContext{Config: cfg}
+	out := filepath.Join(t.TempDir(), "heap.pb.gz")
+	cmd := &cobra.Command{}
+	cmd.Flags().String("type", "heap", "")
+	cmd.Flags().Duration("duration", 0, "")
+	cmd.Flags().String("output-file", out, "")
+	cmd.SetContext(context.Background())
+	var stdout bytes.Buffer
+	cmd.SetOut(&stdout)
+	assert.ErrorIs(t, CLI_Profile(appCtx, cmd, nil), ErrNotRunning)
+
+	startAdmin(t, cfg)
+	require.NoError(t, CLI_Profile(appCtx, cmd, nil))
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, gzipMagic, data[:2])
+	assert.Contains(t, stdout.String(), "Wrote heap profile")
+
+	require.NoError(t, cmd.Flags().Set("type", "cpu"))
+	require.NoError(t, cmd.Flags().Set("duration", "1s"))
+	require.NoError(t, pprof.StartCPUProfile(io.Discard))
+	err = CLI_Profile(appCtx, cmd, nil)
+	pprof.StopCPUProfile()
+	assert.ErrorIs(t, err, ErrProfileBusy)
+
+	require.NoError(t, cmd.Flags().Set("type", "threads"))
+	assert.ErrorContains(t, CLI_Profile(appCtx, cmd, nil), "unknown profile type")
+}
@@ -0,0 +1,509 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"gopkg.in/yaml.v3"
+
+	"github.com/srediag/srediag/internal/core"
+	"github.com/srediag/srediag/internal/diagnose"
+	"github.com/srediag/srediag/internal/plugin"
+)
+
+// Package service provides the core service orchestration, lifecycle management, and CLI stubs for the SREDIAG collector service.
+//
+// This file defines the live reload of a running agent. Reload re-reads the agent config and the collector config,
+// starts changed plugin binaries next to the running ones, and validates every collector instance that has to
+// change before touching any of them. It then restarts only the changed instances (see instances.go); the others
+// keep running. If a changed instance fails to start, the instances already replaced are rolled back to the last
+// known-good config. Plugin changes are committed only once the collector runs the new config.
+//
+// Usage:
+//   - Call Service.Reload on SIGHUP, on 'POST /v1/reload' or when the config watcher fires; it logs its outcome.
+//   - Use WriteReloadReport to print the ReloadReport.
+//
+// Best Practices:
+//   - Treat a ReloadError with Invalid set as a user error: nothing was changed.
+//   - Settings outside the collector config are re-read but only take effect on restart (RestartRequired).
+
+// ReloadReport describes what a reload changed.
+//
+// Fields:
+//   - Time: When the reload ran.
+//   - Added, Removed, Changed: Components ("receivers/otlp"), pipelines ("pipelines/traces"), service settings
+//     ("service/telemetry"), plugins ("plugins/<name>") and agent config sections ("config/<section>").
+//   - Restarted: Collector instances that were (re)started, by pipeline group ("logs,metrics") or "extensions".
+//   - Unchanged: Collector instances that kept running.
+//   - RestartRequired: Changed agent config sections that only take effect when the agent restarts.
+//   - RolledBack: Whether a failed start was rolled back to the previous config.
+//   - ConfigDigest: The config digest after the reload (see StatusReport).
+//   - Error: Why the reload failed, empty on success.
+type ReloadReport struct {
+	Time            time.Time `json:"time" yaml:"time"`
+	Added           []string  `json:"added,omitempty" yaml:"added,omitempty"`
+	Removed         []string  `json:"removed,omitempty" yaml:"removed,omitempty"`
+	Changed         []string  `json:"changed,omitempty" yaml:"changed,omitempty"`
+	Restarted       []string  `json:"restarted,omitempty" yaml:"restarted,omitempty"`
+	Unchanged       []string  `json:"unchanged,omitempty" yaml:"unchanged,omitempty"`
+	RestartRequired []string  `json:"restart_required,omitempty" yaml:"restart_required,omitempty"`
+	RolledBack      bool      `json:"rolled_back,omitempty" yaml:"rolled_back,omitempty"`
+	ConfigDigest    string    `json:"config_digest,omitempty" yaml:"config_digest,omitempty"`
+	Error           string    `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ReloadError is returned by a failed reload.
+//
+// Fields:
+//   - Invalid: The new config failed to load or validate; nothing was changed.
+//   - Err: The cause.
+type ReloadError struct {
+	Invalid bool
+	Err     error
+}
+
+// Error implements error.
+func (e *ReloadError) Error() string {
+	if e.Invalid {
+		return "invalid configuration: " + e.Err.Error()
+	}
+	return "reload failed: " + e.Err.Error()
+}
+
+// Unwrap returns the cause.
+func (e *ReloadError) Unwrap() error { return e.Err }
+
+// SrediagExitCode maps an invalid config to diagnose.ExitVerification (2), any other failure to diagnose.ExitError.
+func (e *ReloadError) SrediagExitCode() int {
+	if e.Invalid {
+		return diagnose.ExitVerification
+	}
+	return diagnose.ExitError
+}
+
+// SetConfigLoader sets how Reload re-reads the agent config; without one, Reload keeps the config given to
+// SetAgentInfo and only re-reads the collector config.
+//
+// Parameters:
+//   - load: Loads and validates the agent config.
+func (s *Service) SetConfigLoader(load func() (*core.Config, error)) {
+	s.configLoader = load
+}
+
+// SetPluginLoader adds the component factories of the loaded plugins and lets Reload swap plugin binaries. A plugin
+// factory whose type is already built in is ignored.
+//
+// Parameters:
+//   - loader: The loader that loaded the plugins (see plugin.Loader.LoadPlugins).
+//   - dir: The plugin directory (plugins.exec_dir).
+func (s *Service) SetPluginLoader(loader *plugin.Loader, dir string) {
+	s.pluginLoader, s.pluginDir = loader, dir
+	s.usePluginFactories(loader.GetFactories())
+}
+
+// usePluginFactories replaces the plugin factories of the service and returns the component types that were
+// added or removed ("<kind>/<type>").
+func (s *Service) usePluginFactories(receivers, processors, exporters, extensions map[component.Type]component.Factory) map[string]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pluginTypes == nil {
+		s.pluginTypes = make(map[string][]component.Type)
+	}
+	changed := make(map[string]bool)
+	for kind, target := range map[string]*map[component.Type]component.Factory{
+		"receiver": &s.receivers, "processor": &s.processors, "exporter": &s.exporters, "extension": &s.extensions,
+	} {
+		loaded := map[string]map[component.Type]component.Factory{
+			"receiver": receivers, "processor": processors, "exporter": exporters, "extension": extensions,
+		}[kind]
+		factories := maps.Clone(*target)
+		if factories == nil {
+			factories = make(map[component.Type]component.Factory)
+		}
+		for _, typ := range s.pluginTypes[kind] {
+			delete(factories, typ)
+			changed[kind+"/"+typ.String()] = true
+		}
+		s.pluginTypes[kind] = nil
+		for typ, f := range loaded {
+			if _, dup := factories[typ]; dup {
+				s.logger.Warn("Plugin factory shadowed by a built-in component",
+					core.ZapString("kind", kind), core.ZapString("type", typ.String()))
+				continue
+			}
+			factories[typ] = f
+			s.pluginTypes[kind] = append(s.pluginTypes[kind], typ)
+			changed[kind+"/"+typ.String()] = true
+		}
+		*target = factories
+	}
+	return changed
+}
+
+// pluginFactories is a snapshot of the component factories of the service and of the types served by plugins.
+type pluginFactories struct {
+	receivers, processors, exporters, extensions map[component.Type]component.Factory
+	pluginTypes                                  map[string][]component.Type
+}
+
+// snapshotFactories returns the current factories. usePluginFactories replaces the factory maps rather than
+// changing them, so the snapshot stays valid.
+func (s *Service) snapshotFactories() pluginFactories {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return pluginFactories{
+		receivers: s.receivers, processors: s.processors, exporters: s.exporters, extensions: s.extensions,
+		pluginTypes: maps.Clone(s.pluginTypes),
+	}
+}
+
+// restoreFactories puts back factories taken by snapshotFactories.
+func (s *Service) restoreFactories(f pluginFactories) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.receivers, s.processors, s.exporters, s.extensions = f.receivers, f.processors, f.exporters, f.extensions
+	s.pluginTypes = f.pluginTypes
+}
+
+// Reload re-reads the configuration and applies what changed while the agent keeps running.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts.
+//
+// Returns:
+//   - *ReloadReport: What changed, also on failure.
+//   - error: A *ReloadError if the new config is invalid or fails to start (the previous config then keeps
+//     running), or an error if the service is not running.
+//
+// Side Effects:
+//   - Restarts changed collector instances, swaps plugins and logs the outcome.
+func (s *Service) Reload(ctx context.Context) (*ReloadReport, error) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	s.mu.Lock()
+	state, oldCfg, oldConf := s.state, s.agentConfig, s.collectorConf
+	s.mu.Unlock()
+	if state != StateRunning {
+		return nil, fmt.Errorf("cannot reload: service is %s", state)
+	}
+	s.logger.Info("Reloading configuration")
+
+	report := &ReloadReport{Time: time.Now()}
+	err := s.reload(ctx, report, oldCfg, oldConf)
+	s.mu.Lock()
+	report.ConfigDigest = configDigest(s.agentConfig, s.collectorConf)
+	s.mu.Unlock()
+	if err != nil {
+		report.Error = err.Error()
+		var reloadErr *ReloadError
+		if errors.As(err, &reloadErr) {
+			// The client rebuilds the ReloadError from the report; keep only the cause.
+			report.Error = reloadErr.Err.Error()
+		}
+		s.logger.Error("Reload failed", core.ZapError(err), core.ZapReflect("rolled_back", report.RolledBack))
+		return report, err
+	}
+	s.logger.Info("Configuration reloaded",
+		core.ZapReflect("added", report.Added),
+		core.ZapReflect("removed", report.Removed),
+		core.ZapReflect("changed", report.Changed),
+		core.ZapReflect("restarted", report.Restarted))
+	if len(report.RestartRequired) > 0 {
+		s.logger.Warn("Some settings only take effect after a restart", core.ZapReflect("sections", report.RestartRequired))
+	}
+	return report, nil
+}
+
+// reload runs the steps of Reload and fills report.
+func (s *Service) reload(ctx context.Context, report *ReloadReport, oldCfg *core.Config, oldConf map[string]any) error {
+	cfg := oldCfg
+	if s.configLoader != nil {
+		loaded, err := s.configLoader()
+		if err != nil {
+			return &ReloadError{Invalid: true, Err: fmt.Errorf("failed to load agent config: %w", err)}
+		}
+		cfg = loaded
+	}
+	diffAgentConfig(report, oldCfg, cfg)
+
+	path := s.collectorConfig
+	if path != "" && cfg != nil && cfg.Collector.Enabled && cfg.Collector.ConfigPath != "" {
+		path = cfg.Collector.ConfigPath
+	}
+	var conf map[string]any
+	if path != "" {
+		loaded, err := LoadCollectorConfig(path)
+		if err != nil {
+			return &ReloadError{Invalid: true, Err: fmt.Errorf("failed to load collector config: %w", err)}
+		}
+		conf = loaded
+	}
+
+	// New and replaced plugins run next to the loaded ones, which keep serving the running instances until the new
+	// collector config is up; on failure the new processes are discarded and the previous factories restored.
+	swapped, undo := map[string]bool{}, func() {}
+	var pending *plugin.PendingSync
+	if s.pluginLoader != nil {
+		var err error
+		pending, err = s.pluginLoader.Prepare(ctx, s.pluginDir)
+		if err != nil {
+			return &ReloadError{Err: fmt.Errorf("failed to sync plugins: %w", err)}
+		}
+		defer pending.Discard(context.WithoutCancel(ctx))
+		report.Added = append(report.Added, prefixed("plugins/", pending.Loaded)...)
+		report.Removed = append(report.Removed, prefixed("plugins/", pending.Unloaded)...)
+		report.Changed = append(report.Changed, prefixed("plugins/", pending.Swapped)...)
+		if pending.Changed() {
+			previous := s.snapshotFactories()
+			swapped = s.usePluginFactories(pending.Factories())
+			undo = func() { s.restoreFactories(previous) }
+		}
+	}
+
+	if conf != nil {
+		diffCollectorConfig(report, oldConf, conf)
+		if err := s.applyCollector(ctx, report, conf, swapped, undo); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		s.collectorConfig = path
+		s.mu.Unlock()
+	}
+	if pending != nil {
+		pending.Commit(ctx)
+	}
+	s.mu.Lock()
+	s.agentConfig = cfg
+	s.mu.Unlock()
+	return nil
+}
+
+// applyCollector moves the collector to conf: unchanged instances keep running, the others are validated, then
+// stopped and started. Instances using a swapped plugin type restart even if their config is unchanged. On failure
+// undo restores the previous factories before the previous instances are restarted.
+func (s *Service) applyCollector(ctx context.Context, report *ReloadReport, conf map[string]any, swapped map[string]bool, undo func()) error {
+	next := splitCollectorConfig(conf)
+	s.mu.Lock()
+	current := maps.Clone(s.instances)
+	s.mu.Unlock()
+
+	var stale, fresh []*collectorInstance
+	for key, inst := range next {
+		old, ok := current[key]
+		switch {
+		case !ok:
+			fresh = append(fresh, inst)
+		case old.digest != inst.digest || old.uses(swapped):
+			stale, fresh = append(stale, old), append(fresh, inst)
+		default:
+			next[key] = old
+			report.Unchanged = append(report.Unchanged, old.name())
+		}
+	}
+	for key, old := range current {
+		if _, ok := next[key]; !ok {
+			stale = append(stale, old)
+		}
+	}
+	stale, fresh = sortedInstances(stale), sortedInstances(fresh)
+	slices.Sort(report.Unchanged)
+
+	for _, inst := range fresh {
+		if err := s.validateInstance(ctx, inst); err != nil {
+			undo()
+			return &ReloadError{Invalid: true, Err: fmt.Errorf("collector instance %s: %w", inst.name(), err)}
+		}
+	}
+
+	for _, old := range slices.Backward(stale) {
+		if err := s.stopInstance(ctx, old); err != nil {
+			s.logger.Warn("Failed to stop collector instance", core.ZapString("instance", old.name()), core.ZapError(err))
+		}
+	}
+	var started []*collectorInstance
+	for _, inst := range fresh {
+		if err := s.startInstance(ctx, inst); err != nil {
+			report.RolledBack = true
+			return s.rollback(ctx, started, stale, undo, fmt.Errorf("collector instance %s: %w", inst.name(), err))
+		}
+		started = append(started, inst)
+		report.Restarted = append(report.Restarted, inst.name())
+	}
+
+	s.mu.Lock()
+	s.instances, s.collectorConf = next, conf
+	s.mu.Unlock()
+	return nil
+}
+
+// rollback stops the instances started by a failed reload, restores the previous factories with undo and restarts
+// the instances it stopped. If the previous config cannot be restored either, the collector is failed, which stops
+// the agent (see Done).
+func (s *Service) rollback(ctx context.Context, started, stopped []*collectorInstance, undo func(), cause error) *ReloadError {
+	s.logger.Warn("Rolling back to the last known-good collector config", core.ZapError(cause))
+	ctx = context.WithoutCancel(ctx)
+	for _, inst := range slices.Backward(started) {
+		if err := s.stopInstance(ctx, inst); err != nil {
+			s.logger.Warn("Failed to stop collector instance", core.ZapString("instance", inst.name()), core.ZapError(err))
+		}
+	}
+	undo()
+	for _, old := range stopped {
+		if err := s.startInstance(ctx, old); err != nil {
+			err = fmt.Errorf("rollback failed: collector instance %s: %w", old.name(), err)
+			s.collectorFailed(err)
+			return &ReloadError{Err: errors.Join(cause, err)}
+		}
+	}
+	return &ReloadError{Err: cause}
+}
+
+// diffCollectorConfig adds the differences between two merged collector configs to report.
+func diffCollectorConfig(report *ReloadReport, oldConf, newConf map[string]any) {
+	for _, section := range collectorSections {
+		oldSection, _ := oldConf[section].(map[string]any)
+		newSection, _ := newConf[section].(map[string]any)
+		diffMaps(report, section+"/", oldSection, newSection)
+	}
+	oldService, _ := oldConf["service"].(map[string]any)
+	newService, _ := newConf["service"].(map[string]any)
+	oldPipelines, _ := oldService["pipelines"].(map[string]any)
+	newPipelines, _ := newService["pipelines"].(map[string]any)
+	diffMaps(report, "pipelines/", oldPipelines, newPipelines)
+	for _, key := range []string{"extensions", "telemetry"} {
+		if !reflect.DeepEqual(oldService[key], newService[key]) {
+			report.Changed = append(report.Changed, "service/"+key)
+		}
+	}
+}
+
+// diffAgentConfig adds the changed top-level sections of the agent config to report. Only collector.config_path is
+// applied by a reload; any other change requires a restart.
+func diffAgentConfig(report *ReloadReport, oldCfg, newCfg *core.Config) {
+	if oldCfg == nil || newCfg == nil || oldCfg == newCfg {
+		return
+	}
+	oldSections, newSections := configSections(oldCfg), configSections(newCfg)
+	for _, section := range slices.Sorted(maps.Keys(newSections)) {
+		if reflect.DeepEqual(oldSections[section], newSections[section]) {
+			continue
+		}
+		report.Changed = append(report.Changed, "config/"+section)
+		if section == "collector" {
+			oldCollector, newCollector := oldCfg.Collector, newCfg.Collector
+			oldCollector.ConfigPath, newCollector.ConfigPath = "", ""
+			if oldCollector == newCollector {
+				continue
+			}
+		}
+		report.RestartRequired = append(report.RestartRequired, section)
+	}
+}
+
+// configSections returns the agent config as a map of its top-level YAML sections.
+func configSections(cfg *core.Config) map[string]any {
+	var out map[string]any
+	if data, err := yaml.Marshal(cfg); err == nil {
+		_ = yaml.Unmarshal(data, &out)
+	}
+	return out
+}
+
+// diffMaps adds the keys added to, removed from or changed between two config maps to report.
+func diffMaps(report *ReloadReport, prefix string, oldMap, newMap map[string]any) {
+	for _, key := range slices.Sorted(maps.Keys(newMap)) {
+		old, ok := oldMap[key]
+		switch {
+		case !ok:
+			report.Added = append(report.Added, prefix+key)
+		case !reflect.DeepEqual(old, newMap[key]):
+			report.Changed = append(report.Changed, prefix+key)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(oldMap)) {
+		if _, ok := newMap[key]; !ok {
+			report.Removed = append(report.Removed, prefix+key)
+		}
+	}
+}
+
+// prefixed returns items with prefix prepended.
+func prefixed(prefix string, items []string) []string {
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		out = append(out, prefix+item)
+	}
+	return out
+}
+
+// WriteReloadReport renders a reload report.
+//
+// Parameters:
+//   - w: Destination writer.
+//   - r: Report to render.
+//   - format: One of "table" (default), "json" or "yaml".
+//
+// Returns:
+//   - error: If the format is unknown or encoding fails, returns a detailed error.
+func WriteReloadReport(w io.Writer, r *ReloadReport, format string) error {
+	switch strings.ToLower(format) {
+	case "", "table":
+		return writeReloadTable(w, r)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("failed to encode reload report as YAML: %w", err)
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("unsupported output format %q (expected table, json, or yaml)", format)
+	}
+}
+
+// writeReloadTable renders a reload report as aligned text.
+func writeReloadTable(w io.Writer, r *ReloadReport) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	switch {
+	case r.Error != "" && r.RolledBack:
+		fmt.Fprintf(tw, "Result:\tfailed, rolled back\n")
+	case r.Error != "":
+		fmt.Fprintf(tw, "Result:\tfailed, nothing changed\n")
+	case len(r.Added)+len(r.Removed)+len(r.Changed) == 0:
+		fmt.Fprintf(tw, "Result:\tno changes\n")
+	default:
+		fmt.Fprintf(tw, "Result:\treloaded\n")
+	}
+	for _, row := range []struct {
+		label string
+		items []string
+	}{
+		{"Added", r.Added}, {"Removed", r.Removed}, {"Changed", r.Changed},
+		{"Restarted", r.Restarted}, {"Unchanged", r.Unchanged}, {"Restart required", r.RestartRequired},
+	} {
+		if len(row.items) > 0 {
+			fmt.Fprintf(tw, "%s:\t%s\n", row.label, strings.Join(row.items, ", "))
+		}
+	}
+	if r.Error != "" {
+		fmt.Fprintf(tw, "Error:\t%s\n", r.Error)
+	}
+	if r.ConfigDigest != "" {
+		fmt.Fprintf(tw, "Config digest:\t%s\n", r.ConfigDigest)
+	}
+	return tw.Flush()
+}
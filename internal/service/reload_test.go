@@ -0,0 +1,315 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/receiver"
+
+	"github.com/srediag/srediag/internal/core"
+	"github.com/srediag/srediag/internal/diagnose"
+)
+
+const twoGroupConfig = `
+receivers:
+  nop:
+  nop/logs:
+exporters:
+  nop:
+  nop/logs:
+service:
+  telemetry:
+    metrics:
+      level: none
+  pipelines:
+    traces:
+      receivers: [nop]
+      exporters: [nop]
+    logs:
+      receivers: [nop/logs]
+      exporters: [nop/logs]
+`
+
+// failingReceiverType is a receiver whose Start always fails, e.g. because its port is taken.
+var failingReceiverType = component.MustNewType("failing")
+
+// failingReceiverFactory returns the factory of failingReceiverType.
+func failingReceiverFactory() receiver.Factory {
+	return tracesReceiverFactory(failingReceiverType, func(context.Context, component.Host) error {
+		return errors.New("address already in use")
+	})
+}
+
+// tracesReceiverFactory returns a factory of traces receivers of type typ that run start.
+func tracesReceiverFactory(typ component.Type, start component.StartFunc) receiver.Factory {
+	return receiver.NewFactory(typ,
+		func() component.Config { return &struct{}{} },
+		receiver.WithTraces(func(context.Context, receiver.Settings, component.Config, consumer.Traces) (receiver.Traces, error) {
+			return struct {
+				component.StartFunc
+				component.ShutdownFunc
+			}{StartFunc: start}, nil
+		}, component.StabilityLevelStable))
+}
+
+// startReloadService starts a nop collector service on the given config and returns it with its config path.
+func startReloadService(t *testing.T, conf string) (*Service, string) {
+	t.Helper()
+	path := writeFile(t, t.TempDir(), "service.yaml", conf)
+	svc := newNopService(t, path, &bytes.Buffer{})
+	failing := failingReceiverFactory()
+	svc.receivers[failing.Type()] = failing
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+	require.NoError(t, svc.Start(ctx))
+	t.Cleanup(func() { _ = svc.Stop(context.Background()) })
+	return svc, path
+}
+
+func TestSplitCollectorConfig(t *testing.T) {
+	conf := map[string]any{
+		"receivers":  map[string]any{"otlp": nil, "filelog": map[string]any{"storage": "file_storage"}},
+		"processors": map[string]any{"batch": nil},
+		"exporters":  map[string]any{"otlp": nil, "debug": nil},
+		"connectors": map[string]any{"count": nil},
+		"extensions": map[string]any{"file_storage": nil, "zpages": nil, "diag_exporter": nil},
+		"service": map[string]any{
+			"extensions": []any{"file_storage", "zpages", "diag_exporter"},
+			"pipelines": map[string]any{
+				"traces":  map[string]any{"receivers": []any{"otlp"}, "processors": []any{"batch"}, "exporters": []any{"count"}},
+				"metrics": map[string]any{"receivers": []any{"count"}, "processors": []any{"batch"}, "exporters": []any{"otlp"}},
+				"logs":    map[string]any{"receivers": []any{"filelog"}, "processors": []any{"batch"}, "exporters": []any{"debug"}},
+			},
+		},
+	}
+
+	instances := splitCollectorConfig(conf)
+	require.Len(t, instances, 3)
+
+	group := instances["metrics,traces"]
+	require.NotNil(t, group, "pipelines joined by a connector share an instance")
+	assert.Contains(t, group.types, "connector/count")
+	assert.Contains(t, group.types, "processor/batch")
+
+	logs := instances["logs"]
+	require.NotNil(t, logs, "a shared processor does not join pipelines")
+	assert.Contains(t, logs.conf["extensions"], "file_storage", "a referenced extension runs with its pipeline")
+	assert.Equal(t, []any{"file_storage"}, logs.conf["service"].(map[string]any)["extensions"])
+	assert.Equal(t, map[string]any{"level": "none"}, logs.conf["service"].(map[string]any)["telemetry"].(map[string]any)["metrics"])
+
+	extras := instances[extensionsInstance]
+	require.NotNil(t, extras)
+	assert.Equal(t, "extensions", extras.name())
+	assert.Equal(t, map[string]any{"zpages": nil}, extras.conf["extensions"], "the diag_exporter is left out")
+	assert.Equal(t, []any{"zpages"}, extras.conf["service"].(map[string]any)["extensions"])
+
+	again := splitCollectorConfig(conf)
+	assert.Equal(t, group.digest, again["metrics,traces"].digest, "digests are stable")
+}
+
+func TestService_ReloadRestartsChangedPipelines(t *testing.T) {
+	svc, path := startReloadService(t, twoGroupConfig)
+	traces, extras := svc.instances["traces"], svc.instances[extensionsInstance]
+	tracesSvc := traces.svc
+
+	report, err := svc.Reload(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, report.Restarted, "an unchanged config restarts nothing")
+	assert.Equal(t, []string{"extensions", "logs", "traces"}, report.Unchanged)
+
+	require.NoError(t, os.WriteFile(path, []byte(twoGroupConfig+`
+    logs/2:
+      receivers: [nop/logs]
+      exporters: [nop]
+`), 0o600))
+	report, err = svc.Reload(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pipelines/logs/2"}, report.Added)
+	assert.Equal(t, []string{"logs,logs/2,traces"}, report.Restarted, "pipelines sharing a component restart together")
+	assert.Equal(t, []string{"extensions"}, report.Unchanged)
+	assert.Same(t, extras, svc.instances[extensionsInstance])
+	assert.Nil(t, traces.svc, "the replaced instance was stopped")
+	assert.NotSame(t, tracesSvc, svc.instances["logs,logs/2,traces"].svc)
+	assert.NotEmpty(t, report.ConfigDigest)
+
+	select {
+	case <-svc.Done():
+		t.Fatalf("collector stopped: %v", svc.Err())
+	default:
+	}
+}
+
+func TestService_ReloadInvalidConfig(t *testing.T) {
+	svc, path := startReloadService(t, twoGroupConfig)
+	before := svc.instances
+
+	for name, conf := range map[string]string{
+		"syntax":            "receivers: [",
+		"unknown component": "receivers:\n  otlp:\nexporters:\n  nop:\nservice:\n  pipelines:\n    traces:\n      receivers: [otlp]\n      exporters: [nop]\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(path, []byte(conf), 0o600))
+			report, err := svc.Reload(context.Background())
+			var reloadErr *ReloadError
+			require.ErrorAs(t, err, &reloadErr)
+			assert.True(t, reloadErr.Invalid)
+			assert.Equal(t, diagnose.ExitVerification, diagnose.ExitCode(err))
+			assert.NotEmpty(t, report.Error)
+			assert.False(t, report.RolledBack)
+			assert.Equal(t, before, svc.instances, "nothing was changed")
+			for _, inst := range svc.instances {
+				assert.NotNil(t, inst.svc, "instance %s still runs", inst.name())
+			}
+		})
+	}
+}
+
+func TestService_ReloadRollsBack(t *testing.T) {
+	svc, path := startReloadService(t, twoGroupConfig)
+	traces := svc.instances["traces"]
+
+	conf := `
+receivers:
+  failing:
+  nop/logs:
+exporters:
+  nop:
+  nop/logs:
+service:
+  telemetry:
+    metrics:
+      level: none
+  pipelines:
+    traces:
+      receivers: [failing]
+      exporters: [nop]
+    logs:
+      receivers: [nop/logs]
+      exporters: [nop/logs]
+`
+	require.NoError(t, os.WriteFile(path, []byte(conf), 0o600))
+	report, err := svc.Reload(context.Background())
+	var reloadErr *ReloadError
+	require.ErrorAs(t, err, &reloadErr)
+	assert.False(t, reloadErr.Invalid)
+	assert.Equal(t, diagnose.ExitError, diagnose.ExitCode(err))
+	assert.ErrorContains(t, err, "address already in use")
+	assert.True(t, report.RolledBack)
+	assert.Same(t, traces, svc.instances["traces"])
+	assert.NotNil(t, traces.svc, "the previous instance was restarted")
+	assert.Contains(t, svc.collectorConf["receivers"], "nop", "the last known-good config is kept")
+
+	var out bytes.Buffer
+	require.NoError(t, WriteReloadReport(&out, report, "table"))
+	assert.Contains(t, out.String(), "failed, rolled back")
+}
+
+func TestService_ReloadRollbackRestoresPluginFactories(t *testing.T) {
+	plugged := component.MustNewType("plugged")
+	path := writeFile(t, t.TempDir(), "service.yaml", `
+receivers:
+  plugged:
+exporters:
+  nop:
+service:
+  telemetry:
+    metrics:
+      level: none
+  pipelines:
+    traces:
+      receivers: [plugged]
+      exporters: [nop]
+`)
+	svc := newNopService(t, path, &bytes.Buffer{})
+	working := tracesReceiverFactory(plugged, nil)
+	svc.usePluginFactories(map[component.Type]component.Factory{plugged: working}, nil, nil, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	require.NoError(t, svc.Start(ctx))
+	t.Cleanup(func() { _ = svc.Stop(context.Background()) })
+	traces := svc.instances["traces"]
+
+	// The plugin binary is replaced by a build whose receiver fails to start.
+	previous := svc.snapshotFactories()
+	broken := tracesReceiverFactory(plugged, func(context.Context, component.Host) error { return errors.New("broken build") })
+	swapped := svc.usePluginFactories(map[component.Type]component.Factory{plugged: broken}, nil, nil, nil)
+	report := &ReloadReport{}
+	err := svc.applyCollector(ctx, report, svc.collectorConf, swapped, func() { svc.restoreFactories(previous) })
+	assert.ErrorContains(t, err, "broken build")
+	assert.True(t, report.RolledBack)
+	assert.Same(t, working, svc.receivers[plugged], "the previous plugin factories are restored")
+	assert.Same(t, traces, svc.instances["traces"])
+	assert.NotNil(t, traces.svc, "the previous instance was restarted with the previous plugin")
+	select {
+	case <-svc.Done():
+		t.Fatalf("collector stopped: %v", svc.Err())
+	default:
+	}
+}
+
+func TestService_ReloadAgentConfig(t *testing.T) {
+	svc, path := startReloadService(t, twoGroupConfig)
+	cfg := core.NewConfig()
+	cfg.Collector.Enabled, cfg.Collector.ConfigPath = true, path
+	svc.SetAgentInfo(core.BuildInfo{}, cfg)
+
+	next := core.NewConfig()
+	next.Collector.Enabled, next.Collector.ConfigPath = true, writeFile(t, t.TempDir(), "moved.yaml", twoGroupConfig)
+	next.Logging.Level = "debug"
+	svc.SetConfigLoader(func() (*core.Config, error) { return next, nil })
+
+	report, err := svc.Reload(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"config/collector", "config/logging"}, report.Changed)
+	assert.Equal(t, []string{"logging"}, report.RestartRequired, "a new collector.config_path is applied")
+	assert.Equal(t, next.Collector.ConfigPath, svc.collectorConfig)
+
+	svc.SetConfigLoader(func() (*core.Config, error) { return nil, errors.New("bad yaml") })
+	_, err = svc.Reload(context.Background())
+	assert.Equal(t, diagnose.ExitVerification, diagnose.ExitCode(err))
+}
+
+func TestCLI_Reload(t *testing.T) {
+	cfg := daemonConfig(t)
+	appCtx := &core.AppContext{Config: cfg}
+	cmd := &cobra.Command{}
+	cmd.Flags().String("output", "table", "")
+	cmd.Flags().Duration("timeout", 5*time.Second, "")
+	cmd.SetContext(context.Background())
+	assert.ErrorIs(t, CLI_Reload(appCtx, cmd, nil), ErrNotRunning)
+
+	startAdmin(t, cfg)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	require.NoError(t, CLI_Reload(appCtx, cmd, nil))
+	assert.Contains(t, out.String(), "no changes")
+}
+
+func TestConfigWatcher(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewConfigWatcher(core.NewTestLogger(&bytes.Buffer{}), []string{dir, dir + "/missing"}, 50*time.Millisecond)
+	require.NoError(t, err)
+	defer func() { _ = w.Close() }()
+
+	writeFile(t, dir, "service.yaml", "a: 1\n")
+	writeFile(t, dir, "service.yaml", "a: 2\n")
+	select {
+	case <-w.Changes():
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change signalled")
+	}
+	select {
+	case <-w.Changes():
+		t.Fatal("edits were not debounced")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
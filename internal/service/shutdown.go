@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package service provides the core service orchestration, lifecycle management, and CLI stubs for the SREDIAG collector service.
+//
+// This file defines the ordered shutdown of the agent (docs/cli/service.md §2.2). Service.Stop first stops the
+// background producers (garbage collector, profiler, remote job runner), then every pipeline instance of the
+// embedded collector at once, so all receivers stop accepting before processors and exporters drain, then the
+// extensions instance; then the diag_exporter syncs its persistent queue and the plugin processes are terminated.
+// Everything shares one deadline, service.shutdown_timeout; whatever could not be flushed by then is reported as
+// ErrDataDropped, which makes 'srediag service start' exit non-zero.
+//
+// Usage:
+//   - Bound Service.Stop with ShutdownTimeout and check errors.Is(err, ErrDataDropped).
+//
+// Best Practices:
+//   - Keep service.shutdown_timeout below the stop timeout of the supervisor (systemd TimeoutStopSec, Kubernetes
+//     terminationGracePeriodSeconds), or the agent is killed before it can flush.
+
+// ErrDataDropped marks a shutdown that could not flush everything: a pipeline did not drain before the deadline or
+// a persistent queue could not be synced.
+var ErrDataDropped = errors.New("data dropped during shutdown")
+
+// ShutdownTimeout returns the shutdown deadline of the agent.
+//
+// Parameters:
+//   - cfg: Agent configuration.
+//
+// Returns:
+//   - time.Duration: service.shutdown_timeout, or 30s when it is not set.
+//   - error: If service.shutdown_timeout is not a positive duration.
+func ShutdownTimeout(cfg *core.Config) (time.Duration, error) {
+	if cfg == nil || cfg.Service.ShutdownTimeout == "" {
+		return stopTimeout, nil
+	}
+	d, err := time.ParseDuration(cfg.Service.ShutdownTimeout)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid service.shutdown_timeout %q", cfg.Service.ShutdownTimeout)
+	}
+	return d, nil
+}
+
+// stopCollector stops the collector instances: the pipeline instances concurrently, so their receivers stop
+// together, then the extensions instance, which the pipelines may still use while they drain. An instance that is
+// still draining at the deadline of ctx is abandoned and reported as ErrDataDropped.
+func (s *Service) stopCollector(ctx context.Context) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	s.mu.Lock()
+	instances := slices.Collect(maps.Values(s.instances))
+	s.instances, s.collectorState = nil, "Closing"
+	s.mu.Unlock()
+	defer s.setCollectorState("Closed")
+
+	var pipelines []*collectorInstance
+	var extensions *collectorInstance
+	for _, inst := range sortedInstances(instances) {
+		if inst.key == extensionsInstance {
+			extensions = inst
+		} else {
+			pipelines = append(pipelines, inst)
+		}
+	}
+	errs := s.stopInstances(ctx, pipelines)
+	if extensions != nil {
+		errs = append(errs, s.stopInstances(ctx, []*collectorInstance{extensions})...)
+	}
+	return errors.Join(errs...)
+}
+
+// stopInstances shuts the instances down concurrently and waits until they are done or ctx expires.
+func (s *Service) stopInstances(ctx context.Context, instances []*collectorInstance) []error {
+	var mu sync.Mutex
+	var errs []error
+	pending := make(map[string]bool, len(instances))
+	for _, inst := range instances {
+		pending[inst.name()] = true
+	}
+	var wg sync.WaitGroup
+	for _, inst := range instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := s.stopInstance(ctx, inst)
+			mu.Lock()
+			defer mu.Unlock()
+			delete(pending, inst.name())
+			if err != nil {
+				if ctx.Err() != nil {
+					// Shutdown gave up on the exporters' queues.
+					err = fmt.Errorf("%w: %w", ErrDataDropped, err)
+				}
+				errs = append(errs, err)
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	out := slices.Clone(errs)
+	for _, name := range slices.Sorted(maps.Keys(pending)) {
+		out = append(out, fmt.Errorf("%w: collector instance %s did not drain before the shutdown deadline", ErrDataDropped, name))
+	}
+	return out
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/extension"
+	"go.opentelemetry.io/collector/receiver"
+
+	"github.com/srediag/srediag/internal/core"
+	"github.com/srediag/srediag/internal/diagnose"
+	"github.com/srediag/srediag/internal/queue"
+)
+
+const recorderConfig = `
+receivers:
+  recorder:
+exporters:
+  recorder:
+extensions:
+  recorder:
+service:
+  extensions: [recorder]
+  telemetry:
+    metrics:
+      level: none
+  pipelines:
+    traces:
+      receivers: [recorder]
+      exporters: [recorder]
+`
+
+// recorderType is the type of the receiver, exporter and extension that record their shutdown.
+var recorderType = component.MustNewType("recorder")
+
+// shutdownRecorder collects the kinds of the recorder components in the order they shut down.
+type shutdownRecorder struct {
+	mu    sync.Mutex
+	order []string
+	// drain is how long the exporter takes to shut down, ignoring the deadline.
+	drain time.Duration
+}
+
+// component returns a component that records kind when it shuts down.
+func (r *shutdownRecorder) component(kind string) component.Component {
+	return struct {
+		component.StartFunc
+		component.ShutdownFunc
+	}{ShutdownFunc: func(context.Context) error {
+		if kind == "exporter" {
+			time.Sleep(r.drain)
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.order = append(r.order, kind)
+		return nil
+	}}
+}
+
+// Order returns the recorded shutdowns.
+func (r *shutdownRecorder) Order() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.order...)
+}
+
+// newRecorderService returns a started service running recorderConfig.
+func newRecorderService(t *testing.T, rec *shutdownRecorder) *Service {
+	t.Helper()
+	newConfig := func() component.Config { return &struct{}{} }
+	receivers := receiver.NewFactory(recorderType, newConfig,
+		receiver.WithTraces(func(context.Context, receiver.Settings, component.Config, consumer.Traces) (receiver.Traces, error) {
+			return rec.component("receiver"), nil
+		}, component.StabilityLevelStable))
+	exporters := exporter.NewFactory(recorderType, newConfig,
+		exporter.WithTraces(func(context.Context, exporter.Settings, component.Config) (exporter.Traces, error) {
+			return struct {
+				component.Component
+				consumer.Traces
+			}{rec.component("exporter"), consumertest.NewNop()}, nil
+		}, component.StabilityLevelStable))
+	extensions := extension.NewFactory(recorderType, newConfig,
+		func(context.Context, extension.Settings, component.Config) (extension.Extension, error) {
+			return rec.component("extension"), nil
+		}, component.StabilityLevelStable)
+	svc := NewService(core.NewTestLogger(&bytes.Buffer{}),
+		map[component.Type]component.Factory{recorderType: receivers}, nil,
+		map[component.Type]component.Factory{recorderType: exporters},
+		map[component.Type]component.Factory{recorderType: extensions})
+	svc.SetCollector(writeFile(t, t.TempDir(), "service.yaml", recorderConfig), component.BuildInfo{Command: "srediag"})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	require.NoError(t, svc.Start(ctx))
+	return svc
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	cfg := core.NewConfig()
+	d, err := ShutdownTimeout(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, stopTimeout, d)
+	cfg.Service.ShutdownTimeout = "2m"
+	d, err = ShutdownTimeout(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, d)
+	for _, bad := range []string{"soon", "0s", "-1s"} {
+		cfg.Service.ShutdownTimeout = bad
+		_, err = ShutdownTimeout(cfg)
+		assert.ErrorContains(t, err, "invalid service.shutdown_timeout", bad)
+	}
+}
+
+func TestService_StopOrder(t *testing.T) {
+	rec := &shutdownRecorder{}
+	svc := newRecorderService(t, rec)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, svc.Stop(ctx))
+	assert.Equal(t, []string{"receiver", "exporter", "extension"}, rec.Order(),
+		"receivers stop before the exporters drain, extensions last")
+}
+
+func TestService_StopDrainDeadline(t *testing.T) {
+	rec := &shutdownRecorder{drain: time.Second}
+	svc := newRecorderService(t, rec)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := svc.Stop(ctx)
+	assert.ErrorIs(t, err, ErrDataDropped)
+	assert.ErrorContains(t, err, "collector instance traces did not drain")
+	assert.Less(t, time.Since(start), time.Second, "Stop returns at the deadline")
+	select {
+	case <-svc.Done():
+	default:
+		t.Fatal("Done not closed after Stop")
+	}
+}
+
+func TestService_StopSyncsDiagExporterQueue(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	endpoint := "otlp://" + lis.Addr().String()
+	require.NoError(t, lis.Close())
+	dir := t.TempDir()
+	exp, err := diagnose.NewDiagExporter(&diagnose.DiagExporterConfig{
+		Endpoint: endpoint, Timeout: 100 * time.Millisecond, Queue: queue.Config{Enabled: true, Dir: dir},
+	}, core.NewTestLogger(&bytes.Buffer{}))
+	require.NoError(t, err)
+	svc := NewService(core.NewTestLogger(&bytes.Buffer{}), nil, nil, nil, nil)
+	svc.SetDiagExporter(exp)
+	require.NoError(t, svc.Start(context.Background()))
+	report := diagnose.NewReport("system")
+	report.Add(diagnose.Finding{Check: "system.disk.full", Severity: diagnose.SeverityWarning, Message: "disk is 95% full"})
+	require.NoError(t, svc.ExportReport(context.Background(), report))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, svc.Stop(ctx))
+	q, err := queue.Open(dir, queue.Options{})
+	require.NoError(t, err)
+	defer func() { _ = q.Close() }()
+	assert.Equal(t, uint64(1), q.Stats().Depth, "the undelivered report is kept for the next start")
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/srediag/srediag/internal/core"
+	"github.com/srediag/srediag/internal/plugin"
+)
+
+// Package service provides the core service orchestration, lifecycle management, and CLI stubs for the SREDIAG collector service.
+//
+// This file defines the status snapshot of a running agent, served by the admin API (GET /v1/status) and printed by
+// 'srediag service status'.
+//
+// Usage:
+//   - Call Service.Status for the current snapshot; WriteStatus renders it as a table, JSON or YAML.
+//
+// Best Practices:
+//   - Only add fields that are cheap to compute; status is polled by scripts and health checks.
+
+// Service states reported in StatusReport.State.
+const (
+	StateStarting = "starting"
+	StateRunning  = "running"
+	StateStopping = "stopping"
+	StateStopped  = "stopped"
+)
+
+// StatusReport is a snapshot of the running agent.
+//
+// Fields:
+//   - State: One of the State* constants.
+//   - PID: Process ID of the agent.
+//   - Version, Commit: Build information of the agent binary.
+//   - StartedAt, Uptime: When the service started and for how long it has been running.
+//   - ConfigDigest: "sha256:<hex>" over the effective agent config and the merged collector config.
+//   - Collector: Embedded collector state and pipelines.
+//   - Plugins: Loaded plugins and their health.
+//   - RemoteJobs: Whether the remote diagnostics job runner is active.
+type StatusReport struct {
+	State        string          `json:"state" yaml:"state"`
+	PID          int             `json:"pid" yaml:"pid"`
+	Version      string          `json:"version" yaml:"version"`
+	Commit       string          `json:"commit,omitempty" yaml:"commit,omitempty"`
+	StartedAt    time.Time       `json:"started_at" yaml:"started_at"`
+	Uptime       string          `json:"uptime" yaml:"uptime"`
+	ConfigDigest string          `json:"config_digest" yaml:"config_digest"`
+	Collector    CollectorStatus `json:"collector" yaml:"collector"`
+	Plugins      []PluginStatus  `json:"plugins" yaml:"plugins"`
+	RemoteJobs   bool            `json:"remote_jobs" yaml:"remote_jobs"`
+}
+
+// CollectorStatus describes the embedded collector.
+//
+// Fields:
+//   - Enabled: Whether collector.enabled is set.
+//   - State: The collector state (Starting, Running, Closing, Closed) when enabled.
+//   - Config: The pipeline config path (collector.config_path).
+//   - Pipelines: The configured pipelines, sorted by name.
+type CollectorStatus struct {
+	Enabled   bool             `json:"enabled" yaml:"enabled"`
+	State     string           `json:"state,omitempty" yaml:"state,omitempty"`
+	Config    string           `json:"config,omitempty" yaml:"config,omitempty"`
+	Pipelines []PipelineStatus `json:"pipelines,omitempty" yaml:"pipelines,omitempty"`
+}
+
+// PipelineStatus lists the components of one collector pipeline.
+type PipelineStatus struct {
+	Name       string   `json:"name" yaml:"name"`
+	Receivers  []string `json:"receivers" yaml:"receivers"`
+	Processors []string `json:"processors,omitempty" yaml:"processors,omitempty"`
+	Exporters  []string `json:"exporters" yaml:"exporters"`
+}
+
+// PluginStatus describes one loaded plugin.
+type PluginStatus struct {
+	Name    string `json:"name" yaml:"name"`
+	Type    string `json:"type" yaml:"type"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+	State   string `json:"state" yaml:"state"`
+}
+
+// Status returns a snapshot of the service.
+//
+// Parameters:
+//   - ctx: Context for the plugin health checks.
+//
+// Returns:
+//   - *StatusReport: The current status.
+func (s *Service) Status(ctx context.Context) *StatusReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := &StatusReport{
+		State:        s.state,
+		PID:          os.Getpid(),
+		Version:      s.agentInfo.Version,
+		Commit:       s.agentInfo.Commit,
+		StartedAt:    s.startedAt,
+		ConfigDigest: configDigest(s.agentConfig, s.collectorConf),
+		Collector:    CollectorStatus{Enabled: s.collectorConfig != "", Config: s.collectorConfig},
+		Plugins:      []PluginStatus{},
+		RemoteJobs:   s.jobRunner != nil,
+	}
+	if r.State == "" {
+		r.State = StateStopped
+	}
+	if !s.startedAt.IsZero() {
+		r.Uptime = time.Since(s.startedAt).Round(time.Second).String()
+	}
+	if r.Collector.Enabled {
+		r.Collector.State = s.collectorState
+	}
+	r.Collector.Pipelines = pipelineStatus(s.collectorConf)
+	if s.plugins != nil {
+		health := s.plugins.CheckHealth(ctx)
+		for _, meta := range s.plugins.List() {
+			p := PluginStatus{Name: meta.Name, Type: string(meta.Type), Version: meta.Version, State: "unknown"}
+			if h, ok := health[meta.Name]; ok && h != nil {
+				p.State = h.Status
+			}
+			r.Plugins = append(r.Plugins, p)
+		}
+		slices.SortFunc(r.Plugins, func(a, b PluginStatus) int { return strings.Compare(a.Name, b.Name) })
+	}
+	return r
+}
+
+// pipelineStatus lists the pipelines of a merged collector config.
+func pipelineStatus(conf map[string]any) []PipelineStatus {
+	svc, _ := conf["service"].(map[string]any)
+	pipelines, _ := svc["pipelines"].(map[string]any)
+	out := make([]PipelineStatus, 0, len(pipelines))
+	for name, p := range pipelines {
+		pipeline, _ := p.(map[string]any)
+		out = append(out, PipelineStatus{
+			Name:       name,
+			Receivers:  stringList(pipeline["receivers"]),
+			Processors: stringList(pipeline["processors"]),
+			Exporters:  stringList(pipeline["exporters"]),
+		})
+	}
+	slices.SortFunc(out, func(a, b PipelineStatus) int { return strings.Compare(a.Name, b.Name) })
+	return out
+}
+
+// stringList converts a decoded YAML list of strings.
+func stringList(v any) []string {
+	items, _ := v.([]any)
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		out = append(out, fmt.Sprint(item))
+	}
+	return out
+}
+
+// configDigest hashes the effective agent config and the merged collector config. JSON encoding sorts map keys, so
+// equal configs always give the same digest.
+func configDigest(cfg *core.Config, collector map[string]any) string {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	_ = enc.Encode(cfg)
+	_ = enc.Encode(collector)
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// WriteStatus renders a status report.
+//
+// Parameters:
+//   - w: Destination writer.
+//   - r: Status to render.
+//   - format: One of "table" (default), "json" or "yaml".
+//
+// Returns:
+//   - error: If the format is unknown or encoding fails, returns a detailed error.
+func WriteStatus(w io.Writer, r *StatusReport, format string) error {
+	switch strings.ToLower(format) {
+	case "", "table":
+		return writeStatusTable(w, r)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("failed to encode status as YAML: %w", err)
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("unsupported output format %q (expected table, json, or yaml)", format)
+	}
+}
+
+// writeStatusTable renders a status report as aligned text.
+func writeStatusTable(w io.Writer, r *StatusReport) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "State:\t%s\n", r.State)
+	fmt.Fprintf(tw, "PID:\t%d\n", r.PID)
+	version := r.Version
+	if r.Commit != "" {
+		version += " (" + r.Commit + ")"
+	}
+	fmt.Fprintf(tw, "Version:\t%s\n", version)
+	if !r.StartedAt.IsZero() {
+		fmt.Fprintf(tw, "Started:\t%s (up %s)\n", r.StartedAt.Format(time.RFC3339), r.Uptime)
+	}
+	fmt.Fprintf(tw, "Config digest:\t%s\n", r.ConfigDigest)
+	fmt.Fprintf(tw, "Remote jobs:\t%t\n", r.RemoteJobs)
+	if !r.Collector.Enabled {
+		fmt.Fprintf(tw, "Collector:\tdisabled\n")
+	} else {
+		fmt.Fprintf(tw, "Collector:\t%s (%s)\n", r.Collector.State, r.Collector.Config)
+	}
+	if len(r.Collector.Pipelines) > 0 {
+		fmt.Fprintln(tw, "\nPIPELINE\tRECEIVERS\tPROCESSORS\tEXPORTERS")
+		for _, p := range r.Collector.Pipelines {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Name,
+				strings.Join(p.Receivers, ","), strings.Join(p.Processors, ","), strings.Join(p.Exporters, ","))
+		}
+	}
+	if len(r.Plugins) > 0 {
+		fmt.Fprintln(tw, "\nPLUGIN\tTYPE\tVERSION\tSTATE")
+		for _, p := range r.Plugins {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Name, p.Type, p.Version, p.State)
+		}
+	}
+	return tw.Flush()
+}
+
+// pluginStatusSource is the part of the plugin manager used for status reports.
+type pluginStatusSource interface {
+	List() []plugin.PluginMetadata
+	CheckHealth(ctx context.Context) map[string]*plugin.PluginHealth
+}
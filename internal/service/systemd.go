@@ -0,0 +1,375 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package service provides the core service orchestration, lifecycle management, and CLI stubs for the SREDIAG collector service.
+//
+// This file defines the systemd integration: RenderUnit writes a hardened srediag.service for the current binary
+// and config (ProtectSystem=strict, NoNewPrivileges, MemoryMax above collector.memory_limit_mib, a bounded
+// capability set and the directories the agent writes), InstallSystemdUnit installs and enables it with systemctl, and
+// UninstallSystemdUnit reverses that. Both system units (/etc/systemd/system) and user units (~/.config/systemd/user) are
+// supported.
+//
+// Usage:
+//   - Call NewUnitOptions with the loaded config, then RenderUnit for --dry-run or InstallSystemdUnit to install it.
+//
+// Best Practices:
+//   - Re-run 'srediag service install-unit' after changing paths or limits instead of editing the unit by hand.
+
+// unitName is the name of the installed systemd unit.
+const unitName = "srediag.service"
+
+// unitCapabilities bounds what a system unit may do: privileged receiver ports, reading any log or /proc entry,
+// and the eBPF probes (with the memlock they need on older kernels).
+var unitCapabilities = []string{
+	"CAP_BPF", "CAP_DAC_READ_SEARCH", "CAP_NET_BIND_SERVICE", "CAP_PERFMON", "CAP_SYS_PTRACE", "CAP_SYS_RESOURCE",
+}
+
+// unitMemoryHeadroomPct and unitMemoryHeadroomMinMiB size the margin MemoryMax= leaves above
+// collector.memory_limit_mib, so the memory limiter gets to shed load before the kernel OOM-kills the agent.
+const (
+	unitMemoryHeadroomPct    = 25
+	unitMemoryHeadroomMinMiB = 64
+)
+
+// systemUnitDir is where system units are installed; patchable for tests.
+var systemUnitDir = "/etc/systemd/system"
+
+// systemctl runs systemctl, with --user for user units; patchable for tests.
+var systemctl = func(ctx context.Context, userScope bool, args ...string) error {
+	if userScope {
+		args = append([]string{"--user"}, args...)
+	}
+	out, err := exec.CommandContext(ctx, "systemctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// UnitOptions describes the systemd unit of the agent.
+type UnitOptions struct {
+	User            bool                // User unit (systemctl --user) instead of a system unit
+	Executable      string              // Absolute path of the srediag binary
+	ConfigFile      string              // Absolute --config of the agent; empty uses the default search
+	RunAs           string              // System units: account the agent runs as (default: root)
+	PIDFile         string              // service.pid_file pinned for the agent
+	Socket          string              // service.socket pinned for the agent
+	ShutdownTimeout time.Duration       // service.shutdown_timeout; TimeoutStopSec= leaves 15s on top
+	MemoryLimitMiB  int                 // collector.memory_limit_mib; MemoryMax= adds headroom, 0 leaves it unset
+	CPUQuotaPct     int                 // CPUQuota= (security.runtime.cpu_guard_pct); 0 leaves it unset
+	Directories     map[string][]string // Directories systemd creates, e.g. RuntimeDirectory: [srediag]
+	ReadWritePaths  []string            // Other directories the agent writes under ProtectSystem=strict
+}
+
+// NewUnitOptions returns the unit of the current binary for the given config.
+//
+// Parameters:
+//   - cfg: Agent configuration; service.*, collector.memory_limit_mib, security.runtime and the diagnostics
+//     directories are used.
+//   - configFile: --config of the agent, made absolute; empty uses the default search.
+//   - userScope: Whether to render a user unit.
+//
+// Returns:
+//   - UnitOptions: Unit with the PID file, socket and state directories placed in systemd-managed directories
+//     where they match /run, /var/lib, /var/log (system) or $XDG_RUNTIME_DIR (user).
+//   - error: If the binary or config path cannot be resolved.
+func NewUnitOptions(cfg *core.Config, configFile string, userScope bool) (UnitOptions, error) {
+	exe, err := daemonExecutable()
+	if err != nil {
+		return UnitOptions{}, fmt.Errorf("failed to locate the srediag binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	if configFile != "" {
+		if configFile, err = filepath.Abs(configFile); err != nil {
+			return UnitOptions{}, fmt.Errorf("failed to resolve config path: %w", err)
+		}
+	}
+	shutdownTimeout, err := ShutdownTimeout(cfg)
+	if err != nil {
+		return UnitOptions{}, err
+	}
+	pidFile, socket, _ := daemonPaths(cfg)
+	opts := UnitOptions{
+		User:            userScope,
+		Executable:      exe,
+		ConfigFile:      configFile,
+		PIDFile:         pidFile,
+		Socket:          socket,
+		ShutdownTimeout: shutdownTimeout,
+		MemoryLimitMiB:  cfg.Collector.MemoryLimitMiB,
+		CPUQuotaPct:     cfg.Security.Runtime.CPUGuardPct,
+		Directories:     map[string][]string{},
+	}
+
+	managed := map[string]string{"RuntimeDirectory": "/run", "StateDirectory": "/var/lib", "LogsDirectory": "/var/log"}
+	if userScope {
+		managed = map[string]string{}
+		if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+			managed["RuntimeDirectory"] = dir
+		}
+	}
+	dirs := []string{filepath.Dir(pidFile), filepath.Dir(socket), core.DefaultStateDir()}
+	for _, dir := range []string{cfg.Diagnostics.Baseline.Dir, cfg.Diagnostics.Remote.WorkDir, cfg.Diagnostics.Bundle.OutputDir, cfg.Service.Profiling.Dir} {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+next:
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+		for directive, base := range managed {
+			if filepath.Dir(dir) == filepath.Clean(base) {
+				if name := filepath.Base(dir); !slices.Contains(opts.Directories[directive], name) {
+					opts.Directories[directive] = append(opts.Directories[directive], name)
+				}
+				continue next
+			}
+		}
+		opts.ReadWritePaths = append(opts.ReadWritePaths, dir)
+	}
+	opts.ReadWritePaths = outermostDirs(opts.ReadWritePaths)
+	return opts, nil
+}
+
+// outermostDirs sorts dirs and drops duplicates and directories inside another one.
+func outermostDirs(dirs []string) []string {
+	slices.Sort(dirs)
+	var out []string
+	for _, dir := range dirs {
+		if n := len(out); n > 0 && (dir == out[n-1] || strings.HasPrefix(dir, out[n-1]+string(filepath.Separator))) {
+			continue
+		}
+		out = append(out, dir)
+	}
+	return out
+}
+
+// RenderUnit returns the content of srediag.service for opts.
+//
+// Parameters:
+//   - opts: Unit to render.
+//
+// Returns:
+//   - string: The unit file.
+func RenderUnit(opts UnitOptions) string {
+	var b strings.Builder
+	line := func(key string, values ...string) {
+		fmt.Fprintf(&b, "%s=%s\n", key, strings.Join(values, " "))
+	}
+	command := func(verb string) string {
+		args := []string{unitQuote(opts.Executable), "service", verb}
+		if opts.ConfigFile != "" {
+			args = append(args, "--config", unitQuote(opts.ConfigFile))
+		}
+		return strings.Join(args, " ")
+	}
+
+	b.WriteString("# Generated by 'srediag service install-unit'; re-run it instead of editing this file.\n")
+	b.WriteString("[Unit]\n")
+	line("Description", "SREDIAG diagnostics agent")
+	line("Documentation", "https://github.com/srediag/srediag")
+	if !opts.User {
+		line("Wants", "network-online.target")
+		line("After", "network-online.target")
+	}
+
+	b.WriteString("\n[Service]\n")
+	line("Type", "simple")
+	line("ExecStart", command("start"))
+	line("ExecReload", command("reload"))
+	line("Restart", "on-failure")
+	line("RestartSec", "5s")
+	shutdownTimeout := cmp.Or(opts.ShutdownTimeout, stopTimeout)
+	line("TimeoutStopSec", fmt.Sprintf("%ds", int(shutdownTimeout.Round(time.Second).Seconds())+15))
+	line("Environment", unitQuote("SREDIAG_SERVICE_PID_FILE="+opts.PIDFile))
+	line("Environment", unitQuote("SREDIAG_SERVICE_SOCKET="+opts.Socket))
+	if opts.RunAs != "" && !opts.User {
+		line("User", opts.RunAs)
+	}
+	for _, directive := range []string{"RuntimeDirectory", "StateDirectory", "LogsDirectory"} {
+		if names := opts.Directories[directive]; len(names) > 0 {
+			line(directive, names...)
+		}
+	}
+	if len(opts.ReadWritePaths) > 0 {
+		paths := make([]string, len(opts.ReadWritePaths))
+		for i, path := range opts.ReadWritePaths {
+			paths[i] = unitQuote(path)
+		}
+		line("ReadWritePaths", paths...)
+	}
+	if opts.MemoryLimitMiB > 0 {
+		line("MemoryMax", strconv.Itoa(unitMemoryMax(opts.MemoryLimitMiB))+"M")
+	}
+	if opts.CPUQuotaPct > 0 {
+		line("CPUQuota", strconv.Itoa(opts.CPUQuotaPct)+"%")
+	}
+	line("LimitMEMLOCK", "16M")
+
+	b.WriteString("\n# Hardening\n")
+	line("NoNewPrivileges", "yes")
+	line("ProtectSystem", "strict")
+	line("PrivateTmp", "yes")
+	line("SystemCallArchitectures", "native")
+	if !opts.User {
+		line("ProtectHome", "read-only")
+		line("ProtectKernelTunables", "yes")
+		line("ProtectKernelModules", "yes")
+		line("ProtectControlGroups", "yes")
+		line("RestrictSUIDSGID", "yes")
+		line("RestrictRealtime", "yes")
+		line("LockPersonality", "yes")
+		line("CapabilityBoundingSet", unitCapabilities...)
+		if opts.RunAs != "" {
+			line("AmbientCapabilities", unitCapabilities...)
+		}
+	}
+
+	b.WriteString("\n[Install]\n")
+	if opts.User {
+		line("WantedBy", "default.target")
+	} else {
+		line("WantedBy", "multi-user.target")
+	}
+	return b.String()
+}
+
+// unitMemoryMax returns the MemoryMax= of the unit in MiB: the collector memory limit plus
+// unitMemoryHeadroomPct of it, at least unitMemoryHeadroomMinMiB.
+func unitMemoryMax(limitMiB int) int {
+	return limitMiB + max(limitMiB*unitMemoryHeadroomPct/100, unitMemoryHeadroomMinMiB)
+}
+
+// unitQuote escapes % specifiers and quotes s if it contains blanks or quotes.
+func unitQuote(s string) string {
+	s = strings.ReplaceAll(s, "%", "%%")
+	if !strings.ContainsAny(s, " \t\"'\\") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// UnitPath returns where the unit is installed: /etc/systemd/system/srediag.service, or
+// $XDG_CONFIG_HOME/systemd/user/srediag.service for a user unit.
+//
+// Parameters:
+//   - userScope: Whether to return the user unit path.
+//
+// Returns:
+//   - string: Path of the unit file.
+//   - error: If the user config directory cannot be determined.
+func UnitPath(userScope bool) (string, error) {
+	if !userScope {
+		return filepath.Join(systemUnitDir, unitName), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user unit directory: %w", err)
+	}
+	return filepath.Join(dir, "systemd", "user", unitName), nil
+}
+
+// InstallSystemdUnit writes the unit, creates its ReadWritePaths, reloads systemd and enables the unit.
+//
+// Parameters:
+//   - ctx: Context of the systemctl calls.
+//   - opts: Unit to install.
+//   - start: Whether to also start the agent (systemctl enable --now).
+//
+// Returns:
+//   - string: Path of the installed unit.
+//   - error: If the unit cannot be written or systemctl fails.
+func InstallSystemdUnit(ctx context.Context, opts UnitOptions, start bool) (string, error) {
+	path, err := UnitPath(opts.User)
+	if err != nil {
+		return "", err
+	}
+	uid, gid := -1, -1
+	if opts.RunAs != "" && !opts.User {
+		account, err := user.Lookup(opts.RunAs)
+		if err != nil {
+			return "", fmt.Errorf("failed to look up --run-as user: %w", err)
+		}
+		uid, _ = strconv.Atoi(account.Uid)
+		gid, _ = strconv.Atoi(account.Gid)
+	}
+	for _, dir := range opts.ReadWritePaths {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return "", fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		if uid >= 0 {
+			if err := os.Chown(dir, uid, gid); err != nil {
+				return "", fmt.Errorf("failed to hand %s to %s: %w", dir, opts.RunAs, err)
+			}
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create unit directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(RenderUnit(opts)), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write unit: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return "", fmt.Errorf("failed to write unit: %w", err)
+	}
+
+	if err := systemctl(ctx, opts.User, "daemon-reload"); err != nil {
+		return path, err
+	}
+	enable := []string{"enable", unitName}
+	if start {
+		enable = []string{"enable", "--now", unitName}
+	}
+	return path, systemctl(ctx, opts.User, enable...)
+}
+
+// UninstallSystemdUnit stops and disables the unit, removes it and reloads systemd. The directories of the agent are
+// left in place.
+//
+// Parameters:
+//   - ctx: Context of the systemctl calls.
+//   - userScope: Whether to remove the user unit.
+//
+// Returns:
+//   - string: Path of the removed unit.
+//   - error: If the unit is not installed, or it cannot be removed or systemctl fails.
+func UninstallSystemdUnit(ctx context.Context, userScope bool) (string, error) {
+	path, err := UnitPath(userScope)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return path, fmt.Errorf("systemd unit %s is not installed", path)
+		}
+		return path, fmt.Errorf("failed to read unit: %w", err)
+	}
+	if err := systemctl(ctx, userScope, "disable", "--now", unitName); err != nil {
+		return path, err
+	}
+	if err := os.Remove(path); err != nil {
+		return path, fmt.Errorf("failed to remove unit: %w", err)
+	}
+	return path, systemctl(ctx, userScope, "daemon-reload")
+}
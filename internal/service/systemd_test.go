@@ -0,0 +1,165 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// fakeSystemd points the unit directories at temp dirs and records systemctl calls instead of running them.
+func fakeSystemd(t *testing.T) (unitDir string, calls *[]string) {
+	t.Helper()
+	unitDir = t.TempDir()
+	oldDir, oldSystemctl, oldExe := systemUnitDir, systemctl, daemonExecutable
+	t.Cleanup(func() { systemUnitDir, systemctl, daemonExecutable = oldDir, oldSystemctl, oldExe })
+	systemUnitDir = unitDir
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(unitDir, "config"))
+	calls = &[]string{}
+	systemctl = func(_ context.Context, userScope bool, args ...string) error {
+		if userScope {
+			args = append([]string{"--user"}, args...)
+		}
+		*calls = append(*calls, strings.Join(args, " "))
+		return nil
+	}
+	daemonExecutable = func() (string, error) { return "/usr/bin/srediag", nil }
+	return unitDir, calls
+}
+
+func TestRenderUnit_System(t *testing.T) {
+	fakeSystemd(t)
+	cfg := core.NewConfig()
+	cfg.Service.PIDFile = "/run/srediag/srediag.pid"
+	cfg.Service.Socket = "/run/srediag/srediag.sock"
+	cfg.Collector.MemoryLimitMiB = 512
+	cfg.Security.Runtime.CPUGuardPct = 50
+	cfg.Diagnostics.Baseline.Dir = "/var/lib/srediag-baselines"
+	cfg.Diagnostics.Remote.WorkDir = "/srv/srediag jobs/%i"
+	cfg.Service.ShutdownTimeout = "2m"
+
+	opts, err := NewUnitOptions(cfg, "/etc/srediag/srediag.yaml", false)
+	require.NoError(t, err)
+	opts.RunAs = "srediag"
+	unit := RenderUnit(opts)
+
+	for _, want := range []string{
+		"ExecStart=/usr/bin/srediag service start --config /etc/srediag/srediag.yaml\n",
+		"ExecReload=/usr/bin/srediag service reload --config /etc/srediag/srediag.yaml\n",
+		"Environment=SREDIAG_SERVICE_SOCKET=/run/srediag/srediag.sock\n",
+		"User=srediag\n",
+		"RuntimeDirectory=srediag\n",
+		"ReadWritePaths=\"/srv/srediag jobs/%%i\"\n",
+		"MemoryMax=640M\n", // 512 MiB limit + 25 % headroom
+		"CPUQuota=50%\n",
+		"TimeoutStopSec=135s\n",
+		"NoNewPrivileges=yes\n",
+		"ProtectSystem=strict\n",
+		"CapabilityBoundingSet=CAP_BPF CAP_DAC_READ_SEARCH CAP_NET_BIND_SERVICE CAP_PERFMON CAP_SYS_PTRACE CAP_SYS_RESOURCE\n",
+		"AmbientCapabilities=CAP_BPF",
+		"WantedBy=multi-user.target\n",
+	} {
+		assert.Contains(t, unit, want)
+	}
+	assert.Contains(t, opts.Directories["StateDirectory"], "srediag-baselines")
+}
+
+func TestRenderUnit_User(t *testing.T) {
+	fakeSystemd(t)
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	cfg := core.NewConfig()
+	cfg.Service.PIDFile = filepath.Join(runtimeDir, "srediag", "srediag.pid")
+	cfg.Service.Socket = filepath.Join(runtimeDir, "srediag", "srediag.sock")
+
+	opts, err := NewUnitOptions(cfg, "", true)
+	require.NoError(t, err)
+	unit := RenderUnit(opts)
+	assert.Contains(t, unit, "ExecStart=/usr/bin/srediag service start\n")
+	assert.Contains(t, unit, "RuntimeDirectory=srediag\n")
+	assert.Contains(t, unit, "ProtectSystem=strict\n")
+	assert.Contains(t, unit, "WantedBy=default.target\n")
+	assert.NotContains(t, unit, "CapabilityBoundingSet", "a user manager cannot grant capabilities")
+	assert.NotContains(t, unit, "MemoryMax", "no collector.memory_limit_mib")
+	assert.Contains(t, unit, "TimeoutStopSec=45s\n")
+}
+
+func TestUnitMemoryMax(t *testing.T) {
+	for limit, want := range map[int]int{
+		128:  192,  // 25 % is below the 64 MiB floor
+		256:  320,  // 25 % equals the floor
+		2048: 2560, // 25 % headroom
+	} {
+		assert.Equal(t, want, unitMemoryMax(limit), "memory_limit_mib %d", limit)
+	}
+}
+
+func TestCLI_InstallUnit(t *testing.T) {
+	unitDir, calls := fakeSystemd(t)
+	cfg := daemonConfig(t)
+	cfg.Diagnostics.Bundle.OutputDir = filepath.Join(t.TempDir(), "bundles")
+	appCtx := &core.AppContext{Config: cfg}
+	cmd := &cobra.Command{}
+	cmd.Flags().String("config", "srediag.yaml", "")
+	cmd.Flags().Bool("user", false, "")
+	cmd.Flags().Bool("dry-run", true, "")
+	cmd.Flags().Bool("now", true, "")
+	cmd.Flags().String("run-as", "", "")
+	cmd.SetContext(context.Background())
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	require.NoError(t, CLI_InstallUnit(appCtx, cmd, nil))
+	abs, _ := filepath.Abs("srediag.yaml")
+	assert.Contains(t, out.String(), "--config "+abs)
+	assert.NoFileExists(t, filepath.Join(unitDir, unitName), "--dry-run writes nothing")
+	assert.Empty(t, *calls)
+
+	require.NoError(t, cmd.Flags().Set("dry-run", "false"))
+	out.Reset()
+	require.NoError(t, CLI_InstallUnit(appCtx, cmd, nil))
+	written, err := os.ReadFile(filepath.Join(unitDir, unitName))
+	require.NoError(t, err)
+	assert.Contains(t, string(written), "ProtectSystem=strict")
+	assert.DirExists(t, cfg.Diagnostics.Bundle.OutputDir, "ReadWritePaths are created")
+	assert.Equal(t, []string{"daemon-reload", "enable --now srediag.service"}, *calls)
+
+	*calls = nil
+	require.NoError(t, CLI_UninstallUnit(appCtx, cmd, nil))
+	assert.NoFileExists(t, filepath.Join(unitDir, unitName))
+	assert.Equal(t, []string{"disable --now srediag.service", "daemon-reload"}, *calls)
+	assert.ErrorContains(t, CLI_UninstallUnit(appCtx, cmd, nil), "is not installed")
+
+	require.NoError(t, cmd.Flags().Set("user", "true"))
+	require.NoError(t, cmd.Flags().Set("run-as", "nobody"))
+	assert.ErrorContains(t, CLI_InstallUnit(appCtx, cmd, nil), "--run-as only applies to system units")
+}
+
+func TestCLI_InstallUnit_UserScope(t *testing.T) {
+	unitDir, calls := fakeSystemd(t)
+	appCtx := &core.AppContext{Config: daemonConfig(t)}
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("user", true, "")
+	cmd.Flags().Bool("dry-run", false, "")
+	cmd.SetContext(context.Background())
+	cmd.SetOut(&bytes.Buffer{})
+
+	require.NoError(t, CLI_InstallUnit(appCtx, cmd, nil))
+	assert.FileExists(t, filepath.Join(unitDir, "config", "systemd", "user", unitName))
+	assert.Equal(t, []string{"--user daemon-reload", "--user enable srediag.service"}, *calls)
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	require.NoError(t, cmd.Flags().Set("dry-run", "true"))
+	require.NoError(t, CLI_UninstallUnit(appCtx, cmd, nil))
+	assert.Contains(t, out.String(), "systemctl --user disable --now srediag.service\n")
+	assert.FileExists(t, filepath.Join(unitDir, "config", "systemd", "user", unitName), "--dry-run removes nothing")
+}
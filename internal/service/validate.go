@@ -0,0 +1,780 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/provider/envprovider"
+	"go.opentelemetry.io/collector/confmap/xconfmap"
+	"go.opentelemetry.io/collector/connector"
+	"go.opentelemetry.io/collector/otelcol"
+	"go.opentelemetry.io/collector/pipeline"
+	"gopkg.in/yaml.v3"
+
+	"github.com/srediag/srediag/internal/core"
+	"github.com/srediag/srediag/internal/diagnose"
+)
+
+// Package service provides the core service orchestration, lifecycle management, and CLI stubs for the SREDIAG collector service.
+//
+// This file defines the offline validation of a collector config ('srediag service validate'). The config files are
+// strictly decoded (unknown keys in the config, service and pipeline sections are errors), every component ID is
+// resolved against the built-in and plugin factories, every component config is unmarshalled and validated by its
+// factory, and the pipelines are checked for references, signal support and unused components. Nothing is started.
+//
+// Usage:
+//   - Call Service.ValidateCollectorConfig with the config path; the report lists every issue with file, line and
+//     field path. Use WriteValidationReport to print it and ValidationReport.Err to fail on issues.
+//
+// Best Practices:
+//   - Validate with the same plugins.exec_dir as the agent, or plugin components are reported as unknown.
+
+// ValidationIssue is one problem found in a collector config.
+//
+// Fields:
+//   - File, Line: Where the offending setting is written; Line is 0 if unknown.
+//   - Path: Field path of the setting, e.g. "receivers.otlp.protocols.grpc" or "service.pipelines.traces.exporters[0]".
+//   - Message: What is wrong.
+type ValidationIssue struct {
+	File    string `json:"file,omitempty" yaml:"file,omitempty"`
+	Line    int    `json:"line,omitempty" yaml:"line,omitempty"`
+	Path    string `json:"path,omitempty" yaml:"path,omitempty"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// String formats the issue as "file:line: path: message".
+func (i ValidationIssue) String() string {
+	var b strings.Builder
+	if i.File != "" {
+		b.WriteString(i.File)
+		if i.Line > 0 {
+			b.WriteString(":" + strconv.Itoa(i.Line))
+		}
+		b.WriteString(": ")
+	}
+	if i.Path != "" {
+		b.WriteString(i.Path + ": ")
+	}
+	b.WriteString(i.Message)
+	return b.String()
+}
+
+// ValidationReport is the result of ValidateCollectorConfig.
+//
+// Fields:
+//   - Config: The validated collector config file.
+//   - Pipelines, Components: How many pipelines and components the merged config defines.
+//   - Issues: Every problem found, ordered by file and line; empty if the config is valid.
+type ValidationReport struct {
+	Config     string            `json:"config" yaml:"config"`
+	Pipelines  int               `json:"pipelines" yaml:"pipelines"`
+	Components int               `json:"components" yaml:"components"`
+	Issues     []ValidationIssue `json:"issues,omitempty" yaml:"issues,omitempty"`
+}
+
+// Err returns a *ValidationError if the report has issues.
+//
+// Returns:
+//   - error: nil if the config is valid.
+func (r *ValidationReport) Err() error {
+	if len(r.Issues) == 0 {
+		return nil
+	}
+	return &ValidationError{Config: r.Config, Issues: r.Issues}
+}
+
+// ValidationError reports an invalid collector config.
+type ValidationError struct {
+	Config string
+	Issues []ValidationIssue
+}
+
+// Error implements error.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid collector config %s: %d problem(s) found", e.Config, len(e.Issues))
+}
+
+// SrediagExitCode maps an invalid config to diagnose.ExitVerification (2).
+func (e *ValidationError) SrediagExitCode() int { return diagnose.ExitVerification }
+
+// collectorSchema, collectorServiceSchema and collectorPipelineSchema are the strictly decoded parts of a collector
+// config; component settings are checked by their factories instead.
+type collectorSchema struct {
+	Receivers  map[string]any         `yaml:"receivers"`
+	Processors map[string]any         `yaml:"processors"`
+	Exporters  map[string]any         `yaml:"exporters"`
+	Extensions map[string]any         `yaml:"extensions"`
+	Connectors map[string]any         `yaml:"connectors"`
+	Service    collectorServiceSchema `yaml:"service"`
+}
+
+type collectorServiceSchema struct {
+	Extensions []string                           `yaml:"extensions"`
+	Pipelines  map[string]collectorPipelineSchema `yaml:"pipelines"`
+	Telemetry  map[string]any                     `yaml:"telemetry"`
+}
+
+type collectorPipelineSchema struct {
+	Receivers  []string `yaml:"receivers"`
+	Processors []string `yaml:"processors"`
+	Exporters  []string `yaml:"exporters"`
+}
+
+// pipelineLists are the component lists of a pipeline.
+var pipelineLists = []string{"receivers", "processors", "exporters"}
+
+// pipelineSignals are the signals a pipeline can carry.
+var pipelineSignals = []pipeline.Signal{pipeline.SignalTraces, pipeline.SignalMetrics, pipeline.SignalLogs}
+
+var (
+	// yamlErrorRe matches YAML errors with a line, optionally prefixed by the file of decodeYAMLFile.
+	yamlErrorRe = regexp.MustCompile(`^(?:failed to parse (.+?): )?(?:yaml: )?line (\d+): (.*)$`)
+	// invalidKeysRe and fieldErrorRe match the lines of a mapstructure decoding error.
+	invalidKeysRe = regexp.MustCompile(`^'([^']*)' has invalid keys: (.*)$`)
+	fieldErrorRe  = regexp.MustCompile(`^(?:error decoding )?'([^']*)':? (.*)$`)
+)
+
+// yamlPosition is a line in a config file.
+type yamlPosition struct {
+	file string
+	line int
+}
+
+// configInclude is a file included with '!include' at a field path.
+type configInclude struct {
+	file string
+	path string
+}
+
+// collectorValidation collects the issues of one config and where each of its field paths is set.
+type collectorValidation struct {
+	config    string
+	positions map[string]yamlPosition
+	paths     map[yamlPosition]string
+	refs      map[string]string
+	issues    []ValidationIssue
+}
+
+// ValidateCollectorConfig validates a collector config offline against the service's component factories.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts.
+//   - path: Collector config file (collector.config_path).
+//
+// Returns:
+//   - *ValidationReport: All issues found; use Err to turn them into an error.
+func (s *Service) ValidateCollectorConfig(ctx context.Context, path string) *ValidationReport {
+	v := &collectorValidation{
+		config:    path,
+		positions: make(map[string]yamlPosition),
+		paths:     make(map[yamlPosition]string),
+	}
+	report := &ValidationReport{Config: path}
+	if v.index(path, "", true) {
+		if conf, err := LoadCollectorConfig(path); err != nil {
+			v.addError(path, "", err)
+		} else {
+			report.Pipelines, report.Components = v.check(ctx, s, conf)
+		}
+	}
+	slices.SortStableFunc(v.issues, func(a, b ValidationIssue) int {
+		if c := strings.Compare(a.File, b.File); c != 0 {
+			return c
+		}
+		return a.Line - b.Line
+	})
+	report.Issues = v.issues
+	return report
+}
+
+// check runs the semantic checks on the merged config and returns its number of pipelines and components.
+func (v *collectorValidation) check(ctx context.Context, s *Service, conf map[string]any) (int, int) {
+	pluginsDir := filepath.Join(filepath.Dir(v.config), "plugins.d")
+	components := 0
+	for _, section := range collectorSections {
+		settings, _ := conf[section].(map[string]any)
+		components += len(settings)
+		for _, name := range slices.Sorted(maps.Keys(settings)) {
+			typ, _, _ := strings.Cut(name, "/")
+			if file := filepath.Join(pluginsDir, section, typ+"@service.yaml"); fileExists(file) {
+				v.index(file, section+"."+name, false)
+			}
+		}
+	}
+	svcConf, _ := conf["service"].(map[string]any)
+	pipelines, _ := svcConf["pipelines"].(map[string]any)
+
+	exts, _ := conf["extensions"].(map[string]any)
+	if _, err := DiagExporterConfig(&ServiceConfig{Extensions: exts}); err != nil {
+		v.addDecodeError("extensions."+diagnose.DiagExporterType.String(), errors.Unwrap(err))
+	}
+	conf = withoutDiagExporter(conf)
+	factories, err := s.collectorFactories()
+	if err != nil {
+		v.add("", "%v", err)
+		return len(pipelines), components
+	}
+	before := len(v.issues)
+	v.findRefs(conf)
+	v.checkComponents(ctx, conf, factories)
+	v.checkPipelines(conf, factories)
+	v.checkUnused(conf)
+	if len(v.issues) == before {
+		// What is left is checked by otelcol itself, e.g. connector cycles and service.telemetry.
+		for _, inst := range sortedInstances(slices.Collect(maps.Values(splitCollectorConfig(conf)))) {
+			if err := s.validateInstance(ctx, inst); err != nil {
+				v.add("service", "%v", err)
+			}
+		}
+	}
+	return len(pipelines), components
+}
+
+// findRefs records the first reference of every component by a pipeline or service.extensions, keyed by
+// "<section>/<id>".
+func (v *collectorValidation) findRefs(conf map[string]any) {
+	v.refs = make(map[string]string)
+	svcConf, _ := conf["service"].(map[string]any)
+	connectors, _ := conf["connectors"].(map[string]any)
+	for i, ext := range stringList(svcConf["extensions"]) {
+		if _, ok := v.refs["extensions/"+ext]; !ok {
+			v.refs["extensions/"+ext] = fmt.Sprintf("service.extensions[%d]", i)
+		}
+	}
+	pipelines, _ := svcConf["pipelines"].(map[string]any)
+	for _, name := range slices.Sorted(maps.Keys(pipelines)) {
+		p, _ := pipelines[name].(map[string]any)
+		for _, list := range pipelineLists {
+			for i, ref := range stringList(p[list]) {
+				section := list
+				if _, ok := connectors[ref]; ok && list != "processors" {
+					section = "connectors"
+				}
+				if _, ok := v.refs[section+"/"+ref]; !ok {
+					v.refs[section+"/"+ref] = fmt.Sprintf("service.pipelines.%s.%s[%d]", name, list, i)
+				}
+			}
+		}
+	}
+}
+
+// checkComponents resolves every component against its factory, then unmarshals and validates its config.
+func (v *collectorValidation) checkComponents(ctx context.Context, conf map[string]any, factories otelcol.Factories) {
+	resolver, err := confmap.NewResolver(confmap.ResolverSettings{
+		URIs:              []string{collectorConfigScheme + ":validate"},
+		ProviderFactories: []confmap.ProviderFactory{newCollectorConfigProviderFactory(conf), envprovider.NewFactory()},
+	})
+	if err != nil {
+		v.add("", "failed to create config resolver: %v", err)
+		return
+	}
+	defer func() { _ = resolver.Shutdown(ctx) }()
+	resolved, err := resolver.Resolve(ctx)
+	if err != nil {
+		v.add("", "failed to resolve config: %v", err)
+		return
+	}
+	for _, section := range collectorSections {
+		kind := strings.TrimSuffix(section, "s")
+		components, _ := conf[section].(map[string]any)
+		for _, name := range slices.Sorted(maps.Keys(components)) {
+			path := v.componentPath(section, name)
+			var id component.ID
+			if err := id.UnmarshalText([]byte(name)); err != nil {
+				v.add(path, "invalid %s ID: %v", kind, err)
+				continue
+			}
+			factory := sectionFactory(factories, section, id.Type())
+			if factory == nil {
+				v.add(path, "unknown %s type %q: it is not built in and no loaded plugin provides it", kind, id.Type())
+				continue
+			}
+			cfg := factory.CreateDefaultConfig()
+			sub, err := resolved.Sub(section + confmap.KeyDelimiter + name)
+			if err == nil {
+				err = sub.Unmarshal(cfg)
+			}
+			if err != nil {
+				v.addDecodeError(section+"."+name, err)
+				continue
+			}
+			if err := xconfmap.Validate(cfg); err != nil {
+				v.addValidateError(section+"."+name, err)
+			}
+		}
+	}
+}
+
+// checkPipelines checks the pipeline IDs, that every pipeline has receivers and exporters, that every referenced
+// component supports the pipeline's signal and that connectors join pipelines they can convert between.
+func (v *collectorValidation) checkPipelines(conf map[string]any, factories otelcol.Factories) {
+	svcConf, _ := conf["service"].(map[string]any)
+	pipelines, _ := svcConf["pipelines"].(map[string]any)
+	connectors, _ := conf["connectors"].(map[string]any)
+	asExporter := make(map[string][]pipeline.Signal)
+	asReceiver := make(map[string][]pipeline.Signal)
+	for _, name := range slices.Sorted(maps.Keys(pipelines)) {
+		path := "service.pipelines." + name
+		var pid pipeline.ID
+		if err := pid.UnmarshalText([]byte(name)); err != nil {
+			v.add(path, "invalid pipeline ID: %v", err)
+			continue
+		}
+		signal := pid.Signal()
+		if !slices.Contains(pipelineSignals, signal) {
+			v.add(path, "unsupported signal %q (expected traces, metrics or logs)", signal)
+			continue
+		}
+		p, _ := pipelines[name].(map[string]any)
+		for _, list := range pipelineLists {
+			kind := strings.TrimSuffix(list, "s")
+			refs := stringList(p[list])
+			if len(refs) == 0 && list != "processors" {
+				v.add(path, "pipeline has no %s", list)
+			}
+			for i, ref := range refs {
+				refPath := fmt.Sprintf("%s.%s[%d]", path, list, i)
+				if slices.Index(refs, ref) < i {
+					v.add(refPath, "%s %q is listed more than once", kind, ref)
+					continue
+				}
+				if _, ok := connectors[ref]; ok && list != "processors" {
+					if list == "receivers" {
+						asReceiver[ref] = append(asReceiver[ref], signal)
+					} else {
+						asExporter[ref] = append(asExporter[ref], signal)
+					}
+					continue
+				}
+				var id component.ID
+				if id.UnmarshalText([]byte(ref)) != nil {
+					continue // reported with the component
+				}
+				factory, ok := sectionFactory(factories, list, id.Type()).(signalFactory)
+				if ok && signalStability(factory, signal) == component.StabilityLevelUndefined {
+					v.add(refPath, "%s %q does not support %s", kind, ref, signal)
+				}
+			}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(connectors)) {
+		path := v.componentPath("connectors", name)
+		from, to := uniqueSignals(asExporter[name]), uniqueSignals(asReceiver[name])
+		switch {
+		case len(from) == 0 && len(to) == 0:
+			continue // reported as unused
+		case len(from) == 0:
+			v.add(path, "connector %q is used as a receiver but not as an exporter of any pipeline", name)
+			continue
+		case len(to) == 0:
+			v.add(path, "connector %q is used as an exporter but not as a receiver of any pipeline", name)
+			continue
+		}
+		var id component.ID
+		if id.UnmarshalText([]byte(name)) != nil {
+			continue
+		}
+		factory, ok := factories.Connectors[id.Type()]
+		if !ok {
+			continue
+		}
+		for _, signal := range from {
+			if !slices.ContainsFunc(to, func(t pipeline.Signal) bool {
+				return connectorStability(factory, signal, t) != component.StabilityLevelUndefined
+			}) {
+				v.add(path, "connector %q is used as an exporter of %s but cannot feed any of its receiver pipelines (%s)", name, signal, signalNames(to))
+			}
+		}
+		for _, signal := range to {
+			if !slices.ContainsFunc(from, func(f pipeline.Signal) bool {
+				return connectorStability(factory, f, signal) != component.StabilityLevelUndefined
+			}) {
+				v.add(path, "connector %q is used as a receiver of %s but cannot be fed by any of its exporter pipelines (%s)", name, signal, signalNames(from))
+			}
+		}
+	}
+}
+
+// checkUnused reports components that are defined but not used, and extensions listed more than once.
+func (v *collectorValidation) checkUnused(conf map[string]any) {
+	svcConf, _ := conf["service"].(map[string]any)
+	listed := stringList(svcConf["extensions"])
+	for i, ext := range listed {
+		if slices.Index(listed, ext) < i {
+			v.add(fmt.Sprintf("service.extensions[%d]", i), "extension %q is listed more than once", ext)
+		}
+	}
+	for _, section := range collectorSections {
+		kind := strings.TrimSuffix(section, "s")
+		components, _ := conf[section].(map[string]any)
+		for _, name := range slices.Sorted(maps.Keys(components)) {
+			if _, ok := v.refs[section+"/"+name]; ok {
+				continue
+			}
+			if section == "extensions" {
+				v.add(section+"."+name, "extension %q is defined but not listed in service.extensions", name)
+			} else {
+				v.add(section+"."+name, "%s %q is defined but not used in any pipeline", kind, name)
+			}
+		}
+	}
+}
+
+// componentPath returns the field path to report for a component: its settings, or its first reference if it is
+// only named by a pipeline or service.extensions.
+func (v *collectorValidation) componentPath(section, name string) string {
+	path := section + "." + name
+	if _, ok := v.positions[path]; !ok && v.refs[section+"/"+name] != "" {
+		return v.refs[section+"/"+name]
+	}
+	return path
+}
+
+// index parses a config file with its includes and records where each field is set, below prefix. With strict, the
+// file and its includes are also strictly decoded against the schema of their place in the config.
+func (v *collectorValidation) index(file, prefix string, strict bool) bool {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		v.addAt(yamlPosition{file: file}, prefix, fmt.Sprintf("failed to read collector config: %v", err))
+		return false
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		v.addError(file, prefix, err)
+		return false
+	}
+	if len(doc.Content) == 0 {
+		return true
+	}
+	files := make(map[*yaml.Node]string)
+	if err := resolveIncludes(&doc, filepath.Dir(file), []string{file}, files); err != nil {
+		v.addError(file, prefix, err)
+		return false
+	}
+	var includes []configInclude
+	v.walk(doc.Content[0], file, prefix, files, &includes)
+	if strict {
+		v.strict(file, data, prefix)
+		for _, inc := range includes {
+			if data, err := os.ReadFile(inc.file); err == nil {
+				v.strict(inc.file, data, inc.path)
+			}
+		}
+	}
+	return true
+}
+
+// walk records the position of every mapping key and sequence item below node.
+func (v *collectorValidation) walk(node *yaml.Node, file, path string, files map[*yaml.Node]string, includes *[]configInclude) {
+	if included, ok := files[node]; ok {
+		file = included
+		*includes = append(*includes, configInclude{file: file, path: path})
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			p := joinPath(path, key.Value)
+			v.record(p, yamlPosition{file: file, line: key.Line})
+			v.walk(node.Content[i+1], file, p, files, includes)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			v.record(p, yamlPosition{file: file, line: item.Line})
+			v.walk(item, file, p, files, includes)
+		}
+	}
+}
+
+// record remembers the first position of a field path.
+func (v *collectorValidation) record(path string, pos yamlPosition) {
+	if _, ok := v.positions[path]; !ok {
+		v.positions[path] = pos
+	}
+	if _, ok := v.paths[pos]; !ok {
+		v.paths[pos] = path
+	}
+}
+
+// strict decodes a config file with core.StrictYAMLUnmarshal against the schema of its place in the config.
+// '!include' tags are skipped; the included files are decoded on their own.
+func (v *collectorValidation) strict(file string, data []byte, path string) {
+	schema := collectorSchemaAt(path)
+	if schema == nil {
+		return
+	}
+	var typeErr *yaml.TypeError
+	if !errors.As(core.StrictYAMLUnmarshal(data, schema), &typeErr) {
+		return
+	}
+	for _, msg := range typeErr.Errors {
+		m := yamlErrorRe.FindStringSubmatch(msg)
+		if m == nil || strings.Contains(m[3], "cannot unmarshal !include") {
+			continue
+		}
+		line, _ := strconv.Atoi(m[2])
+		text := m[3]
+		if field, ok := strings.CutPrefix(text, "field "); ok {
+			if name, _, found := strings.Cut(field, " not found in type "); found {
+				text = fmt.Sprintf("unknown field %q", name)
+			}
+		}
+		pos := yamlPosition{file: file, line: line}
+		v.addAt(pos, v.paths[pos], text)
+	}
+}
+
+// collectorSchemaAt returns the strict schema of a field path, or nil for free-form settings.
+func collectorSchemaAt(path string) any {
+	switch path {
+	case "":
+		return &collectorSchema{}
+	case "service":
+		return &collectorServiceSchema{}
+	case "service.extensions":
+		return &[]string{}
+	case "service.pipelines":
+		return &map[string]collectorPipelineSchema{}
+	}
+	rest, ok := strings.CutPrefix(path, "service.pipelines.")
+	if !ok {
+		return nil
+	}
+	if _, list, ok := strings.Cut(rest, "."); ok {
+		if slices.Contains(pipelineLists, list) {
+			return &[]string{}
+		}
+		return nil
+	}
+	return &collectorPipelineSchema{}
+}
+
+// add records an issue at the position of a field path.
+func (v *collectorValidation) add(path, format string, args ...any) {
+	v.addAt(v.locate(path), path, fmt.Sprintf(format, args...))
+}
+
+// addAt records an issue at a position.
+func (v *collectorValidation) addAt(pos yamlPosition, path, message string) {
+	v.issues = append(v.issues, ValidationIssue{File: pos.file, Line: pos.line, Path: path, Message: message})
+}
+
+// addError records a YAML or include error, taking file and line from the message where present.
+func (v *collectorValidation) addError(file, path string, err error) {
+	msg := err.Error()
+	m := yamlErrorRe.FindStringSubmatch(msg)
+	if m == nil {
+		v.addAt(yamlPosition{file: file}, path, msg)
+		return
+	}
+	if m[1] != "" {
+		file = m[1]
+	}
+	line, _ := strconv.Atoi(m[2])
+	v.addAt(yamlPosition{file: file, line: line}, path, m[3])
+}
+
+// addDecodeError records the errors of unmarshalling a component config, one issue per offending field.
+func (v *collectorValidation) addDecodeError(path string, err error) {
+	for _, line := range strings.Split(err.Error(), "\n") {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "* ")
+		switch {
+		case line == "" || strings.HasPrefix(line, "decoding failed"):
+		case invalidKeysRe.MatchString(line):
+			m := invalidKeysRe.FindStringSubmatch(line)
+			for _, key := range strings.Split(m[2], ", ") {
+				v.add(joinPath(joinPath(path, m[1]), key), "unknown field %q", key)
+			}
+		case fieldErrorRe.MatchString(line):
+			m := fieldErrorRe.FindStringSubmatch(line)
+			v.add(joinPath(path, m[1]), "%s", m[2])
+		default:
+			v.add(path, "%s", line)
+		}
+	}
+}
+
+// addValidateError records the errors of xconfmap.Validate, one issue per field.
+func (v *collectorValidation) addValidateError(path string, err error) {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, e := range errs {
+		msg, field := e.Error(), ""
+		if i := strings.Index(msg, ": "); i > 0 && !strings.Contains(msg[:i], " ") {
+			field, msg = strings.ReplaceAll(msg[:i], confmap.KeyDelimiter, "."), msg[i+2:]
+		}
+		v.add(joinPath(path, field), "%s", msg)
+	}
+}
+
+// locate returns the position of a field path, or of its closest parent that has one.
+func (v *collectorValidation) locate(path string) yamlPosition {
+	for p := path; p != ""; p = parentPath(p) {
+		if pos, ok := v.positions[p]; ok {
+			return pos
+		}
+	}
+	return yamlPosition{file: v.config}
+}
+
+// joinPath appends a field to a field path.
+func joinPath(path, field string) string {
+	switch {
+	case path == "":
+		return field
+	case field == "":
+		return path
+	}
+	return path + "." + field
+}
+
+// parentPath strips the last field or index of a field path.
+func parentPath(path string) string {
+	i := strings.LastIndexAny(path, ".[")
+	if i < 0 {
+		return ""
+	}
+	return path[:i]
+}
+
+// fileExists reports whether path exists.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// sectionFactory returns the factory of a component type in a config section, or nil.
+func sectionFactory(factories otelcol.Factories, section string, typ component.Type) component.Factory {
+	var factory component.Factory
+	var ok bool
+	switch section {
+	case "receivers":
+		factory, ok = factories.Receivers[typ]
+	case "processors":
+		factory, ok = factories.Processors[typ]
+	case "exporters":
+		factory, ok = factories.Exporters[typ]
+	case "extensions":
+		factory, ok = factories.Extensions[typ]
+	case "connectors":
+		factory, ok = factories.Connectors[typ]
+	}
+	if !ok {
+		return nil
+	}
+	return factory
+}
+
+// signalFactory is implemented by receiver, processor and exporter factories.
+type signalFactory interface {
+	TracesStability() component.StabilityLevel
+	MetricsStability() component.StabilityLevel
+	LogsStability() component.StabilityLevel
+}
+
+// signalStability returns the stability of a factory for a signal; StabilityLevelUndefined means unsupported.
+func signalStability(f signalFactory, signal pipeline.Signal) component.StabilityLevel {
+	switch signal {
+	case pipeline.SignalTraces:
+		return f.TracesStability()
+	case pipeline.SignalMetrics:
+		return f.MetricsStability()
+	case pipeline.SignalLogs:
+		return f.LogsStability()
+	}
+	return component.StabilityLevelUndefined
+}
+
+// connectorStability returns the stability of a connector from one signal to another.
+func connectorStability(f connector.Factory, from, to pipeline.Signal) component.StabilityLevel {
+	stability := map[[2]pipeline.Signal]func() component.StabilityLevel{
+		{pipeline.SignalTraces, pipeline.SignalTraces}:   f.TracesToTracesStability,
+		{pipeline.SignalTraces, pipeline.SignalMetrics}:  f.TracesToMetricsStability,
+		{pipeline.SignalTraces, pipeline.SignalLogs}:     f.TracesToLogsStability,
+		{pipeline.SignalMetrics, pipeline.SignalTraces}:  f.MetricsToTracesStability,
+		{pipeline.SignalMetrics, pipeline.SignalMetrics}: f.MetricsToMetricsStability,
+		{pipeline.SignalMetrics, pipeline.SignalLogs}:    f.MetricsToLogsStability,
+		{pipeline.SignalLogs, pipeline.SignalTraces}:     f.LogsToTracesStability,
+		{pipeline.SignalLogs, pipeline.SignalMetrics}:    f.LogsToMetricsStability,
+		{pipeline.SignalLogs, pipeline.SignalLogs}:       f.LogsToLogsStability,
+	}[[2]pipeline.Signal{from, to}]
+	if stability == nil {
+		return component.StabilityLevelUndefined
+	}
+	return stability()
+}
+
+// uniqueSignals returns signals without duplicates, in order.
+func uniqueSignals(signals []pipeline.Signal) []pipeline.Signal {
+	var out []pipeline.Signal
+	for _, s := range signals {
+		if !slices.Contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// signalNames joins signal names for messages.
+func signalNames(signals []pipeline.Signal) string {
+	names := make([]string, 0, len(signals))
+	for _, s := range signals {
+		names = append(names, s.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// WriteValidationReport renders a validation report.
+//
+// Parameters:
+//   - w: Destination writer.
+//   - r: Report to render.
+//   - format: One of "table" (default), "json" or "yaml".
+//
+// Returns:
+//   - error: If the format is unknown or encoding fails, returns a detailed error.
+func WriteValidationReport(w io.Writer, r *ValidationReport, format string) error {
+	switch strings.ToLower(format) {
+	case "", "table":
+		for _, issue := range r.Issues {
+			if _, err := fmt.Fprintln(w, issue.String()); err != nil {
+				return err
+			}
+		}
+		if len(r.Issues) > 0 {
+			_, err := fmt.Fprintf(w, "%s: %d problem(s) found\n", r.Config, len(r.Issues))
+			return err
+		}
+		_, err := fmt.Fprintf(w, "%s: OK (%d pipelines, %d components)\n", r.Config, r.Pipelines, r.Components)
+		return err
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("failed to encode validation report as YAML: %w", err)
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("unsupported output format %q (expected table, json, or yaml)", format)
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/connector/connectortest"
+
+	"github.com/srediag/srediag/internal/core"
+	"github.com/srediag/srediag/internal/diagnose"
+)
+
+func TestValidateCollectorConfig_Issues(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "pipelines/metrics.yaml", "receivers: [nop, failing]\nexporters: [nop]\nextra: 1\n")
+	writeFile(t, dir, "plugins.d/receivers/nop@service.yaml", "endpoint: localhost:4317\n")
+	path := writeFile(t, dir, "service.yaml", `receivers:
+  nop/unused:
+exporters:
+  nop:
+    bogus: true
+extensions:
+  zpages:
+service:
+  extensions: [zpages]
+  pipelines:
+    traces:
+      receivers: [nop, otlp]
+      exporters: [nop]
+      exporter: [nop]
+    metrics: !include pipelines/metrics.yaml
+`)
+	svc := newNopService(t, path, &bytes.Buffer{})
+	failing := failingReceiverFactory()
+	svc.receivers[failing.Type()] = failing
+
+	report := svc.ValidateCollectorConfig(context.Background(), path)
+	included := filepath.Join(dir, "pipelines/metrics.yaml")
+	plugin := filepath.Join(dir, "plugins.d/receivers/nop@service.yaml")
+	assert.ElementsMatch(t, []ValidationIssue{
+		{File: path, Line: 2, Path: "receivers.nop/unused", Message: `receiver "nop/unused" is defined but not used in any pipeline`},
+		{File: path, Line: 5, Path: "exporters.nop.bogus", Message: `unknown field "bogus"`},
+		{File: path, Line: 7, Path: "extensions.zpages", Message: `unknown extension type "zpages": it is not built in and no loaded plugin provides it`},
+		{File: path, Line: 12, Path: "service.pipelines.traces.receivers[1]", Message: `unknown receiver type "otlp": it is not built in and no loaded plugin provides it`},
+		{File: path, Line: 14, Path: "service.pipelines.traces.exporter", Message: `unknown field "exporter"`},
+		{File: plugin, Line: 1, Path: "receivers.nop.endpoint", Message: `unknown field "endpoint"`},
+		{File: plugin, Line: 1, Path: "receivers.nop/unused.endpoint", Message: `unknown field "endpoint"`},
+		{File: included, Line: 1, Path: "service.pipelines.metrics.receivers[1]", Message: `receiver "failing" does not support metrics`},
+		{File: included, Line: 3, Path: "service.pipelines.metrics.extra", Message: `unknown field "extra"`},
+	}, report.Issues)
+	assert.Equal(t, 2, report.Pipelines)
+
+	err := report.Err()
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, diagnose.ExitVerification, diagnose.ExitCode(err))
+
+	var out bytes.Buffer
+	require.NoError(t, WriteValidationReport(&out, report, "table"))
+	assert.Contains(t, out.String(), path+":5: exporters.nop.bogus: unknown field \"bogus\"\n")
+	assert.Contains(t, out.String(), "9 problem(s) found")
+}
+
+func TestValidateCollectorConfig_Valid(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "service.yaml", nopPipelineConfig)
+	report := newNopService(t, path, &bytes.Buffer{}).ValidateCollectorConfig(context.Background(), path)
+	assert.Empty(t, report.Issues)
+	assert.NoError(t, report.Err())
+	assert.Equal(t, 1, report.Pipelines)
+	assert.Equal(t, 3, report.Components)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteValidationReport(&out, report, ""))
+	assert.Equal(t, path+": OK (1 pipelines, 3 components)\n", out.String())
+}
+
+func TestValidateCollectorConfig_Connectors(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "service.yaml", `receivers:
+  nop:
+exporters:
+  nop:
+connectors:
+  nop/forward:
+service:
+  pipelines:
+    traces:
+      receivers: [nop]
+      exporters: [nop/forward]
+    metrics:
+      receivers: [nop]
+      exporters: [nop]
+`)
+	svc := newNopService(t, path, &bytes.Buffer{})
+	forward := connectortest.NewNopFactory()
+	svc.SetConnectors(map[component.Type]component.Factory{forward.Type(): forward})
+	report := svc.ValidateCollectorConfig(context.Background(), path)
+	require.Len(t, report.Issues, 1)
+	assert.Equal(t, ValidationIssue{
+		File: path, Line: 6, Path: "connectors.nop/forward",
+		Message: `connector "nop/forward" is used as an exporter but not as a receiver of any pipeline`,
+	}, report.Issues[0])
+}
+
+func TestValidateCollectorConfig_Syntax(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "service.yaml", "receivers:\n  nop:\n exporters: [\n")
+	report := newNopService(t, path, &bytes.Buffer{}).ValidateCollectorConfig(context.Background(), path)
+	require.Len(t, report.Issues, 1)
+	assert.Equal(t, path, report.Issues[0].File)
+	assert.Positive(t, report.Issues[0].Line)
+}
+
+func TestCLI_Validate(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "service.yaml", nopPipelineConfig)
+	appCtx := &core.AppContext{Config: core.NewConfig(), Logger: core.NewTestLogger(&bytes.Buffer{})}
+	cmd := &cobra.Command{}
+	cmd.Flags().String("output", "json", "")
+	cmd.SetContext(context.Background())
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	// Without a ComponentManager no component type is known.
+	err := CLI_Validate(appCtx, cmd, []string{path})
+	assert.Equal(t, diagnose.ExitVerification, diagnose.ExitCode(err))
+	var report ValidationReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assert.Equal(t, path, report.Config)
+	assert.NotEmpty(t, report.Issues)
+
+	appCtx.Config.Collector.ConfigPath = ""
+	assert.ErrorContains(t, CLI_Validate(appCtx, cmd, nil), "no collector config")
+}
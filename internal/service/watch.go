@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/srediag/srediag/internal/core"
+)
+
+// Package service provides the core service orchestration, lifecycle management, and CLI stubs for the SREDIAG collector service.
+//
+// This file defines the optional config watcher (collector.watch): it watches the directories of the agent config,
+// the collector config, its plugins.d sub-directories and the plugin binaries, and signals a reload once edits have
+// settled. Directories rather than files are watched because editors and config management replace files.
+//
+// Usage:
+//   - Call NewConfigWatcher with watchPaths(cfg, configFile), reload on every value of Changes, Close on exit.
+//
+// Best Practices:
+//   - Keep unrelated, frequently written files out of the watched directories; a reload of an unchanged config
+//     restarts nothing, but it is not free.
+
+// watchDebounce is how long the watcher waits for edits to settle before it signals a change.
+const watchDebounce = time.Second
+
+// ConfigWatcher signals changes of the files a reload re-reads.
+type ConfigWatcher struct {
+	logger  *core.Logger
+	watcher *fsnotify.Watcher
+	changes chan struct{}
+	done    chan struct{}
+}
+
+// NewConfigWatcher watches the given directories; missing ones are skipped.
+//
+// Parameters:
+//   - logger: Logger for watch errors.
+//   - dirs: Directories to watch (see watchPaths).
+//   - debounce: Quiet period after the last event before a change is signalled.
+//
+// Returns:
+//   - *ConfigWatcher: The watcher; call Close when done.
+//   - error: If the watcher cannot be created, returns a detailed error.
+func NewConfigWatcher(logger *core.Logger, dirs []string, debounce time.Duration) (*ConfigWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			_ = watcher.Close()
+			return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+	w := &ConfigWatcher{logger: logger, watcher: watcher, changes: make(chan struct{}, 1), done: make(chan struct{})}
+	go w.run(debounce)
+	return w, nil
+}
+
+// Changes returns a channel that receives a value once edits have settled.
+//
+// Returns:
+//   - <-chan struct{}: The change channel.
+func (w *ConfigWatcher) Changes() <-chan struct{} {
+	return w.changes
+}
+
+// Close stops watching.
+//
+// Returns:
+//   - error: If the watcher cannot be closed, returns a detailed error.
+func (w *ConfigWatcher) Close() error {
+	err := w.watcher.Close()
+	<-w.done
+	return err
+}
+
+// run debounces file events into change signals until the watcher is closed.
+func (w *ConfigWatcher) run(debounce time.Duration) {
+	defer close(w.done)
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	for {
+		select {
+		case ev, ok := <-w.watcher.Events:
+			if !ok {
+				timer.Stop()
+				return
+			}
+			if ev.Op == fsnotify.Chmod {
+				continue
+			}
+			timer.Reset(debounce)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				timer.Stop()
+				return
+			}
+			w.logger.Warn("Config watcher error", core.ZapError(err))
+		case <-timer.C:
+			select {
+			case w.changes <- struct{}{}:
+			default:
+				// A reload is already pending; it will see this edit too.
+			}
+		}
+	}
+}
+
+// watchPaths returns the directories watched for collector.watch: those of the agent config and the collector
+// config, the collector's plugins.d sections and the plugin binary directories.
+func watchPaths(cfg *core.Config, configFile string) []string {
+	var dirs []string
+	if configFile != "" {
+		dirs = append(dirs, filepath.Dir(configFile))
+	}
+	if cfg.Collector.Enabled && cfg.Collector.ConfigPath != "" {
+		dir := filepath.Dir(cfg.Collector.ConfigPath)
+		dirs = append(dirs, dir)
+		for _, section := range collectorSections {
+			dirs = append(dirs, filepath.Join(dir, "plugins.d", section))
+		}
+		if cfg.Plugins.ExecDir != "" {
+			for _, typ := range []core.ComponentType{core.TypeReceiver, core.TypeProcessor, core.TypeExporter, core.TypeExtension} {
+				dirs = append(dirs, filepath.Join(cfg.Plugins.ExecDir, string(typ)+"s"))
+			}
+		}
+	}
+	for i, dir := range dirs {
+		if abs, err := filepath.Abs(dir); err == nil {
+			dirs[i] = abs
+		}
+	}
+	slices.Sort(dirs)
+	return slices.Compact(dirs)
+}